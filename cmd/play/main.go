@@ -0,0 +1,134 @@
+// Command play joins a session from a terminal and plays it by typing
+// moves in the game's notation, such as b2 in tic-tac-toe:
+//
+//	play -code ABCD -player alice
+//
+// Each state is printed as JSON. A line reading start starts the match
+// for its host, quit leaves, a line starting with a slash or a quote is
+// sent to the chat, as a command such as /help or, without the quote, as
+// something to say, and any other line is sent as a move.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/server"
+)
+
+func main() {
+	addr := flag.String("server", "http://localhost:8080", "the server's base URL")
+	code := flag.String("code", "", "the session to join")
+	player := flag.String("player", "", "the player ID to join as")
+	token := flag.String("token", "", "the seat's join secret, to take back a seat this client or another held")
+	flag.Parse()
+	if *code == "" || *player == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	url := "ws" + strings.TrimPrefix(strings.TrimSuffix(*addr, "/"), "http") + "/api/sessions/" + *code + "/ws"
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+	defer conn.CloseNow()
+	if err := send(ctx, conn, "join", map[string]string{"playerId": *player, "token": *token}); err != nil {
+		log.Fatalf("join: %v", err)
+	}
+	go func() {
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				log.Fatalf("connection closed: %v", err)
+			}
+			var msg server.WSMessage
+			if err := json.Unmarshal(data, &msg); err == nil {
+				show(msg)
+			}
+		}
+	}()
+
+	lines := bufio.NewScanner(os.Stdin)
+	for lines.Scan() {
+		var err error
+		switch line := strings.TrimSpace(lines.Text()); line {
+		case "":
+		case "quit":
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		case "start":
+			err = send(ctx, conn, "start", nil)
+		default:
+			if strings.HasPrefix(line, "/") || strings.HasPrefix(line, "'") {
+				err = send(ctx, conn, "chat", map[string]string{"text": strings.TrimPrefix(line, "'")})
+				break
+			}
+			err = send(ctx, conn, "action_text", map[string]string{"text": line})
+		}
+		if err != nil {
+			log.Fatalf("send: %v", err)
+		}
+	}
+}
+
+func send(ctx context.Context, conn *websocket.Conn, msgType string, payload any) error {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(server.WSMessage{Type: msgType, Payload: p})
+	if err != nil {
+		return err
+	}
+	return conn.Write(ctx, websocket.MessageText, data)
+}
+
+// show prints the messages a player needs to follow the match.
+func show(msg server.WSMessage) {
+	switch msg.Type {
+	case "state":
+		var sp struct {
+			State        json.RawMessage   `json:"state"`
+			ValidActions []json.RawMessage `json:"validActions"`
+		}
+		json.Unmarshal(msg.Payload, &sp)
+		fmt.Println(string(sp.State))
+		if len(sp.ValidActions) > 0 {
+			fmt.Println("Your move:")
+		}
+	case "seat":
+		var seat struct {
+			Token string `json:"token"`
+		}
+		json.Unmarshal(msg.Payload, &seat)
+		fmt.Printf("Seat token %s; pass it as -token to come back to this seat.\n", seat.Token)
+	case "chat":
+		var line struct {
+			PlayerID string `json:"playerId"`
+			Text     string `json:"text"`
+		}
+		json.Unmarshal(msg.Payload, &line)
+		if line.PlayerID != "" {
+			fmt.Print(line.PlayerID + ": ")
+		}
+		fmt.Println(line.Text)
+	case "events", "message":
+		fmt.Println(msg.Type + ": " + string(msg.Payload))
+	case "error":
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(msg.Payload, &e)
+		fmt.Println("Error: " + e.Message)
+	}
+}
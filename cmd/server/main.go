@@ -1,15 +1,30 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/fs"
 	"log"
+	"maps"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	games "games"
 	"games/internal/game"
+	"games/internal/game/script"
+	"games/internal/game/subprocess"
 	"games/internal/game/tictactoe"
+	"games/internal/game/wasm"
+	"games/internal/mail"
+	"games/internal/metrics"
+	"games/internal/push"
 	"games/internal/server"
 	"games/internal/session"
 	"games/internal/storage"
@@ -26,30 +41,522 @@ func main() {
 		dbPath = p
 	}
 
-	store, err := storage.New(dbPath)
-	if err != nil {
-		log.Fatalf("open database: %v", err)
+	backend := os.Getenv("STORAGE")
+	if backend != "" && backend != "sqlite" && backend != "memory" {
+		log.Fatalf("STORAGE: unknown backend %q: want sqlite or memory", backend)
+	}
+	var queryTimeout, slowQuery time.Duration = -1, -1
+	if v := os.Getenv("DB_QUERY_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("DB_QUERY_TIMEOUT: %v", err)
+		}
+		queryTimeout = d
+	}
+	if v := os.Getenv("SLOW_QUERY_THRESHOLD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("SLOW_QUERY_THRESHOLD: %v", err)
+		}
+		slowQuery = d
 	}
+	// openStore opens the database of the default site, or of a tenant
+	// when given its name.
+	openStore := func(tenant string) (storage.Backend, *metrics.Histogram) {
+		if backend == "memory" {
+			if tenant == "" {
+				log.Printf("ephemeral mode: sessions are kept in memory and lost on restart")
+			}
+			return storage.NewMemory(), nil
+		}
+		path := dbPath
+		if tenant != "" {
+			ext := filepath.Ext(dbPath)
+			path = strings.TrimSuffix(dbPath, ext) + "-" + tenant + ext
+		}
+		db, err := storage.New(path)
+		if err != nil {
+			log.Fatalf("open database %s: %v", path, err)
+		}
+		if queryTimeout >= 0 {
+			db.SetQueryTimeout(queryTimeout)
+		}
+		if slowQuery >= 0 {
+			db.SetSlowQueryThreshold(slowQuery)
+		}
+		return db, db.QueryMetrics()
+	}
+	store, queryMetrics := openStore("")
 	defer store.Close()
+	var tenantStores []storage.Backend
+	defer func() {
+		for _, s := range tenantStores {
+			s.Close()
+		}
+	}()
 
 	registry := game.NewRegistry()
 	registry.Register(tictactoe.TicTacToe{})
+	registry.Register(tictactoe.Blitz{})
+	for _, strategy := range tictactoe.Strategies() {
+		registry.RegisterStrategy("tictactoe", strategy)
+		registry.RegisterStrategy("blitz", strategy)
+	}
+	sources := gameSources{
+		plugins: filepath.SplitList(os.Getenv("GAME_PLUGINS")),
+		scripts: filepath.SplitList(os.Getenv("GAME_SCRIPTS")),
+		timeout: subprocess.DefaultCallTimeout,
+		builtin: registry.Names(),
+	}
+	if v := os.Getenv("GAME_PLUGIN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("GAME_PLUGIN_TIMEOUT: %v", err)
+		}
+		sources.timeout = d
+	}
+	loaded, err := sources.load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, g := range loaded {
+		if c, ok := g.(io.Closer); ok {
+			defer c.Close()
+		}
+		registry.Register(g)
+	}
+	if path := os.Getenv("GAME_OPTIONS"); path != "" {
+		if err := configureOptions(registry, path); err != nil {
+			log.Fatalf("game options: %v", err)
+		}
+	}
 
-	mgr := session.NewManager(registry, store)
-	if err := mgr.Restore(); err != nil {
-		log.Printf("warning: restore sessions: %v", err)
+	limits := game.DefaultLimits
+	for name, limit := range map[string]*int{
+		"MAX_STATE_BYTES":     &limits.MaxStateBytes,
+		"MAX_BROADCAST_BYTES": &limits.MaxBroadcastBytes,
+	} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.Fatalf("%s: want a byte count, got %q", name, v)
+			}
+			*limit = n
+		}
 	}
+	registry.SetDefaultLimits(limits)
 
-	go mgr.CleanupLoop(1*time.Minute, 1*time.Hour)
+	features, err := session.ParseFeatures(os.Getenv("FEATURES"))
+	if err != nil {
+		log.Fatalf("FEATURES: %v", err)
+	}
+	abandonAfter := 30 * time.Minute
+	if v := os.Getenv("ABANDON_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("ABANDON_AFTER: %v", err)
+		}
+		abandonAfter = d
+	}
+	cleanup := session.CleanupPolicy{MaxAge: session.DefaultCleanupMaxAge, DryRun: os.Getenv("CLEANUP_DRY_RUN") != ""}
+	if v := os.Getenv("CLEANUP_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("CLEANUP_MAX_AGE: %v", err)
+		}
+		cleanup.MaxAge = d
+	}
+	seasons, err := session.ParseSeasonLength(os.Getenv("SEASONS"))
+	if err != nil {
+		log.Fatalf("SEASONS: %v", err)
+	}
+	var surveyInterval time.Duration
+	if v := os.Getenv("SURVEY_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("SURVEY_INTERVAL: %v", err)
+		}
+		surveyInterval = d
+	}
 
 	webFS, err := fs.Sub(games.WebFS, "web")
 	if err != nil {
 		log.Fatalf("web fs: %v", err)
 	}
-	srv := server.New(registry, mgr, webFS)
+	dev := os.Getenv("DEV") != ""
+	if dev {
+		// Serve the frontend from the working tree so edits need no rebuild.
+		webFS = os.DirFS("web")
+		log.Printf("dev mode: serving web/ from disk")
+	}
+	messageRate := float64(server.DefaultMessageRate)
+	if v := os.Getenv("WS_MESSAGE_RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			log.Fatalf("WS_MESSAGE_RATE: want messages a second, got %q", v)
+		}
+		messageRate = rate
+	}
+	compression, threshold := server.DefaultCompression, 0
+	if v := os.Getenv("WS_COMPRESSION"); v != "" {
+		compression = v
+	}
+	if v := os.Getenv("WS_COMPRESSION_THRESHOLD"); v != "" {
+		if threshold, err = strconv.Atoi(v); err != nil {
+			log.Fatalf("WS_COMPRESSION_THRESHOLD: want bytes, got %q", v)
+		}
+	}
+	recordDir := os.Getenv("WS_RECORD_DIR")
+	baseURL := strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	guestKey := os.Getenv("GUEST_KEY")
+	frameAncestors := strings.Fields(os.Getenv("FRAME_ANCESTORS"))
+	branding := session.Branding{
+		Name:           os.Getenv("BRAND_NAME"),
+		LogoURL:        os.Getenv("BRAND_LOGO_URL"),
+		AccentColor:    os.Getenv("BRAND_ACCENT_COLOR"),
+		SecondaryColor: os.Getenv("BRAND_SECONDARY_COLOR"),
+	}
+
+	var iceServers []server.ICEServer
+	if v := os.Getenv("RTC_ICE_SERVERS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &iceServers); err != nil {
+			log.Fatalf("RTC_ICE_SERVERS: %v", err)
+		}
+	}
+
+	var adminTokens map[string]string
+	if v := os.Getenv("ADMIN_TOKENS"); v != "" {
+		adminTokens = make(map[string]string)
+		for _, pair := range strings.Split(v, ",") {
+			name, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok || name == "" || token == "" {
+				log.Fatalf("ADMIN_TOKENS: expected name:token, got %q", pair)
+			}
+			adminTokens[token] = name
+		}
+	}
+
+	var sender *push.Sender
+	if key := os.Getenv("VAPID_PRIVATE_KEY"); key != "" {
+		keys, err := push.ParseKeys(key)
+		if err != nil {
+			log.Fatalf("vapid keys: %v", err)
+		}
+		subject := "mailto:admin@localhost"
+		if v := os.Getenv("VAPID_SUBJECT"); v != "" {
+			subject = v
+		}
+		sender = push.NewSender(keys, subject)
+	}
+
+	var mailer mail.Mailer
+	mailURL := baseURL
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			log.Fatal("SMTP_FROM is required when SMTP_ADDR is set")
+		}
+		if mailURL == "" {
+			mailURL = "http://localhost" + addr
+		}
+		mailer = mail.NewSMTPMailer(smtpAddr, from, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"))
+	}
+
+	ctx := context.Background()
+	// startSite runs the sessions of the default site, or of a tenant when
+	// given its name, and returns the server for them.
+	startSite := func(tenant string, registry *game.Registry, store storage.Backend) *server.Server {
+		mgr := session.NewManager(registry, store)
+		for _, f := range features {
+			mgr.SetFeature(f)
+		}
+		if err := mgr.Restore(ctx); err != nil {
+			log.Printf("warning: restore sessions: %v", err)
+		}
+		mgr.SetCleanupPolicy(cleanup)
+		mgr.SetSeasonLength(seasons)
+		mgr.SetSurveyInterval(surveyInterval)
+		go mgr.CleanupLoop(ctx, 1*time.Minute, abandonAfter)
+		go mgr.PurgeLoop(ctx, 1*time.Hour, 7*24*time.Hour)
+		go mgr.MaintainLoop(ctx, 6*time.Hour)
+		go mgr.SeasonLoop(ctx, 1*time.Minute)
+
+		srv := server.New(registry, mgr, webFS)
+		go srv.ClockLoop(ctx, 100*time.Millisecond)
+		srv.SetDevMode(dev)
+		srv.SetMessageRate(messageRate)
+		if err := srv.SetCompression(compression, threshold); err != nil {
+			log.Fatalf("WS_COMPRESSION: %v", err)
+		}
+		if recordDir != "" {
+			dir := filepath.Join(recordDir, tenant)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				log.Fatalf("WS_RECORD_DIR: %v", err)
+			}
+			srv.SetWSRecordDir(dir)
+			log.Printf("recording WebSocket traffic to %s", dir)
+		}
+		srv.SetBaseURL(baseURL)
+		if err := srv.SetFrameAncestors(frameAncestors); err != nil {
+			log.Fatalf("FRAME_ANCESTORS: %v", err)
+		}
+		if err := srv.SetICEServers(iceServers); err != nil {
+			log.Fatalf("RTC_ICE_SERVERS: %v", err)
+		}
+		srv.SetAdminTokens(adminTokens)
+		if guestKey != "" {
+			// Each tenant signs its own guests
+			srv.SetGuestKey([]byte(guestKey + "/" + tenant))
+		}
+
+		prefix := ""
+		if tenant != "" {
+			prefix = "/t/" + tenant
+		}
+		if sender != nil {
+			notifier := push.NewNotifier(sender, mgr)
+			notifier.SetPathPrefix(prefix)
+			events, _ := mgr.Events().Subscribe(256)
+			go notifier.Run(events)
+			srv.SetPushKey(notifier.PublicKey())
+		}
+		if mailer != nil {
+			notifier := mail.NewNotifier(mailer, mgr, mailURL+prefix)
+			events, _ := mgr.Events().Subscribe(256)
+			go notifier.Run(events)
+			srv.SetMailer(notifier)
+		}
+		return srv
+	}
+
+	srv := startSite("", registry, store)
+	srv.SetGameLoader(sources.loader(nil))
+	if err := srv.SetBranding(branding); err != nil {
+		log.Fatalf("BRAND_*: %v", err)
+	}
+	if queryMetrics != nil {
+		srv.RegisterMetrics(queryMetrics)
+	}
+	var handler http.Handler = srv
+	if path := os.Getenv("TENANTS"); path != "" {
+		tenants, err := startTenants(path, srv, registry, func(name string, registry *game.Registry, config tenantConfig) (*server.Server, error) {
+			store, _ := openStore(name)
+			tenantStores = append(tenantStores, store)
+			srv := startSite(name, registry, store)
+			srv.SetGameLoader(sources.loader(config.Games))
+			// A tenant's branding goes over the site's
+			if err := srv.SetBranding(config.Branding.Over(branding)); err != nil {
+				return nil, err
+			}
+			return srv, nil
+		})
+		if err != nil {
+			log.Fatalf("TENANTS: %v", err)
+		}
+		handler = tenants
+	}
 
 	log.Printf("listening on %s", addr)
-	if err := http.ListenAndServe(addr, srv); err != nil {
+	if err := http.ListenAndServe(addr, handler); err != nil {
 		log.Fatalf("server: %v", err)
 	}
 }
+
+// tenantConfig is a tenant's entry in the TENANTS file.
+type tenantConfig struct {
+	// Games are the games the tenant offers; none means every game.
+	Games []string `json:"games"`
+	// Branding is how the tenant's pages present it, over the site's.
+	Branding session.Branding `json:"branding"`
+}
+
+// startTenants reads the JSON file at path, mapping tenant names to their
+// configs, and serves each tenant the server start returns for it
+// alongside root.
+func startTenants(path string, root *server.Server, registry *game.Registry, start func(name string, registry *game.Registry, config tenantConfig) (*server.Server, error)) (*server.Tenants, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]tenantConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	tenants := server.NewTenants(root)
+	for _, name := range slices.Sorted(maps.Keys(config)) {
+		if err := server.CheckTenantName(name); err != nil {
+			return nil, err
+		}
+		sub, err := registry.Subset(config[name].Games...)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %q: %v", name, err)
+		}
+		srv, err := start(name, sub, config[name])
+		if err != nil {
+			return nil, fmt.Errorf("tenant %q: %v", name, err)
+		}
+		if err := tenants.Add(name, srv); err != nil {
+			return nil, err
+		}
+		log.Printf("tenant %s served at /t/%s/", name, name)
+	}
+	return tenants, nil
+}
+
+// gameSources are where the games loaded at runtime come from: plugin
+// commands and Starlark scripts, each a file or a directory of them. They
+// are loaded at startup and again whenever an admin reloads games.
+type gameSources struct {
+	plugins []string
+	scripts []string
+	timeout time.Duration // per plugin call
+	builtin []string      // names loaded games may not take
+}
+
+// load starts every plugin and parses every script. If any fails, the
+// plugins already started are stopped.
+func (src gameSources) load() (games []game.Game, err error) {
+	defer func() {
+		if err != nil {
+			for _, g := range games {
+				closeGame(g)
+			}
+			games = nil
+		}
+	}()
+	from := make(map[string]string) // game name -> path
+	add := func(g game.Game, path string) error {
+		name := g.Info().Name
+		if other, ok := from[name]; ok {
+			return fmt.Errorf("%s: game %q already loaded from %s", path, name, other)
+		}
+		if slices.Contains(src.builtin, name) {
+			return fmt.Errorf("%s: game %q already registered", path, name)
+		}
+		from[name] = path
+		games = append(games, g)
+		return nil
+	}
+
+	plugins, err := expandPaths(src.plugins, func(e fs.DirEntry) bool {
+		info, err := e.Info()
+		return err == nil && info.Mode().IsRegular() && (filepath.Ext(e.Name()) == ".wasm" || info.Mode()&0o111 != 0)
+	})
+	if err != nil {
+		return games, fmt.Errorf("GAME_PLUGINS: %v", err)
+	}
+	for _, path := range plugins {
+		g, err := startPlugin(path)
+		if err != nil {
+			return games, fmt.Errorf("GAME_PLUGINS: %v", err)
+		}
+		g.SetCallTimeout(src.timeout)
+		if err := add(g, path); err != nil {
+			g.Close()
+			return games, fmt.Errorf("GAME_PLUGINS: %v", err)
+		}
+		log.Printf("game %s served by %s", g.Info().Name, path)
+	}
+
+	scripts, err := expandPaths(src.scripts, func(e fs.DirEntry) bool {
+		return e.Type().IsRegular() && filepath.Ext(e.Name()) == ".star"
+	})
+	if err != nil {
+		return games, fmt.Errorf("GAME_SCRIPTS: %v", err)
+	}
+	for _, path := range scripts {
+		g, err := script.Load(path)
+		if err != nil {
+			return games, fmt.Errorf("GAME_SCRIPTS: %v", err)
+		}
+		if err := add(g, path); err != nil {
+			return games, fmt.Errorf("GAME_SCRIPTS: %v", err)
+		}
+		log.Printf("game %s scripted in %s", g.Info().Name, path)
+	}
+	return games, nil
+}
+
+// loader returns what reloads a site's games: those named, or every game
+// when none are. It is nil when no games are loaded at runtime.
+func (src gameSources) loader(names []string) func() ([]game.Game, error) {
+	if len(src.plugins) == 0 && len(src.scripts) == 0 {
+		return nil
+	}
+	return func() ([]game.Game, error) {
+		games, err := src.load()
+		if err != nil || len(names) == 0 {
+			return games, err
+		}
+		kept := games[:0]
+		for _, g := range games {
+			if slices.Contains(names, g.Info().Name) {
+				kept = append(kept, g)
+			} else {
+				closeGame(g)
+			}
+		}
+		return kept, nil
+	}
+}
+
+// expandPaths replaces each directory among paths with the files in it
+// that match, so games can be added by dropping files in.
+func expandPaths(paths []string, match func(fs.DirEntry) bool) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if match(e) {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+func closeGame(g game.Game) {
+	if c, ok := g.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// startPlugin starts a game process. A WebAssembly module runs inside the
+// server instead, kept from the filesystem and network and held to the
+// default fuel and memory.
+func startPlugin(path string) (*subprocess.Game, error) {
+	if filepath.Ext(path) == ".wasm" {
+		return wasm.Load(path, wasm.Limits{})
+	}
+	return subprocess.Start(path)
+}
+
+// configureOptions applies operator option overrides from a JSON file
+// mapping game names to option specs.
+func configureOptions(registry *game.Registry, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config map[string][]game.Option
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	for name, options := range config {
+		if err := registry.Configure(name, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+// Command tictactoe-plugin serves tic-tac-toe over the game process
+// protocol, as an example for games added through GAME_PLUGINS. Built with
+// GOOS=wasip1 GOARCH=wasm it runs inside the server, in the WebAssembly
+// interpreter of package wasm.
+package main
+
+import (
+	"log"
+	"os"
+
+	"games/internal/game/subprocess"
+	"games/internal/game/tictactoe"
+)
+
+func main() {
+	if err := subprocess.Serve(tictactoe.TicTacToe{}, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("serve: %v", err)
+	}
+}
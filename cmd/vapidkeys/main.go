@@ -0,0 +1,18 @@
+// Command vapidkeys prints a new VAPID key pair for Web Push.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"games/internal/push"
+)
+
+func main() {
+	keys, err := push.GenerateKeys()
+	if err != nil {
+		log.Fatalf("generate keys: %v", err)
+	}
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\n", keys.PublicKey())
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", keys.PrivateKey())
+}
@@ -0,0 +1,180 @@
+// Command wstypes writes TypeScript declarations of the session WebSocket
+// protocol, and of the built-in games' state views, for the web frontend:
+//
+//	go run ./cmd/wstypes -o web/js/types.d.ts
+//
+// go generate runs it from the repository root. The declarations are
+// built from the schema GET /api/ws-schema serves, so they follow the Go
+// types.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/server"
+)
+
+// builtinGames are the games whose state views get declarations. Games in
+// other processes and scripts shape their states at run time.
+var builtinGames = []game.Game{tictactoe.TicTacToe{}}
+
+func main() {
+	out := flag.String("o", "", "file to write; standard output if empty")
+	flag.Parse()
+	ts, err := generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(ts)
+		return
+	}
+	if err := os.WriteFile(*out, ts, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// document is the part of the AsyncAPI document the declarations need.
+type document struct {
+	Operations map[string]struct {
+		Messages []schema `json:"messages"`
+	} `json:"operations"`
+	Components struct {
+		Messages map[string]struct {
+			Name    string `json:"name"`
+			Summary string `json:"summary"`
+			Payload schema `json:"payload"`
+		} `json:"messages"`
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type schema struct {
+	Ref                  string            `json:"$ref"`
+	Type                 string            `json:"type"`
+	Const                *string           `json:"const"`
+	Properties           map[string]schema `json:"properties"`
+	Required             []string          `json:"required"`
+	Items                *schema           `json:"items"`
+	AdditionalProperties *schema           `json:"additionalProperties"`
+}
+
+func generate() ([]byte, error) {
+	states := make(map[string]any)
+	for _, g := range builtinGames {
+		info := g.Info()
+		players := make([]string, info.MinPlayers)
+		for i := range players {
+			players[i] = "p" + strconv.Itoa(i+1)
+		}
+		states[info.Name] = g.NewMatch(game.MatchConfig{PlayerIDs: players}).State(players[0])
+	}
+	data, err := json.Marshal(server.WSSchema(states))
+	if err != nil {
+		return nil, err
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by go run ./cmd/wstypes; DO NOT EDIT.\n")
+	b.WriteString("//\n// Types of the session WebSocket protocol, from the Go types the server\n")
+	b.WriteString("// marshals. Use them from plain JavaScript through JSDoc, as in\n")
+	b.WriteString("// /** @param {import(\"./types\").StatePayload} payload */.\n")
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("\n")
+		writeInterface(&b, name, doc.Components.Schemas[name])
+	}
+
+	for _, union := range []struct{ name, operation, doc string }{
+		{"ClientMessage", "receiveClientMessages", "A message a client sends."},
+		{"ServerMessage", "sendServerMessages", "A message the server sends."},
+	} {
+		op, ok := doc.Operations[union.operation]
+		if !ok {
+			return nil, fmt.Errorf("schema has no %s operation", union.operation)
+		}
+		fmt.Fprintf(&b, "\n/** %s */\nexport type %s =", union.doc, union.name)
+		for _, ref := range op.Messages {
+			msg, ok := doc.Components.Messages[refName(ref.Ref)]
+			if !ok {
+				return nil, fmt.Errorf("schema has no message %s", ref.Ref)
+			}
+			payload := msg.Payload.Properties["payload"]
+			field := "payload: " + tsType(payload)
+			if payload.Type == "null" {
+				field = "payload?: null"
+			}
+			fmt.Fprintf(&b, "\n    /** %s */\n    | { type: %q; %s }", msg.Summary, msg.Name, field)
+		}
+		b.WriteString(";\n")
+	}
+	return b.Bytes(), nil
+}
+
+func writeInterface(b *bytes.Buffer, name string, s schema) {
+	fmt.Fprintf(b, "export interface %s {\n", name)
+	props := make([]string, 0, len(s.Properties))
+	for prop := range s.Properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+	for _, prop := range props {
+		optional := "?"
+		for _, r := range s.Required {
+			if r == prop {
+				optional = ""
+			}
+		}
+		fmt.Fprintf(b, "    %s%s: %s;\n", prop, optional, tsType(s.Properties[prop]))
+	}
+	b.WriteString("}\n")
+}
+
+func tsType(s schema) string {
+	switch {
+	case s.Ref != "":
+		return refName(s.Ref)
+	case s.Const != nil:
+		return strconv.Quote(*s.Const)
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "null":
+		return "null"
+	case "array":
+		return tsType(*s.Items) + "[]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "Record<string, " + tsType(*s.AdditionalProperties) + ">"
+		}
+	}
+	return "unknown"
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
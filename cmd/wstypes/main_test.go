@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestTypesUpToDate(t *testing.T) {
+	ts, err := generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	committed, err := os.ReadFile("../../web/js/types.d.ts")
+	if err != nil {
+		t.Fatalf("read types: %v", err)
+	}
+	if !bytes.Equal(ts, committed) {
+		t.Fatal("web/js/types.d.ts is out of date; run go generate")
+	}
+}
@@ -2,6 +2,12 @@ module games
 
 go 1.24.5
 
+require (
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
+	modernc.org/sqlite v1.45.0
+	nhooyr.io/websocket v1.8.17
+)
+
 require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/google/uuid v1.6.0 // indirect
@@ -13,6 +19,4 @@ require (
 	modernc.org/libc v1.67.6 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.11.0 // indirect
-	modernc.org/sqlite v1.45.0 // indirect
-	nhooyr.io/websocket v1.8.17 // indirect
 )
@@ -0,0 +1,110 @@
+package event
+
+import (
+	"sync"
+	"time"
+
+	"games/internal/game"
+)
+
+// Event types published on the bus.
+const (
+	SessionCreated = "session_created"
+	MatchStarted   = "match_started"
+	MatchFinished  = "match_finished"
+
+	ChallengeIssued   = "challenge_issued"
+	ChallengeAccepted = "challenge_accepted"
+	ChallengeDeclined = "challenge_declined"
+
+	FriendRequested = "friend_requested"
+	FriendAccepted  = "friend_accepted"
+
+	YourTurn  = "your_turn"
+	LobbyFull = "lobby_full"
+
+	AchievementUnlocked = "achievement_unlocked"
+
+	TournamentRound    = "tournament_round"
+	TournamentResult   = "tournament_result"
+	TournamentFinished = "tournament_finished"
+)
+
+// Event is something that happened to a session.
+type Event struct {
+	Type        string              `json:"type"`
+	SessionCode string              `json:"sessionCode"`
+	GameType    string              `json:"gameType"`
+	Players     []string            `json:"players,omitempty"`
+	Results     []game.PlayerResult `json:"results,omitempty"`
+	Abandoned   bool                `json:"abandoned,omitempty"` // finished because every player left
+	ChallengeID string              `json:"challengeId,omitempty"`
+	Achievement string              `json:"achievement,omitempty"`
+	Tournament  string              `json:"tournamentId,omitempty"`
+	Private     bool                `json:"-"` // not shown in public feeds
+	Recipients  []string            `json:"-"` // if set, shown only to these players
+	At          time.Time           `json:"at"`
+}
+
+// Bus fans events out to subscribers and remembers the most recent ones.
+type Bus struct {
+	mu      sync.RWMutex
+	subs    map[chan Event]struct{}
+	recent  []Event
+	history int
+}
+
+// NewBus creates a bus that remembers the last history events.
+func NewBus(history int) *Bus {
+	return &Bus{
+		subs:    make(map[chan Event]struct{}),
+		history: history,
+	}
+}
+
+// Publish delivers e to every subscriber. Subscribers that are not keeping
+// up miss the event rather than blocking the publisher.
+func (b *Bus) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.recent = append(b.recent, e)
+	if len(b.recent) > b.history {
+		b.recent = b.recent[len(b.recent)-b.history:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of future events and a function that ends the
+// subscription and closes the channel.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Recent returns the remembered events, oldest first.
+func (b *Bus) Recent() []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]Event, len(b.recent))
+	copy(out, b.recent)
+	return out
+}
@@ -0,0 +1,44 @@
+package event
+
+import "testing"
+
+func TestPublishSubscribe(t *testing.T) {
+	b := NewBus(10)
+	ch, unsubscribe := b.Subscribe(4)
+
+	b.Publish(Event{Type: SessionCreated, SessionCode: "abc"})
+	e := <-ch
+	if e.Type != SessionCreated || e.SessionCode != "abc" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if e.At.IsZero() {
+		t.Fatal("expected publish to stamp the event time")
+	}
+
+	unsubscribe()
+	unsubscribe() // safe to call twice
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel closed after unsubscribe")
+	}
+	b.Publish(Event{Type: MatchStarted}) // must not panic on closed subscriber
+}
+
+func TestSlowSubscriberDoesNotBlock(t *testing.T) {
+	b := NewBus(10)
+	_, unsubscribe := b.Subscribe(1)
+	defer unsubscribe()
+	for i := 0; i < 5; i++ {
+		b.Publish(Event{Type: MatchStarted})
+	}
+}
+
+func TestRecentKeepsLastN(t *testing.T) {
+	b := NewBus(3)
+	for _, code := range []string{"a", "b", "c", "d"} {
+		b.Publish(Event{Type: SessionCreated, SessionCode: code})
+	}
+	recent := b.Recent()
+	if len(recent) != 3 || recent[0].SessionCode != "b" || recent[2].SessionCode != "d" {
+		t.Fatalf("expected [b c d], got %+v", recent)
+	}
+}
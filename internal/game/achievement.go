@@ -0,0 +1,18 @@
+package game
+
+// Achievement is a badge a player earns once, for something they did in a
+// match, such as winning without a move wasted.
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Achiever is implemented by games with achievements of their own, on top
+// of those every game shares. IDs need only be unique within the game.
+type Achiever interface {
+	Achievements() []Achievement
+	// Earned returns the IDs of the achievements playerID earned in m, a
+	// finished match. It must not modify m.
+	Earned(m Match, playerID string) []string
+}
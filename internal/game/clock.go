@@ -0,0 +1,124 @@
+package game
+
+import (
+	"maps"
+	"time"
+)
+
+// Clock is a chess clock with a Fischer increment: every player's bank
+// starts at Base and gains Increment with each move they make. A match
+// keeps its clock in its state and presses it from ApplyActionAt with the
+// time the move counts as made, so banks are saved and replayed along
+// with the match and the match never reads the time itself.
+//
+// Nobody's time runs until the first move, as on most chess sites, so the
+// first player is not charged for the wait while everyone arrives.
+type Clock struct {
+	Base      time.Duration            `json:"base"`
+	Increment time.Duration            `json:"increment"`
+	Banks     map[string]time.Duration `json:"banks"` // as of Since for the running player
+	// Running is the player whose time is counting down, since Since.
+	Running string    `json:"running,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+	// Flagged is the player whose time ran out, which ends the match.
+	Flagged string `json:"flagged,omitempty"`
+}
+
+// NewClock returns a clock giving each of players base time and increment
+// per move.
+func NewClock(players []string, base, increment time.Duration) *Clock {
+	banks := make(map[string]time.Duration, len(players))
+	for _, id := range players {
+		banks[id] = base
+	}
+	return &Clock{Base: base, Increment: increment, Banks: banks}
+}
+
+// Remaining returns playerID's time left at the given time.
+func (c *Clock) Remaining(playerID string, at time.Time) time.Duration {
+	left := c.Banks[playerID]
+	if playerID == c.Running && at.After(c.Since) {
+		left -= at.Sub(c.Since)
+	}
+	return max(left, 0)
+}
+
+// Press charges playerID for a move made at the given time, adds the
+// increment and starts next's time; next is "" when the move ends the
+// match. A move made with no time left should be refused instead, and
+// left to FlagFall.
+func (c *Clock) Press(playerID, next string, at time.Time) {
+	c.Banks[playerID] = c.Remaining(playerID, at) + c.Increment
+	c.Running, c.Since = next, at
+	if next == "" {
+		c.Since = time.Time{}
+	}
+}
+
+// Deadline returns the player whose time is running and when it runs out,
+// or false if nobody's is.
+func (c *Clock) Deadline() (string, time.Time, bool) {
+	if c.Running == "" {
+		return "", time.Time{}, false
+	}
+	return c.Running, c.Since.Add(c.Banks[c.Running]), true
+}
+
+// FlagFall flags the running player if their time is gone at the given
+// time, reporting whether it did.
+func (c *Clock) FlagFall(at time.Time) bool {
+	if c.Running == "" || c.Remaining(c.Running, at) > 0 {
+		return false
+	}
+	c.Banks[c.Running] = 0
+	c.Flagged = c.Running
+	c.Running, c.Since = "", time.Time{}
+	return true
+}
+
+// Clone returns an independent copy of the clock.
+func (c *Clock) Clone() *Clock {
+	d := *c
+	d.Banks = maps.Clone(c.Banks)
+	return &d
+}
+
+// ClockView is a clock as a state shows it. Clients count the running
+// player's time down from Since by the server's clock.
+type ClockView struct {
+	Banks     map[string]int64 `json:"banks"` // milliseconds left, as of since for the running player
+	Increment int64            `json:"increment"`
+	Running   string           `json:"running,omitempty"`
+	Since     int64            `json:"since,omitempty"` // Unix milliseconds
+	Flagged   string           `json:"flagged,omitempty"`
+}
+
+// View returns the clock as a state shows it.
+func (c *Clock) View() *ClockView {
+	v := &ClockView{
+		Banks:     make(map[string]int64, len(c.Banks)),
+		Increment: c.Increment.Milliseconds(),
+		Running:   c.Running,
+		Flagged:   c.Flagged,
+	}
+	for id, left := range c.Banks {
+		v.Banks[id] = left.Milliseconds()
+	}
+	if !c.Since.IsZero() {
+		v.Since = c.Since.UnixMilli()
+	}
+	return v
+}
+
+// TimeoutResults ranks a match lost on time: players is the match's
+// players, and flagged lost to all the others, who share first place.
+func TimeoutResults(players []string, flagged string) []PlayerResult {
+	results := make([]PlayerResult, len(players))
+	for i, id := range players {
+		results[i] = PlayerResult{PlayerID: id, Rank: 1, Score: 1, Outcome: OutcomeWin}
+		if id == flagged {
+			results[i] = PlayerResult{PlayerID: id, Rank: 2, Outcome: OutcomeTimeout}
+		}
+	}
+	return results
+}
@@ -0,0 +1,29 @@
+package game
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Commitment proves a match's seed was fixed before play. The server
+// publishes Hash when the match starts and fills in Seed and Salt once it
+// is over; anyone can then check that the hash matches, so the seed, and
+// every shuffle and roll drawn from it, was not changed mid-game.
+type Commitment struct {
+	Hash string `json:"hash"`
+	Seed int64  `json:"seed,omitempty"`
+	Salt string `json:"salt,omitempty"`
+}
+
+// Commit hashes a seed with a secret salt: the hex SHA-256 of
+// "<salt>:<seed>". The salt keeps the seed from being guessed from the hash.
+func Commit(seed int64, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + strconv.FormatInt(seed, 10)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify reports whether a revealed seed and salt match the hash.
+func (c Commitment) Verify() bool {
+	return c.Salt != "" && Commit(c.Seed, c.Salt) == c.Hash
+}
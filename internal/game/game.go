@@ -1,17 +1,56 @@
 package game
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
 
 // GameInfo describes a game type for the lobby.
 type GameInfo struct {
 	Name       string `json:"name"`
 	MinPlayers int    `json:"minPlayers"`
 	MaxPlayers int    `json:"maxPlayers"`
+	// Version names the game's rules. A game changes it whenever a change
+	// to the rules would play a stored match differently; games whose
+	// rules never change may leave it empty.
+	Version string `json:"version,omitempty"`
+	// Options are the settings a session of this game may choose.
+	Options []Option `json:"options,omitempty"`
+	// Handicaps are settings the host may give each player on their own,
+	// with the default being an even start.
+	Handicaps []Option `json:"handicaps,omitempty"`
+	// Variants are named ways to play the game, chosen with
+	// VariantOption.
+	Variants []Variant `json:"variants,omitempty"`
+}
+
+// Option is an integer setting chosen when a session is created, such as
+// a board size or a time limit in seconds. Flags use the range 0 to 1.
+type Option struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     int    `json:"default"`
+	Min         int    `json:"min"`
+	Max         int    `json:"max"`
 }
 
 // MatchConfig holds settings for creating a new match.
 type MatchConfig struct {
 	PlayerIDs []string
+	// Options holds a value for every option in the game's info.
+	Options map[string]int
+	// Handicaps holds the values of the game's handicaps for players
+	// given any; players left out start even.
+	Handicaps Handicaps
+	// Seed is the only source of randomness a match may use. Games keep
+	// their generator's state in the match (a math/rand/v2 PCG marshals),
+	// so restores and replays of a transcript repeat every draw.
+	Seed int64
+	// Setup is the starting position the host set up, written as the
+	// game documents it, or nil for the usual start. NewMatch only sees a
+	// setup the game's ValidateSetup accepted.
+	Setup json.RawMessage
 }
 
 // Action represents a move a player can make.
@@ -20,11 +59,52 @@ type Action struct {
 	Payload json.RawMessage `json:"payload"`
 }
 
+// Outcome says how a match ended for one player.
+type Outcome string
+
+const (
+	OutcomeWin       Outcome = "win"
+	OutcomeLoss      Outcome = "loss"
+	OutcomeDraw      Outcome = "draw"
+	OutcomeForfeit   Outcome = "forfeit"   // the player resigned or was removed
+	OutcomeTimeout   Outcome = "timeout"   // the player ran out of time
+	OutcomeAbandoned Outcome = "abandoned" // every player left before the end
+)
+
 // PlayerResult holds the outcome for one player.
 type PlayerResult struct {
-	PlayerID string `json:"playerId"`
-	Rank     int    `json:"rank"`   // 1 = first place
-	Score    int    `json:"score"`
+	PlayerID string  `json:"playerId"`
+	Rank     int     `json:"rank"` // 1 = first place
+	Score    int     `json:"score"`
+	Outcome  Outcome `json:"outcome,omitempty"`
+	// Detail is optional game-specific data about the result, such as the
+	// line that won a game of tic-tac-toe.
+	Detail json.RawMessage `json:"detail,omitempty"`
+}
+
+// Results returns m's results with every outcome filled in. Where a game
+// leaves Outcome empty, a first place shared with another player is a
+// draw, any other first place a win, and the rest losses.
+func Results(m Match) []PlayerResult {
+	results := m.Results()
+	firsts := 0
+	for _, r := range results {
+		if r.Rank == 1 {
+			firsts++
+		}
+	}
+	for i, r := range results {
+		switch {
+		case r.Outcome != "":
+		case r.Rank != 1:
+			results[i].Outcome = OutcomeLoss
+		case firsts > 1:
+			results[i].Outcome = OutcomeDraw
+		default:
+			results[i].Outcome = OutcomeWin
+		}
+	}
+	return results
 }
 
 // Game describes a game type (chess, poker, etc.)
@@ -35,12 +115,204 @@ type Game interface {
 
 // Match is one in-progress game session.
 type Match interface {
+	// State is the match as playerID sees it, or as a spectator does for
+	// "". It is marshaled after the session lets go of the match, so it
+	// must not share memory that later actions change.
 	State(playerID string) any
 	ValidActions(playerID string) []Action
 	ApplyAction(playerID string, action Action) error
 	IsOver() bool
 	Results() []PlayerResult
+	// Clone returns an independent deep copy of the match.
+	Clone() Match
 	// MarshalJSON / UnmarshalJSON support for persistence
 	MarshalJSON() ([]byte, error)
 	UnmarshalJSON(data []byte) error
 }
+
+// Event describes something an action did, such as a capture or a dice
+// roll, so frontends can animate it instead of diffing two states. Events
+// are public; secrets go in a Message.
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data,omitempty"`
+}
+
+// EventApplier is implemented by matches that report the events an action
+// caused. ApplyActionWithEvents must behave exactly like ApplyAction.
+type EventApplier interface {
+	ApplyActionWithEvents(playerID string, action Action) ([]Event, error)
+}
+
+// Apply applies an action to m, returning its events when m reports them.
+func Apply(m Match, playerID string, action Action) ([]Event, error) {
+	if ea, ok := m.(EventApplier); ok {
+		return ea.ApplyActionWithEvents(playerID, action)
+	}
+	return nil, m.ApplyAction(playerID, action)
+}
+
+// Describer is implemented by matches that can say in words what an
+// action did, such as "alice placed X in the center", for screen readers
+// and voice clients that cannot see the board. Describe is called just
+// after the action is applied, with the events it caused. Like the
+// events, the description goes to every watcher, so it must not reveal
+// what only the mover could see.
+type Describer interface {
+	Describe(playerID string, action Action, events []Event) string
+}
+
+// Describe returns what m says an action did, or "" when m is not a
+// Describer.
+func Describe(m Match, playerID string, action Action, events []Event) string {
+	if d, ok := m.(Describer); ok {
+		return d.Describe(playerID, action, events)
+	}
+	return ""
+}
+
+// TimedMatch is implemented by matches that judge actions by when they
+// were made, such as one with a chess clock. A match must not read the
+// clock itself, or replays of its history would differ.
+type TimedMatch interface {
+	// ApplyActionAt behaves like ApplyAction, with the time the action
+	// counts as made. It returns the events the action caused, if the
+	// match reports any.
+	ApplyActionAt(playerID string, action Action, at time.Time) ([]Event, error)
+}
+
+// ApplyAt applies an action made at the given time to m, passing the time
+// on when m is a TimedMatch.
+func ApplyAt(m Match, playerID string, action Action, at time.Time) ([]Event, error) {
+	if tm, ok := m.(TimedMatch); ok {
+		return tm.ApplyActionAt(playerID, action, at)
+	}
+	return Apply(m, playerID, action)
+}
+
+// LagCompensator is implemented by games played against strict clocks
+// that forgive players some network delay. An action counts as made when
+// the client says it sent it, but no earlier than LagWindow before the
+// server received it.
+type LagCompensator interface {
+	LagWindow() time.Duration
+}
+
+// ClockedMatch is implemented by matches played on a Clock, whose time can
+// run out while nobody moves. The server watches the deadline and calls
+// FlagFall once it passes, so the match ends without waiting for a move.
+type ClockedMatch interface {
+	TimedMatch
+	// ClockDeadline returns when the running player's time runs out, or
+	// false if nobody's is running.
+	ClockDeadline() (time.Time, bool)
+	// FlagFall ends the match on time if the running player's time is
+	// gone at the given time, reporting whether it did.
+	FlagFall(at time.Time) bool
+}
+
+// Thumbnailer is implemented by matches with a compact view of
+// themselves, such as the mini-boards of a lobby. Like the spectator's
+// State it must show nothing hidden, and it should be much smaller.
+type Thumbnailer interface {
+	Thumbnail() any
+}
+
+// Thumbnail returns m's compact view, or the spectator's State when m does
+// not implement Thumbnailer.
+func Thumbnail(m Match) any {
+	if t, ok := m.(Thumbnailer); ok {
+		return t.Thumbnail()
+	}
+	return m.State("")
+}
+
+// Message is an event meant for one player only, such as the card they
+// drew, kept out of the state other players can see.
+type Message struct {
+	PlayerID string `json:"-"`
+	Payload  any    `json:"payload"`
+}
+
+// Messenger is implemented by matches that send players private messages.
+// The server delivers them after the match starts and after every action.
+type Messenger interface {
+	// TakeMessages returns the messages queued since the last call and
+	// clears the queue.
+	TakeMessages() []Message
+}
+
+// TurnSkipper is implemented by matches that can pass over a player's
+// turn, so the others need not wait on someone who stopped responding.
+type TurnSkipper interface {
+	// SkipTurn moves play on as if playerID had taken their turn.
+	SkipTurn(playerID string) error
+}
+
+// PlayerRemover is implemented by matches that can carry on without one of
+// their players. A removed player has no valid actions from then on.
+type PlayerRemover interface {
+	RemovePlayer(playerID string) error
+}
+
+// CheatChecker is implemented by matches that can spot play no honest
+// client would produce, such as a reply faster than a person could read
+// the board or an action the interface never offers. Flagged actions are
+// still applied; the game refuses illegal ones in ApplyAction as usual.
+type CheatChecker interface {
+	// CheckAction is called before action is applied, with how long the
+	// match had been unchanged. It returns why the action looks
+	// impossible, or "" if it does not.
+	CheckAction(playerID string, action Action, elapsed time.Duration) string
+}
+
+// PreviousMatch is the match played before the next one in a session, in
+// a rematch or a party's next round.
+type PreviousMatch struct {
+	PlayerIDs []string       `json:"playerIds"` // in that match's turn order
+	Results   []PlayerResult `json:"results"`
+}
+
+// TurnOrderer is implemented by games that decide who moves first, such
+// as one that randomizes colors or lets the last game's loser start.
+// Without it the first seat moves first.
+type TurnOrderer interface {
+	// TurnOrder returns players reordered into turn order. players are
+	// in seat order; previous is nil for a session's first match. Any
+	// randomness must come from seed.
+	TurnOrder(players []string, previous *PreviousMatch, seed int64) []string
+}
+
+// TurnOrder asks g for the turn order of a new match, keeping players'
+// seat order when g does not implement TurnOrderer or returns anything
+// other than a reordering of players.
+func TurnOrder(g Game, players []string, previous *PreviousMatch, seed int64) []string {
+	to, ok := g.(TurnOrderer)
+	if !ok {
+		return players
+	}
+	order := to.TurnOrder(slices.Clone(players), previous, seed)
+	if len(order) != len(players) {
+		return players
+	}
+	sorted, want := slices.Sorted(slices.Values(order)), slices.Sorted(slices.Values(players))
+	if !slices.Equal(sorted, want) {
+		return players
+	}
+	return order
+}
+
+// StrategyInfo describes a bot strategy for the lobby.
+type StrategyInfo struct {
+	Name        string `json:"name"`
+	Difficulty  int    `json:"difficulty"` // 1 = easiest
+	Description string `json:"description"`
+}
+
+// Strategy chooses moves for a bot player.
+type Strategy interface {
+	Info() StrategyInfo
+	// ChooseAction picks one of m.ValidActions(playerID). It must not
+	// modify m.
+	ChooseAction(m Match, playerID string) (Action, error)
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+// resultsMatch is a finished match with fixed results.
+type resultsMatch struct {
+	stubMatch
+	results []PlayerResult
+}
+
+func (m *resultsMatch) Results() []PlayerResult { return m.results }
+
+func TestResultsFillsOutcomes(t *testing.T) {
+	m := &resultsMatch{results: []PlayerResult{
+		{PlayerID: "alice", Rank: 1},
+		{PlayerID: "bob", Rank: 1},
+		{PlayerID: "carol", Rank: 3},
+		{PlayerID: "dave", Rank: 4, Outcome: OutcomeForfeit},
+	}}
+	want := []Outcome{OutcomeDraw, OutcomeDraw, OutcomeLoss, OutcomeForfeit}
+	for i, r := range Results(m) {
+		if r.Outcome != want[i] {
+			t.Fatalf("%s: expected %s, got %s", r.PlayerID, want[i], r.Outcome)
+		}
+	}
+
+	m.results = []PlayerResult{{PlayerID: "alice", Rank: 1}, {PlayerID: "bob", Rank: 2}}
+	if r := Results(m); r[0].Outcome != OutcomeWin || r[1].Outcome != OutcomeLoss {
+		t.Fatalf("expected a win and a loss, got %+v", r)
+	}
+}
+
+// loserFirstGame lets the last match's loser start, and would also try
+// to seat a stranger when asked to.
+type loserFirstGame struct {
+	stubGame
+	stranger bool
+}
+
+func (g loserFirstGame) TurnOrder(players []string, previous *PreviousMatch, _ int64) []string {
+	if g.stranger {
+		return []string{"mallory", players[1]}
+	}
+	if previous == nil {
+		return players
+	}
+	for _, r := range previous.Results {
+		if r.Outcome == OutcomeLoss {
+			i := slices.Index(players, r.PlayerID)
+			return append([]string{r.PlayerID}, slices.Delete(players, i, i+1)...)
+		}
+	}
+	return players
+}
+
+func TestTurnOrder(t *testing.T) {
+	players := []string{"alice", "bob", "carol"}
+	previous := &PreviousMatch{
+		PlayerIDs: players,
+		Results:   []PlayerResult{{PlayerID: "carol", Rank: 1, Outcome: OutcomeWin}, {PlayerID: "bob", Rank: 2, Outcome: OutcomeLoss}},
+	}
+	if got := TurnOrder(stubGame{}, players, previous, 1); !slices.Equal(got, players) {
+		t.Fatalf("expected seat order without a hook, got %v", got)
+	}
+	if got := TurnOrder(loserFirstGame{}, players, nil, 1); !slices.Equal(got, players) {
+		t.Fatalf("expected seat order for a first match, got %v", got)
+	}
+	if got := TurnOrder(loserFirstGame{}, players, previous, 1); !slices.Equal(got, []string{"bob", "alice", "carol"}) {
+		t.Fatalf("expected the loser to start, got %v", got)
+	}
+	if !slices.Equal(players, []string{"alice", "bob", "carol"}) {
+		t.Fatalf("expected the players to be left alone, got %v", players)
+	}
+	if got := TurnOrder(loserFirstGame{stranger: true}, players, nil, 1); !slices.Equal(got, players) {
+		t.Fatalf("expected an invalid order to be ignored, got %v", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Package gametest holds checks every game implementation should pass.
+package gametest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"games/internal/game"
+)
+
+// FuzzApplyAction fuzzes g's ApplyAction. Each input plays a match into
+// some position with valid actions it picks, then sends an arbitrary
+// action from an arbitrary player. The target fails if the match panics,
+// rejects one of its own valid actions, changes when an action is
+// rejected, or produces a state that does not marshal.
+//
+// Call it from a game package's Fuzz function:
+//
+//	func FuzzApplyAction(f *testing.F) {
+//		gametest.FuzzApplyAction(f, MyGame{}, []string{"alice", "bob"})
+//	}
+func FuzzApplyAction(f *testing.F, g game.Game, players []string) {
+	options, err := game.ResolveOptions(g.Info().Options, nil)
+	if err != nil {
+		f.Fatalf("default options: %v", err)
+	}
+	newMatch := func() game.Match {
+		return g.NewMatch(game.MatchConfig{PlayerIDs: players, Options: options, Seed: 1})
+	}
+
+	m := newMatch()
+	for i, p := range players {
+		for _, a := range m.ValidActions(p) {
+			f.Add([]byte{}, uint8(i), a.Type, []byte(a.Payload))
+		}
+	}
+	f.Add([]byte{0, 1, 2}, uint8(0), "", []byte(`null`))
+
+	f.Fuzz(func(t *testing.T, setup []byte, seat uint8, actionType string, payload []byte) {
+		m := newMatch()
+		for _, pick := range setup {
+			if m.IsOver() {
+				break
+			}
+			player, actions := "", []game.Action(nil)
+			for _, p := range players {
+				if actions = m.ValidActions(p); len(actions) > 0 {
+					player = p
+					break
+				}
+			}
+			if player == "" {
+				break
+			}
+			a := actions[int(pick)%len(actions)]
+			if _, err := game.Apply(m, player, a); err != nil {
+				t.Fatalf("valid action %s %s rejected: %v", a.Type, a.Payload, err)
+			}
+		}
+
+		before, err := m.MarshalJSON()
+		if err != nil {
+			t.Fatalf("marshal match: %v", err)
+		}
+		player := players[int(seat)%len(players)]
+		if _, err := game.Apply(m, player, game.Action{Type: actionType, Payload: payload}); err != nil {
+			after, _ := m.MarshalJSON()
+			if !bytes.Equal(before, after) {
+				t.Fatalf("rejected action changed the match: %v", err)
+			}
+		}
+
+		for _, p := range append([]string{""}, players...) {
+			if _, err := json.Marshal(m.State(p)); err != nil {
+				t.Fatalf("marshal state for %q: %v", p, err)
+			}
+			m.ValidActions(p)
+		}
+		if m.IsOver() {
+			game.Results(m)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"fmt"
+	"maps"
+)
+
+// Handicaps give some players an uneven start, such as extra pieces, a
+// head start or a score multiplier: option values by player ID, then by
+// the name of a handicap in the game's info. A player with none is left
+// out, and an even match has no handicaps at all.
+type Handicaps map[string]map[string]int
+
+// HandicapChecker is implemented by games whose handicaps must agree
+// across players, such as one where only a single player may take a
+// given square. ResolveHandicaps has already checked each player's values
+// against the specs.
+type HandicapChecker interface {
+	CheckHandicaps(h Handicaps) error
+}
+
+// ResolveHandicaps checks requested handicaps against g's specs, fills in
+// defaults for handicaps a player was not given and asks g to check them
+// as a whole. Players whose values are all defaults, the even start, are
+// dropped; it returns nil if nobody is left.
+func ResolveHandicaps(g Game, requested Handicaps) (Handicaps, error) {
+	specs := g.Info().Handicaps
+	var resolved Handicaps
+	for playerID, values := range requested {
+		values, err := ResolveOptions(specs, values)
+		if err != nil {
+			return nil, fmt.Errorf("handicap of %s: %w", playerID, err)
+		}
+		even := true
+		for _, o := range specs {
+			even = even && values[o.Name] == o.Default
+		}
+		if even {
+			continue
+		}
+		if resolved == nil {
+			resolved = make(Handicaps)
+		}
+		resolved[playerID] = values
+	}
+	if hc, ok := g.(HandicapChecker); ok && resolved != nil {
+		if err := hc.CheckHandicaps(resolved); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// Only returns the handicaps of the given players, or nil if none of them
+// has one.
+func (h Handicaps) Only(players []string) Handicaps {
+	var only Handicaps
+	for _, id := range players {
+		if values, ok := h[id]; ok {
+			if only == nil {
+				only = make(Handicaps)
+			}
+			only[id] = maps.Clone(values)
+		}
+	}
+	return only
+}
@@ -0,0 +1,42 @@
+package game
+
+import "fmt"
+
+// Limits caps how large a game's serialized state may grow, so a buggy
+// game cannot fill the database or every player's connection. Zero leaves
+// a size unlimited.
+type Limits struct {
+	// MaxStateBytes caps the marshaled match state, as stored.
+	MaxStateBytes int `json:"maxStateBytes"`
+	// MaxBroadcastBytes caps one state message sent to a player or
+	// spectator.
+	MaxBroadcastBytes int `json:"maxBroadcastBytes"`
+}
+
+// DefaultLimits are the limits of games that do not set their own.
+var DefaultLimits = Limits{MaxStateBytes: 1 << 20, MaxBroadcastBytes: 256 << 10}
+
+// Limiter is implemented by games whose state legitimately needs different
+// limits than the defaults.
+type Limiter interface {
+	Limits() Limits
+}
+
+// SizeError reports serialized state over its limit.
+type SizeError struct {
+	What  string // "match state", "state message", ...
+	Size  int
+	Limit int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("%s is %d bytes, over the limit of %d", e.What, e.Size, e.Limit)
+}
+
+// CheckSize returns a *SizeError if size is over limit.
+func CheckSize(what string, size, limit int) error {
+	if limit > 0 && size > limit {
+		return &SizeError{What: what, Size: size, Limit: limit}
+	}
+	return nil
+}
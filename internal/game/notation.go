@@ -0,0 +1,26 @@
+package game
+
+import "errors"
+
+// ErrNoNotation is returned by ParseMove for matches that do not read
+// moves written as text.
+var ErrNoNotation = errors.New("this game has no move notation")
+
+// Notator is implemented by matches whose moves can be written as text,
+// such as "b2" for the centre of a tic-tac-toe board, so a terminal or a
+// chat bot can play without building actions.
+type Notator interface {
+	// ParseMove returns the action text stands for when playerID makes
+	// it. Whether the action is allowed now is left to ApplyAction.
+	ParseMove(playerID, text string) (Action, error)
+}
+
+// ParseMove reads a move written as text, returning ErrNoNotation when m
+// is not a Notator.
+func ParseMove(m Match, playerID, text string) (Action, error) {
+	n, ok := m.(Notator)
+	if !ok {
+		return Action{}, ErrNoNotation
+	}
+	return n.ParseMove(playerID, text)
+}
@@ -0,0 +1,45 @@
+package game
+
+import "fmt"
+
+// ResolveOptions checks requested option values against specs and fills in
+// defaults for any that were not given. Unknown names and out-of-range
+// values are errors.
+func ResolveOptions(specs []Option, requested map[string]int) (map[string]int, error) {
+	known := make(map[string]Option, len(specs))
+	for _, o := range specs {
+		known[o.Name] = o
+	}
+	for name := range requested {
+		if _, ok := known[name]; !ok {
+			return nil, fmt.Errorf("unknown option %q", name)
+		}
+	}
+	resolved := make(map[string]int, len(specs))
+	for _, o := range specs {
+		v, ok := requested[o.Name]
+		if !ok {
+			v = o.Default
+		}
+		if v < o.Min || v > o.Max {
+			return nil, fmt.Errorf("option %q must be between %d and %d", o.Name, o.Min, o.Max)
+		}
+		resolved[o.Name] = v
+	}
+	return resolved, nil
+}
+
+// narrow applies an operator override to a game's own option spec. The
+// override may only tighten the range the game supports.
+func narrow(spec, override Option) (Option, error) {
+	if override.Min < spec.Min || override.Max > spec.Max || override.Min > override.Max {
+		return Option{}, fmt.Errorf("option %q: range %d..%d is outside %d..%d", spec.Name, override.Min, override.Max, spec.Min, spec.Max)
+	}
+	if override.Default < override.Min || override.Default > override.Max {
+		return Option{}, fmt.Errorf("option %q: default %d is outside %d..%d", spec.Name, override.Default, override.Min, override.Max)
+	}
+	if override.Description == "" {
+		override.Description = spec.Description
+	}
+	return override, nil
+}
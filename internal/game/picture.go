@@ -0,0 +1,33 @@
+package game
+
+// Shape is how a mark in a Picture is drawn.
+type Shape int
+
+const (
+	Empty Shape = iota
+	Cross
+	Ring
+	Disc
+)
+
+// Mark is what a cell of a Picture holds: a shape in the colour of the
+// player, by seat, whose it is. The zero Mark is an empty cell.
+type Mark struct {
+	Shape  Shape
+	Player int
+}
+
+// Picture is a match drawn as a grid of cells, which the server renders
+// as the images of lobby thumbnails, link previews and match histories.
+// Cells run row by row from the top, Cols*Rows of them.
+type Picture struct {
+	Cols, Rows int
+	Cells      []Mark
+}
+
+// Renderer is implemented by matches that can be drawn as a Picture, such
+// as board games. Like the spectator's State, a picture must show nothing
+// hidden.
+type Renderer interface {
+	Picture() Picture
+}
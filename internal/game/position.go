@@ -0,0 +1,49 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNoPositionSetup is returned by SetPosition and ValidateSetup for
+// games that cannot be set up in an arbitrary position.
+var ErrNoPositionSetup = errors.New("this game has no position setup")
+
+// PositionSetter is implemented by matches that can be set up in a chosen
+// position, such as a puzzle to practise from. The position is written in
+// whatever form the game documents.
+type PositionSetter interface {
+	// SetPosition replaces the position of the match, keeping its players
+	// and options, and refuses positions the rules could never reach or
+	// that leave nothing to play.
+	SetPosition(position json.RawMessage) error
+}
+
+// SetPosition sets m up in the given position, returning ErrNoPositionSetup
+// when m is not a PositionSetter.
+func SetPosition(m Match, position json.RawMessage) error {
+	ps, ok := m.(PositionSetter)
+	if !ok {
+		return ErrNoPositionSetup
+	}
+	return ps.SetPosition(position)
+}
+
+// SetupValidator is implemented by games whose host may edit the starting
+// position before a match starts. NewMatch starts from a setup in
+// MatchConfig, applied after any handicaps.
+type SetupValidator interface {
+	// ValidateSetup refuses a setup that is malformed or that the match
+	// could not start from.
+	ValidateSetup(setup json.RawMessage) error
+}
+
+// ValidateSetup checks a setup for g, returning ErrNoPositionSetup when g
+// is not a SetupValidator.
+func ValidateSetup(g Game, setup json.RawMessage) error {
+	sv, ok := g.(SetupValidator)
+	if !ok {
+		return ErrNoPositionSetup
+	}
+	return sv.ValidateSetup(setup)
+}
@@ -2,18 +2,35 @@ package game
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
 )
 
-// Registry holds all registered game types.
+// Registry holds all registered game types and their bot strategies.
 type Registry struct {
-	mu    sync.RWMutex
-	games map[string]Game
+	mu         sync.RWMutex
+	games      map[string]Game
+	strategies map[string]map[string]Strategy // game name -> strategy name
+	options    map[string][]Option            // operator overrides by game name
+	limits     map[string]Limits              // operator overrides by game name
+	defaults   Limits                         // for games without limits of their own
+	disabled   map[string]bool                // games turned off by an operator
+	previous   map[string]map[string]Game     // game name -> rules version
 }
 
 // NewRegistry creates an empty registry.
 func NewRegistry() *Registry {
-	return &Registry{games: make(map[string]Game)}
+	return &Registry{
+		games:      make(map[string]Game),
+		strategies: make(map[string]map[string]Strategy),
+		options:    make(map[string][]Option),
+		limits:     make(map[string]Limits),
+		defaults:   DefaultLimits,
+		disabled:   make(map[string]bool),
+		previous:   make(map[string]map[string]Game),
+	}
 }
 
 // Register adds a game type. Panics on duplicate names.
@@ -27,6 +44,80 @@ func (r *Registry) Register(g Game) {
 	r.games[name] = g
 }
 
+// RegisterPrevious keeps an earlier version of a registered game's rules,
+// so matches started under it and restored after an upgrade play on by
+// those rules. Panics if the game is unknown or the version is the
+// current one or already kept.
+func (r *Registry) RegisterPrevious(g Game) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := g.Info()
+	current, ok := r.games[info.Name]
+	if !ok {
+		panic(fmt.Sprintf("game %q not registered", info.Name))
+	}
+	if info.Version == current.Info().Version {
+		panic(fmt.Sprintf("game %q rules %q are the current ones", info.Name, info.Version))
+	}
+	if _, exists := r.previous[info.Name][info.Version]; exists {
+		panic(fmt.Sprintf("game %q rules %q already registered", info.Name, info.Version))
+	}
+	if r.previous[info.Name] == nil {
+		r.previous[info.Name] = make(map[string]Game)
+	}
+	r.previous[info.Name][info.Version] = g
+}
+
+// Rules returns the game that plays by the named version of its rules:
+// the current one, or an earlier one kept with RegisterPrevious.
+func (r *Registry) Rules(name, version string) (Game, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.games[name]
+	if !ok {
+		return nil, false
+	}
+	if g.Info().Version == version {
+		return g, true
+	}
+	g, ok = r.previous[name][version]
+	return g, ok
+}
+
+// Replace swaps in a new version of a registered game, keeping its
+// strategies, option overrides, limits and whether it is enabled, and
+// returns the version it replaced. Sessions that already hold the old
+// version keep it.
+func (r *Registry) Replace(g Game) (Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := g.Info().Name
+	old, ok := r.games[name]
+	if !ok {
+		return nil, fmt.Errorf("game %q not registered", name)
+	}
+	r.games[name] = g
+	return old, nil
+}
+
+// Unregister removes a game with its strategies and operator settings and
+// returns it, so it can be shut down once no match needs it.
+func (r *Registry) Unregister(name string) (Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.games[name]
+	if !ok {
+		return nil, fmt.Errorf("game %q not registered", name)
+	}
+	delete(r.games, name)
+	delete(r.strategies, name)
+	delete(r.options, name)
+	delete(r.limits, name)
+	delete(r.disabled, name)
+	delete(r.previous, name)
+	return g, nil
+}
+
 // Get returns a game by name.
 func (r *Registry) Get(name string) (Game, bool) {
 	r.mu.RLock()
@@ -35,13 +126,214 @@ func (r *Registry) Get(name string) (Game, bool) {
 	return g, ok
 }
 
-// List returns info for all registered games.
+// List returns info for all enabled games, with operator-configured
+// options in place of the games' own.
 func (r *Registry) List() []GameInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	infos := make([]GameInfo, 0, len(r.games))
-	for _, g := range r.games {
-		infos = append(infos, g.Info())
+	for name, g := range r.games {
+		if r.disabled[name] {
+			continue
+		}
+		info := g.Info()
+		info.Options = r.optionsLocked(name, optionSpecs(info))
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Names returns the names of all registered games, disabled ones
+// included, in order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.games))
+}
+
+// SetEnabled turns a game on or off without a restart, such as while a
+// bug in it is being exploited. A disabled game is left out of List and
+// no new sessions of it may start, but matches under way play on.
+func (r *Registry) SetEnabled(gameName string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.games[gameName]; !ok {
+		return fmt.Errorf("game %q not registered", gameName)
+	}
+	if enabled {
+		delete(r.disabled, gameName)
+	} else {
+		r.disabled[gameName] = true
+	}
+	return nil
+}
+
+// Enabled reports whether a game is registered and not disabled.
+func (r *Registry) Enabled(gameName string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.games[gameName]
+	return ok && !r.disabled[gameName]
+}
+
+// Configure replaces the defaults and ranges of a game's options. Each
+// override must name an option the game declares and stay within the range
+// the game supports; options not mentioned keep the game's settings.
+func (r *Registry) Configure(gameName string, overrides []Option) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.games[gameName]
+	if !ok {
+		return fmt.Errorf("game %q not registered", gameName)
+	}
+	specs := optionSpecs(g.Info())
+	configured := make([]Option, 0, len(overrides))
+	for _, o := range overrides {
+		i := slices.IndexFunc(specs, func(s Option) bool { return s.Name == o.Name })
+		if i < 0 {
+			return fmt.Errorf("game %q has no option %q", gameName, o.Name)
+		}
+		n, err := narrow(specs[i], o)
+		if err != nil {
+			return err
+		}
+		configured = append(configured, n)
+	}
+	r.options[gameName] = configured
+	return nil
+}
+
+// Options returns the effective option specs for a game.
+func (r *Registry) Options(gameName string) []Option {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.games[gameName]
+	if !ok {
+		return nil
+	}
+	return r.optionsLocked(gameName, optionSpecs(g.Info()))
+}
+
+func (r *Registry) optionsLocked(gameName string, specs []Option) []Option {
+	effective := slices.Clone(specs)
+	for _, o := range r.options[gameName] {
+		if i := slices.IndexFunc(effective, func(s Option) bool { return s.Name == o.Name }); i >= 0 {
+			effective[i] = o
+		}
+	}
+	return effective
+}
+
+// RegisterStrategy adds a bot strategy for a registered game. Panics if the
+// game is unknown or the strategy name is already taken for that game.
+func (r *Registry) RegisterStrategy(gameName string, s Strategy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.games[gameName]; !ok {
+		panic(fmt.Sprintf("game %q not registered", gameName))
+	}
+	name := s.Info().Name
+	if r.strategies[gameName] == nil {
+		r.strategies[gameName] = make(map[string]Strategy)
+	}
+	if _, exists := r.strategies[gameName][name]; exists {
+		panic(fmt.Sprintf("strategy %q already registered for game %q", name, gameName))
+	}
+	r.strategies[gameName][name] = s
+}
+
+// Strategy returns a game's bot strategy by name.
+func (r *Registry) Strategy(gameName, name string) (Strategy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.strategies[gameName][name]
+	return s, ok
+}
+
+// Strategies returns info for a game's bot strategies, easiest first.
+func (r *Registry) Strategies(gameName string) []StrategyInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]StrategyInfo, 0, len(r.strategies[gameName]))
+	for _, s := range r.strategies[gameName] {
+		infos = append(infos, s.Info())
 	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Difficulty != infos[j].Difficulty {
+			return infos[i].Difficulty < infos[j].Difficulty
+		}
+		return infos[i].Name < infos[j].Name
+	})
 	return infos
 }
+
+// SetDefaultLimits changes the limits of games that set none of their own
+// and have no override.
+func (r *Registry) SetDefaultLimits(l Limits) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaults = l
+}
+
+// SetLimits overrides a game's own limits.
+func (r *Registry) SetLimits(gameName string, l Limits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.games[gameName]; !ok {
+		return fmt.Errorf("game %q not registered", gameName)
+	}
+	r.limits[gameName] = l
+	return nil
+}
+
+// Limits returns the effective limits for a game: an override, the game's
+// own, or the defaults.
+func (r *Registry) Limits(gameName string) Limits {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if l, ok := r.limits[gameName]; ok {
+		return l
+	}
+	if l, ok := r.games[gameName].(Limiter); ok {
+		return l.Limits()
+	}
+	return r.defaults
+}
+
+// Subset returns a registry of the named games with their strategies,
+// options and limits, for a tenant offering fewer games than the
+// deployment. No names means every game.
+func (r *Registry) Subset(names ...string) (*Registry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(names) == 0 {
+		for name := range r.games {
+			names = append(names, name)
+		}
+	}
+	sub := NewRegistry()
+	sub.defaults = r.defaults
+	for _, name := range names {
+		g, ok := r.games[name]
+		if !ok {
+			return nil, fmt.Errorf("game %q not registered", name)
+		}
+		sub.games[name] = g
+		if s, ok := r.strategies[name]; ok {
+			sub.strategies[name] = maps.Clone(s)
+		}
+		if o, ok := r.options[name]; ok {
+			sub.options[name] = o
+		}
+		if l, ok := r.limits[name]; ok {
+			sub.limits[name] = l
+		}
+		if r.disabled[name] {
+			sub.disabled[name] = true
+		}
+		if p, ok := r.previous[name]; ok {
+			sub.previous[name] = maps.Clone(p)
+		}
+	}
+	return sub, nil
+}
@@ -2,6 +2,7 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 )
 
@@ -10,10 +11,13 @@ type stubGame struct {
 	name       string
 	minPlayers int
 	maxPlayers int
+	version    string
+	options    []Option
+	variants   []Variant
 }
 
 func (s stubGame) Info() GameInfo {
-	return GameInfo{Name: s.name, MinPlayers: s.minPlayers, MaxPlayers: s.maxPlayers}
+	return GameInfo{Name: s.name, MinPlayers: s.minPlayers, MaxPlayers: s.maxPlayers, Version: s.version, Options: s.options, Variants: s.variants}
 }
 
 func (s stubGame) NewMatch(config MatchConfig) Match {
@@ -23,13 +27,14 @@ func (s stubGame) NewMatch(config MatchConfig) Match {
 // stubMatch is a minimal Match implementation.
 type stubMatch struct{}
 
-func (m *stubMatch) State(playerID string) any              { return nil }
-func (m *stubMatch) ValidActions(playerID string) []Action   { return nil }
-func (m *stubMatch) ApplyAction(string, Action) error        { return nil }
-func (m *stubMatch) IsOver() bool                            { return false }
-func (m *stubMatch) Results() []PlayerResult                 { return nil }
-func (m *stubMatch) MarshalJSON() ([]byte, error)            { return json.Marshal(struct{}{}) }
-func (m *stubMatch) UnmarshalJSON(data []byte) error         { return nil }
+func (m *stubMatch) State(playerID string) any             { return nil }
+func (m *stubMatch) ValidActions(playerID string) []Action { return nil }
+func (m *stubMatch) ApplyAction(string, Action) error      { return nil }
+func (m *stubMatch) IsOver() bool                          { return false }
+func (m *stubMatch) Results() []PlayerResult               { return nil }
+func (m *stubMatch) Clone() Match                          { return &stubMatch{} }
+func (m *stubMatch) MarshalJSON() ([]byte, error)          { return json.Marshal(struct{}{}) }
+func (m *stubMatch) UnmarshalJSON(data []byte) error       { return nil }
 
 func TestRegistryRegisterAndGet(t *testing.T) {
 	r := NewRegistry()
@@ -89,3 +94,344 @@ func TestRegistryDuplicatePanics(t *testing.T) {
 	}()
 	r.Register(g) // should panic
 }
+
+// stubStrategy is a minimal Strategy implementation.
+type stubStrategy struct {
+	name       string
+	difficulty int
+}
+
+func (s stubStrategy) Info() StrategyInfo {
+	return StrategyInfo{Name: s.name, Difficulty: s.difficulty}
+}
+
+func (s stubStrategy) ChooseAction(Match, string) (Action, error) { return Action{}, nil }
+
+func TestRegistryStrategies(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubGame{name: "test", minPlayers: 2, maxPlayers: 2})
+	r.RegisterStrategy("test", stubStrategy{name: "hard", difficulty: 3})
+	r.RegisterStrategy("test", stubStrategy{name: "easy", difficulty: 1})
+
+	infos := r.Strategies("test")
+	if len(infos) != 2 || infos[0].Name != "easy" || infos[1].Name != "hard" {
+		t.Fatalf("expected [easy hard], got %v", infos)
+	}
+	if _, ok := r.Strategy("test", "hard"); !ok {
+		t.Fatal("expected to find registered strategy")
+	}
+	if _, ok := r.Strategy("test", "nonexistent"); ok {
+		t.Fatal("expected not found for unregistered strategy")
+	}
+	if len(r.Strategies("other")) != 0 {
+		t.Fatal("expected no strategies for unknown game")
+	}
+}
+
+func TestRegistryStrategyUnknownGamePanics(t *testing.T) {
+	r := NewRegistry()
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic registering strategy for unknown game")
+		}
+	}()
+	r.RegisterStrategy("missing", stubStrategy{name: "easy"})
+}
+
+func TestRegistryConfigureOptions(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubGame{name: "test", minPlayers: 2, maxPlayers: 2, options: []Option{
+		{Name: "size", Description: "Board size", Default: 3, Min: 3, Max: 9},
+		{Name: "timer", Default: 0, Min: 0, Max: 600},
+	}})
+
+	if err := r.Configure("test", []Option{{Name: "size", Default: 5, Min: 4, Max: 6}}); err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+	opts := r.Options("test")
+	if len(opts) != 2 || opts[0].Default != 5 || opts[0].Min != 4 || opts[0].Max != 6 {
+		t.Fatalf("expected narrowed size option, got %+v", opts)
+	}
+	if opts[0].Description != "Board size" {
+		t.Fatalf("expected description kept from game, got %q", opts[0].Description)
+	}
+	if opts[1].Max != 600 {
+		t.Fatalf("expected unconfigured option unchanged, got %+v", opts[1])
+	}
+	if got := r.List()[0].Options; got[0].Default != 5 {
+		t.Fatalf("expected List to report configured options, got %+v", got)
+	}
+
+	for _, bad := range []Option{
+		{Name: "missing", Default: 1, Min: 0, Max: 1},
+		{Name: "size", Default: 3, Min: 2, Max: 6}, // below game minimum
+		{Name: "size", Default: 8, Min: 4, Max: 6}, // default out of range
+		{Name: "size", Default: 5, Min: 6, Max: 4}, // empty range
+	} {
+		if err := r.Configure("test", []Option{bad}); err == nil {
+			t.Fatalf("expected error configuring %+v", bad)
+		}
+	}
+	if err := r.Configure("other", nil); err == nil {
+		t.Fatal("expected error configuring unknown game")
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	specs := []Option{{Name: "size", Default: 3, Min: 3, Max: 9}}
+
+	got, err := ResolveOptions(specs, nil)
+	if err != nil || got["size"] != 3 {
+		t.Fatalf("expected default size 3, got %v (%v)", got, err)
+	}
+	got, err = ResolveOptions(specs, map[string]int{"size": 7})
+	if err != nil || got["size"] != 7 {
+		t.Fatalf("expected size 7, got %v (%v)", got, err)
+	}
+	if _, err := ResolveOptions(specs, map[string]int{"size": 10}); err == nil {
+		t.Fatal("expected error for out-of-range value")
+	}
+	if _, err := ResolveOptions(specs, map[string]int{"colour": 1}); err == nil {
+		t.Fatal("expected error for unknown option")
+	}
+}
+
+func TestVariants(t *testing.T) {
+	g := stubGame{name: "connect", minPlayers: 2, maxPlayers: 2,
+		options: []Option{{Name: "length", Default: 4, Min: 3, Max: 6}, {Name: "wrap", Default: 0, Min: 0, Max: 1}},
+		variants: []Variant{
+			{Name: "connect-five", Options: map[string]int{"length": 5}},
+			{Name: "cylinder", Options: map[string]int{"wrap": 1}},
+		},
+	}
+	r := NewRegistry()
+	r.Register(g)
+	opts := r.Options("connect")
+	if len(opts) != 3 || opts[2].Name != VariantOption || opts[2].Max != 2 {
+		t.Fatalf("expected a variant option choosing between the variants, got %+v", opts)
+	}
+	if got := r.List()[0]; len(got.Variants) != 2 || len(got.Options) != 3 {
+		t.Fatalf("expected List to show the variants under the game, got %+v", got)
+	}
+
+	n, err := VariantNamed(g.Info(), "cylinder")
+	if err != nil || n != 2 {
+		t.Fatalf("expected cylinder to be variant 2, got %d %v", n, err)
+	}
+	if _, err := VariantNamed(g.Info(), "connect-six"); err == nil {
+		t.Fatal("expected an unknown variant refused")
+	}
+	requested, err := ResolveVariant(g.Info(), map[string]int{VariantOption: 1, "wrap": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := ResolveOptions(opts, requested)
+	if err != nil || resolved["length"] != 5 || resolved["wrap"] != 1 {
+		t.Fatalf("expected connect five on a cylinder, got %v %v", resolved, err)
+	}
+	if v := VariantOf(g.Info(), resolved); v == nil || v.Name != "connect-five" {
+		t.Fatalf("expected the variant named, got %+v", v)
+	}
+	if _, err := ResolveVariant(g.Info(), map[string]int{VariantOption: 1, "length": 4}); err == nil {
+		t.Fatal("expected an option at odds with the variant refused")
+	}
+	if _, err := ResolveVariant(g.Info(), map[string]int{VariantOption: 3}); err == nil {
+		t.Fatal("expected a variant out of range refused")
+	}
+	if v := VariantOf(g.Info(), map[string]int{"length": 5}); v != nil {
+		t.Fatalf("expected the standard rules without a variant, got %+v", v)
+	}
+
+	if err := r.Configure("connect", []Option{{Name: VariantOption, Default: 0, Min: 0, Max: 1}}); err != nil {
+		t.Fatalf("expected operators able to narrow the variants, got %v", err)
+	}
+}
+
+// limitedGame is a stubGame with limits of its own.
+type limitedGame struct{ stubGame }
+
+func (limitedGame) Limits() Limits { return Limits{MaxStateBytes: 64} }
+
+func TestRegistryLimits(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubGame{name: "plain", minPlayers: 2, maxPlayers: 2})
+	r.Register(limitedGame{stubGame{name: "limited", minPlayers: 2, maxPlayers: 2}})
+
+	if got := r.Limits("plain"); got != DefaultLimits {
+		t.Fatalf("expected the default limits, got %+v", got)
+	}
+	if got := r.Limits("limited"); got.MaxStateBytes != 64 || got.MaxBroadcastBytes != 0 {
+		t.Fatalf("expected the game's own limits, got %+v", got)
+	}
+	r.SetDefaultLimits(Limits{MaxStateBytes: 10})
+	if got := r.Limits("plain"); got.MaxStateBytes != 10 {
+		t.Fatalf("expected the new defaults, got %+v", got)
+	}
+	if err := r.SetLimits("limited", Limits{MaxStateBytes: 128}); err != nil {
+		t.Fatalf("set limits: %v", err)
+	}
+	if got := r.Limits("limited"); got.MaxStateBytes != 128 {
+		t.Fatalf("expected the override, got %+v", got)
+	}
+	if err := r.SetLimits("nonexistent", Limits{}); err == nil {
+		t.Fatal("expected error for unknown game")
+	}
+}
+
+func TestRegistryEnabled(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubGame{name: "chess", minPlayers: 2, maxPlayers: 2})
+	r.Register(stubGame{name: "go", minPlayers: 2, maxPlayers: 2})
+
+	if err := r.SetEnabled("chess", false); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if r.Enabled("chess") || !r.Enabled("go") || r.Enabled("checkers") {
+		t.Fatal("expected only go enabled")
+	}
+	if infos := r.List(); len(infos) != 1 || infos[0].Name != "go" {
+		t.Fatalf("expected a disabled game left out of the list, got %v", infos)
+	}
+	if names := r.Names(); len(names) != 2 || names[0] != "chess" {
+		t.Fatalf("expected every game named, got %v", names)
+	}
+	if _, ok := r.Get("chess"); !ok {
+		t.Fatal("expected a disabled game still found, for matches under way")
+	}
+	r.SetEnabled("chess", true)
+	if !r.Enabled("chess") {
+		t.Fatal("expected chess enabled again")
+	}
+	if err := r.SetEnabled("checkers", false); err == nil {
+		t.Fatal("expected error for unknown game")
+	}
+}
+
+func TestRegistrySubset(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubGame{name: "chess", minPlayers: 2, maxPlayers: 2})
+	r.Register(stubGame{name: "go", minPlayers: 2, maxPlayers: 2})
+	r.RegisterStrategy("chess", stubStrategy{name: "easy", difficulty: 1})
+	r.SetLimits("chess", Limits{MaxStateBytes: 32})
+
+	sub, err := r.Subset("chess")
+	if err != nil {
+		t.Fatalf("subset: %v", err)
+	}
+	if _, ok := sub.Get("go"); ok || len(sub.List()) != 1 {
+		t.Fatalf("expected only chess, got %v", sub.List())
+	}
+	if _, ok := sub.Strategy("chess", "easy"); !ok {
+		t.Fatal("expected chess's strategy kept")
+	}
+	if got := sub.Limits("chess"); got.MaxStateBytes != 32 {
+		t.Fatalf("expected chess's limits kept, got %+v", got)
+	}
+	if all, _ := r.Subset(); len(all.List()) != 2 {
+		t.Fatalf("expected every game with no names, got %v", all.List())
+	}
+	if _, err := r.Subset("checkers"); err == nil {
+		t.Fatal("expected error for unknown game")
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	if err := CheckSize("match state", 10, 0); err != nil {
+		t.Fatalf("expected no limit, got %v", err)
+	}
+	if err := CheckSize("match state", 10, 10); err != nil {
+		t.Fatalf("expected a state at the limit to pass, got %v", err)
+	}
+	err := CheckSize("match state", 11, 10)
+	var se *SizeError
+	if !errors.As(err, &se) || se.Size != 11 || se.Limit != 10 {
+		t.Fatalf("expected a size error, got %v", err)
+	}
+}
+
+func TestCommitmentVerify(t *testing.T) {
+	c := Commitment{Hash: Commit(42, "pepper")}
+	if c.Verify() {
+		t.Fatal("an unrevealed commitment must not verify")
+	}
+	c.Seed, c.Salt = 42, "pepper"
+	if !c.Verify() {
+		t.Fatal("expected the revealed seed to verify")
+	}
+	c.Seed = 43
+	if c.Verify() {
+		t.Fatal("expected a different seed to fail")
+	}
+}
+
+func TestRegistryReplaceAndUnregister(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubGame{name: "chess", minPlayers: 2, maxPlayers: 2})
+	r.RegisterStrategy("chess", stubStrategy{name: "easy", difficulty: 1})
+	r.SetEnabled("chess", false)
+
+	old, err := r.Replace(stubGame{name: "chess", minPlayers: 2, maxPlayers: 4})
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if old.Info().MaxPlayers != 2 {
+		t.Fatalf("expected the old version returned, got %+v", old.Info())
+	}
+	if g, _ := r.Get("chess"); g.Info().MaxPlayers != 4 {
+		t.Fatalf("expected the new version registered, got %+v", g.Info())
+	}
+	if _, ok := r.Strategy("chess", "easy"); !ok || r.Enabled("chess") {
+		t.Fatal("expected strategies and the disabled flag kept")
+	}
+	if _, err := r.Replace(stubGame{name: "go"}); err == nil {
+		t.Fatal("expected error replacing an unknown game")
+	}
+
+	if _, err := r.Unregister("chess"); err != nil {
+		t.Fatalf("unregister: %v", err)
+	}
+	if _, ok := r.Get("chess"); ok || len(r.Strategies("chess")) != 0 || len(r.Names()) != 0 {
+		t.Fatal("expected chess and its strategies gone")
+	}
+	if _, err := r.Unregister("chess"); err == nil {
+		t.Fatal("expected error unregistering twice")
+	}
+	// The name is free to register again
+	r.Register(stubGame{name: "chess", minPlayers: 2, maxPlayers: 2})
+	if !r.Enabled("chess") {
+		t.Fatal("expected a registered-again game enabled")
+	}
+}
+
+func TestRegistryRules(t *testing.T) {
+	r := NewRegistry()
+	r.Register(stubGame{name: "chess", minPlayers: 2, maxPlayers: 2, version: "2"})
+	r.RegisterPrevious(stubGame{name: "chess", minPlayers: 2, maxPlayers: 4, version: "1"})
+
+	if g, ok := r.Rules("chess", "2"); !ok || g.Info().MaxPlayers != 2 {
+		t.Fatal("expected the current rules")
+	}
+	if g, ok := r.Rules("chess", "1"); !ok || g.Info().MaxPlayers != 4 {
+		t.Fatal("expected the earlier rules kept")
+	}
+	if _, ok := r.Rules("chess", "0"); ok {
+		t.Fatal("expected rules never registered not found")
+	}
+	if infos := r.List(); len(infos) != 1 || infos[0].Version != "2" {
+		t.Fatalf("expected only the current rules listed, got %v", infos)
+	}
+	sub, _ := r.Subset("chess")
+	if _, ok := sub.Rules("chess", "1"); !ok {
+		t.Fatal("expected a subset to keep earlier rules")
+	}
+	for _, g := range []Game{stubGame{name: "chess", version: "2"}, stubGame{name: "chess", version: "1"}, stubGame{name: "go"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %+v refused", g.Info())
+				}
+			}()
+			r.RegisterPrevious(g)
+		}()
+	}
+}
@@ -0,0 +1,312 @@
+// Package script loads games written in Starlark, a small dialect of
+// Python, so simple turn-based games can be tried out without a Go build.
+//
+// A script defines a global info and these functions:
+//
+//	info = {"name": "...", "minPlayers": 2, "maxPlayers": 2, "options": [...]}
+//	def new_match(players, options, seed): return the first state
+//	def valid_actions(state, player): return [{"type": ..., "payload": ...}]
+//	def apply(state, player, action): return the next state, or (state, events)
+//	def results(state): return None while in play, else [{"playerId", "rank", "score"}]
+//	def view(state, player): optional; what player sees, the state without it
+//	def describe(state, player, action, events): optional; what an action did, in words
+//
+// info, actions, events and results take the same fields as their JSON
+// forms in package game. apply rejects an action by calling fail with the
+// reason. A state is plain data, made of dicts, lists, strings, numbers,
+// bools and None, that round-trips through JSON as matches are stored.
+// Each call gets its own copy, so a function may change the state it is
+// given.
+package script
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	starjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+
+	"games/internal/game"
+)
+
+// DefaultMaxSteps bounds the work one call into a script may do, so a
+// script that loops forever is stopped.
+const DefaultMaxSteps = 1_000_000
+
+// Game is a game defined by a script.
+type Game struct {
+	info     game.GameInfo
+	maxSteps uint64
+
+	newMatch     starlark.Callable
+	validActions starlark.Callable
+	apply        starlark.Callable
+	results      starlark.Callable
+	view         starlark.Callable // nil when players see the whole state
+	describe     starlark.Callable // nil when the script does not narrate
+}
+
+// Load reads and runs the script at path, returning its game.
+func Load(path string) (*Game, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Parse runs a script, returning its game. filename appears in errors.
+func Parse(filename string, src []byte) (*Game, error) {
+	thread := &starlark.Thread{Name: filename}
+	thread.SetMaxExecutionSteps(DefaultMaxSteps)
+	globals, err := starlark.ExecFile(thread, filename, src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Game{maxSteps: DefaultMaxSteps}
+	info, ok := globals["info"]
+	if !ok {
+		return nil, fmt.Errorf("%s: no info", filename)
+	}
+	data, err := toJSON(thread, info)
+	if err != nil {
+		return nil, fmt.Errorf("%s: info: %w", filename, err)
+	}
+	if err := json.Unmarshal(data, &g.info); err != nil {
+		return nil, fmt.Errorf("%s: info: %w", filename, err)
+	}
+	if g.info.Name == "" {
+		return nil, fmt.Errorf("%s: info has no name", filename)
+	}
+
+	for name, fn := range map[string]*starlark.Callable{
+		"new_match":     &g.newMatch,
+		"valid_actions": &g.validActions,
+		"apply":         &g.apply,
+		"results":       &g.results,
+		"view":          &g.view,
+		"describe":      &g.describe,
+	} {
+		v, ok := globals[name]
+		if !ok {
+			if name == "view" || name == "describe" {
+				continue
+			}
+			return nil, fmt.Errorf("%s: no function %s", filename, name)
+		}
+		if *fn, ok = v.(starlark.Callable); !ok {
+			return nil, fmt.Errorf("%s: %s is a %s, not a function", filename, name, v.Type())
+		}
+	}
+	return g, nil
+}
+
+// SetMaxSteps changes how many steps one call into the script may take.
+// It is DefaultMaxSteps unless set.
+func (g *Game) SetMaxSteps(n uint64) {
+	g.maxSteps = n
+}
+
+// call runs fn with args, which are passed to the script as their JSON
+// forms.
+func (g *Game) call(fn starlark.Callable, args ...any) (starlark.Value, *starlark.Thread, error) {
+	thread := &starlark.Thread{
+		Name:  g.info.Name,
+		Print: func(_ *starlark.Thread, msg string) { log.Printf("game %s: %s", g.info.Name, msg) },
+	}
+	thread.SetMaxExecutionSteps(g.maxSteps)
+	values := make(starlark.Tuple, len(args))
+	for i, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if values[i], err = fromJSON(thread, data); err != nil {
+			return nil, nil, err
+		}
+	}
+	result, err := starlark.Call(thread, fn, values, nil)
+	if err != nil {
+		return nil, nil, scriptError(err)
+	}
+	return result, thread, nil
+}
+
+// callJSON runs fn with args and decodes its result into out.
+func (g *Game) callJSON(out any, fn starlark.Callable, args ...any) error {
+	result, thread, err := g.call(fn, args...)
+	if err != nil {
+		return err
+	}
+	data, err := toJSON(thread, result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// scriptError turns an error from a script into the message a player
+// sees, without the "fail: " that Starlark puts before a failure's
+// reason.
+func scriptError(err error) error {
+	var eval *starlark.EvalError
+	if errors.As(err, &eval) {
+		return errors.New(strings.TrimPrefix(eval.Msg, "fail: "))
+	}
+	return err
+}
+
+func fromJSON(thread *starlark.Thread, data []byte) (starlark.Value, error) {
+	return starlark.Call(thread, starjson.Module.Members["decode"], starlark.Tuple{starlark.String(data)}, nil)
+}
+
+func toJSON(thread *starlark.Thread, v starlark.Value) (json.RawMessage, error) {
+	s, err := starlark.Call(thread, starjson.Module.Members["encode"], starlark.Tuple{v}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(s.(starlark.String)), nil
+}
+
+func (g *Game) Info() game.GameInfo {
+	return g.info
+}
+
+// NewMatch starts a match with the script's new_match. Since Game cannot
+// return an error, a script that fails gives a match that is already
+// over with no results.
+func (g *Game) NewMatch(config game.MatchConfig) game.Match {
+	options := config.Options
+	if options == nil {
+		options = map[string]int{}
+	}
+	m := &match{g: g}
+	var state json.RawMessage
+	err := g.callJSON(&state, g.newMatch, config.PlayerIDs, options, config.Seed)
+	if err == nil {
+		err = m.load(state)
+	}
+	if err != nil {
+		log.Printf("game %s: new match: %v", g.info.Name, err)
+		m.over = true
+	}
+	return m
+}
+
+// match holds a match as the JSON form of the script's state. IsOver and
+// Results answer from the script's results when the state last changed.
+type match struct {
+	g       *Game
+	state   json.RawMessage
+	over    bool
+	results []game.PlayerResult
+}
+
+// load makes state the match's state, asking the script for its results.
+func (m *match) load(state json.RawMessage) error {
+	var results []game.PlayerResult
+	if err := m.g.callJSON(&results, m.g.results, state); err != nil {
+		return fmt.Errorf("results: %w", err)
+	}
+	m.state, m.over, m.results = state, results != nil, results
+	return nil
+}
+
+func (m *match) State(playerID string) any {
+	if m.g.view == nil {
+		return m.state
+	}
+	var view json.RawMessage
+	if err := m.g.callJSON(&view, m.g.view, m.state, playerID); err != nil {
+		log.Printf("game %s: view: %v", m.g.info.Name, err)
+		return nil
+	}
+	return view
+}
+
+func (m *match) ValidActions(playerID string) []game.Action {
+	var actions []game.Action
+	if err := m.g.callJSON(&actions, m.g.validActions, m.state, playerID); err != nil {
+		log.Printf("game %s: valid actions: %v", m.g.info.Name, err)
+		return nil
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	return actions
+}
+
+func (m *match) ApplyAction(playerID string, action game.Action) error {
+	_, err := m.ApplyActionWithEvents(playerID, action)
+	return err
+}
+
+func (m *match) ApplyActionWithEvents(playerID string, action game.Action) ([]game.Event, error) {
+	result, thread, err := m.g.call(m.g.apply, m.state, playerID, action)
+	if err != nil {
+		return nil, err
+	}
+	var events []game.Event
+	if t, ok := result.(starlark.Tuple); ok && len(t) == 2 {
+		data, err := toJSON(thread, t[1])
+		if err != nil {
+			return nil, fmt.Errorf("events: %w", err)
+		}
+		if err := json.Unmarshal(data, &events); err != nil {
+			return nil, fmt.Errorf("events: %w", err)
+		}
+		result = t[0]
+	}
+	state, err := toJSON(thread, result)
+	if err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+	if err := m.load(state); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Describe narrates an action with the script's describe, given the
+// state after it. A script without one, or that fails, says nothing.
+func (m *match) Describe(playerID string, action game.Action, events []game.Event) string {
+	if m.g.describe == nil {
+		return ""
+	}
+	var text string
+	if err := m.g.callJSON(&text, m.g.describe, m.state, playerID, action, events); err != nil {
+		log.Printf("game %s: describe: %v", m.g.info.Name, err)
+		return ""
+	}
+	return text
+}
+
+func (m *match) IsOver() bool {
+	return m.over
+}
+
+func (m *match) Results() []game.PlayerResult {
+	return append([]game.PlayerResult(nil), m.results...)
+}
+
+func (m *match) Clone() game.Match {
+	c := *m
+	c.results = m.Results()
+	return &c
+}
+
+func (m *match) MarshalJSON() ([]byte, error) {
+	if m.state == nil {
+		return []byte("null"), nil
+	}
+	return m.state, nil
+}
+
+func (m *match) UnmarshalJSON(data []byte) error {
+	return m.load(append(json.RawMessage(nil), data...))
+}
@@ -0,0 +1,189 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/gametest"
+	"games/internal/game/tictactoe"
+)
+
+func loadTicTacToe(t testing.TB) *Game {
+	t.Helper()
+	g, err := Load("examples/tictactoe.star")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return g
+}
+
+// sameJSON reports whether a and b have equal JSON forms, ignoring the
+// order of object keys.
+func sameJSON(t *testing.T, a, b any) bool {
+	t.Helper()
+	var va, vb any
+	for _, p := range []struct {
+		in  any
+		out *any
+	}{{a, &va}, {b, &vb}} {
+		data, err := json.Marshal(p.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, p.out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// TestTicTacToeConformance plays random games of the sample script and
+// the built-in tic-tac-toe side by side, checking the two agree on every
+// view, action, event and result.
+func TestTicTacToeConformance(t *testing.T) {
+	script := loadTicTacToe(t)
+	if info, want := script.Info(), (tictactoe.TicTacToe{}).Info(); info.Name != "tictactoe-script" || !reflect.DeepEqual(info.Options, want.Options) {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	players := []string{"alice", "bob"}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range 40 {
+		config := game.MatchConfig{PlayerIDs: players, Options: map[string]int{"misere": i % 2}}
+		want := tictactoe.TicTacToe{}.NewMatch(config)
+		got := script.NewMatch(config)
+		for !want.IsOver() {
+			for _, p := range players {
+				if !sameJSON(t, got.State(p), want.State(p)) {
+					t.Fatalf("game %d: %s sees %v, want %v", i, p, got.State(p), want.State(p))
+				}
+				if !reflect.DeepEqual(got.ValidActions(p), want.ValidActions(p)) {
+					t.Fatalf("game %d: %s may play %v, want %v", i, p, got.ValidActions(p), want.ValidActions(p))
+				}
+			}
+			if got.IsOver() {
+				t.Fatalf("game %d: script match over early", i)
+			}
+
+			player := players[0]
+			if len(want.ValidActions(player)) == 0 {
+				player = players[1]
+			}
+			other := players[0]
+			if other == player {
+				other = players[1]
+			}
+			cell := rng.IntN(9)
+			action := game.Action{Type: "move", Payload: json.RawMessage(fmt.Sprintf(`{"cell":%d}`, cell))}
+			if _, err := game.Apply(got, other, action); err == nil || err.Error() != "not your turn" {
+				t.Fatalf("game %d: expected the script to refuse a move out of turn, got %v", i, err)
+			}
+			wantEvents, wantErr := game.Apply(want, player, action)
+			gotEvents, gotErr := game.Apply(got, player, action)
+			if (gotErr == nil) != (wantErr == nil) || (wantErr != nil && gotErr.Error() != wantErr.Error()) {
+				t.Fatalf("game %d: cell %d: script said %v, want %v", i, cell, gotErr, wantErr)
+			}
+			if !sameJSON(t, gotEvents, wantEvents) {
+				t.Fatalf("game %d: events %v, want %v", i, gotEvents, wantEvents)
+			}
+			if wantErr == nil {
+				gotText, wantText := game.Describe(got, player, action, gotEvents), game.Describe(want, player, action, wantEvents)
+				if gotText != wantText {
+					t.Fatalf("game %d: narrated %q, want %q", i, gotText, wantText)
+				}
+			}
+		}
+		if !got.IsOver() {
+			t.Fatalf("game %d: script match not over", i)
+		}
+		gotResults, wantResults := game.Results(got), game.Results(want)
+		for j := range wantResults {
+			if wantResults[j].Detail == nil {
+				gotResults[j].Detail = nil
+			}
+		}
+		if !sameJSON(t, gotResults, wantResults) {
+			t.Fatalf("game %d: results %+v, want %+v", i, gotResults, wantResults)
+		}
+	}
+}
+
+func TestScriptMatchPersistence(t *testing.T) {
+	g := loadTicTacToe(t)
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}})
+	if err := m.ApplyAction("alice", game.Action{Type: "move", Payload: json.RawMessage(`{"cell":4}`)}); err != nil {
+		t.Fatal(err)
+	}
+	clone := m.Clone()
+	if err := m.ApplyAction("bob", game.Action{Type: "move", Payload: json.RawMessage(`{"cell":0}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(clone.ValidActions("bob")); n != 8 {
+		t.Fatalf("expected the clone to be unaffected, got %d actions", n)
+	}
+
+	data, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"_", "_"}})
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if !sameJSON(t, restored.State("alice"), m.State("alice")) {
+		t.Fatalf("restored %v, want %v", restored.State("alice"), m.State("alice"))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, src, want string
+	}{
+		{"syntax", "def (", "test.star:1:"},
+		{"no info", "x = 1", "no info"},
+		{"no name", `info = {"minPlayers": 2}`, "no name"},
+		{"no function", `info = {"name": "g"}`, "no function"},
+		{"not a function", "info = {\"name\": \"g\"}\nnew_match = 1\nvalid_actions = 1\napply = 1\nresults = 1", "not a function"},
+	} {
+		_, err := Parse("test.star", []byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestMaxSteps(t *testing.T) {
+	g, err := Parse("loop.star", []byte(`
+info = {"name": "loop", "minPlayers": 1, "maxPlayers": 1}
+def new_match(players, options, seed):
+    return {}
+def valid_actions(state, player):
+    return []
+def apply(state, player, action):
+    for i in range(100000000):
+        pass
+    return state
+def results(state):
+    return None
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetMaxSteps(1000)
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice"}})
+	if m.IsOver() {
+		t.Fatal("expected the match to start")
+	}
+	err = m.ApplyAction("alice", game.Action{Type: "spin"})
+	if err == nil || !strings.Contains(err.Error(), "too many steps") {
+		t.Fatalf("expected the loop to be stopped, got %v", err)
+	}
+}
+
+func FuzzApplyAction(f *testing.F) {
+	gametest.FuzzApplyAction(f, loadTicTacToe(f), []string{"alice", "bob"})
+}
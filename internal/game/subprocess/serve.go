@@ -0,0 +1,122 @@
+package subprocess
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"games/internal/game"
+)
+
+// Serve answers requests for g read from r, writing responses to w, until
+// r ends. A game written in Go becomes a game process with
+//
+//	func main() {
+//		if err := subprocess.Serve(MyGame{}, os.Stdin, os.Stdout); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// Each request loads its match afresh from the state it carries, so g's
+// matches must restore completely from their MarshalJSON output.
+func Serve(g game.Game, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	for scanner.Scan() {
+		var req struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		resp := response{}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("malformed request: %v", err)
+		} else {
+			resp.ID = req.ID
+			result, err := handle(g, req.Method, req.Params)
+			if err == nil {
+				resp.Result, err = json.Marshal(result)
+			}
+			if err != nil {
+				resp.Error = err.Error()
+			}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle runs one request, turning a panic in the game into an error so
+// one bad match does not stop the process.
+func handle(g game.Game, method string, raw json.RawMessage) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("%s: game panicked: %v", method, p)
+		}
+	}()
+
+	if method == "info" {
+		return g.Info(), nil
+	}
+	if method == "new_match" {
+		var params newMatchParams
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, err
+		}
+		m := g.NewMatch(game.MatchConfig{PlayerIDs: params.PlayerIDs, Options: params.Options, Handicaps: params.Handicaps, Seed: params.Seed})
+		return statusOf(m, nil)
+	}
+
+	var params matchParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	// The same placeholder players the session package restores with;
+	// the state replaces them.
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"_", "_"}})
+	if err := m.UnmarshalJSON(params.State); err != nil {
+		return nil, fmt.Errorf("load state: %w", err)
+	}
+	switch method {
+	case "state":
+		return m.State(params.PlayerID), nil
+	case "valid_actions":
+		actions := m.ValidActions(params.PlayerID)
+		if actions == nil {
+			actions = []game.Action{}
+		}
+		return actions, nil
+	case "apply":
+		if params.Action == nil {
+			return nil, fmt.Errorf("apply: no action")
+		}
+		events, err := game.Apply(m, params.PlayerID, *params.Action)
+		if err != nil {
+			return nil, err
+		}
+		return statusOf(m, events)
+	case "status":
+		return statusOf(m, nil)
+	}
+	return nil, fmt.Errorf("unknown method %q", method)
+}
+
+func statusOf(m game.Match, events []game.Event) (status, error) {
+	state, err := m.MarshalJSON()
+	if err != nil {
+		return status{}, err
+	}
+	st := status{State: state, Over: m.IsOver(), Events: events}
+	if st.Over {
+		st.Results = m.Results()
+	}
+	return st, nil
+}
@@ -0,0 +1,352 @@
+// Package subprocess runs games in separate processes, so a server can
+// host games it was not compiled with.
+//
+// A game process reads requests from stdin and writes responses to
+// stdout, one JSON object per line:
+//
+//	{"id": 1, "method": "apply", "params": {...}}
+//	{"id": 1, "result": {...}}
+//	{"id": 2, "error": "not your turn"}
+//
+// The protocol is stateless: every request carries the match state the
+// process returned last, so a process holds nothing between requests and
+// may be restarted at any time. The methods are:
+//
+//	info           {}                                     -> GameInfo
+//	new_match      {playerIds, options, handicaps, seed}  -> {state, over, results}
+//	state          {state, playerId}                      -> any
+//	valid_actions  {state, playerId}                      -> [Action]
+//	apply          {state, playerId, action}              -> {state, over, results, events}
+//	status         {state}                                -> {state, over, results}
+//
+// Serve implements the process side for games written in Go. Open serves
+// a game over the same protocol without a process, as package wasm does
+// for WebAssembly modules.
+package subprocess
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"games/internal/game"
+)
+
+// DefaultCallTimeout bounds how long a game process may take to answer.
+const DefaultCallTimeout = 5 * time.Second
+
+type request struct {
+	ID     uint64 `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params"`
+}
+
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type newMatchParams struct {
+	PlayerIDs []string       `json:"playerIds"`
+	Options   map[string]int `json:"options"`
+	Handicaps game.Handicaps `json:"handicaps,omitempty"`
+	Seed      int64          `json:"seed"`
+}
+
+type matchParams struct {
+	State    json.RawMessage `json:"state"`
+	PlayerID string          `json:"playerId,omitempty"`
+	Action   *game.Action    `json:"action,omitempty"`
+}
+
+// status is what a game process reports after creating, changing or
+// loading a match.
+type status struct {
+	State   json.RawMessage     `json:"state"`
+	Over    bool                `json:"over"`
+	Results []game.PlayerResult `json:"results,omitempty"`
+	Events  []game.Event        `json:"events,omitempty"`
+}
+
+// A Conn carries requests to a game that does not run as a process, and
+// brings back its answers: one JSON object each way, as on a process's
+// stdin and stdout. Close drops whatever the game holds, as stopping a
+// process does; the next Exchange starts it afresh.
+type Conn interface {
+	Exchange(request []byte) (response []byte, err error)
+	Close() error
+}
+
+// Game is a game played by a separate process. It starts the process on
+// first use and again whenever it exits or stops answering.
+type Game struct {
+	path    string
+	args    []string
+	conn    Conn // instead of a process, when not nil
+	timeout time.Duration
+	info    game.GameInfo
+
+	mu     sync.Mutex // serializes calls
+	proc   *process
+	nextID uint64
+}
+
+// process is one run of a game's command.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan []byte // closed when stdout ends
+}
+
+// Start runs the command at path, asks it for its game's info and returns
+// the game, ready to register.
+func Start(path string, args ...string) (*Game, error) {
+	return open(&Game{path: path, args: args, timeout: DefaultCallTimeout})
+}
+
+// Open returns the game answering over c, asking it for its info as Start
+// does. name stands for the game in errors.
+func Open(name string, c Conn) (*Game, error) {
+	return open(&Game{path: name, conn: c})
+}
+
+func open(g *Game) (*Game, error) {
+	if err := g.call("info", struct{}{}, &g.info); err != nil {
+		g.Close()
+		return nil, fmt.Errorf("%s: %w", g.path, err)
+	}
+	if g.info.Name == "" {
+		g.Close()
+		return nil, fmt.Errorf("%s: game has no name", g.path)
+	}
+	return g, nil
+}
+
+// SetCallTimeout changes how long the process may take to answer one
+// request before it is killed. It is DefaultCallTimeout unless set, and
+// does not apply to a game served over a Conn.
+func (g *Game) SetCallTimeout(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.timeout = d
+}
+
+// Close stops the game's process. A later call starts it again.
+func (g *Game) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stopLocked()
+}
+
+func (g *Game) stopLocked() error {
+	if g.conn != nil {
+		return g.conn.Close()
+	}
+	if g.proc == nil {
+		return nil
+	}
+	p := g.proc
+	g.proc = nil
+	p.stdin.Close()
+	go func() {
+		for range p.lines {
+		}
+	}()
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+func (g *Game) startLocked() error {
+	cmd := exec.Command(g.path, g.args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p := &process{cmd: cmd, stdin: stdin, lines: make(chan []byte)}
+	go func() {
+		defer close(p.lines)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(nil, 16<<20)
+		for scanner.Scan() {
+			p.lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+	}()
+	g.proc = p
+	return nil
+}
+
+// call sends one request and decodes the result into out. A process that
+// exits, writes something other than the answer or takes too long is
+// stopped, to be started afresh by the next call.
+func (g *Game) call(method string, params, out any) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nextID++
+	req, err := json.Marshal(request{ID: g.nextID, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	line, err := g.exchangeLocked(req)
+	if err != nil {
+		g.stopLocked()
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil || resp.ID != g.nextID {
+		g.stopLocked()
+		return fmt.Errorf("%s: malformed answer %.100q", method, line)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// exchangeLocked sends req to the game and returns its answer, starting
+// the game's process first if need be.
+func (g *Game) exchangeLocked(req []byte) ([]byte, error) {
+	if g.conn != nil {
+		return g.conn.Exchange(req)
+	}
+	if g.proc == nil {
+		if err := g.startLocked(); err != nil {
+			return nil, fmt.Errorf("start game process: %w", err)
+		}
+	}
+	if _, err := g.proc.stdin.Write(append(req, '\n')); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(g.timeout)
+	defer timer.Stop()
+	select {
+	case line, ok := <-g.proc.lines:
+		if !ok {
+			return nil, errors.New("game process exited")
+		}
+		return line, nil
+	case <-timer.C:
+		g.proc.cmd.Process.Kill()
+		return nil, fmt.Errorf("no answer within %s", g.timeout)
+	}
+}
+
+// Info returns the info the process reported when it started.
+func (g *Game) Info() game.GameInfo {
+	return g.info
+}
+
+// NewMatch asks the process for a new match. Since Game cannot return an
+// error, a process that fails gives a match that is already over with no
+// results.
+func (g *Game) NewMatch(config game.MatchConfig) game.Match {
+	var st status
+	params := newMatchParams{PlayerIDs: config.PlayerIDs, Options: config.Options, Handicaps: config.Handicaps, Seed: config.Seed}
+	if err := g.call("new_match", params, &st); err != nil {
+		log.Printf("game %s: new match: %v", g.info.Name, err)
+		return &match{g: g, over: true}
+	}
+	return &match{g: g, state: st.State, over: st.Over, results: st.Results}
+}
+
+// match is a match held by the server as the state its process returned.
+// IsOver and Results answer from the last status without a call.
+type match struct {
+	g       *Game
+	state   json.RawMessage
+	over    bool
+	results []game.PlayerResult
+}
+
+func (m *match) State(playerID string) any {
+	var state json.RawMessage
+	if err := m.g.call("state", matchParams{State: m.state, PlayerID: playerID}, &state); err != nil {
+		log.Printf("game %s: state: %v", m.g.info.Name, err)
+		return nil
+	}
+	return state
+}
+
+func (m *match) ValidActions(playerID string) []game.Action {
+	var actions []game.Action
+	if err := m.g.call("valid_actions", matchParams{State: m.state, PlayerID: playerID}, &actions); err != nil {
+		log.Printf("game %s: valid actions: %v", m.g.info.Name, err)
+		return nil
+	}
+	return actions
+}
+
+func (m *match) ApplyAction(playerID string, action game.Action) error {
+	_, err := m.ApplyActionWithEvents(playerID, action)
+	return err
+}
+
+func (m *match) ApplyActionWithEvents(playerID string, action game.Action) ([]game.Event, error) {
+	var st status
+	if err := m.g.call("apply", matchParams{State: m.state, PlayerID: playerID, Action: &action}, &st); err != nil {
+		return nil, err
+	}
+	m.state, m.over, m.results = st.State, st.Over, st.Results
+	return st.Events, nil
+}
+
+func (m *match) IsOver() bool {
+	return m.over
+}
+
+func (m *match) Results() []game.PlayerResult {
+	return append([]game.PlayerResult(nil), m.results...)
+}
+
+func (m *match) Clone() game.Match {
+	c := *m
+	c.results = m.Results()
+	return &c
+}
+
+func (m *match) MarshalJSON() ([]byte, error) {
+	if m.state == nil {
+		return []byte("null"), nil
+	}
+	return m.state, nil
+}
+
+// UnmarshalJSON loads a stored state, asking the process whether the
+// match it holds is over.
+func (m *match) UnmarshalJSON(data []byte) error {
+	var st status
+	if err := m.g.call("status", matchParams{State: data}, &st); err != nil {
+		return err
+	}
+	if st.State == nil {
+		st.State = append(json.RawMessage(nil), data...)
+	}
+	m.state, m.over, m.results = st.State, st.Over, st.Results
+	return nil
+}
@@ -0,0 +1,162 @@
+package subprocess
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"games/internal/game"
+	"games/internal/game/gametest"
+	"games/internal/game/tictactoe"
+)
+
+// TestMain lets the test binary stand in for a game process: run with
+// SUBPROCESS_HELPER set it serves tic-tac-toe, or hangs when asked to.
+func TestMain(m *testing.M) {
+	switch os.Getenv("SUBPROCESS_HELPER") {
+	case "":
+		os.Exit(m.Run())
+	case "hang":
+		bufio.NewReader(os.Stdin).ReadString('\n')
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	default:
+		if err := Serve(tictactoe.TicTacToe{}, os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+func startHelper(t testing.TB, mode string) *Game {
+	t.Helper()
+	t.Setenv("SUBPROCESS_HELPER", mode)
+	g, err := Start(os.Args[0])
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+func move(cell int) game.Action {
+	return game.Action{Type: "move", Payload: json.RawMessage(fmt.Sprintf(`{"cell":%d}`, cell))}
+}
+
+func TestSubprocessMatch(t *testing.T) {
+	g := startHelper(t, "tictactoe")
+	if g.Info().Name != "tictactoe" {
+		t.Fatalf("unexpected info %+v", g.Info())
+	}
+
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}})
+	if n := len(m.ValidActions("alice")); n != 9 {
+		t.Fatalf("expected 9 actions for alice, got %d", n)
+	}
+	if err := m.ApplyAction("bob", move(0)); err == nil {
+		t.Fatal("expected bob's move out of turn to be rejected")
+	}
+	for i, cell := range []int{0, 3, 1, 4} {
+		player := []string{"alice", "bob"}[i%2]
+		if err := m.ApplyAction(player, move(cell)); err != nil {
+			t.Fatalf("move %d: %v", cell, err)
+		}
+	}
+
+	saved, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := m.Clone()
+	if err := m.ApplyAction("alice", move(2)); err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsOver() || m.Results()[0].Rank != 1 {
+		t.Fatalf("expected alice to win, got %+v", m.Results())
+	}
+	if clone.IsOver() {
+		t.Fatal("expected the clone to be unaffected")
+	}
+
+	restored := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"_", "_"}})
+	if err := restored.UnmarshalJSON(saved); err != nil {
+		t.Fatal(err)
+	}
+	if restored.IsOver() || len(restored.ValidActions("alice")) != 5 {
+		t.Fatal("expected the restored match to be mid-game")
+	}
+	var state struct {
+		Board [9]int `json:"board"`
+	}
+	raw, _ := json.Marshal(restored.State("alice"))
+	if err := json.Unmarshal(raw, &state); err != nil || state.Board[4] != 2 {
+		t.Fatalf("unexpected state %s: %v", raw, err)
+	}
+}
+
+func TestSubprocessRestarts(t *testing.T) {
+	g := startHelper(t, "tictactoe")
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}})
+	if err := m.ApplyAction("alice", move(4)); err != nil {
+		t.Fatal(err)
+	}
+	g.Close()
+	if n := len(m.ValidActions("bob")); n != 8 {
+		t.Fatalf("expected the match to carry on in a new process, got %d actions", n)
+	}
+}
+
+func TestSubprocessTimeout(t *testing.T) {
+	t.Setenv("SUBPROCESS_HELPER", "hang")
+	g := &Game{path: os.Args[0], timeout: 100 * time.Millisecond}
+	defer g.Close()
+	err := g.call("info", struct{}{}, &g.info)
+	if err == nil || !strings.Contains(err.Error(), "no answer") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if g.proc != nil {
+		t.Fatal("expected the hung process to be stopped")
+	}
+}
+
+func TestStartFails(t *testing.T) {
+	if _, err := Start("/nonexistent/game"); err == nil {
+		t.Fatal("expected an error for a missing command")
+	}
+}
+
+func TestServe(t *testing.T) {
+	in := strings.NewReader(`{"id":1,"method":"info"}
+not json
+{"id":2,"method":"apply","params":{"state":null,"playerId":"alice"}}
+{"id":3,"method":"dance","params":{"state":null}}
+`)
+	var out strings.Builder
+	if err := Serve(tictactoe.TicTacToe{}, in, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 responses, got %q", lines)
+	}
+	var resp response
+	json.Unmarshal([]byte(lines[0]), &resp)
+	if resp.ID != 1 || resp.Error != "" || !strings.Contains(string(resp.Result), `"tictactoe"`) {
+		t.Fatalf("unexpected info response %s", lines[0])
+	}
+	for _, line := range lines[1:] {
+		resp = response{}
+		json.Unmarshal([]byte(line), &resp)
+		if resp.Error == "" {
+			t.Fatalf("expected an error, got %s", line)
+		}
+	}
+}
+
+func FuzzApplyAction(f *testing.F) {
+	gametest.FuzzApplyAction(f, startHelper(f, "tictactoe"), []string{"alice", "bob"})
+}
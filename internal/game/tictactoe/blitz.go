@@ -0,0 +1,20 @@
+package tictactoe
+
+import "games/internal/game"
+
+// Blitz is tic-tac-toe against a chess clock with a Fischer increment:
+// each player has the "clock" option's seconds for the whole match, gains
+// the "increment" option's seconds with every move, and loses on time
+// when the clock runs out. The matches are tic-tac-toe's own, so the
+// renderer, notation and bots all carry over.
+type Blitz struct{ TicTacToe }
+
+func (b Blitz) Info() game.GameInfo {
+	info := b.TicTacToe.Info()
+	info.Name = "blitz"
+	info.Options = append(info.Options,
+		game.Option{Name: "clock", Description: "Seconds on each player's clock", Default: 60, Min: 1, Max: 3600},
+		game.Option{Name: "increment", Description: "Seconds added to a player's clock with each move", Default: 2, Min: 0, Max: 60},
+	)
+	return info
+}
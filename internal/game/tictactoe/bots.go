@@ -0,0 +1,159 @@
+package tictactoe
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+
+	"games/internal/game"
+)
+
+// Strategies returns the tic-tac-toe bot strategies, easiest first.
+func Strategies() []game.Strategy {
+	return []game.Strategy{RandomBot{}, BlockingBot{}, PerfectBot{}}
+}
+
+// RandomBot plays a uniformly random empty cell.
+type RandomBot struct{}
+
+func (RandomBot) Info() game.StrategyInfo {
+	return game.StrategyInfo{Name: "random", Difficulty: 1, Description: "Plays any open cell"}
+}
+
+func (RandomBot) ChooseAction(gm game.Match, playerID string) (game.Action, error) {
+	m, err := botView(gm, playerID)
+	if err != nil {
+		return game.Action{}, err
+	}
+	empty := m.emptyCells()
+	return moveAction(empty[rand.IntN(len(empty))]), nil
+}
+
+// BlockingBot wins when it can, blocks an immediate loss, and otherwise
+// plays randomly.
+type BlockingBot struct{}
+
+func (BlockingBot) Info() game.StrategyInfo {
+	return game.StrategyInfo{Name: "blocking", Difficulty: 2, Description: "Takes wins and blocks threats"}
+}
+
+func (BlockingBot) ChooseAction(gm game.Match, playerID string) (game.Action, error) {
+	m, err := botView(gm, playerID)
+	if err != nil {
+		return game.Action{}, err
+	}
+	me := m.Turn + 1
+	if m.Misere {
+		// Any cell that doesn't complete our own line is safe.
+		var safe []int
+		for _, cell := range m.emptyCells() {
+			b := m.Board
+			b[cell] = me
+			if !hasLine(b, me) {
+				safe = append(safe, cell)
+			}
+		}
+		if len(safe) > 0 {
+			return moveAction(safe[rand.IntN(len(safe))]), nil
+		}
+		return RandomBot{}.ChooseAction(gm, playerID)
+	}
+	for _, mark := range []int{me, 3 - me} {
+		for _, cell := range m.emptyCells() {
+			b := m.Board
+			b[cell] = mark
+			if hasLine(b, mark) {
+				return moveAction(cell), nil
+			}
+		}
+	}
+	return RandomBot{}.ChooseAction(gm, playerID)
+}
+
+// PerfectBot plays minimax and never loses.
+type PerfectBot struct{}
+
+func (PerfectBot) Info() game.StrategyInfo {
+	return game.StrategyInfo{Name: "perfect", Difficulty: 3, Description: "Never loses"}
+}
+
+func (PerfectBot) ChooseAction(gm game.Match, playerID string) (game.Action, error) {
+	m, err := botView(gm, playerID)
+	if err != nil {
+		return game.Action{}, err
+	}
+	me := m.Turn + 1
+	best, bestScore := -1, -2
+	for _, cell := range m.emptyCells() {
+		b := m.Board
+		b[cell] = me
+		if score := -negamax(b, 3-me, m.Misere); score > bestScore {
+			best, bestScore = cell, score
+		}
+	}
+	return moveAction(best), nil
+}
+
+// negamax scores board b from the perspective of mark, who is to move:
+// 1 = win, 0 = draw, -1 = loss. In misère play the line the opponent just
+// completed loses for them.
+func negamax(b [9]int, mark int, misere bool) int {
+	if hasLine(b, 3-mark) {
+		if misere {
+			return 1
+		}
+		return -1
+	}
+	best, moved := -2, false
+	for cell, v := range b {
+		if v != 0 {
+			continue
+		}
+		moved = true
+		b[cell] = mark
+		if score := -negamax(b, 3-mark, misere); score > best {
+			best = score
+		}
+		b[cell] = 0
+	}
+	if !moved {
+		return 0
+	}
+	return best
+}
+
+// botView checks that it is playerID's move in a tic-tac-toe match.
+func botView(gm game.Match, playerID string) (*Match, error) {
+	m, ok := gm.(*Match)
+	if !ok {
+		return nil, fmt.Errorf("not a tictactoe match")
+	}
+	if m.Done || m.Players[m.Turn] != playerID {
+		return nil, fmt.Errorf("not %s's turn", playerID)
+	}
+	return m, nil
+}
+
+func (m *Match) emptyCells() []int {
+	var cells []int
+	for i, v := range m.Board {
+		if v == 0 {
+			cells = append(cells, i)
+		}
+	}
+	return cells
+}
+
+func hasLine(b [9]int, mark int) bool {
+	for _, line := range winLines {
+		if b[line[0]] == mark && b[line[1]] == mark && b[line[2]] == mark {
+			return true
+		}
+	}
+	return false
+}
+
+func moveAction(cell int) game.Action {
+	payload, _ := json.Marshal(movePayload{Cell: cell})
+	return game.Action{Type: "move", Payload: payload}
+}
@@ -0,0 +1,125 @@
+package tictactoe
+
+import (
+	"encoding/json"
+	"testing"
+
+	"games/internal/game"
+)
+
+func chosenCell(t *testing.T, s game.Strategy, m *Match, playerID string) int {
+	t.Helper()
+	action, err := s.ChooseAction(m, playerID)
+	if err != nil {
+		t.Fatalf("%s: choose action: %v", s.Info().Name, err)
+	}
+	var mv movePayload
+	if err := json.Unmarshal(action.Payload, &mv); err != nil {
+		t.Fatalf("unmarshal move: %v", err)
+	}
+	return mv.Cell
+}
+
+func TestStrategiesChooseValidMoves(t *testing.T) {
+	for _, s := range Strategies() {
+		m := newTestMatch()
+		m.ApplyAction("alice", makeMove(4))
+		cell := chosenCell(t, s, m, "bob")
+		if err := m.Clone().ApplyAction("bob", makeMove(cell)); err != nil {
+			t.Fatalf("%s chose invalid cell %d: %v", s.Info().Name, cell, err)
+		}
+		if _, err := s.ChooseAction(m, "alice"); err == nil {
+			t.Fatalf("%s: expected error choosing out of turn", s.Info().Name)
+		}
+	}
+}
+
+func TestBlockingBotBlocks(t *testing.T) {
+	m := newTestMatch()
+	m.ApplyAction("alice", makeMove(0))
+	m.ApplyAction("bob", makeMove(8))
+	m.ApplyAction("alice", makeMove(1))
+	// alice threatens cell 2
+	if cell := chosenCell(t, BlockingBot{}, m, "bob"); cell != 2 {
+		t.Fatalf("expected block at 2, got %d", cell)
+	}
+}
+
+func TestBlockingBotPrefersWin(t *testing.T) {
+	m := newTestMatch()
+	m.ApplyAction("alice", makeMove(0))
+	m.ApplyAction("bob", makeMove(3))
+	m.ApplyAction("alice", makeMove(1))
+	m.ApplyAction("bob", makeMove(4))
+	m.ApplyAction("alice", makeMove(8))
+	// bob can win at 5 or block at 2
+	if cell := chosenCell(t, BlockingBot{}, m, "bob"); cell != 5 {
+		t.Fatalf("expected win at 5, got %d", cell)
+	}
+}
+
+func TestPerfectBotNeverLoses(t *testing.T) {
+	// Perfect against perfect always draws
+	m := newTestMatch()
+	for !m.Done {
+		pid := m.Players[m.Turn]
+		if err := m.ApplyAction(pid, makeMove(chosenCell(t, PerfectBot{}, m, pid))); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+	}
+	if m.Winner != -1 {
+		t.Fatalf("expected draw between perfect bots, winner %d", m.Winner)
+	}
+
+	// Perfect never loses to random
+	for i := 0; i < 20; i++ {
+		m := newTestMatch()
+		for !m.Done {
+			var s game.Strategy = RandomBot{}
+			if m.Turn == 1 {
+				s = PerfectBot{}
+			}
+			pid := m.Players[m.Turn]
+			m.ApplyAction(pid, makeMove(chosenCell(t, s, m, pid)))
+		}
+		if m.Winner == 0 {
+			t.Fatalf("perfect bot lost to random: %v", m.Board)
+		}
+	}
+}
+
+func newMisereMatch() *Match {
+	g := TicTacToe{}
+	return g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}, Options: map[string]int{"misere": 1}}).(*Match)
+}
+
+func TestBlockingBotAvoidsLineInMisere(t *testing.T) {
+	m := newMisereMatch()
+	m.ApplyAction("alice", makeMove(0))
+	m.ApplyAction("bob", makeMove(4))
+	m.ApplyAction("alice", makeMove(1))
+	m.ApplyAction("bob", makeMove(8))
+	// cell 2 would complete alice's own line
+	for i := 0; i < 20; i++ {
+		if cell := chosenCell(t, BlockingBot{}, m, "alice"); cell == 2 {
+			t.Fatal("blocking bot completed its own line in misère")
+		}
+	}
+}
+
+func TestPerfectBotNeverLosesMisere(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		m := newMisereMatch()
+		for !m.Done {
+			var s game.Strategy = RandomBot{}
+			if m.Turn == 1 {
+				s = PerfectBot{}
+			}
+			pid := m.Players[m.Turn]
+			m.ApplyAction(pid, makeMove(chosenCell(t, s, m, pid)))
+		}
+		if m.Winner == 0 {
+			t.Fatalf("perfect bot lost misère to random: %v", m.Board)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package tictactoe
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"games/internal/game"
 )
@@ -15,34 +17,116 @@ func (t TicTacToe) Info() game.GameInfo {
 		Name:       "tictactoe",
 		MinPlayers: 2,
 		MaxPlayers: 2,
+		Options: []game.Option{
+			{Name: "misere", Description: "Three in a row loses", Default: 0, Min: 0, Max: 1},
+		},
+		Handicaps: []game.Option{
+			{Name: "centre", Description: "Starts with a mark in the centre", Default: 0, Min: 0, Max: 1},
+		},
+		Variants: []game.Variant{
+			{Name: "misere", Description: "Misère: three in a row loses", Options: map[string]int{"misere": 1}},
+		},
 	}
 }
 
+// CheckHandicaps allows only one player to start in the centre.
+func (t TicTacToe) CheckHandicaps(h game.Handicaps) error {
+	centre := 0
+	for _, values := range h {
+		centre += values["centre"]
+	}
+	if centre > 1 {
+		return fmt.Errorf("only one player can start in the centre")
+	}
+	return nil
+}
+
 func (t TicTacToe) NewMatch(config game.MatchConfig) game.Match {
 	m := &Match{
 		Players: [2]string{config.PlayerIDs[0], config.PlayerIDs[1]},
 		Board:   [9]int{},
 		Turn:    0,
+		Misere:  config.Options["misere"] == 1,
+	}
+	for i, id := range m.Players {
+		if config.Handicaps[id]["centre"] == 1 {
+			m.Board[4] = i + 1
+		}
+	}
+	if config.Setup != nil {
+		// validated before the match was made; the setup replaces the board
+		m.Board, m.Turn, _ = parsePosition(config.Setup)
+	}
+	if base := config.Options["clock"]; base > 0 {
+		increment := time.Duration(config.Options["increment"]) * time.Second
+		m.Clock = game.NewClock(m.Players[:], time.Duration(base)*time.Second, increment)
 	}
 	return m
 }
 
+func (t TicTacToe) Achievements() []game.Achievement {
+	return []game.Achievement{
+		{ID: "flawless", Name: "Flawless", Description: "Win with three in a row from your first three marks"},
+	}
+}
+
+// Earned awards "flawless" to a winner whose only marks on the board make
+// the winning line.
+func (t TicTacToe) Earned(gm game.Match, playerID string) []string {
+	m, ok := gm.(*Match)
+	if !ok || !m.Done || m.Misere || m.Winner < 0 || m.Players[m.Winner] != playerID {
+		return nil
+	}
+	mark := m.Winner + 1
+	if _, ok := m.completedLine(mark); !ok {
+		return nil // won on time
+	}
+	marks := 0
+	for _, v := range m.Board {
+		if v == mark {
+			marks++
+		}
+	}
+	if marks != 3 {
+		return nil
+	}
+	return []string{"flawless"}
+}
+
+// TurnOrder swaps X and O when the same two players play again; otherwise
+// the first seat plays X.
+func (t TicTacToe) TurnOrder(players []string, previous *game.PreviousMatch, _ int64) []string {
+	if previous == nil || len(previous.PlayerIDs) != 2 {
+		return players
+	}
+	last := previous.PlayerIDs
+	if !(players[0] == last[0] && players[1] == last[1]) && !(players[0] == last[1] && players[1] == last[0]) {
+		return players
+	}
+	return []string{last[1], last[0]}
+}
+
 // Match implements game.Match for tic-tac-toe.
 type Match struct {
 	Players [2]string `json:"players"`
 	Board   [9]int    `json:"board"` // 0=empty, 1=player0(X), 2=player1(O)
 	Turn    int       `json:"turn"`  // index into Players
 	Done    bool      `json:"done"`
-	Winner  int       `json:"winner"` // -1=draw, 0 or 1=winner index
+	Winner  int       `json:"winner"`           // -1=draw, 0 or 1=winner index
+	Misere  bool      `json:"misere,omitempty"` // completing a line loses
+	// Clock is nil for an untimed match.
+	Clock *game.Clock `json:"clock,omitempty"`
 }
 
 type stateView struct {
-	Board   [9]int   `json:"board"`
-	Turn    string   `json:"turn"`
-	You     int      `json:"you"`    // 1=X, 2=O
-	Players []string `json:"players"`
-	Done    bool     `json:"done"`
-	Winner  string   `json:"winner,omitempty"`
+	Board   [9]int          `json:"board"`
+	Turn    string          `json:"turn"`
+	You     int             `json:"you"` // 1=X, 2=O
+	Players []string        `json:"players"`
+	Done    bool            `json:"done"`
+	Winner  string          `json:"winner,omitempty"`
+	Misere  bool            `json:"misere,omitempty"`
+	Clock   *game.ClockView `json:"clock,omitempty"`
 }
 
 func (m *Match) State(playerID string) any {
@@ -56,6 +140,10 @@ func (m *Match) State(playerID string) any {
 		You:     you + 1,
 		Players: m.Players[:],
 		Done:    m.Done,
+		Misere:  m.Misere,
+	}
+	if m.Clock != nil {
+		view.Clock = m.Clock.View()
 	}
 	if m.Done {
 		if m.Winner == -1 {
@@ -67,6 +155,30 @@ func (m *Match) State(playerID string) any {
 	return view
 }
 
+// Thumbnail is the board as nine characters, row by row: X, O or "."
+// for an empty cell.
+func (m *Match) Thumbnail() any {
+	var b [9]byte
+	for i, v := range m.Board {
+		b[i] = ".XO"[v]
+	}
+	return string(b[:])
+}
+
+// Picture draws X as a cross and O as a ring.
+func (m *Match) Picture() game.Picture {
+	p := game.Picture{Cols: 3, Rows: 3, Cells: make([]game.Mark, 9)}
+	for i, v := range m.Board {
+		switch v {
+		case 1:
+			p.Cells[i] = game.Mark{Shape: game.Cross, Player: 0}
+		case 2:
+			p.Cells[i] = game.Mark{Shape: game.Ring, Player: 1}
+		}
+	}
+	return p
+}
+
 type movePayload struct {
 	Cell int `json:"cell"`
 }
@@ -91,38 +203,226 @@ func (m *Match) ValidActions(playerID string) []game.Action {
 	return actions
 }
 
+// ParseMove reads a cell as a column from a to c and a row from 1 to 3,
+// counting from the top left: "a1" is the corner cell 0 and "b2" the
+// centre.
+func (m *Match) ParseMove(_ string, text string) (game.Action, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if len(text) != 2 || text[0] < 'a' || text[0] > 'c' || text[1] < '1' || text[1] > '3' {
+		return game.Action{}, fmt.Errorf("want a cell such as b2, got %q", text)
+	}
+	payload, _ := json.Marshal(movePayload{Cell: int(text[1]-'1')*3 + int(text[0]-'a')})
+	return game.Action{Type: "move", Payload: payload}, nil
+}
+
+// position is a board set up to start from or to practise: nine
+// characters row by row as in a thumbnail, and the mark to move, X or O.
+// Turn may be left out when the board's counts of marks decide it.
+type position struct {
+	Board string `json:"board"`
+	Turn  string `json:"turn,omitempty"`
+}
+
+// SetPosition sets the board up as position describes. A timed match
+// starts its clocks over, with nobody's time running until the next move.
+func (m *Match) SetPosition(data json.RawMessage) error {
+	board, turn, err := parsePosition(data)
+	if err != nil {
+		return err
+	}
+	m.Board, m.Turn, m.Done, m.Winner = board, turn, false, 0
+	if m.Clock != nil {
+		m.Clock = game.NewClock(m.Players[:], m.Clock.Base, m.Clock.Increment)
+	}
+	return nil
+}
+
+// ValidateSetup accepts the positions SetPosition does.
+func (t TicTacToe) ValidateSetup(setup json.RawMessage) error {
+	_, _, err := parsePosition(setup)
+	return err
+}
+
+// parsePosition reads a position and the index of the player to move. The
+// counts of X and O may differ by one at most, the mark with fewer moves
+// next, and the board must have no line and an empty cell.
+func parsePosition(data json.RawMessage) ([9]int, int, error) {
+	var board [9]int
+	var p position
+	if err := json.Unmarshal(data, &p); err != nil {
+		return board, 0, fmt.Errorf("invalid position: %w", err)
+	}
+	if len(p.Board) != 9 {
+		return board, 0, fmt.Errorf("want a board of nine cells, got %d", len(p.Board))
+	}
+	var counts [3]int
+	for i := range p.Board {
+		v := strings.IndexByte(".XO", p.Board[i])
+		if v < 0 {
+			return board, 0, fmt.Errorf("cell %d is %q, want X, O or .", i, p.Board[i])
+		}
+		board[i] = v
+		counts[v]++
+	}
+	x, o := counts[1], counts[2]
+	turn := 0
+	switch {
+	case x == o+1:
+		turn = 1
+	case x == o:
+	case o == x+1:
+	default:
+		return board, 0, fmt.Errorf("%d X and %d O cannot come from taking turns", x, o)
+	}
+	switch p.Turn {
+	case "":
+	case "X", "O":
+		want := strings.Index("XO", p.Turn)
+		if (want == 0 && x > o) || (want == 1 && o > x) {
+			return board, 0, fmt.Errorf("%s cannot move with more marks than the other side", p.Turn)
+		}
+		turn = want
+	default:
+		return board, 0, fmt.Errorf("turn is %q, want X or O", p.Turn)
+	}
+	set := &Match{Board: board}
+	if _, ok := set.completedLine(1); ok {
+		return board, 0, fmt.Errorf("the board already has a line")
+	}
+	if _, ok := set.completedLine(2); ok {
+		return board, 0, fmt.Errorf("the board already has a line")
+	}
+	if set.boardFull() {
+		return board, 0, fmt.Errorf("the board is full")
+	}
+	return board, turn, nil
+}
+
 func (m *Match) ApplyAction(playerID string, action game.Action) error {
+	_, err := m.ApplyActionWithEvents(playerID, action)
+	return err
+}
+
+// ApplyActionWithEvents applies a move and reports a "placed" event with
+// the cell and mark, then "line" with the completed line's cells or "draw"
+// when the move ends the game.
+func (m *Match) ApplyActionWithEvents(playerID string, action game.Action) ([]game.Event, error) {
 	if m.Done {
-		return fmt.Errorf("game is over")
+		return nil, fmt.Errorf("game is over")
 	}
 	if playerID != m.Players[m.Turn] {
-		return fmt.Errorf("not your turn")
+		return nil, fmt.Errorf("not your turn")
 	}
 	if action.Type != "move" {
-		return fmt.Errorf("unknown action type: %s", action.Type)
+		return nil, fmt.Errorf("unknown action type: %s", action.Type)
 	}
 	var move movePayload
 	if err := json.Unmarshal(action.Payload, &move); err != nil {
-		return fmt.Errorf("invalid move payload: %w", err)
+		return nil, fmt.Errorf("invalid move payload: %w", err)
 	}
 	if move.Cell < 0 || move.Cell > 8 {
-		return fmt.Errorf("cell %d out of range", move.Cell)
+		return nil, fmt.Errorf("cell %d out of range", move.Cell)
 	}
 	if m.Board[move.Cell] != 0 {
-		return fmt.Errorf("cell %d already occupied", move.Cell)
+		return nil, fmt.Errorf("cell %d already occupied", move.Cell)
 	}
 
 	m.Board[move.Cell] = m.Turn + 1 // 1 for X, 2 for O
-	if m.checkWin(m.Turn + 1) {
+	events := []game.Event{{Type: "placed", Data: placedEvent{Cell: move.Cell, Mark: "XO"[m.Turn : m.Turn+1]}}}
+	if line, ok := m.completedLine(m.Turn + 1); ok {
 		m.Done = true
 		m.Winner = m.Turn
+		if m.Misere {
+			m.Winner = 1 - m.Turn
+		}
+		events = append(events, game.Event{Type: "line", Data: lineEvent{Cells: line}})
 	} else if m.boardFull() {
 		m.Done = true
 		m.Winner = -1
+		events = append(events, game.Event{Type: "draw"})
 	} else {
 		m.Turn = 1 - m.Turn
 	}
-	return nil
+	return events, nil
+}
+
+// ApplyActionAt applies a move made at the given time, pressing the clock
+// of a timed match. A move made after the player's time ran out is
+// refused; FlagFall ends the match.
+func (m *Match) ApplyActionAt(playerID string, action game.Action, at time.Time) ([]game.Event, error) {
+	if m.Clock == nil {
+		return m.ApplyActionWithEvents(playerID, action)
+	}
+	if !m.Done && playerID == m.Clock.Running && m.Clock.Remaining(playerID, at) == 0 {
+		return nil, fmt.Errorf("out of time")
+	}
+	events, err := m.ApplyActionWithEvents(playerID, action)
+	if err != nil {
+		return nil, err
+	}
+	next := ""
+	if !m.Done {
+		next = m.Players[m.Turn]
+	}
+	m.Clock.Press(playerID, next, at)
+	return events, nil
+}
+
+// ClockDeadline returns when the player to move runs out of time.
+func (m *Match) ClockDeadline() (time.Time, bool) {
+	if m.Clock == nil || m.Done {
+		return time.Time{}, false
+	}
+	_, deadline, ok := m.Clock.Deadline()
+	return deadline, ok
+}
+
+// FlagFall ends a timed match, lost by the player to move, once their time
+// is gone.
+func (m *Match) FlagFall(at time.Time) bool {
+	if m.Clock == nil || m.Done || !m.Clock.FlagFall(at) {
+		return false
+	}
+	m.Done = true
+	m.Winner = 1 - m.Turn
+	return true
+}
+
+// cellNames say where each cell is, for narration.
+var cellNames = [9]string{
+	"the top left corner", "the top middle", "the top right corner",
+	"the middle left", "the center", "the middle right",
+	"the bottom left corner", "the bottom middle", "the bottom right corner",
+}
+
+// Describe narrates a move from its events: the mark placed and where,
+// and how the move ended the game if it did.
+func (m *Match) Describe(playerID string, _ game.Action, events []game.Event) string {
+	var b strings.Builder
+	for _, e := range events {
+		switch e.Type {
+		case "placed":
+			p := e.Data.(placedEvent)
+			fmt.Fprintf(&b, "%s placed %s in %s", playerID, p.Mark, cellNames[p.Cell])
+		case "line":
+			fmt.Fprintf(&b, ", completing a line. %s wins.", m.Players[m.Winner])
+		case "draw":
+			b.WriteString(", filling the board. The game is a draw.")
+		}
+	}
+	if s := b.String(); s != "" && !strings.HasSuffix(s, ".") {
+		b.WriteString(".")
+	}
+	return b.String()
+}
+
+type placedEvent struct {
+	Cell int    `json:"cell"`
+	Mark string `json:"mark"`
+}
+
+type lineEvent struct {
+	Cells [3]int `json:"cells"`
 }
 
 func (m *Match) IsOver() bool {
@@ -133,17 +433,34 @@ func (m *Match) Results() []game.PlayerResult {
 	if !m.Done {
 		return nil
 	}
+	if m.Clock != nil && m.Clock.Flagged != "" {
+		return game.TimeoutResults(m.Players[:], m.Clock.Flagged)
+	}
 	if m.Winner == -1 {
 		return []game.PlayerResult{
-			{PlayerID: m.Players[0], Rank: 1, Score: 0},
-			{PlayerID: m.Players[1], Rank: 1, Score: 0},
+			{PlayerID: m.Players[0], Rank: 1, Score: 0, Outcome: game.OutcomeDraw},
+			{PlayerID: m.Players[1], Rank: 1, Score: 0, Outcome: game.OutcomeDraw},
 		}
 	}
 	loser := 1 - m.Winner
+	var detail json.RawMessage
+	for mark := 1; mark <= 2; mark++ {
+		if line, ok := m.completedLine(mark); ok {
+			detail, _ = json.Marshal(lineEvent{Cells: line})
+		}
+	}
 	return []game.PlayerResult{
-		{PlayerID: m.Players[m.Winner], Rank: 1, Score: 1},
-		{PlayerID: m.Players[loser], Rank: 2, Score: 0},
+		{PlayerID: m.Players[m.Winner], Rank: 1, Score: 1, Outcome: game.OutcomeWin, Detail: detail},
+		{PlayerID: m.Players[loser], Rank: 2, Score: 0, Outcome: game.OutcomeLoss, Detail: detail},
+	}
+}
+
+func (m *Match) Clone() game.Match {
+	c := *m // all fields but the clock are values
+	if m.Clock != nil {
+		c.Clock = m.Clock.Clone()
 	}
+	return &c
 }
 
 func (m *Match) MarshalJSON() ([]byte, error) {
@@ -162,13 +479,14 @@ var winLines = [][3]int{
 	{0, 4, 8}, {2, 4, 6}, // diags
 }
 
-func (m *Match) checkWin(mark int) bool {
+// completedLine returns a line of three of mark, if the board has one.
+func (m *Match) completedLine(mark int) ([3]int, bool) {
 	for _, line := range winLines {
 		if m.Board[line[0]] == mark && m.Board[line[1]] == mark && m.Board[line[2]] == mark {
-			return true
+			return line, true
 		}
 	}
-	return false
+	return [3]int{}, false
 }
 
 func (m *Match) boardFull() bool {
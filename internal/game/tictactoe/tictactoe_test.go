@@ -3,8 +3,10 @@ package tictactoe
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"games/internal/game"
+	"games/internal/game/gametest"
 )
 
 func newTestMatch() *Match {
@@ -93,6 +95,12 @@ func TestWinDetection(t *testing.T) {
 	if results[1].PlayerID != "bob" || results[1].Rank != 2 {
 		t.Fatalf("expected bob to lose, got %+v", results)
 	}
+	if results[0].Outcome != game.OutcomeWin || results[1].Outcome != game.OutcomeLoss {
+		t.Fatalf("expected win and loss outcomes, got %+v", results)
+	}
+	if string(results[0].Detail) != `{"cells":[0,1,2]}` {
+		t.Fatalf("expected the winning line as detail, got %s", results[0].Detail)
+	}
 }
 
 func TestDraw(t *testing.T) {
@@ -122,6 +130,9 @@ func TestDraw(t *testing.T) {
 	if results[0].Rank != 1 || results[1].Rank != 1 {
 		t.Fatalf("expected draw (both rank 1), got %+v", results)
 	}
+	if results[0].Outcome != game.OutcomeDraw || results[1].Outcome != game.OutcomeDraw {
+		t.Fatalf("expected draw outcomes, got %+v", results)
+	}
 }
 
 func TestStateHidesNothing(t *testing.T) {
@@ -169,6 +180,9 @@ func TestGameInfo(t *testing.T) {
 	if info.MinPlayers != 2 || info.MaxPlayers != 2 {
 		t.Fatalf("expected 2 players, got min=%d max=%d", info.MinPlayers, info.MaxPlayers)
 	}
+	if len(info.Options) != 1 || info.Options[0].Name != "misere" || info.Options[0].Default != 0 {
+		t.Fatalf("expected misere option defaulting off, got %+v", info.Options)
+	}
 }
 
 func TestActionAfterGameOver(t *testing.T) {
@@ -189,3 +203,306 @@ func TestActionAfterGameOver(t *testing.T) {
 		t.Fatal("expected no valid actions after game over")
 	}
 }
+
+func TestCloneIsIndependent(t *testing.T) {
+	m := newTestMatch()
+	m.ApplyAction("alice", makeMove(0))
+
+	c := m.Clone().(*Match)
+	c.ApplyAction("bob", makeMove(4))
+
+	if m.Board[4] != 0 {
+		t.Fatal("move on clone leaked into original")
+	}
+	if m.Turn != 1 || c.Turn != 0 {
+		t.Fatalf("expected turns 1 and 0, got %d and %d", m.Turn, c.Turn)
+	}
+}
+
+func TestMisereLineLoses(t *testing.T) {
+	m := newMisereMatch()
+	m.ApplyAction("alice", makeMove(0))
+	m.ApplyAction("bob", makeMove(3))
+	m.ApplyAction("alice", makeMove(1))
+	m.ApplyAction("bob", makeMove(4))
+	m.ApplyAction("alice", makeMove(2))
+
+	if !m.IsOver() {
+		t.Fatal("game should be over")
+	}
+	if results := m.Results(); results[0].PlayerID != "bob" || results[0].Rank != 1 {
+		t.Fatalf("expected bob to win misère, got %+v", results)
+	}
+}
+
+func TestApplyActionEvents(t *testing.T) {
+	m := newTestMatch()
+	events, err := game.Apply(m, "alice", makeMove(0))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(events) != 1 || events[0].Data != (placedEvent{Cell: 0, Mark: "X"}) {
+		t.Fatalf("expected X placed at 0, got %+v", events)
+	}
+	m.ApplyAction("bob", makeMove(3))
+	m.ApplyAction("alice", makeMove(1))
+	m.ApplyAction("bob", makeMove(4))
+	events, _ = game.Apply(m, "alice", makeMove(2))
+	if len(events) != 2 || events[1].Type != "line" || events[1].Data != (lineEvent{Cells: [3]int{0, 1, 2}}) {
+		t.Fatalf("expected the top row reported, got %+v", events)
+	}
+	if _, err := game.Apply(m, "bob", makeMove(5)); err == nil {
+		t.Fatal("expected error after game over")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	m := newTestMatch()
+	narrate := func(player string, cell int) string {
+		t.Helper()
+		events, err := game.Apply(m, player, makeMove(cell))
+		if err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		return game.Describe(m, player, makeMove(cell), events)
+	}
+	if got := narrate("alice", 4); got != "alice placed X in the center." {
+		t.Fatalf("unexpected narration %q", got)
+	}
+	if got := narrate("bob", 0); got != "bob placed O in the top left corner." {
+		t.Fatalf("unexpected narration %q", got)
+	}
+	narrate("alice", 3)
+	narrate("bob", 1)
+	if got := narrate("alice", 5); got != "alice placed X in the middle right, completing a line. alice wins." {
+		t.Fatalf("unexpected narration %q", got)
+	}
+}
+
+func TestTurnOrderSwapsOnRematch(t *testing.T) {
+	g := TicTacToe{}
+	players := []string{"alice", "bob"}
+	if got := game.TurnOrder(g, players, nil, 0); got[0] != "alice" {
+		t.Fatalf("expected the first seat to play X, got %v", got)
+	}
+	previous := &game.PreviousMatch{PlayerIDs: []string{"alice", "bob"}}
+	if got := game.TurnOrder(g, players, previous, 0); got[0] != "bob" || got[1] != "alice" {
+		t.Fatalf("expected colors to swap, got %v", got)
+	}
+	previous.PlayerIDs = []string{"bob", "alice"}
+	if got := game.TurnOrder(g, players, previous, 0); got[0] != "alice" {
+		t.Fatalf("expected colors to swap back, got %v", got)
+	}
+	previous.PlayerIDs = []string{"alice", "carol"}
+	if got := game.TurnOrder(g, players, previous, 0); got[0] != "alice" {
+		t.Fatalf("expected seat order against a new opponent, got %v", got)
+	}
+}
+
+func FuzzApplyAction(f *testing.F) {
+	gametest.FuzzApplyAction(f, TicTacToe{}, []string{"alice", "bob"})
+}
+
+func TestThumbnail(t *testing.T) {
+	m := newTestMatch()
+	m.ApplyAction("alice", makeMove(4))
+	m.ApplyAction("bob", makeMove(0))
+	if got := game.Thumbnail(m); got != "O...X...." {
+		t.Errorf("expected O...X...., got %v", got)
+	}
+}
+
+func TestPicture(t *testing.T) {
+	m := newTestMatch()
+	m.ApplyAction("alice", makeMove(4))
+	m.ApplyAction("bob", makeMove(0))
+	p := m.Picture()
+	if p.Cols != 3 || p.Rows != 3 || len(p.Cells) != 9 {
+		t.Fatalf("expected a 3x3 picture, got %+v", p)
+	}
+	if p.Cells[4] != (game.Mark{Shape: game.Cross, Player: 0}) || p.Cells[0] != (game.Mark{Shape: game.Ring, Player: 1}) || p.Cells[8] != (game.Mark{}) {
+		t.Errorf("unexpected cells %+v", p.Cells)
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	m := newTestMatch()
+	for text, cell := range map[string]int{"a1": 0, "c1": 2, "b2": 4, " A3 ": 6, "C3": 8} {
+		action, err := game.ParseMove(m, "alice", text)
+		if err != nil {
+			t.Errorf("%q: %v", text, err)
+			continue
+		}
+		var mp movePayload
+		json.Unmarshal(action.Payload, &mp)
+		if action.Type != "move" || mp.Cell != cell {
+			t.Errorf("%q: expected cell %d, got %+v", text, cell, action)
+		}
+	}
+	for _, text := range []string{"", "d1", "a4", "a0", "b22", "22"} {
+		if _, err := game.ParseMove(m, "alice", text); err == nil {
+			t.Errorf("expected %q refused", text)
+		}
+	}
+}
+
+func TestBlitzClock(t *testing.T) {
+	g := Blitz{}
+	if info := g.Info(); info.Name != "blitz" || len(info.Options) != 3 {
+		t.Fatalf("expected blitz with misere, clock and increment options, got %+v", info)
+	}
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}, Options: map[string]int{"clock": 10, "increment": 2}}).(*Match)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := m.ClockDeadline(); ok {
+		t.Fatal("expected no clock running before the first move")
+	}
+	if _, err := m.ApplyActionAt("alice", makeMove(4), start); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ApplyActionAt("bob", makeMove(0), start.Add(3*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Clock.Banks["bob"]; got != 9*time.Second {
+		t.Fatalf("expected bob to have 10s - 3s + 2s, got %v", got)
+	}
+	deadline, ok := m.ClockDeadline()
+	if want := start.Add(3*time.Second + 12*time.Second); !ok || !deadline.Equal(want) {
+		t.Fatalf("expected alice's deadline at %v, got %v %v", want, deadline, ok)
+	}
+
+	data, _ := m.MarshalJSON()
+	var restored Match
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Clock.Banks["alice"] != 12*time.Second || restored.Clock.Running != "alice" {
+		t.Fatalf("expected the clock to survive a round trip, got %+v", restored.Clock)
+	}
+
+	if _, err := m.ApplyActionAt("alice", makeMove(8), deadline); err == nil {
+		t.Fatal("expected a move after the flag fell to be refused")
+	}
+	if m.FlagFall(deadline.Add(-time.Millisecond)) {
+		t.Fatal("expected no flag fall before the deadline")
+	}
+	if !m.FlagFall(deadline) || !m.IsOver() {
+		t.Fatal("expected the flag to fall at the deadline")
+	}
+	results := m.Results()
+	if results[0].PlayerID != "alice" || results[0].Outcome != game.OutcomeTimeout || results[1].Outcome != game.OutcomeWin {
+		t.Fatalf("expected alice to lose on time, got %+v", results)
+	}
+	if view := m.State("bob").(stateView); view.Winner != "bob" || view.Clock.Flagged != "alice" {
+		t.Fatalf("expected the state to show bob winning on time, got %+v", view)
+	}
+}
+
+func TestCentreHandicap(t *testing.T) {
+	g := TicTacToe{}
+	if _, err := game.ResolveHandicaps(g, game.Handicaps{"alice": {"centre": 1}, "bob": {"centre": 1}}); err == nil {
+		t.Fatal("expected two players in the centre refused")
+	}
+	h, err := game.ResolveHandicaps(g, game.Handicaps{"alice": {}, "bob": {"centre": 1}})
+	if err != nil || len(h) != 1 {
+		t.Fatalf("expected only bob's handicap, got %v %v", h, err)
+	}
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}, Handicaps: h}).(*Match)
+	if m.Board[4] != 2 || m.Turn != 0 {
+		t.Fatalf("expected O in the centre with X to move, got %+v", m)
+	}
+	if err := m.ApplyAction("alice", makeMove(4)); err == nil {
+		t.Fatal("expected the centre taken")
+	}
+}
+
+func TestFlawless(t *testing.T) {
+	g := TicTacToe{}
+	m := newTestMatch()
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		m.ApplyAction(m.Players[i%2], makeMove(cell))
+	}
+	if got := g.Earned(m, "alice"); len(got) != 1 || got[0] != "flawless" {
+		t.Fatalf("expected alice flawless, got %v", got)
+	}
+	if got := g.Earned(m, "bob"); got != nil {
+		t.Fatalf("expected nothing for the loser, got %v", got)
+	}
+
+	// A fourth mark means a move that did not count
+	m = newTestMatch()
+	for i, cell := range []int{0, 3, 8, 4, 1, 6, 2} {
+		m.ApplyAction(m.Players[i%2], makeMove(cell))
+	}
+	if !m.IsOver() || m.Players[m.Winner] != "alice" {
+		t.Fatalf("expected alice to win, got %+v", m)
+	}
+	if got := g.Earned(m, "alice"); got != nil {
+		t.Fatalf("expected a four-mark win not flawless, got %v", got)
+	}
+}
+
+func TestSetPosition(t *testing.T) {
+	m := newTestMatch()
+	if err := game.SetPosition(m, json.RawMessage(`{"board":"X...O...X"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if m.Board != [9]int{1, 0, 0, 0, 2, 0, 0, 0, 1} || m.Turn != 1 || m.Done {
+		t.Fatalf("unexpected match %+v", m)
+	}
+	if err := m.ApplyAction("bob", makeMove(2)); err != nil {
+		t.Fatalf("O should move next: %v", err)
+	}
+	if err := game.SetPosition(m, json.RawMessage(`{"board":"....O....","turn":"X"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if m.Turn != 0 {
+		t.Errorf("expected X to move, got turn %d", m.Turn)
+	}
+	for _, bad := range []string{
+		`{"board":"XX......."}`,
+		`{"board":"XXXOO...."}`,
+		`{"board":"XOXXOOOXX"}`,
+		`{"board":"X..O"}`,
+		`{"board":"X...O...Z"}`,
+		`{"board":"X........","turn":"X"}`,
+		`{"board":".........","turn":"Y"}`,
+	} {
+		if err := game.SetPosition(newTestMatch(), json.RawMessage(bad)); err == nil {
+			t.Errorf("expected %s refused", bad)
+		}
+	}
+}
+
+func TestSetupStartsMatch(t *testing.T) {
+	g := TicTacToe{}
+	if err := game.ValidateSetup(g, json.RawMessage(`{"board":"XXX......"}`)); err == nil {
+		t.Fatal("expected a finished setup refused")
+	}
+	setup := json.RawMessage(`{"board":"X...O....","turn":"X"}`)
+	if err := game.ValidateSetup(g, setup); err != nil {
+		t.Fatal(err)
+	}
+	h := game.Handicaps{"bob": {"centre": 1}}
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}, Handicaps: h, Setup: setup}).(*Match)
+	if m.Board != [9]int{1, 0, 0, 0, 2, 0, 0, 0, 0} || m.Turn != 0 {
+		t.Fatalf("expected the match to start from the setup, got %+v", m)
+	}
+	if err := m.ApplyAction("alice", makeMove(8)); err != nil {
+		t.Fatalf("X should move first: %v", err)
+	}
+}
+
+func TestSetPositionRestartsClock(t *testing.T) {
+	m := Blitz{}.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}, Options: map[string]int{"clock": 60, "increment": 2}}).(*Match)
+	start := time.Now()
+	if _, err := m.ApplyActionAt("alice", makeMove(4), start.Add(10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetPosition(json.RawMessage(`{"board":"........."}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.ClockDeadline(); ok || m.Clock.Banks["alice"] != 60*time.Second {
+		t.Errorf("expected fresh clocks, got %+v", m.Clock)
+	}
+}
@@ -0,0 +1,78 @@
+package game
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// VariantOption is the option holding a session's variant: 0 for the
+// game's standard rules, or one more than the variant's index in the
+// game's info. Games declaring variants get it without listing it.
+const VariantOption = "variant"
+
+// Variant is a named way to play a game, such as misère tic-tac-toe: a
+// set of option values chosen together, listed under its game in the
+// lobby rather than as a game of its own.
+type Variant struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Options     map[string]int `json:"options"`
+}
+
+// optionSpecs returns the options a session of the game may choose: its
+// own, and VariantOption when it declares variants.
+func optionSpecs(info GameInfo) []Option {
+	if len(info.Variants) == 0 {
+		return info.Options
+	}
+	return append(slices.Clone(info.Options), Option{
+		Name:        VariantOption,
+		Description: "Variant of the rules",
+		Default:     0,
+		Min:         0,
+		Max:         len(info.Variants),
+	})
+}
+
+// VariantNamed returns the value of VariantOption that chooses the named
+// variant of the game.
+func VariantNamed(info GameInfo, name string) (int, error) {
+	i := slices.IndexFunc(info.Variants, func(v Variant) bool { return v.Name == name })
+	if i < 0 {
+		return 0, fmt.Errorf("%s has no variant %q", info.Name, name)
+	}
+	return i + 1, nil
+}
+
+// ResolveVariant fills in the option values fixed by the variant that
+// requested options choose, returning the options to resolve. A value
+// the request gives that differs from the variant's is an error.
+func ResolveVariant(info GameInfo, requested map[string]int) (map[string]int, error) {
+	n, ok := requested[VariantOption]
+	if !ok || n == 0 || len(info.Variants) == 0 {
+		return requested, nil
+	}
+	if n < 0 || n > len(info.Variants) {
+		return nil, fmt.Errorf("option %q must be between 0 and %d", VariantOption, len(info.Variants))
+	}
+	v := info.Variants[n-1]
+	resolved := maps.Clone(requested)
+	for name, value := range v.Options {
+		if given, ok := requested[name]; ok && given != value {
+			return nil, fmt.Errorf("variant %q plays with option %q at %d", v.Name, name, value)
+		}
+		resolved[name] = value
+	}
+	return resolved, nil
+}
+
+// VariantOf returns the variant resolved options choose, or nil for the
+// game's standard rules.
+func VariantOf(info GameInfo, options map[string]int) *Variant {
+	n := options[VariantOption]
+	if n <= 0 || n > len(info.Variants) {
+		return nil
+	}
+	return &info.Variants[n-1]
+}
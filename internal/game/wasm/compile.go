@@ -0,0 +1,200 @@
+package wasm
+
+import "encoding/binary"
+
+// instr is one instruction with its immediates decoded. Instructions
+// after the 0xfc prefix take opcodes from 0x100.
+//
+// A block, loop or if keeps in a the index of its else, if it has one,
+// and in b the index of its end; an else keeps its end in b. Their c
+// holds how many values they take from the stack in its high half and
+// how many they leave in its low half.
+type instr struct {
+	op   uint16
+	a, b uint32
+	c    uint64
+}
+
+const (
+	opUnreachable  = 0x00
+	opNop          = 0x01
+	opBlock        = 0x02
+	opLoop         = 0x03
+	opIf           = 0x04
+	opElse         = 0x05
+	opEnd          = 0x0b
+	opBr           = 0x0c
+	opBrIf         = 0x0d
+	opBrTable      = 0x0e
+	opReturn       = 0x0f
+	opCall         = 0x10
+	opCallIndirect = 0x11
+	opDrop         = 0x1a
+	opSelect       = 0x1b
+	opSelectTyped  = 0x1c
+	opLocalGet     = 0x20
+	opLocalSet     = 0x21
+	opLocalTee     = 0x22
+	opGlobalGet    = 0x23
+	opGlobalSet    = 0x24
+	opI32Load      = 0x28
+	opI64Store32   = 0x3e
+	opMemorySize   = 0x3f
+	opMemoryGrow   = 0x40
+	opI32Const     = 0x41
+	opI64Const     = 0x42
+	opF32Const     = 0x43
+	opF64Const     = 0x44
+	opNumericFirst = 0x45 // i32.eqz
+	opNumericLast  = 0xc4 // i64.extend32_s
+
+	opPrefix       = 0xfc
+	opTruncSatLast = 0x107 // i64.trunc_sat_f64_u
+	opMemoryInit   = 0x108
+	opDataDrop     = 0x109
+	opMemoryCopy   = 0x10a
+	opMemoryFill   = 0x10b
+)
+
+// function reads a function's locals and body, compiling them into f.
+func (r *reader) function(m *Module, f *function) {
+	t := m.types[f.typ]
+	locals := len(t.params)
+	n := r.count()
+	for range n {
+		k := r.u32()
+		r.valType()
+		if uint64(locals)+uint64(k) > maxLocals {
+			r.fail("more than %d locals", maxLocals)
+		}
+		locals += int(k)
+		f.locals += int(k)
+	}
+
+	var open []int // blocks not yet ended, innermost last
+	for {
+		pc := len(f.code)
+		in := instr{op: uint16(r.byte())}
+		switch op := in.op; {
+		case op == opBlock || op == opLoop || op == opIf:
+			p, res := r.blockType(m)
+			in.c = uint64(p)<<32 | uint64(res)
+			open = append(open, pc)
+		case op == opElse:
+			if len(open) == 0 || f.code[open[len(open)-1]].op != opIf || f.code[open[len(open)-1]].a != 0 {
+				r.fail("else without if")
+			}
+			f.code[open[len(open)-1]].a = uint32(pc)
+		case op == opEnd:
+			if len(open) == 0 {
+				f.code = append(f.code, in)
+				if r.pos != len(r.b) {
+					r.fail("code after the end of a function")
+				}
+				return
+			}
+			start := &f.code[open[len(open)-1]]
+			start.b = uint32(pc)
+			if start.a != 0 {
+				f.code[start.a].b = uint32(pc)
+			}
+			open = open[:len(open)-1]
+		case op == opBr || op == opBrIf:
+			in.a = r.u32()
+			if int(in.a) > len(open) {
+				r.fail("unknown label %d", in.a)
+			}
+		case op == opBrTable:
+			targets := make([]uint32, r.count()+1)
+			for i := range targets {
+				if targets[i] = r.u32(); int(targets[i]) > len(open) {
+					r.fail("unknown label %d", targets[i])
+				}
+			}
+			in.a = uint32(len(f.tables))
+			f.tables = append(f.tables, targets)
+		case op == opCall:
+			if in.a = r.u32(); int(in.a) >= len(m.funcs) {
+				r.fail("unknown function %d", in.a)
+			}
+		case op == opCallIndirect:
+			if in.a = r.u32(); int(in.a) >= len(m.types) {
+				r.fail("unknown type %d", in.a)
+			}
+			if r.u32() != 0 || m.table == nil {
+				r.fail("unknown table")
+			}
+		case op == opSelectTyped:
+			if r.count() != 1 {
+				r.fail("select with other than one type")
+			}
+			r.valType()
+			in.op = opSelect
+		case op >= opLocalGet && op <= opLocalTee:
+			if in.a = r.u32(); int(in.a) >= locals {
+				r.fail("unknown local %d", in.a)
+			}
+		case op == opGlobalGet || op == opGlobalSet:
+			if in.a = r.u32(); int(in.a) >= len(m.globals) {
+				r.fail("unknown global %d", in.a)
+			}
+			if op == opGlobalSet && !m.globals[in.a].mutable {
+				r.fail("global %d is immutable", in.a)
+			}
+		case op >= opI32Load && op <= opI64Store32:
+			r.needMemory(m)
+			r.u32() // alignment, a hint
+			in.a = r.u32()
+		case op == opMemorySize || op == opMemoryGrow:
+			r.needMemory(m)
+			if r.byte() != 0 {
+				r.fail("unknown memory")
+			}
+		case op == opI32Const:
+			in.c = uint64(uint32(r.sleb(32)))
+		case op == opI64Const:
+			in.c = uint64(r.sleb(64))
+		case op == opF32Const:
+			in.c = uint64(binary.LittleEndian.Uint32(r.bytes(4)))
+		case op == opF64Const:
+			in.c = binary.LittleEndian.Uint64(r.bytes(8))
+		case op == opPrefix:
+			in.op = 0x100 + uint16(r.u32())
+			switch in.op {
+			case opMemoryInit:
+				r.needMemory(m)
+				in.a = r.u32()
+				if r.byte() != 0 {
+					r.fail("unknown memory")
+				}
+			case opDataDrop:
+				in.a = r.u32()
+			case opMemoryCopy:
+				r.needMemory(m)
+				if r.byte() != 0 || r.byte() != 0 {
+					r.fail("unknown memory")
+				}
+			case opMemoryFill:
+				r.needMemory(m)
+				if r.byte() != 0 {
+					r.fail("unknown memory")
+				}
+			default:
+				if in.op > opTruncSatLast {
+					r.fail("unsupported instruction 0xfc %d", in.op-0x100)
+				}
+			}
+		case op == opUnreachable || op == opNop || op == opReturn || op == opDrop || op == opSelect:
+		case op >= opNumericFirst && op <= opNumericLast:
+		default:
+			r.fail("unsupported instruction 0x%02x", op)
+		}
+		f.code = append(f.code, in)
+	}
+}
+
+func (r *reader) needMemory(m *Module) {
+	if m.memory == nil {
+		r.fail("memory instruction without a memory")
+	}
+}
@@ -0,0 +1,889 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"runtime"
+)
+
+// PageSize is the size of a page of linear memory, the unit memory grows
+// by.
+const PageSize = 64 << 10
+
+// What an instance may use beyond its limits, so a module cannot exhaust
+// the server's stack or memory by recursing. The locals of the calls
+// under way count against the memory limit as well.
+const (
+	maxCallDepth = 10_000
+	maxStack     = 1 << 20 // values
+	localSize    = 8       // bytes a local takes, whatever its type
+)
+
+var (
+	// ErrOutOfFuel ends a call that ran more instructions than its
+	// instance's limit allows.
+	ErrOutOfFuel = errors.New("out of fuel")
+	// ErrMemoryLimit is returned for a module that needs more memory to
+	// start than its instance's limit allows, and ends a call whose
+	// nested calls' locals need more.
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+
+	errUnreachable      = errors.New("unreachable executed")
+	errOutOfBounds      = errors.New("out of bounds memory access")
+	errDivideByZero     = errors.New("integer divide by zero")
+	errIntegerOverflow  = errors.New("integer overflow")
+	errConversion       = errors.New("invalid conversion to integer")
+	errUndefinedElement = errors.New("undefined element")
+	errUninitialized    = errors.New("uninitialized element")
+	errIndirectType     = errors.New("indirect call type mismatch")
+	errCallStack        = errors.New("call stack exhausted")
+)
+
+// trap carries a run-time error out of the interpreter to the call that
+// started it.
+type trap struct {
+	err error
+}
+
+// Limits bound what one instance may use. A zero field takes its default.
+type Limits struct {
+	Fuel   int64 // instructions one call may run, DefaultFuel by default
+	Memory int   // bytes of linear memory, in whole pages; DefaultMemory by default
+}
+
+// The default Limits.
+const (
+	DefaultFuel   = 50_000_000
+	DefaultMemory = 64 << 20
+)
+
+// Instance is a module's memory, globals and table, ready to call. Its
+// methods must not be called concurrently.
+type Instance struct {
+	m        *Module
+	stdio    Stdio
+	fuel     int64 // per call
+	left     int64 // of the call under way
+	maxPages uint32
+	mem      []byte
+	globals  []uint64
+	table    []uint32 // function index + 1, or 0 where uninitialized
+	dropped  []bool   // data segments
+	stack    []uint64
+	depth    int
+	slots    int // locals the calls under way may still take
+	maxSlots int // locals the calls under way may take in all
+}
+
+// Instantiate gives m its own memory, globals and table and runs its
+// start function, if it has one. The instance's WASI standard streams
+// are stdio.
+func (m *Module) Instantiate(l Limits, stdio Stdio) (*Instance, error) {
+	if l.Fuel <= 0 {
+		l.Fuel = DefaultFuel
+	}
+	if l.Memory <= 0 {
+		l.Memory = DefaultMemory
+	}
+	in := &Instance{
+		m:        m,
+		stdio:    stdio,
+		fuel:     l.Fuel,
+		maxPages: uint32(min(l.Memory/PageSize, maxPages)),
+		maxSlots: l.Memory / localSize,
+		globals:  make([]uint64, len(m.globals)),
+		dropped:  make([]bool, len(m.data)),
+	}
+	if m.memory != nil {
+		if m.memory.min > in.maxPages {
+			return nil, fmt.Errorf("module needs %d bytes of memory: %w", int(m.memory.min)*PageSize, ErrMemoryLimit)
+		}
+		if m.memory.hasMax {
+			in.maxPages = min(in.maxPages, m.memory.max)
+		}
+		in.mem = make([]byte, int(m.memory.min)*PageSize)
+	}
+	for i, g := range m.globals {
+		in.globals[i] = g.init
+	}
+	if m.table != nil {
+		in.table = make([]uint32, m.table.min)
+	}
+	for _, seg := range m.elems {
+		if uint64(seg.offset)+uint64(len(seg.funcs)) > uint64(len(in.table)) {
+			return nil, errors.New("element segment out of bounds")
+		}
+		for i, f := range seg.funcs {
+			in.table[int(seg.offset)+i] = f + 1
+		}
+	}
+	for i, seg := range m.data {
+		if !seg.active {
+			continue
+		}
+		if uint64(seg.offset)+uint64(len(seg.data)) > uint64(len(in.mem)) {
+			return nil, errors.New("data segment out of bounds")
+		}
+		copy(in.mem[seg.offset:], seg.data)
+		in.dropped[i] = true
+	}
+	if m.start >= 0 {
+		if err := in.invoke(uint32(m.start), nil); err != nil {
+			return nil, fmt.Errorf("start: %w", err)
+		}
+	}
+	return in, nil
+}
+
+// Call runs the exported function name with args, which must match its
+// parameters, and returns its results. Values of every type travel as
+// their bits: an i32 in the low half of a uint64, a float as from
+// math.Float64bits or math.Float32bits. A trap ends the call with an
+// error, leaving the instance as it stood.
+func (in *Instance) Call(name string, args ...uint64) ([]uint64, error) {
+	e, ok := in.m.exports[name]
+	if !ok || e.kind != exportFunc {
+		return nil, fmt.Errorf("no exported function %q", name)
+	}
+	if t := in.m.types[in.m.funcs[e.index].typ]; len(args) != len(t.params) {
+		return nil, fmt.Errorf("%s takes %d arguments, not %d", name, len(t.params), len(args))
+	}
+	if err := in.invoke(e.index, args); err != nil {
+		return nil, err
+	}
+	return append([]uint64(nil), in.stack...), nil
+}
+
+// Refuel gives the call under way its full fuel again. A host that feeds
+// a module one request after another through stdin refuels as it hands
+// each over, so the limit applies to each request rather than to the
+// call that serves them all.
+func (in *Instance) Refuel() {
+	in.left = in.fuel
+}
+
+// Memory returns the instance's linear memory. It is valid until the next
+// call, which may grow it.
+func (in *Instance) Memory() []byte {
+	return in.mem
+}
+
+// invoke runs function f with args, leaving its results on the stack.
+func (in *Instance) invoke(f uint32, args []uint64) (err error) {
+	in.left, in.depth, in.slots = in.fuel, 0, in.maxSlots
+	in.stack = append(in.stack[:0], args...)
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case trap:
+				err = p.err
+			case runtime.Error:
+				// Only a module the decoder let through without
+				// validating gets here, by using values it never
+				// pushed.
+				err = fmt.Errorf("invalid module: %v", p)
+			default:
+				panic(p)
+			}
+			in.stack = in.stack[:0]
+		}
+	}()
+	in.call(f)
+	return nil
+}
+
+// call runs function f, taking its arguments from the stack and leaving
+// its results there.
+func (in *Instance) call(f uint32) {
+	fn := &in.m.funcs[f]
+	t := &in.m.types[fn.typ]
+	in.depth++
+	if in.depth > maxCallDepth || len(in.stack) > maxStack {
+		panic(trap{errCallStack})
+	}
+	n := len(in.stack) - len(t.params)
+	if fn.host != nil {
+		errno := fn.host(in, in.stack[n:])
+		in.stack = in.stack[:n]
+		if len(t.results) > 0 {
+			in.pushI32(errno)
+		}
+		in.depth--
+		return
+	}
+	size := len(t.params) + fn.locals
+	if size > in.slots {
+		panic(trap{ErrMemoryLimit})
+	}
+	in.slots -= size
+	locals := make([]uint64, size)
+	copy(locals, in.stack[n:])
+	in.stack = in.stack[:n]
+	in.exec(fn, locals)
+	in.slots += size
+	// A return may leave values under the results.
+	results := len(in.stack) - len(t.results)
+	copy(in.stack[n:], in.stack[results:])
+	in.stack = in.stack[:n+len(t.results)]
+	in.depth--
+}
+
+// label is where a branch goes: back to the start of a loop, or past the
+// end of a block, taking arity values along.
+type label struct {
+	cont   int
+	loop   bool
+	arity  int
+	height int
+}
+
+// branch unwinds the stack to the label depth blocks out and returns the
+// instruction before the one to continue at, with the labels still open.
+// It returns -1 for a branch out of the function.
+func (in *Instance) branch(labels []label, depth int) (int, []label) {
+	if depth == len(labels) {
+		return -1, nil
+	}
+	l := labels[len(labels)-1-depth]
+	n := len(in.stack)
+	copy(in.stack[l.height:], in.stack[n-l.arity:])
+	in.stack = in.stack[:l.height+l.arity]
+	if l.loop {
+		return l.cont, labels[:len(labels)-depth]
+	}
+	return l.cont, labels[:len(labels)-1-depth]
+}
+
+func (in *Instance) push(v uint64) {
+	in.stack = append(in.stack, v)
+}
+
+func (in *Instance) pop() uint64 {
+	v := in.stack[len(in.stack)-1]
+	in.stack = in.stack[:len(in.stack)-1]
+	return v
+}
+
+func (in *Instance) pushI32(v uint32) { in.push(uint64(v)) }
+func (in *Instance) popI32() uint32   { return uint32(in.pop()) }
+
+func (in *Instance) pushF32(f float32) { in.push(uint64(math.Float32bits(f))) }
+func (in *Instance) popF32() float32   { return math.Float32frombits(uint32(in.pop())) }
+
+func (in *Instance) pushF64(f float64) { in.push(math.Float64bits(f)) }
+func (in *Instance) popF64() float64   { return math.Float64frombits(in.pop()) }
+
+func (in *Instance) pushBool(b bool) {
+	if b {
+		in.push(1)
+	} else {
+		in.push(0)
+	}
+}
+
+// addr pops an address and returns where in memory size bytes at offset
+// from it start, trapping if any fall outside.
+func (in *Instance) addr(offset uint32, size int) int {
+	a := uint64(in.popI32()) + uint64(offset)
+	if a+uint64(size) > uint64(len(in.mem)) {
+		panic(trap{errOutOfBounds})
+	}
+	return int(a)
+}
+
+// span checks that n bytes from a lie within a buffer of length size.
+func span(a, n uint32, size int) {
+	if uint64(a)+uint64(n) > uint64(size) {
+		panic(trap{errOutOfBounds})
+	}
+}
+
+func (in *Instance) exec(f *function, locals []uint64) {
+	code := f.code
+	var labels []label
+	for pc := 0; pc < len(code); pc++ {
+		in.left--
+		if in.left < 0 {
+			panic(trap{ErrOutOfFuel})
+		}
+		ins := &code[pc]
+		switch ins.op {
+		case opUnreachable:
+			panic(trap{errUnreachable})
+		case opNop:
+		case opBlock, opLoop, opIf:
+			params, results := int(ins.c>>32), int(uint32(ins.c))
+			if ins.op == opIf && in.popI32() == 0 {
+				if ins.a != 0 {
+					pc = int(ins.a) // into the else branch
+				} else {
+					pc = int(ins.b) - 1 // to the end
+				}
+			}
+			l := label{cont: int(ins.b), arity: results, height: len(in.stack) - params}
+			if ins.op == opLoop {
+				l = label{cont: pc, loop: true, arity: params, height: len(in.stack) - params}
+			}
+			labels = append(labels, l)
+		case opElse:
+			// The then branch is done.
+			pc = int(ins.b) - 1
+		case opEnd:
+			if len(labels) == 0 {
+				return
+			}
+			labels = labels[:len(labels)-1]
+		case opBr:
+			if pc, labels = in.branch(labels, int(ins.a)); pc < 0 {
+				return
+			}
+		case opBrIf:
+			if in.popI32() != 0 {
+				if pc, labels = in.branch(labels, int(ins.a)); pc < 0 {
+					return
+				}
+			}
+		case opBrTable:
+			targets := f.tables[ins.a]
+			i := min(int(in.popI32()), len(targets)-1)
+			if pc, labels = in.branch(labels, int(targets[i])); pc < 0 {
+				return
+			}
+		case opReturn:
+			return
+		case opCall:
+			in.call(ins.a)
+		case opCallIndirect:
+			i := in.popI32()
+			if int(i) >= len(in.table) {
+				panic(trap{errUndefinedElement})
+			}
+			fn := in.table[i]
+			if fn == 0 {
+				panic(trap{errUninitialized})
+			}
+			if !in.m.types[in.m.funcs[fn-1].typ].equal(in.m.types[ins.a]) {
+				panic(trap{errIndirectType})
+			}
+			in.call(fn - 1)
+
+		case opDrop:
+			in.pop()
+		case opSelect:
+			c, b, a := in.popI32(), in.pop(), in.pop()
+			if c != 0 {
+				in.push(a)
+			} else {
+				in.push(b)
+			}
+		case opLocalGet:
+			in.push(locals[ins.a])
+		case opLocalSet:
+			locals[ins.a] = in.pop()
+		case opLocalTee:
+			locals[ins.a] = in.stack[len(in.stack)-1]
+		case opGlobalGet:
+			in.push(in.globals[ins.a])
+		case opGlobalSet:
+			in.globals[ins.a] = in.pop()
+
+		case opI32Const, opI64Const, opF32Const, opF64Const:
+			in.push(ins.c)
+
+		case opMemorySize:
+			in.pushI32(uint32(len(in.mem) / PageSize))
+		case opMemoryGrow:
+			n, pages := in.popI32(), uint32(len(in.mem)/PageSize)
+			if uint64(pages)+uint64(n) > uint64(in.maxPages) {
+				in.pushI32(math.MaxUint32)
+				break
+			}
+			in.mem = append(in.mem, make([]byte, int(n)*PageSize)...)
+			in.pushI32(pages)
+		case opMemoryInit:
+			n, s, d := in.popI32(), in.popI32(), in.popI32()
+			var data []byte
+			if !in.dropped[ins.a] {
+				data = in.m.data[ins.a].data
+			}
+			span(s, n, len(data))
+			span(d, n, len(in.mem))
+			copy(in.mem[d:], data[s:s+n])
+		case opDataDrop:
+			in.dropped[ins.a] = true
+		case opMemoryCopy:
+			n, s, d := in.popI32(), in.popI32(), in.popI32()
+			span(s, n, len(in.mem))
+			span(d, n, len(in.mem))
+			copy(in.mem[d:], in.mem[s:s+n])
+		case opMemoryFill:
+			n, v, d := in.popI32(), in.popI32(), in.popI32()
+			span(d, n, len(in.mem))
+			b := in.mem[d : d+n]
+			for i := range b {
+				b[i] = byte(v)
+			}
+
+		default:
+			switch {
+			case ins.op >= opI32Load && ins.op <= opI64Store32:
+				in.memory(ins)
+			case ins.op >= 0x100:
+				in.truncSat(ins.op)
+			default:
+				in.numeric(ins.op)
+			}
+		}
+	}
+}
+
+// memory runs a load or store.
+func (in *Instance) memory(ins *instr) {
+	le := binary.LittleEndian
+	switch ins.op {
+	case 0x28: // i32.load
+		in.pushI32(le.Uint32(in.mem[in.addr(ins.a, 4):]))
+	case 0x29: // i64.load
+		in.push(le.Uint64(in.mem[in.addr(ins.a, 8):]))
+	case 0x2a: // f32.load
+		in.pushI32(le.Uint32(in.mem[in.addr(ins.a, 4):]))
+	case 0x2b: // f64.load
+		in.push(le.Uint64(in.mem[in.addr(ins.a, 8):]))
+	case 0x2c: // i32.load8_s
+		in.pushI32(uint32(int8(in.mem[in.addr(ins.a, 1)])))
+	case 0x2d: // i32.load8_u
+		in.pushI32(uint32(in.mem[in.addr(ins.a, 1)]))
+	case 0x2e: // i32.load16_s
+		in.pushI32(uint32(int16(le.Uint16(in.mem[in.addr(ins.a, 2):]))))
+	case 0x2f: // i32.load16_u
+		in.pushI32(uint32(le.Uint16(in.mem[in.addr(ins.a, 2):])))
+	case 0x30: // i64.load8_s
+		in.push(uint64(int8(in.mem[in.addr(ins.a, 1)])))
+	case 0x31: // i64.load8_u
+		in.push(uint64(in.mem[in.addr(ins.a, 1)]))
+	case 0x32: // i64.load16_s
+		in.push(uint64(int16(le.Uint16(in.mem[in.addr(ins.a, 2):]))))
+	case 0x33: // i64.load16_u
+		in.push(uint64(le.Uint16(in.mem[in.addr(ins.a, 2):])))
+	case 0x34: // i64.load32_s
+		in.push(uint64(int32(le.Uint32(in.mem[in.addr(ins.a, 4):]))))
+	case 0x35: // i64.load32_u
+		in.push(uint64(le.Uint32(in.mem[in.addr(ins.a, 4):])))
+	case 0x36, 0x38: // i32.store, f32.store
+		v := in.pop()
+		le.PutUint32(in.mem[in.addr(ins.a, 4):], uint32(v))
+	case 0x37, 0x39: // i64.store, f64.store
+		v := in.pop()
+		le.PutUint64(in.mem[in.addr(ins.a, 8):], v)
+	case 0x3a, 0x3c: // i32.store8, i64.store8
+		v := in.pop()
+		in.mem[in.addr(ins.a, 1)] = byte(v)
+	case 0x3b, 0x3d: // i32.store16, i64.store16
+		v := in.pop()
+		le.PutUint16(in.mem[in.addr(ins.a, 2):], uint16(v))
+	case 0x3e: // i64.store32
+		v := in.pop()
+		le.PutUint32(in.mem[in.addr(ins.a, 4):], uint32(v))
+	}
+}
+
+// numeric runs an instruction from i32.eqz to i64.extend32_s.
+func (in *Instance) numeric(op uint16) {
+	switch op {
+	case 0x45: // i32.eqz
+		in.pushBool(in.popI32() == 0)
+	case 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f:
+		b, a := in.popI32(), in.popI32()
+		in.pushBool(compare(op-0x46, uint64(a), uint64(b), int64(int32(a)), int64(int32(b))))
+	case 0x50: // i64.eqz
+		in.pushBool(in.pop() == 0)
+	case 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5a:
+		b, a := in.pop(), in.pop()
+		in.pushBool(compare(op-0x51, a, b, int64(a), int64(b)))
+	case 0x5b, 0x5c, 0x5d, 0x5e, 0x5f, 0x60:
+		b, a := in.popF32(), in.popF32()
+		in.pushBool(compareFloat(op-0x5b, float64(a), float64(b)))
+	case 0x61, 0x62, 0x63, 0x64, 0x65, 0x66:
+		b, a := in.popF64(), in.popF64()
+		in.pushBool(compareFloat(op-0x61, a, b))
+
+	case 0x67: // i32.clz
+		in.pushI32(uint32(bits.LeadingZeros32(in.popI32())))
+	case 0x68: // i32.ctz
+		in.pushI32(uint32(bits.TrailingZeros32(in.popI32())))
+	case 0x69: // i32.popcnt
+		in.pushI32(uint32(bits.OnesCount32(in.popI32())))
+	case 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70, 0x71, 0x72, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78:
+		b, a := in.popI32(), in.popI32()
+		in.pushI32(binaryI32(op, a, b))
+	case 0x79: // i64.clz
+		in.push(uint64(bits.LeadingZeros64(in.pop())))
+	case 0x7a: // i64.ctz
+		in.push(uint64(bits.TrailingZeros64(in.pop())))
+	case 0x7b: // i64.popcnt
+		in.push(uint64(bits.OnesCount64(in.pop())))
+	case 0x7c, 0x7d, 0x7e, 0x7f, 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a:
+		b, a := in.pop(), in.pop()
+		in.push(binaryI64(op, a, b))
+
+	case 0x8b: // f32.abs
+		in.pushI32(in.popI32() &^ (1 << 31))
+	case 0x8c: // f32.neg
+		in.pushI32(in.popI32() ^ (1 << 31))
+	case 0x8d, 0x8e, 0x8f, 0x90, 0x91:
+		in.pushF32(float32(unaryFloat(op-0x8d, float64(in.popF32()))))
+	case 0x92, 0x93, 0x94, 0x95:
+		b, a := in.popF32(), in.popF32()
+		switch op {
+		case 0x92:
+			in.pushF32(a + b)
+		case 0x93:
+			in.pushF32(a - b)
+		case 0x94:
+			in.pushF32(a * b)
+		case 0x95:
+			in.pushF32(a / b)
+		}
+	case 0x96: // f32.min
+		b, a := in.popF32(), in.popF32()
+		in.pushF32(float32(math.Min(float64(a), float64(b))))
+	case 0x97: // f32.max
+		b, a := in.popF32(), in.popF32()
+		in.pushF32(float32(math.Max(float64(a), float64(b))))
+	case 0x98: // f32.copysign
+		b, a := in.popI32(), in.popI32()
+		in.pushI32(a&^(1<<31) | b&(1<<31))
+	case 0x99: // f64.abs
+		in.push(in.pop() &^ (1 << 63))
+	case 0x9a: // f64.neg
+		in.push(in.pop() ^ (1 << 63))
+	case 0x9b, 0x9c, 0x9d, 0x9e, 0x9f:
+		in.pushF64(unaryFloat(op-0x9b, in.popF64()))
+	case 0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5:
+		b, a := in.popF64(), in.popF64()
+		switch op {
+		case 0xa0:
+			in.pushF64(a + b)
+		case 0xa1:
+			in.pushF64(a - b)
+		case 0xa2:
+			in.pushF64(a * b)
+		case 0xa3:
+			in.pushF64(a / b)
+		case 0xa4:
+			in.pushF64(math.Min(a, b))
+		case 0xa5:
+			in.pushF64(math.Max(a, b))
+		}
+	case 0xa6: // f64.copysign
+		b, a := in.pop(), in.pop()
+		in.push(a&^(1<<63) | b&(1<<63))
+
+	case 0xa7: // i32.wrap_i64
+		in.pushI32(uint32(in.pop()))
+	case 0xa8: // i32.trunc_f32_s
+		in.pushI32(uint32(int32(trunc(float64(in.popF32()), -1<<31, 1<<31))))
+	case 0xa9: // i32.trunc_f32_u
+		in.pushI32(uint32(trunc(float64(in.popF32()), 0, 1<<32)))
+	case 0xaa: // i32.trunc_f64_s
+		in.pushI32(uint32(int32(trunc(in.popF64(), -1<<31, 1<<31))))
+	case 0xab: // i32.trunc_f64_u
+		in.pushI32(uint32(trunc(in.popF64(), 0, 1<<32)))
+	case 0xac: // i64.extend_i32_s
+		in.push(uint64(int32(in.popI32())))
+	case 0xad: // i64.extend_i32_u
+		in.push(uint64(in.popI32()))
+	case 0xae: // i64.trunc_f32_s
+		in.push(uint64(int64(trunc(float64(in.popF32()), -1<<63, 1<<63))))
+	case 0xaf: // i64.trunc_f32_u
+		in.push(truncU64(trunc(float64(in.popF32()), 0, 1<<64)))
+	case 0xb0: // i64.trunc_f64_s
+		in.push(uint64(int64(trunc(in.popF64(), -1<<63, 1<<63))))
+	case 0xb1: // i64.trunc_f64_u
+		in.push(truncU64(trunc(in.popF64(), 0, 1<<64)))
+	case 0xb2: // f32.convert_i32_s
+		in.pushF32(float32(int32(in.popI32())))
+	case 0xb3: // f32.convert_i32_u
+		in.pushF32(float32(in.popI32()))
+	case 0xb4: // f32.convert_i64_s
+		in.pushF32(float32(int64(in.pop())))
+	case 0xb5: // f32.convert_i64_u
+		in.pushF32(float32(in.pop()))
+	case 0xb6: // f32.demote_f64
+		in.pushF32(float32(in.popF64()))
+	case 0xb7: // f64.convert_i32_s
+		in.pushF64(float64(int32(in.popI32())))
+	case 0xb8: // f64.convert_i32_u
+		in.pushF64(float64(in.popI32()))
+	case 0xb9: // f64.convert_i64_s
+		in.pushF64(float64(int64(in.pop())))
+	case 0xba: // f64.convert_i64_u
+		in.pushF64(float64(in.pop()))
+	case 0xbb: // f64.promote_f32
+		in.pushF64(float64(in.popF32()))
+	case 0xbc, 0xbd, 0xbe, 0xbf:
+		// Reinterpretations: the bits stay as they are.
+
+	case 0xc0: // i32.extend8_s
+		in.pushI32(uint32(int8(in.popI32())))
+	case 0xc1: // i32.extend16_s
+		in.pushI32(uint32(int16(in.popI32())))
+	case 0xc2: // i64.extend8_s
+		in.push(uint64(int8(in.pop())))
+	case 0xc3: // i64.extend16_s
+		in.push(uint64(int16(in.pop())))
+	case 0xc4: // i64.extend32_s
+		in.push(uint64(int32(in.pop())))
+	}
+}
+
+// compare runs the integer comparison i places after eq: eq, ne, lt_s,
+// lt_u, gt_s, gt_u, le_s, le_u, ge_s, ge_u.
+func compare(i uint16, a, b uint64, sa, sb int64) bool {
+	switch i {
+	case 0:
+		return a == b
+	case 1:
+		return a != b
+	case 2:
+		return sa < sb
+	case 3:
+		return a < b
+	case 4:
+		return sa > sb
+	case 5:
+		return a > b
+	case 6:
+		return sa <= sb
+	case 7:
+		return a <= b
+	case 8:
+		return sa >= sb
+	default:
+		return a >= b
+	}
+}
+
+// compareFloat runs the float comparison i places after eq: eq, ne, lt,
+// gt, le, ge.
+func compareFloat(i uint16, a, b float64) bool {
+	switch i {
+	case 0:
+		return a == b
+	case 1:
+		return a != b
+	case 2:
+		return a < b
+	case 3:
+		return a > b
+	case 4:
+		return a <= b
+	default:
+		return a >= b
+	}
+}
+
+// unaryFloat runs the rounding i places after ceil: ceil, floor, trunc,
+// nearest, sqrt. Each rounds a float32 exactly in float64 arithmetic.
+func unaryFloat(i uint16, x float64) float64 {
+	switch i {
+	case 0:
+		return math.Ceil(x)
+	case 1:
+		return math.Floor(x)
+	case 2:
+		return math.Trunc(x)
+	case 3:
+		return math.RoundToEven(x)
+	default:
+		return math.Sqrt(x)
+	}
+}
+
+func binaryI32(op uint16, a, b uint32) uint32 {
+	switch op {
+	case 0x6a:
+		return a + b
+	case 0x6b:
+		return a - b
+	case 0x6c:
+		return a * b
+	case 0x6d: // div_s
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int32(a) == math.MinInt32 && int32(b) == -1 {
+			panic(trap{errIntegerOverflow})
+		}
+		return uint32(int32(a) / int32(b))
+	case 0x6e: // div_u
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return a / b
+	case 0x6f: // rem_s
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int32(b) == -1 {
+			return 0
+		}
+		return uint32(int32(a) % int32(b))
+	case 0x70: // rem_u
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return a % b
+	case 0x71:
+		return a & b
+	case 0x72:
+		return a | b
+	case 0x73:
+		return a ^ b
+	case 0x74:
+		return a << (b & 31)
+	case 0x75:
+		return uint32(int32(a) >> (b & 31))
+	case 0x76:
+		return a >> (b & 31)
+	case 0x77:
+		return bits.RotateLeft32(a, int(b&31))
+	default: // rotr
+		return bits.RotateLeft32(a, -int(b&31))
+	}
+}
+
+func binaryI64(op uint16, a, b uint64) uint64 {
+	switch op {
+	case 0x7c:
+		return a + b
+	case 0x7d:
+		return a - b
+	case 0x7e:
+		return a * b
+	case 0x7f: // div_s
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int64(a) == math.MinInt64 && int64(b) == -1 {
+			panic(trap{errIntegerOverflow})
+		}
+		return uint64(int64(a) / int64(b))
+	case 0x80: // div_u
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return a / b
+	case 0x81: // rem_s
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int64(b) == -1 {
+			return 0
+		}
+		return uint64(int64(a) % int64(b))
+	case 0x82: // rem_u
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return a % b
+	case 0x83:
+		return a & b
+	case 0x84:
+		return a | b
+	case 0x85:
+		return a ^ b
+	case 0x86:
+		return a << (b & 63)
+	case 0x87:
+		return uint64(int64(a) >> (b & 63))
+	case 0x88:
+		return a >> (b & 63)
+	case 0x89:
+		return bits.RotateLeft64(a, int(b&63))
+	default: // rotr
+		return bits.RotateLeft64(a, -int(b&63))
+	}
+}
+
+// trunc truncates x toward zero, trapping unless the result lies in
+// [lo, hi).
+func trunc(x, lo, hi float64) float64 {
+	if math.IsNaN(x) {
+		panic(trap{errConversion})
+	}
+	t := math.Trunc(x)
+	if t < lo || t >= hi {
+		panic(trap{errIntegerOverflow})
+	}
+	return t
+}
+
+// truncU64 converts a float in [0, 2^64) to the integer.
+func truncU64(t float64) uint64 {
+	if t >= 1<<63 {
+		return uint64(t-(1<<63)) | 1<<63
+	}
+	return uint64(t)
+}
+
+// truncSat runs a saturating conversion, from i32.trunc_sat_f32_s to
+// i64.trunc_sat_f64_u.
+func (in *Instance) truncSat(op uint16) {
+	switch op - 0x100 {
+	case 0:
+		in.pushI32(uint32(int32(sat(float64(in.popF32()), math.MinInt32, math.MaxInt32))))
+	case 1:
+		in.pushI32(uint32(sat(float64(in.popF32()), 0, math.MaxUint32)))
+	case 2:
+		in.pushI32(uint32(int32(sat(in.popF64(), math.MinInt32, math.MaxInt32))))
+	case 3:
+		in.pushI32(uint32(sat(in.popF64(), 0, math.MaxUint32)))
+	case 4:
+		in.push(satI64(float64(in.popF32())))
+	case 5:
+		in.push(satU64(float64(in.popF32())))
+	case 6:
+		in.push(satI64(in.popF64()))
+	case 7:
+		in.push(satU64(in.popF64()))
+	}
+}
+
+// sat truncates x toward zero, clamped to [lo, hi], with NaN as zero.
+func sat(x, lo, hi float64) float64 {
+	switch {
+	case math.IsNaN(x):
+		return 0
+	case x <= lo:
+		return lo
+	case x >= hi:
+		return hi
+	}
+	return math.Trunc(x)
+}
+
+func satI64(x float64) uint64 {
+	switch {
+	case math.IsNaN(x):
+		return 0
+	case x < -1<<63:
+		return 1 << 63
+	case x >= 1<<63:
+		return math.MaxInt64
+	}
+	return uint64(int64(x))
+}
+
+func satU64(x float64) uint64 {
+	switch {
+	case math.IsNaN(x) || x <= -1:
+		return 0
+	case x >= 1<<64:
+		return math.MaxUint64
+	}
+	return truncU64(math.Trunc(x))
+}
@@ -0,0 +1,525 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// What a module may declare, so decoding one cannot make the server
+// allocate without bound.
+const (
+	maxLocals    = 50_000  // per function, parameters included
+	maxTableSize = 1 << 20 // elements
+	maxPages     = 1 << 16 // the 4 GiB of a 32-bit address space
+)
+
+type valType byte
+
+const (
+	typeI32 valType = 0x7f
+	typeI64 valType = 0x7e
+	typeF32 valType = 0x7d
+	typeF64 valType = 0x7c
+)
+
+type funcType struct {
+	params, results []valType
+}
+
+func (t funcType) equal(u funcType) bool {
+	return slices.Equal(t.params, u.params) && slices.Equal(t.results, u.results)
+}
+
+// function is a function's body compiled for the interpreter, or a
+// function of the host the module imports.
+type function struct {
+	typ    uint32
+	locals int // declared beyond the parameters
+	code   []instr
+	tables [][]uint32 // br_table targets, the default last
+	host   hostFunc   // for an import
+}
+
+type global struct {
+	mutable bool
+	init    uint64
+}
+
+type limits struct {
+	min, max uint32
+	hasMax   bool
+}
+
+type export struct {
+	kind  byte
+	index uint32
+}
+
+const (
+	exportFunc   = 0
+	exportTable  = 1
+	exportMemory = 2
+	exportGlobal = 3
+)
+
+type elemSegment struct {
+	offset uint32
+	funcs  []uint32
+}
+
+type dataSegment struct {
+	active bool
+	offset uint32
+	data   []byte
+}
+
+// Module is a decoded WebAssembly module, ready to instantiate any number
+// of times.
+type Module struct {
+	types    []funcType
+	funcs    []function // imports first
+	imported int
+	table    *limits // nil without a table
+	memory   *limits // nil without a memory
+	globals  []global
+	exports  map[string]export
+	start    int // -1 without a start function
+	elems    []elemSegment
+	data     []dataSegment
+}
+
+// formatError is a malformed or unsupported module, raised while
+// decoding it and returned by Compile.
+type formatError struct {
+	msg string
+}
+
+func (e formatError) Error() string {
+	return e.msg
+}
+
+type reader struct {
+	b   []byte
+	pos int
+}
+
+func (r *reader) fail(format string, args ...any) {
+	panic(formatError{fmt.Sprintf(format, args...)})
+}
+
+func (r *reader) byte() byte {
+	if r.pos >= len(r.b) {
+		r.fail("unexpected end of module")
+	}
+	b := r.b[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *reader) bytes(n int) []byte {
+	if n < 0 || n > len(r.b)-r.pos {
+		r.fail("unexpected end of module")
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// uleb reads an unsigned LEB128 integer of at most bits bits.
+func (r *reader) uleb(bits uint) uint64 {
+	var v uint64
+	var shift uint
+	for {
+		b := r.byte()
+		v |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+		if shift >= bits {
+			r.fail("integer too long")
+		}
+	}
+	if bits < 64 && v >= 1<<bits {
+		r.fail("integer too large")
+	}
+	return v
+}
+
+// sleb reads a signed LEB128 integer of at most bits bits.
+func (r *reader) sleb(bits uint) int64 {
+	var v int64
+	var shift uint
+	var b byte
+	for {
+		b = r.byte()
+		v |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+		if shift >= bits {
+			r.fail("integer too long")
+		}
+	}
+	if shift < 64 && b&0x40 != 0 {
+		v |= -1 << shift
+	}
+	return v
+}
+
+func (r *reader) u32() uint32 {
+	return uint32(r.uleb(32))
+}
+
+// count reads the length of a vector, each element of which takes at
+// least one byte.
+func (r *reader) count() int {
+	n := r.u32()
+	if int(n) > len(r.b)-r.pos {
+		r.fail("vector longer than its section")
+	}
+	return int(n)
+}
+
+func (r *reader) name() string {
+	s := r.bytes(r.count())
+	if !utf8.Valid(s) {
+		r.fail("name is not UTF-8")
+	}
+	return string(s)
+}
+
+func (r *reader) valType() valType {
+	switch t := valType(r.byte()); t {
+	case typeI32, typeI64, typeF32, typeF64:
+		return t
+	default:
+		r.fail("unsupported value type 0x%x", byte(t))
+		return 0
+	}
+}
+
+func (r *reader) valTypes() []valType {
+	types := make([]valType, r.count())
+	for i := range types {
+		types[i] = r.valType()
+	}
+	return types
+}
+
+func (r *reader) limits(most uint32) *limits {
+	var l limits
+	switch flags := r.byte(); flags {
+	case 0x00:
+		l.min = r.u32()
+	case 0x01:
+		l.min, l.max, l.hasMax = r.u32(), r.u32(), true
+		if l.max < l.min {
+			r.fail("maximum size below the minimum")
+		}
+	default:
+		r.fail("unsupported limits 0x%x", flags)
+	}
+	if l.min > most {
+		r.fail("size %d over the limit of %d", l.min, most)
+	}
+	return &l
+}
+
+// constExpr reads an initializer: a constant, or an earlier global.
+func (r *reader) constExpr(m *Module) uint64 {
+	var v uint64
+	switch op := r.byte(); op {
+	case 0x41:
+		v = uint64(uint32(r.sleb(32)))
+	case 0x42:
+		v = uint64(r.sleb(64))
+	case 0x43:
+		v = uint64(binary.LittleEndian.Uint32(r.bytes(4)))
+	case 0x44:
+		v = binary.LittleEndian.Uint64(r.bytes(8))
+	case 0x23:
+		i := r.u32()
+		if int(i) >= len(m.globals) {
+			r.fail("unknown global %d", i)
+		}
+		v = m.globals[i].init
+	default:
+		r.fail("unsupported initializer 0x%x", op)
+	}
+	if r.byte() != 0x0b {
+		r.fail("initializer is not a single constant")
+	}
+	return v
+}
+
+// Compile decodes a module in the WebAssembly binary format. Modules may
+// use the core instructions along with sign extension, saturating
+// conversions, multiple results and bulk memory. They may import only
+// functions of WASI preview 1, which gives them their standard streams,
+// a clock and random numbers but refuses files and everything else.
+func Compile(bin []byte) (m *Module, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			fe, ok := p.(formatError)
+			if !ok {
+				panic(p)
+			}
+			m, err = nil, fe
+		}
+	}()
+	if len(bin) < 8 || string(bin[:4]) != "\x00asm" {
+		return nil, errors.New("not a WebAssembly module")
+	}
+	if v := binary.LittleEndian.Uint32(bin[4:8]); v != 1 {
+		return nil, fmt.Errorf("unsupported WebAssembly version %d", v)
+	}
+
+	m = &Module{exports: make(map[string]export), start: -1}
+	r := &reader{b: bin, pos: 8}
+	var last int
+	var code bool
+	for r.pos < len(r.b) {
+		id := r.byte()
+		sec := &reader{b: r.bytes(int(r.u32()))}
+		if id != 0 {
+			if sectionOrder(id) <= last {
+				r.fail("section %d out of order", id)
+			}
+			last = sectionOrder(id)
+		}
+		switch id {
+		case 0: // custom, such as names and debug info
+			continue
+		case 1:
+			m.types = make([]funcType, sec.count())
+			for i := range m.types {
+				if sec.byte() != 0x60 {
+					sec.fail("malformed function type")
+				}
+				m.types[i] = funcType{params: sec.valTypes(), results: sec.valTypes()}
+			}
+		case 2:
+			n := sec.count()
+			for range n {
+				m.funcs = append(m.funcs, sec.importFunc(m))
+			}
+			m.imported = len(m.funcs)
+		case 3:
+			n := sec.count()
+			for range n {
+				t := sec.u32()
+				if int(t) >= len(m.types) {
+					sec.fail("unknown type %d", t)
+				}
+				m.funcs = append(m.funcs, function{typ: t})
+			}
+		case 4:
+			switch sec.count() {
+			case 0:
+			case 1:
+				if sec.byte() != 0x70 {
+					sec.fail("unsupported table element type")
+				}
+				m.table = sec.limits(maxTableSize)
+			default:
+				sec.fail("multiple tables")
+			}
+		case 5:
+			switch sec.count() {
+			case 0:
+			case 1:
+				m.memory = sec.limits(maxPages)
+			default:
+				sec.fail("multiple memories")
+			}
+		case 6:
+			n := sec.count()
+			for range n {
+				sec.valType()
+				mut := sec.byte()
+				if mut > 1 {
+					sec.fail("malformed global mutability")
+				}
+				m.globals = append(m.globals, global{mutable: mut == 1, init: sec.constExpr(m)})
+			}
+		case 7:
+			n := sec.count()
+			for range n {
+				name := sec.name()
+				e := export{kind: sec.byte(), index: sec.u32()}
+				if _, ok := m.exports[name]; ok {
+					sec.fail("duplicate export %q", name)
+				}
+				m.exports[name] = e
+			}
+		case 8:
+			i := sec.u32()
+			if int(i) >= len(m.funcs) {
+				sec.fail("unknown start function %d", i)
+			}
+			m.start = int(i)
+		case 9:
+			n := sec.count()
+			for range n {
+				m.elems = append(m.elems, sec.elemSegment(m)...)
+			}
+		case 10:
+			if sec.count() != len(m.funcs)-m.imported {
+				sec.fail("function and code sections differ in length")
+			}
+			code = true
+			for i := m.imported; i < len(m.funcs); i++ {
+				body := &reader{b: sec.bytes(int(sec.u32()))}
+				body.function(m, &m.funcs[i])
+			}
+		case 11:
+			n := sec.count()
+			for range n {
+				m.data = append(m.data, sec.dataSegment(m))
+			}
+		case 12:
+			sec.u32()
+		default:
+			r.fail("unknown section %d", id)
+		}
+		if sec.pos != len(sec.b) {
+			r.fail("section %d is longer than its contents", id)
+		}
+	}
+	if len(m.funcs) > m.imported && !code {
+		r.fail("functions without code")
+	}
+	for name, e := range m.exports {
+		var n int
+		switch e.kind {
+		case exportFunc:
+			n = len(m.funcs)
+		case exportTable:
+			n = present(m.table)
+		case exportMemory:
+			n = present(m.memory)
+		case exportGlobal:
+			n = len(m.globals)
+		default:
+			r.fail("export %q of unknown kind %d", name, e.kind)
+		}
+		if int(e.index) >= n {
+			r.fail("export %q of an unknown index %d", name, e.index)
+		}
+	}
+	return m, nil
+}
+
+// sectionOrder ranks the sections in the order they must come, the data
+// count between the elements and the code.
+func sectionOrder(id byte) int {
+	if id == 12 {
+		return 95
+	}
+	return int(id) * 10
+}
+
+func present(l *limits) int {
+	if l == nil {
+		return 0
+	}
+	return 1
+}
+
+// importFunc reads an import, which must be a WASI function.
+func (r *reader) importFunc(m *Module) function {
+	module, name := r.name(), r.name()
+	if kind := r.byte(); kind != exportFunc || module != wasiModule {
+		r.fail("import %s.%s: only WASI functions may be imported", module, name)
+	}
+	t := r.u32()
+	if int(t) >= len(m.types) {
+		r.fail("unknown type %d", t)
+	}
+	host, ok := wasiFunc(name, m.types[t])
+	if !ok {
+		r.fail("import %s.%s has the wrong type", module, name)
+	}
+	return function{typ: t, host: host}
+}
+
+// elemSegment reads an element segment, returning it when it fills the
+// table as the module starts. Passive and declared segments serve
+// instructions this package does not run, so they are dropped.
+func (r *reader) elemSegment(m *Module) []elemSegment {
+	flags := r.u32()
+	var seg elemSegment
+	switch flags {
+	case 0:
+		seg.offset = uint32(r.constExpr(m))
+	case 1, 3:
+		if r.byte() != 0x00 {
+			r.fail("unsupported element kind")
+		}
+	case 2:
+		if r.u32() != 0 {
+			r.fail("unknown table")
+		}
+		seg.offset = uint32(r.constExpr(m))
+		if r.byte() != 0x00 {
+			r.fail("unsupported element kind")
+		}
+	default:
+		r.fail("unsupported element segment %d", flags)
+	}
+	seg.funcs = make([]uint32, r.count())
+	for i := range seg.funcs {
+		if seg.funcs[i] = r.u32(); int(seg.funcs[i]) >= len(m.funcs) {
+			r.fail("unknown function %d", seg.funcs[i])
+		}
+	}
+	if flags&1 != 0 {
+		return nil
+	}
+	if m.table == nil {
+		r.fail("element segment without a table")
+	}
+	return []elemSegment{seg}
+}
+
+func (r *reader) dataSegment(m *Module) dataSegment {
+	var seg dataSegment
+	switch flags := r.u32(); flags {
+	case 0:
+		seg.active, seg.offset = true, uint32(r.constExpr(m))
+	case 1:
+	case 2:
+		if r.u32() != 0 {
+			r.fail("unknown memory")
+		}
+		seg.active, seg.offset = true, uint32(r.constExpr(m))
+	default:
+		r.fail("unsupported data segment %d", flags)
+	}
+	if seg.active && m.memory == nil {
+		r.fail("data segment without a memory")
+	}
+	seg.data = r.bytes(r.count())
+	return seg
+}
+
+// blockType reads the type of a block as how many values it takes from
+// the stack and how many it leaves.
+func (r *reader) blockType(m *Module) (params, results int) {
+	switch t := r.sleb(33); {
+	case t == -64: // empty
+		return 0, 0
+	case t >= -4 && t <= -1:
+		return 0, 1
+	case t >= 0 && t < int64(len(m.types)):
+		return len(m.types[t].params), len(m.types[t].results)
+	default:
+		r.fail("unknown block type %d", t)
+		return 0, 0
+	}
+}
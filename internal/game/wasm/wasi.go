@@ -0,0 +1,293 @@
+package wasm
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// wasiModule is the module WASI preview 1 functions are imported from.
+const wasiModule = "wasi_snapshot_preview1"
+
+// WASI error numbers.
+const (
+	errnoSuccess = 0
+	errnoBadf    = 8
+	errnoFault   = 21
+	errnoInval   = 28
+	errnoIO      = 29
+	errnoNosys   = 52
+)
+
+// hostFunc is a WASI function, returning its error number. Its arguments
+// are the module's, as on the stack.
+type hostFunc func(in *Instance, args []uint64) uint32
+
+// Stdio is what an instance reads and writes as its standard streams. A
+// nil reader is empty and a nil writer discards.
+type Stdio struct {
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+}
+
+// ExitError is a module's call to proc_exit, which ends the call under
+// way.
+type ExitError struct {
+	Status uint32
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exited with status %d", e.Status)
+}
+
+// wasiFuncs are the WASI functions a module may use, by name, with their
+// parameters as i for i32 and I for i64. Each returns an error number
+// but proc_exit, which does not return. Modules have no arguments or
+// environment and no directories, so no files.
+var wasiFuncs = map[string]struct {
+	params string
+	fn     hostFunc
+}{
+	"args_get":            {"ii", wasiNone},
+	"args_sizes_get":      {"ii", wasiSizes},
+	"environ_get":         {"ii", wasiNone},
+	"environ_sizes_get":   {"ii", wasiSizes},
+	"clock_time_get":      {"iIi", wasiClockTimeGet},
+	"random_get":          {"ii", wasiRandomGet},
+	"fd_read":             {"iiii", wasiFdRead},
+	"fd_write":            {"iiii", wasiFdWrite},
+	"fd_close":            {"i", wasiStdFd},
+	"fd_fdstat_get":       {"ii", wasiFdstatGet},
+	"fd_fdstat_set_flags": {"ii", wasiStdFd},
+	"fd_prestat_get":      {"ii", wasiBadf},
+	"fd_prestat_dir_name": {"iii", wasiBadf},
+	"poll_oneoff":         {"iiii", wasiPollOneoff},
+	"sched_yield":         {"", wasiNone},
+	"proc_exit":           {"i", wasiProcExit},
+}
+
+// wasiFunc returns the function a module imports by name with type t.
+// Those not in wasiFuncs fail with ENOSYS.
+func wasiFunc(name string, t funcType) (hostFunc, bool) {
+	f, ok := wasiFuncs[name]
+	if !ok {
+		return func(*Instance, []uint64) uint32 { return errnoNosys }, t.equal(funcType{params: t.params, results: []valType{typeI32}})
+	}
+	params := make([]valType, len(f.params))
+	for i, c := range f.params {
+		params[i] = typeI32
+		if c == 'I' {
+			params[i] = typeI64
+		}
+	}
+	results := []valType{typeI32}
+	if name == "proc_exit" {
+		results = nil
+	}
+	return f.fn, t.equal(funcType{params: params, results: results})
+}
+
+// region returns n bytes of memory from p, or false if they fall outside
+// it.
+func (in *Instance) region(p, n uint32) ([]byte, bool) {
+	if uint64(p)+uint64(n) > uint64(len(in.mem)) {
+		return nil, false
+	}
+	return in.mem[p : p+n], true
+}
+
+func (in *Instance) putU32(p uint32, v uint32) bool {
+	b, ok := in.region(p, 4)
+	if ok {
+		binary.LittleEndian.PutUint32(b, v)
+	}
+	return ok
+}
+
+func (in *Instance) putU64(p uint32, v uint64) bool {
+	b, ok := in.region(p, 8)
+	if ok {
+		binary.LittleEndian.PutUint64(b, v)
+	}
+	return ok
+}
+
+// iovecs returns the buffers of the n iovecs at p.
+func (in *Instance) iovecs(p, n uint32) ([][]byte, bool) {
+	if n > math.MaxUint32/8 {
+		return nil, false
+	}
+	vecs, ok := in.region(p, n*8)
+	if !ok {
+		return nil, false
+	}
+	bufs := make([][]byte, n)
+	for i := range bufs {
+		v := vecs[i*8:]
+		if bufs[i], ok = in.region(binary.LittleEndian.Uint32(v), binary.LittleEndian.Uint32(v[4:])); !ok {
+			return nil, false
+		}
+	}
+	return bufs, true
+}
+
+func wasiNone(*Instance, []uint64) uint32 {
+	return errnoSuccess
+}
+
+func wasiBadf(*Instance, []uint64) uint32 {
+	return errnoBadf
+}
+
+// wasiSizes answers args_sizes_get and environ_sizes_get: there are
+// none.
+func wasiSizes(in *Instance, args []uint64) uint32 {
+	if !in.putU32(uint32(args[0]), 0) || !in.putU32(uint32(args[1]), 0) {
+		return errnoFault
+	}
+	return errnoSuccess
+}
+
+// monotonic is where the monotonic clock starts.
+var monotonic = time.Now()
+
+func wasiClockTimeGet(in *Instance, args []uint64) uint32 {
+	var t int64
+	switch args[0] {
+	case 0: // realtime
+		t = time.Now().UnixNano()
+	case 1: // monotonic
+		t = int64(time.Since(monotonic))
+	default:
+		return errnoInval
+	}
+	if !in.putU64(uint32(args[2]), uint64(t)) {
+		return errnoFault
+	}
+	return errnoSuccess
+}
+
+func wasiRandomGet(in *Instance, args []uint64) uint32 {
+	b, ok := in.region(uint32(args[0]), uint32(args[1]))
+	if !ok {
+		return errnoFault
+	}
+	rand.Read(b)
+	return errnoSuccess
+}
+
+// wasiFdRead reads stdin into the first of the buffers with room, in one
+// read so a module waiting for input gets what there is.
+func wasiFdRead(in *Instance, args []uint64) uint32 {
+	if args[0] != 0 {
+		return errnoBadf
+	}
+	bufs, ok := in.iovecs(uint32(args[1]), uint32(args[2]))
+	if !ok {
+		return errnoFault
+	}
+	var n int
+	for _, b := range bufs {
+		if len(b) == 0 {
+			continue
+		}
+		if in.stdio.Stdin != nil {
+			var err error
+			if n, err = in.stdio.Stdin.Read(b); err != nil && err != io.EOF {
+				return errnoIO
+			}
+		}
+		break
+	}
+	if !in.putU32(uint32(args[3]), uint32(n)) {
+		return errnoFault
+	}
+	return errnoSuccess
+}
+
+func wasiFdWrite(in *Instance, args []uint64) uint32 {
+	var w io.Writer
+	switch args[0] {
+	case 1:
+		w = in.stdio.Stdout
+	case 2:
+		w = in.stdio.Stderr
+	default:
+		return errnoBadf
+	}
+	bufs, ok := in.iovecs(uint32(args[1]), uint32(args[2]))
+	if !ok {
+		return errnoFault
+	}
+	var n int
+	for _, b := range bufs {
+		if w != nil {
+			if _, err := w.Write(b); err != nil {
+				return errnoIO
+			}
+		}
+		n += len(b)
+	}
+	if !in.putU32(uint32(args[3]), uint32(n)) {
+		return errnoFault
+	}
+	return errnoSuccess
+}
+
+// wasiStdFd answers fd_close and fd_fdstat_set_flags, which do nothing
+// to the standard streams, the only files a module has.
+func wasiStdFd(in *Instance, args []uint64) uint32 {
+	if args[0] > 2 {
+		return errnoBadf
+	}
+	return errnoSuccess
+}
+
+// wasiFdstatGet describes the standard streams as character devices.
+func wasiFdstatGet(in *Instance, args []uint64) uint32 {
+	if args[0] > 2 {
+		return errnoBadf
+	}
+	b, ok := in.region(uint32(args[1]), 24)
+	if !ok {
+		return errnoFault
+	}
+	clear(b)
+	b[0] = 2 // character device
+	binary.LittleEndian.PutUint64(b[8:], math.MaxUint64)
+	return errnoSuccess
+}
+
+// wasiPollOneoff reports every subscription ready at once: a module
+// cannot sleep, and its stdin blocks until there is a request.
+func wasiPollOneoff(in *Instance, args []uint64) uint32 {
+	n := uint32(args[2])
+	if n > math.MaxUint32/48 {
+		return errnoFault
+	}
+	subs, ok := in.region(uint32(args[0]), n*48)
+	if !ok {
+		return errnoFault
+	}
+	events, ok := in.region(uint32(args[1]), n*32)
+	if !ok {
+		return errnoFault
+	}
+	for i := range n {
+		s, e := subs[i*48:], events[i*32:(i+1)*32]
+		clear(e)
+		copy(e[:8], s[:8]) // userdata
+		e[10] = s[8]       // type
+	}
+	if !in.putU32(uint32(args[3]), n) {
+		return errnoFault
+	}
+	return errnoSuccess
+}
+
+func wasiProcExit(in *Instance, args []uint64) uint32 {
+	panic(trap{&ExitError{Status: uint32(args[0])}})
+}
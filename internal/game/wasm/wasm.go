@@ -0,0 +1,225 @@
+// Package wasm runs games compiled to WebAssembly inside the server, so
+// games from people the operator does not trust can be hosted safely.
+//
+// A game module is a WASI program that speaks the protocol of package
+// subprocess on its stdin and stdout, as cmd/tictactoe-plugin does when
+// built with GOOS=wasip1 GOARCH=wasm. It runs in an interpreter of this
+// package's own rather than as a process. The module gets its standard
+// streams, a clock and random numbers, and nothing else: no files,
+// network, arguments or environment. The server, not the module, limits
+// the instructions it may run to answer each request, its fuel, and how
+// far its memory may grow.
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"games/internal/game/subprocess"
+)
+
+// maxLine bounds a line a module writes to stdout, as a game process's
+// lines are bounded.
+const maxLine = 16 << 20
+
+// maxStderr bounds what one run of a module may write to stderr, which
+// goes to the server's log, so a module cannot flood it.
+const maxStderr = 64 << 10
+
+// Load reads the module at path and returns its game, ready to register,
+// with each run of the module held to l.
+func Load(path string, l Limits) (*subprocess.Game, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Compile(bin)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	e, ok := m.exports["_start"]
+	if !ok || e.kind != exportFunc || len(m.types[m.funcs[e.index].typ].params) != 0 {
+		return nil, fmt.Errorf("%s: module has no _start function", path)
+	}
+	return subprocess.Open(path, &conn{path: path, module: m, limits: l})
+}
+
+// conn serves a game's requests from a run of its module, started on the
+// first request and again after the module ends, as a game process is.
+type conn struct {
+	path   string // prefixes what the module logs
+	module *Module
+	limits Limits
+	run    *run // nil when not running
+}
+
+// run is one run of a module, in a goroutine of its own that waits in
+// fd_read for each request. The module never blocks otherwise, so it
+// answers, or ends, within its fuel.
+type run struct {
+	requests chan []byte
+	lines    chan []byte   // closed when the module ends
+	stop     chan struct{} // closed to end the module
+	err      error         // why the module ended, set before lines closes
+
+	// Used by the module's goroutine alone.
+	inst     *Instance
+	in       []byte // what is left of the request being read
+	answered bool   // whether a line followed the last request
+	out      []byte // a partial line written to stdout
+	errOut   []byte // a partial line written to stderr
+	errLeft  int    // bytes stderr may still log
+}
+
+func (c *conn) start() *run {
+	r := &run{
+		requests: make(chan []byte),
+		lines:    make(chan []byte),
+		stop:     make(chan struct{}),
+		answered: true,
+		errLeft:  maxStderr,
+	}
+	go func() {
+		defer close(r.lines)
+		r.inst, r.err = c.module.Instantiate(c.limits, Stdio{Stdin: stdin{r}, Stdout: stdout{r}, Stderr: stderr{r, c.path}})
+		if r.err == nil {
+			_, r.err = r.inst.Call("_start")
+		}
+	}()
+	return r
+}
+
+func (c *conn) Exchange(req []byte) ([]byte, error) {
+	if c.run == nil {
+		c.run = c.start()
+	}
+	r := c.run
+	select {
+	case r.requests <- append(req, '\n'):
+	case line, ok := <-r.lines:
+		// Something other than the answer, which the caller turns
+		// away.
+		if ok {
+			return line, nil
+		}
+		return nil, c.ended(r)
+	}
+	line, ok := <-r.lines
+	if !ok {
+		return nil, c.ended(r)
+	}
+	return line, nil
+}
+
+// ended reports why r's module stopped answering.
+func (c *conn) ended(r *run) error {
+	close(r.stop)
+	c.run = nil
+	var exit *ExitError
+	if r.err == nil || errors.As(r.err, &exit) {
+		return errors.New("game module exited")
+	}
+	return r.err
+}
+
+// Close ends the module's run. One that is waiting for a request reads
+// the end of its input; one still running stops at the latest when its
+// fuel runs out.
+func (c *conn) Close() error {
+	if c.run != nil {
+		close(c.run.stop)
+		c.run = nil
+	}
+	return nil
+}
+
+// stdin is a run's standard input: the requests sent to it, one after
+// another. Each request refuels the module. A module that wants more
+// before it has answered would wait for ever, so it reads the end of its
+// input instead.
+type stdin struct{ r *run }
+
+func (s stdin) Read(p []byte) (int, error) {
+	r := s.r
+	if len(r.in) == 0 {
+		if !r.answered {
+			return 0, io.EOF
+		}
+		select {
+		case r.in = <-r.requests:
+			r.answered = false
+			if r.inst != nil {
+				r.inst.Refuel()
+			}
+		case <-r.stop:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.in)
+	r.in = r.in[n:]
+	return n, nil
+}
+
+// stdout is a run's standard output, split into lines for the conn.
+type stdout struct{ r *run }
+
+func (s stdout) Write(p []byte) (int, error) {
+	r := s.r
+	r.out = append(r.out, p...)
+	for {
+		i := bytes.IndexByte(r.out, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.Clone(r.out[:i])
+		r.out = r.out[i+1:]
+		r.answered = true
+		select {
+		case r.lines <- line:
+		case <-r.stop:
+			return 0, io.ErrClosedPipe
+		}
+	}
+	if len(r.out) > maxLine {
+		return 0, errors.New("line too long")
+	}
+	return len(p), nil
+}
+
+// stderr is a run's standard error, logged a line at a time under the
+// module's path until the run has written maxStderr bytes. The rest is
+// dropped.
+type stderr struct {
+	r    *run
+	path string
+}
+
+func (s stderr) Write(p []byte) (int, error) {
+	r := s.r
+	if r.errLeft == 0 {
+		return len(p), nil
+	}
+	kept := p[:min(len(p), r.errLeft)]
+	r.errLeft -= len(kept)
+	r.errOut = append(r.errOut, kept...)
+	for {
+		i := bytes.IndexByte(r.errOut, '\n')
+		if i < 0 {
+			break
+		}
+		log.Printf("%s: %s", s.path, r.errOut[:i])
+		r.errOut = r.errOut[i+1:]
+	}
+	if r.errLeft == 0 {
+		if len(r.errOut) > 0 {
+			log.Printf("%s: %s", s.path, r.errOut)
+			r.errOut = nil
+		}
+		log.Printf("%s: wrote more than %d bytes to stderr; dropping the rest", s.path, maxStderr)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,509 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"games/internal/game"
+)
+
+// The tests assemble their modules by hand, from these.
+
+func uleb(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// vec prefixes items with their count.
+func vec(items ...[]byte) []byte {
+	b := uleb(uint64(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func section(id byte, items ...[]byte) []byte {
+	contents := vec(items...)
+	return append(append([]byte{id}, uleb(uint64(len(contents)))...), contents...)
+}
+
+func module(sections ...[]byte) []byte {
+	b := []byte("\x00asm\x01\x00\x00\x00")
+	for _, s := range sections {
+		b = append(b, s...)
+	}
+	return b
+}
+
+func sig(params, results string) []byte {
+	return append(append([]byte{0x60}, vec(types(params)...)...), vec(types(results)...)...)
+}
+
+// types spells value types as i, I, f and F for i32, i64, f32 and f64.
+func types(s string) [][]byte {
+	var b [][]byte
+	for _, c := range s {
+		b = append(b, []byte{map[rune]byte{'i': 0x7f, 'I': 0x7e, 'f': 0x7d, 'F': 0x7c}[c]})
+	}
+	return b
+}
+
+// body is a function's code with its locals, given as types.
+func body(locals string, code ...byte) []byte {
+	var groups [][]byte
+	for _, t := range types(locals) {
+		groups = append(groups, append([]byte{1}, t...))
+	}
+	b := append(vec(groups...), code...)
+	return append(uleb(uint64(len(b))), b...)
+}
+
+func name(s string) []byte {
+	return append(uleb(uint64(len(s))), s...)
+}
+
+func exportFn(s string, index int) []byte {
+	return append(append(name(s), exportFunc), uleb(uint64(index))...)
+}
+
+func exportMem(s string) []byte {
+	return append(name(s), exportMemory, 0)
+}
+
+func i32(v int32) uint64 { return uint64(uint32(v)) }
+
+func i32Const(v int32) []byte { return append([]byte{opI32Const}, sleb(int64(v))...) }
+func i64Const(v int64) []byte { return append([]byte{opI64Const}, sleb(v)...) }
+
+func code(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// testModule exports small functions, each exercising part of the
+// interpreter, and a memory of one page that may grow to three.
+func testModule() []byte {
+	return module(
+		section(1, sig("I", "I"), sig("i", "i"), sig("ii", "i"), sig("F", "I")),
+		section(3, []byte{0}, []byte{1}, []byte{2}, []byte{1}, []byte{1}, []byte{1}, []byte{1}, []byte{3}),
+		section(4, []byte{0x70, 0x00, 0x01}),
+		section(5, []byte{0x01, 0x01, 0x03}),
+		section(7,
+			exportFn("fac", 0), exportFn("sum", 1), exportFn("div", 2), exportFn("grow", 3),
+			exportFn("load", 4), exportFn("pick", 5), exportFn("indirect", 6), exportFn("trunc", 7),
+		),
+		section(9, code([]byte{0x00}, i32Const(0), []byte{0x0b}, vec([]byte{1}))),
+		section(10,
+			// fac(n): n == 0 ? 1 : n * fac(n-1)
+			body("", code(
+				[]byte{0x20, 0x00, 0x50, 0x04, 0x7e}, i64Const(1),
+				[]byte{0x05, 0x20, 0x00, 0x20, 0x00}, i64Const(1),
+				[]byte{0x7d, 0x10, 0x00, 0x7e, 0x0b, 0x0b},
+			)...),
+			// sum(n): 1 + 2 + ... + n, in a loop
+			body("i",
+				0x02, 0x40, 0x03, 0x40,
+				0x20, 0x00, 0x45, 0x0d, 0x01,
+				0x20, 0x01, 0x20, 0x00, 0x6a, 0x21, 0x01,
+				0x20, 0x00, 0x41, 0x01, 0x6b, 0x21, 0x00,
+				0x0c, 0x00, 0x0b, 0x0b,
+				0x20, 0x01, 0x0b,
+			),
+			// div(a, b): a / b, signed
+			body("", 0x20, 0x00, 0x20, 0x01, 0x6d, 0x0b),
+			// grow(n): memory.grow n
+			body("", 0x20, 0x00, 0x40, 0x00, 0x0b),
+			// load(a): i32.load a
+			body("", 0x20, 0x00, 0x28, 0x02, 0x00, 0x0b),
+			// pick(i): [10, 20][i], 30 beyond
+			body("", code(
+				[]byte{0x02, 0x40, 0x02, 0x40, 0x02, 0x40, 0x20, 0x00, 0x0e, 0x02, 0x00, 0x01, 0x02, 0x0b},
+				i32Const(10), []byte{0x0f, 0x0b},
+				i32Const(20), []byte{0x0f, 0x0b},
+				i32Const(30), []byte{0x0b},
+			)...),
+			// indirect(n): sum(n) through the table
+			body("", 0x20, 0x00, 0x41, 0x00, 0x11, 0x01, 0x00, 0x0b),
+			// trunc(x): i64.trunc_f64_s x
+			body("", 0x20, 0x00, 0xb0, 0x0b),
+		),
+	)
+}
+
+func instantiate(t *testing.T, bin []byte, l Limits) *Instance {
+	t.Helper()
+	m, err := Compile(bin)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	in, err := m.Instantiate(l, Stdio{})
+	if err != nil {
+		t.Fatalf("instantiate: %v", err)
+	}
+	return in
+}
+
+func TestInterpreter(t *testing.T) {
+	in := instantiate(t, testModule(), Limits{})
+	for _, tt := range []struct {
+		name string
+		args []uint64
+		want uint64
+	}{
+		{"fac", []uint64{20}, 2432902008176640000},
+		{"sum", []uint64{100}, 5050},
+		{"div", []uint64{i32(-7), 2}, i32(-3)},
+		{"pick", []uint64{0}, 10},
+		{"pick", []uint64{1}, 20},
+		{"pick", []uint64{7}, 30},
+		{"indirect", []uint64{10}, 55},
+		{"trunc", []uint64{math.Float64bits(-3.9)}, uint64(math.MaxUint64 - 2)},
+		{"grow", []uint64{1}, 1},
+		{"load", []uint64{PageSize + 4}, 0},
+	} {
+		got, err := in.Call(tt.name, tt.args...)
+		if err != nil || len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s%v = %v, %v; want %d", tt.name, tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestTraps(t *testing.T) {
+	in := instantiate(t, testModule(), Limits{})
+	for _, tt := range []struct {
+		name string
+		args []uint64
+		want string
+	}{
+		{"div", []uint64{7, 0}, "integer divide by zero"},
+		{"div", []uint64{i32(math.MinInt32), i32(-1)}, "integer overflow"},
+		{"load", []uint64{PageSize - 2}, "out of bounds memory access"},
+		{"trunc", []uint64{math.Float64bits(math.NaN())}, "invalid conversion to integer"},
+		{"trunc", []uint64{math.Float64bits(1e19)}, "integer overflow"},
+		{"fac", []uint64{math.MaxUint64}, "call stack exhausted"},
+	} {
+		if _, err := in.Call(tt.name, tt.args...); err == nil || err.Error() != tt.want {
+			t.Errorf("%s%v: expected %q, got %v", tt.name, tt.args, tt.want, err)
+		}
+	}
+	// A trap leaves the instance usable.
+	if got, err := in.Call("sum", 4); err != nil || got[0] != 10 {
+		t.Fatalf("expected the instance to run after a trap, got %v %v", got, err)
+	}
+}
+
+func TestFuelLimit(t *testing.T) {
+	in := instantiate(t, testModule(), Limits{Fuel: 10_000})
+	if got, err := in.Call("sum", 100); err != nil || got[0] != 5050 {
+		t.Fatalf("expected a short loop to finish, got %v %v", got, err)
+	}
+	if _, err := in.Call("sum", 1_000_000); !errors.Is(err, ErrOutOfFuel) {
+		t.Fatalf("expected a long loop stopped, got %v", err)
+	}
+	// Each call has its own fuel.
+	if got, err := in.Call("sum", 100); err != nil || got[0] != 5050 {
+		t.Fatalf("expected fuel for the next call, got %v %v", got, err)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	in := instantiate(t, testModule(), Limits{Memory: 2 * PageSize})
+	if got, err := in.Call("grow", 1); err != nil || got[0] != 1 {
+		t.Fatalf("expected memory to grow within the limit, got %v %v", got, err)
+	}
+	if got, err := in.Call("grow", 1); err != nil || got[0] != math.MaxUint32 {
+		t.Fatalf("expected growth past the limit refused, got %v %v", got, err)
+	}
+	if n := len(in.Memory()); n != 2*PageSize {
+		t.Fatalf("expected two pages, got %d bytes", n)
+	}
+
+	// The module's own maximum holds under a larger limit.
+	in = instantiate(t, testModule(), Limits{})
+	if got, _ := in.Call("grow", 3); got[0] != math.MaxUint32 {
+		t.Fatalf("expected the module's maximum kept, got %v", got)
+	}
+
+	big := module(section(5, []byte{0x00, 0x02}))
+	m, err := Compile(big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Instantiate(Limits{Memory: PageSize}, Stdio{}); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("expected a module needing too much memory refused, got %v", err)
+	}
+}
+
+func TestLocalsLimit(t *testing.T) {
+	// deep() recurses with 4096 i64 locals a frame, 32 KiB of them.
+	locals := append(uleb(1), append(uleb(4096), 0x7e)...)
+	deep := append(locals, 0x10, 0x00, 0x0b)
+	bin := module(
+		section(1, sig("", "")),
+		section(3, []byte{0}),
+		section(7, exportFn("deep", 0)),
+		section(10, append(uleb(uint64(len(deep))), deep...)),
+	)
+	in := instantiate(t, bin, Limits{Memory: 4 * PageSize})
+	if _, err := in.Call("deep"); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("expected the frames held to the memory limit, got %v", err)
+	}
+	// Well short of the depth limit under the default memory, too.
+	in = instantiate(t, bin, Limits{})
+	if _, err := in.Call("deep"); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("expected the frames held to the default memory limit, got %v", err)
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	for name, bin := range map[string][]byte{
+		"not wasm": []byte("#!/bin/sh\n"),
+		"host import": module(
+			section(1, sig("", "")),
+			section(2, code(name("env"), name("f"), []byte{0x00, 0x00})),
+		),
+		"WASI import of the wrong type": module(
+			section(1, sig("i", "i")),
+			section(2, code(name(wasiModule), name("fd_write"), []byte{0x00, 0x00})),
+		),
+		"truncated": testModule()[:40],
+		"unsupported instruction": module(
+			section(1, sig("", "")),
+			section(3, []byte{0}),
+			section(10, body("", 0xd0, 0x70, 0x1a, 0x0b)), // ref.null func; drop
+		),
+		"unknown local": module(
+			section(1, sig("", "")),
+			section(3, []byte{0}),
+			section(10, body("", 0x20, 0x01, 0x1a, 0x0b)),
+		),
+	} {
+		if _, err := Compile(bin); err == nil {
+			t.Errorf("%s: expected the module refused", name)
+		}
+	}
+}
+
+// wasiGame is a game module that reads each request with fd_read, runs
+// then, and writes reply, until its input ends. Its memory holds the
+// iovec for reading at 0, the one for writing at 16, the count read at
+// 32 and reply at 1024.
+func wasiGame(reply string, then ...byte) []byte {
+	reply += "\n"
+	read := code(i32Const(0), i32Const(0), i32Const(1), i32Const(32), []byte{opCall, 0, opDrop})
+	return module(
+		section(1, sig("iiii", "i"), sig("", "")),
+		section(2,
+			code(name(wasiModule), name("fd_read"), []byte{0x00, 0x00}),
+			code(name(wasiModule), name("fd_write"), []byte{0x00, 0x00}),
+		),
+		section(3, []byte{1}),
+		section(5, []byte{0x00, 0x01}),
+		section(7, exportFn("_start", 2)),
+		section(10, body("", code(
+			[]byte{opLoop, 0x40},
+			read,
+			i32Const(32), []byte{0x28, 0x02, 0x00, 0x45, opBrIf, 0x01}, // return at the end of input
+			then,
+			i32Const(1), i32Const(16), i32Const(1), i32Const(40), []byte{opCall, 1, opDrop},
+			[]byte{opBr, 0x00, opEnd, opEnd},
+		)...)),
+		section(11,
+			code([]byte{0x00}, i32Const(0), []byte{0x0b}, name(iovec(256, 4096))),
+			code([]byte{0x00}, i32Const(16), []byte{0x0b}, name(iovec(1024, len(reply)))),
+			code([]byte{0x00}, i32Const(1024), []byte{0x0b}, name(reply)),
+		),
+	)
+}
+
+func iovec(ptr, n int) string {
+	return string(binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, uint32(ptr)), uint32(n)))
+}
+
+func compile(t *testing.T, bin []byte) *Module {
+	t.Helper()
+	m, err := Compile(bin)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return m
+}
+
+func writeModule(t *testing.T, bin []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "game.wasm")
+	if err := os.WriteFile(path, bin, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const cannedInfo = `{"id":1,"result":{"name":"canned","minPlayers":2,"maxPlayers":2}}`
+
+func TestLoad(t *testing.T) {
+	g, err := Load(writeModule(t, wasiGame(cannedInfo)), Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if info := g.Info(); info.Name != "canned" || info.MaxPlayers != 2 {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	if _, err := Load(writeModule(t, testModule()), Limits{}); err == nil || !strings.Contains(err.Error(), "_start") {
+		t.Fatalf("expected a module without _start refused, got %v", err)
+	}
+}
+
+func TestFuelPerRequest(t *testing.T) {
+	c := &conn{module: compile(t, wasiGame(cannedInfo)), limits: Limits{Fuel: 100}}
+	defer c.Close()
+	for i := range 10 {
+		if resp, err := c.Exchange([]byte(`{"id":1,"method":"info"}`)); err != nil || string(resp) != cannedInfo {
+			t.Fatalf("request %d: expected the answer within fuel, got %q %v", i, resp, err)
+		}
+	}
+
+	spin := []byte{opLoop, 0x40, opBr, 0x00, opEnd}
+	if _, err := Load(writeModule(t, wasiGame(cannedInfo, spin...)), Limits{Fuel: 1000}); !errors.Is(err, ErrOutOfFuel) {
+		t.Fatalf("expected a module that never answers stopped, got %v", err)
+	}
+}
+
+func TestMemoryLimitEnforced(t *testing.T) {
+	// Grows memory a page at a time until refused, then traps.
+	hog := code(
+		[]byte{opLoop, 0x40},
+		i32Const(1), []byte{opMemoryGrow, 0x00}, i32Const(-1), []byte{0x47, opBrIf, 0x00},
+		[]byte{opEnd, opUnreachable},
+	)
+	_, err := Load(writeModule(t, wasiGame(cannedInfo, hog...)), Limits{Memory: 4 * PageSize})
+	if err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Fatalf("expected memory held to the limit, got %v", err)
+	}
+
+	big := wasiGame(cannedInfo)
+	if _, err := Load(writeModule(t, big), Limits{Memory: PageSize / 2}); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("expected a module needing more memory than allowed refused, got %v", err)
+	}
+}
+
+func TestStderrBounded(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	// Writes its 4 KiB read buffer to stderr until its fuel runs out.
+	flood := code(
+		[]byte{opLoop, 0x40},
+		i32Const(2), i32Const(0), i32Const(1), i32Const(40), []byte{opCall, 1, opDrop},
+		[]byte{opBr, 0x00, opEnd},
+	)
+	path := writeModule(t, wasiGame(cannedInfo, flood...))
+	if _, err := Load(path, Limits{Fuel: 100_000}); !errors.Is(err, ErrOutOfFuel) {
+		t.Fatalf("expected the module stopped by its fuel, got %v", err)
+	}
+	if n := logged.Len(); n > 2*maxStderr {
+		t.Errorf("expected stderr held to %d bytes, logged %d", maxStderr, n)
+	}
+	if out := logged.String(); !strings.Contains(out, path+": ") || !strings.Contains(out, "dropping the rest") {
+		t.Errorf("expected stderr logged under the module's path and cut off, got %.200q", out)
+	}
+}
+
+func TestReadWithoutAnswering(t *testing.T) {
+	// Reads a second time before answering, so waits for ever unless
+	// it reads the end of its input.
+	again := code(
+		i32Const(0), i32Const(0), i32Const(1), i32Const(32), []byte{opCall, 0, opDrop},
+		i32Const(32), []byte{0x28, 0x02, 0x00, 0x45, opBrIf, 0x01},
+	)
+	_, err := Load(writeModule(t, wasiGame(cannedInfo, again...)), Limits{})
+	if err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Fatalf("expected the module to end, got %v", err)
+	}
+}
+
+// TestTicTacToePlugin plays the example game process, built for WASI, in
+// the interpreter.
+func TestTicTacToePlugin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a module")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+	path := filepath.Join(t.TempDir(), "tictactoe.wasm")
+	build := exec.Command(gobin, "build", "-o", path, "games/cmd/tictactoe-plugin")
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+
+	g, err := Load(path, Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if g.Info().Name != "tictactoe" {
+		t.Fatalf("unexpected info %+v", g.Info())
+	}
+	move := func(cell int) game.Action {
+		return game.Action{Type: "move", Payload: json.RawMessage(fmt.Sprintf(`{"cell":%d}`, cell))}
+	}
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}})
+	if err := m.ApplyAction("bob", move(0)); err == nil {
+		t.Fatal("expected bob's move out of turn to be rejected")
+	}
+	for i, cell := range []int{0, 3, 1, 4} {
+		player := []string{"alice", "bob"}[i%2]
+		if err := m.ApplyAction(player, move(cell)); err != nil {
+			t.Fatalf("move %d: %v", cell, err)
+		}
+	}
+	// A fresh run of the module carries on from the state.
+	g.Close()
+	if n := len(m.ValidActions("alice")); n != 5 {
+		t.Fatalf("expected 5 actions for alice, got %d", n)
+	}
+	if err := m.ApplyAction("alice", move(2)); err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsOver() || m.Results()[0].Rank != 1 {
+		t.Fatalf("expected alice to win, got %+v", m.Results())
+	}
+}
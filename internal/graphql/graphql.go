@@ -0,0 +1,558 @@
+// Package graphql answers read-only GraphQL queries over plain Go values.
+//
+// A value's fields are the ones encoding/json would marshal, by their JSON
+// names; Extend adds computed fields, such as a game's stats, to a Go
+// type. Values that marshal themselves, such as times, are scalars. There
+// is no type system beyond the Go types, so there is no introspection and
+// a field that fails is null whatever its type; mutations and
+// subscriptions are refused.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MaxDepth is how deeply selections may nest.
+const MaxDepth = 12
+
+// MaxCost is how many selections a query may make: its fields, each
+// alias counting as a field of its own, its fragment spreads and its
+// inline fragments, with a fragment's counted every time it is spread.
+const MaxCost = 500
+
+// Resolver computes a field of source, the value the field is selected
+// on, or nil on the query root.
+type Resolver func(ctx context.Context, source any, args map[string]any) (any, error)
+
+// Object names a type and gives it fields.
+type Object struct {
+	Name   string
+	Fields map[string]Resolver
+}
+
+// Schema answers queries from a root object.
+type Schema struct {
+	query *Object
+	types map[reflect.Type]*Object
+}
+
+// NewSchema returns a schema whose queries start at query.
+func NewSchema(query *Object) *Schema {
+	return &Schema{query: query, types: make(map[reflect.Type]*Object)}
+}
+
+// Extend gives values of sample's Go type, or pointers to it, the fields
+// of o on top of the ones they marshal to JSON, and o's name in
+// __typename.
+func (s *Schema) Extend(sample any, o *Object) {
+	s.types[indirectType(reflect.TypeOf(sample))] = o
+}
+
+// Request is a query, as clients POST it.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is a query's result. Data is absent when the query could not
+// run at all.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Error is an error in a query, at the document locations or response
+// path it concerns.
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	Path      []any      `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Location is a position in the query document, counting from 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Execute runs a query.
+func (s *Schema) Execute(ctx context.Context, req Request) Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return Response{Errors: []*Error{err.(*Error)}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return Response{Errors: []*Error{{Message: op.kind + " operations are not supported; only queries are"}}}
+	}
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if doc.cost(op.sel, MaxCost) > MaxCost {
+		return Response{Errors: []*Error{{Message: fmt.Sprintf("query makes more than %d selections", MaxCost)}}}
+	}
+	e := &executor{schema: s, doc: doc, vars: vars}
+	data := e.selectObject(ctx, s.query, nil, op.sel, nil)
+	return Response{Data: data, Errors: e.errors}
+}
+
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required for a document with several operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("no operation named %q", name)
+}
+
+// cost counts the selections in sel, and in the fragments they spread
+// each time they are spread, stopping once it is past budget. Every
+// selection counts, so a fragment spread in a cycle ends the count too.
+func (doc *document) cost(sel []*selection, budget int) int {
+	n := 0
+	for _, s := range sel {
+		if n > budget {
+			break
+		}
+		n++
+		if s.spread != "" {
+			if f, ok := doc.fragments[s.spread]; ok {
+				n += doc.cost(f.sel, budget-n)
+			}
+		}
+		n += doc.cost(s.sel, budget-n)
+	}
+	return n
+}
+
+func coerceVariables(op *operation, given map[string]any) (map[string]any, error) {
+	vars := make(map[string]any, len(op.vars))
+	for _, v := range op.vars {
+		val, ok := given[v.name]
+		if !ok && v.hasDef {
+			val, ok = v.def, true
+		}
+		if v.nonNull && val == nil {
+			return nil, fmt.Errorf("variable $%s is required", v.name)
+		}
+		if ok {
+			vars[v.name] = val
+		}
+	}
+	return vars, nil
+}
+
+// executor runs one operation, collecting field errors as it goes.
+type executor struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]any
+	errors []*Error
+}
+
+// fieldGroup is the fields selected under one response key, merged as
+// fragments may select a field more than once.
+type fieldGroup struct {
+	key    string
+	fields []*selection
+}
+
+// selectObject resolves the selections on source, typed by obj if it is
+// the root or extended.
+func (e *executor) selectObject(ctx context.Context, obj *Object, source any, sel []*selection, path []any) *orderedMap {
+	if len(path) > MaxDepth {
+		e.fail(sel[0], path, "query is nested more than %d levels deep", MaxDepth)
+		return nil
+	}
+	typeName := e.typeName(obj, source)
+	groups, err := e.collect(typeName, sel, nil, map[string]bool{})
+	if err != nil {
+		e.fail(sel[0], path, "%s", err)
+		return nil
+	}
+	out := &orderedMap{values: make(map[string]any, len(groups))}
+	for _, g := range groups {
+		out.keys = append(out.keys, g.key)
+		out.values[g.key] = e.resolveField(ctx, obj, typeName, source, g, append(path[:len(path):len(path)], g.key))
+	}
+	return out
+}
+
+// collect flattens sel into fields by response key, in the order they
+// first appear, following fragments whose type condition matches.
+func (e *executor) collect(typeName string, sel []*selection, groups []fieldGroup, visited map[string]bool) ([]fieldGroup, error) {
+	for _, s := range sel {
+		include, err := e.included(s)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+		switch {
+		case s.spread != "":
+			if visited[s.spread] {
+				continue
+			}
+			visited[s.spread] = true
+			f, ok := e.doc.fragments[s.spread]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", s.spread)
+			}
+			if f.typeCond != typeName {
+				continue
+			}
+			if groups, err = e.collect(typeName, f.sel, groups, visited); err != nil {
+				return nil, err
+			}
+		case s.name == "":
+			if s.typeCond != "" && s.typeCond != typeName {
+				continue
+			}
+			if groups, err = e.collect(typeName, s.sel, groups, visited); err != nil {
+				return nil, err
+			}
+		default:
+			key := s.name
+			if s.alias != "" {
+				key = s.alias
+			}
+			found := false
+			for i := range groups {
+				if groups[i].key == key {
+					groups[i].fields = append(groups[i].fields, s)
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, fieldGroup{key: key, fields: []*selection{s}})
+			}
+		}
+	}
+	return groups, nil
+}
+
+// included applies @skip and @include.
+func (e *executor) included(s *selection) (bool, error) {
+	for _, d := range s.directives {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		cond, ok := e.value(d.args["if"]).(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s needs a boolean if argument", d.name)
+		}
+		if cond == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *executor) resolveField(ctx context.Context, obj *Object, typeName string, source any, g fieldGroup, path []any) any {
+	f := g.fields[0]
+	if f.name == "__typename" {
+		return typeName
+	}
+	var val any
+	if r, ok := obj.fields()[f.name]; ok {
+		args := make(map[string]any, len(f.args))
+		for name, v := range f.args {
+			args[name] = e.value(v)
+		}
+		var err error
+		if val, err = r(ctx, source, args); err != nil {
+			e.fail(f, path, "%s", err)
+			return nil
+		}
+	} else if v, ok := jsonField(source, f.name); ok {
+		if len(f.args) > 0 {
+			e.fail(f, path, "field %q takes no arguments", f.name)
+			return nil
+		}
+		val = v
+	} else {
+		e.fail(f, path, "cannot query field %q on type %q", f.name, typeName)
+		return nil
+	}
+	var sub []*selection
+	for _, f := range g.fields {
+		sub = append(sub, f.sel...)
+	}
+	return e.complete(ctx, f, val, sub, path)
+}
+
+// complete turns a resolved value into its response: sub-selections are
+// applied to objects and to each element of lists.
+func (e *executor) complete(ctx context.Context, f *selection, val any, sub []*selection, path []any) any {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	t := rv.Type()
+	if isScalar(t) {
+		if sub != nil {
+			e.fail(f, path, "field %q is a scalar and has no fields to select", f.name)
+			return nil
+		}
+		return rv.Interface()
+	}
+	if sub == nil {
+		e.fail(f, path, "field %q is an object; select the fields wanted from it", f.name)
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		list := make([]any, rv.Len())
+		for i := range list {
+			list[i] = e.complete(ctx, f, rv.Index(i).Interface(), sub, append(path[:len(path):len(path)], i))
+		}
+		return list
+	}
+	obj := e.schema.types[t]
+	if rv.Kind() == reflect.Map && rv.IsNil() {
+		return nil
+	}
+	return e.selectObject(ctx, obj, rv.Interface(), sub, path)
+}
+
+// value substitutes variables into an argument value.
+func (e *executor) value(v any) any {
+	switch v := v.(type) {
+	case variable:
+		return e.vars[string(v)]
+	case enumValue:
+		return string(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = e.value(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = e.value(item)
+		}
+		return out
+	}
+	return v
+}
+
+func (e *executor) typeName(obj *Object, source any) string {
+	if obj != nil && obj.Name != "" {
+		return obj.Name
+	}
+	if source == nil {
+		return "Query"
+	}
+	return indirectType(reflect.TypeOf(source)).Name()
+}
+
+func (e *executor) fail(s *selection, path []any, format string, args ...any) {
+	e.errors = append(e.errors, &Error{
+		Message:   fmt.Sprintf(format, args...),
+		Locations: []Location{s.loc},
+		Path:      path,
+	})
+}
+
+func (o *Object) fields() map[string]Resolver {
+	if o == nil {
+		return nil
+	}
+	return o.Fields
+}
+
+var marshalerType = reflect.TypeFor[json.Marshaler]()
+
+// isScalar reports whether values of t are leaves of a response: numbers,
+// strings and the like, values that marshal themselves, and lists and
+// maps of them.
+func isScalar(t reflect.Type) bool {
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return isScalar(t.Elem())
+	case reflect.Map:
+		return isScalar(t.Elem())
+	case reflect.Struct:
+		return false
+	case reflect.Interface:
+		return false
+	}
+	return true
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// jsonField returns the field of v encoding/json would marshal under
+// name, or the entry of a map keyed by it.
+func jsonField(v any, name string) (any, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		item := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !item.IsValid() {
+			return nil, true
+		}
+		return item.Interface(), true
+	case reflect.Struct:
+		index, ok := jsonFields(rv.Type())[name]
+		if !ok {
+			return nil, false
+		}
+		f, err := rv.FieldByIndexErr(index)
+		if err != nil {
+			return nil, true // through a nil embedded pointer
+		}
+		return f.Interface(), true
+	}
+	return nil, false
+}
+
+// fieldIndexes caches jsonFields by type.
+var fieldIndexes sync.Map
+
+// jsonFields maps the JSON names of t's fields to their indexes,
+// flattening embedded structs as encoding/json does. A field shallower
+// in the embedding wins.
+func jsonFields(t reflect.Type) map[string][]int {
+	if m, ok := fieldIndexes.Load(t); ok {
+		return m.(map[string][]int)
+	}
+	m := make(map[string][]int)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		var embedded [][]int
+		for i := range t.NumField() {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			idx := append(index[:len(index):len(index)], i)
+			if f.Anonymous && name == "" && indirectType(f.Type).Kind() == reflect.Struct {
+				embedded = append(embedded, idx)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if _, taken := m[name]; !taken {
+				m[name] = idx
+			}
+		}
+		for _, idx := range embedded {
+			walk(indirectType(t.FieldByIndex(idx[len(index):]).Type), idx)
+		}
+	}
+	walk(t, nil)
+	fieldIndexes.Store(t, m)
+	return m
+}
+
+// orderedMap is an object in a response, keeping its fields in the order
+// they were selected.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		val, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// StringArg returns the string argument name, or "" if it is absent or
+// null.
+func StringArg(args map[string]any, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a string", name)
+}
+
+// IntArg returns the integer argument name, or def if it is absent or
+// null. Variables decoded from JSON arrive as floats, so whole floats
+// count.
+func IntArg(args map[string]any, name string, def int) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an integer", name)
+}
@@ -0,0 +1,195 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type author struct {
+	Name string `json:"name"`
+}
+
+type book struct {
+	Title     string    `json:"title"`
+	Published time.Time `json:"published"`
+	Tags      []string  `json:"tags,omitempty"`
+	Author    *author   `json:"author"`
+	secret    string
+}
+
+type link struct {
+	Next *link `json:"next"`
+}
+
+type shelf struct {
+	book
+	Position int `json:"position"`
+}
+
+func testSchema() *Schema {
+	books := []book{
+		{Title: "Dune", Published: time.Date(1965, 8, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"sf"}, Author: &author{"Herbert"}},
+		{Title: "Emma", Published: time.Date(1815, 12, 23, 0, 0, 0, 0, time.UTC), Author: &author{"Austen"}},
+	}
+	s := NewSchema(&Object{Name: "Query", Fields: map[string]Resolver{
+		"books": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			limit, err := IntArg(args, "limit", len(books))
+			if err != nil {
+				return nil, err
+			}
+			return books[:min(limit, len(books))], nil
+		},
+		"book": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			title, err := StringArg(args, "title")
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range books {
+				if b.Title == title {
+					return &b, nil
+				}
+			}
+			return nil, nil
+		},
+		"shelf": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+			return shelf{book: books[0], Position: 3}, nil
+		},
+		"chain": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+			var l *link
+			for range 2 * MaxDepth {
+				l = &link{Next: l}
+			}
+			return l, nil
+		},
+		"broken": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+			return nil, fmt.Errorf("out of order")
+		},
+	}})
+	s.Extend(book{}, &Object{Name: "Book", Fields: map[string]Resolver{
+		"shout": func(ctx context.Context, src any, _ map[string]any) (any, error) {
+			return strings.ToUpper(src.(book).Title), nil
+		},
+	}})
+	return s
+}
+
+// run executes a query and returns its response as JSON.
+func run(t *testing.T, query string, vars map[string]any) string {
+	t.Helper()
+	resp := testSchema().Execute(context.Background(), Request{Query: query, Variables: vars})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	for _, tc := range []struct {
+		name, query string
+		vars        map[string]any
+		want        string
+	}{
+		{
+			name:  "fields in selection order",
+			query: `{ books { title author { name } } }`,
+			want:  `{"data":{"books":[{"title":"Dune","author":{"name":"Herbert"}},{"title":"Emma","author":{"name":"Austen"}}]}}`,
+		},
+		{
+			name:  "aliases, arguments and extended fields",
+			query: `query { first: books(limit: 1) { shout __typename } emma: book(title: "Emma") { published } }`,
+			want:  `{"data":{"first":[{"shout":"DUNE","__typename":"Book"}],"emma":{"published":"1815-12-23T00:00:00Z"}}}`,
+		},
+		{
+			name:  "variables, defaults and directives",
+			query: `query Q($n: Int = 1, $t: Boolean!) { books(limit: $n) { title tags @include(if: $t) } }`,
+			vars:  map[string]any{"t": false},
+			want:  `{"data":{"books":[{"title":"Dune"}]}}`,
+		},
+		{
+			name:  "fragments merge into one field",
+			query: `{ book(title: "Dune") { ...T ... on Book { author { name } } author { __typename } } } fragment T on Book { title }`,
+			want:  `{"data":{"book":{"title":"Dune","author":{"name":"Herbert","__typename":"author"}}}}`,
+		},
+		{
+			name:  "embedded fields flattened",
+			query: `{ shelf { title position } }`,
+			want:  `{"data":{"shelf":{"title":"Dune","position":3}}}`,
+		},
+		{
+			name:  "missing value is null",
+			query: `{ book(title: "Ulysses") { title } }`,
+			want:  `{"data":{"book":null}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := run(t, tc.query, tc.vars); got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, query string
+		want        string
+	}{
+		{"resolver error nulls the field", `{ broken books(limit: 1) { title } }`,
+			`{"data":{"broken":null,"books":[{"title":"Dune"}]},"errors":[{"message":"out of order","locations":[{"line":1,"column":3}],"path":["broken"]}]}`},
+		{"unknown field", `{ book(title: "Dune") { secret } }`,
+			`{"data":{"book":{"secret":null}},"errors":[{"message":"cannot query field \"secret\" on type \"Book\"","locations":[{"line":1,"column":25}],"path":["book","secret"]}]}`},
+		{"object without selection", `{ books }`,
+			`{"data":{"books":null},"errors":[{"message":"field \"books\" is an object; select the fields wanted from it","locations":[{"line":1,"column":3}],"path":["books"]}]}`},
+		{"syntax", "{\n  books {",
+			`{"errors":[{"message":"syntax error: expected a name, got end of document","locations":[{"line":2,"column":10}]}]}`},
+		{"mutation refused", `mutation { books { title } }`,
+			`{"errors":[{"message":"mutation operations are not supported; only queries are"}]}`},
+		{"missing variable", `query ($t: String!) { book(title: $t) { title } }`,
+			`{"errors":[{"message":"variable $t is required"}]}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := run(t, tc.query, nil); got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExecuteDepthLimit(t *testing.T) {
+	query := "{ chain " + strings.Repeat("{ next ", MaxDepth) + "{ __typename }" + strings.Repeat(" }", MaxDepth) + " }"
+	resp := testSchema().Execute(context.Background(), Request{Query: query})
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "nested") {
+		t.Fatalf("expected a deeply nested query refused, got %+v", resp.Errors)
+	}
+}
+
+func TestExecuteCostLimit(t *testing.T) {
+	var aliases strings.Builder
+	for i := range MaxCost + 1 {
+		fmt.Fprintf(&aliases, "b%d: books { title } ", i)
+	}
+	// Each fragment doubles the one it spreads
+	fragments := "fragment f0 on Query { books { title } }"
+	for i := 1; i <= 20; i++ {
+		fragments += fmt.Sprintf(" fragment f%d on Query { ...f%d ...f%d }", i, i-1, i-1)
+	}
+	for name, query := range map[string]string{
+		"aliases":   "{ " + aliases.String() + "}",
+		"fragments": "{ ...f20 } " + fragments,
+		"cycle":     "{ ...loop } fragment loop on Query { books { title } ...loop }",
+	} {
+		resp := testSchema().Execute(context.Background(), Request{Query: query})
+		if resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "selections") {
+			t.Errorf("%s: expected the query refused before it ran, got %+v", name, resp)
+		}
+	}
+	resp := testSchema().Execute(context.Background(), Request{Query: "{ a: books { title } b: books { title } }"})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("expected a cheap query with aliases to run, got %+v", resp.Errors)
+	}
+}
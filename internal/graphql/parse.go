@@ -0,0 +1,489 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed query document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind string // query, mutation or subscription
+	name string
+	vars []varDef
+	sel  []*selection
+}
+
+type varDef struct {
+	name    string
+	nonNull bool
+	def     any // default value; nil if none
+	hasDef  bool
+}
+
+type fragment struct {
+	typeCond string
+	sel      []*selection
+}
+
+// selection is a field, a fragment spread or an inline fragment.
+type selection struct {
+	alias, name string // a field, unless name is empty
+	args        map[string]any
+	directives  []directive
+	sel         []*selection // nil for a leaf field
+	spread      string       // the fragment a spread names
+	typeCond    string       // an inline fragment's type condition
+	loc         Location
+}
+
+type directive struct {
+	name string
+	args map[string]any
+}
+
+// variable is a reference to a variable in an argument value, resolved
+// when the query runs.
+type variable string
+
+// enumValue is an enum literal. Resolvers see it as a string.
+type enumValue string
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	loc  Location
+}
+
+// parser reads a query document a token at a time.
+type parser struct {
+	src       string
+	pos       int
+	line, col int
+	tok       token
+}
+
+func parse(src string) (doc *document, err error) {
+	p := &parser{src: src, line: 1, col: 1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, perr
+		}
+	}()
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek(tokPunct, "{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", sel: p.selectionSet()})
+		case p.peek(tokName, "fragment"):
+			p.next()
+			name := p.name()
+			if name == "on" {
+				p.fail("unexpected name \"on\"")
+			}
+			p.expectName("on")
+			f := &fragment{typeCond: p.name()}
+			p.directives()
+			f.sel = p.selectionSet()
+			if _, dup := doc.fragments[name]; dup {
+				p.fail("fragment %q defined twice", name)
+			}
+			doc.fragments[name] = f
+		case p.peek(tokName, "query"), p.peek(tokName, "mutation"), p.peek(tokName, "subscription"):
+			op := &operation{kind: p.tok.val}
+			p.next()
+			if p.tok.kind == tokName {
+				op.name = p.name()
+			}
+			if p.skip("(") {
+				for !p.skip(")") {
+					op.vars = append(op.vars, p.varDef())
+				}
+			}
+			p.directives()
+			op.sel = p.selectionSet()
+			doc.operations = append(doc.operations, op)
+		default:
+			p.fail("unexpected %s", p.describe())
+		}
+	}
+	if len(doc.operations) == 0 {
+		p.fail("no operation in the document")
+	}
+	return doc, nil
+}
+
+func (p *parser) varDef() varDef {
+	p.expect("$")
+	v := varDef{name: p.name()}
+	p.expect(":")
+	v.nonNull = p.typeRef()
+	if p.skip("=") {
+		v.def, v.hasDef = p.value(true), true
+	}
+	p.directives()
+	return v
+}
+
+// typeRef skips a type reference, reporting whether it is non-null.
+func (p *parser) typeRef() bool {
+	if p.skip("[") {
+		p.typeRef()
+		p.expect("]")
+	} else {
+		p.name()
+	}
+	return p.skip("!")
+}
+
+func (p *parser) selectionSet() []*selection {
+	p.expect("{")
+	var sel []*selection
+	for !p.skip("}") {
+		sel = append(sel, p.selection())
+	}
+	if len(sel) == 0 {
+		p.fail("empty selection set")
+	}
+	return sel
+}
+
+func (p *parser) selection() *selection {
+	s := &selection{loc: p.tok.loc}
+	if p.skip("...") {
+		if p.tok.kind == tokName && p.tok.val != "on" {
+			s.spread = p.name()
+			s.directives = p.directives()
+			return s
+		}
+		if p.tok.kind == tokName {
+			p.next() // on
+			s.typeCond = p.name()
+		}
+		s.directives = p.directives()
+		s.sel = p.selectionSet()
+		return s
+	}
+	s.name = p.name()
+	if p.skip(":") {
+		s.alias, s.name = s.name, p.name()
+	}
+	if p.skip("(") {
+		s.args = make(map[string]any)
+		for !p.skip(")") {
+			name := p.name()
+			p.expect(":")
+			s.args[name] = p.value(false)
+		}
+	}
+	s.directives = p.directives()
+	if p.peek(tokPunct, "{") {
+		s.sel = p.selectionSet()
+	}
+	return s
+}
+
+func (p *parser) directives() []directive {
+	var ds []directive
+	for p.skip("@") {
+		d := directive{name: p.name(), args: make(map[string]any)}
+		if p.skip("(") {
+			for !p.skip(")") {
+				name := p.name()
+				p.expect(":")
+				d.args[name] = p.value(false)
+			}
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// value parses an argument value; constant values, such as variable
+// defaults, may not refer to variables.
+func (p *parser) value(constant bool) any {
+	t := p.tok
+	switch {
+	case t.kind == tokPunct && t.val == "$" && !constant:
+		p.next()
+		return variable(p.name())
+	case t.kind == tokPunct && t.val == "[":
+		p.next()
+		list := []any{}
+		for !p.skip("]") {
+			list = append(list, p.value(constant))
+		}
+		return list
+	case t.kind == tokPunct && t.val == "{":
+		p.next()
+		obj := make(map[string]any)
+		for !p.skip("}") {
+			name := p.name()
+			p.expect(":")
+			obj[name] = p.value(constant)
+		}
+		return obj
+	case t.kind == tokInt:
+		p.next()
+		n, err := strconv.ParseInt(t.val, 10, 64)
+		if err != nil {
+			p.failAt(t.loc, "integer %s out of range", t.val)
+		}
+		return int(n)
+	case t.kind == tokFloat:
+		p.next()
+		f, _ := strconv.ParseFloat(t.val, 64)
+		return f
+	case t.kind == tokString:
+		p.next()
+		return t.val
+	case t.kind == tokName:
+		p.next()
+		switch t.val {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(t.val)
+	}
+	p.fail("expected a value, got %s", p.describe())
+	return nil
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.fail("expected a name, got %s", p.describe())
+	}
+	name := p.tok.val
+	p.next()
+	return name
+}
+
+func (p *parser) expectName(name string) {
+	if !p.peek(tokName, name) {
+		p.fail("expected %q, got %s", name, p.describe())
+	}
+	p.next()
+}
+
+func (p *parser) expect(punct string) {
+	if !p.skip(punct) {
+		p.fail("expected %q, got %s", punct, p.describe())
+	}
+}
+
+// skip consumes the given punctuator if it is next.
+func (p *parser) skip(punct string) bool {
+	if p.peek(tokPunct, punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) peek(kind tokenKind, val string) bool {
+	return p.tok.kind == kind && p.tok.val == val
+}
+
+func (p *parser) describe() string {
+	switch p.tok.kind {
+	case tokEOF:
+		return "end of document"
+	case tokString:
+		return "string " + strconv.Quote(p.tok.val)
+	}
+	return strconv.Quote(p.tok.val)
+}
+
+func (p *parser) fail(format string, args ...any) {
+	p.failAt(p.tok.loc, format, args...)
+}
+
+func (p *parser) failAt(loc Location, format string, args ...any) {
+	panic(&Error{Message: "syntax error: " + fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+// next reads the following token, skipping whitespace, commas and
+// comments.
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\n':
+			p.advance(1)
+			p.line, p.col = p.line+1, 1
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			p.advance(1)
+			continue
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.advance(1)
+			}
+			continue
+		}
+		break
+	}
+	loc := Location{Line: p.line, Column: p.col}
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, loc: loc}
+		return
+	}
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.advance(3)
+		p.tok = token{kind: tokPunct, val: "...", loc: loc}
+	case strings.IndexByte("!$():=@[]{}|&", c) >= 0:
+		p.advance(1)
+		p.tok = token{kind: tokPunct, val: string(c), loc: loc}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.advance(1)
+		}
+		p.tok = token{kind: tokName, val: p.src[start:p.pos], loc: loc}
+	case c == '-' || isDigit(c):
+		p.tok = p.number(loc)
+	case c == '"':
+		p.tok = token{kind: tokString, val: p.string(loc), loc: loc}
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.failAt(loc, "unexpected character %q", r)
+	}
+}
+
+func (p *parser) number(loc Location) token {
+	start := p.pos
+	kind := tokInt
+	if p.src[p.pos] == '-' {
+		p.advance(1)
+	}
+	digits := func() {
+		n := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.advance(1)
+		}
+		if p.pos == n {
+			p.failAt(loc, "malformed number")
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokFloat
+		p.advance(1)
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokFloat
+		p.advance(1)
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.advance(1)
+		}
+		digits()
+	}
+	return token{kind: kind, val: p.src[start:p.pos], loc: loc}
+}
+
+// string reads a quoted string, or a """block string""" with its
+// indentation left as written.
+func (p *parser) string(loc Location) string {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		p.advance(3)
+		end := strings.Index(p.src[p.pos:], `"""`)
+		if end < 0 {
+			p.failAt(loc, "unterminated string")
+		}
+		s := p.src[p.pos : p.pos+end]
+		for _, c := range []byte(s) {
+			if c == '\n' {
+				p.line, p.col = p.line+1, 0
+			}
+			p.advance(1)
+		}
+		p.advance(3)
+		return s
+	}
+	p.advance(1)
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.failAt(loc, "unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == '"' {
+			p.advance(1)
+			return b.String()
+		}
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			b.WriteRune(r)
+			p.advance(size)
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			p.failAt(loc, "unterminated string")
+		}
+		esc := p.src[p.pos+1]
+		p.advance(2)
+		switch esc {
+		case '"', '\\', '/':
+			b.WriteByte(esc)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if p.pos+4 > len(p.src) {
+				p.failAt(loc, "malformed unicode escape")
+			}
+			n, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+			if err != nil {
+				p.failAt(loc, "malformed unicode escape")
+			}
+			b.WriteRune(rune(n))
+			p.advance(4)
+		default:
+			p.failAt(loc, "unknown escape \\%c", esc)
+		}
+	}
+}
+
+func (p *parser) advance(n int) {
+	p.pos += n
+	p.col += n
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
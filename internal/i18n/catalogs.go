@@ -0,0 +1,78 @@
+package i18n
+
+// The catalogs share their keys; a message's verbs are numbered, %[1]s
+// for its first argument, and every translation takes the same ones.
+// Numbers and dates come to them already formatted for the locale.
+
+var english = &Locale{
+	Tag:      "en",
+	Name:     "English",
+	Dir:      LTR,
+	decimal:  ".",
+	group:    ",",
+	dateTime: "Jan 2, 2006, 3:04 PM",
+	messages: map[string]string{
+		"list.separator": ", ",
+		"list.versus":    " vs ",
+
+		// Link previews of a session
+		"preview.title":          "%[1]s: %[2]s",
+		"preview.join":           "Join a game of %[1]s",
+		"preview.waiting":        "Waiting for players",
+		"preview.waiting_joined": "Waiting for players, %[1]s joined",
+		"preview.playing":        "In play",
+		"preview.finished":       "Finished",
+		"preview.stopped":        "Stopped",
+		"preview.session":        "%[1]s. Session %[2]s.",
+
+		// A session's timeline exported as text
+		"timeline.title":     "Session %[1]s: %[2]s",
+		"timeline.exported":  "Exported %[1]s UTC",
+		"timeline.entry":     "[%[1]s] %[2]s",
+		"timeline.joined":    "%[1]s joined",
+		"timeline.started":   "The match started: %[1]s",
+		"timeline.moved":     "%[1]s made move %[2]s",
+		"timeline.finished":  "The match finished",
+		"timeline.abandoned": "The match was abandoned",
+		"timeline.result":    "%[1]s. %[2]s, score %[3]s",
+		"timeline.chat":      "%[1]s: %[2]s",
+	},
+}
+
+var arabic = &Locale{
+	Tag:      "ar",
+	Name:     "العربية",
+	Dir:      RTL,
+	decimal:  "٫",
+	group:    "٬",
+	digits:   []rune("٠١٢٣٤٥٦٧٨٩"),
+	dateTime: "2 January 2006، 15:04",
+	months: []string{
+		"يناير", "فبراير", "مارس", "أبريل", "مايو", "يونيو",
+		"يوليو", "أغسطس", "سبتمبر", "أكتوبر", "نوفمبر", "ديسمبر",
+	},
+	messages: map[string]string{
+		"list.separator": "، ",
+		"list.versus":    " ضد ",
+
+		"preview.title":          "%[1]s: %[2]s",
+		"preview.join":           "انضم إلى لعبة %[1]s",
+		"preview.waiting":        "بانتظار اللاعبين",
+		"preview.waiting_joined": "بانتظار اللاعبين، انضم %[1]s",
+		"preview.playing":        "قيد اللعب",
+		"preview.finished":       "انتهت",
+		"preview.stopped":        "متوقفة",
+		"preview.session":        "%[1]s. الجلسة %[2]s.",
+
+		"timeline.title":     "الجلسة %[1]s: %[2]s",
+		"timeline.exported":  "صُدِّرت في %[1]s بالتوقيت العالمي",
+		"timeline.entry":     "[%[1]s] %[2]s",
+		"timeline.joined":    "انضم %[1]s",
+		"timeline.started":   "بدأت المباراة: %[1]s",
+		"timeline.moved":     "لعب %[1]s النقلة %[2]s",
+		"timeline.finished":  "انتهت المباراة",
+		"timeline.abandoned": "تُركت المباراة دون إكمال",
+		"timeline.result":    "%[1]s. %[2]s، النقاط: %[3]s",
+		"timeline.chat":      "%[1]s: %[2]s",
+	},
+}
@@ -0,0 +1,172 @@
+// Package i18n picks the locale a request asks for and formats text for
+// it: messages from the locale's catalog, numbers and dates in its
+// conventions, and the direction its script is written in.
+package i18n
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Text directions, as the HTML dir attribute takes them.
+const (
+	LTR = "ltr"
+	RTL = "rtl"
+)
+
+// Locale is a language the server speaks: its catalog of messages and how
+// it writes numbers and dates.
+type Locale struct {
+	Tag  string // BCP 47 language tag, such as "en"
+	Name string // the language's name in itself
+	Dir  string // LTR or RTL
+
+	decimal string // separates a number's fraction
+	group   string // separates its thousands
+	// digits replace 0 to 9; empty keeps ASCII digits.
+	digits []rune
+	// dateTime is a time.Format layout. A locale with months of its own
+	// spells the month out as "January", which is replaced by its name.
+	dateTime string
+	months   []string
+	messages map[string]string
+}
+
+// Default is the locale used when a request asks for none the server
+// has.
+var Default = english
+
+var locales = map[string]*Locale{}
+
+func init() {
+	for _, l := range []*Locale{english, arabic} {
+		locales[l.Tag] = l
+	}
+}
+
+// Locales lists every locale, by tag.
+func Locales() []*Locale {
+	list := make([]*Locale, 0, len(locales))
+	for _, l := range locales {
+		list = append(list, l)
+	}
+	slices.SortFunc(list, func(a, b *Locale) int { return strings.Compare(a.Tag, b.Tag) })
+	return list
+}
+
+// Lookup returns the locale for a language tag, or for its language
+// alone when there is none for the region or script, such as "ar" for
+// "ar-EG".
+func Lookup(tag string) (*Locale, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if l, ok := locales[tag]; ok {
+		return l, true
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	l, ok := locales[base]
+	return l, ok
+}
+
+// Match returns the locale an Accept-Language header prefers most among
+// those the server has, or Default.
+func Match(acceptLanguage string) *Locale {
+	type choice struct {
+		tag string
+		q   float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if tag != "" && q > 0 {
+			choices = append(choices, choice{tag, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	for _, c := range choices {
+		if l, ok := Lookup(c.tag); ok {
+			return l
+		}
+	}
+	return Default
+}
+
+// T formats the message key from the locale's catalog with args, falling
+// back to Default's when the catalog lacks it and to the key itself when
+// neither has it. Messages number their verbs, as in %[1]s, so a
+// translation can put the arguments in its own order.
+func (l *Locale) T(key string, args ...any) string {
+	format, ok := l.messages[key]
+	if !ok {
+		if format, ok = Default.messages[key]; !ok {
+			return key
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// List joins items as the locale writes a list.
+func (l *Locale) List(items []string) string {
+	return strings.Join(items, l.T("list.separator"))
+}
+
+// FormatInt writes n with the locale's thousands separator and digits.
+func (l *Locale) FormatInt(n int64) string {
+	return l.FormatNumber(float64(n), 0)
+}
+
+// FormatNumber writes f rounded to decimals places, with the locale's
+// separators and digits.
+func (l *Locale) FormatNumber(f float64, decimals int) string {
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+	var b strings.Builder
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		b.WriteString("-")
+		s = rest
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString(l.decimal)
+		b.WriteString(frac)
+	}
+	return l.localDigits(b.String())
+}
+
+// FormatDateTime writes t, in its own time zone, as the locale writes a
+// date and time.
+func (l *Locale) FormatDateTime(t time.Time) string {
+	s := t.Format(l.dateTime)
+	if l.months != nil {
+		s = strings.Replace(s, t.Month().String(), l.months[t.Month()-1], 1)
+	}
+	return l.localDigits(s)
+}
+
+func (l *Locale) localDigits(s string) string {
+	if l.digits == nil {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return l.digits[r-'0']
+		}
+		return r
+	}, s)
+}
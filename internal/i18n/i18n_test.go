@@ -0,0 +1,78 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+var verb = regexp.MustCompile(`%\[\d+\][a-z]`)
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, l := range Locales() {
+		for key, format := range Default.messages {
+			translated, ok := l.messages[key]
+			if !ok {
+				t.Errorf("%s: missing %s", l.Tag, key)
+				continue
+			}
+			want, got := verb.FindAllString(format, -1), verb.FindAllString(translated, -1)
+			slices.Sort(want)
+			slices.Sort(got)
+			if !slices.Equal(want, got) {
+				t.Errorf("%s: %s takes %v, want %v", l.Tag, key, got, want)
+			}
+		}
+		for key := range l.messages {
+			if _, ok := Default.messages[key]; !ok {
+				t.Errorf("%s: %s is not in %s", l.Tag, key, Default.Tag)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, c := range []struct {
+		header, want string
+	}{
+		{"", "en"},
+		{"fr-FR, de;q=0.8", "en"},
+		{"ar-EG", "ar"},
+		{"fr;q=0.9, AR;q=0.8, en;q=0.5", "ar"},
+		{"en;q=0.2, ar;q=0.7", "ar"},
+		{"ar;q=0, en", "en"},
+		{"ar;q=bad, en", "en"},
+	} {
+		if got := Match(c.header); got.Tag != c.want {
+			t.Errorf("Match(%q) = %s, want %s", c.header, got.Tag, c.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	ar, _ := Lookup("ar")
+	at := time.Date(2026, time.March, 7, 14, 5, 0, 0, time.UTC)
+	for _, c := range []struct {
+		got, want string
+	}{
+		{english.FormatInt(1234567), "1,234,567"},
+		{english.FormatInt(-999), "-999"},
+		{english.FormatNumber(-1234.5, 1), "-1,234.5"},
+		{ar.FormatInt(1234567), "١٬٢٣٤٬٥٦٧"},
+		{ar.FormatNumber(2.5, 1), "٢٫٥"},
+		{english.FormatDateTime(at), "Mar 7, 2026, 2:05 PM"},
+		{ar.FormatDateTime(at), "٧ مارس ٢٠٢٦، ١٤:٠٥"},
+		{english.List([]string{"alice", "bob"}), "alice, bob"},
+		{ar.List([]string{"alice", "bob"}), "alice، bob"},
+		{ar.T("timeline.moved", "alice", "٣"), "لعب alice النقلة ٣"},
+		{ar.T("no.such.key"), "no.such.key"},
+	} {
+		if c.got != c.want {
+			t.Errorf("got %q, want %q", c.got, c.want)
+		}
+	}
+	if ar.Dir != RTL || english.Dir != LTR {
+		t.Fatalf("expected Arabic right to left and English left to right")
+	}
+}
@@ -0,0 +1,93 @@
+// Package mail sends templated notification emails over SMTP.
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Mailer delivers a plain-text email.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends mail through an SMTP relay.
+type SMTPMailer struct {
+	addr string // host:port
+	from string
+	auth smtp.Auth // nil for relays without authentication
+}
+
+// NewSMTPMailer creates a mailer for the relay at addr. If username is
+// empty no authentication is attempted.
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, msg.Bytes())
+}
+
+// Each template's first line is the subject; the rest is the body.
+var templates = template.Must(template.New("").Parse(`
+{{define "verify"}}Confirm your email address
+Hi {{.PlayerID}},
+
+Confirm this address to receive game notifications:
+
+{{.URL}}
+
+If you did not ask for this, ignore this email.
+{{end}}
+
+{{define "turn"}}Your move in {{.GameType}}
+Hi {{.PlayerID}},
+
+{{if .Opponent}}{{.Opponent}} has moved. {{end}}It's your turn in {{.GameType}}.
+
+{{.URL}}
+{{end}}
+
+{{define "challenge"}}{{.Opponent}} challenged you to {{.GameType}}
+Hi {{.PlayerID}},
+
+{{.Opponent}} has challenged you to a game of {{.GameType}}. Answer from the lobby:
+
+{{.URL}}
+{{end}}
+`))
+
+// TemplateData is what the email templates may refer to.
+type TemplateData struct {
+	PlayerID string
+	Opponent string
+	GameType string
+	URL      string
+}
+
+// Render executes the named template, returning its subject and body.
+func Render(name string, data TemplateData) (subject, body string, err error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", "", err
+	}
+	subject, body, _ = strings.Cut(strings.TrimLeft(buf.String(), "\n"), "\n")
+	return subject, body, nil
+}
@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"games/internal/event"
+	"games/internal/storage"
+)
+
+type sentMail struct{ to, subject, body string }
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []sentMail
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentMail{to, subject, body})
+	return nil
+}
+
+type fakeDirectory struct {
+	contacts map[string]*storage.EmailContactRow
+	online   map[string]bool
+}
+
+func (d *fakeDirectory) EmailContact(ctx context.Context, playerID string) (*storage.EmailContactRow, error) {
+	c, ok := d.contacts[playerID]
+	if !ok {
+		return nil, errors.New("no email")
+	}
+	return c, nil
+}
+
+func (d *fakeDirectory) IsOnline(playerID string) bool { return d.online[playerID] }
+
+func TestRender(t *testing.T) {
+	subject, body, err := Render("challenge", TemplateData{PlayerID: "bob", Opponent: "alice", GameType: "tictactoe", URL: "http://x/"})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if subject != "alice challenged you to tictactoe" {
+		t.Fatalf("subject = %q", subject)
+	}
+	if !strings.HasPrefix(body, "Hi bob,") || !strings.Contains(body, "http://x/") {
+		t.Fatalf("unexpected body: %q", body)
+	}
+}
+
+func TestNotifierRespectsPreferences(t *testing.T) {
+	mailer := &fakeMailer{}
+	dir := &fakeDirectory{
+		contacts: map[string]*storage.EmailContactRow{
+			"bob":   {PlayerID: "bob", Email: "bob@example.com", Verified: true, NotifyTurns: true, NotifyChallenges: false},
+			"carol": {PlayerID: "carol", Email: "carol@example.com", Verified: false, NotifyTurns: true},
+			"dave":  {PlayerID: "dave", Email: "dave@example.com", Verified: true, NotifyTurns: true},
+		},
+		online: map[string]bool{"dave": true},
+	}
+	n := NewNotifier(mailer, dir, "http://games.example")
+
+	events := make(chan event.Event, 5)
+	turn := event.Event{Type: event.YourTurn, SessionCode: "abc", GameType: "tictactoe", Players: []string{"alice", "bob"}, Recipients: []string{"bob"}}
+	events <- turn
+	events <- turn // within the reminder interval: not sent again
+	events <- event.Event{Type: event.ChallengeIssued, GameType: "tictactoe", Recipients: []string{"bob"}}
+	events <- event.Event{Type: event.YourTurn, SessionCode: "def", Recipients: []string{"carol"}}
+	events <- event.Event{Type: event.YourTurn, SessionCode: "ghi", Recipients: []string{"dave"}}
+	close(events)
+	n.Run(events)
+
+	if len(mailer.sent) != 1 {
+		t.Fatalf("expected exactly one email, got %+v", mailer.sent)
+	}
+	m := mailer.sent[0]
+	if m.to != "bob@example.com" || m.subject != "Your move in tictactoe" {
+		t.Fatalf("unexpected email: %+v", m)
+	}
+	if !strings.Contains(m.body, "http://games.example/session.html?code=abc&player=bob") {
+		t.Fatalf("expected session link in body: %q", m.body)
+	}
+}
+
+func TestSendVerification(t *testing.T) {
+	mailer := &fakeMailer{}
+	n := NewNotifier(mailer, &fakeDirectory{}, "http://games.example")
+	if err := n.SendVerification("bob", "bob@example.com", "tok"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(mailer.sent) != 1 || !strings.Contains(mailer.sent[0].body, "http://games.example/api/email/verify?token=tok") {
+		t.Fatalf("unexpected verification mail: %+v", mailer.sent)
+	}
+}
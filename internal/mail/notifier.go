@@ -0,0 +1,120 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"games/internal/event"
+	"games/internal/storage"
+)
+
+// Directory is what the notifier needs to know about players. It is
+// satisfied by *session.Manager.
+type Directory interface {
+	EmailContact(ctx context.Context, playerID string) (*storage.EmailContactRow, error)
+	IsOnline(playerID string) bool
+}
+
+// reminderInterval is the least time between turn reminders for the same
+// player and session, so a quick back-and-forth is not an email per move.
+const reminderInterval = time.Hour
+
+// Notifier emails players about bus events addressed to them, if they have
+// a verified address, opted in, and are not connected to a game.
+type Notifier struct {
+	mailer  Mailer
+	dir     Directory
+	baseURL string // public site URL for links, without trailing slash
+
+	mu       sync.Mutex
+	reminded map[string]time.Time // player + session -> last turn reminder
+}
+
+// NewNotifier creates a notifier linking to baseURL.
+func NewNotifier(mailer Mailer, dir Directory, baseURL string) *Notifier {
+	return &Notifier{mailer: mailer, dir: dir, baseURL: baseURL, reminded: make(map[string]time.Time)}
+}
+
+// SendVerification emails the link that confirms a player's address.
+func (n *Notifier) SendVerification(playerID, email, token string) error {
+	subject, body, err := Render("verify", TemplateData{
+		PlayerID: playerID,
+		URL:      n.baseURL + "/api/email/verify?token=" + url.QueryEscape(token),
+	})
+	if err != nil {
+		return err
+	}
+	return n.mailer.Send(email, subject, body)
+}
+
+// Run delivers notifications for events until the channel is closed.
+func (n *Notifier) Run(events <-chan event.Event) {
+	for e := range events {
+		for _, playerID := range e.Recipients {
+			n.notify(e, playerID)
+		}
+	}
+}
+
+func (n *Notifier) notify(e event.Event, playerID string) {
+	var name string
+	switch e.Type {
+	case event.YourTurn:
+		name = "turn"
+	case event.ChallengeIssued:
+		name = "challenge"
+	default:
+		return
+	}
+	if n.dir.IsOnline(playerID) {
+		return
+	}
+	c, err := n.dir.EmailContact(context.Background(), playerID)
+	if err != nil || !c.Verified {
+		return
+	}
+	if (name == "turn" && !c.NotifyTurns) || (name == "challenge" && !c.NotifyChallenges) {
+		return
+	}
+	if name == "turn" && !n.shouldRemind(playerID, e.SessionCode) {
+		return
+	}
+
+	data := TemplateData{PlayerID: playerID, GameType: e.GameType, URL: n.baseURL + "/"}
+	for _, p := range e.Players {
+		if p != playerID {
+			data.Opponent = p
+			break
+		}
+	}
+	if e.SessionCode != "" {
+		data.URL = n.baseURL + "/session.html?code=" + e.SessionCode + "&player=" + url.QueryEscape(playerID)
+	}
+	subject, body, err := Render(name, data)
+	if err != nil {
+		log.Printf("mail: render %s: %v", name, err)
+		return
+	}
+	if err := n.mailer.Send(c.Email, subject, body); err != nil {
+		log.Printf("mail to %s: %v", playerID, err)
+	}
+}
+
+func (n *Notifier) shouldRemind(playerID, sessionCode string) bool {
+	key := playerID + "\x00" + sessionCode
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if last, ok := n.reminded[key]; ok && time.Since(last) < reminderInterval {
+		return false
+	}
+	for k, last := range n.reminded {
+		if time.Since(last) >= reminderInterval {
+			delete(n.reminded, k)
+		}
+	}
+	n.reminded[key] = time.Now()
+	return true
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Counter counts events, separately for each combination of label
+// values.
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries // by label values joined with "\x00"
+}
+
+type counterSeries struct {
+	values []string
+	count  uint64
+}
+
+// NewCounter returns a counter named name, with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{name: name, help: help, labels: labels, series: make(map[string]*counterSeries)}
+}
+
+// Inc counts one event under the label values, given in the order of the
+// counter's label names.
+func (c *Counter) Inc(values ...string) {
+	if len(values) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", c.name, len(c.labels), len(values)))
+	}
+	key := strings.Join(values, "\x00")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{values: values}
+		c.series[key] = s
+	}
+	s.count++
+}
+
+// Count returns how many events were counted under the label values.
+func (c *Counter) Count(values ...string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[strings.Join(values, "\x00")]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// WriteTo writes the counter in the text exposition format, its series
+// in label order.
+func (c *Counter) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := c.series[k]
+		fmt.Fprintf(&b, "%s%s %d\n", c.name, braced(labelPairs(c.labels, s.values)), s.count)
+	}
+	c.mu.Unlock()
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
@@ -0,0 +1,170 @@
+// Package metrics keeps latency histograms and event counters and writes
+// them in the Prometheus text exposition format, for scraping and for
+// alerts on percentiles.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets a
+// latency falls into: fine below 10ms, where messages and queries should
+// be, and coarse up to the seconds a stalled database takes.
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts durations into buckets, separately for each
+// combination of label values.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series // by label values joined with "\x00"
+}
+
+type series struct {
+	values []string
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram returns a histogram of durations named name, with the
+// given label names and DefaultBuckets.
+func NewHistogram(name, help string, labels ...string) *Histogram {
+	return &Histogram{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: DefaultBuckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// Observe records a duration under the label values, given in the order
+// of the histogram's label names.
+func (h *Histogram) Observe(d time.Duration, values ...string) {
+	if len(values) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", h.name, len(h.labels), len(values)))
+	}
+	secs := d.Seconds()
+	i := sort.SearchFloat64s(h.buckets, secs)
+	key := strings.Join(values, "\x00")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &series{values: values, counts: make([]uint64, len(h.buckets)+1)}
+		h.series[key] = s
+	}
+	s.counts[i]++
+	s.sum += secs
+	s.count++
+}
+
+// Count returns how many durations were observed under the label values.
+func (h *Histogram) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[strings.Join(values, "\x00")]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// WriteTo writes the histogram in the text exposition format, its series
+// in label order.
+func (h *Histogram) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		var cumulative uint64
+		for i, n := range s.counts {
+			cumulative += n
+			le := "+Inf"
+			if i < len(h.buckets) {
+				le = strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(&b, "%s_bucket{%s} %d\n", h.name, labelPairs(h.labels, s.values, "le", le), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_sum%s %s\n", h.name, braced(labelPairs(h.labels, s.values)), strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count%s %d\n", h.name, braced(labelPairs(h.labels, s.values)), s.count)
+	}
+	h.mu.Unlock()
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labelPairs formats label values, and any extra name and value pairs,
+// as name="value" pairs.
+func labelPairs(labels, values []string, extra ...string) string {
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, v := range values {
+		pairs = append(pairs, labels[i]+"="+quote(v))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+quote(extra[i+1]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func braced(pairs string) string {
+	if pairs == "" {
+		return ""
+	}
+	return "{" + pairs + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+// Metric is a histogram or counter, as a Registry writes it.
+type Metric interface {
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// Registry is a set of metrics written out together.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []Metric
+}
+
+// Register adds metrics to the registry.
+func (r *Registry) Register(ms ...Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, ms...)
+}
+
+// WriteTo writes every registered metric, in the order registered.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	ms := append([]Metric(nil), r.metrics...)
+	r.mu.Unlock()
+	var total int64
+	for _, m := range ms {
+		n, err := m.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
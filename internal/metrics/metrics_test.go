@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramExposition(t *testing.T) {
+	h := NewHistogram("test_duration_seconds", "How long tests take.", "game")
+	h.Observe(3*time.Millisecond, "tictactoe")
+	h.Observe(20*time.Millisecond, "tictactoe")
+	h.Observe(30*time.Second, "tictactoe")
+	h.Observe(time.Millisecond, `say "hi"`)
+
+	var r Registry
+	r.Register(h)
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# HELP test_duration_seconds How long tests take.\n# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{game="tictactoe",le="0.0025"} 0` + "\n",
+		`test_duration_seconds_bucket{game="tictactoe",le="0.005"} 1` + "\n",
+		`test_duration_seconds_bucket{game="tictactoe",le="0.025"} 2` + "\n",
+		`test_duration_seconds_bucket{game="tictactoe",le="10"} 2` + "\n",
+		`test_duration_seconds_bucket{game="tictactoe",le="+Inf"} 3` + "\n",
+		`test_duration_seconds_sum{game="tictactoe"} 30.023` + "\n",
+		`test_duration_seconds_count{game="tictactoe"} 3` + "\n",
+		`test_duration_seconds_bucket{game="say \"hi\"",le="0.001"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if h.Count("tictactoe") != 3 {
+		t.Errorf("expected 3 observations, got %d", h.Count("tictactoe"))
+	}
+}
+
+func TestCounterExposition(t *testing.T) {
+	c := NewCounter("test_events_total", "Events seen.", "action", "reason")
+	c.Inc("kept", "active")
+	c.Inc("kept", "active")
+	c.Inc("removed", "empty")
+
+	var r Registry
+	r.Register(c)
+	var b strings.Builder
+	r.WriteTo(&b)
+	out := b.String()
+	for _, want := range []string{
+		"# HELP test_events_total Events seen.\n# TYPE test_events_total counter\n",
+		`test_events_total{action="kept",reason="active"} 2` + "\n",
+		`test_events_total{action="removed",reason="empty"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if c.Count("removed", "empty") != 1 || c.Count("removed", "idle") != 0 {
+		t.Error("unexpected counts")
+	}
+}
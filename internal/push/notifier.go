@@ -0,0 +1,118 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"games/internal/event"
+	"games/internal/storage"
+)
+
+// Directory is what the notifier needs to know about players. It is
+// satisfied by *session.Manager.
+type Directory interface {
+	PushSubscriptions(ctx context.Context, playerID string) ([]storage.PushSubscriptionRow, error)
+	DeletePushSubscription(ctx context.Context, endpoint string) error
+	IsOnline(playerID string) bool
+}
+
+// Message is the JSON payload the service worker turns into a notification.
+type Message struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"` // opened when the notification is clicked
+	Tag   string `json:"tag"` // replaces an earlier notification with the same tag
+}
+
+const messageTTL = 24 * time.Hour
+
+// Notifier pushes bus events addressed to specific players to their
+// browsers. Players connected to a game are not pushed, since they already
+// see the event in the page.
+type Notifier struct {
+	sender *Sender
+	dir    Directory
+	prefix string // path the site is served under, such as /t/school
+}
+
+// NewNotifier creates a notifier that delivers through sender.
+func NewNotifier(sender *Sender, dir Directory) *Notifier {
+	return &Notifier{sender: sender, dir: dir}
+}
+
+// SetPathPrefix makes notifications link to pages under prefix, for a
+// tenant's players.
+func (n *Notifier) SetPathPrefix(prefix string) {
+	n.prefix = prefix
+}
+
+// PublicKey returns the VAPID key browsers subscribe with.
+func (n *Notifier) PublicKey() string {
+	return n.sender.keys.PublicKey()
+}
+
+// Run delivers notifications for events until the channel is closed.
+func (n *Notifier) Run(events <-chan event.Event) {
+	for e := range events {
+		for _, playerID := range e.Recipients {
+			msg, ok := messageFor(e, playerID, n.prefix)
+			if !ok || n.dir.IsOnline(playerID) {
+				continue
+			}
+			n.notify(playerID, msg)
+		}
+	}
+}
+
+func (n *Notifier) notify(playerID string, msg Message) {
+	subs, err := n.dir.PushSubscriptions(context.Background(), playerID)
+	if err != nil {
+		log.Printf("push: list subscriptions for %s: %v", playerID, err)
+		return
+	}
+	payload, _ := json.Marshal(msg)
+	for _, row := range subs {
+		var sub Subscription
+		sub.Endpoint = row.Endpoint
+		sub.Keys.P256dh = row.P256dh
+		sub.Keys.Auth = row.Auth
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := n.sender.Send(ctx, sub, payload, messageTTL)
+		cancel()
+		switch {
+		case errors.Is(err, ErrGone), errors.Is(err, ErrEndpoint):
+			// Gone, or kept from before endpoints were checked.
+			n.dir.DeletePushSubscription(context.Background(), row.Endpoint)
+		case err != nil:
+			log.Printf("push to %s: %v", playerID, err)
+		}
+	}
+}
+
+// messageFor describes an event to one of its recipients, linking to pages
+// under prefix, and reports false for events that do not warrant a
+// notification.
+func messageFor(e event.Event, playerID, prefix string) (Message, bool) {
+	opponent := ""
+	for _, p := range e.Players {
+		if p != playerID {
+			opponent = p
+			break
+		}
+	}
+	sessionURL := prefix + "/session.html?code=" + e.SessionCode + "&player=" + playerID
+	switch e.Type {
+	case event.YourTurn:
+		return Message{Title: "Your move", Body: "It's your turn in " + e.GameType + ".", URL: sessionURL, Tag: "turn-" + e.SessionCode}, true
+	case event.ChallengeIssued:
+		return Message{Title: "New challenge", Body: opponent + " challenged you to " + e.GameType + ".", URL: prefix + "/", Tag: "challenge-" + e.ChallengeID}, true
+	case event.LobbyFull:
+		return Message{Title: "Lobby full", Body: "Your " + e.GameType + " session is ready to start.", URL: sessionURL, Tag: "lobby-" + e.SessionCode}, true
+	default:
+		return Message{}, false
+	}
+}
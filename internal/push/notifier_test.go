@@ -0,0 +1,91 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"games/internal/event"
+	"games/internal/storage"
+)
+
+type fakeDirectory struct {
+	mu      sync.Mutex
+	subs    map[string][]storage.PushSubscriptionRow
+	online  map[string]bool
+	deleted []string
+}
+
+func (d *fakeDirectory) PushSubscriptions(ctx context.Context, playerID string) ([]storage.PushSubscriptionRow, error) {
+	return d.subs[playerID], nil
+}
+
+func (d *fakeDirectory) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deleted = append(d.deleted, endpoint)
+	return nil
+}
+
+func (d *fakeDirectory) IsOnline(playerID string) bool {
+	return d.online[playerID]
+}
+
+func TestNotifierDelivers(t *testing.T) {
+	keys, _ := GenerateKeys()
+	var mu sync.Mutex
+	bodies := map[string][]byte{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	bob, bobSub := newTestBrowser(t, ts.URL+"/bob")
+	_, carolSub := newTestBrowser(t, ts.URL+"/carol")
+	_, goneSub := newTestBrowser(t, ts.URL+"/gone")
+	row := func(player string, sub Subscription) storage.PushSubscriptionRow {
+		return storage.PushSubscriptionRow{Endpoint: sub.Endpoint, PlayerID: player, P256dh: sub.Keys.P256dh, Auth: sub.Keys.Auth}
+	}
+	dir := &fakeDirectory{
+		subs: map[string][]storage.PushSubscriptionRow{
+			"bob":   {row("bob", bobSub), row("bob", goneSub)},
+			"carol": {row("carol", carolSub)},
+		},
+		online: map[string]bool{"carol": true},
+	}
+
+	events := make(chan event.Event, 3)
+	events <- event.Event{Type: event.ChallengeIssued, GameType: "tictactoe", Players: []string{"alice", "bob"}, ChallengeID: "ch-1", Recipients: []string{"bob"}}
+	events <- event.Event{Type: event.YourTurn, SessionCode: "abc", GameType: "tictactoe", Recipients: []string{"carol"}}
+	events <- event.Event{Type: event.MatchStarted, SessionCode: "abc", Players: []string{"bob"}}
+	close(events)
+	n := NewNotifier(testSender(keys), dir)
+	n.SetPathPrefix("/t/school")
+	n.Run(events)
+
+	if _, ok := bodies["/carol"]; ok {
+		t.Fatal("online players should not be pushed")
+	}
+	var msg Message
+	if err := json.Unmarshal(bob.decrypt(t, bodies["/bob"]), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if msg.Title != "New challenge" || msg.Body != "alice challenged you to tictactoe." || msg.URL != "/t/school/" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if len(dir.deleted) != 1 || dir.deleted[0] != goneSub.Endpoint {
+		t.Fatalf("expected gone subscription deleted, got %v", dir.deleted)
+	}
+}
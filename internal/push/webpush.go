@@ -0,0 +1,279 @@
+// Package push delivers Web Push notifications (RFC 8030) with VAPID
+// authentication (RFC 8292) and aes128gcm payload encryption (RFC 8291).
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrGone is returned when the push service reports that a
+	// subscription no longer exists and should be deleted.
+	ErrGone = errors.New("push subscription gone")
+	// ErrEndpoint is returned for a subscription whose endpoint is not an
+	// https URL of a public host, which no browser's push service lacks.
+	ErrEndpoint = errors.New("push endpoint must be an https URL of a public host")
+)
+
+// Subscription is a browser's PushSubscription as serialized by toJSON().
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// Keys is a VAPID application server key pair.
+type Keys struct {
+	private *ecdsa.PrivateKey
+	public  []byte // uncompressed P-256 point
+}
+
+// GenerateKeys creates a new VAPID key pair.
+func GenerateKeys() (*Keys, error) {
+	k, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return keysFromECDH(k)
+}
+
+// ParseKeys loads a VAPID key pair from the base64url private scalar, as
+// printed by Keys.PrivateKey.
+func ParseKeys(privateKey string) (*Keys, error) {
+	d, err := decodeBase64(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode VAPID private key: %w", err)
+	}
+	k, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("parse VAPID private key: %w", err)
+	}
+	return keysFromECDH(k)
+}
+
+func keysFromECDH(k *ecdh.PrivateKey) (*Keys, error) {
+	pub := k.PublicKey().Bytes()
+	priv := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(k.Bytes()),
+	}
+	return &Keys{private: priv, public: pub}, nil
+}
+
+// PublicKey returns the base64url application server key browsers pass to
+// pushManager.subscribe.
+func (k *Keys) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(k.public)
+}
+
+// PrivateKey returns the base64url private scalar for configuration.
+func (k *Keys) PrivateKey() string {
+	return base64.RawURLEncoding.EncodeToString(k.private.D.FillBytes(make([]byte, 32)))
+}
+
+// Sender encrypts and delivers push messages.
+type Sender struct {
+	keys          *Keys
+	subject       string // contact URI for the push service, e.g. "mailto:ops@example.com"
+	client        *http.Client
+	checkEndpoint func(string) error // CheckEndpoint but in tests
+}
+
+// NewSender creates a sender that signs requests with keys.
+func NewSender(keys *Keys, subject string) *Sender {
+	return &Sender{keys: keys, subject: subject, client: &http.Client{Timeout: 10 * time.Second}, checkEndpoint: CheckEndpoint}
+}
+
+// CheckEndpoint returns ErrEndpoint unless endpoint is an https URL whose
+// host is not localhost or a loopback, private or link-local address, so
+// subscribing cannot aim the server's requests inside its own network.
+func CheckEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return ErrEndpoint
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrEndpoint
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if ip = ip.Unmap(); !ip.IsGlobalUnicast() || ip.IsPrivate() {
+			return ErrEndpoint
+		}
+	}
+	return nil
+}
+
+// Send delivers payload to one subscription. Messages are kept by the push
+// service for up to ttl while the browser is offline.
+func (s *Sender) Send(ctx context.Context, sub Subscription, payload []byte, ttl time.Duration) error {
+	if err := s.checkEndpoint(sub.Endpoint); err != nil {
+		return err
+	}
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	token, err := s.vapidToken(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Authorization", "vapid t="+token+", k="+s.keys.PublicKey())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("push service returned %s", resp.Status)
+	}
+	return nil
+}
+
+// vapidToken signs a JWT for the push service that owns endpoint.
+func (s *Sender) vapidToken(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parse endpoint: %w", err)
+	}
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, _ := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.subject,
+	})
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.keys.private, digest[:])
+	if err != nil {
+		return "", err
+	}
+	raw := make([]byte, 64)
+	r.FillBytes(raw[:32])
+	sig.FillBytes(raw[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+const recordSize = 4096
+
+// encrypt builds an aes128gcm message body for sub as described in RFC 8291.
+func encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	uaPublic, err := decodeBase64(sub.Keys.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("decode p256dh: %w", err)
+	}
+	authSecret, err := decodeBase64(sub.Keys.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("decode auth: %w", err)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("parse p256dh: %w", err)
+	}
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	cek, nonce, err := deriveKeys(shared, authSecret, salt, uaPublic, asPublic)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload)+1+gcm.Overhead() > recordSize {
+		return nil, fmt.Errorf("payload too large: %d bytes", len(payload))
+	}
+	plaintext := append(append([]byte{}, payload...), 0x02) // last-record delimiter
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// deriveKeys computes the content encryption key and nonce from the ECDH
+// shared secret.
+func deriveKeys(shared, authSecret, salt, uaPublic, asPublic []byte) (cek, nonce []byte, err error) {
+	prkKey, err := hkdf.Extract(sha256.New, shared, authSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cek, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16); err != nil {
+		return nil, nil, err
+	}
+	if nonce, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12); err != nil {
+		return nil, nil, err
+	}
+	return cek, nonce, nil
+}
+
+// decodeBase64 accepts base64url with or without padding, as browsers vary.
+func decodeBase64(s string) ([]byte, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
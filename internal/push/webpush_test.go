@@ -0,0 +1,190 @@
+package push
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBrowser is the user agent side of a subscription.
+type testBrowser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newTestBrowser(t *testing.T, endpoint string) (*testBrowser, Subscription) {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	b := &testBrowser{key: key, auth: make([]byte, 16)}
+	rand.Read(b.auth)
+	var sub Subscription
+	sub.Endpoint = endpoint
+	sub.Keys.P256dh = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	sub.Keys.Auth = base64.RawURLEncoding.EncodeToString(b.auth)
+	return b, sub
+}
+
+// decrypt reverses encrypt as a browser would.
+func (b *testBrowser) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Fatalf("record size = %d", rs)
+	}
+	idLen := int(body[20])
+	asPublic := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		t.Fatalf("parse server key: %v", err)
+	}
+	shared, err := b.key.ECDH(asKey)
+	if err != nil {
+		t.Fatalf("ecdh: %v", err)
+	}
+	cek, nonce, err := deriveKeys(shared, b.auth, salt, b.key.PublicKey().Bytes(), asPublic)
+	if err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatal("missing last-record delimiter")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestKeysRoundTrip(t *testing.T) {
+	keys, err := GenerateKeys()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	parsed, err := ParseKeys(keys.PrivateKey())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if parsed.PublicKey() != keys.PublicKey() {
+		t.Fatal("parsed key has a different public key")
+	}
+	if _, err := ParseKeys("not-a-key"); err == nil {
+		t.Fatal("expected error for invalid key")
+	}
+}
+
+// testSender is a Sender that delivers to the loopback addresses of test
+// servers.
+func testSender(keys *Keys) *Sender {
+	s := NewSender(keys, "mailto:test@example.com")
+	s.checkEndpoint = func(string) error { return nil }
+	return s
+}
+
+func TestSendEncryptsAndSigns(t *testing.T) {
+	keys, _ := GenerateKeys()
+	var gotBody []byte
+	var gotHeader http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	browser, sub := newTestBrowser(t, ts.URL+"/push/abc")
+	sender := testSender(keys)
+	if err := sender.Send(context.Background(), sub, []byte(`{"title":"hi"}`), time.Hour); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if got := browser.decrypt(t, gotBody); string(got) != `{"title":"hi"}` {
+		t.Fatalf("decrypted %q", got)
+	}
+	if gotHeader.Get("Content-Encoding") != "aes128gcm" || gotHeader.Get("TTL") != "3600" {
+		t.Fatalf("unexpected headers: %v", gotHeader)
+	}
+
+	// Verify the VAPID JWT signature with the advertised public key
+	auth := gotHeader.Get("Authorization")
+	token := strings.TrimPrefix(strings.Split(auth, ",")[0], "vapid t=")
+	if !strings.HasSuffix(auth, "k="+keys.PublicKey()) {
+		t.Fatalf("authorization missing public key: %s", auth)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT: %s", token)
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(&keys.private.PublicKey, digest[:], r, s) {
+		t.Fatal("JWT signature does not verify")
+	}
+}
+
+func TestSendReportsGone(t *testing.T) {
+	keys, _ := GenerateKeys()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer ts.Close()
+
+	_, sub := newTestBrowser(t, ts.URL)
+	err := testSender(keys).Send(context.Background(), sub, []byte("x"), time.Minute)
+	if !errors.Is(err, ErrGone) {
+		t.Fatalf("expected ErrGone, got %v", err)
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	for endpoint, ok := range map[string]bool{
+		"https://fcm.googleapis.com/fcm/send/abc":    true,
+		"https://updates.push.services.mozilla.com/": true,
+		"https://203.0.114.7/push":                   true,
+		"http://fcm.googleapis.com/fcm/send/abc":     false,
+		"ftp://push.example/1":                       false,
+		"https:///nohost":                            false,
+		"https://localhost/push":                     false,
+		"https://api.localhost./push":                false,
+		"https://127.0.0.1:8080/push":                false,
+		"https://10.0.0.5/push":                      false,
+		"https://192.168.1.1/push":                   false,
+		"https://169.254.169.254/latest/meta-data":   false,
+		"https://[::1]/push":                         false,
+		"https://[::ffff:127.0.0.1]/push":            false,
+		"https://[fd00::1]/push":                     false,
+		"https://0.0.0.0/push":                       false,
+	} {
+		if err := CheckEndpoint(endpoint); (err == nil) != ok {
+			t.Errorf("%s: expected ok %v, got %v", endpoint, ok, err)
+		}
+	}
+
+	// Nor is anything sent to an endpoint stored before it was checked.
+	keys, _ := GenerateKeys()
+	_, sub := newTestBrowser(t, "https://127.0.0.1/push")
+	if err := NewSender(keys, "mailto:test@example.com").Send(context.Background(), sub, []byte("x"), time.Minute); !errors.Is(err, ErrEndpoint) {
+		t.Fatalf("expected a loopback endpoint refused, got %v", err)
+	}
+}
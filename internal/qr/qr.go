@@ -0,0 +1,397 @@
+// Package qr encodes short text, such as join URLs, as QR codes (ISO/IEC
+// 18004) and renders them as SVG or PNG. It supports byte mode at error
+// correction level M in versions 1 to 10, enough for about 200 bytes.
+package qr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooLong is returned for text that does not fit in a version 10 symbol.
+var ErrTooLong = errors.New("qr: text too long")
+
+// Code is a QR symbol: a square grid of dark and light modules.
+type Code struct {
+	Size    int // modules per side
+	version int
+	modules [][]bool // [y][x], true = dark
+	isFunc  [][]bool // finder, timing, alignment and format modules
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// blockSpec is the error correction layout of one version at level M.
+type blockSpec struct {
+	ecPerBlock int
+	blocks     []int // data codewords in each block
+}
+
+var levelM = [...]blockSpec{
+	1:  {10, []int{16}},
+	2:  {16, []int{28}},
+	3:  {26, []int{44}},
+	4:  {18, []int{32, 32}},
+	5:  {24, []int{43, 43}},
+	6:  {16, []int{27, 27, 27, 27}},
+	7:  {18, []int{31, 31, 31, 31}},
+	8:  {22, []int{38, 38, 39, 39}},
+	9:  {22, []int{36, 36, 36, 37, 37}},
+	10: {26, []int{43, 43, 43, 43, 44}},
+}
+
+var alignmentPositions = [...][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// Encode builds the smallest symbol that holds text.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	for v := 1; v < len(levelM); v++ {
+		capacity := 0
+		for _, n := range levelM[v].blocks {
+			capacity += n
+		}
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > 8*capacity {
+			continue
+		}
+		c := newCode(v)
+		c.drawFunctionPatterns()
+		c.drawCodewords(interleave(v, encodeData(data, countBits, capacity)))
+		c.applyBestMask()
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(data))
+}
+
+func newCode(version int) *Code {
+	size := 17 + 4*version
+	c := &Code{Size: size, version: version}
+	c.modules = make([][]bool, size)
+	c.isFunc = make([][]bool, size)
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.isFunc[y] = make([]bool, size)
+	}
+	return c
+}
+
+// encodeData builds the data codewords: byte mode indicator, length,
+// payload, terminator and padding.
+func encodeData(data []byte, countBits, capacity int) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(uint32(len(data)), countBits)
+	for _, b := range data {
+		bits.append(uint32(b), 8)
+	}
+	bits.append(0, min(4, 8*capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	out := bits.bytes()
+	for pad := byte(0xEC); len(out) < capacity; pad ^= 0xEC ^ 0x11 {
+		out = append(out, pad)
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// interleave splits data into blocks, appends each block's error
+// correction, and interleaves the result as the symbol requires.
+func interleave(version int, data []byte) []byte {
+	spec := levelM[version]
+	gen := rsGenerator(spec.ecPerBlock)
+	var blocks, ecs [][]byte
+	for _, n := range spec.blocks {
+		blocks = append(blocks, data[:n])
+		ecs = append(ecs, rsRemainder(data[:n], gen))
+		data = data[n:]
+	}
+	var out []byte
+	for i := 0; i < spec.blocks[len(spec.blocks)-1]; i++ {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for _, ec := range ecs {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+func (c *Code) setFunc(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunc[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunc(6, i, i%2 == 0)
+		c.setFunc(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	pos := alignmentPositions[c.version]
+	last := len(pos) - 1
+	for i, y := range pos {
+		for j, x := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // overlaps a finder
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.setFunc(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	c.drawFormat(0) // reserve the area; redrawn once the mask is chosen
+	if c.version >= 7 {
+		c.drawVersion()
+	}
+}
+
+// drawFinder draws a finder pattern centred at (x, y) with its separator.
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.setFunc(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+// drawFormat writes both copies of the format information for level M and
+// the given mask, plus the dark module.
+func (c *Code) drawFormat(mask int) {
+	data := 0b00<<3 | mask // level M
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunc(8, i, bit(i))
+	}
+	c.setFunc(8, 7, bit(6))
+	c.setFunc(8, 8, bit(7))
+	c.setFunc(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunc(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		c.setFunc(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunc(8, c.Size-15+i, bit(i))
+	}
+	c.setFunc(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersion() {
+	rem := c.version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := c.version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunc(a, b, dark)
+		c.setFunc(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at
+// a time from the bottom right, skipping function modules.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert // upward
+				}
+				if c.isFunc[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.isFunc[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask tries every mask and keeps the one with the lowest penalty.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // masking is its own inverse
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// penalty scores the symbol by the four rules of the standard: long runs,
+// 2x2 blocks, finder-like sequences, and dark/light imbalance.
+func (c *Code) penalty() int {
+	p := 0
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return c.modules[x][y]
+		}
+		return c.modules[y][x]
+	}
+	finderLike := []bool{true, false, true, true, true, false, true}
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < c.Size; y++ {
+			run := 1
+			for x := 1; x <= c.Size; x++ {
+				if x < c.Size && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					p += 3 + run - 5
+				}
+				run = 1
+			}
+			for x := 0; x+7 <= c.Size; x++ {
+				match := true
+				for k, dark := range finderLike {
+					if at(x+k, y, transpose) != dark {
+						match = false
+						break
+					}
+				}
+				if match && (c.lightRun(x-4, x, y, transpose) || c.lightRun(x+7, x+11, y, transpose)) {
+					p += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				m := c.modules[y][x]
+				if c.modules[y][x+1] == m && c.modules[y+1][x] == m && c.modules[y+1][x+1] == m {
+					p += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return p + max(k, 0)*10
+}
+
+// lightRun reports whether modules from..to-1 of a line are all light;
+// positions outside the symbol count as light.
+func (c *Code) lightRun(from, to, y int, transpose bool) bool {
+	for x := from; x < to; x++ {
+		if x < 0 || x >= c.Size {
+			continue
+		}
+		if (transpose && c.modules[x][y]) || (!transpose && c.modules[y][x]) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,201 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// Version 1-M "HELLO WORLD" from the standard's worked example.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsGenerator(10)); !bytes.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+// decode reads a symbol back: it checks the format and version
+// information, removes the mask, collects the codewords in placement
+// order, verifies every block's error correction, and returns the text.
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	format := 0
+	for i := 0; i <= 5; i++ {
+		format |= b2i(c.Dark(8, i)) << i
+	}
+	format |= b2i(c.Dark(8, 7))<<6 | b2i(c.Dark(8, 8))<<7 | b2i(c.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		format |= b2i(c.Dark(14-i, 8)) << i
+	}
+	second := 0
+	for i := 0; i < 8; i++ {
+		second |= b2i(c.Dark(c.Size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= b2i(c.Dark(8, c.Size-15+i)) << i
+	}
+	if format != second {
+		t.Fatalf("format copies differ: %015b vs %015b", format, second)
+	}
+	format ^= 0x5412
+	if level := format >> 13; level != 0b00 {
+		t.Fatalf("expected level M, got %02b", level)
+	}
+	mask := format >> 10 & 7
+	rem := format >> 10
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	if format&0x3FF != rem {
+		t.Fatalf("format BCH mismatch")
+	}
+	if !c.Dark(8, c.Size-8) {
+		t.Fatal("missing dark module")
+	}
+
+	version := (c.Size - 17) / 4
+	if version >= 7 {
+		bits := 0
+		for i := 0; i < 18; i++ {
+			bits |= b2i(c.Dark(c.Size-11+i%3, i/3)) << i
+		}
+		if bits>>12 != version {
+			t.Fatalf("version info %d, want %d", bits>>12, version)
+		}
+	}
+
+	// Function modules are redrawn by a fresh symbol of the same version.
+	fresh := newCode(version)
+	fresh.drawFunctionPatterns()
+	var raw []byte
+	var cur byte
+	n := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if fresh.isFunc[y][x] {
+					continue
+				}
+				cur = cur<<1 | byte(b2i(c.Dark(x, y) != maskBit(mask, x, y)))
+				if n++; n%8 == 0 {
+					raw = append(raw, cur)
+					cur = 0
+				}
+			}
+		}
+	}
+
+	spec := levelM[version]
+	blocks := make([][]byte, len(spec.blocks))
+	i := 0
+	for col := 0; col < spec.blocks[len(spec.blocks)-1]; col++ {
+		for b, size := range spec.blocks {
+			if col < size {
+				blocks[b] = append(blocks[b], raw[i])
+				i++
+			}
+		}
+	}
+	var data []byte
+	gen := rsGenerator(spec.ecPerBlock)
+	for b := range blocks {
+		ec := make([]byte, spec.ecPerBlock)
+		for k := range ec {
+			ec[k] = raw[i+k*len(blocks)+b]
+		}
+		if !bytes.Equal(rsRemainder(blocks[b], gen), ec) {
+			t.Fatalf("block %d: error correction mismatch", b)
+		}
+		data = append(data, blocks[b]...)
+	}
+
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("expected byte mode, got %04b", data[0]>>4)
+	}
+	var bits bitBuffer
+	for _, d := range data {
+		bits.append(uint32(d), 8)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	length := 0
+	for _, bit := range bits[4 : 4+countBits] {
+		length = length<<1 | b2i(bit)
+	}
+	return string(bitBuffer(bits[4+countBits : 4+countBits+8*length]).bytes())
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, n := range []int{1, 14, 20, 40, 60, 84, 106, 122, 152, 180, 213} {
+		text := strings.Repeat("https://example.com/s/", 10)[:n]
+		c, err := Encode(text)
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", n, err)
+		}
+		if got := decode(t, c); got != text {
+			t.Fatalf("%d bytes (version %d): decoded %q", n, c.version, got)
+		}
+	}
+}
+
+func TestEncodePicksSmallestVersion(t *testing.T) {
+	c, _ := Encode("hello")
+	if c.Size != 21 {
+		t.Fatalf("expected version 1 (21 modules), got %d", c.Size)
+	}
+	c, _ = Encode(strings.Repeat("x", 15)) // 14 bytes is the version 1-M limit
+	if c.Size != 25 {
+		t.Fatalf("expected version 2 (25 modules), got %d", c.Size)
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(strings.Repeat("x", 214)); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("expected ErrTooLong, got %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	c, _ := Encode("https://example.com/s/abc123")
+	svg := string(c.SVG())
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+		t.Fatalf("unexpected svg: %.80s", svg)
+	}
+	data, err := c.PNG(4)
+	if err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if w := img.Bounds().Dx(); w != (c.Size+8)*4 {
+		t.Fatalf("expected width %d, got %d", (c.Size+8)*4, w)
+	}
+	// Top-left module of the finder pattern is dark, the quiet zone light.
+	if r, _, _, _ := img.At(16, 16).RGBA(); r != 0 {
+		t.Fatal("expected dark finder corner")
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Fatal("expected light quiet zone")
+	}
+}
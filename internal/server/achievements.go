@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+
+	"games/internal/event"
+	"games/internal/session"
+)
+
+// achievementEventBuffer is how many events the achievement engine and
+// its toasts may fall behind the bus before they miss some.
+const achievementEventBuffer = 256
+
+type achievementsResponse struct {
+	Achievements []session.AchievementInfo `json:"achievements"`
+}
+
+type playerAchievementsResponse struct {
+	Achievements []session.UnlockedAchievement `json:"achievements"`
+}
+
+// handleListAchievements describes every achievement players can earn.
+func (s *Server) handleListAchievements(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, achievementsResponse{Achievements: s.manager.Achievements()})
+}
+
+// handlePlayerAchievements returns the achievements a player unlocked,
+// for their profile.
+func (s *Server) handlePlayerAchievements(w http.ResponseWriter, r *http.Request) {
+	unlocked, err := s.manager.PlayerAchievements(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, playerAchievementsResponse{Achievements: unlocked})
+}
+
+// toastAchievements tells players of the achievements they unlock, on
+// those of their connections to the session they earned them in that
+// asked for toasts, until the channel is closed.
+func (s *Server) toastAchievements(events <-chan event.Event) {
+	for e := range events {
+		if e.Type != event.AchievementUnlocked {
+			continue
+		}
+		sess, ok := s.manager.Get(e.SessionCode)
+		if !ok {
+			continue
+		}
+		info, _ := s.manager.Achievement(e.Achievement)
+		info.ID = e.Achievement
+		msg := encodeWSMsg("achievement_unlocked", session.UnlockedAchievement{
+			AchievementInfo: info,
+			SessionCode:     e.SessionCode,
+			UnlockedAt:      e.At,
+		})
+		for _, id := range e.Recipients {
+			for _, send := range sess.PlayerSends(id) {
+				if _, ok := s.toasts.Load(send); ok {
+					sendEncoded(send, msg)
+				}
+			}
+		}
+	}
+}
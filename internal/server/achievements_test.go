@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/session"
+)
+
+func TestAchievementToasts(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	code := sess.Code
+	alice := joinWith(t, env, code, joinPayload{PlayerID: "alice", Toasts: true})
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		conn := []*websocket.Conn{alice, bob}[i%2]
+		sendWS(ctx, conn, "action", makeAction(t, cell))
+		readState(t, ctx, alice)
+		readState(t, ctx, bob)
+	}
+
+	var toasts []session.UnlockedAchievement
+	for len(toasts) < 2 {
+		msg := wsRead(ctx, t, alice)
+		if msg.Type != "achievement_unlocked" {
+			continue
+		}
+		var a session.UnlockedAchievement
+		json.Unmarshal(msg.Payload, &a)
+		toasts = append(toasts, a)
+	}
+	if toasts[0].ID != "first_win" || toasts[1].ID != "tictactoe/flawless" || toasts[1].Name != "Flawless" || toasts[1].SessionCode != code {
+		t.Fatalf("unexpected toasts %+v", toasts)
+	}
+
+	resp, err := http.Get(env.ts.URL + "/api/players/alice/achievements")
+	if err != nil {
+		t.Fatalf("GET achievements: %v", err)
+	}
+	var body playerAchievementsResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if len(body.Achievements) != 2 || body.Achievements[1].Description == "" || body.Achievements[0].UnlockedAt.IsZero() {
+		t.Fatalf("expected alice's two achievements, got %+v", body)
+	}
+
+	resp, err = http.Get(env.ts.URL + "/api/achievements")
+	if err != nil {
+		t.Fatalf("GET all achievements: %v", err)
+	}
+	var all achievementsResponse
+	json.NewDecoder(resp.Body).Decode(&all)
+	resp.Body.Close()
+	if len(all.Achievements) != 4 || all.Achievements[3].GameType != "tictactoe" {
+		t.Fatalf("expected the generic achievements and tic-tac-toe's, got %+v", all)
+	}
+}
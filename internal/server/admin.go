@@ -0,0 +1,382 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"games/internal/game"
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+// Admin endpoints authenticate with "Authorization: Bearer <token>" using
+// a token configured with SetAdminTokens. Every authorized call is written
+// to the audit log. Rejected ones are only logged, ten at once and then
+// one a second, so a flood of bad tokens fills neither the audit log
+// nor the server's.
+const (
+	rejectedAdminLogRate  = 1
+	rejectedAdminLogBurst = 10
+)
+
+type kickRequest struct {
+	PlayerID string `json:"playerId"`
+}
+
+type pinRequest struct {
+	Pinned *bool `json:"pinned"`
+}
+
+type auditEntryResponse struct {
+	ID          int64     `json:"id"`
+	Actor       string    `json:"actor"`
+	Action      string    `json:"action"`
+	SessionCode string    `json:"sessionCode,omitempty"`
+	PlayerID    string    `json:"playerId,omitempty"`
+	Detail      string    `json:"detail,omitempty"`
+	Status      int       `json:"status"`
+	At          time.Time `json:"at"`
+}
+
+// adminHandler serves an admin request. It fills in the audit entry's
+// target and detail; actor, action and status are recorded for it.
+type adminHandler func(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow)
+
+// SetAdminTokens configures the admins allowed to call admin endpoints, as
+// a map from bearer token to admin name.
+func (s *Server) SetAdminTokens(tokens map[string]string) {
+	s.adminTokens = tokens
+}
+
+// authenticateAdmin returns the name of the admin owning the request's
+// bearer token.
+func (s *Server) authenticateAdmin(r *http.Request) (string, bool) {
+	token := bearerToken(r)
+	if token == "" {
+		return "", false
+	}
+	for t, name := range s.adminTokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// admin wraps h with admin authentication and audit logging.
+func (s *Server) admin(action string, h adminHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, ok := s.authenticateAdmin(r)
+		if !ok {
+			if s.adminLimiter.Allow("") {
+				log.Printf("admin %s rejected: %s %s from %s", action, r.Method, r.URL.Path, r.RemoteAddr)
+			}
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "admin authentication required"})
+			return
+		}
+		entry := storage.AuditRow{Actor: actor, Action: action, SessionCode: r.PathValue("code")}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r, &entry)
+		entry.Status = rec.status
+		s.manager.RecordAudit(r.Context(), entry)
+	}
+}
+
+func (s *Server) handleAdminDeleteSession(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	sess, ok := s.manager.Get(entry.SessionCode)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	info := sess.Info()
+	entry.Detail = info.GameType + " " + string(info.Status)
+	for _, pid := range info.Players {
+		sess.Kick(pid)
+	}
+	s.manager.Remove(r.Context(), entry.SessionCode)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// handleAdminCleanup runs a cleanup pass now and reports what it decided
+// about each session and why. ?dryRun=true only reports.
+func (s *Server) handleAdminCleanup(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	dryRun := false
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "dryRun must be true or false"})
+			return
+		}
+	}
+	report := s.manager.Cleanup(r.Context(), dryRun)
+	entry.Detail = fmt.Sprintf("removed %d kept %d dry_run=%t", report.Removed, report.Kept, report.DryRun)
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleAdminPinSession pins a session, such as a tournament or a demo,
+// so cleanup never removes it, or unpins it.
+func (s *Server) handleAdminPinSession(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	var req pinRequest
+	if err := decodeJSON(r.Body, &req); err != nil || req.Pinned == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "pinned required"})
+		return
+	}
+	entry.Detail = fmt.Sprintf("pinned=%t", *req.Pinned)
+	sess, ok := s.manager.Get(entry.SessionCode)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	if err := s.manager.SetPinned(r.Context(), sess, *req.Pinned); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, sess.Info())
+}
+
+func (s *Server) handleAdminKick(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	var req kickRequest
+	if err := decodeJSON(r.Body, &req); err != nil || req.PlayerID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "playerId required"})
+		return
+	}
+	entry.PlayerID = req.PlayerID
+	if err := s.manager.Kick(entry.SessionCode, req.PlayerID); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if sess, ok := s.manager.Get(entry.SessionCode); ok {
+		s.broadcastState(sess)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "kicked"})
+}
+
+type deletedSessionResponse struct {
+	Code      string    `json:"code"`
+	GameType  string    `json:"gameType"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+func (s *Server) handleAdminDeletedSessions(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	rows, err := s.manager.DeletedSessions(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	out := make([]deletedSessionResponse, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, deletedSessionResponse{
+			Code:      row.Code,
+			GameType:  row.GameType,
+			Status:    row.Status,
+			CreatedAt: row.CreatedAt,
+			DeletedAt: row.DeletedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleAdminRestoreSession(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	sess, err := s.manager.Undelete(r.Context(), entry.SessionCode)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, sess.Info())
+}
+
+type databaseSizeResponse struct {
+	Bytes     int64            `json:"bytes"`
+	FreeBytes int64            `json:"freeBytes"`
+	Rows      map[string]int64 `json:"rows"`
+}
+
+func (s *Server) handleAdminDatabase(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	size, err := s.manager.DatabaseSize(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, databaseSizeResponse{Bytes: size.Bytes, FreeBytes: size.FreeBytes, Rows: size.Rows})
+}
+
+// handleAdminLimits reports every game's size limits and how often its
+// state went over them.
+func (s *Server) handleAdminLimits(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	writeJSON(w, http.StatusOK, s.manager.LimitReports())
+}
+
+// gameStatus tells whether a game is offered.
+type gameStatus struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+type gameEnabledRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func (s *Server) handleAdminGames(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	names := s.registry.Names()
+	games := make([]gameStatus, len(names))
+	for i, name := range names {
+		games[i] = gameStatus{Name: name, Enabled: s.registry.Enabled(name)}
+	}
+	writeJSON(w, http.StatusOK, games)
+}
+
+// handleAdminSetGame turns a game on or off. A disabled game is hidden
+// from the lobby and refuses new sessions; matches under way play on.
+func (s *Server) handleAdminSetGame(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	var req gameEnabledRequest
+	if err := decodeJSON(r.Body, &req); err != nil || req.Enabled == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "enabled required"})
+		return
+	}
+	name := r.PathValue("name")
+	entry.Detail = fmt.Sprintf("%s enabled=%t", name, *req.Enabled)
+	if err := s.registry.SetEnabled(name, *req.Enabled); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "game not found"})
+		return
+	}
+	s.cache.invalidate(gamesCacheTag)
+	writeJSON(w, http.StatusOK, gameStatus{Name: name, Enabled: *req.Enabled})
+}
+
+type conductResponse struct {
+	Total   session.ConductReport   `json:"total"`
+	Players []session.ConductReport `json:"players"`
+}
+
+// handleAdminConduct reports how many actions each player had refused and
+// why, and the anti-cheat flags raised against them. ?player= narrows it
+// to one player.
+func (s *Server) handleAdminConduct(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	players, total := s.manager.ConductReports()
+	if id := r.URL.Query().Get("player"); id != "" {
+		entry.PlayerID = id
+		players = slices.DeleteFunc(players, func(c session.ConductReport) bool { return c.PlayerID != id })
+	}
+	writeJSON(w, http.StatusOK, conductResponse{Total: total, Players: players})
+}
+
+// handleAdminCompression reports how session connections negotiated
+// compression and how much it saved.
+func (s *Server) handleAdminCompression(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	writeJSON(w, http.StatusOK, s.compressionReport())
+}
+
+// handleAdminAudit lists audit entries, filtered by the actor, action,
+// session, player, since (RFC 3339) and limit query parameters.
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	q := r.URL.Query()
+	f := storage.AuditFilter{
+		Actor:       q.Get("actor"),
+		Action:      q.Get("action"),
+		SessionCode: q.Get("session"),
+		PlayerID:    q.Get("player"),
+		Limit:       100,
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be an RFC 3339 time"})
+			return
+		}
+		f.Since = since
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		f.Limit = n
+	}
+	entry.Detail = r.URL.RawQuery
+
+	rows, err := s.manager.AuditLog(r.Context(), f)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	out := make([]auditEntryResponse, 0, len(rows))
+	for _, a := range rows {
+		out = append(out, auditEntryResponse{
+			ID:          a.ID,
+			Actor:       a.Actor,
+			Action:      a.Action,
+			SessionCode: a.SessionCode,
+			PlayerID:    a.PlayerID,
+			Detail:      a.Detail,
+			Status:      a.Status,
+			At:          a.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// replayResponse is the outcome of replaying a transcript: the final
+// position as an observer sees it, or the position before the move that
+// failed along with the error.
+type replayResponse struct {
+	State   any                 `json:"state"`
+	Results []game.PlayerResult `json:"results,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// handleAdminTranscript returns the current match's transcript, including
+// its seed, for reproducing a reported bug with the replay endpoint.
+func (s *Server) handleAdminTranscript(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	sess, ok := s.manager.Get(entry.SessionCode)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	t, err := sess.Transcript()
+	if err != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// handleAdminReplay plays a transcript in memory and reports where it ends.
+func (s *Server) handleAdminReplay(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	var t session.Transcript
+	if err := decodeJSON(r.Body, &t); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid transcript"})
+		return
+	}
+	entry.Detail = fmt.Sprintf("%s seed %d, %d moves", t.GameType, t.Seed, len(t.Moves))
+	m, err := session.Replay(s.registry, t)
+	if m == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	resp := replayResponse{State: m.State("")}
+	if err != nil {
+		resp.Error = err.Error()
+	} else if m.IsOver() {
+		resp.Results = game.Results(m)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
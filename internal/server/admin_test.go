@@ -0,0 +1,581 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/session"
+	"games/internal/storage"
+
+	"nhooyr.io/websocket"
+)
+
+func adminRequest(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	return resp
+}
+
+func TestAdminKickAndAudit(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	bobConn := wsConnect(t, env.ts, code, "bob")
+	defer bobConn.CloseNow()
+	readState(t, ctx, bobConn)
+
+	resp := adminRequest(t, "POST", env.ts.URL+"/api/admin/sessions/"+code+"/kick", "wrong", `{"playerId":"bob"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+
+	resp = adminRequest(t, "POST", env.ts.URL+"/api/admin/sessions/"+code+"/kick", "secret", `{"playerId":"bob"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	// Bob is told and disconnected
+	for {
+		msg, err := readWS(ctx, bobConn)
+		if err != nil {
+			if websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+				t.Fatalf("expected policy violation close, got %v", err)
+			}
+			break
+		}
+		if msg.Type == "state" {
+			continue
+		}
+		if msg.Type != "error" {
+			t.Fatalf("unexpected message %s", msg.Type)
+		}
+	}
+	sess, _ := env.mgr.Get(code)
+	if sess.GetPlayer("bob") != nil {
+		t.Fatal("bob should have been removed")
+	}
+
+	resp = adminRequest(t, "DELETE", env.ts.URL+"/api/admin/sessions/"+code, "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if _, ok := env.mgr.Get(code); ok {
+		t.Fatal("session should have been deleted")
+	}
+
+	resp = adminRequest(t, "GET", env.ts.URL+"/api/admin/audit?session="+code, "secret", "")
+	defer resp.Body.Close()
+	var entries []auditEntryResponse
+	json.NewDecoder(resp.Body).Decode(&entries)
+	if len(entries) != 2 {
+		t.Fatalf("expected the kick and delete audited without the rejected kick, got %+v", entries)
+	}
+	if e := entries[1]; e.Actor != "root" || e.Action != "session.kick" || e.PlayerID != "bob" {
+		t.Fatalf("unexpected kick entry %+v", e)
+	}
+	if e := entries[0]; e.Action != "session.delete" || e.Status != http.StatusOK {
+		t.Fatalf("unexpected delete entry %+v", e)
+	}
+}
+
+// TestAdminRejectedLogged checks that rejected admin calls stay out of the
+// audit log and are logged only up to the burst.
+func TestAdminRejectedLogged(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	for range rejectedAdminLogBurst + 5 {
+		resp := adminRequest(t, "POST", env.ts.URL+"/api/admin/cleanup", "wrong", "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", resp.StatusCode)
+		}
+	}
+	if n := strings.Count(logged.String(), "admin session.cleanup rejected"); n != rejectedAdminLogBurst {
+		t.Errorf("expected %d rejections logged, got %d:\n%s", rejectedAdminLogBurst, n, logged.String())
+	}
+	entries, err := env.mgr.AuditLog(t.Context(), storage.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected nothing audited, got %+v", entries)
+	}
+}
+
+func TestAdminAuditValidation(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	resp := adminRequest(t, "GET", env.ts.URL+"/api/admin/audit?since=yesterday", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminDatabaseSize(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+
+	resp := adminRequest(t, "GET", env.ts.URL+"/api/admin/database", "secret", "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var size databaseSizeResponse
+	json.NewDecoder(resp.Body).Decode(&size)
+	if size.Rows["sessions"] != 1 {
+		t.Fatalf("expected one session counted, got %+v", size)
+	}
+}
+
+func TestStateOverLimits(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	env.srv.registry.SetLimits("tictactoe", game.Limits{MaxStateBytes: 16, MaxBroadcastBytes: 64})
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	conn := wsConnect(t, env.ts, code, "alice")
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+	if msg := readError(t, ctx, conn); msg != "game state too large to send" {
+		t.Fatalf("unexpected error %q", msg)
+	}
+
+	sess, _ := env.mgr.Get(code)
+	sess.AddPlayer("bob")
+	start := startViaAPI(t, env, code)
+	start.Body.Close()
+	if start.StatusCode != http.StatusOK {
+		t.Fatalf("expected the match to start anyway, got %d", start.StatusCode)
+	}
+
+	resp := adminRequest(t, "GET", env.ts.URL+"/api/admin/limits", "secret", "")
+	defer resp.Body.Close()
+	var reports []session.LimitReport
+	json.NewDecoder(resp.Body).Decode(&reports)
+	if len(reports) != 1 || reports[0].MaxStateBytes != 16 || reports[0].StatesRefused != 1 || reports[0].BroadcastsDropped < 2 {
+		t.Fatalf("unexpected limit reports %+v", reports)
+	}
+}
+
+func TestAdminRestoreDeletedSession(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	sess, _ := env.mgr.Get(code)
+	sess.AddPlayer("bob")
+	start := startViaAPI(t, env, code)
+	start.Body.Close()
+
+	resp := adminRequest(t, "DELETE", env.ts.URL+"/api/admin/sessions/"+code, "secret", "")
+	resp.Body.Close()
+
+	resp = adminRequest(t, "GET", env.ts.URL+"/api/admin/sessions/deleted", "secret", "")
+	var deleted []deletedSessionResponse
+	json.NewDecoder(resp.Body).Decode(&deleted)
+	resp.Body.Close()
+	if len(deleted) != 1 || deleted[0].Code != code || deleted[0].Status != "playing" {
+		t.Fatalf("unexpected deleted sessions: %+v", deleted)
+	}
+
+	resp = adminRequest(t, "POST", env.ts.URL+"/api/admin/sessions/"+code+"/restore", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	restored, ok := env.mgr.Get(code)
+	if !ok {
+		t.Fatal("restored session should be back in memory")
+	}
+	restored.RLock()
+	hasMatch := restored.Match != nil
+	restored.RUnlock()
+	if !hasMatch {
+		t.Fatal("restored session should have its match state")
+	}
+
+	resp = adminRequest(t, "POST", env.ts.URL+"/api/admin/sessions/"+code+"/restore", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 restoring a live session, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminTranscriptReplays(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	resp := adminRequest(t, "GET", env.ts.URL+"/api/admin/sessions/"+code+"/transcript", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 before the match starts, got %d", resp.StatusCode)
+	}
+
+	conn := wsConnect(t, env.ts, code, "alice")
+	defer conn.CloseNow()
+	readState(t, ctx, conn)
+	sendWS(ctx, conn, "add_bot", addBotPayload{Strategy: "random"})
+	readState(t, ctx, conn)
+	sendWS(ctx, conn, "start", nil)
+	sp := readState(t, ctx, conn)
+	for len(sp.Results) == 0 {
+		if len(sp.ValidActions) > 0 {
+			sendWS(ctx, conn, "action", actionPayload{Action: sp.ValidActions[0]})
+		}
+		sp = readState(t, ctx, conn)
+	}
+
+	resp = adminRequest(t, "GET", env.ts.URL+"/api/admin/sessions/"+code+"/transcript", "secret", "")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var tr session.Transcript
+	json.Unmarshal(body, &tr)
+	if tr.Seed == 0 || len(tr.Players) != 2 || len(tr.Moves) == 0 {
+		t.Fatalf("unexpected transcript: %s", body)
+	}
+
+	resp = adminRequest(t, "POST", env.ts.URL+"/api/admin/replays", "secret", string(body))
+	var replay replayResponse
+	json.NewDecoder(resp.Body).Decode(&replay)
+	resp.Body.Close()
+	if replay.Error != "" || len(replay.Results) != 2 {
+		t.Fatalf("expected the replay to finish, got %+v", replay)
+	}
+	if !reflect.DeepEqual(replay.Results[0], sp.Results[0]) {
+		t.Fatalf("replay results %+v differ from the match's %+v", replay.Results, sp.Results)
+	}
+}
+
+// hastyGame is tic-tac-toe that flags any move made within an hour of the
+// last.
+type hastyGame struct{ tictactoe.TicTacToe }
+
+func (hastyGame) Info() game.GameInfo {
+	info := tictactoe.TicTacToe{}.Info()
+	info.Name = "hasty"
+	return info
+}
+
+func (g hastyGame) NewMatch(config game.MatchConfig) game.Match {
+	return &hastyMatch{Match: g.TicTacToe.NewMatch(config)}
+}
+
+type hastyMatch struct{ game.Match }
+
+func (m *hastyMatch) CheckAction(playerID string, action game.Action, elapsed time.Duration) string {
+	if elapsed < time.Hour {
+		return "moved within " + elapsed.Round(time.Hour).String()
+	}
+	return ""
+}
+
+func (m *hastyMatch) Clone() game.Match {
+	return &hastyMatch{Match: m.Match.Clone()}
+}
+
+func TestAdminConduct(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	env.srv.registry.Register(hastyGame{})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "hasty")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, sess.Code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	sendWS(ctx, alice, "action", map[string]string{"action": "e4"})
+	readError(t, ctx, alice)
+	sendWS(ctx, bob, "action", makeAction(t, 0))
+	readError(t, ctx, bob)
+	sendWS(ctx, alice, "action", makeAction(t, 9))
+	readError(t, ctx, alice)
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	resp := adminRequest(t, "GET", env.ts.URL+"/api/admin/conduct", "secret", "")
+	var all conductResponse
+	json.NewDecoder(resp.Body).Decode(&all)
+	resp.Body.Close()
+	if all.Total.Accepted != 1 || all.Total.Malformed != 1 || all.Total.WrongTurn != 1 || all.Total.Invalid != 1 || all.Total.RejectRate != 0.75 {
+		t.Fatalf("unexpected totals %+v", all.Total)
+	}
+	if len(all.Players) != 2 || all.Players[0].PlayerID != "alice" {
+		t.Fatalf("expected alice, with the most refused actions, first, got %+v", all.Players)
+	}
+
+	resp = adminRequest(t, "GET", env.ts.URL+"/api/admin/conduct?player=alice", "secret", "")
+	var one conductResponse
+	json.NewDecoder(resp.Body).Decode(&one)
+	resp.Body.Close()
+	if len(one.Players) != 1 {
+		t.Fatalf("expected only alice, got %+v", one.Players)
+	}
+	alices := one.Players[0]
+	if alices.Accepted != 1 || alices.Malformed != 1 || alices.Invalid != 1 || alices.RejectRate != 2.0/3 {
+		t.Fatalf("unexpected counts %+v", alices)
+	}
+	// Refused actions are checked too
+	if len(alices.Flags) != 2 || alices.Flags[0].SessionCode != sess.Code || alices.Flags[0].Reason != "moved within 0s" {
+		t.Fatalf("expected both hasty moves flagged, got %+v", alices.Flags)
+	}
+}
+
+func TestAdminFeatures(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	env.srv.SetDevMode(true)
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+
+	resp := adminRequest(t, "PUT", env.ts.URL+"/api/admin/features/new-timers", "secret", `{"percent":0}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set feature: %d", resp.StatusCode)
+	}
+	resp = adminRequest(t, "PUT", env.ts.URL+"/api/admin/features/new-timers", "secret", `{"percent":150}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a bad percentage refused, got %d", resp.StatusCode)
+	}
+	if env.mgr.FeatureEnabled(sess, "new-timers") {
+		t.Fatal("expected no session to have a feature at 0%")
+	}
+
+	url := env.ts.URL + "/api/admin/sessions/" + sess.Code + "/features/new-timers"
+	resp = adminRequest(t, "PUT", url, "secret", `{"enabled":true}`)
+	var features []session.SessionFeature
+	json.NewDecoder(resp.Body).Decode(&features)
+	resp.Body.Close()
+	if len(features) != 1 || !features[0].Enabled || !features[0].Overridden {
+		t.Fatalf("expected the feature forced on, got %+v", features)
+	}
+	if !env.mgr.FeatureEnabled(sess, "new-timers") {
+		t.Fatal("expected the override to take effect")
+	}
+
+	var debug []session.SessionFeature
+	getDebug(t, env.ts.URL+"/api/debug/sessions/"+sess.Code+"/features", &debug)
+	if len(debug) != 1 || !debug[0].Enabled {
+		t.Fatalf("expected the debug endpoint to show the feature on, got %+v", debug)
+	}
+
+	resp = adminRequest(t, "DELETE", env.ts.URL+"/api/admin/features/new-timers", "secret", "")
+	resp.Body.Close()
+	resp = adminRequest(t, "GET", env.ts.URL+"/api/admin/features", "secret", "")
+	var all []session.Feature
+	json.NewDecoder(resp.Body).Decode(&all)
+	resp.Body.Close()
+	if len(all) != 0 || env.mgr.FeatureEnabled(sess, "new-timers") {
+		t.Fatalf("expected the feature removed, got %+v", all)
+	}
+}
+
+func TestAdminDisableGame(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	// A match under way when the game is disabled
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.CloseNow()
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.CloseNow()
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	listed := func() bool {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + "/api/games")
+		if err != nil {
+			t.Fatalf("list games: %v", err)
+		}
+		defer resp.Body.Close()
+		var games []game.GameInfo
+		json.NewDecoder(resp.Body).Decode(&games)
+		return slices.ContainsFunc(games, func(g game.GameInfo) bool { return g.Name == "tictactoe" })
+	}
+	if !listed() {
+		t.Fatal("expected tictactoe listed")
+	}
+
+	resp := adminRequest(t, "PUT", env.ts.URL+"/api/admin/games/tictactoe", "secret", `{"enabled":false}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("disable: %d", resp.StatusCode)
+	}
+	if listed() {
+		t.Error("expected a disabled game hidden from the list")
+	}
+	resp = postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"carol"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected new sessions refused, got %d", resp.StatusCode)
+	}
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	if sp := readState(t, ctx, alice); sp.State == nil {
+		t.Error("expected the match under way to play on")
+	}
+
+	resp = adminRequest(t, "GET", env.ts.URL+"/api/admin/games", "secret", "")
+	var games []gameStatus
+	json.NewDecoder(resp.Body).Decode(&games)
+	resp.Body.Close()
+	if len(games) != 1 || games[0].Name != "tictactoe" || games[0].Enabled {
+		t.Fatalf("expected tictactoe listed as disabled, got %+v", games)
+	}
+	for body, want := range map[string]int{`{}`: http.StatusBadRequest, `{"enabled":true}`: http.StatusOK} {
+		resp = adminRequest(t, "PUT", env.ts.URL+"/api/admin/games/tictactoe", "secret", body)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", body, want, resp.StatusCode)
+		}
+	}
+	resp = adminRequest(t, "PUT", env.ts.URL+"/api/admin/games/chess", "secret", `{"enabled":false}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected an unknown game not found, got %d", resp.StatusCode)
+	}
+	if !listed() {
+		t.Error("expected tictactoe listed again once enabled")
+	}
+}
+
+func TestAdminCleanup(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+
+	cleanup := func(query string) session.CleanupReport {
+		t.Helper()
+		resp := adminRequest(t, "POST", env.ts.URL+"/api/admin/cleanup"+query, "secret", "")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("cleanup%s: %d", query, resp.StatusCode)
+		}
+		var report session.CleanupReport
+		json.NewDecoder(resp.Body).Decode(&report)
+		return report
+	}
+	report := cleanup("?dryRun=true")
+	if !report.DryRun || report.Removed != 1 || len(report.Decisions) != 1 ||
+		report.Decisions[0].Action != session.CleanupWouldRemove || report.Decisions[0].Reason != session.CleanupEmpty {
+		t.Fatalf("expected the empty session reported as would be removed, got %+v", report)
+	}
+	if _, ok := env.mgr.Get(sess.Code); !ok {
+		t.Fatal("expected a dry run to keep the session")
+	}
+	if report = cleanup(""); report.DryRun || report.Removed != 1 {
+		t.Fatalf("expected the empty session removed, got %+v", report)
+	}
+	if _, ok := env.mgr.Get(sess.Code); ok {
+		t.Fatal("expected the session gone")
+	}
+
+	resp := adminRequest(t, "POST", env.ts.URL+"/api/admin/cleanup?dryRun=maybe", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a bad dryRun refused, got %d", resp.StatusCode)
+	}
+
+	resp, _ = http.Get(env.ts.URL + "/metrics")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{
+		"# TYPE games_cleanup_sessions_total counter",
+		`games_cleanup_sessions_total{action="would_remove",reason="empty"} 1`,
+		`games_cleanup_sessions_total{action="removed",reason="empty"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+}
+
+func TestAdminPinSession(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	sess, _ := env.mgr.Get(code)
+	sess.RemovePlayer("alice")
+
+	for body, want := range map[string]int{`{}`: http.StatusBadRequest, `{"pinned":true}`: http.StatusOK} {
+		resp := adminRequest(t, "PUT", env.ts.URL+"/api/admin/sessions/"+code+"/pin", "secret", body)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", body, want, resp.StatusCode)
+		}
+	}
+	resp := adminRequest(t, "PUT", env.ts.URL+"/api/admin/sessions/ZZZZ/pin", "secret", `{"pinned":true}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected an unknown session not found, got %d", resp.StatusCode)
+	}
+	env.mgr.Cleanup(t.Context(), false)
+	if _, ok := env.mgr.Get(code); !ok {
+		t.Fatal("expected the pinned session kept though empty")
+	}
+
+	resp = postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"bob","expireAfter":"168h"}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if s, ok := env.mgr.Get(created.Code); !ok || s.Info().ExpireAfter != "168h0m0s" {
+		t.Fatalf("expected the session created with its own expiry")
+	}
+	for _, expiry := range []string{"soon", "-1h", "1ms"} {
+		resp = postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"bob","expireAfter":"`+expiry+`"}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected the expiry refused, got %d", expiry, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/game"
+	"games/internal/session"
+)
+
+// Analysis WebSocket messages. The client sends "goto", "step", "action"
+// or "mainline"; the server answers each with an "analysis" message
+// carrying the resulting position, or an "error".
+
+type gotoPayload struct {
+	Ply int `json:"ply"`
+}
+
+type stepPayload struct {
+	Delta int `json:"delta"`
+}
+
+type analysisActionPayload struct {
+	PlayerID string      `json:"playerId,omitempty"` // defaults to the player to move
+	Action   game.Action `json:"action"`
+}
+
+// handleAnalysis serves a finished match's analysis over a WebSocket.
+// The analysis plays copies of the match, so a panic in the game ends only
+// the connection, leaving the session as it was.
+func (s *Server) handleAnalysis(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	sess, ok := s.manager.Get(code)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	var analysis *session.Analysis
+	err := session.Protect(func() (err error) {
+		analysis, err = sess.Analyze()
+		return err
+	})
+	if err != nil {
+		logAnalysisPanic(code, err)
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	viewer := r.URL.Query().Get("player")
+
+	raw, err := s.acceptWS(w, r)
+	if err != nil {
+		log.Printf("websocket accept: %v", err)
+		return
+	}
+	path := "analysis"
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	conn, recorded := s.recordConn(raw, sess, path)
+	defer recorded()
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(maxWSMessageBytes)
+
+	ctx := r.Context()
+	if err := s.writeAnalysis(ctx, conn, code, analysis, viewer); err != nil {
+		return
+	}
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		var msg WSMessage
+		if err := unmarshalStrict(data, &msg); err != nil {
+			sendWSError(ctx, conn, "invalid message")
+			continue
+		}
+		err = session.Protect(func() error { return handleAnalysisMessage(analysis, msg) })
+		if errors.As(err, new(*session.PanicError)) {
+			logAnalysisPanic(code, err)
+			sendWSError(ctx, conn, err.Error())
+			return
+		}
+		if err != nil {
+			sendWSError(ctx, conn, err.Error())
+			continue
+		}
+		if err := s.writeAnalysis(ctx, conn, code, analysis, viewer); err != nil {
+			return
+		}
+	}
+}
+
+// writeAnalysis sends the analysis position to viewer, failing if the
+// game panics drawing it.
+func (s *Server) writeAnalysis(ctx context.Context, conn *wsConn, code string, a *session.Analysis, viewer string) error {
+	var view session.AnalysisView
+	err := session.Protect(func() error {
+		view = a.View(viewer)
+		return nil
+	})
+	if err != nil {
+		logAnalysisPanic(code, err)
+		sendWSError(ctx, conn, err.Error())
+		return err
+	}
+	p, err := json.Marshal(view)
+	if err != nil {
+		return err
+	}
+	msg, err := json.Marshal(WSMessage{Type: "analysis", Payload: p})
+	if err != nil {
+		return err
+	}
+	s.countMessage(msg)
+	return conn.Write(ctx, websocket.MessageText, msg)
+}
+
+// logAnalysisPanic logs a panic in the game of an analysis with its
+// stack. Other errors were the viewer's and are not logged.
+func logAnalysisPanic(code string, err error) {
+	var perr *session.PanicError
+	if errors.As(err, &perr) {
+		log.Printf("analysis of session %s: game panicked: %v\n%s", code, perr.Value, perr.Stack)
+	}
+}
+
+func handleAnalysisMessage(a *session.Analysis, msg WSMessage) error {
+	switch msg.Type {
+	case "goto":
+		var p gotoPayload
+		if err := unmarshalStrict(msg.Payload, &p); err != nil {
+			return fmt.Errorf("invalid %s payload", msg.Type)
+		}
+		return a.Goto(p.Ply)
+	case "step":
+		var p stepPayload
+		if err := unmarshalStrict(msg.Payload, &p); err != nil {
+			return fmt.Errorf("invalid %s payload", msg.Type)
+		}
+		return a.Step(p.Delta)
+	case "action":
+		var p analysisActionPayload
+		if err := unmarshalStrict(msg.Payload, &p); err != nil {
+			return fmt.Errorf("invalid %s payload", msg.Type)
+		}
+		return a.Play(p.PlayerID, p.Action)
+	case "mainline":
+		return a.Mainline()
+	default:
+		return fmt.Errorf("unknown message type: %s", msg.Type)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/game/tictactoe"
+	"games/internal/session"
+)
+
+// finishedSession creates a tic-tac-toe session where the first player wins
+// along the top row, recording history the same way the WS handler does.
+func finishedSession(t *testing.T, env *testEnv) *session.Session {
+	t.Helper()
+	sess, err := env.mgr.Create(t.Context(), "tictactoe")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	players := sess.Match.(*tictactoe.Match).Players
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		action := makeAction(t, cell).Action
+		if err := sess.Match.ApplyAction(players[i%2], action); err != nil {
+			t.Fatalf("move %d: %v", i, err)
+		}
+		sess.History = append(sess.History, session.Move{PlayerID: players[i%2], Action: action})
+	}
+	sess.Finish()
+	return sess
+}
+
+func readAnalysis(t *testing.T, msg WSMessage) session.AnalysisView {
+	t.Helper()
+	if msg.Type != "analysis" {
+		t.Fatalf("expected analysis message, got %q: %s", msg.Type, string(msg.Payload))
+	}
+	var v session.AnalysisView
+	if err := json.Unmarshal(msg.Payload, &v); err != nil {
+		t.Fatalf("unmarshal analysis: %v", err)
+	}
+	return v
+}
+
+func TestAnalysisRejectsUnfinished(t *testing.T) {
+	env := setupTestEnv(t)
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+
+	resp, err := http.Get(env.ts.URL + "/api/sessions/" + sess.Code + "/analysis")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+}
+
+func TestAnalysisWebSocket(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess := finishedSession(t, env)
+	url := strings.Replace(env.ts.URL, "http://", "ws://", 1) + "/api/sessions/" + sess.Code + "/analysis"
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	v := readAnalysis(t, wsRead(ctx, t, conn))
+	if v.Ply != 5 || v.MainlineLength != 5 {
+		t.Fatalf("expected to start at final ply 5, got %d/%d", v.Ply, v.MainlineLength)
+	}
+
+	if err := sendWS(ctx, conn, "goto", gotoPayload{Ply: 2}); err != nil {
+		t.Fatalf("send goto: %v", err)
+	}
+	v = readAnalysis(t, wsRead(ctx, t, conn))
+	if v.Ply != 2 || len(v.ValidActions) != 7 {
+		t.Fatalf("expected ply 2 with 7 valid actions, got ply %d with %d", v.Ply, len(v.ValidActions))
+	}
+
+	if err := sendWS(ctx, conn, "action", analysisActionPayload{Action: makeAction(t, 8).Action}); err != nil {
+		t.Fatalf("send action: %v", err)
+	}
+	v = readAnalysis(t, wsRead(ctx, t, conn))
+	if v.BranchPly != 2 || v.Length != 3 {
+		t.Fatalf("expected branch at ply 2, got branchPly=%d len=%d", v.BranchPly, v.Length)
+	}
+
+	if err := sendWS(ctx, conn, "step", stepPayload{Delta: 5}); err != nil {
+		t.Fatalf("send step: %v", err)
+	}
+	if errMsg := readError(t, ctx, conn); !strings.Contains(errMsg, "out of range") {
+		t.Fatalf("expected out of range error, got %q", errMsg)
+	}
+
+	if len(sess.History) != 5 || !sess.Match.IsOver() {
+		t.Fatal("analysis modified the recorded match")
+	}
+}
+
+func TestAnalysisGamePanic(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.registry.Register(buggyGame{})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "buggy")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, cell := range []int{0, 3, 1, 4, 2} {
+		action := makeAction(t, cell).Action
+		for _, pid := range []string{"alice", "bob"} {
+			if len(sess.Match.ValidActions(pid)) > 0 {
+				if err := sess.Match.ApplyAction(pid, action); err != nil {
+					t.Fatalf("move %d: %v", cell, err)
+				}
+				sess.History = append(sess.History, session.Move{PlayerID: pid, Action: action})
+				break
+			}
+		}
+	}
+	sess.Finish()
+
+	before := env.srv.compressionReport().Connections
+	url := strings.Replace(env.ts.URL, "http://", "ws://", 1) + "/api/sessions/" + sess.Code + "/analysis"
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	readAnalysis(t, wsRead(ctx, t, conn))
+	if got := env.srv.compressionReport().Connections; got != before+1 {
+		t.Fatalf("expected the analysis connection counted, got %d after %d", got, before)
+	}
+
+	sendWS(ctx, conn, "goto", gotoPayload{Ply: 2})
+	readAnalysis(t, wsRead(ctx, t, conn))
+	sendWS(ctx, conn, "action", analysisActionPayload{Action: makeAction(t, 8).Action})
+	if msg := readError(t, ctx, conn); msg != "the game stopped after an internal error" {
+		t.Fatalf("expected the panic reported, got %q", msg)
+	}
+	if _, _, err := conn.Read(ctx); err == nil {
+		t.Fatal("expected the analysis connection closed after the panic")
+	}
+	if info := sess.Info(); info.Status != session.StatusFinished {
+		t.Fatalf("expected the session left finished, got %s", info.Status)
+	}
+}
+
+func TestAnalysisRecorded(t *testing.T) {
+	env := setupTestEnv(t)
+	dir := t.TempDir()
+	env.srv.SetWSRecordDir(dir)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess := finishedSession(t, env)
+	url := strings.Replace(env.ts.URL, "http://", "ws://", 1) + "/api/sessions/" + sess.Code + "/analysis?player=alice"
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	readAnalysis(t, wsRead(ctx, t, conn))
+	sendWS(ctx, conn, "goto", gotoPayload{Ply: 1})
+	readAnalysis(t, wsRead(ctx, t, conn))
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	var frames []wsFrame
+	for range 50 {
+		tr, _ := readTranscript(filepath.Join(dir, sess.Code+".jsonl"))
+		if frames = transcriptFrames(tr); len(frames) > 0 && frames[len(frames)-1].Closed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(frames) != 4 {
+		t.Fatalf("expected two server messages, the goto and the close recorded, got %+v", frames)
+	}
+	for _, fr := range frames[:3] {
+		if fr.Path != "analysis?player=alice" {
+			t.Fatalf("expected the analysis path on every message, got %+v", fr)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"games/internal/game"
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+// benchGame is a game built for benchmarks: any number of players take
+// turns ticking a counter, and the state carries as many cells as the
+// "cells" option asks for, so its size can be dialed.
+type benchGame struct{}
+
+func (benchGame) Info() game.GameInfo {
+	return game.GameInfo{
+		Name:       "bench",
+		MinPlayers: 1,
+		MaxPlayers: 64,
+		Options:    []game.Option{{Name: "cells", Default: 16, Min: 1, Max: 1 << 16}},
+	}
+}
+
+func (benchGame) NewMatch(config game.MatchConfig) game.Match {
+	return &benchMatch{Players: config.PlayerIDs, Cells: make([]int, config.Options["cells"])}
+}
+
+type benchMatch struct {
+	Players []string `json:"players"`
+	Cells   []int    `json:"cells"`
+	Turn    int      `json:"turn"`
+}
+
+type benchState struct {
+	Cells []int  `json:"cells"`
+	Turn  string `json:"turn"`
+	You   string `json:"you,omitempty"`
+}
+
+func (m *benchMatch) current() string { return m.Players[m.Turn%len(m.Players)] }
+
+func (m *benchMatch) State(playerID string) any {
+	return benchState{Cells: slices.Clone(m.Cells), Turn: m.current(), You: playerID}
+}
+
+func (m *benchMatch) ValidActions(playerID string) []game.Action {
+	if playerID != m.current() {
+		return nil
+	}
+	return []game.Action{{Type: "tick"}}
+}
+
+func (m *benchMatch) ApplyAction(playerID string, action game.Action) error {
+	if playerID != m.current() {
+		return fmt.Errorf("not your turn")
+	}
+	m.Cells[m.Turn%len(m.Cells)]++
+	m.Turn++
+	return nil
+}
+
+func (m *benchMatch) IsOver() bool                 { return false }
+func (m *benchMatch) Results() []game.PlayerResult { return nil }
+
+func (m *benchMatch) Clone() game.Match {
+	c := *m
+	c.Players = append([]string(nil), m.Players...)
+	c.Cells = append([]int(nil), m.Cells...)
+	return &c
+}
+
+func (m *benchMatch) MarshalJSON() ([]byte, error) {
+	type plain benchMatch
+	return json.Marshal((*plain)(m))
+}
+
+func (m *benchMatch) UnmarshalJSON(data []byte) error {
+	type plain benchMatch
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// newBenchMatch starts a bench match with the given players, spectators
+// and state size, every one of them connected and read from as fast as
+// the server sends.
+func newBenchMatch(b *testing.B, players, spectators, cells int) (*Server, *session.Session) {
+	b.Helper()
+	store, err := storage.New(":memory:")
+	if err != nil {
+		b.Fatalf("open db: %v", err)
+	}
+	b.Cleanup(func() { store.Close() })
+	reg := game.NewRegistry()
+	reg.Register(benchGame{})
+	mgr := session.NewManager(reg, store)
+	srv := New(reg, mgr, fstest.MapFS{})
+
+	sess, err := mgr.CreateWithOptions(b.Context(), "bench", map[string]int{"cells": cells})
+	if err != nil {
+		b.Fatalf("create: %v", err)
+	}
+	var sends []chan []byte
+	for i := range players {
+		pid := fmt.Sprintf("player-%d", i)
+		sess.AddPlayer(pid)
+		send := make(chan []byte, 64)
+		sess.ConnectPlayer(pid, send)
+		sends = append(sends, send)
+	}
+	for i := range spectators {
+		send := make(chan []byte, 64)
+		sess.AddSpectator(fmt.Sprintf("spectator-%d", i), send)
+		sends = append(sends, send)
+	}
+	for _, send := range sends {
+		go func() {
+			for range send {
+			}
+		}()
+	}
+	b.Cleanup(func() {
+		for _, send := range sends {
+			close(send)
+		}
+	})
+	if err := sess.Start(); err != nil {
+		b.Fatalf("start: %v", err)
+	}
+	srv.matchStarted(b.Context(), sess)
+	return srv, sess
+}
+
+// BenchmarkApplyAction measures the whole path of a move: applying it,
+// persisting the move and state, and broadcasting the result.
+func BenchmarkApplyAction(b *testing.B) {
+	for _, players := range []int{2, 8, 32} {
+		for _, cells := range []int{16, 4096} {
+			b.Run(fmt.Sprintf("players=%d/cells=%d", players, cells), func(b *testing.B) {
+				srv, sess := newBenchMatch(b, players, 4, cells)
+				tick := game.Action{Type: "tick"}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := range b.N {
+					pid := fmt.Sprintf("player-%d", i%players)
+					if err := srv.applyAction(b.Context(), sess, session.Move{PlayerID: pid, Action: tick, At: time.Now()}); err != nil {
+						b.Fatalf("apply: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkBroadcastState measures sending the state to every player and
+// spectator, without applying or persisting anything.
+func BenchmarkBroadcastState(b *testing.B) {
+	for _, players := range []int{2, 8, 32} {
+		for _, spectators := range []int{0, 32} {
+			b.Run(fmt.Sprintf("players=%d/spectators=%d", players, spectators), func(b *testing.B) {
+				srv, sess := newBenchMatch(b, players, spectators, 1024)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					srv.broadcastState(sess)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkSaveMove measures persisting one move and the state it leads
+// to.
+func BenchmarkSaveMove(b *testing.B) {
+	for _, cells := range []int{16, 4096} {
+		b.Run(fmt.Sprintf("cells=%d", cells), func(b *testing.B) {
+			srv, sess := newBenchMatch(b, 2, 0, cells)
+			mv := session.Move{PlayerID: "player-0", Action: game.Action{Type: "tick"}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				if err := srv.manager.SaveMove(b.Context(), sess, i+1, mv); err != nil {
+					b.Fatalf("save move: %v", err)
+				}
+			}
+		})
+	}
+}
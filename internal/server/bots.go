@@ -0,0 +1,222 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"games/internal/game"
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+// External bots authenticate with "Authorization: Bearer <apiKey>". They
+// either hold a WebSocket like any player or, if registered with a webhook
+// URL, are POSTed a turnNotification whenever it is their move and reply
+// through the REST action endpoint.
+
+type registerBotRequest struct {
+	Name       string `json:"name"`
+	WebhookURL string `json:"webhookUrl,omitempty"`
+}
+
+type registerBotResponse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"apiKey"`
+}
+
+type createSandboxRequest struct {
+	GameType string `json:"gameType"`
+	Opponent string `json:"opponent"` // built-in strategy name
+}
+
+type turnNotification struct {
+	SessionCode string `json:"sessionCode"`
+	PlayerID    string `json:"playerId"`
+	statePayload
+}
+
+func (s *Server) handleRegisterBot(w http.ResponseWriter, r *http.Request) {
+	var req registerBotRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if req.WebhookURL != "" {
+		u, err := url.Parse(req.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "webhookUrl must be an http(s) URL"})
+			return
+		}
+	}
+	bot, key, err := s.manager.RegisterBot(r.Context(), req.Name, req.WebhookURL)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, registerBotResponse{ID: bot.ID, Name: bot.Name, APIKey: key})
+}
+
+// authenticateBot resolves the bot behind the request's API key, writing a
+// 401 and returning false if there is none.
+func (s *Server) authenticateBot(w http.ResponseWriter, r *http.Request) (*storage.BotRow, bool) {
+	bot, err := s.manager.AuthenticateBot(r.Context(), bearerToken(r))
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	return bot, true
+}
+
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func (s *Server) handleCreateSandbox(w http.ResponseWriter, r *http.Request) {
+	bot, ok := s.authenticateBot(w, r)
+	if !ok {
+		return
+	}
+	var req createSandboxRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	sess, err := s.manager.CreateSandbox(r.Context(), req.GameType, bot, req.Opponent)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	s.matchStarted(r.Context(), sess)
+	s.broadcastState(sess)
+	s.playBots(r.Context(), sess, 0)
+	writeJSON(w, http.StatusCreated, createSessionResponse{Code: sess.Code})
+}
+
+func (s *Server) handleBotJoin(w http.ResponseWriter, r *http.Request) {
+	bot, ok := s.authenticateBot(w, r)
+	if !ok {
+		return
+	}
+	sess, ok := s.manager.Get(r.PathValue("code"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	if sess.GetPlayer(bot.ID) == nil {
+		if err := sess.AddPlayer(bot.ID); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		s.manager.RecordTimeline(r.Context(), sess.Code, session.TimelineJoined, bot.ID, nil)
+	}
+	sess.SetWebhook(bot.ID, bot.WebhookURL)
+	s.broadcastState(sess)
+	writeJSON(w, http.StatusOK, sess.Info())
+}
+
+func (s *Server) handleBotState(w http.ResponseWriter, r *http.Request) {
+	bot, ok := s.authenticateBot(w, r)
+	if !ok {
+		return
+	}
+	sess, ok := s.manager.Get(r.PathValue("code"))
+	if !ok || sess.GetPlayer(bot.ID) == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	s.writePlayerState(w, r, sess, bot.ID)
+}
+
+func (s *Server) handleBotAction(w http.ResponseWriter, r *http.Request) {
+	bot, ok := s.authenticateBot(w, r)
+	if !ok {
+		return
+	}
+	if !s.botLimiter.Allow(bot.ID) {
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+		return
+	}
+	sess, ok := s.manager.Get(r.PathValue("code"))
+	if !ok || sess.GetPlayer(bot.ID) == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	received := time.Now()
+	var ap actionPayload
+	if err := decodeJSON(r.Body, &ap); err != nil {
+		s.manager.CountAction(bot.ID, session.RejectMalformed)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid action payload"})
+		return
+	}
+	if err := s.applyAction(r.Context(), sess, ap.move(bot.ID, received)); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	s.playBots(r.Context(), sess, 0)
+	s.writePlayerState(w, r, sess, bot.ID)
+}
+
+// playerState builds the state payload one player would receive. It fails
+// with a *session.PanicError if the game panics.
+func playerState(sess *session.Session, playerID string) (statePayload, error) {
+	sess.RLock()
+	defer sess.RUnlock()
+	sp := statePayload{
+		SessionInfo: sess.InfoLocked(),
+		Scoreboard:  sess.ScoreboardLocked(),
+		Fairness:    sess.CommitmentLocked(),
+	}
+	if sess.Match == nil || sess.Status == session.StatusWaiting || sess.Status == session.StatusErrored {
+		return sp, nil
+	}
+	err := session.Protect(func() error {
+		seat := sess.SeatToPlayLocked(playerID)
+		sp.State = sess.Match.State(seat)
+		sp.ValidActions = sess.Match.ValidActions(seat)
+		if sess.Match.IsOver() {
+			sp.Results = game.Results(sess.Match)
+		}
+		return nil
+	})
+	return sp, err
+}
+
+// writePlayerState responds with the state one player would receive,
+// failing the session if its game panics.
+func (s *Server) writePlayerState(w http.ResponseWriter, r *http.Request, sess *session.Session, playerID string) {
+	sp, err := playerState(sess, playerID)
+	if s.failIfPanicked(r.Context(), sess, err) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, sp)
+}
+
+// notifyWebhook POSTs a turn notification to an external bot. Delivery is
+// best effort; the bot can always poll its state instead.
+func (s *Server) notifyWebhook(hook string, n turnNotification) {
+	body, err := json.Marshal(n)
+	if err != nil {
+		log.Printf("marshal turn notification: %v", err)
+		return
+	}
+	resp, err := s.webhookClient.Post(hook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("webhook %s: %v", n.PlayerID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("webhook %s: status %d", n.PlayerID, resp.StatusCode)
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+func registerBot(t *testing.T, ts *httptest.Server, name, webhook string) registerBotResponse {
+	t.Helper()
+	body := fmt.Sprintf(`{"name":%q,"webhookUrl":%q}`, name, webhook)
+	resp, err := http.Post(ts.URL+"/api/bots", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("register bot: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var reg registerBotResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reg); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return reg
+}
+
+func botRequest(t *testing.T, method, url, key string, body any) *http.Response {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, url, strings.NewReader(string(data)))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	return resp
+}
+
+func TestRegisterBotValidation(t *testing.T) {
+	env := setupTestEnv(t)
+
+	for _, body := range []string{`{"name":""}`, `{"name":"x","webhookUrl":"ftp://example.com"}`} {
+		resp, err := http.Post(env.ts.URL+"/api/bots", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestBotEndpointsRequireKey(t *testing.T) {
+	env := setupTestEnv(t)
+
+	resp := botRequest(t, "POST", env.ts.URL+"/api/bots/sandbox", "", createSandboxRequest{GameType: "tictactoe", Opponent: "random"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without key, got %d", resp.StatusCode)
+	}
+	resp = botRequest(t, "POST", env.ts.URL+"/api/bots/sandbox", "wrong", createSandboxRequest{GameType: "tictactoe", Opponent: "random"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong key, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookBotPlaysSandbox(t *testing.T) {
+	env := setupTestEnv(t)
+
+	turns := make(chan turnNotification, 16)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n turnNotification
+		json.NewDecoder(r.Body).Decode(&n)
+		turns <- n
+	}))
+	defer hook.Close()
+
+	bot := registerBot(t, env.ts, "hookbot", hook.URL)
+	resp := botRequest(t, "POST", env.ts.URL+"/api/bots/sandbox", bot.APIKey, createSandboxRequest{GameType: "tictactoe", Opponent: "random"})
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	sess, _ := env.mgr.Get(created.Code)
+	if !sess.Info().Sandbox {
+		t.Fatal("expected sandbox session")
+	}
+
+	// Answer every turn notification with the first valid action
+	for {
+		select {
+		case n := <-turns:
+			if n.SessionCode != created.Code || n.PlayerID != bot.ID {
+				t.Fatalf("unexpected notification: %+v", n)
+			}
+			url := env.ts.URL + "/api/sessions/" + created.Code + "/bot/actions"
+			resp := botRequest(t, "POST", url, bot.APIKey, actionPayload{Action: n.ValidActions[0]})
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200 for action, got %d", resp.StatusCode)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for turn notification")
+		}
+		sess.RLock()
+		over := sess.Match.IsOver()
+		sess.RUnlock()
+		if over {
+			break
+		}
+	}
+
+	resp = botRequest(t, "GET", env.ts.URL+"/api/sessions/"+created.Code+"/bot/state", bot.APIKey, nil)
+	var sp statePayload
+	json.NewDecoder(resp.Body).Decode(&sp)
+	resp.Body.Close()
+	if sp.Results == nil {
+		t.Fatal("expected results in final bot state")
+	}
+}
+
+func TestBotActionRateLimit(t *testing.T) {
+	env := setupTestEnv(t)
+	bot := registerBot(t, env.ts, "spammer", "")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	resp := botRequest(t, "POST", env.ts.URL+"/api/sessions/"+sess.Code+"/bot/join", bot.APIKey, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for join, got %d", resp.StatusCode)
+	}
+
+	limited := false
+	for i := 0; i < 20 && !limited; i++ {
+		resp := botRequest(t, "POST", env.ts.URL+"/api/sessions/"+sess.Code+"/bot/actions", bot.APIKey, makeAction(t, 0))
+		resp.Body.Close()
+		limited = resp.StatusCode == http.StatusTooManyRequests
+	}
+	if !limited {
+		t.Fatal("expected rate limit to kick in")
+	}
+}
+
+func TestWSBotAuthentication(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	bot := registerBot(t, env.ts, "wsbot", "")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+
+	_, resp, err := websocket.Dial(ctx, wsURL(env.ts, sess.Code), &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": []string{"Bearer wrong"}},
+	})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad key, got %v", err)
+	}
+
+	// Humans cannot claim bot IDs
+	conn := wsConnect(t, env.ts, sess.Code, bot.ID)
+	if errMsg := readError(t, ctx, conn); !strings.Contains(errMsg, "reserved for bots") {
+		t.Fatalf("expected reserved ID error, got %q", errMsg)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	conn, _, err = websocket.Dial(ctx, wsURL(env.ts, sess.Code), &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": []string{"Bearer " + bot.APIKey}},
+	})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	wsSend(ctx, t, conn, joinMsg(bot.ID))
+	sp := readState(t, ctx, conn)
+	if !containsPlayer(sp.SessionInfo.Players, bot.ID) {
+		t.Fatalf("expected bot seated, got %v", sp.SessionInfo.Players)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(1000, 2)
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("expected burst to be allowed")
+	}
+	if l.Allow("a") {
+		t.Fatal("expected third immediate request to be limited")
+	}
+	if !l.Allow("b") {
+		t.Fatal("expected keys to be limited independently")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !l.Allow("a") {
+		t.Fatal("expected tokens to refill over time")
+	}
+}
+
+func TestRateLimiterDropsFullBuckets(t *testing.T) {
+	l := newRateLimiter(1000, 1)
+	l.Allow("a")
+	l.Allow("b")
+	l.swept = time.Time{}
+	time.Sleep(5 * time.Millisecond)
+	l.Allow("c")
+	if _, ok := l.buckets["a"]; ok || len(l.buckets) != 1 {
+		t.Fatalf("expected the refilled buckets dropped, got %v", l.buckets)
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"games/internal/session"
+)
+
+type clubBrandingRequest struct {
+	PlayerID string `json:"playerId"` // the owner or an admin
+	session.Branding
+}
+
+// SetBranding sets how the site presents itself: its name, logo and
+// colors, which every page applies. A tenant's server has its own.
+func (s *Server) SetBranding(b session.Branding) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	s.branding = b
+	return nil
+}
+
+// handleBranding returns the site's branding, or with ?club= the club's
+// over the site's, for the pages to apply.
+func (s *Server) handleBranding(w http.ResponseWriter, r *http.Request) {
+	b := s.branding
+	if id := r.URL.Query().Get("club"); id != "" {
+		c, err := s.manager.Club(r.Context(), id)
+		if err != nil {
+			writeJSON(w, clubErrorStatus(err), map[string]string{"error": err.Error()})
+			return
+		}
+		if c.Branding != nil {
+			b = c.Branding.Over(b)
+		}
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (s *Server) handleSetClubBranding(w http.ResponseWriter, r *http.Request) {
+	var req clubBrandingRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	b := req.Branding
+	b.Name = strings.TrimSpace(b.Name)
+	c, err := s.manager.SetClubBranding(r.Context(), r.PathValue("id"), req.PlayerID, b)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, session.ErrClubNotFound) || errors.Is(err, session.ErrNotPermitted) {
+			status = clubErrorStatus(err)
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"games/internal/session"
+)
+
+func TestBranding(t *testing.T) {
+	env := setupTestEnv(t)
+	if err := env.srv.SetBranding(session.Branding{AccentColor: "blue"}); err == nil {
+		t.Fatal("expected a color that is not #rrggbb refused")
+	}
+	site := session.Branding{Name: "Games Night", AccentColor: "#112233", SecondaryColor: "#445566"}
+	if err := env.srv.SetBranding(site); err != nil {
+		t.Fatal(err)
+	}
+	getBranding := func(query string, want int) session.Branding {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + "/api/branding" + query)
+		if err != nil {
+			t.Fatalf("GET branding: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("expected %d for %q, got %d", want, query, resp.StatusCode)
+		}
+		var b session.Branding
+		json.NewDecoder(resp.Body).Decode(&b)
+		return b
+	}
+	if b := getBranding("", http.StatusOK); b != site {
+		t.Fatalf("expected the site's branding, got %+v", b)
+	}
+	getBranding("?club=cl-missing", http.StatusNotFound)
+
+	alice, aliceID := guestBrowser(t, env.ts)
+	bob, bobID := guestBrowser(t, env.ts)
+	resp := browserPost(t, alice, env.ts.URL+"/api/clubs", `{"name":"Rooks","playerId":"`+aliceID+`"}`)
+	var club session.Club
+	json.NewDecoder(resp.Body).Decode(&club)
+	resp.Body.Close()
+	browserPost(t, bob, env.ts.URL+"/api/clubs/"+club.ID+"/join", `{"playerId":"`+bobID+`"}`).Body.Close()
+
+	for _, c := range []struct {
+		client *http.Client
+		body   string
+		want   int
+	}{
+		{bob, `{"playerId":"` + bobID + `","name":"Rooks CC"}`, http.StatusForbidden},
+		{bob, `{"playerId":"` + aliceID + `","name":"Rooks CC"}`, http.StatusForbidden},
+		{alice, `{"playerId":"` + aliceID + `","accentColor":"#zzzzzz"}`, http.StatusBadRequest},
+		{alice, `{"playerId":"` + aliceID + `","logoUrl":"data:image/png;base64,AA=="}`, http.StatusBadRequest},
+		{alice, `{"playerId":"` + aliceID + `","name":"Rooks CC","logoUrl":"https://rooks.example/logo.png","accentColor":"#aa0000"}`, http.StatusOK},
+	} {
+		set := browserDo(t, c.client, "PUT", env.ts.URL+"/api/clubs/"+club.ID+"/branding", c.body)
+		set.Body.Close()
+		if set.StatusCode != c.want {
+			t.Fatalf("expected %d for %s, got %d", c.want, c.body, set.StatusCode)
+		}
+	}
+	want := session.Branding{Name: "Rooks CC", LogoURL: "https://rooks.example/logo.png", AccentColor: "#aa0000", SecondaryColor: "#445566"}
+	if b := getBranding("?club="+club.ID, http.StatusOK); b != want {
+		t.Fatalf("expected the club's branding over the site's, got %+v", b)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"games/internal/event"
+)
+
+const (
+	// cacheTTL bounds how stale a cached response can get through changes
+	// that publish no event, such as a player joining.
+	cacheTTL         = 5 * time.Second
+	cacheEventBuffer = 256
+)
+
+// responseCache keeps the responses of hot read endpoints for a short
+// while. Each response is tagged with what it depends on, and events from
+// the bus drop the responses they may have changed.
+type responseCache struct {
+	ttl    time.Duration
+	events <-chan event.Event
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+	gen     int // bumped by every invalidation
+}
+
+type cachedResponse struct {
+	contentType string
+	body        []byte
+	tag         string
+	expires     time.Time
+}
+
+func newResponseCache(ttl time.Duration, events <-chan event.Event) *responseCache {
+	return &responseCache{ttl: ttl, events: events, entries: make(map[string]cachedResponse)}
+}
+
+// get returns the live response cached under key, if any, and the
+// generation to hand to put when caching a fresh one.
+func (c *responseCache) get(key string) (cachedResponse, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyEventsLocked()
+	resp, ok := c.entries[key]
+	if ok && time.Now().After(resp.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	return resp, c.gen, ok
+}
+
+// put caches resp under key unless something was invalidated since gen,
+// in which case resp may already be stale.
+func (c *responseCache) put(key string, resp cachedResponse, gen int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyEventsLocked()
+	if c.gen != gen {
+		return
+	}
+	resp.expires = time.Now().Add(c.ttl)
+	c.entries[key] = resp
+}
+
+// invalidate drops every response tagged tag.
+func (c *responseCache) invalidate(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(tag)
+}
+
+func (c *responseCache) invalidateLocked(tag string) {
+	c.gen++
+	for key, resp := range c.entries {
+		if tag == "" || resp.tag == tag {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// applyEventsLocked invalidates for the events published since it last
+// ran. The bus drops events for a full subscriber, so a full buffer
+// clears the whole cache. The caller must hold the lock.
+func (c *responseCache) applyEventsLocked() {
+	if len(c.events) == cap(c.events) {
+		c.invalidateLocked("")
+	}
+	for {
+		select {
+		case e := <-c.events:
+			switch e.Type {
+			case event.SessionCreated, event.MatchStarted:
+				c.invalidateLocked(lobbyCacheTag)
+			case event.MatchFinished:
+				c.invalidateLocked(lobbyCacheTag)
+				c.invalidateLocked(gameCacheTag(e.GameType))
+			}
+		default:
+			return
+		}
+	}
+}
+
+// lobbyCacheTag tags session listings.
+const lobbyCacheTag = "lobby"
+
+// gamesCacheTag tags the list of games.
+const gamesCacheTag = "games"
+
+// gameCacheTag tags the responses about one game's matches.
+func gameCacheTag(name string) string { return "game:" + name }
+
+func lobbyTag(*http.Request) string  { return lobbyCacheTag }
+func gamesTag(*http.Request) string  { return gamesCacheTag }
+func gameTag(r *http.Request) string { return gameCacheTag(r.PathValue("name")) }
+
+// bodyRecorder captures a response while writing it through.
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bodyRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// cached serves h's successful responses from the cache, keyed by path
+// and query, under the tag tag gives the request.
+func (s *Server) cached(tag func(*http.Request) string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path + "?" + r.URL.RawQuery
+		resp, gen, ok := s.cache.get(key)
+		if ok {
+			w.Header().Set("Content-Type", resp.contentType)
+			w.Write(resp.body)
+			return
+		}
+		rec := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		if rec.status == http.StatusOK {
+			s.cache.put(key, cachedResponse{
+				contentType: w.Header().Get("Content-Type"),
+				body:        rec.body.Bytes(),
+				tag:         tag(r),
+			}, gen)
+		}
+	}
+}
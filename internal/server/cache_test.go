@@ -0,0 +1,66 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"games/internal/event"
+)
+
+func TestResponseCache(t *testing.T) {
+	events := make(chan event.Event, 2)
+	c := newResponseCache(time.Hour, events)
+	put := func(key, tag string) {
+		_, gen, _ := c.get(key)
+		c.put(key, cachedResponse{body: []byte(key), tag: tag}, gen)
+	}
+	cached := func(key string) bool {
+		_, _, ok := c.get(key)
+		return ok
+	}
+
+	put("lobby", lobbyCacheTag)
+	put("stats", gameCacheTag("tictactoe"))
+	put("other", gameCacheTag("chess"))
+	events <- event.Event{Type: event.MatchFinished, GameType: "tictactoe"}
+	if cached("lobby") || cached("stats") || !cached("other") {
+		t.Fatal("expected a finished match to drop the lobby and its game's responses only")
+	}
+
+	_, gen, _ := c.get("stats")
+	c.invalidate(gameCacheTag("chess"))
+	c.put("stats", cachedResponse{tag: gameCacheTag("tictactoe")}, gen)
+	if cached("stats") {
+		t.Fatal("expected a response computed across an invalidation not to be cached")
+	}
+
+	put("lobby", lobbyCacheTag)
+	events <- event.Event{Type: event.YourTurn}
+	events <- event.Event{Type: event.YourTurn}
+	if cached("lobby") {
+		t.Fatal("expected a full event buffer to clear the cache")
+	}
+
+	c.ttl = -time.Second
+	put("lobby", lobbyCacheTag)
+	if cached("lobby") {
+		t.Fatal("expected an expired response to be dropped")
+	}
+}
+
+func TestListSessionsCached(t *testing.T) {
+	env := setupTestEnv(t)
+	createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	if page, _ := getSessionPage(t, env.ts.URL+"/api/sessions", ""); len(page.Sessions) != 1 {
+		t.Fatalf("expected one session, got %+v", page)
+	}
+
+	env.mgr.Create(t.Context(), "tictactoe") // publishes nothing
+	if page, _ := getSessionPage(t, env.ts.URL+"/api/sessions", ""); len(page.Sessions) != 1 {
+		t.Fatalf("expected the cached listing, got %+v", page)
+	}
+	createSessionViaAPI(t, env.ts, "tictactoe", "bob")
+	if page, _ := getSessionPage(t, env.ts.URL+"/api/sessions", ""); len(page.Sessions) != 3 {
+		t.Fatalf("expected a new session to refresh the listing, got %+v", page)
+	}
+}
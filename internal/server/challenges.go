@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+// Challenged players learn of challenges from the lobby feed
+// (GET /api/feed?player=<id>) or by polling GET /api/challenges?player=<id>.
+// Like issuing and answering challenges, both are for the player's own
+// browser alone.
+
+type createChallengeRequest struct {
+	GameType     string `json:"gameType"`
+	ChallengerID string `json:"challengerId"`
+	TargetID     string `json:"targetId"`
+}
+
+type answerChallengeRequest struct {
+	PlayerID string `json:"playerId"`
+}
+
+type challengeResponse struct {
+	ID           string    `json:"id"`
+	GameType     string    `json:"gameType"`
+	ChallengerID string    `json:"challengerId"`
+	TargetID     string    `json:"targetId"`
+	Status       string    `json:"status"`
+	SessionCode  string    `json:"sessionCode,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+func newChallengeResponse(c storage.ChallengeRow) challengeResponse {
+	return challengeResponse{
+		ID:           c.ID,
+		GameType:     c.GameType,
+		ChallengerID: c.ChallengerID,
+		TargetID:     c.TargetID,
+		Status:       c.Status,
+		SessionCode:  c.SessionCode,
+		ExpiresAt:    c.ExpiresAt,
+	}
+}
+
+func (s *Server) handleCreateChallenge(w http.ResponseWriter, r *http.Request) {
+	var req createChallengeRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.ChallengerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	c, err := s.manager.IssueChallenge(r.Context(), strings.TrimSpace(req.GameType), req.ChallengerID, req.TargetID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, newChallengeResponse(*c))
+}
+
+func (s *Server) handleListChallenges(w http.ResponseWriter, r *http.Request) {
+	player := r.URL.Query().Get("player")
+	if player == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "player required"})
+		return
+	}
+	if !s.isGuest(r, player) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	rows, err := s.manager.Challenges(r.Context(), player)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	out := make([]challengeResponse, 0, len(rows))
+	for _, c := range rows {
+		out = append(out, newChallengeResponse(c))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleAcceptChallenge starts the challenged player's match, returning
+// its code with their seat's join secret.
+func (s *Server) handleAcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	var req answerChallengeRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	sess, err := s.manager.AcceptChallenge(r.Context(), r.PathValue("id"), req.PlayerID)
+	if err != nil {
+		writeJSON(w, challengeErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	// The target's seat is claimed here, as a host's is on creating a
+	// session. The challenger's is their guest ID's, which only their own
+	// browser can take.
+	token := sess.ClaimSeat(req.PlayerID)
+	s.matchStarted(r.Context(), sess)
+	writeJSON(w, http.StatusCreated, createSessionResponse{Code: sess.Code, PlayerID: req.PlayerID, Token: token})
+}
+
+func (s *Server) handleDeclineChallenge(w http.ResponseWriter, r *http.Request) {
+	var req answerChallengeRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	if err := s.manager.DeclineChallenge(r.Context(), r.PathValue("id"), req.PlayerID); err != nil {
+		writeJSON(w, challengeErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "declined"})
+}
+
+func challengeErrorStatus(err error) int {
+	if errors.Is(err, session.ErrChallengeNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusConflict
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"games/internal/event"
+	"games/internal/session"
+)
+
+func postJSON(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	return resp
+}
+
+func TestChallengeFlow(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, aliceID := guestBrowser(t, env.ts)
+	bob, bobID := guestBrowser(t, env.ts)
+
+	resp := browserPost(t, alice, env.ts.URL+"/api/challenges", `{"gameType":"tictactoe","challengerId":"`+aliceID+`","targetId":"`+bobID+`"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var c challengeResponse
+	json.NewDecoder(resp.Body).Decode(&c)
+	if c.Status != "pending" || c.TargetID != bobID {
+		t.Fatalf("unexpected challenge: %+v", c)
+	}
+
+	listResp, err := bob.Get(env.ts.URL + "/api/challenges?player=" + bobID)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	defer listResp.Body.Close()
+	var pending []challengeResponse
+	json.NewDecoder(listResp.Body).Decode(&pending)
+	if len(pending) != 1 || pending[0].ID != c.ID {
+		t.Fatalf("expected bob to see the challenge, got %+v", pending)
+	}
+
+	wrong := browserPost(t, alice, env.ts.URL+"/api/challenges/"+c.ID+"/accept", `{"playerId":"`+aliceID+`"}`)
+	wrong.Body.Close()
+	if wrong.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for challenger accepting, got %d", wrong.StatusCode)
+	}
+
+	acceptResp := browserPost(t, bob, env.ts.URL+"/api/challenges/"+c.ID+"/accept", `{"playerId":"`+bobID+`"}`)
+	defer acceptResp.Body.Close()
+	if acceptResp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", acceptResp.StatusCode)
+	}
+	var created createSessionResponse
+	json.NewDecoder(acceptResp.Body).Decode(&created)
+	sess, ok := env.mgr.Get(created.Code)
+	if !ok {
+		t.Fatal("accepted challenge should create a session")
+	}
+	if info := sess.Info(); info.Status != session.StatusPlaying || len(info.Players) != 2 {
+		t.Fatalf("expected a started match with both players, got %+v", info)
+	}
+	if created.PlayerID != bobID || created.Token == "" || !sess.AcceptsToken(bobID, created.Token) || sess.AcceptsToken(bobID, "guess") {
+		t.Fatalf("expected bob's seat claimed with the returned secret, got %+v", created)
+	}
+
+	missing := browserPost(t, bob, env.ts.URL+"/api/challenges/ch-missing/decline", `{"playerId":"`+bobID+`"}`)
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", missing.StatusCode)
+	}
+}
+
+// TestChallengesOnlyForOwnBrowser checks that no one issues, sees or
+// answers challenges as another player, or follows their feed.
+func TestChallengesOnlyForOwnBrowser(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, aliceID := guestBrowser(t, env.ts)
+	mallory, _ := guestBrowser(t, env.ts)
+	_, bobID := guestBrowser(t, env.ts)
+	resp := browserPost(t, alice, env.ts.URL+"/api/challenges", `{"gameType":"tictactoe","challengerId":"`+aliceID+`","targetId":"`+bobID+`"}`)
+	var c challengeResponse
+	json.NewDecoder(resp.Body).Decode(&c)
+	resp.Body.Close()
+
+	for _, r := range []struct {
+		method, path, body string
+	}{
+		{"POST", "/api/challenges", `{"gameType":"tictactoe","challengerId":"` + aliceID + `","targetId":"` + bobID + `"}`},
+		{"GET", "/api/challenges?player=" + bobID, ""},
+		{"POST", "/api/challenges/" + c.ID + "/accept", `{"playerId":"` + bobID + `"}`},
+		{"POST", "/api/challenges/" + c.ID + "/decline", `{"playerId":"` + bobID + `"}`},
+		{"GET", "/api/feed/snapshot?player=" + bobID, ""},
+		{"GET", "/api/feed?player=" + bobID, ""},
+	} {
+		resp := browserDo(t, mallory, r.method, env.ts.URL+r.path, r.body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s from another browser: expected 403, got %d", r.method, r.path, resp.StatusCode)
+		}
+	}
+	if rows, _ := env.mgr.Challenges(t.Context(), bobID); len(rows) != 1 || rows[0].Status != "pending" {
+		t.Fatalf("expected alice's challenge still pending, got %+v", rows)
+	}
+}
+
+func TestFeedFilterRecipients(t *testing.T) {
+	e := event.Event{Type: event.ChallengeIssued, GameType: "tictactoe", Recipients: []string{"bob"}}
+
+	for player, want := range map[string]bool{"": false, "alice": false, "bob": true} {
+		req, _ := http.NewRequest("GET", "/api/feed?player="+player, nil)
+		if got := parseFeedFilter(req).match(e); got != want {
+			t.Fatalf("player %q: match = %v, want %v", player, got, want)
+		}
+	}
+}
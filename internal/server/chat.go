@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"games/internal/session"
+)
+
+// maxChatLength caps a chat line, in characters.
+const maxChatLength = 500
+
+// chatPayload is a line a player typed into the session's chat.
+type chatPayload struct {
+	Text string `json:"text"`
+}
+
+// chatMessagePayload is a chat line as everyone in the session is shown
+// it. PlayerID is empty for the server's answers to commands, which only
+// the player who sent the command sees.
+type chatMessagePayload struct {
+	PlayerID string    `json:"playerId,omitempty"`
+	Text     string    `json:"text"`
+	At       time.Time `json:"at"`
+}
+
+// chatCommand is a slash command, which stands for one of the WebSocket's
+// other messages so a client that only shows chat can still run the
+// session.
+type chatCommand struct {
+	msgType string
+	usage   string // the arguments, for /help; empty for none
+	help    string
+	// hostOnly commands are refused, and left out of /help, for everyone
+	// but the host. The message they stand for checks again.
+	hostOnly bool
+	// payload builds the message's payload from the arguments of a
+	// command that takes any.
+	payload func(args string) (any, error)
+}
+
+var chatCommands = map[string]chatCommand{
+	"start":   {msgType: "start", help: "starts the match", hostOnly: true},
+	"rematch": {msgType: "rematch", help: "plays the same game again with the same players", hostOnly: true},
+	"next":    {msgType: "next_game", help: "starts a party's next round", hostOnly: true},
+	"handoff": {msgType: "handoff", help: "gives a code to move your seat to another device"},
+	"move": {msgType: "action_text", usage: "<move>", help: "makes a move written in the game's notation, such as b2",
+		payload: func(args string) (any, error) { return actionTextPayload{Text: args}, nil }},
+	"skip": {msgType: "vote", usage: "<player>", help: "votes to skip an unresponsive player's turn",
+		payload: votePayloadFor(session.VoteSkip)},
+	"remove": {msgType: "vote", usage: "<player>", help: "votes to remove an unresponsive player from the match",
+		payload: votePayloadFor(session.VoteRemove)},
+}
+
+func votePayloadFor(kind session.VoteKind) func(string) (any, error) {
+	return func(args string) (any, error) {
+		if strings.ContainsAny(args, " \t") {
+			return nil, fmt.Errorf("/%s takes one player ID", kind)
+		}
+		return votePayload{Kind: string(kind), PlayerID: args}, nil
+	}
+}
+
+// chat shows a player's line to everyone in the session and records it in
+// the timeline, or runs it if it is a slash command.
+func (s *Server) chat(ctx context.Context, sess *session.Session, playerID string, send chan []byte, text string) {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		sendWSMsg(send, "error", errorPayload{Message: "chat message is empty"})
+		return
+	case utf8.RuneCountInString(text) > maxChatLength:
+		sendWSMsg(send, "error", errorPayload{Message: "chat message is too long"})
+		return
+	case strings.HasPrefix(text, "/"):
+		s.runCommand(ctx, sess, playerID, send, text[1:])
+		return
+	}
+	// Players chatting after a match are still using the session, so
+	// cleanup measures its idleness from their last line
+	if err := s.manager.Touch(ctx, sess); err != nil {
+		log.Printf("save activity: %v", err)
+	}
+	msg := encodeWSMsg("chat", chatMessagePayload{PlayerID: playerID, Text: text, At: time.Now()})
+	for _, pid := range sess.PlayerIDs() {
+		for _, c := range sess.PlayerSends(pid) {
+			sendEncoded(c, msg)
+		}
+	}
+	sess.RLock()
+	for _, c := range sess.Spectators {
+		sendEncoded(c, msg)
+	}
+	sess.RUnlock()
+	s.manager.RecordTimeline(ctx, sess.Code, session.TimelineChat, playerID, map[string]string{"text": text})
+}
+
+// runCommand runs the slash command line, without its slash, as the
+// message it stands for, after checking playerID may.
+func (s *Server) runCommand(ctx context.Context, sess *session.Session, playerID string, send chan []byte, line string) {
+	name, args, _ := strings.Cut(line, " ")
+	name, args = strings.ToLower(name), strings.TrimSpace(args)
+	isHost := sess.Info().HostID == playerID
+	if name == "help" {
+		sendWSMsg(send, "chat", chatMessagePayload{Text: commandHelp(isHost), At: time.Now()})
+		return
+	}
+	cmd, ok := chatCommands[name]
+	if !ok {
+		sendWSMsg(send, "error", errorPayload{Message: "unknown command /" + name + "; /help lists them"})
+		return
+	}
+	if cmd.hostOnly && !isHost {
+		sendWSMsg(send, "error", errorPayload{Message: "only the host can /" + name})
+		return
+	}
+	var payload any
+	switch {
+	case cmd.payload == nil && args != "":
+		sendWSMsg(send, "error", errorPayload{Message: "/" + name + " takes no arguments"})
+		return
+	case cmd.payload != nil && args == "":
+		sendWSMsg(send, "error", errorPayload{Message: "usage: /" + name + " " + cmd.usage})
+		return
+	case cmd.payload != nil:
+		var err error
+		if payload, err = cmd.payload(args); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+	}
+	data, _ := json.Marshal(payload)
+	s.handleMessage(ctx, sess, playerID, send, WSMessage{Type: cmd.msgType, Payload: data})
+}
+
+// commandHelp lists the commands a player may run.
+func commandHelp(isHost bool) string {
+	lines := []string{"/help lists these commands"}
+	for name, cmd := range chatCommands {
+		if cmd.hostOnly && !isHost {
+			continue
+		}
+		usage := "/" + name
+		if cmd.usage != "" {
+			usage += " " + cmd.usage
+		}
+		lines = append(lines, usage+" "+cmd.help)
+	}
+	sort.Strings(lines[1:])
+	return strings.Join(lines, "\n")
+}
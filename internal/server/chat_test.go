@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/session"
+)
+
+func TestChat(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, bob)
+
+	// readUntil skips other messages, such as state broadcasts
+	readUntil := func(conn *websocket.Conn, msgType string) WSMessage {
+		t.Helper()
+		for {
+			msg := wsRead(ctx, t, conn)
+			if msg.Type == msgType {
+				return msg
+			}
+			if msg.Type == "error" {
+				t.Fatalf("expected %s, got error %s", msgType, msg.Payload)
+			}
+		}
+	}
+	chat := func(conn *websocket.Conn, text string) {
+		t.Helper()
+		if err := sendWS(ctx, conn, "chat", chatPayload{Text: text}); err != nil {
+			t.Fatalf("send chat: %v", err)
+		}
+	}
+	refused := func(conn *websocket.Conn, text, want string) {
+		t.Helper()
+		chat(conn, text)
+		if got := readError(t, ctx, conn); got != want {
+			t.Errorf("%s: expected %q, got %q", text, want, got)
+		}
+	}
+
+	chat(bob, " hello ")
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		var line chatMessagePayload
+		json.Unmarshal(readUntil(conn, "chat").Payload, &line)
+		if line.PlayerID != "bob" || line.Text != "hello" {
+			t.Errorf("expected bob's line, got %+v", line)
+		}
+	}
+
+	refused(bob, "/start", "only the host can /start")
+	refused(bob, "/resign", "unknown command /resign; /help lists them")
+	refused(bob, "/move", "usage: /move <move>")
+	refused(bob, "/handoff now", "/handoff takes no arguments")
+	refused(bob, "   ", "chat message is empty")
+	refused(bob, strings.Repeat("x", maxChatLength+1), "chat message is too long")
+
+	chat(bob, "/help")
+	var help chatMessagePayload
+	json.Unmarshal(readUntil(bob, "chat").Payload, &help)
+	if help.PlayerID != "" || !strings.Contains(help.Text, "/move <move>") || strings.Contains(help.Text, "/start") {
+		t.Errorf("expected a guest's help without host commands, got %+v", help)
+	}
+
+	chat(alice, "/START")
+	readUntil(alice, "state")
+	sess, _ := env.mgr.Get(code)
+	if sess.Info().Status != session.StatusPlaying {
+		t.Fatalf("expected /start to start the match, got %s", sess.Info().Status)
+	}
+	// Alice joined first, so moves first
+	chat(alice, "/move b2")
+	readUntil(alice, "events")
+
+	entries, err := env.mgr.Timeline(context.Background(), code, 0, 0)
+	if err != nil {
+		t.Fatalf("timeline: %v", err)
+	}
+	var said []string
+	for _, e := range entries {
+		if e.Type == session.TimelineChat {
+			said = append(said, e.PlayerID+" "+string(e.Detail))
+		}
+	}
+	if len(said) != 1 || said[0] != `bob {"text":"hello"}` {
+		t.Errorf("expected only bob's line in the timeline, got %q", said)
+	}
+}
+
+// TestChatKeepsSessionActive checks that cleanup counts a session's idle
+// time from its last chat line, not its last move or join.
+func TestChatKeepsSessionActive(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	sess, _ := env.mgr.Get(code)
+	sess.Lock()
+	sess.ExpireAfter = time.Minute
+	sess.LastActivity = time.Now().Add(-time.Hour)
+	sess.Unlock()
+	decision := func() session.CleanupDecision {
+		t.Helper()
+		for _, d := range env.mgr.Cleanup(ctx, true).Decisions {
+			if d.Code == code {
+				return d
+			}
+		}
+		t.Fatalf("expected a decision for %s", code)
+		return session.CleanupDecision{}
+	}
+	if d := decision(); d.Reason != session.CleanupExpired {
+		t.Fatalf("expected the idle session due to expire, got %+v", d)
+	}
+
+	if err := sendWS(ctx, alice, "chat", chatPayload{Text: "still here"}); err != nil {
+		t.Fatalf("send chat: %v", err)
+	}
+	for wsRead(ctx, t, alice).Type != "chat" {
+	}
+	if d := decision(); d.Reason != session.CleanupUnexpired || d.Action != session.CleanupKept {
+		t.Fatalf("expected chatting to keep the session, got %+v", d)
+	}
+}
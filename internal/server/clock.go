@@ -0,0 +1,35 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// pingPayload is a client's clock probe; ClientTime is its own clock when
+// it sent the probe, in whatever unit it likes, and comes back unchanged.
+type pingPayload struct {
+	ClientTime json.Number `json:"clientTime"`
+}
+
+// pongPayload answers a probe with the server's clock in Unix
+// milliseconds. The client takes half the round trip off it to estimate
+// how far its clock is from the server's.
+type pongPayload struct {
+	ClientTime json.Number `json:"clientTime"`
+	ServerTime int64       `json:"serverTime"`
+}
+
+// serverNow is the server clock as sent to clients, in Unix milliseconds.
+func serverNow() int64 {
+	return time.Now().UnixMilli()
+}
+
+// pong answers a clock probe.
+func pong(send chan []byte, payload json.RawMessage) {
+	var pp pingPayload
+	if len(payload) > 0 && unmarshalStrict(payload, &pp) != nil {
+		sendWSMsg(send, "error", errorPayload{Message: "invalid ping payload"})
+		return
+	}
+	sendWSMsg(send, "pong", pongPayload{ClientTime: pp.ClientTime, ServerTime: serverNow()})
+}
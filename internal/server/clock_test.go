@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSClockProbes(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	before := time.Now().UnixMilli()
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	if sp := readState(t, ctx, alice); sp.ServerTime < before || sp.ServerTime > time.Now().UnixMilli() {
+		t.Fatalf("expected the state stamped with the server time, got %d", sp.ServerTime)
+	}
+
+	wall := joinWith(t, env, sess.Code, joinPayload{PlayerID: "wall", Spectate: true})
+	defer wall.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, wall)
+
+	for _, conn := range []*websocket.Conn{alice, wall} {
+		before := time.Now().UnixMilli()
+		sendWS(ctx, conn, "ping", pingPayload{ClientTime: "1234.5"})
+		msg := wsRead(ctx, t, conn)
+		if msg.Type != "pong" {
+			t.Fatalf("expected pong, got %q: %s", msg.Type, msg.Payload)
+		}
+		var pp pongPayload
+		json.Unmarshal(msg.Payload, &pp)
+		if pp.ClientTime != "1234.5" || pp.ServerTime < before || pp.ServerTime > time.Now().UnixMilli() {
+			t.Fatalf("unexpected pong %+v", pp)
+		}
+	}
+
+	sendWS(ctx, wall, "start", nil)
+	if msg := readError(t, ctx, wall); msg != "spectators cannot send messages" {
+		t.Fatalf("expected spectators still refused, got %q", msg)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"games/internal/session"
+)
+
+type createClubRequest struct {
+	Name     string `json:"name"`
+	PlayerID string `json:"playerId"` // the owner
+}
+
+type clubPlayerRequest struct {
+	PlayerID string `json:"playerId"`
+}
+
+type clubRoleRequest struct {
+	PlayerID string `json:"playerId"` // the owner making the change
+	Role     string `json:"role"`
+}
+
+type clubsResponse struct {
+	Clubs []session.Club `json:"clubs"`
+}
+
+type clubLeaderboardResponse struct {
+	Standings []session.ClubStanding `json:"standings"`
+}
+
+func (s *Server) handleCreateClub(w http.ResponseWriter, r *http.Request) {
+	var req createClubRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	c, err := s.manager.CreateClub(r.Context(), req.Name, req.PlayerID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (s *Server) handleListClubs(w http.ResponseWriter, r *http.Request) {
+	list, err := s.manager.Clubs(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, clubsResponse{Clubs: list})
+}
+
+func (s *Server) handleGetClub(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Club(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, clubErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) handleJoinClub(w http.ResponseWriter, r *http.Request) {
+	var req clubPlayerRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	c, err := s.manager.JoinClub(r.Context(), r.PathValue("id"), req.PlayerID)
+	if err != nil {
+		writeJSON(w, clubErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// handleSetClubRole lets the owner, from their own browser, make a member
+// an admin or back.
+func (s *Server) handleSetClubRole(w http.ResponseWriter, r *http.Request) {
+	var req clubRoleRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	c, err := s.manager.SetClubRole(r.Context(), r.PathValue("id"), req.PlayerID, r.PathValue("player"), strings.TrimSpace(req.Role))
+	if err != nil {
+		writeJSON(w, clubErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// handleRemoveClubMember takes a member out of a club: themselves
+// leaving, or removed by the owner or an admin named in the body, each
+// from their own browser.
+func (s *Server) handleRemoveClubMember(w http.ResponseWriter, r *http.Request) {
+	var req clubPlayerRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	c, err := s.manager.RemoveClubMember(r.Context(), r.PathValue("id"), req.PlayerID, r.PathValue("player"))
+	if err != nil {
+		writeJSON(w, clubErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// handleClubLobby shows a member, named by ?playerId= and calling from
+// their own browser, the club with its tournaments and the games of them
+// in play.
+func (s *Server) handleClubLobby(w http.ResponseWriter, r *http.Request) {
+	playerID := r.URL.Query().Get("playerId")
+	if !s.isGuest(r, playerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	lobby, err := s.manager.ClubLobby(r.Context(), r.PathValue("id"), playerID)
+	if err != nil {
+		writeJSON(w, clubErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, lobby)
+}
+
+// handleClubLeaderboard ranks a club's members over its tournaments, for
+// a member named by ?playerId= and calling from their own browser; ?game=
+// narrows it to one game type.
+func (s *Server) handleClubLeaderboard(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !s.isGuest(r, q.Get("playerId")) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	standings, err := s.manager.ClubLeaderboard(r.Context(), r.PathValue("id"), q.Get("playerId"), q.Get("game"))
+	if err != nil {
+		writeJSON(w, clubErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, clubLeaderboardResponse{Standings: standings})
+}
+
+func (s *Server) handlePlayerClubs(w http.ResponseWriter, r *http.Request) {
+	list, err := s.manager.PlayerClubs(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, clubsResponse{Clubs: list})
+}
+
+func clubErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, session.ErrClubNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, session.ErrNotPermitted):
+		return http.StatusForbidden
+	}
+	return http.StatusConflict
+}
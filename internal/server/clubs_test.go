@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"games/internal/session"
+)
+
+func TestClubFlow(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, aliceID := guestBrowser(t, env.ts)
+	bob, bobID := guestBrowser(t, env.ts)
+	carol, carolID := guestBrowser(t, env.ts)
+	erin, erinID := guestBrowser(t, env.ts)
+	get := func(client *http.Client, path string) *http.Response {
+		t.Helper()
+		resp, err := client.Get(env.ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return resp
+	}
+
+	resp := browserPost(t, alice, env.ts.URL+"/api/clubs", `{"name":"Rooks","playerId":"`+aliceID+`"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var club session.Club
+	json.NewDecoder(resp.Body).Decode(&club)
+	for _, m := range []struct {
+		client *http.Client
+		id     string
+	}{{bob, bobID}, {carol, carolID}} {
+		join := browserPost(t, m.client, env.ts.URL+"/api/clubs/"+club.ID+"/join", `{"playerId":"`+m.id+`"}`)
+		join.Body.Close()
+		if join.StatusCode != http.StatusOK {
+			t.Fatalf("expected %s to join, got %d", m.id, join.StatusCode)
+		}
+	}
+	missing := browserPost(t, bob, env.ts.URL+"/api/clubs/cl-missing/join", `{"playerId":"`+bobID+`"}`)
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown club, got %d", missing.StatusCode)
+	}
+
+	denied := browserDo(t, bob, "PUT", env.ts.URL+"/api/clubs/"+club.ID+"/members/"+carolID, `{"playerId":"`+bobID+`","role":"admin"}`)
+	denied.Body.Close()
+	if denied.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a member appointing an admin, got %d", denied.StatusCode)
+	}
+	promote := browserDo(t, alice, "PUT", env.ts.URL+"/api/clubs/"+club.ID+"/members/"+bobID, `{"playerId":"`+aliceID+`","role":"admin"}`)
+	promote.Body.Close()
+	if promote.StatusCode != http.StatusOK {
+		t.Fatalf("expected bob made an admin, got %d", promote.StatusCode)
+	}
+
+	// The admin holds a private tournament the open list leaves out
+	tnResp := browserPost(t, bob, env.ts.URL+"/api/tournaments", `{"name":"Club night","gameType":"tictactoe","format":"swiss","playerId":"`+bobID+`","club":"`+club.ID+`"}`)
+	defer tnResp.Body.Close()
+	if tnResp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the admin to create a club tournament, got %d", tnResp.StatusCode)
+	}
+	var tn session.Tournament
+	json.NewDecoder(tnResp.Body).Decode(&tn)
+	outsider := browserPost(t, erin, env.ts.URL+"/api/tournaments/"+tn.ID+"/join", `{"playerId":"`+erinID+`"}`)
+	outsider.Body.Close()
+	if outsider.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-member entering, got %d", outsider.StatusCode)
+	}
+	for _, q := range []struct {
+		query string
+		want  int
+	}{
+		{"", 0},
+		{"?club=" + club.ID + "&playerId=" + carolID, 1},
+	} {
+		listResp := get(carol, "/api/tournaments"+q.query)
+		var list tournamentsResponse
+		json.NewDecoder(listResp.Body).Decode(&list)
+		listResp.Body.Close()
+		if len(list.Tournaments) != q.want {
+			t.Fatalf("expected %d tournaments for %q, got %+v", q.want, q.query, list)
+		}
+	}
+	hidden := get(erin, "/api/tournaments?club="+club.ID+"&playerId="+erinID)
+	hidden.Body.Close()
+	if hidden.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 listing the club's for a non-member, got %d", hidden.StatusCode)
+	}
+
+	lobbyResp := get(carol, "/api/clubs/"+club.ID+"/lobby?playerId="+carolID)
+	defer lobbyResp.Body.Close()
+	var lobby session.ClubLobby
+	json.NewDecoder(lobbyResp.Body).Decode(&lobby)
+	if lobby.Club == nil || lobby.Club.MemberCount != 3 || len(lobby.Tournaments) != 1 || lobby.Tournaments[0].ID != tn.ID {
+		t.Fatalf("expected the club and its tournament in the lobby, got %+v", lobby)
+	}
+
+	boardResp := get(carol, "/api/clubs/"+club.ID+"/leaderboard?playerId="+carolID)
+	defer boardResp.Body.Close()
+	var board clubLeaderboardResponse
+	json.NewDecoder(boardResp.Body).Decode(&board)
+	if len(board.Standings) != 3 || board.Standings[0].PlayerID != aliceID || board.Standings[1].Role != session.RoleAdmin {
+		t.Fatalf("expected every member on the leaderboard, got %+v", board)
+	}
+	closed := get(erin, "/api/clubs/"+club.ID+"/leaderboard?playerId="+erinID)
+	closed.Body.Close()
+	if closed.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-member, got %d", closed.StatusCode)
+	}
+
+	// carol leaves the club
+	left := browserDo(t, carol, "DELETE", env.ts.URL+"/api/clubs/"+club.ID+"/members/"+carolID, `{"playerId":"`+carolID+`"}`)
+	left.Body.Close()
+	if left.StatusCode != http.StatusOK {
+		t.Fatalf("expected carol to leave, got %d", left.StatusCode)
+	}
+	mineResp := get(carol, "/api/players/"+carolID+"/clubs")
+	defer mineResp.Body.Close()
+	var mine clubsResponse
+	json.NewDecoder(mineResp.Body).Decode(&mine)
+	if len(mine.Clubs) != 0 {
+		t.Fatalf("expected carol in no clubs, got %+v", mine)
+	}
+
+	allResp := get(http.DefaultClient, "/api/clubs")
+	defer allResp.Body.Close()
+	var all clubsResponse
+	json.NewDecoder(allResp.Body).Decode(&all)
+	if len(all.Clubs) != 1 || all.Clubs[0].MemberCount != 2 || all.Clubs[0].Members != nil {
+		t.Fatalf("expected the club listed without its members, got %+v", all)
+	}
+}
+
+// TestClubActsOnlyForOwnBrowser checks that naming a member is not enough
+// to act for them: the owner's ID is public, but only their browser may
+// use it.
+func TestClubActsOnlyForOwnBrowser(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, aliceID := guestBrowser(t, env.ts)
+	mallory, malloryID := guestBrowser(t, env.ts)
+	resp := browserPost(t, alice, env.ts.URL+"/api/clubs", `{"name":"Rooks","playerId":"`+aliceID+`"}`)
+	var club session.Club
+	json.NewDecoder(resp.Body).Decode(&club)
+	resp.Body.Close()
+	browserPost(t, mallory, env.ts.URL+"/api/clubs/"+club.ID+"/join", `{"playerId":"`+malloryID+`"}`).Body.Close()
+	tnResp := browserPost(t, alice, env.ts.URL+"/api/tournaments", `{"name":"Club night","gameType":"tictactoe","format":"swiss","playerId":"`+aliceID+`","club":"`+club.ID+`"}`)
+	var tn session.Tournament
+	json.NewDecoder(tnResp.Body).Decode(&tn)
+	tnResp.Body.Close()
+
+	as := `{"playerId":"` + aliceID + `"}`
+	for _, c := range []struct {
+		method, path, body string
+	}{
+		{"POST", "/api/clubs", `{"name":"Fake","playerId":"` + aliceID + `"}`},
+		{"POST", "/api/clubs/" + club.ID + "/join", as},
+		{"PUT", "/api/clubs/" + club.ID + "/members/" + malloryID, `{"playerId":"` + aliceID + `","role":"admin"}`},
+		{"DELETE", "/api/clubs/" + club.ID + "/members/" + aliceID, as},
+		{"PUT", "/api/clubs/" + club.ID + "/branding", `{"playerId":"` + aliceID + `","name":"Pwned"}`},
+		{"POST", "/api/tournaments", `{"name":"Fake","gameType":"tictactoe","format":"swiss","playerId":"` + aliceID + `","club":"` + club.ID + `"}`},
+		{"POST", "/api/tournaments/" + tn.ID + "/join", as},
+		{"POST", "/api/tournaments/" + tn.ID + "/start", as},
+		{"GET", "/api/clubs/" + club.ID + "/lobby?playerId=" + aliceID, ""},
+		{"GET", "/api/clubs/" + club.ID + "/leaderboard?playerId=" + aliceID, ""},
+		{"GET", "/api/tournaments?club=" + club.ID + "&playerId=" + aliceID, ""},
+	} {
+		resp := browserDo(t, mallory, c.method, env.ts.URL+c.path, c.body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s as alice from another browser: expected 403, got %d", c.method, c.path, resp.StatusCode)
+		}
+	}
+	got, err := env.mgr.Club(t.Context(), club.ID)
+	if err != nil || got.MemberCount != 2 || got.Branding != nil {
+		t.Fatalf("expected the club untouched, got %+v %v", got, err)
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSCompression(t *testing.T) {
+	for _, tt := range []struct {
+		mode       string
+		compressed int64
+		saves      bool
+	}{
+		{"on", 1, true},
+		{"context", 1, true},
+		{"off", 0, false},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			env := setupTestEnv(t)
+			env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+			if err := env.srv.SetCompression(tt.mode, 64); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := timeoutCtx(t)
+			defer cancel()
+
+			code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+			conn, _, err := websocket.Dial(ctx, wsURL(env.ts, code), &websocket.DialOptions{
+				CompressionMode: websocket.CompressionContextTakeover,
+			})
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close(websocket.StatusNormalClosure, "")
+			sendWS(ctx, conn, "join", joinPayload{PlayerID: "alice", Token: seatToken(env.ts, code, "alice")})
+			readState(t, ctx, conn)
+
+			// The written bytes are counted once the write returns, which
+			// may be after the client has read them
+			var report compressionReport
+			for range 50 {
+				resp := adminRequest(t, "GET", env.ts.URL+"/api/admin/compression", "secret", "")
+				json.NewDecoder(resp.Body).Decode(&report)
+				resp.Body.Close()
+				if report.WireBytes > 0 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if report.Mode != tt.mode || report.Connections != 1 || report.CompressedConnections != tt.compressed {
+				t.Fatalf("unexpected report %+v", report)
+			}
+			if report.MessageBytes == 0 || (report.Ratio < 1) != tt.saves {
+				t.Fatalf("expected compression to save bytes: %v, got %+v", tt.saves, report)
+			}
+		})
+	}
+
+	if err := setupTestEnv(t).srv.SetCompression("gzip", 0); err == nil {
+		t.Fatal("expected an unknown mode refused")
+	}
+}
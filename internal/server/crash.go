@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+
+	"games/internal/session"
+)
+
+// failIfPanicked fails sess if err is a panic in its game, telling its
+// players and spectators the game has stopped. It reports whether err was
+// a panic. The session lock must not be held.
+func (s *Server) failIfPanicked(ctx context.Context, sess *session.Session, err error) bool {
+	var perr *session.PanicError
+	if !errors.As(err, &perr) {
+		return false
+	}
+	if !s.manager.Fail(ctx, sess, perr) {
+		return true
+	}
+	s.broadcastState(sess)
+	msg := encodeWSMsg("error", errorPayload{Message: perr.Error()})
+	for _, pid := range sess.PlayerIDs() {
+		for _, send := range sess.PlayerSends(pid) {
+			sendEncoded(send, msg)
+		}
+	}
+	sess.RLock()
+	for _, send := range sess.Spectators {
+		sendEncoded(send, msg)
+	}
+	sess.RUnlock()
+	return true
+}
+
+// recoverSession is deferred by goroutines that drive a session on their
+// own, so a panic in its game fails that session instead of the server.
+func (s *Server) recoverSession(sess *session.Session) {
+	if v := recover(); v != nil {
+		s.failIfPanicked(context.Background(), sess, &session.PanicError{Value: v, Stack: debug.Stack()})
+	}
+}
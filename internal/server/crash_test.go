@@ -0,0 +1,192 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/session"
+
+	"nhooyr.io/websocket"
+)
+
+// buggyGame is tic-tac-toe whose match panics when the last cell is
+// taken.
+type buggyGame struct{ tictactoe.TicTacToe }
+
+func (buggyGame) Info() game.GameInfo {
+	info := tictactoe.TicTacToe{}.Info()
+	info.Name = "buggy"
+	return info
+}
+
+func (g buggyGame) NewMatch(config game.MatchConfig) game.Match {
+	return &buggyMatch{Match: g.TicTacToe.NewMatch(config)}
+}
+
+type buggyMatch struct{ game.Match }
+
+func (m *buggyMatch) ApplyAction(playerID string, action game.Action) error {
+	if string(action.Payload) == `{"cell":8}` {
+		panic("index out of range")
+	}
+	return m.Match.ApplyAction(playerID, action)
+}
+
+func (m *buggyMatch) Clone() game.Match {
+	return &buggyMatch{Match: m.Match.Clone()}
+}
+
+func TestWSGamePanicFailsOnlyItsSession(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.registry.Register(buggyGame{})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	other, _ := env.mgr.Create(t.Context(), "tictactoe")
+	other.AddPlayer("carol")
+	other.AddPlayer("dave")
+	if err := other.Start(); err != nil {
+		t.Fatalf("start other: %v", err)
+	}
+
+	sess, _ := env.mgr.Create(t.Context(), "buggy")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, sess.Code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "action", makeAction(t, 8))
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		sp := readState(t, ctx, conn)
+		if sp.SessionInfo.Status != session.StatusErrored || sp.State != nil || len(sp.ValidActions) != 0 {
+			t.Fatalf("expected an errored session without a state, got %+v", sp)
+		}
+		if msg := readError(t, ctx, conn); msg != "the game stopped after an internal error" {
+			t.Fatalf("unexpected error %q", msg)
+		}
+	}
+	sendWS(ctx, bob, "action", makeAction(t, 0))
+	if msg := readError(t, ctx, bob); msg != "game stopped after an error" {
+		t.Fatalf("expected further moves refused, got %q", msg)
+	}
+
+	if err := other.Match.ApplyAction(other.PlayerIDs()[0], makeAction(t, 4).Action); err != nil {
+		t.Fatalf("expected the other session to play on, got %v", err)
+	}
+	if info := other.Info(); info.Status != session.StatusPlaying {
+		t.Fatalf("expected the other session still playing, got %s", info.Status)
+	}
+
+	resp, err := http.Get(env.ts.URL + "/api/sessions?status=errored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var page sessionPage
+	json.NewDecoder(resp.Body).Decode(&page)
+	if len(page.Sessions) != 1 || page.Sessions[0].Code != sess.Code {
+		t.Fatalf("expected the errored status stored, got %+v", page)
+	}
+}
+
+func TestGamePanicOnStart(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.registry.Register(panicOnStartGame{})
+
+	sess, _ := env.mgr.Create(t.Context(), "panic-on-start")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	resp := startViaAPI(t, env, sess.Code)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+	if info := sess.Info(); info.Status != session.StatusErrored {
+		t.Fatalf("expected the session errored, got %s", info.Status)
+	}
+}
+
+type panicOnStartGame struct{ tictactoe.TicTacToe }
+
+func (panicOnStartGame) Info() game.GameInfo {
+	info := tictactoe.TicTacToe{}.Info()
+	info.Name = "panic-on-start"
+	return info
+}
+
+func (panicOnStartGame) NewMatch(game.MatchConfig) game.Match {
+	panic("no board")
+}
+
+func TestWSRestoreAndAbortErroredGame(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.registry.Register(buggyGame{})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "buggy")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, sess.Code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	// crash has mover take a cell that panics, and checks both players
+	// are told
+	crash := func(mover *websocket.Conn, cell int) {
+		t.Helper()
+		sendWS(ctx, mover, "action", makeAction(t, cell))
+		for _, conn := range []*websocket.Conn{alice, bob} {
+			sp := readState(t, ctx, conn)
+			if sp.SessionInfo.Status != session.StatusErrored || sp.SessionInfo.Error == "" {
+				t.Fatalf("expected an errored session with its error, got %+v", sp.SessionInfo)
+			}
+			readError(t, ctx, conn)
+		}
+	}
+	crash(alice, 8)
+
+	sendWS(ctx, bob, "restore", nil)
+	if msg := readError(t, ctx, bob); msg != "only the host can restore the game" {
+		t.Fatalf("unexpected error %q", msg)
+	}
+	sendWS(ctx, alice, "restore", nil)
+	sp := readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	if sp.SessionInfo.Status != session.StatusPlaying || sp.SessionInfo.Error != "" || len(sp.ValidActions) != 9 {
+		t.Fatalf("expected the match back before the crash, got %+v", sp)
+	}
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	crash(bob, 8)
+
+	sendWS(ctx, alice, "abort", nil)
+	sp = readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	if sp.SessionInfo.Status != session.StatusWaiting || sp.State != nil || len(sp.SessionInfo.Players) != 2 {
+		t.Fatalf("expected the session back in its lobby, got %+v", sp)
+	}
+	if matches, _ := env.mgr.ArchivedMatches(t.Context(), "buggy", 10); len(matches) != 0 {
+		t.Fatalf("expected the aborted match unrecorded, got %+v", matches)
+	}
+	sendWS(ctx, alice, "abort", nil)
+	if msg := readError(t, ctx, alice); msg != "game has not stopped after an error" {
+		t.Fatalf("unexpected error %q", msg)
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"crypto/hmac"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A guest cookie is sent with any request the browser makes to the site,
+// including ones another site's page triggers. So a request carrying one
+// must show it comes from the site's own pages: a state-changing request
+// by the CSRF token in its header, which only the site's pages can read
+// from the csrf cookie, and a WebSocket upgrade, which browsers send
+// without custom headers, by its Origin.
+const (
+	csrfCookie = "csrf"
+	csrfHeader = "X-CSRF-Token"
+)
+
+// csrfToken is the CSRF token of a guest, derived from its ID so the
+// server need not keep it.
+func (s *Server) csrfToken(guest string) string {
+	return s.signGuest("csrf/" + guest)
+}
+
+// setCSRFCookie gives the browser of guest its CSRF token, in a cookie the
+// site's pages read and copy into the header of requests they make.
+func (s *Server) setCSRFCookie(w http.ResponseWriter, r *http.Request, guest string) {
+	token := s.csrfToken(guest)
+	if c, err := r.Cookie(csrfCookie); err == nil && c.Value == token {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     s.prefix + "/",
+		MaxAge:   int(guestCookieAge / time.Second),
+		Secure:   s.secureCookies(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// secureCookies reports whether cookies set in answer to r are sent over
+// HTTPS only: when the site is served over HTTPS.
+func (s *Server) secureCookies(r *http.Request) bool {
+	return strings.HasPrefix(s.siteURL(r), "https:")
+}
+
+// checkCSRF refuses a request carrying a guest cookie that another site may
+// have made: a state-changing one without the guest's CSRF token, or a
+// WebSocket upgrade from another origin. Requests without the cookie, such
+// as those of bots and scripts, authenticate some other way or not at all,
+// and pass.
+func (s *Server) checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	guest, ok := s.guestID(r)
+	if !ok {
+		return true
+	}
+	switch {
+	case isWebSocketUpgrade(r):
+		if !s.sameOrigin(r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-origin WebSocket refused"})
+			return false
+		}
+	case r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions:
+		if !hmac.Equal([]byte(r.Header.Get(csrfHeader)), []byte(s.csrfToken(guest))) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "missing or invalid CSRF token"})
+			return false
+		}
+	}
+	return true
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// sameOrigin reports whether r comes from a page of the site: its Origin,
+// if it has one, names the host it was sent to or the public base URL.
+func (s *Server) sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	base, err := url.Parse(s.baseURL)
+	return err == nil && s.baseURL != "" && strings.EqualFold(u.Host, base.Host)
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// browserPost posts JSON as the site's pages do, with the CSRF token from
+// client's csrf cookie in its header.
+func browserPost(t *testing.T, client *http.Client, rawURL, body string) *http.Response {
+	t.Helper()
+	return browserDo(t, client, "POST", rawURL, body)
+}
+
+// browserDo sends a JSON request as the site's pages do, with the CSRF
+// token from client's csrf cookie in its header.
+func browserDo(t *testing.T, client *http.Client, method, rawURL, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, rawURL, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	u, _ := url.Parse(rawURL)
+	for _, c := range client.Jar.Cookies(u) {
+		if c.Name == csrfCookie {
+			req.Header.Set(csrfHeader, c.Value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, rawURL, err)
+	}
+	return resp
+}
+
+// guestBrowser is a browser that has loaded the lobby, holding a guest
+// cookie, and the guest ID it plays as.
+func guestBrowser(t *testing.T, ts *httptest.Server) (*http.Client, string) {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	resp, err := client.Get(ts.URL + "/api/guest")
+	if err != nil {
+		t.Fatalf("get guest: %v", err)
+	}
+	defer resp.Body.Close()
+	var g guestResponse
+	json.NewDecoder(resp.Body).Decode(&g)
+	return client, g.PlayerID
+}
+
+func TestCSRF(t *testing.T) {
+	env := setupTestEnv(t)
+	jar, _ := cookiejar.New(nil)
+	browser := &http.Client{Jar: jar}
+	resp, err := browser.Get(env.ts.URL + "/")
+	if err != nil {
+		t.Fatalf("load lobby: %v", err)
+	}
+	resp.Body.Close()
+	for _, c := range resp.Cookies() {
+		switch c.Name {
+		case guestCookie:
+			if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+				t.Errorf("expected an HttpOnly Lax guest cookie, got %+v", c)
+			}
+		case csrfCookie:
+			if c.HttpOnly || c.SameSite != http.SameSiteStrictMode {
+				t.Errorf("expected a readable Strict CSRF cookie, got %+v", c)
+			}
+		}
+	}
+
+	// A state-changing request with the guest cookie needs the token
+	body := `{"gameType":"tictactoe","playerId":"alice"}`
+	resp, err = browser.Post(env.ts.URL+"/api/sessions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a create without the token refused, got %d", resp.StatusCode)
+	}
+	resp = browserPost(t, browser, env.ts.URL+"/api/sessions", body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected a create with the token, got %d", resp.StatusCode)
+	}
+	// Reads, and clients without the cookie, need none
+	if resp, err = browser.Get(env.ts.URL + "/api/sessions"); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a read without the token, got %d", resp.StatusCode)
+	}
+	resp = postJSON(t, env.ts.URL+"/api/sessions", body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected a create without the cookie, got %d", resp.StatusCode)
+	}
+
+	// A WebSocket carries no header the page sets, so its origin is checked
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "bob")
+	dial := func(client *http.Client, origin string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		opts := &websocket.DialOptions{HTTPClient: client, HTTPHeader: http.Header{}}
+		if origin != "" {
+			opts.HTTPHeader.Set("Origin", origin)
+		}
+		conn, _, err := websocket.Dial(ctx, wsURL(env.ts, code), opts)
+		if err == nil {
+			conn.CloseNow()
+		}
+		return err
+	}
+	if err := dial(browser, "https://evil.example"); err == nil {
+		t.Error("expected a cross-origin upgrade with the guest cookie refused")
+	}
+	if err := dial(browser, env.ts.URL); err != nil {
+		t.Errorf("expected a same-origin upgrade accepted: %v", err)
+	}
+	if err := dial(http.DefaultClient, "https://evil.example"); err != nil {
+		t.Errorf("expected an upgrade without the cookie accepted: %v", err)
+	}
+}
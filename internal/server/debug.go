@@ -0,0 +1,228 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"games/internal/game"
+	"games/internal/session"
+)
+
+// devOnly serves h in dev mode and 404s otherwise. Debug endpoints show
+// full match states, secrets included, so they stay off in production.
+func (s *Server) devOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.static.dev {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}
+}
+
+type debugMove struct {
+	Seq      int         `json:"seq"`
+	PlayerID string      `json:"playerId"`
+	Action   game.Action `json:"action"`
+	At       time.Time   `json:"at"`
+	SentAt   time.Time   `json:"sentAt,omitzero"`
+	ActedAt  time.Time   `json:"actedAt,omitzero"`
+}
+
+// debugHistoryResponse lists a stored match's moves and whether replaying
+// them all arrives at the saved state.
+type debugHistoryResponse struct {
+	GameType   string      `json:"gameType"`
+	Moves      []debugMove `json:"moves"`
+	Consistent bool        `json:"consistent"`
+	Error      string      `json:"error,omitempty"` // why the replay failed
+}
+
+// diffOp is one JSON Patch (RFC 6902) operation, with the value replaced
+// or removed as old.
+type diffOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// rawJSON re-marshals a decoded value, keeping null as null.
+func rawJSON(v any) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+type debugDiffResponse struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Ops  []diffOp `json:"ops"`
+}
+
+// storedMatch loads the stored match of the request's session, writing
+// the error response if it cannot.
+func (s *Server) storedMatch(w http.ResponseWriter, r *http.Request) (*session.StoredMatch, bool) {
+	sm, err := s.manager.StoredMatch(r.Context(), r.PathValue("code"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	return sm, true
+}
+
+// stateAt returns the state at point: a number of moves, or "saved" for
+// the state last stored.
+func stateAt(sm *session.StoredMatch, point string) (json.RawMessage, error) {
+	if point == "saved" {
+		return sm.SavedState(), nil
+	}
+	n, err := strconv.Atoi(point)
+	if err != nil {
+		return nil, fmt.Errorf("want a move number or saved, got %q", point)
+	}
+	return sm.StateAt(n)
+}
+
+func (s *Server) handleDebugHistory(w http.ResponseWriter, r *http.Request) {
+	sm, ok := s.storedMatch(w, r)
+	if !ok {
+		return
+	}
+	resp := debugHistoryResponse{GameType: sm.GameType, Moves: make([]debugMove, len(sm.Moves))}
+	for i, mv := range sm.Moves {
+		resp.Moves[i] = debugMove{
+			Seq: i + 1, PlayerID: mv.PlayerID, Action: mv.Action,
+			At: mv.At, SentAt: mv.SentAt, ActedAt: mv.ActedAt,
+		}
+	}
+	final, err := sm.StateAt(len(sm.Moves))
+	if err != nil {
+		resp.Error = err.Error()
+	} else if ops, err := diffJSON(final, sm.SavedState()); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Consistent = len(ops) == 0
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleDebugState(w http.ResponseWriter, r *http.Request) {
+	sm, ok := s.storedMatch(w, r)
+	if !ok {
+		return
+	}
+	state, err := stateAt(sm, r.PathValue("point"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// handleDebugDiff diffs the states at two points of a stored match, by
+// default the start position and the saved state.
+func (s *Server) handleDebugDiff(w http.ResponseWriter, r *http.Request) {
+	sm, ok := s.storedMatch(w, r)
+	if !ok {
+		return
+	}
+	resp := debugDiffResponse{From: r.URL.Query().Get("from"), To: r.URL.Query().Get("to")}
+	if resp.From == "" {
+		resp.From = "0"
+	}
+	if resp.To == "" {
+		resp.To = "saved"
+	}
+	from, err := stateAt(sm, resp.From)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from: " + err.Error()})
+		return
+	}
+	to, err := stateAt(sm, resp.To)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to: " + err.Error()})
+		return
+	}
+	if resp.Ops, err = diffJSON(from, to); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// diffJSON returns the operations that turn document a into b. Objects
+// are compared key by key and arrays index by index; anything else that
+// differs is replaced whole.
+func diffJSON(a, b []byte) ([]diffOp, error) {
+	var va, vb any
+	for _, doc := range []struct {
+		data []byte
+		v    *any
+	}{{a, &va}, {b, &vb}} {
+		dec := json.NewDecoder(bytes.NewReader(doc.data))
+		dec.UseNumber()
+		if err := dec.Decode(doc.v); err != nil {
+			return nil, fmt.Errorf("diff: %w", err)
+		}
+	}
+	ops := []diffOp{}
+	diffValue(&ops, "", va, vb)
+	return ops, nil
+}
+
+func diffValue(ops *[]diffOp, path string, a, b any) {
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			keys := make([]string, 0, len(a)+len(b))
+			for k := range a {
+				keys = append(keys, k)
+			}
+			for k := range b {
+				if _, ok := a[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				p := path + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(k)
+				va, inA := a[k]
+				vb, inB := b[k]
+				switch {
+				case !inB:
+					*ops = append(*ops, diffOp{Op: "remove", Path: p, Old: rawJSON(va)})
+				case !inA:
+					*ops = append(*ops, diffOp{Op: "add", Path: p, Value: rawJSON(vb)})
+				default:
+					diffValue(ops, p, va, vb)
+				}
+			}
+			return
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			for i := range min(len(a), len(b)) {
+				diffValue(ops, path+"/"+strconv.Itoa(i), a[i], b[i])
+			}
+			for i := len(a); i < len(b); i++ {
+				*ops = append(*ops, diffOp{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: rawJSON(b[i])})
+			}
+			// Remove from the end, so each index is still valid when
+			// the patch is applied in order
+			for i := len(a) - 1; i >= len(b); i-- {
+				*ops = append(*ops, diffOp{Op: "remove", Path: path + "/" + strconv.Itoa(i), Old: rawJSON(a[i])})
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*ops = append(*ops, diffOp{Op: "replace", Path: path, Old: rawJSON(a), Value: rawJSON(b)})
+	}
+}
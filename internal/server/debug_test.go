@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"games/internal/session"
+)
+
+func getDebug(t *testing.T, url string, v any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: decode: %v", url, err)
+	}
+}
+
+func TestDebugDiff(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want string
+	}{
+		{`{"a":1}`, `{"a":1}`, `[]`},
+		{`{"a":1,"b":[1,2,3]}`, `{"a":2,"b":[1]}`, `[{"op":"replace","path":"/a","old":1,"value":2},{"op":"remove","path":"/b/2","old":3},{"op":"remove","path":"/b/1","old":2}]`},
+		{`{"x/y":null}`, `{"z":{"n":null}}`, `[{"op":"remove","path":"/x~1y","old":null},{"op":"add","path":"/z","value":{"n":null}}]`},
+		{`[1]`, `{"a":1}`, `[{"op":"replace","path":"","old":[1],"value":{"a":1}}]`},
+	} {
+		ops, err := diffJSON([]byte(tt.a), []byte(tt.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := json.Marshal(ops); string(got) != tt.want {
+			t.Errorf("diff %s %s = %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDebugEndpoints(t *testing.T) {
+	env := setupTestEnv(t)
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	sess, _ := env.mgr.Get(code)
+	sess.AddPlayer("bob")
+	if err := sess.Start(); err != nil {
+		t.Fatal(err)
+	}
+	env.srv.matchStarted(t.Context(), sess)
+	for range 3 {
+		for _, pid := range []string{"alice", "bob"} {
+			if actions := sess.Match.ValidActions(pid); len(actions) > 0 {
+				if err := env.srv.applyAction(t.Context(), sess, session.Move{PlayerID: pid, Action: actions[0], At: time.Now()}); err != nil {
+					t.Fatal(err)
+				}
+				break
+			}
+		}
+	}
+
+	url := env.ts.URL + "/api/debug/sessions/" + code
+	resp, _ := http.Get(url + "/history")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected debug endpoints off outside dev mode, got %d", resp.StatusCode)
+	}
+	env.srv.SetDevMode(true)
+
+	var history debugHistoryResponse
+	getDebug(t, url+"/history", &history)
+	if history.GameType != "tictactoe" || len(history.Moves) != 3 || history.Moves[2].Seq != 3 || !history.Consistent {
+		t.Fatalf("unexpected history %+v", history)
+	}
+
+	var state struct {
+		Board [9]int `json:"board"`
+	}
+	getDebug(t, url+"/states/1", &state)
+	marks := 0
+	for _, v := range state.Board {
+		if v != 0 {
+			marks++
+		}
+	}
+	if marks != 1 {
+		t.Fatalf("expected one mark after the first move, got %v", state.Board)
+	}
+
+	var diff debugDiffResponse
+	getDebug(t, url+"/diff?from=1&to=saved", &diff)
+	if diff.From != "1" || diff.To != "saved" || len(diff.Ops) != 2 {
+		t.Fatalf("expected the two marks placed since, got %+v", diff)
+	}
+
+	for _, path := range []string{"/states/9", "/states/latest", "/diff?from=x"} {
+		resp, _ := http.Get(url + path)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
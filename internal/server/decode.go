@@ -0,0 +1,54 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxBodyBytes caps the size of a REST request body. The largest body a
+// client sends is a replay transcript, which stays well below this.
+const maxBodyBytes = 1 << 20
+
+// maxWSMessageBytes caps the size of a single WebSocket message from a
+// client. Larger messages close the connection.
+const maxWSMessageBytes = 64 << 10
+
+// limitBody rejects requests that declare a body over maxBodyBytes and
+// cuts off the rest at that size.
+func limitBody(w http.ResponseWriter, r *http.Request) bool {
+	if r.ContentLength > maxBodyBytes {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "request body too large"})
+		return false
+	}
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	}
+	return true
+}
+
+// decodeJSON decodes a single JSON value from r into v, rejecting fields
+// v does not have and anything after the value.
+func decodeJSON(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fmt.Errorf("request body too large")
+		}
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// unmarshalStrict is decodeJSON for a message already in memory.
+func unmarshalStrict(data []byte, v any) error {
+	return decodeJSON(bytes.NewReader(data), v)
+}
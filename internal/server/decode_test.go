@@ -0,0 +1,118 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type body struct {
+		Name string `json:"name"`
+	}
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{`{"name":"alice"}`, true},
+		{` {"name":"alice"} ` + "\n", true},
+		{`{"name":"alice","admin":true}`, false},
+		{`{"name":"alice"}{"name":"bob"}`, false},
+		{`{"name":"alice"} trailing`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		var b body
+		err := decodeJSON(strings.NewReader(tt.in), &b)
+		if (err == nil) != tt.ok {
+			t.Errorf("decodeJSON(%q) = %v, want ok=%v", tt.in, err, tt.ok)
+		}
+	}
+}
+
+func TestRequestBodyTooLarge(t *testing.T) {
+	env := setupTestEnv(t)
+
+	big := `{"gameType":"tictactoe","playerId":"` + strings.Repeat("a", maxBodyBytes) + `"}`
+	resp, err := http.Post(env.ts.URL+"/api/sessions", "application/json", strings.NewReader(big))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", resp.StatusCode)
+	}
+
+	// Without a Content-Length the body is cut off while decoding.
+	req, _ := http.NewRequest(http.MethodPost, env.ts.URL+"/api/sessions", io.MultiReader(strings.NewReader(big)))
+	req.ContentLength = -1
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a chunked body, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateSessionUnknownField(t *testing.T) {
+	env := setupTestEnv(t)
+
+	body := `{"gameType":"tictactoe","playerId":"alice","host":true}`
+	resp, err := http.Post(env.ts.URL+"/api/sessions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestWSUnknownField(t *testing.T) {
+	env := setupTestEnv(t)
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	conn := wsConnect(t, env.ts, code, "alice")
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+	readState(t, ctx, conn)
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte(`{"type":"add_bot","payload":{"strategy":"random","seat":0}}`)); err != nil {
+		t.Fatalf("ws write: %v", err)
+	}
+	if msg := readError(t, ctx, conn); msg != "invalid add_bot payload" {
+		t.Fatalf("unexpected error %q", msg)
+	}
+}
+
+func TestWSMessageTooLarge(t *testing.T) {
+	env := setupTestEnv(t)
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	conn := wsConnect(t, env.ts, code, "alice")
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+	readState(t, ctx, conn)
+
+	big := `{"type":"add_bot","payload":{"strategy":"` + strings.Repeat("a", maxWSMessageBytes) + `"}}`
+	if err := conn.Write(ctx, websocket.MessageText, []byte(big)); err != nil {
+		t.Fatalf("ws write: %v", err)
+	}
+	for {
+		_, _, err := conn.Read(ctx)
+		if err == nil {
+			continue
+		}
+		var ce websocket.CloseError
+		if !errors.As(err, &ce) || ce.Code != websocket.StatusMessageTooBig {
+			t.Fatalf("expected the connection to close as too big, got %v", err)
+		}
+		return
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"games/internal/mail"
+	"games/internal/session"
+)
+
+// Verification mail goes to each player, and to each address, a few times
+// at once, then once every ten minutes.
+const (
+	verifyRate  = 1.0 / 600
+	verifyBurst = 3
+)
+
+// errNotYourEmail is the answer to anyone but a player's own browser
+// asking about the player's email.
+var errNotYourEmail = errors.New("only the player's own browser may see or change their email")
+
+type setEmailRequest struct {
+	Email string `json:"email"`
+}
+
+type emailPreferencesRequest struct {
+	NotifyTurns      bool `json:"notifyTurns"`
+	NotifyChallenges bool `json:"notifyChallenges"`
+}
+
+type emailContactResponse struct {
+	Email            string `json:"email"`
+	Verified         bool   `json:"verified"`
+	NotifyTurns      bool   `json:"notifyTurns"`
+	NotifyChallenges bool   `json:"notifyChallenges"`
+}
+
+// SetMailer enables email notifications, sending verification mail through
+// n.
+func (s *Server) SetMailer(n *mail.Notifier) {
+	s.mailer = n
+}
+
+// handleGetEmail returns a player's address and preferences, to their own
+// browser alone.
+func (s *Server) handleGetEmail(w http.ResponseWriter, r *http.Request) {
+	playerID := r.PathValue("id")
+	if !s.isGuest(r, playerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourEmail.Error()})
+		return
+	}
+	c, err := s.manager.EmailContact(r.Context(), playerID)
+	if errors.Is(err, session.ErrNoEmail) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, emailContactResponse{
+		Email:            c.Email,
+		Verified:         c.Verified,
+		NotifyTurns:      c.NotifyTurns,
+		NotifyChallenges: c.NotifyChallenges,
+	})
+}
+
+// handleSetEmail sets the address of the player whose browser calls, and
+// mails it a verification link, as often as the player and the address
+// are each allowed.
+func (s *Server) handleSetEmail(w http.ResponseWriter, r *http.Request) {
+	if s.mailer == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "email notifications are not configured"})
+		return
+	}
+	playerID := r.PathValue("id")
+	if !s.isGuest(r, playerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourEmail.Error()})
+		return
+	}
+	var req setEmailRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.verifyLimiter.Allow("player:"+playerID) || !s.verifyLimiter.Allow("address:"+strings.ToLower(strings.TrimSpace(req.Email))) {
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too many verification emails; try again later"})
+		return
+	}
+	token, err := s.manager.SetEmail(r.Context(), playerID, req.Email)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.mailer.SendVerification(playerID, req.Email, token); err != nil {
+		log.Printf("send verification to %s: %v", playerID, err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "could not send verification email"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "verification sent"})
+}
+
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.manager.VerifyEmail(r.Context(), r.URL.Query().Get("token")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "verified"})
+}
+
+// handleEmailPreferences chooses what the player whose browser calls is
+// emailed about.
+func (s *Server) handleEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	playerID := r.PathValue("id")
+	if !s.isGuest(r, playerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourEmail.Error()})
+		return
+	}
+	var req emailPreferencesRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	err := s.manager.SetEmailPreferences(r.Context(), playerID, req.NotifyTurns, req.NotifyChallenges)
+	if errors.Is(err, session.ErrNoEmail) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
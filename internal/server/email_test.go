@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"games/internal/mail"
+)
+
+type captureMailer struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (m *captureMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+func putJSON(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest("PUT", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT %s: %v", url, err)
+	}
+	return resp
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	env := setupTestEnv(t)
+	mailer := &captureMailer{}
+	env.srv.SetMailer(mail.NewNotifier(mailer, env.mgr, env.ts.URL))
+	alice, id := guestBrowser(t, env.ts)
+	emailURL := env.ts.URL + "/api/players/" + id + "/email"
+
+	bad := browserDo(t, alice, "PUT", emailURL, `{"email":"not an address"}`)
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", bad.StatusCode)
+	}
+
+	resp := browserDo(t, alice, "PUT", emailURL, `{"email":"alice@example.com"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	if len(mailer.bodies) != 1 {
+		t.Fatalf("expected a verification email, got %d", len(mailer.bodies))
+	}
+	link := regexp.MustCompile(`http://\S+/api/email/verify\?token=\S+`).FindString(mailer.bodies[0])
+	if link == "" {
+		t.Fatalf("no verification link in %q", mailer.bodies[0])
+	}
+	verify, err := http.Get(link)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	verify.Body.Close()
+	if verify.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", verify.StatusCode)
+	}
+
+	prefs := browserDo(t, alice, "PUT", emailURL+"/preferences", `{"notifyTurns":false,"notifyChallenges":true}`)
+	prefs.Body.Close()
+	if prefs.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", prefs.StatusCode)
+	}
+
+	get, err := alice.Get(emailURL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer get.Body.Close()
+	var c emailContactResponse
+	json.NewDecoder(get.Body).Decode(&c)
+	if c.Email != "alice@example.com" || !c.Verified || c.NotifyTurns || !c.NotifyChallenges {
+		t.Fatalf("unexpected contact: %+v", c)
+	}
+}
+
+func TestEmailOnlyForOwnBrowser(t *testing.T) {
+	env := setupTestEnv(t)
+	mailer := &captureMailer{}
+	env.srv.SetMailer(mail.NewNotifier(mailer, env.mgr, env.ts.URL))
+	alice, id := guestBrowser(t, env.ts)
+	emailURL := env.ts.URL + "/api/players/" + id + "/email"
+	resp := browserDo(t, alice, "PUT", emailURL, `{"email":"alice@example.com"}`)
+	resp.Body.Close()
+
+	mallory, _ := guestBrowser(t, env.ts)
+	for _, c := range []struct {
+		client       *http.Client
+		method, path string
+		body         string
+	}{
+		{http.DefaultClient, "GET", "", ""},
+		{mallory, "GET", "", ""},
+		{mallory, "PUT", "", `{"email":"mallory@example.com"}`},
+		{mallory, "PUT", "/preferences", `{"notifyTurns":false,"notifyChallenges":false}`},
+	} {
+		var resp *http.Response
+		if c.method == "GET" {
+			resp, _ = c.client.Get(emailURL + c.path)
+		} else {
+			resp = browserDo(t, c.client, c.method, emailURL+c.path, c.body)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden || strings.Contains(string(body), "alice@example.com") {
+			t.Fatalf("%s %s by another caller: expected 403 without the address, got %d %s", c.method, c.path, resp.StatusCode, body)
+		}
+	}
+	if len(mailer.bodies) != 1 {
+		t.Fatalf("expected only alice's verification sent, got %d", len(mailer.bodies))
+	}
+	// Names are not guests, and no browser holds their cookie
+	if resp := putJSON(t, env.ts.URL+"/api/players/bob/email", `{"email":"bob@example.com"}`); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a name's email refused, got %d", resp.StatusCode)
+	}
+}
+
+func TestEmailVerificationRateLimited(t *testing.T) {
+	env := setupTestEnv(t)
+	mailer := &captureMailer{}
+	env.srv.SetMailer(mail.NewNotifier(mailer, env.mgr, env.ts.URL))
+	alice, id := guestBrowser(t, env.ts)
+	for i := range verifyBurst {
+		resp := browserDo(t, alice, "PUT", env.ts.URL+"/api/players/"+id+"/email", fmt.Sprintf(`{"email":"alice%d@example.com"}`, i))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("send %d: expected 202, got %d", i, resp.StatusCode)
+		}
+	}
+	resp := browserDo(t, alice, "PUT", env.ts.URL+"/api/players/"+id+"/email", `{"email":"alice9@example.com"}`)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected the player limited, got %d", resp.StatusCode)
+	}
+
+	// One address, however many players ask for it
+	for i := range verifyBurst + 1 {
+		browser, id := guestBrowser(t, env.ts)
+		resp := browserDo(t, browser, "PUT", env.ts.URL+"/api/players/"+id+"/email", `{"email":"Victim@example.com"}`)
+		resp.Body.Close()
+		want := http.StatusAccepted
+		if i == verifyBurst {
+			want = http.StatusTooManyRequests
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("send %d to one address: expected %d, got %d", i, want, resp.StatusCode)
+		}
+	}
+	if len(mailer.bodies) != 2*verifyBurst {
+		t.Fatalf("expected %d verifications sent, got %d", 2*verifyBurst, len(mailer.bodies))
+	}
+}
+
+func TestSetEmailDisabledWithoutMailer(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, id := guestBrowser(t, env.ts)
+	resp := browserDo(t, alice, "PUT", env.ts.URL+"/api/players/"+id+"/email", `{"email":"alice@example.com"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import "games/internal/session"
+
+// emotePayload is a quick reaction, one of session.Emotes.
+type emotePayload struct {
+	Emote string `json:"emote"`
+}
+
+// emote shows a player's reaction to everyone in the session as an emote
+// message, apart from chat, so games can float it over the board.
+func (s *Server) emote(sess *session.Session, playerID string, send chan []byte, emote string) {
+	e, err := sess.AddEmote(playerID, emote)
+	if err != nil {
+		sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+		return
+	}
+	msg := encodeWSMsg("emote", e)
+	for _, c := range watcherSends(sess) {
+		sendEncoded(c, msg)
+	}
+}
+
+// sendRecentEmotes replays the emotes of the last session.EmoteTTL to a
+// connection that just joined, so a player reconnecting mid-game sees
+// what was said while they were away.
+func sendRecentEmotes(sess *session.Session, send chan []byte) {
+	for _, e := range sess.RecentEmotes() {
+		sendWSMsg(send, "emote", e)
+	}
+}
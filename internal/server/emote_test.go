@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"games/internal/session"
+
+	"nhooyr.io/websocket"
+)
+
+func TestEmote(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	code := sess.Code
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	wall := joinWith(t, env, code, joinPayload{PlayerID: "wall", Spectate: true})
+	defer wall.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, wall)
+
+	sendWS(ctx, alice, "emote", emotePayload{Emote: "👍"})
+	for _, conn := range []*websocket.Conn{alice, bob, wall} {
+		msg := wsRead(ctx, t, conn)
+		var e session.Emote
+		json.Unmarshal(msg.Payload, &e)
+		if msg.Type != "emote" || e.PlayerID != "alice" || e.Emote != "👍" || e.At.IsZero() {
+			t.Fatalf("expected alice's emote, got %s %s", msg.Type, msg.Payload)
+		}
+	}
+
+	sendWS(ctx, alice, "emote", emotePayload{Emote: "🎉"})
+	if msg := wsRead(ctx, t, alice); msg.Type != "error" {
+		t.Fatalf("expected an emote outside the set refused, got %s", msg.Type)
+	}
+	for range session.EmoteBurst - 1 {
+		sendWS(ctx, alice, "emote", emotePayload{Emote: "GG"})
+		wsRead(ctx, t, alice)
+	}
+	sendWS(ctx, alice, "emote", emotePayload{Emote: "GG"})
+	if msg := wsRead(ctx, t, alice); msg.Type != "error" {
+		t.Fatalf("expected emotes over the rate refused, got %s", msg.Type)
+	}
+
+	// bob reconnects and is shown what they missed after the state
+	bob.Close(websocket.StatusNormalClosure, "")
+	bob = wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, bob)
+	var replayed []string
+	for range session.EmoteBurst {
+		msg := wsRead(ctx, t, bob)
+		var e session.Emote
+		json.Unmarshal(msg.Payload, &e)
+		replayed = append(replayed, msg.Type+" "+e.Emote)
+	}
+	if replayed[0] != "emote 👍" || replayed[2] != "emote GG" {
+		t.Fatalf("unexpected replay %v", replayed)
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"games/internal/session"
+)
+
+const (
+	defaultExhibitionDelay = 500 * time.Millisecond
+	maxExhibitionDelay     = 10 * time.Second
+)
+
+type createExhibitionRequest struct {
+	GameType    string   `json:"gameType"`
+	Bots        []string `json:"bots"`                  // strategy name per seat
+	MoveDelayMs *int     `json:"moveDelayMs,omitempty"` // pause before each move; default 500
+}
+
+func (s *Server) handleCreateExhibition(w http.ResponseWriter, r *http.Request) {
+	var req createExhibitionRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	req.GameType = strings.TrimSpace(req.GameType)
+	if req.GameType == "" || len(req.Bots) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "gameType and bots required"})
+		return
+	}
+	delay := defaultExhibitionDelay
+	if req.MoveDelayMs != nil {
+		delay = time.Duration(*req.MoveDelayMs) * time.Millisecond
+		if delay < 0 || delay > maxExhibitionDelay {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "moveDelayMs must be between 0 and 10000"})
+			return
+		}
+	}
+
+	sess, err := s.manager.CreateExhibition(r.Context(), req.GameType, req.Bots)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	s.matchStarted(r.Context(), sess)
+	go s.runExhibition(context.Background(), sess, delay)
+
+	writeJSON(w, http.StatusCreated, createSessionResponse{Code: sess.Code})
+}
+
+// runExhibition plays a bot-only match to completion at a watchable pace
+// and archives the results.
+func (s *Server) runExhibition(ctx context.Context, sess *session.Session, delay time.Duration) {
+	defer s.recoverSession(sess)
+	s.playBots(ctx, sess, delay)
+
+	sess.RLock()
+	over := sess.Status != session.StatusErrored && sess.Match.IsOver()
+	sess.RUnlock()
+	if !over {
+		log.Printf("exhibition %s stopped before the match ended", sess.Code)
+		return
+	}
+	if err := s.manager.ArchiveExhibition(ctx, sess); err != nil {
+		log.Printf("archive exhibition %s: %v", sess.Code, err)
+	}
+	// The standings change after the match finished event went out
+	s.cache.invalidate(gameCacheTag(sess.GameType))
+}
+
+func (s *Server) handleBotStandings(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := s.registry.Get(name); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "game not found"})
+		return
+	}
+	standings, err := s.manager.ExhibitionStandings(r.Context(), name)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, standings)
+}
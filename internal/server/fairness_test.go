@@ -0,0 +1,41 @@
+package server
+
+import (
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSFairnessCommitmentRevealedAtEnd(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	conn := wsConnect(t, env.ts, code, "alice")
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	if sp := readState(t, ctx, conn); sp.Fairness != nil {
+		t.Fatalf("expected no commitment before the match, got %+v", sp.Fairness)
+	}
+	sendWS(ctx, conn, "add_bot", addBotPayload{Strategy: "random"})
+	readState(t, ctx, conn)
+	sendWS(ctx, conn, "start", nil)
+
+	sp := readState(t, ctx, conn)
+	if sp.Fairness == nil || sp.Fairness.Hash == "" {
+		t.Fatal("expected a commitment once the match starts")
+	}
+	hash := sp.Fairness.Hash
+	for len(sp.Results) == 0 {
+		if sp.Fairness.Seed != 0 || sp.Fairness.Salt != "" || sp.Fairness.Hash != hash {
+			t.Fatalf("commitment changed or leaked mid-game: %+v", sp.Fairness)
+		}
+		if len(sp.ValidActions) > 0 {
+			sendWS(ctx, conn, "action", actionPayload{Action: sp.ValidActions[0]})
+		}
+		sp = readState(t, ctx, conn)
+	}
+	if sp.Fairness.Hash != hash || !sp.Fairness.Verify() {
+		t.Fatalf("expected the revealed seed to verify, got %+v", sp.Fairness)
+	}
+}
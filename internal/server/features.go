@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+type featureRequest struct {
+	Percent *int `json:"percent"`
+}
+
+// featureOverrideRequest turns a feature on or off for one session; a
+// null enabled hands it back to the rollout.
+type featureOverrideRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func (s *Server) handleAdminFeatures(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	writeJSON(w, http.StatusOK, s.manager.Features())
+}
+
+// handleAdminSetFeature adds a feature or changes the share of sessions
+// it is rolled out to.
+func (s *Server) handleAdminSetFeature(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	var req featureRequest
+	if err := decodeJSON(r.Body, &req); err != nil || req.Percent == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "percent required"})
+		return
+	}
+	f := session.Feature{Name: r.PathValue("name"), Percent: *req.Percent}
+	entry.Detail = fmt.Sprintf("%s at %d%%", f.Name, f.Percent)
+	if err := s.manager.SetFeature(f); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (s *Server) handleAdminRemoveFeature(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	entry.Detail = r.PathValue("name")
+	if !s.manager.RemoveFeature(r.PathValue("name")) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "feature not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+func (s *Server) handleAdminSessionFeatures(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	s.writeSessionFeatures(w, entry.SessionCode)
+}
+
+// handleAdminOverrideFeature turns a feature on or off for one session
+// whatever its rollout, for trying it out or backing it out of a session
+// that misbehaves.
+func (s *Server) handleAdminOverrideFeature(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	var req featureOverrideRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid override"})
+		return
+	}
+	sess, ok := s.manager.Get(entry.SessionCode)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	name := r.PathValue("name")
+	entry.Detail = name + " back to its rollout"
+	if req.Enabled != nil {
+		entry.Detail = fmt.Sprintf("%s enabled=%t", name, *req.Enabled)
+	}
+	if err := s.manager.OverrideFeature(sess, name, req.Enabled); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	s.writeSessionFeatures(w, entry.SessionCode)
+}
+
+// handleDebugFeatures lists the features a session has.
+func (s *Server) handleDebugFeatures(w http.ResponseWriter, r *http.Request) {
+	s.writeSessionFeatures(w, r.PathValue("code"))
+}
+
+func (s *Server) writeSessionFeatures(w http.ResponseWriter, code string) {
+	sess, ok := s.manager.Get(code)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.manager.SessionFeatures(sess))
+}
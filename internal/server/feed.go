@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"games/internal/event"
+	"games/internal/session"
+)
+
+const (
+	feedRate      = 5  // events per second per feed connection
+	feedBurst     = 20 // events delivered at once before rate limiting applies
+	feedHeartbeat = 30 * time.Second
+)
+
+// feedFilter selects the events a lobby feed client wants, from the
+// gameType and types (comma-separated) query parameters. The player
+// parameter additionally delivers events addressed to that player, such
+// as challenges, to the player's own browser.
+type feedFilter struct {
+	gameType string
+	types    map[string]bool
+	player   string
+}
+
+func parseFeedFilter(r *http.Request) feedFilter {
+	f := feedFilter{
+		gameType: r.URL.Query().Get("gameType"),
+		player:   r.URL.Query().Get("player"),
+	}
+	if types := r.URL.Query().Get("types"); types != "" {
+		f.types = make(map[string]bool)
+		for _, t := range strings.Split(types, ",") {
+			f.types[strings.TrimSpace(t)] = true
+		}
+	}
+	return f
+}
+
+func (f feedFilter) match(e event.Event) bool {
+	if len(e.Recipients) > 0 {
+		if f.player == "" || !slices.Contains(e.Recipients, f.player) {
+			return false
+		}
+	} else if e.Private {
+		return false
+	}
+	if f.gameType != "" && e.GameType != f.gameType {
+		return false
+	}
+	return f.types == nil || f.types[e.Type]
+}
+
+type feedSnapshot struct {
+	Sessions []session.Info `json:"sessions"`
+	Events   []event.Event  `json:"events"`
+}
+
+// handleFeedSnapshot returns the public sessions and recent events a lobby
+// needs on page load, before it subscribes to the live feed.
+func (s *Server) handleFeedSnapshot(w http.ResponseWriter, r *http.Request) {
+	f := parseFeedFilter(r)
+	if f.player != "" && !s.isGuest(r, f.player) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	snap := feedSnapshot{Sessions: []session.Info{}, Events: []event.Event{}}
+	for _, info := range s.manager.List() {
+		if info.Private || info.Status == session.StatusFinished || info.Status == session.StatusErrored {
+			continue
+		}
+		if f.gameType != "" && info.GameType != f.gameType {
+			continue
+		}
+		info.Reservations = nil // who was invited is for the session's players
+		snap.Sessions = append(snap.Sessions, info)
+	}
+	for _, e := range s.manager.Events().Recent() {
+		if f.match(e) {
+			snap.Events = append(snap.Events, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+// handleFeed streams public lobby events as server-sent events. Each
+// connection is rate limited; events beyond the limit are dropped, so
+// clients should refresh from the snapshot if they need an exact view.
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	f := parseFeedFilter(r)
+	if f.player != "" && !s.isGuest(r, f.player) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	events, unsubscribe := s.manager.Events().Subscribe(64)
+	defer unsubscribe()
+	limiter := newRateLimiter(feedRate, feedBurst)
+	heartbeat := time.NewTicker(feedHeartbeat)
+	defer heartbeat.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if !f.match(e) || !limiter.Allow("") {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			flusher.Flush()
+		}
+	}
+}
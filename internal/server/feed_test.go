@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"games/internal/event"
+)
+
+func TestFeedSnapshotHidesPrivate(t *testing.T) {
+	env := setupTestEnv(t)
+
+	public := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	resp, err := http.Post(env.ts.URL+"/api/sessions", "application/json",
+		strings.NewReader(`{"gameType":"tictactoe","playerId":"bob","private":true}`))
+	if err != nil {
+		t.Fatalf("create private: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(env.ts.URL + "/api/feed/snapshot")
+	if err != nil {
+		t.Fatalf("GET snapshot: %v", err)
+	}
+	defer resp.Body.Close()
+	var snap feedSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(snap.Sessions) != 1 || snap.Sessions[0].Code != public {
+		t.Fatalf("expected only the public session, got %+v", snap.Sessions)
+	}
+	if len(snap.Events) != 1 || snap.Events[0].Type != event.SessionCreated {
+		t.Fatalf("expected one public session_created event, got %+v", snap.Events)
+	}
+}
+
+func TestFeedStreamsFilteredEvents(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", env.ts.URL+"/api/feed?types=match_started", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET feed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	// session_created is filtered out; match_started comes through
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	sess, _ := env.mgr.Get(code)
+	sess.AddPlayer("bob")
+	startResp := startViaAPI(t, env, code)
+	startResp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	var eventLine, dataLine string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			eventLine = line
+		}
+		if strings.HasPrefix(line, "data: ") {
+			dataLine = line
+			break
+		}
+	}
+	if eventLine != "event: match_started" {
+		t.Fatalf("expected match_started event, got %q", eventLine)
+	}
+	var e event.Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &e); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	if e.SessionCode != code || len(e.Players) != 2 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
+
+func TestFeedFilterMatch(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/api/feed?gameType=tictactoe&types=match_started,match_finished", nil)
+	f := parseFeedFilter(req)
+
+	cases := []struct {
+		e    event.Event
+		want bool
+	}{
+		{event.Event{Type: event.MatchStarted, GameType: "tictactoe"}, true},
+		{event.Event{Type: event.MatchFinished, GameType: "tictactoe"}, true},
+		{event.Event{Type: event.SessionCreated, GameType: "tictactoe"}, false},
+		{event.Event{Type: event.MatchStarted, GameType: "chess"}, false},
+		{event.Event{Type: event.MatchStarted, GameType: "tictactoe", Private: true}, false},
+	}
+	for _, c := range cases {
+		if got := f.match(c.e); got != c.want {
+			t.Fatalf("match(%+v) = %v, want %v", c.e, got, c.want)
+		}
+	}
+}
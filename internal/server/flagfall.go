@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"games/internal/event"
+	"games/internal/session"
+)
+
+// ClockLoop ends timed matches whose player to move has run out of time,
+// checking every interval until ctx is done. The interval bounds how
+// long past a flag fall a match may run.
+func (s *Server) ClockLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.flagFalls(ctx, time.Now())
+	}
+}
+
+// flagFalls finishes every clocked match whose running player's time is
+// gone at now, then saves, broadcasts and announces it like a match
+// ended by a move.
+func (s *Server) flagFalls(ctx context.Context, now time.Time) {
+	for _, sess := range s.manager.Clocked() {
+		var flagged bool
+		var finished *event.Event
+		sess.Lock()
+		err := session.Protect(func() error {
+			if flagged = sess.FlagFallLocked(now); flagged {
+				sess.LastActivity = now
+				finished = finishIfOverLocked(sess, now)
+			}
+			return nil
+		})
+		sess.Unlock()
+		if s.failIfPanicked(ctx, sess, err) || !flagged {
+			continue
+		}
+		s.saveMatch(ctx, sess, finished)
+		s.broadcastState(sess)
+		if finished != nil {
+			s.manager.Events().Publish(*finished)
+		}
+	}
+}
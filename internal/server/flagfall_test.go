@@ -0,0 +1,62 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/event"
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/session"
+)
+
+func TestFlagFall(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+	env.srv.registry.Register(tictactoe.Blitz{})
+	finished, _ := env.mgr.Events().Subscribe(16)
+
+	sess, err := env.mgr.CreateWithOptions(t.Context(), "blitz", map[string]int{"clock": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, sess.Code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	env.srv.flagFalls(ctx, time.Now().Add(4*time.Second))
+	if sess.Info().Status != session.StatusPlaying {
+		t.Fatal("expected the match to go on while bob has time")
+	}
+	env.srv.flagFalls(ctx, time.Now().Add(6*time.Second))
+	sp := readState(t, ctx, bob)
+	if sp.SessionInfo.Status != session.StatusFinished || len(sp.Results) != 2 {
+		t.Fatalf("expected the match to finish on bob's flag, got %+v", sp.SessionInfo)
+	}
+	for _, r := range sp.Results {
+		if want := map[string]game.Outcome{"alice": game.OutcomeWin, "bob": game.OutcomeTimeout}[r.PlayerID]; r.Outcome != want {
+			t.Fatalf("expected %s to have outcome %s, got %+v", r.PlayerID, want, sp.Results)
+		}
+	}
+	for e := range finished {
+		if e.Type == event.MatchFinished {
+			break
+		}
+	}
+	if len(env.mgr.Clocked()) != 0 {
+		t.Fatal("expected a finished match to leave the clock watch")
+	}
+}
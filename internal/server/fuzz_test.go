@@ -0,0 +1,81 @@
+package server
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+func FuzzDecodeJoin(f *testing.F) {
+	for _, seed := range []string{
+		`{"type":"join","payload":{"playerId":"alice"}}`,
+		`{"type":"join","payload":{"playerId":"watcher","spectate":true}}`,
+		`{"type":"join","payload":{"handoff":"123456"}}`,
+		`{"type":"join","payload":null}`,
+		`{"type":"action"}`,
+		`[]`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		join, err := decodeJoin(data)
+		if err == nil && join.PlayerID == "" && join.Handoff == "" {
+			t.Fatalf("accepted a join naming no player: %q", data)
+		}
+	})
+}
+
+// FuzzHandleMessage feeds arbitrary messages from the host of a started
+// tic-tac-toe match through the same decoding and dispatch as the
+// WebSocket reader loop.
+func FuzzHandleMessage(f *testing.F) {
+	for _, seed := range []string{
+		`{"type":"action","payload":{"action":{"type":"move","payload":{"cell":4}}}}`,
+		`{"type":"action","payload":{"action":{"type":"move","payload":{"cell":-1}}}}`,
+		`{"type":"action","payload":{"action":null}}`,
+		`{"type":"start"}`,
+		`{"type":"next_game"}`,
+		`{"type":"rematch"}`,
+		`{"type":"add_bot","payload":{"strategy":"random"}}`,
+		`{"type":"choose_seat","payload":{"seat":1}}`,
+		`{"type":"choose_seat","payload":{"seat":-1,"playerId":"bob"}}`,
+		`{"type":"shuffle_seats"}`,
+		`{"type":"handoff"}`,
+		`{"type":"turn_order","payload":{"turnOrder":"random"}}`,
+		`{"type":"reserve","payload":{"playerIds":["carol",""]}}`,
+		`{"type":"vote_thresholds","payload":{"skip":0,"remove":99}}`,
+		`{"type":"vote","payload":{"kind":"skip","playerId":"bob"}}`,
+		`{"type":"vote","payload":{"kind":"remove","playerId":"alice"}}`,
+		`{"type":"action","payload":"not an object"}`,
+	} {
+		f.Add([]byte(seed), false)
+		f.Add([]byte(seed), true)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, started bool) {
+		reg := game.NewRegistry()
+		reg.Register(tictactoe.TicTacToe{})
+		for _, strategy := range tictactoe.Strategies() {
+			reg.RegisterStrategy("tictactoe", strategy)
+		}
+		srv := New(reg, session.NewManager(reg, storage.NewMemory()), fstest.MapFS{})
+		sess, _ := srv.manager.Create(t.Context(), "tictactoe")
+		sess.AddPlayer("alice")
+		sess.AddPlayer("bob")
+		if started {
+			sess.Start()
+			srv.matchStarted(t.Context(), sess)
+		}
+		send := make(chan []byte, 64)
+		sess.ConnectPlayer("alice", send)
+
+		msg, err := decodeWSMessage(data)
+		if err != nil {
+			return
+		}
+		srv.handleMessage(t.Context(), sess, "alice", send, msg)
+	})
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"games/internal/game"
+	"games/internal/graphql"
+	"games/internal/session"
+)
+
+// graphqlRequest is a POSTed query. Extensions, such as persisted query
+// hashes, are accepted and ignored.
+type graphqlRequest struct {
+	graphql.Request
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+}
+
+// graphqlPlayer is the player a query asks about by ID.
+type graphqlPlayer struct {
+	ID string `json:"id"`
+}
+
+// handleGraphQL answers read-only GraphQL queries over the games,
+// sessions, players, stats and match history the REST routes serve, so a
+// client can fetch what it needs in one round trip. Queries come as a
+// POSTed JSON body or in the query, operationName and variables
+// parameters of a GET. Changes stay on REST and the WebSocket.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "variables must be a JSON object"})
+				return
+			}
+		}
+	} else if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if req.Query == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "query is required"})
+		return
+	}
+	resp := s.graphql.Execute(r.Context(), req.Request)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest // the query could not run at all
+	}
+	writeJSON(w, status, resp)
+}
+
+// newGraphQLSchema returns the schema handleGraphQL queries. Each field
+// reads what its REST route returns, with the same limits; only public
+// sessions are listed, and a session's invitations are left out as in the
+// lobby feed.
+func (s *Server) newGraphQLSchema() *graphql.Schema {
+	schema := graphql.NewSchema(&graphql.Object{Name: "Query", Fields: map[string]graphql.Resolver{
+		"games": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+			return s.registry.List(), nil
+		},
+		"game": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			name, err := graphql.StringArg(args, "name")
+			if err != nil {
+				return nil, err
+			}
+			g, ok := s.registry.Get(name)
+			if !ok {
+				return nil, nil
+			}
+			return g.Info(), nil
+		},
+		"sessions": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			q := make(url.Values)
+			for name, v := range args {
+				if v != nil {
+					q.Set(name, fmt.Sprint(v))
+				}
+			}
+			f, err := parseSessionFilter(q)
+			if err != nil {
+				return nil, err
+			}
+			f.PublicOnly = true
+			return s.manager.ListSessions(ctx, f)
+		},
+		"session": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			code, err := graphql.StringArg(args, "code")
+			if err != nil {
+				return nil, err
+			}
+			sess, ok := s.manager.Get(code)
+			if !ok {
+				return nil, nil
+			}
+			info := sess.Info()
+			info.Reservations = nil
+			return info, nil
+		},
+		"player": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			id, err := graphql.StringArg(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			if id == "" {
+				return nil, fmt.Errorf("id is required")
+			}
+			return graphqlPlayer{ID: id}, nil
+		},
+	}})
+
+	schema.Extend(game.GameInfo{}, &graphql.Object{Name: "Game", Fields: map[string]graphql.Resolver{
+		"bots": func(ctx context.Context, src any, _ map[string]any) (any, error) {
+			return s.registry.Strategies(src.(game.GameInfo).Name), nil
+		},
+		"botStandings": func(ctx context.Context, src any, _ map[string]any) (any, error) {
+			return s.manager.ExhibitionStandings(ctx, src.(game.GameInfo).Name)
+		},
+		"stats": func(ctx context.Context, src any, _ map[string]any) (any, error) {
+			return s.manager.GameStats(ctx, src.(game.GameInfo).Name)
+		},
+		"matches": func(ctx context.Context, src any, args map[string]any) (any, error) {
+			limit, err := graphql.IntArg(args, "limit", defaultArchivedMatches)
+			if err != nil || limit < 1 || limit > maxArchivedMatches {
+				return nil, fmt.Errorf("limit must be between 1 and %d", maxArchivedMatches)
+			}
+			return s.manager.ArchivedMatches(ctx, src.(game.GameInfo).Name, limit)
+		},
+	}})
+
+	schema.Extend(session.Info{}, &graphql.Object{Name: "Session", Fields: map[string]graphql.Resolver{
+		"scoreboard": func(ctx context.Context, src any, _ map[string]any) (any, error) {
+			sess, ok := s.manager.Get(src.(session.Info).Code)
+			if !ok {
+				return nil, nil
+			}
+			return sess.Scoreboard(), nil
+		},
+	}})
+	schema.Extend(session.Listing{}, &graphql.Object{Name: "SessionListing"})
+
+	schema.Extend(graphqlPlayer{}, &graphql.Object{Name: "Player", Fields: map[string]graphql.Resolver{
+		"friends": func(ctx context.Context, src any, _ map[string]any) (any, error) {
+			return s.manager.Friends(ctx, src.(graphqlPlayer).ID)
+		},
+		"recentOpponents": func(ctx context.Context, src any, args map[string]any) (any, error) {
+			limit, err := graphql.IntArg(args, "limit", defaultRecentOpponents)
+			if err != nil || limit < 1 || limit > maxRecentOpponents {
+				return nil, fmt.Errorf("limit must be between 1 and %d", maxRecentOpponents)
+			}
+			return s.manager.RecentOpponents(ctx, src.(graphqlPlayer).ID, limit)
+		},
+	}})
+	return schema
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// graphqlQuery POSTs a query and decodes the response.
+func graphqlQuery(t *testing.T, env *testEnv, body string) (int, map[string]any) {
+	t.Helper()
+	resp := postJSON(t, env.ts.URL+"/api/graphql", body)
+	defer resp.Body.Close()
+	var out map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestGraphQLLobby(t *testing.T) {
+	env := setupTestEnv(t)
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	if err := env.mgr.RequestFriend(t.Context(), "alice", "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	query := `query Lobby($game: String!) {
+		game(name: $game) { name minPlayers bots { name } stats { matches } matches(limit: 5) { sessionCode durationMs } }
+		sessions(game: $game, status: waiting) { code players }
+		session(code: "` + code + `") { hostId status scoreboard { __typename } }
+		player(id: "alice") { friends { outgoing } recentOpponents { playerId } }
+	}`
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": map[string]any{"game": "tictactoe"}})
+	status, out := graphqlQuery(t, env, string(body))
+	if status != http.StatusOK || out["errors"] != nil {
+		t.Fatalf("expected 200 without errors, got %d %v", status, out)
+	}
+	data := out["data"].(map[string]any)
+
+	g := data["game"].(map[string]any)
+	if g["name"] != "tictactoe" || g["minPlayers"] != 2.0 || len(g["bots"].([]any)) == 0 {
+		t.Errorf("unexpected game %v", g)
+	}
+	if g["stats"].(map[string]any)["matches"] != 0.0 || len(g["matches"].([]any)) != 0 {
+		t.Errorf("expected no history, got %v", g)
+	}
+	sessions := data["sessions"].([]any)
+	if len(sessions) != 1 || sessions[0].(map[string]any)["code"] != code {
+		t.Errorf("expected the waiting session listed, got %v", sessions)
+	}
+	if sess := data["session"].(map[string]any); sess["hostId"] != "alice" || sess["status"] != "waiting" {
+		t.Errorf("unexpected session %v", sess)
+	}
+	player := data["player"].(map[string]any)
+	if outgoing := player["friends"].(map[string]any)["outgoing"].([]any); len(outgoing) != 1 || outgoing[0] != "bob" {
+		t.Errorf("expected alice's request to bob, got %v", player)
+	}
+}
+
+func TestGraphQLGetAndErrors(t *testing.T) {
+	env := setupTestEnv(t)
+
+	resp, err := http.Get(env.ts.URL + "/api/graphql?query=" + url.QueryEscape(`{ game(name: "tictactoe") { maxPlayers name } }`))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	// Fields come back in the order the query selected them
+	var raw json.RawMessage
+	json.NewDecoder(resp.Body).Decode(&raw)
+	if want := `{"data":{"game":{"maxPlayers":2,"name":"tictactoe"}}}`; strings.TrimSpace(string(raw)) != want {
+		t.Errorf("got %s, want %s", raw, want)
+	}
+
+	for _, tc := range []struct {
+		name, body string
+		status     int
+	}{
+		{"mutation", `{"query":"mutation { games { name } }"}`, http.StatusBadRequest},
+		{"syntax", `{"query":"{ games {"}`, http.StatusBadRequest},
+		{"bad limit", `{"query":"{ sessions(limit: 500) { code } }"}`, http.StatusOK},
+		{"no query", `{}`, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, out := graphqlQuery(t, env, tc.body)
+			if status != tc.status {
+				t.Errorf("expected %d, got %d", tc.status, status)
+			}
+			if out["errors"] == nil && out["error"] == nil {
+				t.Errorf("expected an error, got %v", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// GuestPrefix starts the player IDs of guests, which only the browser
+// holding the guest's cookie may play as.
+const GuestPrefix = "guest-"
+
+const (
+	guestCookie    = "guest"
+	guestCookieAge = 365 * 24 * time.Hour
+)
+
+var guestEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newGuestKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// SetGuestKey sets the key guest cookies are signed with. Without one a
+// random key is used, and guests get new identities when the server
+// restarts.
+func (s *Server) SetGuestKey(key []byte) {
+	s.guestKey = key
+}
+
+func newGuestID() string {
+	b := make([]byte, 10)
+	rand.Read(b)
+	return GuestPrefix + strings.ToLower(guestEncoding.EncodeToString(b))
+}
+
+func (s *Server) signGuest(id string) string {
+	mac := hmac.New(sha256.New, s.guestKey)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// guestID returns the player ID in r's guest cookie, if it carries one
+// this server signed.
+func (s *Server) guestID(r *http.Request) (string, bool) {
+	for _, c := range r.CookiesNamed(guestCookie) {
+		id, sig, ok := strings.Cut(c.Value, ".")
+		if ok && strings.HasPrefix(id, GuestPrefix) && hmac.Equal([]byte(sig), []byte(s.signGuest(id))) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// isGuest reports whether r comes from the browser holding playerID's
+// guest cookie, the only caller trusted with what is private to a player.
+func (s *Server) isGuest(r *http.Request, playerID string) bool {
+	guest, ok := s.guestID(r)
+	return ok && guest == playerID
+}
+
+// ensureGuest returns the guest ID of r's browser, giving it a new one in
+// a cookie if it has none, and its CSRF token if it lacks it. The guest
+// cookie is Lax, so a share link followed from elsewhere still seats the
+// guest, and pages cannot read it.
+func (s *Server) ensureGuest(w http.ResponseWriter, r *http.Request) string {
+	id, ok := s.guestID(r)
+	if !ok {
+		id = newGuestID()
+		http.SetCookie(w, &http.Cookie{
+			Name:     guestCookie,
+			Value:    id + "." + s.signGuest(id),
+			Path:     s.prefix + "/",
+			MaxAge:   int(guestCookieAge / time.Second),
+			HttpOnly: true,
+			Secure:   s.secureCookies(r),
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	s.setCSRFCookie(w, r, id)
+	return id
+}
+
+// withGuest gives browsers a guest cookie on the first page they load.
+func (s *Server) withGuest(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ext := path.Ext(r.URL.Path); ext == "" || ext == ".html" {
+			s.ensureGuest(w, r)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+type guestResponse struct {
+	PlayerID string `json:"playerId"`
+}
+
+// handleGuest returns the caller's guest ID, issuing the cookie if it
+// has none, for pages that address the API as the guest.
+func (s *Server) handleGuest(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, guestResponse{PlayerID: s.ensureGuest(w, r)})
+}
+
+// seatAs returns the player ID a browser sitting down as requested takes,
+// and the name it is shown by. A guest takes a seat as its guest ID, shown
+// by the requested name, unless requested names a seat the session holds
+// already, such as one a challenge seated by name; anyone else sits as
+// requested. Only a guest's own browser may sit as its ID.
+func (s *Server) seatAs(r *http.Request, requested string, holds func(string) bool) (playerID, name string, err error) {
+	guest, ok := s.guestID(r)
+	switch {
+	case ok && requested == guest:
+		return guest, "", nil
+	case strings.HasPrefix(requested, GuestPrefix):
+		return "", "", errGuestReserved
+	case ok && !holds(requested):
+		return guest, requested, nil
+	}
+	return requested, "", nil
+}
+
+var errGuestReserved = errors.New("player IDs starting with " + GuestPrefix + " are reserved for guests")
+
+// errNotYourBrowser is the answer to a request acting for a player from
+// anything but the browser holding the player's guest cookie.
+var errNotYourBrowser = errors.New("only the player's own browser may act for them")
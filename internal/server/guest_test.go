@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestGuestIdentity(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	// browser loads the lobby, getting a guest cookie, and learns its ID
+	browser := func() (*http.Client, string) {
+		t.Helper()
+		jar, _ := cookiejar.New(nil)
+		client := &http.Client{Jar: jar}
+		resp, err := client.Get(env.ts.URL + "/")
+		if err != nil {
+			t.Fatalf("load lobby: %v", err)
+		}
+		resp.Body.Close()
+		resp, err = client.Get(env.ts.URL + "/api/guest")
+		if err != nil {
+			t.Fatalf("get guest: %v", err)
+		}
+		defer resp.Body.Close()
+		var g guestResponse
+		json.NewDecoder(resp.Body).Decode(&g)
+		if !strings.HasPrefix(g.PlayerID, GuestPrefix) || len(resp.Cookies()) != 0 {
+			t.Fatalf("expected the lobby's cookie kept, got %q and %v", g.PlayerID, resp.Cookies())
+		}
+		return client, g.PlayerID
+	}
+	dial := func(client *http.Client, code string, join joinPayload) *websocket.Conn {
+		t.Helper()
+		opts := &websocket.DialOptions{}
+		if client != nil {
+			opts.HTTPClient = client
+		}
+		conn, _, err := websocket.Dial(ctx, wsURL(env.ts, code), opts)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { conn.CloseNow() })
+		sendWS(ctx, conn, "join", join)
+		return conn
+	}
+	readSeat := func(conn *websocket.Conn) string {
+		t.Helper()
+		msg, err := readWS(ctx, conn)
+		if err != nil || msg.Type != "seat" {
+			t.Fatalf("expected a seat message, got %v %s", err, msg.Payload)
+		}
+		var seat seatPayload
+		json.Unmarshal(msg.Payload, &seat)
+		return seat.PlayerID
+	}
+
+	one, oneID := browser()
+	two, twoID := browser()
+	if oneID == twoID {
+		t.Fatal("expected each browser its own guest ID")
+	}
+
+	// Both type "alice", and are told apart
+	resp := browserPost(t, one, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"alice"}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if created.PlayerID != oneID {
+		t.Fatalf("expected the creator seated as %s, got %s", oneID, created.PlayerID)
+	}
+	first := dial(one, created.Code, joinPayload{PlayerID: "alice", Token: created.Token})
+	if got := readSeat(first); got != oneID {
+		t.Errorf("expected the first alice back in %s, got %s", oneID, got)
+	}
+	readState(t, ctx, first)
+	second := dial(two, created.Code, joinPayload{PlayerID: "alice"})
+	if got := readSeat(second); got != twoID {
+		t.Errorf("expected the second alice seated as %s, got %s", twoID, got)
+	}
+	info := readState(t, ctx, second).SessionInfo
+	if len(info.Players) != 2 || info.Names[oneID] != "alice" || info.Names[twoID] != "alice" {
+		t.Errorf("expected two guests named alice, got %v %v", info.Players, info.Names)
+	}
+
+	// Nobody else may sit as a guest
+	for _, client := range []*http.Client{nil, two} {
+		conn := dial(client, created.Code, joinPayload{PlayerID: oneID})
+		if msg := readError(t, ctx, conn); !strings.Contains(msg, "reserved for guests") {
+			t.Errorf("expected the guest's seat refused, got %q", msg)
+		}
+	}
+	resp = postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"`+twoID+`"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected creating as a guest refused, got %d", resp.StatusCode)
+	}
+
+	// A seat held by name, as challenges seat players, is joined by name
+	sess, _ := env.mgr.Create(context.Background(), "tictactoe")
+	sess.AddPlayer("carol")
+	conn := dial(one, sess.Code, joinPayload{PlayerID: "carol"})
+	if info := readState(t, ctx, conn).SessionInfo; len(info.Players) != 1 || info.Players[0] != "carol" {
+		t.Errorf("expected carol's seat taken by name, got %v", info.Players)
+	}
+
+	// A forged cookie is replaced
+	req, _ := http.NewRequest("GET", env.ts.URL+"/api/guest", nil)
+	req.AddCookie(&http.Cookie{Name: guestCookie, Value: oneID + ".forged"})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("get guest: %v", err)
+	}
+	var g guestResponse
+	json.NewDecoder(resp.Body).Decode(&g)
+	resp.Body.Close()
+	if g.PlayerID == oneID || len(resp.Cookies()) != 2 {
+		t.Errorf("expected a new guest for a forged cookie, got %s", g.PlayerID)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/game"
+)
+
+func TestWSHandicaps(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	sendWS(ctx, bob, "handicaps", handicapsPayload{Handicaps: game.Handicaps{"bob": {"centre": 1}}})
+	if msg := readError(t, ctx, bob); msg != "only the host can set handicaps" {
+		t.Fatalf("expected the guest refused, got %q", msg)
+	}
+	sendWS(ctx, alice, "handicaps", handicapsPayload{Handicaps: game.Handicaps{"bob": {"corner": 1}}})
+	if msg := readError(t, ctx, alice); msg != `handicap of bob: unknown option "corner"` {
+		t.Fatalf("expected an unknown handicap refused, got %q", msg)
+	}
+
+	sendWS(ctx, alice, "handicaps", handicapsPayload{Handicaps: game.Handicaps{"bob": {"centre": 1}}})
+	readState(t, ctx, alice)
+	if sp := readState(t, ctx, bob); sp.SessionInfo.Handicaps["bob"]["centre"] != 1 {
+		t.Fatalf("expected everyone shown bob's handicap, got %v", sp.SessionInfo.Handicaps)
+	}
+
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	sendWS(ctx, alice, "action", makeAction(t, 0))
+	readState(t, ctx, alice)
+	sess, _ := env.mgr.Get(code)
+	tr, err := sess.Transcript()
+	if err != nil || tr.Handicaps["bob"]["centre"] != 1 {
+		t.Fatalf("expected the handicap in the transcript, got %v %v", tr.Handicaps, err)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSHandoffMovesSeat(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	phone := wsConnect(t, env.ts, code, "alice")
+	defer phone.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, phone)
+
+	sendWS(ctx, phone, "handoff", nil)
+	msg, err := readWS(ctx, phone)
+	if err != nil || msg.Type != "handoff" {
+		t.Fatalf("expected handoff message, got %q %v", msg.Type, err)
+	}
+	var hp handoffPayload
+	json.Unmarshal(msg.Payload, &hp)
+
+	laptop, _, err := websocket.Dial(ctx, wsURL(env.ts, code), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer laptop.Close(websocket.StatusNormalClosure, "")
+	sendWS(ctx, laptop, "join", joinPayload{Handoff: strings.ToLower(hp.Code)})
+	msg, err = readWS(ctx, laptop)
+	if err != nil || msg.Type != "seat" {
+		t.Fatalf("expected seat message, got %q %v", msg.Type, err)
+	}
+	var seat seatPayload
+	json.Unmarshal(msg.Payload, &seat)
+	if seat.PlayerID != "alice" || seat.Token == "" {
+		t.Fatalf("unexpected seat: %+v", seat)
+	}
+	if sp := readState(t, ctx, laptop); sp.SessionInfo.HostID != "alice" {
+		t.Fatalf("expected laptop to hold alice's seat, got %+v", sp.SessionInfo)
+	}
+
+	// The phone is told and disconnected.
+	if msg := readError(t, ctx, phone); !strings.Contains(msg, "another connection") {
+		t.Fatalf("expected seat moved error, got %q", msg)
+	}
+	if _, err := readWS(ctx, phone); websocket.CloseStatus(err) != statusSeatMoved {
+		t.Fatalf("expected close status %d, got %v", statusSeatMoved, err)
+	}
+
+	// The code is single use, and the seat now needs the device token.
+	again, _, _ := websocket.Dial(ctx, wsURL(env.ts, code), nil)
+	defer again.Close(websocket.StatusNormalClosure, "")
+	sendWS(ctx, again, "join", joinPayload{Handoff: hp.Code})
+	if msg := readError(t, ctx, again); !strings.Contains(msg, "invalid or expired") {
+		t.Fatalf("expected used code to be rejected, got %q", msg)
+	}
+	stale := wsConnect(t, env.ts, code, "alice")
+	defer stale.Close(websocket.StatusNormalClosure, "")
+	if msg := readError(t, ctx, stale); !strings.Contains(msg, "another device") {
+		t.Fatalf("expected join without token to be rejected, got %q", msg)
+	}
+}
+
+func TestWSJoinSecret(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	code := sess.Code
+	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, code), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	sendWS(ctx, conn, "join", joinPayload{PlayerID: "alice"})
+	msg, err := readWS(ctx, conn)
+	if err != nil || msg.Type != "seat" {
+		t.Fatalf("expected the first connection given a seat message, got %q %v", msg.Type, err)
+	}
+	var seat seatPayload
+	json.Unmarshal(msg.Payload, &seat)
+	if seat.PlayerID != "alice" || seat.Token == "" {
+		t.Fatalf("expected a join secret for alice, got %+v", seat)
+	}
+	readState(t, ctx, conn)
+
+	// Someone else typing the same name cannot take the seat
+	thief, _, _ := websocket.Dial(ctx, wsURL(env.ts, code), nil)
+	defer thief.Close(websocket.StatusNormalClosure, "")
+	sendWS(ctx, thief, "join", joinPayload{PlayerID: "alice"})
+	if msg := readError(t, ctx, thief); !strings.Contains(msg, "another device") {
+		t.Fatalf("expected join without the secret to be rejected, got %q", msg)
+	}
+
+	// The secret reconnects, and is not handed out again
+	again, _, _ := websocket.Dial(ctx, wsURL(env.ts, code), nil)
+	defer again.Close(websocket.StatusNormalClosure, "")
+	sendWS(ctx, again, "join", joinPayload{PlayerID: "alice", Token: seat.Token})
+	if msg, err := readWS(ctx, again); err != nil || msg.Type != "state" {
+		t.Fatalf("expected the secret to reconnect the seat, got %q %v", msg.Type, err)
+	}
+}
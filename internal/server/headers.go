@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The web app loads every script and style from the site itself and has
+// no inline scripts, styles or event handlers, so its content security
+// policy needs no nonces or 'unsafe-inline'. A page that gains one must
+// move it into a file under web/. Images may also come from any https
+// URL, for the logos branding names.
+const contentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self'; img-src 'self' https:; " +
+	"connect-src 'self' %s; worker-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors %s"
+
+// SetFrameAncestors lets the pages of origins, such as
+// https://school.example, show the site in an iframe. Without any, no
+// other site may frame it.
+func (s *Server) SetFrameAncestors(origins []string) error {
+	for _, o := range origins {
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("frame ancestor %q: want an origin such as https://example.com", o)
+		}
+	}
+	s.frameAncestors = origins
+	return nil
+}
+
+// setSecurityHeaders adds the headers that keep browsers from running the
+// site's pages other than as the site serves them: the content security
+// policy, framing rules, MIME sniffing and referrers.
+func (s *Server) setSecurityHeaders(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	// WebSockets are named in full, as 'self' does not cover them in
+	// every browser
+	ws := "ws://" + r.Host + " wss://" + r.Host
+	ancestors := "'none'"
+	if len(s.frameAncestors) > 0 {
+		ancestors = strings.Join(s.frameAncestors, " ")
+	} else {
+		h.Set("X-Frame-Options", "DENY")
+	}
+	h.Set("Content-Security-Policy", fmt.Sprintf(contentSecurityPolicy, ws, ancestors))
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+	h.Set("Permissions-Policy", "camera=(), microphone=(self), geolocation=()")
+}
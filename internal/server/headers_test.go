@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"games/internal/session"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	env := setupTestEnv(t)
+	get := func(path string) http.Header {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.Header
+	}
+	for _, path := range []string{"/", "/session.html?code=x", "/js/lobby.js", "/api/games"} {
+		h := get(path)
+		csp := h.Get("Content-Security-Policy")
+		for _, want := range []string{"script-src 'self'", "frame-ancestors 'none'", "connect-src 'self' ws://" + strings.TrimPrefix(env.ts.URL, "http://")} {
+			if !strings.Contains(csp, want) {
+				t.Errorf("%s: expected %q in the policy, got %q", path, want, csp)
+			}
+		}
+		if strings.Contains(csp, "unsafe-inline") {
+			t.Errorf("%s: expected no inline scripts or styles allowed, got %q", path, csp)
+		}
+		if h.Get("X-Frame-Options") != "DENY" || h.Get("X-Content-Type-Options") != "nosniff" || h.Get("Referrer-Policy") == "" {
+			t.Errorf("%s: missing security headers in %v", path, h)
+		}
+	}
+
+	// Sites allowed to frame the games are named in the policy instead
+	if err := env.srv.SetFrameAncestors([]string{"https://school.example/path"}); err == nil {
+		t.Error("expected a URL with a path refused")
+	}
+	if err := env.srv.SetFrameAncestors([]string{"https://school.example", "http://localhost:8000"}); err != nil {
+		t.Fatalf("set frame ancestors: %v", err)
+	}
+	h := get("/")
+	if csp := h.Get("Content-Security-Policy"); !strings.Contains(csp, "frame-ancestors https://school.example http://localhost:8000") {
+		t.Errorf("expected the framing sites in the policy, got %q", csp)
+	}
+	if h.Get("X-Frame-Options") != "" {
+		t.Errorf("expected no X-Frame-Options while framing is allowed, got %q", h.Get("X-Frame-Options"))
+	}
+}
+
+// TestSecurityHeadersAllowLogo checks that the policy lets a page show a
+// branding logo hosted on another site.
+func TestSecurityHeadersAllowLogo(t *testing.T) {
+	env := setupTestEnv(t)
+	logo := "https://hillside.example/logo.png"
+	if err := env.srv.SetBranding(session.Branding{Name: "Hillside Games", LogoURL: logo}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(env.ts.URL + "/")
+	if err != nil {
+		t.Fatalf("get /: %v", err)
+	}
+	resp.Body.Close()
+	csp := resp.Header.Get("Content-Security-Policy")
+	var sources []string
+	for _, directive := range strings.Split(csp, ";") {
+		if fields := strings.Fields(directive); len(fields) > 0 && fields[0] == "img-src" {
+			sources = fields[1:]
+		}
+	}
+	u, _ := url.Parse(logo)
+	if !slices.Contains(sources, u.Scheme+":") && !slices.Contains(sources, u.Scheme+"://"+u.Host) {
+		t.Errorf("expected %s allowed by img-src, got %q", logo, csp)
+	}
+	if slices.Contains(sources, "http:") || slices.Contains(sources, "*") {
+		t.Errorf("expected images only over https, got %q", csp)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"games/internal/session"
+)
+
+// inboxEventBuffer is how many events the inbox may fall behind the bus
+// before it misses some.
+const inboxEventBuffer = 256
+
+// errNotYourInbox is the answer to anyone but a player's own browser
+// asking for the player's inbox.
+var errNotYourInbox = errors.New("only the player's own browser may read or clear their inbox")
+
+type inboxResponse struct {
+	Messages []session.InboxMessage `json:"messages"`
+}
+
+// handlePlayerInbox returns what a player missed while away, such as their
+// turn or a challenge, to their own browser alone. Reading leaves the
+// inbox as it is; the page clears what it has shown.
+func (s *Server) handlePlayerInbox(w http.ResponseWriter, r *http.Request) {
+	playerID := r.PathValue("id")
+	if !s.isGuest(r, playerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourInbox.Error()})
+		return
+	}
+	messages, err := s.manager.Inbox(r.Context(), playerID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, inboxResponse{Messages: messages})
+}
+
+// handleClearInbox removes the messages of the player whose browser calls
+// up to the one with ID ?through=, so those that arrived after the page
+// read the inbox stay for next time.
+func (s *Server) handleClearInbox(w http.ResponseWriter, r *http.Request) {
+	playerID := r.PathValue("id")
+	if !s.isGuest(r, playerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourInbox.Error()})
+		return
+	}
+	through, err := strconv.ParseInt(r.URL.Query().Get("through"), 10, 64)
+	if err != nil || through < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "through must be a message ID"})
+		return
+	}
+	if err := s.manager.ClearInbox(r.Context(), playerID, through); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "cleared"})
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"games/internal/event"
+)
+
+func TestPlayerInbox(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, aliceID := guestBrowser(t, env.ts)
+	bob, bobID := guestBrowser(t, env.ts)
+	inbox := func(client *http.Client, playerID string) inboxResponse {
+		t.Helper()
+		resp, err := client.Get(env.ts.URL + "/api/players/" + playerID + "/inbox")
+		if err != nil {
+			t.Fatalf("get inbox: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Cache-Control") != "no-store" {
+			t.Fatalf("inbox: %d %q", resp.StatusCode, resp.Header.Get("Cache-Control"))
+		}
+		var body inboxResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+
+	// bob has a game open, so sees his turn there
+	env.mgr.PlayerConnected(bobID)
+	defer env.mgr.PlayerDisconnected(bobID)
+	bus := env.mgr.Events()
+	bus.Publish(event.Event{Type: event.YourTurn, SessionCode: "AAAA", GameType: "tictactoe", Recipients: []string{aliceID, bobID}})
+	bus.Publish(event.Event{Type: event.YourTurn, SessionCode: "AAAA", GameType: "tictactoe", Recipients: []string{aliceID}})
+	bus.Publish(event.Event{Type: event.SessionCreated, SessionCode: "BBBB", Players: []string{aliceID}})
+	bus.Publish(event.Event{Type: event.ChallengeIssued, ChallengeID: "c1", GameType: "tictactoe", Players: []string{"carol", aliceID}, Recipients: []string{aliceID}})
+	bus.Publish(event.Event{Type: event.MatchFinished, SessionCode: "AAAA", GameType: "tictactoe", Players: []string{aliceID, "ext-bot"}})
+
+	// The inbox fills as the bus delivers
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if size, err := env.mgr.DatabaseSize(t.Context()); err == nil && size.Rows["inbox"] == 3 {
+			break
+		}
+	}
+	got := inbox(alice, aliceID)
+	var types []string
+	for _, m := range got.Messages {
+		types = append(types, m.Type)
+	}
+	if len(types) != 3 || types[0] != event.YourTurn || types[1] != event.ChallengeIssued || types[2] != event.MatchFinished {
+		t.Fatalf("expected one turn, the challenge and the result, got %v", types)
+	}
+	if m := got.Messages[1]; m.ChallengeID != "c1" || m.GameType != "tictactoe" || m.At.IsZero() || m.ID == 0 {
+		t.Errorf("expected the challenge's details, got %+v", m)
+	}
+	if again := inbox(alice, aliceID).Messages; len(again) != 3 {
+		t.Errorf("expected reading to leave the inbox, got %+v", again)
+	}
+
+	// Nobody else reads or clears alice's inbox
+	through := strconv.FormatInt(got.Messages[2].ID, 10)
+	resp, _ := bob.Get(env.ts.URL + "/api/players/" + aliceID + "/inbox")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected another browser refused the inbox, got %d", resp.StatusCode)
+	}
+	resp, _ = http.Get(env.ts.URL + "/api/players/" + aliceID + "/inbox")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a caller without a cookie refused the inbox, got %d", resp.StatusCode)
+	}
+	resp = browserDo(t, bob, "DELETE", env.ts.URL+"/api/players/"+aliceID+"/inbox?through="+through, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected another browser refused clearing, got %d", resp.StatusCode)
+	}
+	// Clearing without the CSRF token is refused, so no other site can
+	req, _ := http.NewRequest("DELETE", env.ts.URL+"/api/players/"+aliceID+"/inbox?through="+through, nil)
+	resp, _ = alice.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected clearing without a CSRF token refused, got %d", resp.StatusCode)
+	}
+
+	// A message arriving after the page read the inbox outlives clearing
+	bus.Publish(event.Event{Type: event.YourTurn, SessionCode: "CCCC", GameType: "tictactoe", Recipients: []string{aliceID}})
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if size, err := env.mgr.DatabaseSize(t.Context()); err == nil && size.Rows["inbox"] == 4 {
+			break
+		}
+	}
+	resp = browserDo(t, alice, "DELETE", env.ts.URL+"/api/players/"+aliceID+"/inbox?through="+through, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("clear inbox: %d", resp.StatusCode)
+	}
+	if left := inbox(alice, aliceID).Messages; len(left) != 1 || left[0].SessionCode != "CCCC" {
+		t.Errorf("expected only the newer turn left, got %+v", left)
+	}
+	if left := inbox(bob, bobID).Messages; len(left) != 0 {
+		t.Errorf("expected nothing kept for a player online, got %+v", left)
+	}
+	if bot, _ := env.mgr.Inbox(t.Context(), "ext-bot"); len(bot) != 0 {
+		t.Errorf("expected nothing kept for a bot, got %+v", bot)
+	}
+}
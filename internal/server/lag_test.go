@@ -0,0 +1,99 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+
+	"nhooyr.io/websocket"
+)
+
+// timedGame is tic-tac-toe played against the clock, forgiving players
+// half a second of lag.
+type timedGame struct{ tictactoe.TicTacToe }
+
+func (timedGame) Info() game.GameInfo {
+	info := tictactoe.TicTacToe{}.Info()
+	info.Name = "timed"
+	return info
+}
+
+func (timedGame) LagWindow() time.Duration { return 500 * time.Millisecond }
+
+func (g timedGame) NewMatch(config game.MatchConfig) game.Match {
+	return &timedMatch{Match: g.TicTacToe.NewMatch(config)}
+}
+
+// timedMatch remembers when each action counted as made.
+type timedMatch struct {
+	game.Match
+	times []time.Time
+}
+
+func (m *timedMatch) ApplyActionAt(playerID string, action game.Action, at time.Time) ([]game.Event, error) {
+	if err := m.Match.ApplyAction(playerID, action); err != nil {
+		return nil, err
+	}
+	m.times = append(m.times, at)
+	return nil, nil
+}
+
+func (m *timedMatch) Clone() game.Match {
+	return &timedMatch{Match: m.Match.Clone(), times: append([]time.Time(nil), m.times...)}
+}
+
+func TestWSLagCompensation(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.registry.Register(timedGame{})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "timed")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, sess.Code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	time.Sleep(100 * time.Millisecond) // room for credit after the start
+
+	sent := time.Now().Add(-50 * time.Millisecond).Truncate(time.Millisecond)
+	ap := makeAction(t, 4)
+	ap.SentAt = sent.UnixMilli()
+	sendWS(ctx, alice, "action", ap)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	ap = makeAction(t, 0)
+	ap.SentAt = time.Now().Add(-time.Hour).UnixMilli()
+	sendWS(ctx, bob, "action", ap)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	sess.RLock()
+	first, second := sess.History[0], sess.History[1]
+	times := sess.Match.(*timedMatch).times
+	sess.RUnlock()
+	if !first.SentAt.Equal(sent) || !first.ActedAt.Equal(sent) {
+		t.Fatalf("expected the first move credited back to when it was sent, got %+v", first)
+	}
+	if !second.ActedAt.Equal(first.ActedAt) {
+		t.Fatalf("expected the second move credited no further back than the first, got %+v", second)
+	}
+	if len(times) != 2 || !times[0].Equal(first.ActedAt) || !times[1].Equal(second.ActedAt) {
+		t.Fatalf("expected the match judged by the credited times, got %v", times)
+	}
+
+	tr, err := sess.Transcript()
+	if err != nil {
+		t.Fatalf("transcript: %v", err)
+	}
+	if len(tr.Moves) != 2 || tr.Moves[0].SentAt.IsZero() || tr.Moves[1].ActedAt.IsZero() {
+		t.Fatalf("expected the timing in the transcript, got %+v", tr.Moves)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+const (
+	defaultSessionPage = 50
+	maxSessionPage     = 200
+)
+
+type sessionPage struct {
+	Sessions []session.Listing `json:"sessions"`
+	// NextOffset is the offset of the following page; absent on the last.
+	NextOffset int `json:"nextOffset,omitempty"`
+}
+
+// parseSessionFilter reads a session listing's game, status, sort,
+// createdAfter and createdBefore (RFC 3339), limit and offset query
+// parameters.
+func parseSessionFilter(q url.Values) (storage.SessionFilter, error) {
+	f := storage.SessionFilter{GameType: q.Get("game"), Limit: defaultSessionPage}
+	switch status := session.Status(q.Get("status")); status {
+	case "", session.StatusWaiting, session.StatusPlaying, session.StatusFinished, session.StatusErrored:
+		f.Status = string(status)
+	default:
+		return f, fmt.Errorf("status must be waiting, playing, finished or errored")
+	}
+	switch sort := storage.SessionSort(q.Get("sort")); sort {
+	case "", storage.SortNewest, storage.SortOldest, storage.SortActivity:
+		f.Sort = sort
+	default:
+		return f, fmt.Errorf("sort must be newest, oldest or activity")
+	}
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"createdAfter", &f.CreatedAfter}, {"createdBefore", &f.CreatedBefore}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be an RFC 3339 time", p.name)
+			}
+			*p.t = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSessionPage {
+			return f, fmt.Errorf("limit must be between 1 and %d", maxSessionPage)
+		}
+		f.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("offset must be a non-negative integer")
+		}
+		f.Offset = n
+	}
+	return f, nil
+}
+
+// listSessions writes one page of the sessions matching f, fetching one
+// extra to tell whether another page follows.
+func (s *Server) listSessions(ctx context.Context, w http.ResponseWriter, f storage.SessionFilter) {
+	limit := f.Limit
+	f.Limit++
+	listings, err := s.manager.ListSessions(ctx, f)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	page := sessionPage{Sessions: listings}
+	if len(listings) > limit {
+		page.Sessions = listings[:limit]
+		page.NextOffset = f.Offset + limit
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleListSessions lists public sessions from storage, including those
+// no longer loaded, a page at a time.
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	f, err := parseSessionFilter(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	f.PublicOnly = true
+	s.listSessions(r.Context(), w, f)
+}
+
+// handleAdminListSessions lists sessions like handleListSessions, private
+// ones included.
+func (s *Server) handleAdminListSessions(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	f, err := parseSessionFilter(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	entry.Detail = r.URL.RawQuery
+	s.listSessions(r.Context(), w, f)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func getSessionPage(t *testing.T, url, token string) (sessionPage, int) {
+	t.Helper()
+	resp := adminRequest(t, "GET", url, token, "")
+	defer resp.Body.Close()
+	var page sessionPage
+	json.NewDecoder(resp.Body).Decode(&page)
+	return page, resp.StatusCode
+}
+
+func TestListSessions(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	first := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	second := createSessionViaAPI(t, env.ts, "tictactoe", "bob")
+	resp, err := http.Post(env.ts.URL+"/api/sessions", "application/json",
+		strings.NewReader(`{"gameType":"tictactoe","playerId":"carol","private":true}`))
+	if err != nil {
+		t.Fatalf("create private session: %v", err)
+	}
+	resp.Body.Close()
+
+	page, status := getSessionPage(t, env.ts.URL+"/api/sessions?sort=oldest&limit=1", "")
+	if status != http.StatusOK || len(page.Sessions) != 1 || page.Sessions[0].Code != first || page.NextOffset != 1 {
+		t.Fatalf("unexpected first page %d %+v", status, page)
+	}
+	if len(page.Sessions[0].Players) != 1 || page.Sessions[0].Players[0] != "alice" {
+		t.Fatalf("expected a loaded session's players, got %+v", page.Sessions[0])
+	}
+	page, _ = getSessionPage(t, env.ts.URL+"/api/sessions?sort=oldest&limit=1&offset=1", "")
+	if len(page.Sessions) != 1 || page.Sessions[0].Code != second || page.NextOffset != 0 {
+		t.Fatalf("expected the private session left out of the last page, got %+v", page)
+	}
+
+	page, _ = getSessionPage(t, env.ts.URL+"/api/admin/sessions?status=waiting", "secret")
+	if len(page.Sessions) != 3 || !page.Sessions[0].Private {
+		t.Fatalf("expected admins to see the private session first, got %+v", page)
+	}
+
+	for _, query := range []string{"status=over", "sort=name", "limit=0", "offset=-1", "createdAfter=yesterday"} {
+		if _, status := getSessionPage(t, env.ts.URL+"/api/sessions?"+query, ""); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, status)
+		}
+	}
+}
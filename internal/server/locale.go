@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"games/internal/i18n"
+)
+
+// langCookie keeps the locale a visitor chose with ?lang= for the pages
+// they open after.
+const langCookie = "lang"
+
+// requestLocale is the locale a request asks for: ?lang=, then the lang
+// cookie, then Accept-Language.
+func requestLocale(r *http.Request) *i18n.Locale {
+	if l, ok := i18n.Lookup(r.URL.Query().Get("lang")); ok {
+		return l
+	}
+	if c, err := r.Cookie(langCookie); err == nil {
+		if l, ok := i18n.Lookup(c.Value); ok {
+			return l
+		}
+	}
+	return i18n.Match(r.Header.Get("Accept-Language"))
+}
+
+// rememberLocale sets the lang cookie when the request chose a locale
+// with ?lang=.
+func rememberLocale(w http.ResponseWriter, r *http.Request) {
+	l, ok := i18n.Lookup(r.URL.Query().Get("lang"))
+	if !ok {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     langCookie,
+		Value:    l.Tag,
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+
+	"nhooyr.io/websocket"
+)
+
+// whisperGame is tic-tac-toe that privately tells each player which cell
+// they just took.
+type whisperGame struct{ tictactoe.TicTacToe }
+
+func (whisperGame) Info() game.GameInfo {
+	info := tictactoe.TicTacToe{}.Info()
+	info.Name = "whisper"
+	return info
+}
+
+func (g whisperGame) NewMatch(config game.MatchConfig) game.Match {
+	return &whisperMatch{Match: g.TicTacToe.NewMatch(config)}
+}
+
+type whisperMatch struct {
+	game.Match
+	queue []game.Message
+}
+
+func (m *whisperMatch) ApplyAction(playerID string, action game.Action) error {
+	if err := m.Match.ApplyAction(playerID, action); err != nil {
+		return err
+	}
+	m.queue = append(m.queue, game.Message{PlayerID: playerID, Payload: "you took " + string(action.Payload)})
+	return nil
+}
+
+func (m *whisperMatch) TakeMessages() []game.Message {
+	q := m.queue
+	m.queue = nil
+	return q
+}
+
+func (m *whisperMatch) Clone() game.Match {
+	return &whisperMatch{Match: m.Match.Clone()}
+}
+
+func TestWSPrivateMessagesReachOnlyTheirPlayer(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.registry.Register(whisperGame{})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "whisper")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, sess.Code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	mover, other := alice, bob // alice joined first
+	sendWS(ctx, mover, "action", makeAction(t, 4))
+
+	msg, err := readWS(ctx, mover)
+	if err != nil || msg.Type != "message" {
+		t.Fatalf("expected private message first, got %q %v", msg.Type, err)
+	}
+	var m game.Message
+	json.Unmarshal(msg.Payload, &m)
+	if m.Payload != `you took {"cell":4}` {
+		t.Fatalf("unexpected message: %s", msg.Payload)
+	}
+	readState(t, ctx, mover)
+	// The other player gets the state and nothing else.
+	readState(t, ctx, other)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"games/internal/metrics"
+	"games/internal/session"
+)
+
+// serverMetrics are the latencies GET /metrics reports, for alerts on
+// percentile regressions by game type.
+type serverMetrics struct {
+	registry   metrics.Registry
+	messages   *metrics.Histogram
+	broadcasts *metrics.Histogram
+}
+
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{
+		messages: metrics.NewHistogram("games_ws_message_duration_seconds",
+			"Time to handle a WebSocket message from a player, bot moves it set off included.", "game", "type"),
+		broadcasts: metrics.NewHistogram("games_broadcast_duration_seconds",
+			"Time to build and queue a state broadcast for every player and spectator of a session.", "game"),
+	}
+	m.registry.Register(m.messages, m.broadcasts)
+	return m
+}
+
+// wsMessageTypes are the client message types latency is labeled with;
+// any other type is labeled "unknown", so clients cannot add series.
+var wsMessageTypes = func() map[string]bool {
+	types := make(map[string]bool, len(wsClientMessages))
+	for _, spec := range wsClientMessages {
+		types[spec.Type] = true
+	}
+	return types
+}()
+
+// RegisterMetrics adds metrics kept elsewhere, such as the store's query
+// durations, to GET /metrics.
+func (s *Server) RegisterMetrics(ms ...metrics.Metric) {
+	s.metrics.registry.Register(ms...)
+}
+
+// handleMetrics writes the metrics in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.registry.WriteTo(w)
+}
+
+// observeMessage records how long handling a message of msgType took.
+func (s *Server) observeMessage(sess *session.Session, msgType string, start time.Time) {
+	if !wsMessageTypes[msgType] {
+		msgType = "unknown"
+	}
+	s.metrics.messages.Observe(time.Since(start), sess.GameType, msgType)
+}
+
+// observeBroadcast records how long a broadcast of a gameType session
+// took.
+func (s *Server) observeBroadcast(gameType string, start time.Time) {
+	s.metrics.broadcasts.Observe(time.Since(start), gameType)
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestMetrics(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+
+	sendWS(ctx, alice, "ping", pingPayload{ClientTime: "1"})
+	wsRead(ctx, t, alice)
+	sendWS(ctx, alice, "no_such_message", nil)
+	readError(t, ctx, alice)
+
+	resp, err := http.Get(env.ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("expected the text format, got %s", resp.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"# TYPE games_ws_message_duration_seconds histogram",
+		`games_ws_message_duration_seconds_count{game="tictactoe",type="ping"} 1`,
+		`games_ws_message_duration_seconds_count{game="tictactoe",type="unknown"} 1`,
+		`games_broadcast_duration_seconds_count{game="tictactoe"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+}
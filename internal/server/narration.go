@@ -0,0 +1,23 @@
+package server
+
+import "games/internal/session"
+
+// narrationPayload says in words what the move at Seq did, as the game
+// describes it, for screen readers and voice clients that would otherwise
+// have to work it out from the board.
+type narrationPayload struct {
+	Seq      int    `json:"seq"`
+	PlayerID string `json:"playerId"`
+	Text     string `json:"text"`
+}
+
+// broadcastNarration sends a move's description to everyone watching who
+// asked for narration, ahead of the state it leads to.
+func (s *Server) broadcastNarration(sess *session.Session, np narrationPayload) {
+	msg := encodeWSMsg("narration", np)
+	for _, send := range watcherSends(sess) {
+		if _, ok := s.narration.Load(send); ok {
+			sendEncoded(send, msg)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestNarration(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	code := sess.Code
+	alice := joinWith(t, env, code, joinPayload{PlayerID: "alice", Narration: true})
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	wall := joinWith(t, env, code, joinPayload{PlayerID: "wall", Spectate: true, Narration: true})
+	defer wall.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, wall)
+
+	sendWS(ctx, alice, "start", nil)
+	for _, conn := range []*websocket.Conn{alice, bob, wall} {
+		readState(t, ctx, conn)
+	}
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+
+	// Those who asked hear the move described between its events and
+	// the state it leads to
+	for _, conn := range []*websocket.Conn{alice, wall} {
+		var types []string
+		var narration narrationPayload
+		for msg := wsRead(ctx, t, conn); ; msg = wsRead(ctx, t, conn) {
+			types = append(types, msg.Type)
+			if msg.Type == "narration" {
+				json.Unmarshal(msg.Payload, &narration)
+			}
+			if msg.Type == "state" {
+				break
+			}
+		}
+		if len(types) != 3 || types[1] != "narration" {
+			t.Fatalf("expected events, narration and state, got %v", types)
+		}
+		if narration != (narrationPayload{Seq: 1, PlayerID: "alice", Text: "alice placed X in the center."}) {
+			t.Fatalf("unexpected narration %+v", narration)
+		}
+	}
+	// bob did not ask for it
+	if msg := wsRead(ctx, t, bob); msg.Type != "events" {
+		t.Fatalf("expected events, got %s", msg.Type)
+	}
+	if msg := wsRead(ctx, t, bob); msg.Type != "state" {
+		t.Fatalf("expected no narration for bob, got %s", msg.Type)
+	}
+}
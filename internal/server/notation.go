@@ -0,0 +1,23 @@
+package server
+
+import (
+	"fmt"
+
+	"games/internal/game"
+	"games/internal/session"
+)
+
+// parseMove reads a move playerID wrote as text in the notation of the
+// session's game.
+func parseMove(sess *session.Session, playerID, text string) (action game.Action, err error) {
+	sess.RLock()
+	defer sess.RUnlock()
+	if sess.Match == nil {
+		return action, fmt.Errorf("game not started")
+	}
+	err = session.Protect(func() error {
+		action, err = game.ParseMove(sess.Match, playerID, text)
+		return err
+	})
+	return action, err
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestActionText(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	if err := sendWS(ctx, alice, "action_text", actionTextPayload{Text: "b2"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if msg := readError(t, ctx, alice); msg != "game not started" {
+		t.Errorf("expected a move before the start refused, got %q", msg)
+	}
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, bob)
+	if resp := startViaAPI(t, env, code); resp.StatusCode != http.StatusOK {
+		t.Fatalf("start: %d", resp.StatusCode)
+	}
+
+	if err := sendWS(ctx, bob, "action_text", actionTextPayload{Text: "z9"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	msg := wsRead(ctx, t, bob)
+	for msg.Type != "error" {
+		msg = wsRead(ctx, t, bob)
+	}
+	var ep errorPayload
+	json.Unmarshal(msg.Payload, &ep)
+	if ep.Message != `want a cell such as b2, got "z9"` {
+		t.Errorf("expected the notation error, got %q", ep.Message)
+	}
+	// Alice joined first, so moves first
+	if err := sendWS(ctx, alice, "action_text", actionTextPayload{Text: "B2"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	for msg = wsRead(ctx, t, alice); msg.Type != "events" && msg.Type != "error"; msg = wsRead(ctx, t, alice) {
+	}
+	var moved struct {
+		Seq    int `json:"seq"`
+		Events []struct {
+			Data struct {
+				Cell int `json:"cell"`
+			} `json:"data"`
+		} `json:"events"`
+	}
+	json.Unmarshal(msg.Payload, &moved)
+	if msg.Type != "events" || moved.Seq != 1 || len(moved.Events) == 0 || moved.Events[0].Data.Cell != 4 {
+		t.Fatalf("expected alice to take the centre, got %s %s", msg.Type, msg.Payload)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"games/internal/session"
+)
+
+// handleSharePage serves the lobby for a share link, /s/{code}, with the
+// session's preview tags, so the link unfurls in chat apps.
+func (s *Server) handleSharePage(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.manager.Get(r.PathValue("code"))
+	if !ok {
+		s.static.ServeHTTP(w, r)
+		return
+	}
+	s.static.servePage(w, r, "index.html", s.sessionMeta(r, sess))
+}
+
+// handleSessionPage serves the session page, with the preview tags of the
+// session ?code= names when it is in memory.
+func (s *Server) handleSessionPage(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.manager.Get(r.URL.Query().Get("code"))
+	if !ok {
+		s.static.ServeHTTP(w, r)
+		return
+	}
+	s.static.servePage(w, r, "session.html", s.sessionMeta(r, sess))
+}
+
+// sessionMeta describes a session for link previews, in the request's
+// locale: its game, players and status, and its board when the game can
+// draw one. Its URL is the session's share link.
+func (s *Server) sessionMeta(r *http.Request, sess *session.Session) *pageMeta {
+	info := sess.Info()
+	loc := requestLocale(r)
+	site := s.siteURL(r)
+	meta := &pageMeta{URL: site + "/s/" + info.Code}
+	meta.Title = loc.T("preview.title", info.GameType, strings.Join(info.Players, loc.T("list.versus")))
+	switch info.Status {
+	case session.StatusWaiting:
+		meta.Title = loc.T("preview.join", info.GameType)
+		meta.Description = loc.T("preview.waiting")
+		if len(info.Players) > 0 {
+			meta.Description = loc.T("preview.waiting_joined", loc.List(info.Players))
+		}
+	case session.StatusPlaying:
+		meta.Description = loc.T("preview.playing")
+	case session.StatusFinished:
+		meta.Description = loc.T("preview.finished")
+	default:
+		meta.Description = loc.T("preview.stopped")
+	}
+	meta.Description = loc.T("preview.session", meta.Description, info.Code)
+	if _, ok, _ := livePicture(sess); ok {
+		meta.Image = site + "/api/sessions/" + info.Code + "/picture?format=png"
+	}
+	return meta
+}
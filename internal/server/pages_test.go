@@ -0,0 +1,97 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSharePreviews(t *testing.T) {
+	env := setupTestEnv(t)
+	shell := []byte(`<html><head><title>x</title>{{template "meta" .}}<script src="/js/app.js"></script></head><body></body></html>`)
+	ts := httptest.NewServer(New(env.srv.registry, env.mgr, fstest.MapFS{
+		"index.html":   &fstest.MapFile{Data: shell},
+		"session.html": &fstest.MapFile{Data: shell},
+		"js/app.js":    &fstest.MapFile{Data: []byte("1")},
+	}))
+	defer ts.Close()
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != contentTypes[".html"] {
+			t.Fatalf("get %s: %d %s", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return string(body)
+	}
+	tag := func(property, content string) string {
+		return `<meta property="` + property + `" content="` + content + `">`
+	}
+
+	code := createSessionViaAPI(t, ts, "tictactoe", `al"ice`)
+	page := get("/s/" + code)
+	for _, want := range []string{
+		tag("og:title", "Join a game of tictactoe"),
+		tag("og:description", "Waiting for players, al&#34;ice joined. Session "+code+"."),
+		tag("og:url", ts.URL+"/s/"+code),
+		"/js/app.js?v=",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected %s in the share page, got %s", want, page)
+		}
+	}
+	if strings.Contains(page, "og:image") {
+		t.Errorf("expected no picture before play, got %s", page)
+	}
+
+	alice := wsConnect(t, ts, code, `al"ice`)
+	readState(t, ctx, alice)
+	bob := wsConnect(t, ts, code, "bob")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	page = get("/session.html?code=" + code + "&player=bob")
+	for _, want := range []string{
+		tag("og:title", "tictactoe: al&#34;ice vs bob"),
+		tag("og:description", "In play. Session "+code+"."),
+		tag("og:image", ts.URL+"/api/sessions/"+code+"/picture?format=png"),
+		`<meta name="twitter:card" content="summary">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected %s in the session page, got %s", want, page)
+		}
+	}
+
+	// A preview in the locale the link was opened with
+	req, _ := http.NewRequest("GET", ts.URL+"/s/"+code, nil)
+	req.Header.Set("Accept-Language", "ar")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("get share page: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want := tag("og:description", "قيد اللعب. الجلسة "+code+"."); !strings.Contains(string(body), want) || resp.Header.Get("Content-Language") != "ar" {
+		t.Errorf("expected %s in the Arabic share page, got %s", want, body)
+	}
+
+	// Without a session the pages carry no preview tags
+	for _, path := range []string{"/s/nope", "/session.html", "/session.html?code=nope", "/"} {
+		if page := get(path); strings.Contains(page, "og:") || strings.Contains(page, "{{") {
+			t.Errorf("%s: expected the plain page, got %s", path, page)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"games/internal/session"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSPartyPlaysQueuedGames(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"alice","party":["tictactoe"]}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+
+	conn := joinWith(t, env, created.Code, joinPayload{PlayerID: "alice", Token: created.Token})
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, conn)
+	sendWS(ctx, conn, "add_bot", addBotPayload{Strategy: "random"})
+	readState(t, ctx, conn)
+
+	// The next game can't be started before the first one ends.
+	sendWS(ctx, conn, "next_game", nil)
+	if msg := readError(t, ctx, conn); !strings.Contains(msg, "not finished") {
+		t.Fatalf("expected not-finished error, got %q", msg)
+	}
+
+	sendWS(ctx, conn, "start", nil)
+	// playToEnd makes alice's moves, starting from state sp, until the
+	// game is over.
+	playToEnd := func(sp statePayload) statePayload {
+		for len(sp.Results) == 0 {
+			if len(sp.ValidActions) > 0 {
+				sendWS(ctx, conn, "action", actionPayload{Action: sp.ValidActions[0]})
+			}
+			sp = readState(t, ctx, conn)
+		}
+		return sp
+	}
+	sp := playToEnd(readState(t, ctx, conn))
+	party := sp.SessionInfo.Party
+	if party == nil || len(party.Rounds) != 1 {
+		t.Fatalf("expected one recorded round, got %+v", party)
+	}
+	total := 0
+	for _, pts := range party.Standings {
+		total += pts
+	}
+	if total < 3 {
+		t.Fatalf("expected points for both players, got %v", party.Standings)
+	}
+
+	sendWS(ctx, conn, "next_game", nil)
+	for {
+		sp = readState(t, ctx, conn)
+		if sp.SessionInfo.Status == "playing" {
+			break
+		}
+	}
+	if sp.SessionInfo.Party.Round != 1 || len(sp.SessionInfo.Bots) != 1 {
+		t.Fatalf("expected second round with the bot kept, got %+v", sp.SessionInfo)
+	}
+	sp = playToEnd(sp)
+	if len(sp.SessionInfo.Party.Rounds) != 2 {
+		t.Fatalf("expected two recorded rounds, got %d", len(sp.SessionInfo.Party.Rounds))
+	}
+	if len(sp.Scoreboard) != 2 || sp.Scoreboard[0].Played != 2 || sp.Scoreboard[0].Rank != 1 {
+		t.Fatalf("expected both rounds on the scoreboard, got %+v", sp.Scoreboard)
+	}
+	resp, err := http.Get(env.ts.URL + "/api/sessions/" + created.Code + "/scoreboard")
+	if err != nil {
+		t.Fatalf("GET scoreboard: %v", err)
+	}
+	var board []session.ScoreEntry
+	json.NewDecoder(resp.Body).Decode(&board)
+	resp.Body.Close()
+	if len(board) != 2 || board[0] != sp.Scoreboard[0] {
+		t.Fatalf("expected the broadcast scoreboard, got %+v", board)
+	}
+
+	sendWS(ctx, conn, "next_game", nil)
+	if msg := readError(t, ctx, conn); !strings.Contains(msg, "no games left") {
+		t.Fatalf("expected end of party, got %q", msg)
+	}
+}
+
+func TestCreatePartyValidation(t *testing.T) {
+	env := setupTestEnv(t)
+	for _, body := range []string{
+		`{"gameType":"tictactoe","playerId":"alice","party":["chess"]}`,
+		`{"gameType":"tictactoe","playerId":"alice","party":["tictactoe"],"options":{"misere":1}}`,
+	} {
+		resp := postJSON(t, env.ts.URL+"/api/sessions", body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestWSRematchSwapsFirstPlayer(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	conn := wsConnect(t, env.ts, code, "alice")
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, conn)
+	sendWS(ctx, conn, "add_bot", addBotPayload{Strategy: "random"})
+	readState(t, ctx, conn)
+
+	sendWS(ctx, conn, "rematch", nil)
+	if msg := readError(t, ctx, conn); !strings.Contains(msg, "not finished") {
+		t.Fatalf("expected not-finished error, got %q", msg)
+	}
+
+	sendWS(ctx, conn, "start", nil)
+	sp := readState(t, ctx, conn)
+	first := sp.SessionInfo.Seats[0]
+	for len(sp.Results) == 0 {
+		if len(sp.ValidActions) > 0 {
+			sendWS(ctx, conn, "action", actionPayload{Action: sp.ValidActions[0]})
+		}
+		sp = readState(t, ctx, conn)
+	}
+
+	sendWS(ctx, conn, "rematch", nil)
+	for {
+		sp = readState(t, ctx, conn)
+		if sp.SessionInfo.Status == "playing" {
+			break
+		}
+	}
+	if sp.SessionInfo.Seats[0] == first || len(sp.SessionInfo.Bots) != 1 {
+		t.Fatalf("expected the bot kept and the other player to move first, got %+v", sp.SessionInfo)
+	}
+}
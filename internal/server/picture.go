@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+
+	"games/internal/game"
+	"games/internal/session"
+)
+
+// pictureSize is roughly how many pixels wide and high a PNG picture is.
+const pictureSize = 240
+
+// playerColors colour marks by seat, repeating past the last.
+var playerColors = []color.RGBA{
+	{0xd3, 0x3f, 0x49, 0xff},
+	{0x2f, 0x6f, 0xc4, 0xff},
+	{0x2e, 0x9e, 0x5b, 0xff},
+	{0xe0, 0x9a, 0x1f, 0xff},
+}
+
+var gridColor = color.RGBA{0xcc, 0xcc, 0xcc, 0xff}
+
+func playerColor(seat int) color.RGBA {
+	return playerColors[((seat%len(playerColors))+len(playerColors))%len(playerColors)]
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// handleSessionPicture draws a session's board, as SVG unless ?format=png
+// is given. A session no longer in memory is drawn from its stored match,
+// so match histories can show how finished games ended.
+func (s *Server) handleSessionPicture(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "svg" && format != "png" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be svg or png"})
+		return
+	}
+	var (
+		p   game.Picture
+		ok  bool
+		err error
+	)
+	if sess, live := s.manager.Get(r.PathValue("code")); live {
+		p, ok, err = livePicture(sess)
+		if s.failIfPanicked(r.Context(), sess, err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	} else {
+		sm, found := s.storedMatch(w, r)
+		if !found {
+			return
+		}
+		p, ok, err = storedPicture(sm)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "nothing to draw for this session"})
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=10")
+	if format == "png" {
+		data, err := renderPNG(p)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(renderSVG(p))
+}
+
+// livePicture draws a session's match, if it has one that can be drawn.
+func livePicture(sess *session.Session) (p game.Picture, ok bool, err error) {
+	sess.RLock()
+	defer sess.RUnlock()
+	r, ok := sess.Match.(game.Renderer)
+	if !ok {
+		return p, false, nil
+	}
+	err = session.Protect(func() error {
+		p = r.Picture()
+		return nil
+	})
+	return p, err == nil, err
+}
+
+// storedPicture draws a stored match as its moves left it.
+func storedPicture(sm *session.StoredMatch) (p game.Picture, ok bool, err error) {
+	err = session.Protect(func() error {
+		m, err := sm.MatchAt(len(sm.Moves))
+		if err != nil {
+			return err
+		}
+		var r game.Renderer
+		if r, ok = m.(game.Renderer); ok {
+			p = r.Picture()
+		}
+		return nil
+	})
+	return p, ok && err == nil, err
+}
+
+// renderSVG draws a picture ten units to a cell.
+func renderSVG(p game.Picture) []byte {
+	var b bytes.Buffer
+	w, h := p.Cols*10, p.Rows*10
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d">`, w, h)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path stroke="%s" stroke-width="0.3" d="`, w, h, hexColor(gridColor))
+	for x := 1; x < p.Cols; x++ {
+		fmt.Fprintf(&b, "M%d 0v%d", x*10, h)
+	}
+	for y := 1; y < p.Rows; y++ {
+		fmt.Fprintf(&b, "M0 %dh%d", y*10, w)
+	}
+	b.WriteString(`"/>`)
+	for i, m := range p.Cells {
+		if i >= p.Cols*p.Rows {
+			break
+		}
+		x, y := i%p.Cols*10+5, i/p.Cols*10+5
+		c := hexColor(playerColor(m.Player))
+		switch m.Shape {
+		case game.Cross:
+			fmt.Fprintf(&b, `<path stroke="%s" stroke-width="1.5" stroke-linecap="round" d="M%d %dl6 6M%d %dl-6 6"/>`, c, x-3, y-3, x+3, y-3)
+		case game.Ring:
+			fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="3.3" fill="none" stroke="%s" stroke-width="1.5"/>`, x, y, c)
+		case game.Disc:
+			fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="4" fill="%s"/>`, x, y, c)
+		}
+	}
+	b.WriteString(`</svg>`)
+	return b.Bytes()
+}
+
+// renderPNG draws a picture about pictureSize pixels across, with the
+// shapes of renderSVG.
+func renderPNG(p game.Picture) ([]byte, error) {
+	if p.Cols < 1 || p.Rows < 1 {
+		return nil, fmt.Errorf("picture of %dx%d cells", p.Cols, p.Rows)
+	}
+	cell := max(pictureSize/max(p.Cols, p.Rows), 8)
+	img := image.NewRGBA(image.Rect(0, 0, p.Cols*cell, p.Rows*cell))
+	for py := 0; py < p.Rows*cell; py++ {
+		for px := 0; px < p.Cols*cell; px++ {
+			col, row := px/cell, py/cell
+			c := color.RGBA{0xff, 0xff, 0xff, 0xff}
+			if (px%cell == 0 && col > 0) || (py%cell == 0 && row > 0) {
+				c = gridColor
+			}
+			if i := row*p.Cols + col; i < len(p.Cells) {
+				// u and v run from -5 to 5 across the cell, as in the SVG
+				u := (float64(px%cell)+0.5)/float64(cell)*10 - 5
+				v := (float64(py%cell)+0.5)/float64(cell)*10 - 5
+				if inShape(p.Cells[i].Shape, u, v) {
+					c = playerColor(p.Cells[i].Player)
+				}
+			}
+			img.SetRGBA(px, py, c)
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// inShape reports whether the point u, v of a cell centred on 0, 0 is
+// inked by shape.
+func inShape(shape game.Shape, u, v float64) bool {
+	r := math.Hypot(u, v)
+	switch shape {
+	case game.Cross:
+		return max(math.Abs(u), math.Abs(v)) <= 3.75 && min(math.Abs(u-v), math.Abs(u+v)) <= 0.75*math.Sqrt2
+	case game.Ring:
+		return math.Abs(r-3.3) <= 0.75
+	case game.Disc:
+		return r <= 4
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"games/internal/session"
+)
+
+func TestSessionPicture(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	get := func(path string) (int, string, []byte) {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Content-Type"), body
+	}
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	if status, _, _ := get("/api/sessions/" + code + "/picture"); status != http.StatusNotFound {
+		t.Errorf("expected a waiting session to have nothing to draw, got %d", status)
+	}
+	alice := wsConnect(t, env.ts, code, "alice")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, bob, "action", makeAction(t, 0))
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	status, ct, svg := get("/api/sessions/" + code + "/picture")
+	if status != http.StatusOK || ct != "image/svg+xml" {
+		t.Fatalf("expected an SVG, got %d %s", status, ct)
+	}
+	if !bytes.Contains(svg, []byte("<circle")) || bytes.Count(svg, []byte("<path")) != 2 {
+		t.Errorf("expected the grid, a cross and a ring, got %s", svg)
+	}
+	status, ct, data := get("/api/sessions/" + code + "/picture?format=png")
+	if status != http.StatusOK || ct != "image/png" {
+		t.Fatalf("expected a PNG, got %d %s", status, ct)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 240 || b.Dy() != 240 {
+		t.Errorf("expected 240x240, got %v", b)
+	}
+	// The centre of the board is alice's cross, its corner bob's ring
+	if r, g, b, _ := img.At(120, 120).RGBA(); r>>8 != 0xd3 || g>>8 != 0x3f || b>>8 != 0x49 {
+		t.Errorf("expected the cross in the first player's colour at the centre, got %x %x %x", r>>8, g>>8, b>>8)
+	}
+	if status, _, _ := get("/api/sessions/" + code + "/picture?format=gif"); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", status)
+	}
+
+	// A server started afresh on the same storage has the session only
+	// in its stored moves
+	restarted := httptest.NewServer(New(env.srv.registry, session.NewManager(env.srv.registry, env.store), fstest.MapFS{}))
+	defer restarted.Close()
+	env.ts = restarted
+	if status, _, stored := get("/api/sessions/" + code + "/picture"); status != http.StatusOK || !bytes.Equal(stored, svg) {
+		t.Errorf("expected the stored match drawn as the live one was, got %d %s", status, stored)
+	}
+	if status, _, _ := get("/api/sessions/nope/picture"); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown session, got %d", status)
+	}
+}
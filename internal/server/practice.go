@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"games/internal/session"
+)
+
+type createPracticeRequest struct {
+	GameType string `json:"gameType"`
+	PlayerID string `json:"playerId"`
+	// Opponent is a built-in strategy to play against; without one the
+	// player plays every seat.
+	Opponent string         `json:"opponent,omitempty"`
+	Options  map[string]int `json:"options,omitempty"`
+}
+
+// handleCreatePractice starts a practice session and seats the player in
+// it, answering like session creation so the client joins the same way.
+func (s *Server) handleCreatePractice(w http.ResponseWriter, r *http.Request) {
+	var req createPracticeRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	req.GameType = strings.TrimSpace(req.GameType)
+	req.PlayerID = strings.TrimSpace(req.PlayerID)
+	if req.GameType == "" || req.PlayerID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "gameType and playerId required"})
+		return
+	}
+	if strings.HasPrefix(req.PlayerID, session.ExternalBotPrefix) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "player IDs starting with " + session.ExternalBotPrefix + " are reserved for bots"})
+		return
+	}
+	playerID, name, err := s.seatAs(r, req.PlayerID, func(string) bool { return false })
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	sess, err := s.manager.CreatePractice(r.Context(), req.GameType, playerID, req.Opponent, req.Options)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	sess.SetName(playerID, name)
+	token := sess.ClaimSeat(playerID)
+	s.matchStarted(r.Context(), sess)
+	s.playBots(r.Context(), sess, 0)
+	writeJSON(w, http.StatusCreated, createSessionResponse{Code: sess.Code, PlayerID: playerID, Token: token})
+}
+
+// moverFor returns the seat a player's action is for: the one they asked
+// to move as, which must be theirs to play, or else the seat they play for
+// now.
+func moverFor(sess *session.Session, playerID, as string) (string, error) {
+	if as != "" {
+		if !sess.ActsFor(playerID, as) {
+			return "", fmt.Errorf("you cannot move for %s", as)
+		}
+		return as, nil
+	}
+	var seat string
+	err := session.Protect(func() error {
+		seat = sess.SeatToPlay(playerID)
+		return nil
+	})
+	return seat, err
+}
+
+// undo takes back the last move in a practice match and lets any bot
+// whose turn it is again play on.
+func (s *Server) undo(ctx context.Context, sess *session.Session, send chan []byte) {
+	_, err := s.manager.Undo(ctx, sess)
+	if s.failIfPanicked(ctx, sess, err) {
+		return
+	}
+	if err != nil {
+		sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+		return
+	}
+	s.broadcastState(sess)
+	s.playBots(ctx, sess, 0)
+}
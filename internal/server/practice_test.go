@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func createPracticeViaAPI(t *testing.T, env *testEnv, body string) createSessionResponse {
+	t.Helper()
+	resp, err := http.Post(env.ts.URL+"/api/practice", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("create practice: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var result createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+	seatTokens.Lock()
+	seatTokens.bySeat[wsURL(env.ts, result.Code)+" "+result.PlayerID] = result.Token
+	seatTokens.Unlock()
+	return result
+}
+
+func TestPracticeHotSeat(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	created := createPracticeViaAPI(t, env, `{"gameType":"tictactoe","playerId":"alice"}`)
+	if created.Token == "" {
+		t.Fatal("expected the seat's token")
+	}
+	alice := wsConnect(t, env.ts, created.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	sp := readState(t, ctx, alice)
+	if !sp.SessionInfo.Practice || !sp.SessionInfo.Private || sp.SessionInfo.Status != "playing" {
+		t.Fatalf("expected a private practice match in play, got %+v", sp.SessionInfo)
+	}
+
+	// alice plays both X and O, each in turn, without naming the seat
+	for _, cell := range []int{0, 4, 1} {
+		if len(sp.ValidActions) == 0 {
+			t.Fatalf("expected alice to have moves for whichever seat is to move, got %+v", sp)
+		}
+		sendWS(ctx, alice, "action", makeAction(t, cell))
+		sp = readState(t, ctx, alice)
+	}
+	board := stateMap(t, sp)["board"].([]any)
+	if board[0] != board[1] || board[4] == board[0] || board[4] == float64(0) {
+		t.Fatalf("expected both marks placed, got %v", board)
+	}
+
+	sendWS(ctx, alice, "undo", nil)
+	sp = readState(t, ctx, alice)
+	if board := stateMap(t, sp)["board"].([]any); board[1] != float64(0) || board[4] == float64(0) {
+		t.Fatalf("expected only the last move taken back, got %v", board)
+	}
+
+	sendWS(ctx, alice, "setup", setupPayload{Position: json.RawMessage(`{"board":"XX.OO....","turn":"X"}`)})
+	sp = readState(t, ctx, alice)
+	if stateMap(t, sp)["board"].([]any)[3] != float64(2) {
+		t.Fatalf("expected the set-up position, got %v", stateMap(t, sp))
+	}
+	sendWS(ctx, alice, "action", actionPayload{Action: makeAction(t, 5).Action, As: "bob"})
+	if msg := readError(t, ctx, alice); !strings.Contains(msg, "cannot move for bob") {
+		t.Fatalf("unexpected error %q", msg)
+	}
+
+	bob := wsConnect(t, env.ts, created.Code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	if msg := readError(t, ctx, bob); !strings.Contains(msg, "practice") {
+		t.Fatalf("expected bob kept out, got %q", msg)
+	}
+	watcher := joinWith(t, env, created.Code, joinPayload{PlayerID: "carol", Spectate: true})
+	defer watcher.Close(websocket.StatusNormalClosure, "")
+	if msg := readError(t, ctx, watcher); !strings.Contains(msg, "practice") {
+		t.Fatalf("expected spectators kept out, got %q", msg)
+	}
+}
+
+func TestPracticeUndoOnlyInPractice(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	sendWS(ctx, alice, "undo", nil)
+	if msg := readError(t, ctx, alice); !strings.Contains(msg, "practice") {
+		t.Fatalf("unexpected error %q", msg)
+	}
+}
+
+func TestPracticeFinishCountsForNothing(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	created := createPracticeViaAPI(t, env, `{"gameType":"tictactoe","playerId":"alice"}`)
+	alice := wsConnect(t, env.ts, created.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	var sp statePayload
+	for _, cell := range []int{0, 3, 1, 4, 2} {
+		sendWS(ctx, alice, "action", makeAction(t, cell))
+		sp = readState(t, ctx, alice)
+	}
+	if sp.SessionInfo.Status != "finished" || len(sp.Results) != 2 {
+		t.Fatalf("expected the match over, got %+v", sp)
+	}
+	if stats, _ := env.mgr.GameStats(t.Context(), "tictactoe"); stats.Matches != 0 {
+		t.Fatalf("expected practice kept out of the stats, got %+v", stats)
+	}
+
+	sendWS(ctx, alice, "undo", nil)
+	if sp = readState(t, ctx, alice); sp.SessionInfo.Status != "playing" || len(sp.ValidActions) == 0 {
+		t.Fatalf("expected the match back in play, got %+v", sp)
+	}
+}
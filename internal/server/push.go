@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"games/internal/event"
+	"games/internal/push"
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+type pushSubscribeRequest struct {
+	PlayerID     string            `json:"playerId"`
+	Subscription push.Subscription `json:"subscription"`
+}
+
+type pushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint"`
+}
+
+// SetPushKey enables Web Push subscriptions, advertising the VAPID public
+// key browsers must subscribe with.
+func (s *Server) SetPushKey(publicKey string) {
+	s.pushKey = publicKey
+}
+
+func (s *Server) handlePushKey(w http.ResponseWriter, r *http.Request) {
+	if s.pushKey == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "push notifications are not configured"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"publicKey": s.pushKey})
+}
+
+// handlePushSubscribe sends a player's notifications to a browser's push
+// service, when the player's own browser asks.
+func (s *Server) handlePushSubscribe(w http.ResponseWriter, r *http.Request) {
+	if s.pushKey == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "push notifications are not configured"})
+		return
+	}
+	var req pushSubscribeRequest
+	// Not decodeJSON: browsers add fields such as expirationTime to the
+	// subscription, and the set differs between them.
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	if err := push.CheckEndpoint(req.Subscription.Endpoint); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	err := s.manager.SavePushSubscription(r.Context(), storage.PushSubscriptionRow{
+		Endpoint: req.Subscription.Endpoint,
+		PlayerID: req.PlayerID,
+		P256dh:   req.Subscription.Keys.P256dh,
+		Auth:     req.Subscription.Keys.Auth,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"status": "subscribed"})
+}
+
+func (s *Server) handlePushUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	var req pushUnsubscribeRequest
+	if err := decodeJSON(r.Body, &req); err != nil || req.Endpoint == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "endpoint required"})
+		return
+	}
+	if err := s.manager.DeletePushSubscription(r.Context(), req.Endpoint); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "unsubscribed"})
+}
+
+// announceTurn tells the human players who must now act that it is their
+// turn. Bots, which are driven or notified separately, are skipped, as is
+// a practice session, whose player is at the board.
+func (s *Server) announceTurn(sess *session.Session) {
+	sess.RLock()
+	if sess.Match == nil || sess.Status == session.StatusErrored || sess.Practice {
+		sess.RUnlock()
+		return
+	}
+	info := sess.InfoLocked()
+	var toMove []string
+	err := session.Protect(func() error {
+		if sess.Match.IsOver() {
+			return nil
+		}
+		for _, pid := range info.Players {
+			p := sess.Players[pid]
+			if p.Strategy == nil && p.Webhook == "" && len(sess.Match.ValidActions(pid)) > 0 {
+				toMove = append(toMove, pid)
+			}
+		}
+		return nil
+	})
+	sess.RUnlock()
+	if s.failIfPanicked(context.Background(), sess, err) || len(toMove) == 0 {
+		return
+	}
+	s.manager.Events().Publish(event.Event{
+		Type:        event.YourTurn,
+		SessionCode: info.Code,
+		GameType:    info.GameType,
+		Players:     info.Players,
+		Recipients:  toMove,
+	})
+}
+
+// announceIfFull tells the host when the last seat in their lobby is taken.
+func (s *Server) announceIfFull(sess *session.Session) {
+	g, ok := s.registry.Get(sess.GameType)
+	if !ok {
+		return
+	}
+	info := sess.Info()
+	if info.Status != session.StatusWaiting || len(info.Players) < g.Info().MaxPlayers {
+		return
+	}
+	s.manager.Events().Publish(event.Event{
+		Type:        event.LobbyFull,
+		SessionCode: info.Code,
+		GameType:    info.GameType,
+		Players:     info.Players,
+		Recipients:  []string{info.HostID},
+	})
+}
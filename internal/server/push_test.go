@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"games/internal/event"
+)
+
+func TestPushEndpointsDisabledByDefault(t *testing.T) {
+	env := setupTestEnv(t)
+	resp, err := http.Get(env.ts.URL + "/api/push/key")
+	if err != nil {
+		t.Fatalf("GET key: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 without VAPID keys, got %d", resp.StatusCode)
+	}
+}
+
+func TestPushSubscribe(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetPushKey("test-key")
+	alice, aliceID := guestBrowser(t, env.ts)
+	mallory, _ := guestBrowser(t, env.ts)
+	subscribe := func(client *http.Client, playerID, endpoint, keys string) int {
+		t.Helper()
+		resp := browserPost(t, client, env.ts.URL+"/api/push/subscriptions",
+			`{"playerId":"`+playerID+`","subscription":{"endpoint":"`+endpoint+`"`+keys+`}}`)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	keys := `,"keys":{"p256dh":"k","auth":"a"}`
+
+	if got := subscribe(alice, aliceID, "https://push.example/1", ""); got != http.StatusBadRequest {
+		t.Fatalf("expected 400 without keys, got %d", got)
+	}
+	// Someone else's notifications cannot be redirected to another browser
+	if got := subscribe(mallory, aliceID, "https://push.example/evil", keys); got != http.StatusForbidden {
+		t.Fatalf("expected 403 subscribing for another player, got %d", got)
+	}
+	// Nor may the server be aimed at itself or its network
+	for _, endpoint := range []string{"http://push.example/1", "https://127.0.0.1/", "https://169.254.169.254/latest", "https://localhost:8080/admin"} {
+		if got := subscribe(alice, aliceID, endpoint, keys); got != http.StatusBadRequest {
+			t.Errorf("expected %s refused, got %d", endpoint, got)
+		}
+	}
+
+	if got := subscribe(alice, aliceID, "https://push.example/1", keys); got != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", got)
+	}
+	subs, _ := env.mgr.PushSubscriptions(t.Context(), aliceID)
+	if len(subs) != 1 || subs[0].Endpoint != "https://push.example/1" {
+		t.Fatalf("expected one subscription, got %+v", subs)
+	}
+}
+
+func TestTurnAndLobbyFullEvents(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+	events, unsubscribe := env.mgr.Events().Subscribe(16)
+	defer unsubscribe()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	bobConn := wsConnect(t, env.ts, code, "bob")
+	defer bobConn.CloseNow()
+	readState(t, ctx, bobConn)
+	resp := startViaAPI(t, env, code)
+	resp.Body.Close()
+
+	want := map[string][]string{event.LobbyFull: {"alice"}}
+	sess, _ := env.mgr.Get(code)
+	sess.RLock()
+	for _, pid := range []string{"alice", "bob"} {
+		if len(sess.Match.ValidActions(pid)) > 0 {
+			want[event.YourTurn] = []string{pid}
+		}
+	}
+	sess.RUnlock()
+
+	timeout := time.After(2 * time.Second)
+	for len(want) > 0 {
+		select {
+		case e := <-events:
+			recipients, ok := want[e.Type]
+			if !ok {
+				continue
+			}
+			if len(e.Recipients) != 1 || e.Recipients[0] != recipients[0] {
+				t.Fatalf("%s: recipients %v, want %v", e.Type, e.Recipients, recipients)
+			}
+			delete(want, e.Type)
+		case <-timeout:
+			t.Fatalf("missing events: %v", want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+
+	"games/internal/qr"
+)
+
+// SetBaseURL sets the public site URL used in generated links, without a
+// trailing slash. When unset, links are built from the request's host.
+func (s *Server) SetBaseURL(url string) {
+	s.baseURL = url
+}
+
+// siteURL is the public URL of the site as seen by the client of r, a
+// tenant's path included.
+func (s *Server) siteURL(r *http.Request) string {
+	if s.baseURL != "" {
+		return s.baseURL + s.prefix
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + s.prefix
+}
+
+// handleSessionQR returns a QR code of the session's share link, as SVG
+// unless ?format=png is given.
+func (s *Server) handleSessionQR(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if _, ok := s.manager.Get(code); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	c, err := qr.Encode(s.siteURL(r) + "/s/" + code)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	switch r.URL.Query().Get("format") {
+	case "", "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(c.SVG())
+	case "png":
+		data, err := c.PNG(8)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be svg or png"})
+	}
+}
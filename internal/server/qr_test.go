@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSessionQR(t *testing.T) {
+	env := setupTestEnv(t)
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+
+	resp, err := http.Get(env.ts.URL + "/api/sessions/" + sess.Code + "/qr")
+	if err != nil {
+		t.Fatalf("GET qr: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("expected svg, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.HasPrefix(string(body), "<svg") {
+		t.Fatalf("unexpected body: %.60s", body)
+	}
+
+	resp, err = http.Get(env.ts.URL + "/api/sessions/" + sess.Code + "/qr?format=png")
+	if err != nil {
+		t.Fatalf("GET qr png: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if _, err := png.Decode(bytes.NewReader(body)); err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+
+	for path, want := range map[string]int{
+		"/api/sessions/" + sess.Code + "/qr?format=gif": http.StatusBadRequest,
+		"/api/sessions/NOPE/qr":                         http.StatusNotFound,
+	} {
+		resp, err := http.Get(env.ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	env := setupTestEnv(t)
+	req, _ := http.NewRequest("GET", "http://games.local:8080/api/sessions/x/qr", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if got := env.srv.siteURL(req); got != "https://games.local:8080" {
+		t.Fatalf("unexpected site URL %q", got)
+	}
+	env.srv.SetBaseURL("https://play.example.com")
+	if got := env.srv.siteURL(req); got != "https://play.example.com" {
+		t.Fatalf("expected configured base URL, got %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"games/internal/game"
+	"games/internal/storage"
+)
+
+// retireInterval is how often a replaced or unregistered game is checked
+// for matches still playing on it.
+const retireInterval = 30 * time.Second
+
+// SetGameLoader lets admins reload the games loaded at runtime, such as
+// plugins and scripts, without a restart. load returns every such game as
+// its files are now; games it returns that are registered already replace
+// them, and the rest are added.
+func (s *Server) SetGameLoader(load func() ([]game.Game, error)) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.loadGames = load
+}
+
+type reloadResponse struct {
+	Added    []string `json:"added"`
+	Replaced []string `json:"replaced"`
+}
+
+// handleAdminReloadGames loads the games again, putting new versions in
+// place for new sessions. Matches under way finish on the version they
+// started with, which is shut down once none is left.
+func (s *Server) handleAdminReloadGames(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.loadGames == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no games are loaded at runtime"})
+		return
+	}
+	games, err := s.loadGames()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	resp := reloadResponse{Added: []string{}, Replaced: []string{}}
+	for _, g := range games {
+		name := g.Info().Name
+		old, err := s.registry.Replace(g)
+		if err != nil {
+			s.registry.Register(g)
+			resp.Added = append(resp.Added, name)
+			continue
+		}
+		resp.Replaced = append(resp.Replaced, name)
+		s.manager.Upgrade(name)
+		go s.manager.Retire(context.Background(), old, retireInterval)
+	}
+	entry.Detail = fmt.Sprintf("added %s; replaced %s", strings.Join(resp.Added, ","), strings.Join(resp.Replaced, ","))
+	s.cache.invalidate(gamesCacheTag)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAdminUnregisterGame removes a game. No new sessions of it may
+// start; matches under way finish first, and it is shut down after.
+func (s *Server) handleAdminUnregisterGame(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	name := r.PathValue("name")
+	entry.Detail = name
+	old, err := s.registry.Unregister(name)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "game not found"})
+		return
+	}
+	go s.manager.Retire(context.Background(), old, retireInterval)
+	s.cache.invalidate(gamesCacheTag)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "unregistered"})
+}
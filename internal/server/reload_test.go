@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"games/internal/game"
+)
+
+func TestAdminReloadGames(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	names := func() []string {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + "/api/games")
+		if err != nil {
+			t.Fatalf("list games: %v", err)
+		}
+		defer resp.Body.Close()
+		var games []game.GameInfo
+		json.NewDecoder(resp.Body).Decode(&games)
+		var names []string
+		for _, g := range games {
+			names = append(names, g.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+	reload := func() (int, reloadResponse) {
+		t.Helper()
+		resp := adminRequest(t, "POST", env.ts.URL+"/api/admin/games/reload", "secret", "")
+		defer resp.Body.Close()
+		var body reloadResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	if status, _ := reload(); status != http.StatusNotFound {
+		t.Fatalf("expected reload refused without a loader, got %d", status)
+	}
+	var loadErr error
+	env.srv.SetGameLoader(func() ([]game.Game, error) {
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return []game.Game{benchGame{}}, nil
+	})
+	names() // cached, so a reload must invalidate the list
+	if status, body := reload(); status != http.StatusOK || !slices.Equal(body.Added, []string{"bench"}) {
+		t.Fatalf("expected bench added, got %d %+v", status, body)
+	}
+	if got := names(); !slices.Equal(got, []string{"bench", "tictactoe"}) {
+		t.Fatalf("expected the added game listed, got %v", got)
+	}
+	if status, body := reload(); status != http.StatusOK || !slices.Equal(body.Replaced, []string{"bench"}) || len(body.Added) != 0 {
+		t.Fatalf("expected bench replaced, got %d %+v", status, body)
+	}
+	loadErr = errors.New("bench.star: syntax error")
+	if status, _ := reload(); status != http.StatusInternalServerError {
+		t.Fatalf("expected a failed load reported, got %d", status)
+	}
+	if _, ok := env.srv.registry.Get("bench"); !ok {
+		t.Fatal("expected a failed load to leave the games as they were")
+	}
+
+	resp := adminRequest(t, "DELETE", env.ts.URL+"/api/admin/games/bench", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unregister: %d", resp.StatusCode)
+	}
+	if got := names(); !slices.Equal(got, []string{"tictactoe"}) {
+		t.Fatalf("expected the unregistered game gone, got %v", got)
+	}
+	resp = postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"bench","playerId":"alice"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected sessions of an unregistered game refused, got %d", resp.StatusCode)
+	}
+	resp = adminRequest(t, "DELETE", env.ts.URL+"/api/admin/games/bench", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected unregistering twice not found, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/session"
+)
+
+var (
+	updateTranscripts = flag.Bool("update", false, "rewrite the server messages of the transcripts in testdata/ws to what the server sends now")
+	replayRealtime    = flag.Bool("replay.realtime", false, "replay transcripts at the pace they were recorded")
+)
+
+// volatileKeys are fields whose values differ from run to run, such as
+// clocks and random tokens. Replays compare only that they are present.
+var volatileKeys = map[string]bool{
+	"serverTime": true, "createdAt": true, "startedAt": true, "finishedAt": true,
+	"lastActivity": true, "expiresAt": true, "at": true, "durationMs": true,
+	"token": true, "seed": true, "salt": true, "hash": true,
+}
+
+// wsTranscript is a recording read back: the session it starts from and
+// the frames that follow.
+type wsTranscript struct {
+	Header wsTranscriptHeader
+	Frames []wsFrame
+}
+
+func readTranscript(path string) (*wsTranscript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var tr wsTranscript
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, maxWSMessageBytes*2)
+	if !sc.Scan() {
+		return nil, errors.New("missing header")
+	}
+	if err := json.Unmarshal(sc.Bytes(), &tr.Header); err != nil {
+		return nil, err
+	}
+	for sc.Scan() {
+		var fr wsFrame
+		if err := json.Unmarshal(sc.Bytes(), &fr); err != nil {
+			return nil, err
+		}
+		tr.Frames = append(tr.Frames, fr)
+	}
+	return &tr, sc.Err()
+}
+
+func transcriptFrames(tr *wsTranscript) []wsFrame {
+	if tr == nil {
+		return nil
+	}
+	return tr.Frames
+}
+
+func (tr *wsTranscript) write(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.Encode(tr.Header)
+	for _, fr := range tr.Frames {
+		enc.Encode(fr)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// normalizeWS puts a server message in the form replays compare: volatile
+// values replaced and the session code, which differs between the
+// recording and the replay, made a placeholder.
+func normalizeWS(msg []byte, code string) (string, error) {
+	var v any
+	if err := json.Unmarshal(msg, &v); err != nil {
+		return "", err
+	}
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, val := range v {
+				if volatileKeys[k] && val != nil {
+					v[k] = "<volatile>"
+				} else {
+					v[k] = walk(val)
+				}
+			}
+		case []any:
+			for i := range v {
+				v[i] = walk(v[i])
+			}
+		case string:
+			return strings.ReplaceAll(v, code, "<code>")
+		}
+		return v
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(walk(v))
+	return buf.String(), err
+}
+
+// wsDiff shows where two normalized messages part, with a few lines
+// around it.
+func wsDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	i := 0
+	for i < len(w) && i < len(g) && w[i] == g[i] {
+		i++
+	}
+	from := max(i-3, 0)
+	return "expected\n" + strings.Join(w[from:min(i+4, len(w))], "\n") +
+		"\ngot\n" + strings.Join(g[from:min(i+4, len(g))], "\n")
+}
+
+func wsMessageType(msg []byte) string {
+	var m WSMessage
+	json.Unmarshal(msg, &m)
+	return m.Type
+}
+
+// replayTranscript plays a transcript's client messages against a new
+// session like the one it recorded, checking the server sends what it
+// sent then. A client message waits for every server message recorded
+// ahead of it, so messages causally ordered then are now. With -update it
+// returns the transcript with the server messages that changed replaced
+// by those received.
+func replayTranscript(t *testing.T, tr *wsTranscript) *wsTranscript {
+	t.Helper()
+	env := setupTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sess, err := env.mgr.CreateWithOptions(ctx, tr.Header.Game, tr.Header.Options)
+	if err != nil {
+		t.Fatalf("create %s session: %v", tr.Header.Game, err)
+	}
+	oldCode, newCode := tr.Header.Code, sess.Code
+
+	got := &wsTranscript{Header: tr.Header}
+	conns := make(map[string]*websocket.Conn)
+	defer func() {
+		for _, conn := range conns {
+			conn.CloseNow()
+		}
+	}()
+	// A connection must receive nothing more than was recorded before it
+	// closes, or before the transcript ends
+	quiet := func(name string, conn *websocket.Conn) {
+		readCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		if _, data, err := conn.Read(readCtx); err == nil {
+			t.Errorf("%s received a message the transcript does not have: %s", name, data)
+		}
+	}
+	start := time.Now()
+	for i, fr := range tr.Frames {
+		got.Frames = append(got.Frames, fr)
+		line := i + 2 // the header is line 1
+		conn, ok := conns[fr.Conn]
+		switch {
+		case fr.Closed:
+			if ok {
+				quiet(fr.Conn, conn)
+				conn.CloseNow()
+				delete(conns, fr.Conn)
+			}
+		case fr.From == "client":
+			if *replayRealtime {
+				time.Sleep(time.Until(start.Add(time.Duration(fr.Ms) * time.Millisecond)))
+			}
+			if !ok {
+				url := wsURL(env.ts, newCode)
+				if fr.Path != "" {
+					url = strings.TrimSuffix(url, "ws") + strings.ReplaceAll(fr.Path, oldCode, newCode)
+				}
+				if conn, _, err = websocket.Dial(ctx, url, nil); err != nil {
+					t.Fatalf("line %d: dial %s: %v", line, fr.Conn, err)
+				}
+				conns[fr.Conn] = conn
+			}
+			msg := []byte(fr.Text)
+			if fr.Message != nil {
+				msg = bytes.ReplaceAll(fr.Message, []byte(oldCode), []byte(newCode))
+			}
+			if err := conn.Write(ctx, websocket.MessageText, msg); err != nil {
+				t.Fatalf("line %d: %s send: %v", line, fr.Conn, err)
+			}
+		case fr.From == "server":
+			if !ok {
+				t.Fatalf("line %d: server message on %s, which is not open", line, fr.Conn)
+			}
+			readCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			_, data, err := conn.Read(readCtx)
+			cancel()
+			if err != nil {
+				t.Fatalf("line %d: %s expected\n%s\nread: %v", line, fr.Conn, fr.Message, err)
+			}
+			want, err := normalizeWS(fr.Message, oldCode)
+			if err != nil {
+				t.Fatalf("line %d: recorded message: %v", line, err)
+			}
+			have, err := normalizeWS(data, newCode)
+			if err != nil {
+				t.Fatalf("line %d: %s received a message that is not JSON: %s", line, fr.Conn, data)
+			}
+			switch {
+			case have == want:
+			case *updateTranscripts:
+				got.Frames[i].Message = bytes.ReplaceAll(data, []byte(newCode), []byte(oldCode))
+			default:
+				t.Fatalf("line %d: %s received a different %s:\n%s", line, fr.Conn, wsMessageType(fr.Message), wsDiff(want, have))
+			}
+		}
+	}
+
+	for name, conn := range conns {
+		quiet(name, conn)
+	}
+	return got
+}
+
+// TestWSReplay replays the transcripts in testdata/ws, catching changes to
+// what the server sends. A transcript comes from a server run with
+// WS_RECORD_DIR set; when a change to the protocol is meant, go test -run
+// TestWSReplay -update rewrites the server messages.
+func TestWSReplay(t *testing.T) {
+	paths, _ := filepath.Glob(filepath.Join("testdata", "ws", "*.jsonl"))
+	if len(paths) == 0 {
+		t.Fatal("no transcripts in testdata/ws")
+	}
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".jsonl"), func(t *testing.T) {
+			tr, err := readTranscript(path)
+			if err != nil {
+				t.Fatalf("read %s: %v", path, err)
+			}
+			got := replayTranscript(t, tr)
+			if *updateTranscripts && !t.Failed() {
+				if err := got.write(path); err != nil {
+					t.Fatalf("write %s: %v", path, err)
+				}
+			}
+		})
+	}
+}
+
+// TestWSRecording records a short game and replays the transcript it
+// makes.
+func TestWSRecording(t *testing.T) {
+	env := setupTestEnv(t)
+	dir := t.TempDir()
+	env.srv.SetWSRecordDir(dir)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, sess.Code, "bob")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	alice.Write(ctx, websocket.MessageText, []byte("not json"))
+	readError(t, ctx, alice)
+	alice.Close(websocket.StatusNormalClosure, "")
+	bob.Close(websocket.StatusNormalClosure, "")
+
+	// The server finishes with a connection after the client does
+	path := filepath.Join(dir, sess.Code+".jsonl")
+	var tr *wsTranscript
+	var texts, closed int
+	for range 50 {
+		tr, _ = readTranscript(path)
+		texts, closed = 0, 0
+		for _, fr := range transcriptFrames(tr) {
+			if fr.Text == "not json" {
+				texts++
+			}
+			if fr.Closed {
+				closed++
+			}
+		}
+		if closed == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tr == nil {
+		t.Fatalf("no transcript at %s", path)
+	}
+	if tr.Header.Game != "tictactoe" || tr.Header.Status != session.StatusWaiting {
+		t.Errorf("expected a waiting tictactoe header, got %+v", tr.Header)
+	}
+	if texts != 1 || closed != 2 {
+		t.Errorf("expected the text message and both closes recorded, got %d and %d in %+v", texts, closed, tr.Frames)
+	}
+	replayTranscript(t, tr)
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+// Each player may report a few problems in a session at once, then one a
+// minute.
+const (
+	reportRate  = 1.0 / 60
+	reportBurst = 3
+)
+
+type reportRequest struct {
+	Note string `json:"note"`
+}
+
+type reportsResponse struct {
+	Reports []session.Report `json:"reports"`
+}
+
+// serverVersion is the build serving the site: the commit from the
+// binary's build info, when it has one, and the version of the web
+// assets.
+func (s *Server) serverVersion() session.ServerVersion {
+	v := session.ServerVersion{Go: runtime.Version(), Assets: s.static.version}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				v.Revision = setting.Value
+			case "vcs.modified":
+				v.Modified = setting.Value == "true"
+			case "vcs.time":
+				v.BuildTime, _ = time.Parse(time.RFC3339, setting.Value)
+			}
+		}
+	}
+	return v
+}
+
+// handleReport stores a player's report of a problem in a session, such
+// as a board that stopped responding, with the match's state, the latest
+// of the session's timeline and the server's version, for admins to read
+// back from /api/admin/reports. Only the session's players and spectators
+// may report, and each is limited once known to be one.
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	sess, ok := s.manager.Get(code)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	var req reportRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	reporter, ok := s.reporter(r, sess)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "a seat token or guest cookie is required"})
+		return
+	}
+	if !sess.Involves(reporter) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": session.ErrNotInSession.Error()})
+		return
+	}
+	if !s.reportLimiter.Allow(code + "/" + reporter) {
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+		return
+	}
+	id, err := s.manager.SubmitReport(r.Context(), sess, reporter, req.Note, s.serverVersion())
+	switch {
+	case errors.Is(err, session.ErrNotInSession):
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusCreated, map[string]int64{"id": id})
+	}
+}
+
+// reporter identifies who reports a problem in sess: the player whose
+// seat token r carries, or else the guest whose cookie it carries, who
+// may be watching.
+func (s *Server) reporter(r *http.Request, sess *session.Session) (string, bool) {
+	if id, ok := sess.SeatWithToken(bearerToken(r)); ok {
+		return id, true
+	}
+	return s.guestID(r)
+}
+
+// handleAdminReports lists problem reports, newest first and without
+// their diagnostics, filtered by ?session= and ?game=, up to ?limit= of
+// them (100 by default).
+func (s *Server) handleAdminReports(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	q := r.URL.Query()
+	f := storage.ReportFilter{
+		SessionCode: q.Get("session"),
+		GameType:    q.Get("game"),
+		Limit:       100,
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		f.Limit = n
+	}
+	entry.SessionCode = f.SessionCode
+	entry.Detail = r.URL.RawQuery
+	reports, err := s.manager.Reports(r.Context(), f)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, reportsResponse{Reports: reports})
+}
+
+// handleAdminReport returns a problem report with its diagnostics.
+func (s *Server) handleAdminReport(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid report id"})
+		return
+	}
+	entry.Detail = r.PathValue("id")
+	report, err := s.manager.GetReport(r.Context(), id)
+	if errors.Is(err, session.ErrReportNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	entry.SessionCode = report.SessionCode
+	entry.PlayerID = report.PlayerID
+	writeJSON(w, http.StatusOK, report)
+}
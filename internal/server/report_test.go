@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"games/internal/session"
+
+	"nhooyr.io/websocket"
+)
+
+func TestReport(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	code := sess.Code
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	url := env.ts.URL + "/api/sessions/" + code + "/report"
+	post := func(token, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST report: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	aliceToken, bobToken := seatToken(env.ts, code, "alice"), seatToken(env.ts, code, "bob")
+	if resp := postJSON(t, env.ts.URL+"/api/sessions/ZZZZ/report", `{"note":"stuck"}`); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown session, got %d", resp.StatusCode)
+	}
+	// A report is from whoever the seat token or guest cookie says
+	for _, token := range []string{"", "not-a-token"} {
+		if resp := post(token, `{"note":"stuck"}`); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401 without a seat token or guest cookie, got %d", resp.StatusCode)
+		}
+	}
+	// Guests outside the session are refused before they are counted
+	stranger, _ := guestBrowser(t, env.ts)
+	for range reportBurst + 1 {
+		resp := browserPost(t, stranger, url, `{"note":"stuck"}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for someone outside the session, got %d", resp.StatusCode)
+		}
+	}
+	if resp := post(aliceToken, `{"note":""}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a note, got %d", resp.StatusCode)
+	}
+	resp := post(aliceToken, `{"note":"the board got stuck"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the report stored, got %d", resp.StatusCode)
+	}
+	// The empty note counted too, so the next report is the last of the burst
+	post(aliceToken, `{"note":"still stuck"}`)
+	if resp := post(aliceToken, `{"note":"really stuck"}`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected a burst of reports limited, got %d", resp.StatusCode)
+	}
+	if resp := post(bobToken, `{"note":"me too"}`); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected bob's own allowance, got %d", resp.StatusCode)
+	}
+	// A spectator is known by their guest cookie
+	watcher, watcherID := guestBrowser(t, env.ts)
+	sess.AddSpectator(watcherID, make(chan []byte, 1))
+	resp = browserPost(t, watcher, url, `{"note":"looks stuck from here"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the spectator's report stored, got %d", resp.StatusCode)
+	}
+
+	resp = adminRequest(t, http.MethodGet, env.ts.URL+"/api/admin/reports?session="+code, "secret", "")
+	var list reportsResponse
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(list.Reports) != 4 || list.Reports[0].PlayerID != watcherID || list.Reports[1].PlayerID != "bob" || list.Reports[3].Diagnostics != nil {
+		t.Fatalf("expected four reports newest first, got %d %+v", resp.StatusCode, list)
+	}
+	first := list.Reports[3]
+
+	resp = adminRequest(t, http.MethodGet, fmt.Sprintf("%s/api/admin/reports/%d", env.ts.URL, first.ID), "secret", "")
+	var report session.Report
+	json.NewDecoder(resp.Body).Decode(&report)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || report.Note != "the board got stuck" || report.Diagnostics == nil {
+		t.Fatalf("expected alice's report with diagnostics, got %d %+v", resp.StatusCode, report)
+	}
+	if report.Server.Go != runtime.Version() || report.Server.Assets != env.srv.static.version {
+		t.Fatalf("expected the server's version, got %+v", report.Server)
+	}
+	var state struct{ Board [9]int }
+	json.Unmarshal(report.State, &state)
+	if state.Board[4] == 0 || report.Transcript == nil || len(report.Transcript.Moves) != 1 {
+		t.Fatalf("expected the match after its first move, got %s %+v", report.State, report.Transcript)
+	}
+	types := map[string]bool{}
+	for _, e := range report.Timeline {
+		types[e.Type] = true
+	}
+	if !types[session.TimelineJoined] || !types[session.TimelineStarted] || !types[session.TimelineMoved] {
+		t.Fatalf("expected the session's timeline, got %+v", report.Timeline)
+	}
+
+	if resp := adminRequest(t, http.MethodGet, env.ts.URL+"/api/admin/reports/999", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown report, got %d", resp.StatusCode)
+	}
+	if resp := adminRequest(t, http.MethodGet, env.ts.URL+"/api/admin/reports", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected admins only, got %d", resp.StatusCode)
+	}
+}
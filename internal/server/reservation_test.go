@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSReservedSeat(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+
+	sendWS(ctx, alice, "reserve", reservePayload{PlayerIDs: []string{"bob"}})
+	sp := readState(t, ctx, alice)
+	if sp.SessionInfo.OpenSeats != 0 || len(sp.SessionInfo.Reservations) != 1 {
+		t.Fatalf("expected bob's seat reserved, got %+v", sp.SessionInfo)
+	}
+
+	// The lobby sees no open seats and not who was invited.
+	resp, err := http.Get(env.ts.URL + "/api/feed/snapshot")
+	if err != nil {
+		t.Fatalf("GET snapshot: %v", err)
+	}
+	var snap feedSnapshot
+	json.NewDecoder(resp.Body).Decode(&snap)
+	resp.Body.Close()
+	if len(snap.Sessions) != 1 || snap.Sessions[0].OpenSeats != 0 || snap.Sessions[0].Reservations != nil {
+		t.Fatalf("unexpected lobby listing: %+v", snap.Sessions)
+	}
+
+	mallory := wsConnect(t, env.ts, code, "mallory")
+	defer mallory.CloseNow()
+	if msg := readError(t, ctx, mallory); !strings.Contains(msg, "reserved") {
+		t.Fatalf("expected reserved-seat error, got %q", msg)
+	}
+
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	if sp := readState(t, ctx, bob); len(sp.SessionInfo.Players) != 2 {
+		t.Fatalf("expected bob seated, got %+v", sp.SessionInfo)
+	}
+}
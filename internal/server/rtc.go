@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"games/internal/session"
+)
+
+// maxRTCSignalBytes bounds the data of one signal; a session description
+// with audio runs to a few kilobytes.
+const maxRTCSignalBytes = 32 << 10
+
+// rtcSignalKinds are the signals players may relay to each other.
+var rtcSignalKinds = map[string]bool{
+	"offer":     true, // a session description opening or renegotiating a call
+	"answer":    true, // the description accepting an offer
+	"candidate": true, // an ICE candidate
+	"hangup":    true, // the sender left the call
+}
+
+// ICEServer is a STUN or TURN server the browsers of a call may use to
+// reach each other, as RTCPeerConnection takes it.
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// rtcSignalPayload is a signal for one other player in the session. The
+// server passes Data on untouched.
+type rtcSignalPayload struct {
+	To   string          `json:"to"`
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// rtcSignalMessagePayload is a signal relayed from another player.
+type rtcSignalMessagePayload struct {
+	From string          `json:"from"`
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// SetICEServers sets the STUN and TURN servers the pages hand to
+// RTCPeerConnection for voice chat. Without any, browsers connect only
+// where they can reach each other directly.
+func (s *Server) SetICEServers(servers []ICEServer) error {
+	for _, ice := range servers {
+		if len(ice.URLs) == 0 {
+			return errors.New("ice server: no urls")
+		}
+		for _, u := range ice.URLs {
+			scheme, _, _ := strings.Cut(u, ":")
+			switch scheme {
+			case "stun", "stuns":
+			case "turn", "turns":
+				if ice.Username == "" || ice.Credential == "" {
+					return fmt.Errorf("ice server %q: turn needs a username and credential", u)
+				}
+			default:
+				return fmt.Errorf("ice server %q: want a stun:, turn: or turns: url", u)
+			}
+		}
+	}
+	s.iceServers = servers
+	return nil
+}
+
+// handleRTCConfig returns the ICE servers for the pages' voice chat.
+func (s *Server) handleRTCConfig(w http.ResponseWriter, r *http.Request) {
+	servers := s.iceServers
+	if servers == nil {
+		servers = []ICEServer{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"iceServers": servers})
+}
+
+// relayRTCSignal passes a WebRTC signal from one player to every
+// connection of another, so their browsers can set up a call without a
+// signaling server of their own. Spectators cannot call.
+func (s *Server) relayRTCSignal(sess *session.Session, from string, send chan []byte, rp rtcSignalPayload) {
+	switch {
+	case !rtcSignalKinds[rp.Kind]:
+		sendWSMsg(send, "error", errorPayload{Message: fmt.Sprintf("unknown rtc_signal kind %q", rp.Kind)})
+	case len(rp.Data) > maxRTCSignalBytes:
+		sendWSMsg(send, "error", errorPayload{Message: "rtc_signal data too large"})
+	case rp.To == from:
+		sendWSMsg(send, "error", errorPayload{Message: "cannot signal yourself"})
+	case !sess.Connected(rp.To):
+		sendWSMsg(send, "error", errorPayload{Message: fmt.Sprintf("player %q is not connected", rp.To)})
+	default:
+		msg := encodeWSMsg("rtc_signal", rtcSignalMessagePayload{From: from, Kind: rp.Kind, Data: rp.Data})
+		for _, to := range sess.PlayerSends(rp.To) {
+			sendEncoded(to, msg)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestRTCSignal(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	code := sess.Code
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	wall := joinWith(t, env, code, joinPayload{PlayerID: "wall", Spectate: true})
+	defer wall.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, wall)
+
+	sendWS(ctx, alice, "rtc_signal", rtcSignalPayload{To: "bob", Kind: "offer", Data: json.RawMessage(`{"type":"offer","sdp":"v=0"}`)})
+	msg := wsRead(ctx, t, bob)
+	if msg.Type != "rtc_signal" {
+		t.Fatalf("expected rtc_signal, got %s", msg.Type)
+	}
+	var got rtcSignalMessagePayload
+	json.Unmarshal(msg.Payload, &got)
+	if got.From != "alice" || got.Kind != "offer" || string(got.Data) != `{"type":"offer","sdp":"v=0"}` {
+		t.Fatalf("unexpected signal %+v", got)
+	}
+
+	sendWS(ctx, bob, "rtc_signal", rtcSignalPayload{To: "alice", Kind: "hangup"})
+	if msg := wsRead(ctx, t, alice); msg.Type != "rtc_signal" {
+		t.Fatalf("expected the hangup relayed, got %s", msg.Type)
+	}
+
+	for _, c := range []struct {
+		payload rtcSignalPayload
+		want    string
+	}{
+		{rtcSignalPayload{To: "bob", Kind: "ring"}, "unknown rtc_signal kind"},
+		{rtcSignalPayload{To: "alice", Kind: "offer"}, "cannot signal yourself"},
+		{rtcSignalPayload{To: "carol", Kind: "offer"}, "not connected"},
+		{rtcSignalPayload{To: "wall", Kind: "offer"}, "not connected"},
+		{rtcSignalPayload{To: "bob", Kind: "candidate", Data: json.RawMessage(`"` + strings.Repeat("a", maxRTCSignalBytes) + `"`)}, "too large"},
+	} {
+		sendWS(ctx, alice, "rtc_signal", c.payload)
+		msg := wsRead(ctx, t, alice)
+		var ep errorPayload
+		json.Unmarshal(msg.Payload, &ep)
+		if msg.Type != "error" || !strings.Contains(ep.Message, c.want) {
+			t.Fatalf("%+v: expected an error with %q, got %s %q", c.payload, c.want, msg.Type, ep.Message)
+		}
+	}
+
+	// Spectators cannot call
+	sendWS(ctx, wall, "rtc_signal", rtcSignalPayload{To: "alice", Kind: "offer"})
+	if msg := wsRead(ctx, t, wall); msg.Type != "error" {
+		t.Fatalf("expected a spectator's signal refused, got %s", msg.Type)
+	}
+}
+
+func TestRTCConfig(t *testing.T) {
+	env := setupTestEnv(t)
+	getConfig := func() string {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + "/api/rtc/config")
+		if err != nil {
+			t.Fatalf("GET rtc config: %v", err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			ICEServers json.RawMessage `json:"iceServers"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return string(body.ICEServers)
+	}
+	if got := getConfig(); got != "[]" {
+		t.Fatalf("expected no ICE servers, got %s", got)
+	}
+
+	for _, bad := range [][]ICEServer{
+		{{}},
+		{{URLs: []string{"https://stun.example.com"}}},
+		{{URLs: []string{"turn:turn.example.com:3478"}}},
+	} {
+		if err := env.srv.SetICEServers(bad); err == nil {
+			t.Fatalf("expected %+v refused", bad)
+		}
+	}
+	servers := []ICEServer{
+		{URLs: []string{"stun:stun.example.com:3478"}},
+		{URLs: []string{"turn:turn.example.com:3478", "turns:turn.example.com:5349"}, Username: "games", Credential: "secret"},
+	}
+	if err := env.srv.SetICEServers(servers); err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"urls":["stun:stun.example.com:3478"]},{"urls":["turn:turn.example.com:3478","turns:turn.example.com:5349"],"username":"games","credential":"secret"}]`
+	if got := getConfig(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"games/internal/session"
+)
+
+// seasonsResponse lists the season under way, if the leaderboards run in
+// seasons, and the finished seasons that can be browsed.
+type seasonsResponse struct {
+	Current *session.Season  `json:"current,omitempty"`
+	Past    []session.Season `json:"past"`
+}
+
+func (s *Server) handleListSeasons(w http.ResponseWriter, r *http.Request) {
+	past, err := s.manager.Seasons(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if past == nil {
+		past = []session.Season{}
+	}
+	writeJSON(w, http.StatusOK, seasonsResponse{Current: s.manager.CurrentSeason(), Past: past})
+}
+
+func (s *Server) handleGetSeason(w http.ResponseWriter, r *http.Request) {
+	snap, err := s.manager.SeasonSnapshot(r.Context(), r.PathValue("name"))
+	if errors.Is(err, session.ErrSeasonNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+func TestSeasonEndpoints(t *testing.T) {
+	env := setupTestEnv(t)
+	now := time.Now()
+	last := session.SeasonMonth.SeasonAt(now).Start.Add(-time.Hour)
+	env.store.ArchiveMatch(t.Context(), storage.ArchivedMatchRow{SessionCode: "old", GameType: "tictactoe", Moves: 7, StartedAt: last.Add(-time.Minute), FinishedAt: last})
+	env.mgr.SetSeasonLength(session.SeasonMonth)
+	if _, err := env.mgr.RolloverSeason(t.Context(), now); err != nil {
+		t.Fatalf("rollover: %v", err)
+	}
+
+	resp, err := http.Get(env.ts.URL + "/api/seasons")
+	if err != nil {
+		t.Fatalf("GET seasons: %v", err)
+	}
+	var list seasonsResponse
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	previous := session.SeasonMonth.SeasonAt(last)
+	if list.Current == nil || list.Current.Name != session.SeasonMonth.SeasonAt(now).Name || len(list.Past) != 1 || list.Past[0].Name != previous.Name {
+		t.Fatalf("unexpected seasons %+v", list)
+	}
+
+	resp, err = http.Get(env.ts.URL + "/api/seasons/" + previous.Name)
+	if err != nil {
+		t.Fatalf("GET season: %v", err)
+	}
+	var snap session.SeasonSnapshot
+	json.NewDecoder(resp.Body).Decode(&snap)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || snap.Games["tictactoe"].Stats.Matches != 1 {
+		t.Fatalf("expected the old match in the season, got %d %+v", resp.StatusCode, snap)
+	}
+
+	resp, err = http.Get(env.ts.URL + "/api/seasons/1999-01")
+	if err != nil {
+		t.Fatalf("GET unknown season: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown season, got %d", resp.StatusCode)
+	}
+}
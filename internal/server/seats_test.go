@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"games/internal/session"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSChooseSeat(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	sendWS(ctx, bob, "choose_seat", chooseSeatPayload{Seat: 0, PlayerID: "alice"})
+	if msg := readError(t, ctx, bob); !strings.Contains(msg, "only the host") {
+		t.Fatalf("expected host-only error, got %q", msg)
+	}
+
+	// The host seats bob first.
+	sendWS(ctx, alice, "choose_seat", chooseSeatPayload{Seat: 0, PlayerID: "bob"})
+	if sp := readState(t, ctx, alice); sp.SessionInfo.Seats[0] != "bob" {
+		t.Fatalf("expected bob in seat 0, got %v", sp.SessionInfo.Seats)
+	}
+	readState(t, ctx, bob)
+
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	if sp := readState(t, ctx, bob); len(sp.ValidActions) == 0 {
+		t.Fatal("expected bob to move first")
+	}
+}
+
+func TestCreateSessionTurnOrder(t *testing.T) {
+	env := setupTestEnv(t)
+
+	resp := postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"alice","turnOrder":"sideways"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown turn order, got %d", resp.StatusCode)
+	}
+
+	resp = postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"alice","turnOrder":"random"}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	sess, _ := env.mgr.Get(created.Code)
+	if got := sess.Info().TurnOrder; got != session.TurnOrderRandom {
+		t.Fatalf("expected random turn order, got %q", got)
+	}
+}
+
+func TestWSTurnOrder(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	if sp := readState(t, ctx, alice); sp.SessionInfo.TurnOrder != session.TurnOrderJoin {
+		t.Fatalf("expected join order by default, got %q", sp.SessionInfo.TurnOrder)
+	}
+
+	sendWS(ctx, alice, "turn_order", turnOrderPayload{TurnOrder: "random"})
+	if sp := readState(t, ctx, alice); sp.SessionInfo.TurnOrder != session.TurnOrderRandom {
+		t.Fatalf("expected random turn order, got %q", sp.SessionInfo.TurnOrder)
+	}
+	sendWS(ctx, alice, "turn_order", turnOrderPayload{TurnOrder: "sideways"})
+	if msg := readError(t, ctx, alice); !strings.Contains(msg, "unknown turn order") {
+		t.Fatalf("expected unknown turn order error, got %q", msg)
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+)
+
+// sections is the set of statePayload fields a connection subscribed to,
+// one bit per field.
+type sections uint8
+
+const (
+	sectionState sections = 1 << iota
+	sectionValidActions
+	sectionSessionInfo
+	sectionResults
+	sectionSummary
+	sectionScoreboard
+	sectionFairness
+
+	allSections sections = 1<<iota - 1
+)
+
+// sectionNames maps the names clients subscribe with, the fields' JSON
+// keys, to sections.
+var sectionNames = map[string]sections{
+	"state":        sectionState,
+	"validActions": sectionValidActions,
+	"sessionInfo":  sectionSessionInfo,
+	"results":      sectionResults,
+	"summary":      sectionSummary,
+	"scoreboard":   sectionScoreboard,
+	"fairness":     sectionFairness,
+}
+
+// parseSections reads the sections a join asks for. None means all of
+// them.
+func parseSections(names []string) (sections, error) {
+	if len(names) == 0 {
+		return allSections, nil
+	}
+	var sec sections
+	for _, name := range names {
+		s, ok := sectionNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown state section: %s", name)
+		}
+		sec |= s
+	}
+	return sec, nil
+}
+
+// subscriptions holds the sections of connections that asked for fewer
+// than all, by send channel.
+type subscriptions struct {
+	m sync.Map
+}
+
+// subscribe records the sections send wants, returning a function that
+// forgets them.
+func (subs *subscriptions) subscribe(send chan []byte, sec sections) func() {
+	if sec == allSections {
+		return func() {}
+	}
+	subs.m.Store(send, sec)
+	return func() { subs.m.Delete(send) }
+}
+
+func (subs *subscriptions) of(send chan []byte) sections {
+	if sec, ok := subs.m.Load(send); ok {
+		return sec.(sections)
+	}
+	return allSections
+}
+
+// filter keeps the sections of sp in sec. The server time is always
+// kept.
+func (sp statePayload) filter(sec sections) any {
+	if sec == allSections {
+		return sp
+	}
+	m := map[string]any{"serverTime": sp.ServerTime}
+	if sec&sectionState != 0 {
+		m["state"] = sp.State
+	}
+	if sec&sectionValidActions != 0 {
+		m["validActions"] = sp.ValidActions
+	}
+	if sec&sectionSessionInfo != 0 {
+		m["sessionInfo"] = sp.SessionInfo
+	}
+	if sec&sectionResults != 0 && sp.Results != nil {
+		m["results"] = sp.Results
+	}
+	if sec&sectionSummary != 0 && sp.Summary != nil {
+		m["summary"] = sp.Summary
+	}
+	if sec&sectionScoreboard != 0 && sp.Scoreboard != nil {
+		m["scoreboard"] = sp.Scoreboard
+	}
+	if sec&sectionFairness != 0 && sp.Fairness != nil {
+		m["fairness"] = sp.Fairness
+	}
+	return m
+}
+
+// stateEncoder marshals one state for many connections, once for each
+// set of sections they subscribed to.
+type stateEncoder struct {
+	s       *Server
+	sp      statePayload
+	encoded map[sections][]byte
+}
+
+func (s *Server) newStateEncoder(sp statePayload) *stateEncoder {
+	return &stateEncoder{s: s, sp: sp, encoded: make(map[sections][]byte)}
+}
+
+// send queues the state on send, with the sections it subscribed to.
+func (e *stateEncoder) send(send chan []byte) {
+	sec := e.s.subs.of(send)
+	msg, ok := e.encoded[sec]
+	if !ok {
+		msg = e.s.encodeState(e.sp, sec)
+		e.encoded[sec] = msg
+	}
+	sendEncoded(send, msg)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// joinWith dials a session and joins with the given join payload.
+func joinWith(t *testing.T, env *testEnv, code string, join joinPayload) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, code), nil)
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	if err := sendWS(ctx, conn, "join", join); err != nil {
+		t.Fatalf("send join: %v", err)
+	}
+	return conn
+}
+
+// readStateKeys reads a state message and returns its payload's keys.
+func readStateKeys(t *testing.T, ctx context.Context, conn *websocket.Conn) []string {
+	t.Helper()
+	msg := wsRead(ctx, t, conn)
+	if msg.Type != "state" {
+		t.Fatalf("expected state message, got %q: %s", msg.Type, msg.Payload)
+	}
+	var fields map[string]json.RawMessage
+	json.Unmarshal(msg.Payload, &fields)
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestWSStateSections(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	alice := wsConnect(t, env.ts, sess.Code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := joinWith(t, env, sess.Code, joinPayload{PlayerID: "bob", Sections: []string{"state", "validActions"}})
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readStateKeys(t, ctx, bob)
+	wall := joinWith(t, env, sess.Code, joinPayload{PlayerID: "wall", Spectate: true, Sections: []string{"sessionInfo"}})
+	defer wall.Close(websocket.StatusNormalClosure, "")
+	if keys := readStateKeys(t, ctx, wall); !slices.Equal(keys, []string{"serverTime", "sessionInfo"}) {
+		t.Fatalf("expected the spectator to get only session info, got %v", keys)
+	}
+
+	sendWS(ctx, alice, "start", nil)
+	if sp := readState(t, ctx, alice); sp.State == nil || len(sp.ValidActions) == 0 || sp.SessionInfo.Code != sess.Code {
+		t.Fatalf("expected alice to get the whole state, got %+v", sp)
+	}
+	if keys := readStateKeys(t, ctx, bob); !slices.Equal(keys, []string{"serverTime", "state", "validActions"}) {
+		t.Fatalf("expected bob to get the state and actions only, got %v", keys)
+	}
+	if keys := readStateKeys(t, ctx, wall); !slices.Equal(keys, []string{"serverTime", "sessionInfo"}) {
+		t.Fatalf("expected the spectator to get only session info, got %v", keys)
+	}
+
+	bad := joinWith(t, env, sess.Code, joinPayload{PlayerID: "carol", Sections: []string{"board"}})
+	defer bad.Close(websocket.StatusNormalClosure, "")
+	if msg := readError(t, ctx, bad); msg != "unknown state section: board" {
+		t.Fatalf("unexpected error %q", msg)
+	}
+}
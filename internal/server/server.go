@@ -1,13 +1,19 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"io/fs"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
+	"games/internal/event"
 	"games/internal/game"
+	"games/internal/graphql"
+	"games/internal/mail"
 	"games/internal/session"
 )
 
@@ -16,7 +22,47 @@ type Server struct {
 	mux      *http.ServeMux
 	registry *game.Registry
 	manager  *session.Manager
-	webFS    fs.FS
+	static   *staticHandler
+	cache    *responseCache
+
+	botLimiter    *rateLimiter // per external bot action rate
+	reportLimiter *rateLimiter // per player problem reports in a session
+	verifyLimiter *rateLimiter // verification mail, per player and per address
+	adminLimiter  *rateLimiter // log lines for rejected admin calls
+	messageRate   float64      // per session connection, in messages a second
+	webhookClient *http.Client
+	pushKey       string            // VAPID public key; empty when Web Push is off
+	mailer        *mail.Notifier    // nil when email is off
+	adminTokens   map[string]string // bearer token -> admin name
+	baseURL       string            // public site URL; empty to use the request host
+	guestKey      []byte            // signs guest cookies
+	prefix        string            // path a tenant's server is mounted at
+	// frameAncestors are the origins allowed to frame the site; none
+	// allows no one.
+	frameAncestors []string
+	branding       session.Branding // the site's look; zero keeps the frontend's own
+	iceServers     []ICEServer      // STUN and TURN servers for voice chat
+
+	compression          string // a compressionModes key
+	compressionThreshold int    // bytes; 0 for the library default
+	wsStats              wsStats
+
+	subs   subscriptions // state sections of thin clients
+	toasts sync.Map      // send channels of connections that asked for toasts
+	// narration holds the send channels of connections that asked for
+	// narration.
+	narration sync.Map
+
+	graphql *graphql.Schema
+	metrics *serverMetrics
+
+	// WebSocket traffic recording, for replay tests
+	recordMu   sync.Mutex
+	recordDir  string                  // empty when not recording
+	recordings map[string]*wsRecording // by session code
+
+	reloadMu  sync.Mutex                  // one reload or unregister at a time
+	loadGames func() ([]game.Game, error) // nil when no games load at runtime
 }
 
 // New creates a server with all routes.
@@ -26,25 +72,163 @@ func New(registry *game.Registry, manager *session.Manager, webFS fs.FS) *Server
 		mux:      http.NewServeMux(),
 		registry: registry,
 		manager:  manager,
-		webFS:    webFS,
+		static:   newStaticHandler(webFS),
+
+		botLimiter:    newRateLimiter(5, 10),
+		reportLimiter: newRateLimiter(reportRate, reportBurst),
+		verifyLimiter: newRateLimiter(verifyRate, verifyBurst),
+		adminLimiter:  newRateLimiter(rejectedAdminLogRate, rejectedAdminLogBurst),
+		messageRate:   DefaultMessageRate,
+		compression:   DefaultCompression,
+		webhookClient: &http.Client{Timeout: 5 * time.Second},
+		metrics:       newServerMetrics(),
+		guestKey:      newGuestKey(),
 	}
+	s.metrics.registry.Register(manager.CleanupMetrics())
+	events, _ := manager.Events().Subscribe(cacheEventBuffer)
+	s.cache = newResponseCache(cacheTTL, events)
+	inbox, _ := manager.Events().Subscribe(inboxEventBuffer)
+	go manager.RunInbox(inbox)
+	achievements, _ := manager.Events().Subscribe(achievementEventBuffer)
+	go manager.RunAchievements(achievements)
+	toasts, _ := manager.Events().Subscribe(achievementEventBuffer)
+	go s.toastAchievements(toasts)
+	tournaments, _ := manager.Events().Subscribe(tournamentEventBuffer)
+	go s.runTournaments(tournaments)
+	surveys, _ := manager.Events().Subscribe(surveyEventBuffer)
+	go s.promptSurveys(surveys)
+	s.graphql = s.newGraphQLSchema()
 	s.routes()
 	return s
 }
 
 func (s *Server) routes() {
 	// API routes
-	s.mux.HandleFunc("GET /api/games", s.handleListGames)
+	s.mux.HandleFunc("GET /api/games", s.cached(gamesTag, s.handleListGames))
+	s.mux.HandleFunc("GET /api/games/{name}/bots", s.handleListBots)
+	s.mux.HandleFunc("GET /api/games/{name}/bots/standings", s.cached(gameTag, s.handleBotStandings))
+	s.mux.HandleFunc("GET /api/games/{name}/stats", s.cached(gameTag, s.handleGameStats))
+	s.mux.HandleFunc("GET /api/games/{name}/matches", s.cached(gameTag, s.handleArchivedMatches))
+	s.mux.HandleFunc("GET /api/seasons", s.handleListSeasons)
+	s.mux.HandleFunc("GET /api/seasons/{name}", s.handleGetSeason)
+	s.mux.HandleFunc("POST /api/exhibitions", s.handleCreateExhibition)
+	s.mux.HandleFunc("POST /api/bots", s.handleRegisterBot)
+	s.mux.HandleFunc("POST /api/bots/sandbox", s.handleCreateSandbox)
+	s.mux.HandleFunc("POST /api/practice", s.handleCreatePractice)
+	s.mux.HandleFunc("POST /api/sessions/{code}/bot/join", s.handleBotJoin)
+	s.mux.HandleFunc("GET /api/sessions/{code}/bot/state", s.handleBotState)
+	s.mux.HandleFunc("POST /api/sessions/{code}/bot/actions", s.handleBotAction)
+	s.mux.HandleFunc("POST /api/sessions/{code}/report", s.handleReport)
+	s.mux.HandleFunc("POST /api/challenges", s.handleCreateChallenge)
+	s.mux.HandleFunc("GET /api/challenges", s.handleListChallenges)
+	s.mux.HandleFunc("POST /api/challenges/{id}/accept", s.handleAcceptChallenge)
+	s.mux.HandleFunc("POST /api/challenges/{id}/decline", s.handleDeclineChallenge)
+	s.mux.HandleFunc("POST /api/tournaments", s.handleCreateTournament)
+	s.mux.HandleFunc("GET /api/tournaments", s.handleListTournaments)
+	s.mux.HandleFunc("GET /api/tournaments/{id}", s.handleGetTournament)
+	s.mux.HandleFunc("POST /api/tournaments/{id}/join", s.handleJoinTournament)
+	s.mux.HandleFunc("POST /api/tournaments/{id}/start", s.handleStartTournament)
+	s.mux.HandleFunc("GET /api/tournaments/{id}/standings", s.handleTournamentStandings)
+	s.mux.HandleFunc("GET /api/tournaments/{id}/stream", s.handleTournamentStream)
+	s.mux.HandleFunc("POST /api/clubs", s.handleCreateClub)
+	s.mux.HandleFunc("GET /api/clubs", s.handleListClubs)
+	s.mux.HandleFunc("GET /api/clubs/{id}", s.handleGetClub)
+	s.mux.HandleFunc("POST /api/clubs/{id}/join", s.handleJoinClub)
+	s.mux.HandleFunc("PUT /api/clubs/{id}/members/{player}", s.handleSetClubRole)
+	s.mux.HandleFunc("DELETE /api/clubs/{id}/members/{player}", s.handleRemoveClubMember)
+	s.mux.HandleFunc("GET /api/clubs/{id}/lobby", s.handleClubLobby)
+	s.mux.HandleFunc("GET /api/clubs/{id}/leaderboard", s.handleClubLeaderboard)
+	s.mux.HandleFunc("PUT /api/clubs/{id}/branding", s.handleSetClubBranding)
+	s.mux.HandleFunc("GET /api/branding", s.handleBranding)
+	s.mux.HandleFunc("GET /api/rtc/config", s.handleRTCConfig)
+	s.mux.HandleFunc("GET /api/players/{id}/clubs", s.handlePlayerClubs)
+	s.mux.HandleFunc("GET /api/players/{id}/friends", s.handleListFriends)
+	s.mux.HandleFunc("POST /api/players/{id}/friends", s.handleRequestFriend)
+	s.mux.HandleFunc("POST /api/players/{id}/friends/{friend}/accept", s.handleAcceptFriend)
+	s.mux.HandleFunc("DELETE /api/players/{id}/friends/{friend}", s.handleRemoveFriend)
+	s.mux.HandleFunc("GET /api/players/{id}/recent", s.handleRecentOpponents)
+	s.mux.HandleFunc("GET /api/players/{id}/sessions", s.handlePlayerSessions)
+	s.mux.HandleFunc("GET /api/players/{id}/turns", s.handlePlayerTurns)
+	s.mux.HandleFunc("GET /api/players/{id}/turns/stream", s.handlePlayerTurnsStream)
+	s.mux.HandleFunc("GET /api/players/{id}/inbox", s.handlePlayerInbox)
+	s.mux.HandleFunc("DELETE /api/players/{id}/inbox", s.handleClearInbox)
+	s.mux.HandleFunc("GET /api/players/{id}/achievements", s.handlePlayerAchievements)
+	s.mux.HandleFunc("GET /api/achievements", s.handleListAchievements)
+	s.mux.HandleFunc("GET /api/push/key", s.handlePushKey)
+	s.mux.HandleFunc("POST /api/push/subscriptions", s.handlePushSubscribe)
+	s.mux.HandleFunc("DELETE /api/push/subscriptions", s.handlePushUnsubscribe)
+	s.mux.HandleFunc("GET /api/players/{id}/email", s.handleGetEmail)
+	s.mux.HandleFunc("PUT /api/players/{id}/email", s.handleSetEmail)
+	s.mux.HandleFunc("PUT /api/players/{id}/email/preferences", s.handleEmailPreferences)
+	s.mux.HandleFunc("GET /api/email/verify", s.handleVerifyEmail)
+	s.mux.HandleFunc("GET /api/admin/sessions", s.admin("session.list", s.handleAdminListSessions))
+	s.mux.HandleFunc("DELETE /api/admin/sessions/{code}", s.admin("session.delete", s.handleAdminDeleteSession))
+	s.mux.HandleFunc("POST /api/admin/cleanup", s.admin("session.cleanup", s.handleAdminCleanup))
+	s.mux.HandleFunc("PUT /api/admin/sessions/{code}/pin", s.admin("session.pin", s.handleAdminPinSession))
+	s.mux.HandleFunc("POST /api/admin/sessions/{code}/kick", s.admin("session.kick", s.handleAdminKick))
+	s.mux.HandleFunc("GET /api/admin/sessions/deleted", s.admin("session.list_deleted", s.handleAdminDeletedSessions))
+	s.mux.HandleFunc("POST /api/admin/sessions/{code}/restore", s.admin("session.restore", s.handleAdminRestoreSession))
+	s.mux.HandleFunc("GET /api/admin/audit", s.admin("audit.list", s.handleAdminAudit))
+	s.mux.HandleFunc("GET /api/admin/sessions/{code}/transcript", s.admin("session.transcript", s.handleAdminTranscript))
+	s.mux.HandleFunc("POST /api/admin/replays", s.admin("match.replay", s.handleAdminReplay))
+	s.mux.HandleFunc("GET /api/admin/database", s.admin("database.size", s.handleAdminDatabase))
+	s.mux.HandleFunc("GET /api/admin/games", s.admin("game.list", s.handleAdminGames))
+	s.mux.HandleFunc("PUT /api/admin/games/{name}", s.admin("game.set_enabled", s.handleAdminSetGame))
+	s.mux.HandleFunc("DELETE /api/admin/games/{name}", s.admin("game.unregister", s.handleAdminUnregisterGame))
+	s.mux.HandleFunc("POST /api/admin/games/reload", s.admin("game.reload", s.handleAdminReloadGames))
+	s.mux.HandleFunc("GET /api/admin/limits", s.admin("limits.view", s.handleAdminLimits))
+	s.mux.HandleFunc("GET /api/admin/conduct", s.admin("conduct.view", s.handleAdminConduct))
+	s.mux.HandleFunc("GET /api/admin/surveys", s.admin("survey.list", s.handleAdminSurveys))
+	s.mux.HandleFunc("GET /api/admin/reports", s.admin("report.list", s.handleAdminReports))
+	s.mux.HandleFunc("GET /api/admin/reports/{id}", s.admin("report.view", s.handleAdminReport))
+	s.mux.HandleFunc("GET /api/admin/compression", s.admin("compression.view", s.handleAdminCompression))
+	s.mux.HandleFunc("GET /api/admin/features", s.admin("feature.list", s.handleAdminFeatures))
+	s.mux.HandleFunc("PUT /api/admin/features/{name}", s.admin("feature.set", s.handleAdminSetFeature))
+	s.mux.HandleFunc("DELETE /api/admin/features/{name}", s.admin("feature.remove", s.handleAdminRemoveFeature))
+	s.mux.HandleFunc("GET /api/admin/sessions/{code}/features", s.admin("session.features", s.handleAdminSessionFeatures))
+	s.mux.HandleFunc("PUT /api/admin/sessions/{code}/features/{name}", s.admin("session.override_feature", s.handleAdminOverrideFeature))
+	s.mux.HandleFunc("GET /api/feed", s.handleFeed)
+	s.mux.HandleFunc("GET /api/feed/snapshot", s.handleFeedSnapshot)
+	s.mux.HandleFunc("GET /api/sessions", s.cached(lobbyTag, s.handleListSessions))
 	s.mux.HandleFunc("POST /api/sessions", s.handleCreateSession)
+	s.mux.HandleFunc("GET /api/guest", s.handleGuest)
+	s.mux.HandleFunc("GET /api/sessions/states", s.handleSessionStates)
 	s.mux.HandleFunc("GET /api/sessions/{code}", s.handleGetSession)
 	s.mux.HandleFunc("GET /api/sessions/{code}/ws", s.handleWebSocket)
+	s.mux.HandleFunc("GET /api/ws-schema", s.handleWSSchema)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.mux.HandleFunc("GET /api/graphql", s.handleGraphQL)
+	s.mux.HandleFunc("POST /api/graphql", s.handleGraphQL)
 	s.mux.HandleFunc("POST /api/sessions/{code}/start", s.handleStartSession)
+	s.mux.HandleFunc("GET /api/sessions/{code}/analysis", s.handleAnalysis)
+	s.mux.HandleFunc("GET /api/sessions/{code}/qr", s.handleSessionQR)
+	s.mux.HandleFunc("GET /api/sessions/{code}/picture", s.handleSessionPicture)
+	s.mux.HandleFunc("GET /api/sessions/{code}/scoreboard", s.handleScoreboard)
+	s.mux.HandleFunc("GET /api/sessions/{code}/events", s.handleSessionEvents)
+
+	// Debug routes, served in dev mode only
+	s.mux.HandleFunc("GET /api/debug/sessions/{code}/history", s.devOnly(s.handleDebugHistory))
+	s.mux.HandleFunc("GET /api/debug/sessions/{code}/states/{point}", s.devOnly(s.handleDebugState))
+	s.mux.HandleFunc("GET /api/debug/sessions/{code}/diff", s.devOnly(s.handleDebugDiff))
+	s.mux.HandleFunc("GET /api/debug/sessions/{code}/features", s.devOnly(s.handleDebugFeatures))
 
 	// Static files
-	s.mux.Handle("/", http.FileServer(http.FS(s.webFS)))
+	s.mux.Handle("GET /s/{code}", s.withGuest(http.HandlerFunc(s.handleSharePage)))
+	s.mux.Handle("GET /session.html", s.withGuest(http.HandlerFunc(s.handleSessionPage)))
+	s.mux.Handle("/", s.withGuest(s.static))
+}
+
+// SetDevMode serves web assets uncached, for use with a webFS read from
+// disk so frontend edits show up on reload.
+func (s *Server) SetDevMode(dev bool) {
+	s.static.dev = dev
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.setSecurityHeaders(w, r)
+	if !limitBody(w, r) || !s.checkCSRF(w, r) {
+		return
+	}
 	s.mux.ServeHTTP(w, r)
 }
 
@@ -52,18 +236,52 @@ func (s *Server) handleListGames(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, s.registry.List())
 }
 
+func (s *Server) handleListBots(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := s.registry.Get(name); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "game not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.registry.Strategies(name))
+}
+
 type createSessionRequest struct {
 	GameType string `json:"gameType"`
 	PlayerID string `json:"playerId"`
+	Private  bool   `json:"private,omitempty"` // hide from the lobby feed
+	// Options are game option values; omitted options take their defaults.
+	Options map[string]int `json:"options,omitempty"`
+	// Variant names one of the game's variants to play, fixing the options
+	// it sets.
+	Variant string `json:"variant,omitempty"`
+	// Party lists further games to play after GameType with the same
+	// roster, making this a party session.
+	Party []string `json:"party,omitempty"`
+	// TurnOrder seats players without a chosen seat in join order (the
+	// default) or, with "random", in an order shuffled from the seed.
+	TurnOrder string `json:"turnOrder,omitempty"`
+	// VoteThresholds override how many players must agree to skip or
+	// remove an unresponsive player.
+	VoteThresholds *session.VoteThresholds `json:"voteThresholds,omitempty"`
+	// ExpireAfter gives the session its own expiry, such as "168h" for a
+	// correspondence game: cleanup keeps it until it has sat idle that
+	// long.
+	ExpireAfter string `json:"expireAfter,omitempty"`
 }
 
 type createSessionResponse struct {
 	Code string `json:"code"`
+	// PlayerID is the creator's seat: a guest's ID, or the one asked for.
+	PlayerID string `json:"playerId"`
+	// Token is the creator's join secret, claimed with the seat. It joins
+	// over the WebSocket and authorizes the host's REST calls, such as
+	// starting the game.
+	Token string `json:"token"`
 }
 
 func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 	var req createSessionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSON(r.Body, &req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
 		return
 	}
@@ -73,18 +291,112 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "gameType and playerId required"})
 		return
 	}
+	if strings.HasPrefix(req.PlayerID, session.ExternalBotPrefix) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "player IDs starting with " + session.ExternalBotPrefix + " are reserved for bots"})
+		return
+	}
+	playerID, name, err := s.seatAs(r, req.PlayerID, func(string) bool { return false })
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	turnOrder, err := session.ParseTurnOrder(req.TurnOrder)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if req.VoteThresholds != nil {
+		if err := req.VoteThresholds.Validate(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	var expireAfter time.Duration
+	if req.ExpireAfter != "" {
+		if expireAfter, err = time.ParseDuration(req.ExpireAfter); err != nil || expireAfter < time.Second {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expireAfter must be a duration of at least a second, such as 168h"})
+			return
+		}
+	}
 
-	sess, err := s.manager.Create(req.GameType)
+	if req.Variant != "" {
+		g, ok := s.registry.Get(req.GameType)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown game type: " + req.GameType})
+			return
+		}
+		n, err := game.VariantNamed(g.Info(), req.Variant)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if req.Options == nil {
+			req.Options = make(map[string]int)
+		}
+		req.Options[game.VariantOption] = n
+	}
+
+	var sess *session.Session
+	if len(req.Party) > 0 {
+		if len(req.Options) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "party sessions use default options"})
+			return
+		}
+		sess, err = s.manager.CreateParty(r.Context(), append([]string{req.GameType}, req.Party...))
+	} else {
+		sess, err = s.manager.CreateWithOptions(r.Context(), req.GameType, req.Options)
+	}
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	if err := sess.AddPlayer(req.PlayerID); err != nil {
+	if turnOrder != session.TurnOrderJoin {
+		if err := s.manager.SetTurnOrder(r.Context(), sess, turnOrder); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if req.VoteThresholds != nil {
+		if err := s.manager.SetVoteThresholds(r.Context(), sess, *req.VoteThresholds); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if req.Private {
+		if err := s.manager.SetPrivate(r.Context(), sess, true); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if expireAfter > 0 {
+		if err := s.manager.SetExpireAfter(r.Context(), sess, expireAfter); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if err := sess.AddPlayer(playerID); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	sess.SetName(playerID, name)
+	token := sess.ClaimSeat(playerID)
+	if err := s.manager.Touch(r.Context(), sess); err != nil {
+		log.Printf("save activity: %v", err)
+	}
+	if err := s.manager.SaveSessionPlayers(r.Context(), sess); err != nil {
+		log.Printf("save players: %v", err)
+	}
+	s.manager.RecordTimeline(r.Context(), sess.Code, session.TimelineJoined, playerID, nil)
+	s.manager.Events().Publish(event.Event{
+		Type:        event.SessionCreated,
+		SessionCode: sess.Code,
+		GameType:    sess.GameType,
+		Players:     []string{playerID},
+		Private:     req.Private,
+	})
 
-	writeJSON(w, http.StatusCreated, createSessionResponse{Code: sess.Code})
+	writeJSON(w, http.StatusCreated, createSessionResponse{Code: sess.Code, PlayerID: playerID, Token: token})
 }
 
 func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
@@ -97,6 +409,17 @@ func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, sess.Info())
 }
 
+func (s *Server) handleScoreboard(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.manager.Get(r.PathValue("code"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, sess.Scoreboard())
+}
+
+// handleStartSession starts a session for its host, who presents their
+// seat's token as a bearer token, as the WebSocket's start message does.
 func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
 	code := r.PathValue("code")
 	sess, ok := s.manager.Get(code)
@@ -104,18 +427,61 @@ func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
 		return
 	}
-	if err := sess.Start(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	playerID, ok := s.seatHolder(w, r, sess)
+	if !ok {
+		return
+	}
+	if sess.Info().HostID != playerID {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only the host can start"})
 		return
 	}
-	if err := s.manager.SaveMatchState(sess); err != nil {
-		log.Printf("save match state: %v", err)
+	if err := sess.Start(); err != nil {
+		status := http.StatusBadRequest
+		if s.failIfPanicked(r.Context(), sess, err) {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
 	}
+	s.matchStarted(r.Context(), sess)
 	// Broadcast new state to all players
 	s.broadcastState(sess)
+	s.playBots(r.Context(), sess, 0)
 	writeJSON(w, http.StatusOK, map[string]string{"status": "started"})
 }
 
+// seatHolder identifies the player calling a session's REST endpoint by
+// the seat token in its Authorization header, writing a 401 and returning
+// false if the token holds no seat.
+func (s *Server) seatHolder(w http.ResponseWriter, r *http.Request, sess *session.Session) (string, bool) {
+	playerID, ok := sess.SeatWithToken(bearerToken(r))
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "a seat token is required"})
+		return "", false
+	}
+	return playerID, true
+}
+
+// matchStarted persists a freshly started match and announces it.
+func (s *Server) matchStarted(ctx context.Context, sess *session.Session) {
+	if err := s.manager.SaveMatchStart(ctx, sess); err != nil {
+		log.Printf("save match start: %v", err)
+	}
+	if err := s.manager.RecordMatchPlayers(ctx, sess); err != nil {
+		log.Printf("record match players: %v", err)
+	}
+	info := sess.Info()
+	s.manager.RecordTimeline(ctx, sess.Code, session.TimelineStarted, "", timelineStart{Players: info.Players})
+	s.manager.Events().Publish(event.Event{
+		Type:        event.MatchStarted,
+		SessionCode: info.Code,
+		GameType:    info.GameType,
+		Players:     info.Players,
+		Private:     info.Private,
+	})
+	s.announceTurn(sess)
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -91,7 +91,7 @@ func TestWebSocketJoinEncodingNotDoubleEncoded(t *testing.T) {
 			t.Fatalf("write: %v", err)
 		}
 
-		msg, err := readWS(ctx, conn)
+		msg, err := readPastSeat(ctx, conn)
 		if err != nil {
 			t.Fatalf("read: %v", err)
 		}
@@ -187,23 +187,13 @@ func TestWebSocketPlayFullGame(t *testing.T) {
 	startState := readState(t, ctx, aliceConn)
 	readState(t, ctx, bobConn)
 
-	// Determine who goes first (map iteration order is non-deterministic)
+	// Alice created the session, so she moves first
 	sm := stateMap(t, startState)
-	firstPlayer, ok := sm["turn"].(string)
-	if !ok {
-		t.Fatalf("expected turn to be string, got %T", sm["turn"])
-	}
-	var firstConn, secondConn *websocket.Conn
-	var secondPlayer string
-	if firstPlayer == "alice" {
-		firstConn = aliceConn
-		secondConn = bobConn
-		secondPlayer = "bob"
-	} else {
-		firstConn = bobConn
-		secondConn = aliceConn
-		secondPlayer = "alice"
+	if sm["turn"] != "alice" {
+		t.Fatalf("expected alice to move first, got %v", sm["turn"])
 	}
+	firstConn, firstPlayer := aliceConn, "alice"
+	secondConn, secondPlayer := bobConn, "bob"
 
 	// First player takes 0, 1, 2 (wins row 0); second takes 3, 4
 	moves := []struct {
@@ -289,22 +279,11 @@ func TestWebSocketActionEncodingCorrectness(t *testing.T) {
 	if err := sendWS(ctx, aliceConn, "start", nil); err != nil {
 		t.Fatalf("send start: %v", err)
 	}
-	startState := readState(t, ctx, aliceConn)
+	readState(t, ctx, aliceConn)
 	readState(t, ctx, bobConn)
 
-	// Make a move with whoever goes first
-	sm := stateMap(t, startState)
-	firstPlayer, ok := sm["turn"].(string)
-	if !ok {
-		t.Fatalf("expected turn to be string, got %T", sm["turn"])
-	}
-	var conn *websocket.Conn
-	if firstPlayer == "alice" {
-		conn = aliceConn
-	} else {
-		conn = bobConn
-	}
-	if err := sendWS(ctx, conn, "action", makeAction(t, 0)); err != nil {
+	// Alice moves first
+	if err := sendWS(ctx, aliceConn, "action", makeAction(t, 0)); err != nil {
 		t.Fatalf("send action: %v", err)
 	}
 
@@ -353,18 +332,11 @@ func TestWebSocketActionWrongTurn(t *testing.T) {
 	startState := readState(t, ctx, aliceConn)
 	readState(t, ctx, bobConn)
 
-	// Send action from wrong player
-	sm := stateMap(t, startState)
-	firstPlayer, ok := sm["turn"].(string)
-	if !ok {
-		t.Fatalf("expected turn to be string, got %T", sm["turn"])
-	}
-	var wrongConn *websocket.Conn
-	if firstPlayer == "alice" {
-		wrongConn = bobConn
-	} else {
-		wrongConn = aliceConn
+	// Send action from wrong player: alice moves first
+	if sm := stateMap(t, startState); sm["turn"] != "alice" {
+		t.Fatalf("expected alice to move first, got %v", sm["turn"])
 	}
+	wrongConn := bobConn
 	if err := sendWS(ctx, wrongConn, "action", makeAction(t, 0)); err != nil {
 		t.Fatalf("send action: %v", err)
 	}
@@ -398,19 +370,11 @@ func TestWebSocketReconnect(t *testing.T) {
 	startState := readState(t, ctx, aliceConn)
 	readState(t, ctx, bobConn)
 
-	// Make a move
-	sm := stateMap(t, startState)
-	firstPlayer, ok := sm["turn"].(string)
-	if !ok {
-		t.Fatalf("expected turn to be string, got %T", sm["turn"])
-	}
-	var firstConn *websocket.Conn
-	if firstPlayer == "alice" {
-		firstConn = aliceConn
-	} else {
-		firstConn = bobConn
+	// Alice moves first
+	if sm := stateMap(t, startState); sm["turn"] != "alice" {
+		t.Fatalf("expected alice to move first, got %v", sm["turn"])
 	}
-	if err := sendWS(ctx, firstConn, "action", makeAction(t, 4)); err != nil {
+	if err := sendWS(ctx, aliceConn, "action", makeAction(t, 4)); err != nil {
 		t.Fatalf("send action: %v", err)
 	}
 	readState(t, ctx, aliceConn)
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 
+	"nhooyr.io/websocket"
+
 	"games/internal/game"
 	"games/internal/session"
 )
@@ -55,6 +57,82 @@ func TestCreateSessionValid(t *testing.T) {
 	}
 }
 
+func TestCreateSessionOptions(t *testing.T) {
+	env := setupTestEnv(t)
+	if err := env.srv.registry.Configure("tictactoe", []game.Option{{Name: "misere", Default: 1, Min: 0, Max: 1}}); err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+
+	resp, err := http.Get(env.ts.URL + "/api/games")
+	if err != nil {
+		t.Fatalf("GET /api/games: %v", err)
+	}
+	var games []game.GameInfo
+	json.NewDecoder(resp.Body).Decode(&games)
+	resp.Body.Close()
+	if len(games[0].Options) != 2 || games[0].Options[0].Default != 1 || games[0].Options[1].Name != game.VariantOption {
+		t.Fatalf("expected configured misere default, got %+v", games[0].Options)
+	}
+
+	// Omitted options take the configured default.
+	resp, err = http.Post(env.ts.URL+"/api/sessions", "application/json", strings.NewReader(`{"gameType":"tictactoe","playerId":"alice"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	sess, _ := env.mgr.Get(created.Code)
+	if got := sess.Info().Options["misere"]; got != 1 {
+		t.Fatalf("expected misere 1 by default, got %d", got)
+	}
+
+	for _, body := range []string{
+		`{"gameType":"tictactoe","playerId":"alice","options":{"misere":2}}`,
+		`{"gameType":"tictactoe","playerId":"alice","options":{"size":4}}`,
+	} {
+		resp, err := http.Post(env.ts.URL+"/api/sessions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestCreateSessionVariant(t *testing.T) {
+	env := setupTestEnv(t)
+
+	resp, err := http.Post(env.ts.URL+"/api/sessions", "application/json", strings.NewReader(`{"gameType":"tictactoe","playerId":"alice","variant":"misere"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	sess, _ := env.mgr.Get(created.Code)
+	if info := sess.Info(); info.Variant != "misere" || info.Options["misere"] != 1 || info.Options[game.VariantOption] != 1 {
+		t.Fatalf("expected a misère session, got %+v", info)
+	}
+
+	for _, body := range []string{
+		`{"gameType":"tictactoe","playerId":"alice","variant":"connect-five"}`,
+		`{"gameType":"tictactoe","playerId":"alice","variant":"misere","options":{"misere":0}}`,
+		`{"gameType":"nope","playerId":"alice","variant":"misere"}`,
+	} {
+		resp, err := http.Post(env.ts.URL+"/api/sessions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
 func TestCreateSessionMissingFields(t *testing.T) {
 	env := setupTestEnv(t)
 
@@ -154,7 +232,7 @@ func TestGetSessionNotFound(t *testing.T) {
 func TestStartSessionValid(t *testing.T) {
 	env := setupTestEnv(t)
 
-	sess, err := env.mgr.Create("tictactoe")
+	sess, err := env.mgr.Create(t.Context(), "tictactoe")
 	if err != nil {
 		t.Fatalf("create session: %v", err)
 	}
@@ -165,10 +243,7 @@ func TestStartSessionValid(t *testing.T) {
 		t.Fatalf("add bob: %v", err)
 	}
 
-	resp, err := http.Post(env.ts.URL+"/api/sessions/"+sess.Code+"/start", "application/json", nil)
-	if err != nil {
-		t.Fatalf("POST start: %v", err)
-	}
+	resp := startViaAPI(t, env, sess.Code)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -179,10 +254,7 @@ func TestStartSessionValid(t *testing.T) {
 func TestStartSessionNotFound(t *testing.T) {
 	env := setupTestEnv(t)
 
-	resp, err := http.Post(env.ts.URL+"/api/sessions/nonexistent/start", "application/json", nil)
-	if err != nil {
-		t.Fatalf("POST: %v", err)
-	}
+	resp := startViaAPI(t, env, "nonexistent")
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNotFound {
@@ -190,10 +262,50 @@ func TestStartSessionNotFound(t *testing.T) {
 	}
 }
 
+func TestStartSessionHostOnly(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, bob)
+
+	start := func(token string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", env.ts.URL+"/api/sessions/"+code+"/start", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST start: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for token, want := range map[string]int{
+		"":                             http.StatusUnauthorized,
+		"not-a-token":                  http.StatusUnauthorized,
+		seatToken(env.ts, code, "bob"): http.StatusForbidden,
+	} {
+		if got := start(token); got != want {
+			t.Errorf("token %q: expected %d, got %d", token, want, got)
+		}
+	}
+	if sess, _ := env.mgr.Get(code); sess.Info().Status != session.StatusWaiting {
+		t.Fatal("expected the session still waiting")
+	}
+	if got := start(seatToken(env.ts, code, "alice")); got != http.StatusOK {
+		t.Fatalf("expected the host to start, got %d", got)
+	}
+}
+
 func TestStartSessionNotEnoughPlayers(t *testing.T) {
 	env := setupTestEnv(t)
 
-	sess, err := env.mgr.Create("tictactoe")
+	sess, err := env.mgr.Create(t.Context(), "tictactoe")
 	if err != nil {
 		t.Fatalf("create session: %v", err)
 	}
@@ -201,13 +313,59 @@ func TestStartSessionNotEnoughPlayers(t *testing.T) {
 		t.Fatalf("add alice: %v", err)
 	}
 
-	resp, err := http.Post(env.ts.URL+"/api/sessions/"+sess.Code+"/start", "application/json", nil)
-	if err != nil {
-		t.Fatalf("POST: %v", err)
-	}
+	resp := startViaAPI(t, env, sess.Code)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusBadRequest {
 		t.Fatalf("expected 400, got %d", resp.StatusCode)
 	}
 }
+
+func TestListBots(t *testing.T) {
+	env := setupTestEnv(t)
+
+	resp, err := http.Get(env.ts.URL + "/api/games/tictactoe/bots")
+	if err != nil {
+		t.Fatalf("GET bots: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var bots []game.StrategyInfo
+	if err := json.NewDecoder(resp.Body).Decode(&bots); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(bots) != 3 || bots[0].Name != "random" || bots[2].Name != "perfect" {
+		t.Fatalf("expected [random blocking perfect], got %v", bots)
+	}
+
+	resp2, err := http.Get(env.ts.URL + "/api/games/chess/bots")
+	if err != nil {
+		t.Fatalf("GET bots: %v", err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown game, got %d", resp2.StatusCode)
+	}
+}
+
+func TestCreateExhibitionValidation(t *testing.T) {
+	env := setupTestEnv(t)
+
+	for _, body := range []string{
+		`not json`,
+		`{"gameType":"tictactoe"}`,
+		`{"gameType":"tictactoe","bots":["random","random"],"moveDelayMs":-1}`,
+		`{"gameType":"tictactoe","bots":["random","genius"]}`,
+	} {
+		resp, err := http.Post(env.ts.URL+"/api/exhibitions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+}
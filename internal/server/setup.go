@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"games/internal/session"
+)
+
+// setupPayload is a position in the form the game documents for it: the
+// starting position before a match starts, or one to practise from.
+type setupPayload struct {
+	Position json.RawMessage `json:"position"`
+}
+
+// setUp sets the starting position of a waiting session, which only its
+// host may do and an empty position undoes, or sets a practice match up in
+// the position sent.
+func (s *Server) setUp(ctx context.Context, sess *session.Session, playerID string, send chan []byte, payload json.RawMessage) {
+	var sp setupPayload
+	if err := unmarshalStrict(payload, &sp); err != nil {
+		sendWSMsg(send, "error", errorPayload{Message: "invalid setup payload"})
+		return
+	}
+	info := sess.Info()
+	if info.Status == session.StatusWaiting {
+		if info.HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can set up the starting position"})
+			return
+		}
+		if err := s.manager.SetSetup(ctx, sess, sp.Position); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.broadcastState(sess)
+		return
+	}
+	if !info.Practice {
+		sendWSMsg(send, "error", errorPayload{Message: "the starting position can only be changed before the game starts"})
+		return
+	}
+	if len(sp.Position) == 0 || string(sp.Position) == "null" {
+		sendWSMsg(send, "error", errorPayload{Message: "invalid setup payload"})
+		return
+	}
+	err := s.manager.SetUpPosition(ctx, sess, sp.Position)
+	if s.failIfPanicked(ctx, sess, err) {
+		return
+	}
+	if err != nil {
+		sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+		return
+	}
+	s.broadcastState(sess)
+	s.playBots(ctx, sess, 0)
+}
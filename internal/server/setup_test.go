@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSSetupBeforeStart(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	setup := setupPayload{Position: json.RawMessage(`{"board":"O...X...."}`)}
+	sendWS(ctx, bob, "setup", setup)
+	if msg := readError(t, ctx, bob); msg != "only the host can set up the starting position" {
+		t.Fatalf("expected the guest refused, got %q", msg)
+	}
+	sendWS(ctx, alice, "setup", setupPayload{Position: json.RawMessage(`{"board":"XXXOO...."}`)})
+	if msg := readError(t, ctx, alice); !strings.Contains(msg, "line") {
+		t.Fatalf("expected a finished board refused, got %q", msg)
+	}
+
+	sendWS(ctx, alice, "setup", setup)
+	readState(t, ctx, alice)
+	if sp := readState(t, ctx, bob); string(sp.SessionInfo.Setup) != `{"board":"O...X...."}` {
+		t.Fatalf("expected everyone shown the setup, got %s", sp.SessionInfo.Setup)
+	}
+
+	sendWS(ctx, alice, "start", nil)
+	sp := readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	if board := stateMap(t, sp)["board"].([]any); board[0] != float64(2) || board[4] != float64(1) {
+		t.Fatalf("expected the match to start from the setup, got %v", board)
+	}
+	sess, _ := env.mgr.Get(code)
+	tr, err := sess.Transcript()
+	if err != nil || string(tr.Setup) != `{"board":"O...X...."}` {
+		t.Fatalf("expected the setup in the transcript, got %s %v", tr.Setup, err)
+	}
+	sendWS(ctx, alice, "setup", setupPayload{})
+	if msg := readError(t, ctx, alice); msg != "the starting position can only be changed before the game starts" {
+		t.Fatalf("expected the setup fixed once the match starts, got %q", msg)
+	}
+}
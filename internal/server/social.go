@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultRecentOpponents = 10
+	maxRecentOpponents     = 50
+)
+
+type friendRequest struct {
+	FriendID string `json:"friendId"`
+}
+
+func (s *Server) handleListFriends(w http.ResponseWriter, r *http.Request) {
+	list, err := s.manager.Friends(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleRequestFriend sends a friend request. Like answering or
+// withdrawing one, it is only for the player's own guest browser.
+func (s *Server) handleRequestFriend(w http.ResponseWriter, r *http.Request) {
+	var req friendRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, r.PathValue("id")) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	if err := s.manager.RequestFriend(r.Context(), r.PathValue("id"), req.FriendID); err != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"status": "requested"})
+}
+
+func (s *Server) handleAcceptFriend(w http.ResponseWriter, r *http.Request) {
+	if !s.isGuest(r, r.PathValue("id")) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	if err := s.manager.AcceptFriend(r.Context(), r.PathValue("id"), r.PathValue("friend")); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "accepted"})
+}
+
+// handleRemoveFriend unfriends, declines a request, or withdraws one.
+func (s *Server) handleRemoveFriend(w http.ResponseWriter, r *http.Request) {
+	if !s.isGuest(r, r.PathValue("id")) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	if err := s.manager.RemoveFriend(r.Context(), r.PathValue("id"), r.PathValue("friend")); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+func (s *Server) handleRecentOpponents(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRecentOpponents
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRecentOpponents {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 50"})
+			return
+		}
+		limit = n
+	}
+	opponents, err := s.manager.RecentOpponents(r.Context(), r.PathValue("id"), limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, opponents)
+}
+
+// handlePlayerSessions lists the sessions a player is in that are not
+// over, for a "My games" dashboard. Their private sessions are listed
+// only to their own guest browser.
+func (s *Server) handlePlayerSessions(w http.ResponseWriter, r *http.Request) {
+	playerID := r.PathValue("id")
+	sessions, err := s.manager.PlayerSessions(r.Context(), playerID, s.isGuest(r, playerID))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/session"
+)
+
+func TestFriendsAndRecentOpponents(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	alice, aliceID := guestBrowser(t, env.ts)
+	bob, bobID := guestBrowser(t, env.ts)
+	resp := browserPost(t, alice, env.ts.URL+"/api/players/"+aliceID+"/friends", `{"friendId":"`+bobID+`"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	resp = browserPost(t, bob, env.ts.URL+"/api/players/"+bobID+"/friends/"+aliceID+"/accept", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	// Bob plays alice while connected, so shows up online and recent
+	resp = browserPost(t, alice, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"alice"}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	code := created.Code
+	bobConn := wsConnectAs(t, env.ts, bob, code, bobID)
+	defer bobConn.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, bobConn)
+	start, _ := http.NewRequest("POST", env.ts.URL+"/api/sessions/"+code+"/start", nil)
+	start.Header.Set("Authorization", "Bearer "+created.Token)
+	startResp, err := http.DefaultClient.Do(start)
+	if err != nil {
+		t.Fatalf("POST start: %v", err)
+	}
+	startResp.Body.Close()
+	if startResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the match started, got %d", startResp.StatusCode)
+	}
+
+	listResp, err := http.Get(env.ts.URL + "/api/players/" + aliceID + "/friends")
+	if err != nil {
+		t.Fatalf("list friends: %v", err)
+	}
+	defer listResp.Body.Close()
+	var list session.FriendList
+	json.NewDecoder(listResp.Body).Decode(&list)
+	if len(list.Friends) != 1 || !list.Friends[0].Online {
+		t.Fatalf("expected bob online, got %+v", list)
+	}
+
+	recentResp, err := http.Get(env.ts.URL + "/api/players/" + aliceID + "/recent")
+	if err != nil {
+		t.Fatalf("recent: %v", err)
+	}
+	defer recentResp.Body.Close()
+	var recent []session.RecentOpponent
+	json.NewDecoder(recentResp.Body).Decode(&recent)
+	if len(recent) != 1 || recent[0].PlayerID != bobID || !recent[0].Friend || recent[0].SessionCode != code {
+		t.Fatalf("unexpected recent opponents: %+v", recent)
+	}
+
+	delResp := browserDo(t, alice, "DELETE", env.ts.URL+"/api/players/"+aliceID+"/friends/"+bobID, "")
+	delResp.Body.Close()
+	if delResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", delResp.StatusCode)
+	}
+}
+
+// TestFriendsOnlyForOwnBrowser checks that no one sends, answers or
+// removes friend requests as another player.
+func TestFriendsOnlyForOwnBrowser(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, aliceID := guestBrowser(t, env.ts)
+	mallory, _ := guestBrowser(t, env.ts)
+	_, bobID := guestBrowser(t, env.ts)
+	resp := browserPost(t, alice, env.ts.URL+"/api/players/"+aliceID+"/friends", `{"friendId":"`+bobID+`"}`)
+	resp.Body.Close()
+
+	for _, r := range []struct {
+		method, path, body string
+	}{
+		{"POST", "/api/players/" + aliceID + "/friends", `{"friendId":"mallory"}`},
+		{"POST", "/api/players/" + bobID + "/friends/" + aliceID + "/accept", ""},
+		{"DELETE", "/api/players/" + bobID + "/friends/" + aliceID, ""},
+		{"DELETE", "/api/players/" + aliceID + "/friends/" + bobID, ""},
+	} {
+		resp := browserDo(t, mallory, r.method, env.ts.URL+r.path, r.body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s from another browser: expected 403, got %d", r.method, r.path, resp.StatusCode)
+		}
+	}
+	noCookie := postJSON(t, env.ts.URL+"/api/players/"+bobID+"/friends/"+aliceID+"/accept", "")
+	noCookie.Body.Close()
+	if noCookie.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 accepting without a guest cookie, got %d", noCookie.StatusCode)
+	}
+
+	list, err := env.mgr.Friends(t.Context(), aliceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Friends) != 0 || len(list.Outgoing) != 1 || list.Outgoing[0] != bobID {
+		t.Fatalf("expected alice's request to bob still pending alone, got %+v", list)
+	}
+}
+
+func TestRecentOpponentsLimitValidation(t *testing.T) {
+	env := setupTestEnv(t)
+	resp, err := http.Get(env.ts.URL + "/api/players/alice/recent?limit=0")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestPlayerSessions(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	playing := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	bob := wsConnect(t, env.ts, playing, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, bob)
+	resp := startViaAPI(t, env, playing)
+	resp.Body.Close()
+	waiting := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	createSessionViaAPI(t, env.ts, "tictactoe", "carol")
+
+	mine := func(player string) map[string]session.Listing {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + "/api/players/" + player + "/sessions")
+		if err != nil {
+			t.Fatalf("sessions of %s: %v", player, err)
+		}
+		defer resp.Body.Close()
+		var listings []session.Listing
+		json.NewDecoder(resp.Body).Decode(&listings)
+		byCode := make(map[string]session.Listing)
+		for _, l := range listings {
+			byCode[l.Code] = l
+		}
+		return byCode
+	}
+	alice, bobs := mine("alice"), mine("bob")
+	if len(alice) != 2 || alice[waiting].Status != session.StatusWaiting || alice[playing].Status != session.StatusPlaying {
+		t.Fatalf("expected alice's waiting and playing sessions, got %+v", alice)
+	}
+	if len(bobs) != 1 || alice[playing].YourTurn == bobs[playing].YourTurn {
+		t.Fatalf("expected exactly one of alice and bob to have the move, got %+v and %+v", alice, bobs)
+	}
+	if len(mine("dave")) != 0 {
+		t.Error("expected no sessions for a player in none")
+	}
+
+	// A private session is listed to its player's own browser alone
+	browser, guest := guestBrowser(t, env.ts)
+	resp = browserPost(t, browser, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"erin","private":true}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if _, ok := mine(guest)[created.Code]; ok {
+		t.Error("expected a private session hidden from others")
+	}
+	resp, err := browser.Get(env.ts.URL + "/api/players/" + guest + "/sessions")
+	if err != nil {
+		t.Fatalf("own sessions: %v", err)
+	}
+	defer resp.Body.Close()
+	var own []session.Listing
+	json.NewDecoder(resp.Body).Decode(&own)
+	if len(own) != 1 || own[0].Code != created.Code || !own[0].Private {
+		t.Errorf("expected the guest's own private session, got %+v", own)
+	}
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"games/internal/i18n"
+)
+
+// staticHandler serves the web frontend. Pages are HTML templates, run
+// with no data here; a page may {{template "meta" .}} for the tags link
+// previews read, which the server fills in for share links, and {{lang}}
+// and {{dir}} for the language and text direction of the request's
+// locale. Pages link
+// their scripts and styles with ?v=<version>, where the version is a hash
+// of the assets, so those URLs can be cached forever and change whenever
+// a build does. Other
+// requests revalidate against the same version as ETag. Unknown paths
+// that aren't API calls or files get index.html for client-side routing.
+// Text assets are gzipped once and kept in memory. In dev mode files are
+// read from disk on every request and never cached.
+type staticHandler struct {
+	fsys    fs.FS
+	version string
+	modTime time.Time
+	dev     bool
+
+	assets sync.Map // name, and locale for pages -> *asset, unused in dev mode
+}
+
+// asset is a file ready to serve, with its gzipped form if worth sending.
+type asset struct {
+	data []byte
+	gz   []byte // nil when the file does not compress usefully
+}
+
+func newStaticHandler(fsys fs.FS) *staticHandler {
+	return &staticHandler{fsys: fsys, version: contentVersion(fsys), modTime: buildTime()}
+}
+
+const immutableCache = "public, max-age=31536000, immutable"
+
+// minGzipSize is the smallest file worth compressing.
+const minGzipSize = 512
+
+// contentTypes are set explicitly rather than left to the system MIME table,
+// which differs between hosts and may lack a charset.
+var contentTypes = map[string]string{
+	".html":        "text/html; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".webmanifest": "application/manifest+json",
+	".svg":         "image/svg+xml",
+	".png":         "image/png",
+	".ico":         "image/x-icon",
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+	if info, err := fs.Stat(h.fsys, name); err == nil && info.IsDir() {
+		name = path.Join(name, "index.html")
+	}
+	loc := requestLocale(r)
+	a, err := h.asset(name, loc)
+	if errors.Is(err, fs.ErrNotExist) && isAppRoute(name) {
+		// Client-side routes such as /s/abc123 load the app shell.
+		name = "index.html"
+		a, err = h.asset(name, loc)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "read "+name, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	header := w.Header()
+	if ct, ok := contentTypes[path.Ext(name)]; ok {
+		header.Set("Content-Type", ct)
+	}
+	header.Set("X-Content-Type-Options", "nosniff")
+	version := h.version
+	if isPage(name) {
+		rememberLocale(w, r)
+		header.Set("Content-Language", loc.Tag)
+		header.Add("Vary", "Accept-Language, Cookie")
+		version += "-" + loc.Tag
+	}
+	if h.dev {
+		header.Set("Cache-Control", "no-store")
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(a.data))
+		return
+	}
+	if r.URL.Query().Get("v") == h.version {
+		header.Set("Cache-Control", immutableCache)
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+	data, etag := a.data, version
+	if a.gz != nil {
+		header.Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r) {
+			header.Set("Content-Encoding", "gzip")
+			data, etag = a.gz, version+"-gz"
+		}
+	}
+	header.Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, name, h.modTime, bytes.NewReader(data))
+}
+
+// asset loads a file, rendering HTML pages for loc and compressing text.
+// Outside dev mode the result is cached, as embedded files never change.
+func (h *staticHandler) asset(name string, loc *i18n.Locale) (*asset, error) {
+	key := name
+	if isPage(name) {
+		key += "@" + loc.Tag
+	}
+	if !h.dev {
+		if a, ok := h.assets.Load(key); ok {
+			return a.(*asset), nil
+		}
+	}
+	var data []byte
+	var err error
+	if isPage(name) {
+		data, err = h.page(name, nil, loc)
+	} else {
+		data, err = fs.ReadFile(h.fsys, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a := &asset{data: data}
+	if h.dev {
+		return a, nil
+	}
+	if compressible(name) && len(a.data) >= minGzipSize {
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(a.data)
+		zw.Close()
+		if buf.Len() < len(a.data) {
+			a.gz = buf.Bytes()
+		}
+	}
+	h.assets.Store(key, a)
+	return a, nil
+}
+
+// pageMeta describes the page a link leads to, for the previews chat
+// apps and social sites show.
+type pageMeta struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string // empty when there is no picture
+}
+
+// metaTemplate renders a page's pageMeta as Open Graph and Twitter card
+// tags, or nothing without one.
+const metaTemplate = `{{define "meta"}}{{with .}}
+    <meta property="og:type" content="website">
+    <meta property="og:title" content="{{.Title}}">
+    <meta property="og:description" content="{{.Description}}">
+    <meta property="og:url" content="{{.URL}}">{{if .Image}}
+    <meta property="og:image" content="{{.Image}}">
+    <meta name="twitter:card" content="summary">{{end}}{{end}}{{end}}`
+
+// page renders the page name with meta for loc, its asset links
+// versioned outside dev mode.
+func (h *staticHandler) page(name string, meta *pageMeta, loc *i18n.Locale) ([]byte, error) {
+	data, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"lang": func() string { return loc.Tag },
+		"dir":  func() string { return loc.Dir },
+	}).Parse(metaTemplate)
+	if err == nil {
+		_, err = tmpl.Parse(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, meta); err != nil {
+		return nil, fmt.Errorf("render %s: %w", name, err)
+	}
+	if h.dev {
+		return b.Bytes(), nil
+	}
+	return versionAssetLinks(b.Bytes(), h.version), nil
+}
+
+// servePage serves a page rendered for one request, such as with the
+// preview tags of a share link.
+func (h *staticHandler) servePage(w http.ResponseWriter, r *http.Request, name string, meta *pageMeta) {
+	loc := requestLocale(r)
+	data, err := h.page(name, meta, loc)
+	if err != nil {
+		http.Error(w, "read "+name, http.StatusInternalServerError)
+		return
+	}
+	rememberLocale(w, r)
+	header := w.Header()
+	header.Set("Content-Type", contentTypes[".html"])
+	header.Set("Content-Language", loc.Tag)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", "no-cache")
+	w.Write(data)
+}
+
+// isAppRoute reports whether a missing path should fall back to the app
+// shell: anything outside the API that doesn't look like a file.
+func isAppRoute(name string) bool {
+	return name != "api" && !strings.HasPrefix(name, "api/") && path.Ext(name) == ""
+}
+
+func isPage(name string) bool {
+	return path.Ext(name) == ".html"
+}
+
+func compressible(name string) bool {
+	switch path.Ext(name) {
+	case ".html", ".css", ".js", ".json", ".webmanifest", ".svg":
+		return true
+	}
+	return false
+}
+
+// acceptsGzip reports whether the client lists gzip in Accept-Encoding
+// without refusing it with q=0.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			return err == nil && v > 0
+		}
+		return true
+	}
+	return false
+}
+
+var assetLink = regexp.MustCompile(`((?:src|href)="/[^"?]+\.(?:js|css))"`)
+
+// versionAssetLinks appends ?v=version to local script and stylesheet links.
+func versionAssetLinks(html []byte, version string) []byte {
+	return assetLink.ReplaceAll(html, []byte(`$1?v=`+version+`"`))
+}
+
+// contentVersion hashes every file in fsys, so the version changes exactly
+// when an asset does.
+func contentVersion(fsys fs.FS) string {
+	h := sha256.New()
+	fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		f, err := fsys.Open(p)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		io.WriteString(h, p)
+		io.Copy(h, f)
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// buildTime is the commit time recorded in the binary's build info, used as
+// Last-Modified for embedded assets, or the process start time without it.
+func buildTime() time.Time {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.time" {
+				if t, err := time.Parse(time.RFC3339, s.Value); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return time.Now().Truncate(time.Second)
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestStatic() *staticHandler {
+	return newStaticHandler(fstest.MapFS{
+		"index.html":    &fstest.MapFile{Data: []byte(`<link href="/css/style.css"><script src="/js/app.js"></script><a href="/">home</a>`)},
+		"js/app.js":     &fstest.MapFile{Data: []byte("console.log(1)")},
+		"css/style.css": &fstest.MapFile{Data: []byte("body{}")},
+	})
+}
+
+func TestStaticVersionsAssetLinks(t *testing.T) {
+	h := newTestStatic()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	body, _ := io.ReadAll(rec.Body)
+	v := "?v=" + h.version
+	if !strings.Contains(string(body), `/js/app.js`+v) || !strings.Contains(string(body), `/css/style.css`+v) {
+		t.Fatalf("expected versioned asset links, got %s", body)
+	}
+	if !strings.Contains(string(body), `href="/"`) {
+		t.Fatalf("expected page links untouched, got %s", body)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Fatalf("expected page to revalidate, got %q", got)
+	}
+}
+
+func TestStaticImmutableWhenVersioned(t *testing.T) {
+	h := newTestStatic()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/js/app.js?v="+h.version, nil))
+	if got := rec.Header().Get("Cache-Control"); got != immutableCache {
+		t.Fatalf("expected immutable caching, got %q", got)
+	}
+
+	// A stale version must not be cached forever.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/js/app.js?v=old", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Fatalf("expected revalidation for stale version, got %q", got)
+	}
+}
+
+func TestStaticETagNotModified(t *testing.T) {
+	h := newTestStatic()
+	req := httptest.NewRequest("GET", "/js/app.js", nil)
+	req.Header.Set("If-None-Match", `"`+h.version+`"`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+}
+
+func TestStaticDevModeNoStore(t *testing.T) {
+	h := newTestStatic()
+	h.dev = true
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("expected no-store in dev mode, got %q", got)
+	}
+	if rec.Header().Get("ETag") != "" {
+		t.Fatal("expected no ETag in dev mode")
+	}
+	if body := rec.Body.String(); strings.Contains(body, "?v=") {
+		t.Fatalf("expected unversioned links in dev mode, got %s", body)
+	}
+}
+
+func TestStaticPageLocale(t *testing.T) {
+	h := newStaticHandler(fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<html lang="{{lang}}" dir="{{dir}}"></html>`)},
+	})
+	get := func(path, acceptLanguage, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: langCookie, Value: cookie})
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, c := range []struct {
+		path, acceptLanguage, cookie, want string
+	}{
+		{"/", "", "", `<html lang="en" dir="ltr">`},
+		{"/", "ar-SA,en;q=0.8", "", `<html lang="ar" dir="rtl">`},
+		{"/", "ar", "en", `<html lang="en" dir="ltr">`},
+		{"/?lang=ar", "en", "en", `<html lang="ar" dir="rtl">`},
+	} {
+		rec := get(c.path, c.acceptLanguage, c.cookie)
+		if body := rec.Body.String(); !strings.HasPrefix(body, c.want) {
+			t.Errorf("%s with %q and cookie %q: got %s, want %s", c.path, c.acceptLanguage, c.cookie, body, c.want)
+		}
+	}
+	rec := get("/?lang=ar", "", "")
+	if got := rec.Result().Cookies(); len(got) != 1 || got[0].Name != langCookie || got[0].Value != "ar" {
+		t.Fatalf("expected the chosen locale remembered, got %v", got)
+	}
+	if rec.Header().Get("ETag") == get("/", "", "").Header().Get("ETag") {
+		t.Fatal("expected each locale's page its own ETag")
+	}
+	if vary := rec.Header().Get("Vary"); !strings.Contains(vary, "Accept-Language") {
+		t.Fatalf("expected the page to vary by language, got %q", vary)
+	}
+}
+
+func TestStaticNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestStatic().ServeHTTP(rec, httptest.NewRequest("GET", "/missing.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestStaticGzip(t *testing.T) {
+	js := strings.Repeat("console.log('hello');\n", 100)
+	h := newStaticHandler(fstest.MapFS{"js/app.js": &fstest.MapFile{Data: []byte(js)}})
+
+	req := httptest.NewRequest("GET", "/js/app.js", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expected gzip encoding")
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/javascript; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != js {
+		t.Fatal("decompressed body differs from file")
+	}
+
+	// Clients that refuse gzip get the plain file.
+	req = httptest.NewRequest("GET", "/js/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != js {
+		t.Fatal("expected uncompressed response")
+	}
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatal("expected nosniff")
+	}
+}
+
+func TestStaticAppRouteFallback(t *testing.T) {
+	h := newTestStatic()
+	for path, want := range map[string]int{
+		"/s/abc123":       http.StatusOK,
+		"/missing.js":     http.StatusNotFound,
+		"/api/nope":       http.StatusNotFound,
+		"/js/missing.css": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+		if want == http.StatusOK && !strings.Contains(rec.Body.String(), "/js/app.js") {
+			t.Fatalf("%s: expected index.html, got %s", path, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/game"
+)
+
+// stressIterations is how many operations TestSessionStress makes in
+// all. make race raises it and runs under the race detector, as the gate
+// for changes to how sessions lock.
+var stressIterations = flag.Int("stress.iterations", 400, "operations TestSessionStress makes against one session")
+
+// stressConn is a connection whose messages are read and checked as fast
+// as the server sends them.
+type stressConn struct {
+	conn *websocket.Conn
+	// done closes once the final ping, with client time -1, is answered:
+	// everything sent before it has been handled.
+	done chan struct{}
+	// token is the seat's join secret, to reconnect with.
+	token string
+}
+
+// TestSessionStress hammers one session from many goroutines at once:
+// players move, reconnect and probe the clock, while spectators come and
+// go, broadcasts run outside any message and REST reads poll the
+// session. Every message every connection receives must decode to a type
+// the protocol defines, and afterwards the stored move log must replay to
+// the live match.
+func TestSessionStress(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.registry.Register(benchGame{})
+	var readers sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	defer readers.Wait()
+	defer cancel()
+	sess, err := env.mgr.CreateWithOptions(ctx, "bench", map[string]int{"cells": 16})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	serverTypes := make(map[string]bool)
+	for _, spec := range wsServerMessages {
+		serverTypes[spec.Type] = true
+	}
+	dial := func(join joinPayload) (*stressConn, error) {
+		conn, _, err := websocket.Dial(ctx, wsURL(env.ts, sess.Code), nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := sendWS(ctx, conn, "join", join); err != nil {
+			conn.CloseNow()
+			return nil, err
+		}
+		// A seat's first connection is given its join secret, and another
+		// player's events may come ahead of the state
+		sc := &stressConn{conn: conn, done: make(chan struct{}), token: join.Token}
+		msg, err := readWS(ctx, conn)
+		for err == nil && (msg.Type == "events" || msg.Type == "seat") {
+			var seat seatPayload
+			if msg.Type == "seat" && json.Unmarshal(msg.Payload, &seat) == nil {
+				sc.token = seat.Token
+			}
+			msg, err = readWS(ctx, conn)
+		}
+		if err != nil || msg.Type != "state" {
+			conn.CloseNow()
+			return nil, fmt.Errorf("%s joined without a state: %v %s", join.PlayerID, err, msg.Payload)
+		}
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				_, data, err := conn.Read(ctx)
+				if err != nil {
+					return
+				}
+				var msg WSMessage
+				if err := json.Unmarshal(data, &msg); err != nil || !serverTypes[msg.Type] {
+					t.Errorf("%s received a malformed message: %s", join.PlayerID, data)
+					continue
+				}
+				var pp pongPayload
+				if msg.Type == "pong" && json.Unmarshal(msg.Payload, &pp) == nil && pp.ClientTime == "-1" {
+					close(sc.done)
+				}
+			}
+		}()
+		return sc, nil
+	}
+
+	const players, workers = 4, 8
+	seats := make([]*stressConn, players)
+	for i := range seats {
+		if seats[i], err = dial(joinPayload{PlayerID: fmt.Sprintf("player-%d", i)}); err != nil {
+			t.Fatalf("join: %v", err)
+		}
+	}
+	// player-0 joined first, so hosts
+	start, _ := http.NewRequest("POST", env.ts.URL+"/api/sessions/"+sess.Code+"/start", nil)
+	start.Header.Set("Authorization", "Bearer "+seats[0].token)
+	resp, err := http.DefaultClient.Do(start)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("start: %d", resp.StatusCode)
+	}
+
+	tick := actionPayload{Action: game.Action{Type: "tick"}}
+	perWorker := max(*stressIterations/workers, 1)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(w), 1))
+			if w < players {
+				// A seated player moves, reconnects and probes the clock
+				pid := fmt.Sprintf("player-%d", w)
+				for range perWorker {
+					var err error
+					switch n := rng.IntN(10); {
+					case n < 7:
+						err = sendWS(ctx, seats[w].conn, "action", tick)
+					case n < 9:
+						err = sendWS(ctx, seats[w].conn, "ping", pingPayload{ClientTime: "1"})
+					default:
+						old := seats[w]
+						if seats[w], err = dial(joinPayload{PlayerID: pid, Token: old.token}); err == nil {
+							old.conn.CloseNow()
+						}
+					}
+					if err != nil {
+						t.Errorf("%s: %v", pid, err)
+						return
+					}
+				}
+				return
+			}
+			// Everyone else watches, broadcasts and polls
+			for i := range perWorker {
+				switch rng.IntN(4) {
+				case 0:
+					sc, err := dial(joinPayload{PlayerID: fmt.Sprintf("watcher-%d-%d", w, i), Spectate: true})
+					if err != nil {
+						t.Errorf("spectate: %v", err)
+						return
+					}
+					sc.conn.CloseNow()
+				case 1:
+					env.srv.broadcastState(sess)
+				case 2, 3:
+					path := "/api/sessions/" + sess.Code
+					if rng.IntN(2) == 0 {
+						path += "/scoreboard"
+					}
+					resp, err := http.Get(env.ts.URL + path)
+					if err != nil {
+						t.Errorf("get %s: %v", path, err)
+						return
+					}
+					resp.Body.Close()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	// Every seat's last ping is answered only after its earlier actions
+	for _, sc := range seats {
+		if err := sendWS(ctx, sc.conn, "ping", pingPayload{ClientTime: "-1"}); err != nil {
+			t.Fatalf("final ping: %v", err)
+		}
+		select {
+		case <-sc.done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("final ping not answered")
+		}
+		sc.conn.CloseNow()
+	}
+	readers.Wait()
+
+	stored, err := env.mgr.StoredMatch(ctx, sess.Code)
+	if err != nil {
+		t.Fatalf("stored match: %v", err)
+	}
+	replayed, err := stored.StateAt(len(stored.Moves))
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	sess.RLock()
+	live, _ := json.Marshal(sess.Match)
+	turn := sess.Match.(*benchMatch).Turn
+	sess.RUnlock()
+	if turn == 0 || turn != len(stored.Moves) {
+		t.Errorf("expected every move the match took stored, got %d moves for %d turns", len(stored.Moves), turn)
+	}
+	if !bytes.Equal(replayed, live) || !bytes.Equal(stored.SavedState(), live) {
+		t.Errorf("stored match diverged from the live one:\nlive     %s\nreplayed %s\nsaved    %s", live, replayed, stored.SavedState())
+	}
+}
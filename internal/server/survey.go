@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"games/internal/event"
+	"games/internal/session"
+	"games/internal/storage"
+)
+
+// surveyEventBuffer is how many events the survey prompter may fall
+// behind the bus before it misses some.
+const surveyEventBuffer = 256
+
+// surveyPayload asks a player how the match that just finished went.
+type surveyPayload struct {
+	SessionCode string   `json:"sessionCode"`
+	GameType    string   `json:"gameType"`
+	Opponents   []string `json:"opponents"`
+}
+
+type surveysResponse struct {
+	Surveys []session.Survey `json:"surveys"`
+}
+
+// promptSurveys sends a survey message to the connected players of each
+// match that finishes, as often as the manager's survey interval allows,
+// until the channel is closed.
+func (s *Server) promptSurveys(events <-chan event.Event) {
+	for e := range events {
+		if e.Type != event.MatchFinished {
+			continue
+		}
+		sess, ok := s.manager.Get(e.SessionCode)
+		if !ok || sess.Info().Status != session.StatusFinished {
+			continue
+		}
+		connected := slices.DeleteFunc(slices.Clone(e.Players), func(id string) bool { return !sess.Connected(id) })
+		for _, id := range s.manager.PromptSurveys(sess, connected) {
+			opponents := slices.DeleteFunc(slices.Clone(e.Players), func(p string) bool { return p == id })
+			for _, send := range sess.PlayerSends(id) {
+				sendWSMsg(send, "survey", surveyPayload{SessionCode: e.SessionCode, GameType: e.GameType, Opponents: opponents})
+			}
+		}
+	}
+}
+
+// answerSurvey stores a player's answer to the survey about the session's
+// last match.
+func (s *Server) answerSurvey(ctx context.Context, sess *session.Session, playerID string, send chan []byte, r session.SurveyResponse) {
+	if err := s.manager.SubmitSurvey(ctx, sess.Code, playerID, r); err != nil {
+		sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+	}
+}
+
+// handleAdminSurveys lists survey answers, newest first, filtered by
+// ?game=, ?player= and ?bugs=1 for bug reports alone, up to ?limit= of
+// them (100 by default).
+func (s *Server) handleAdminSurveys(w http.ResponseWriter, r *http.Request, entry *storage.AuditRow) {
+	q := r.URL.Query()
+	f := storage.SurveyFilter{
+		GameType: q.Get("game"),
+		PlayerID: q.Get("player"),
+		Bugs:     q.Get("bugs") == "1",
+		Limit:    100,
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		f.Limit = n
+	}
+	entry.PlayerID = f.PlayerID
+	entry.Detail = r.URL.RawQuery
+	surveys, err := s.manager.Surveys(r.Context(), f)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, surveysResponse{Surveys: surveys})
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"games/internal/session"
+
+	"nhooyr.io/websocket"
+)
+
+func TestSurvey(t *testing.T) {
+	env := setupTestEnv(t)
+	env.srv.SetAdminTokens(map[string]string{"secret": "root"})
+	env.mgr.SetSurveyInterval(time.Hour)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	code := sess.Code
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	// Nothing to answer before the match ends
+	sendWS(ctx, alice, "survey_answer", session.SurveyResponse{Fun: 5})
+	if msg := wsRead(ctx, t, alice); msg.Type != "error" {
+		t.Fatalf("expected an answer without a survey refused, got %s", msg.Type)
+	}
+
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		conn := []*websocket.Conn{alice, bob}[i%2]
+		sendWS(ctx, conn, "action", makeAction(t, cell))
+		readState(t, ctx, alice)
+		readState(t, ctx, bob)
+	}
+
+	for _, c := range []struct {
+		conn     *websocket.Conn
+		opponent string
+	}{{alice, "bob"}, {bob, "alice"}} {
+		var sp surveyPayload
+		for msg := wsRead(ctx, t, c.conn); ; msg = wsRead(ctx, t, c.conn) {
+			if msg.Type == "survey" {
+				json.Unmarshal(msg.Payload, &sp)
+				break
+			}
+		}
+		if sp.SessionCode != code || sp.GameType != "tictactoe" || !slices.Equal(sp.Opponents, []string{c.opponent}) {
+			t.Fatalf("unexpected survey %+v", sp)
+		}
+	}
+
+	sendWS(ctx, alice, "survey_answer", session.SurveyResponse{Fun: 4, Conduct: 5})
+	// The pong comes once the answer is stored
+	sendWS(ctx, alice, "ping", pingPayload{})
+	if msg := wsRead(ctx, t, alice); msg.Type != "pong" {
+		t.Fatalf("expected pong, got %s %s", msg.Type, msg.Payload)
+	}
+	sendWS(ctx, bob, "survey_answer", session.SurveyResponse{Conduct: 2, Bug: "the board flickered"})
+	sendWS(ctx, bob, "survey_answer", session.SurveyResponse{Fun: 1})
+	if msg := wsRead(ctx, t, bob); msg.Type != "error" {
+		t.Fatalf("expected a second answer refused, got %s", msg.Type)
+	}
+
+	resp := adminRequest(t, http.MethodGet, env.ts.URL+"/api/admin/surveys?bugs=1", "secret", "")
+	var body surveysResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(body.Surveys) != 1 {
+		t.Fatalf("expected bob's bug report, got %d %+v", resp.StatusCode, body)
+	}
+	if s := body.Surveys[0]; s.PlayerID != "bob" || s.Bug != "the board flickered" || s.Conduct != 2 || len(s.Transcript) == 0 {
+		t.Fatalf("unexpected bug report %+v", s)
+	}
+	resp = adminRequest(t, http.MethodGet, env.ts.URL+"/api/admin/surveys?player=alice", "secret", "")
+	var alices surveysResponse
+	json.NewDecoder(resp.Body).Decode(&alices)
+	resp.Body.Close()
+	if len(alices.Surveys) != 1 || alices.Surveys[0].Fun != 4 || alices.Surveys[0].Bug != "" || alices.Surveys[0].Transcript != nil {
+		t.Fatalf("expected alice's rating without a transcript, got %+v", alices.Surveys)
+	}
+	if resp := adminRequest(t, http.MethodGet, env.ts.URL+"/api/admin/surveys?limit=0", "secret", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a bad limit refused, got %d", resp.StatusCode)
+	}
+	if resp := adminRequest(t, http.MethodGet, env.ts.URL+"/api/admin/surveys", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected admins only, got %d", resp.StatusCode)
+	}
+}
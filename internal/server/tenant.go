@@ -0,0 +1,69 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// tenantName is what a tenant may be called, as it appears in URLs.
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// CheckTenantName reports whether name may name a tenant: up to 32
+// lowercase letters, digits and dashes, not starting with a dash.
+func CheckTenantName(name string) error {
+	if !tenantName.MatchString(name) {
+		return fmt.Errorf("tenant %q: want up to 32 lowercase letters, digits and dashes", name)
+	}
+	return nil
+}
+
+// Tenants hosts isolated communities, such as a school or an office, in
+// one deployment. Each tenant is a Server of its own, with its own
+// sessions, listings, leaderboards and games, served under /t/{tenant}/;
+// every other path goes to the default server.
+type Tenants struct {
+	root    *Server
+	tenants map[string]http.Handler
+}
+
+// NewTenants creates a deployment whose default server is root.
+func NewTenants(root *Server) *Tenants {
+	return &Tenants{root: root, tenants: make(map[string]http.Handler)}
+}
+
+// Add serves srv as the tenant name. The links srv makes, such as share
+// links, point under /t/{name}/, and its latencies are reported with the
+// default server's. Tenants must be added before serving.
+func (t *Tenants) Add(name string, srv *Server) error {
+	if err := CheckTenantName(name); err != nil {
+		return err
+	}
+	if _, exists := t.tenants[name]; exists {
+		return fmt.Errorf("tenant %q already added", name)
+	}
+	srv.prefix = "/t/" + name
+	srv.metrics = t.root.metrics
+	t.tenants[name] = http.StripPrefix(srv.prefix, srv)
+	return nil
+}
+
+func (t *Tenants) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/t/")
+	if !ok {
+		t.root.ServeHTTP(w, r)
+		return
+	}
+	name, _, inside := strings.Cut(rest, "/")
+	h, ok := t.tenants[name]
+	if !ok {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return
+	}
+	if !inside {
+		http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestTenants(t *testing.T) {
+	root := setupTestEnv(t)
+	root.srv.registry.Register(benchGame{})
+	school := setupTestEnv(t)
+	tenants := NewTenants(root.srv)
+	if err := tenants.Add("school", school.srv); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	for _, name := range []string{"school", "Office", "-x", ""} {
+		if err := tenants.Add(name, setupTestEnv(t).srv); err == nil {
+			t.Errorf("expected %q refused", name)
+		}
+	}
+	ts := httptest.NewServer(tenants)
+	defer ts.Close()
+
+	code := createSessionViaAPI(t, ts, "tictactoe", "alice")
+	resp := postJSON(t, ts.URL+"/t/school/api/sessions", `{"gameType":"tictactoe","playerId":"carol"}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create in tenant: %d", resp.StatusCode)
+	}
+	if _, ok := school.mgr.Get(created.Code); !ok {
+		t.Fatal("expected the session in the tenant's manager")
+	}
+	if _, ok := root.mgr.Get(created.Code); ok {
+		t.Fatal("expected the tenant's session kept from the default site")
+	}
+
+	listed := func(path string) map[string]bool {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var body sessionPage
+		json.NewDecoder(resp.Body).Decode(&body)
+		codes := make(map[string]bool)
+		for _, s := range body.Sessions {
+			codes[s.Code] = true
+		}
+		return codes
+	}
+	if got := listed("/t/school/api/sessions"); !got[created.Code] || got[code] {
+		t.Errorf("expected only the tenant's session listed in it, got %v", got)
+	}
+	if got := listed("/api/sessions"); got[created.Code] || !got[code] {
+		t.Errorf("expected the default site to list its own sessions, got %v", got)
+	}
+
+	// Each site offers its own games
+	var games []struct{ Name string }
+	resp, _ = http.Get(ts.URL + "/t/school/api/games")
+	json.NewDecoder(resp.Body).Decode(&games)
+	resp.Body.Close()
+	if len(games) != 1 || games[0].Name != "tictactoe" {
+		t.Errorf("expected only tictactoe in the tenant, got %v", games)
+	}
+
+	// A tenant's sessions are played through its own path
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+ts.URL[len("http"):]+"/t/school/api/sessions/"+created.Code+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	sendWS(ctx, conn, "join", joinPayload{PlayerID: "carol", Token: created.Token})
+	readState(t, ctx, conn)
+	if _, _, err := websocket.Dial(ctx, wsURL(ts, created.Code), nil); err == nil {
+		t.Error("expected the tenant's session not found on the default site")
+	}
+
+	req := httptest.NewRequest("GET", "/t/school/api/sessions/"+created.Code+"/qr", nil)
+	req.Host = "games.example"
+	if got := school.srv.siteURL(req); got != "http://games.example/t/school" {
+		t.Errorf("expected links under the tenant's path, got %s", got)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for path, want := range map[string]int{
+		"/t/school":           http.StatusMovedPermanently,
+		"/t/school/":          http.StatusOK,
+		"/t/office/api/games": http.StatusNotFound,
+	} {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -22,8 +23,10 @@ import (
 // --- Test environment ---
 
 type testEnv struct {
-	ts  *httptest.Server
-	mgr *session.Manager
+	ts    *httptest.Server
+	mgr   *session.Manager
+	srv   *Server
+	store *storage.Store
 }
 
 func setupTestEnv(t *testing.T) *testEnv {
@@ -36,6 +39,9 @@ func setupTestEnv(t *testing.T) *testEnv {
 
 	reg := game.NewRegistry()
 	reg.Register(tictactoe.TicTacToe{})
+	for _, strategy := range tictactoe.Strategies() {
+		reg.RegisterStrategy("tictactoe", strategy)
+	}
 	mgr := session.NewManager(reg, store)
 
 	webFS := fstest.MapFS{
@@ -45,7 +51,7 @@ func setupTestEnv(t *testing.T) *testEnv {
 	ts := httptest.NewServer(srv)
 	t.Cleanup(ts.Close)
 
-	return &testEnv{ts: ts, mgr: mgr}
+	return &testEnv{ts: ts, mgr: mgr, srv: srv, store: store}
 }
 
 // --- Context helpers ---
@@ -72,31 +78,108 @@ func createSessionViaAPI(t *testing.T, ts *httptest.Server, gameType, playerID s
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		t.Fatalf("decode response: %v", err)
 	}
+	seatTokens.Lock()
+	seatTokens.bySeat[wsURL(ts, result.Code)+" "+result.PlayerID] = result.Token
+	seatTokens.Unlock()
 	return result.Code
 }
 
+// seatToken returns the token a seat was given, by creating the session
+// or by a "seat" message, for joining it other than through wsConnect.
+func seatToken(ts *httptest.Server, code, playerID string) string {
+	seatTokens.Lock()
+	defer seatTokens.Unlock()
+	return seatTokens.bySeat[wsURL(ts, code)+" "+playerID]
+}
+
+// startViaAPI starts a session over REST as its host, with the host seat's
+// token, claiming the seat if nothing has yet.
+func startViaAPI(t *testing.T, env *testEnv, code string) *http.Response {
+	t.Helper()
+	var token string
+	if sess, ok := env.mgr.Get(code); ok {
+		host := sess.Info().HostID
+		seat := wsURL(env.ts, code) + " " + host
+		if token = seatToken(env.ts, code, host); token == "" {
+			token = sess.ClaimSeat(host)
+			seatTokens.Lock()
+			seatTokens.bySeat[seat] = token
+			seatTokens.Unlock()
+		}
+	}
+	req, _ := http.NewRequest("POST", env.ts.URL+"/api/sessions/"+code+"/start", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST start: %v", err)
+	}
+	return resp
+}
+
 // --- WebSocket helpers ---
 
 func wsURL(ts *httptest.Server, code string) string {
 	return strings.Replace(ts.URL, "http://", "ws://", 1) + "/api/sessions/" + code + "/ws"
 }
 
+// seatTokens remembers the tokens "seat" messages give, by seat, so
+// wsConnect reconnects as a client would.
+var seatTokens = struct {
+	sync.Mutex
+	byConn map[*websocket.Conn]string // conn -> seat it joined
+	bySeat map[string]string          // seat -> token
+}{byConn: make(map[*websocket.Conn]string), bySeat: make(map[string]string)}
+
 // wsConnect dials a WebSocket, sends a join message, and returns the connection.
+// It presents the token of the seat if an earlier connection was given one.
 // The caller is responsible for closing the connection.
 func wsConnect(t *testing.T, ts *httptest.Server, code, playerID string) *websocket.Conn {
+	t.Helper()
+	return wsConnectAs(t, ts, nil, code, playerID)
+}
+
+// wsConnectAs is wsConnect from client, a browser holding a guest cookie,
+// or from no browser when client is nil.
+func wsConnectAs(t *testing.T, ts *httptest.Server, client *http.Client, code, playerID string) *websocket.Conn {
 	t.Helper()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	conn, _, err := websocket.Dial(ctx, wsURL(ts, code), nil)
+	var opts *websocket.DialOptions
+	if client != nil {
+		opts = &websocket.DialOptions{HTTPClient: client}
+	}
+	conn, _, err := websocket.Dial(ctx, wsURL(ts, code), opts)
 	if err != nil {
 		t.Fatalf("ws dial: %v", err)
 	}
-	if err := sendWS(ctx, conn, "join", joinPayload{PlayerID: playerID}); err != nil {
+	seat := wsURL(ts, code) + " " + playerID
+	seatTokens.Lock()
+	seatTokens.byConn[conn] = seat
+	token := seatTokens.bySeat[seat]
+	seatTokens.Unlock()
+	if err := sendWS(ctx, conn, "join", joinPayload{PlayerID: playerID, Token: token}); err != nil {
 		t.Fatalf("send join: %v", err)
 	}
 	return conn
 }
 
+// readPastSeat reads a WebSocket message, first recording the token of a
+// "seat" message for the seat conn joined.
+func readPastSeat(ctx context.Context, conn *websocket.Conn) (WSMessage, error) {
+	msg, err := readWS(ctx, conn)
+	if err != nil || msg.Type != "seat" {
+		return msg, err
+	}
+	var seat seatPayload
+	json.Unmarshal(msg.Payload, &seat)
+	seatTokens.Lock()
+	if key, ok := seatTokens.byConn[conn]; ok {
+		seatTokens.bySeat[key] = seat.Token
+	}
+	seatTokens.Unlock()
+	return readWS(ctx, conn)
+}
+
 // sendWS marshals and sends a typed WebSocket message. Returns an error on failure.
 func sendWS(ctx context.Context, conn *websocket.Conn, msgType string, payload any) error {
 	p, err := json.Marshal(payload)
@@ -135,17 +218,14 @@ func wsSend(ctx context.Context, t *testing.T, conn *websocket.Conn, msg WSMessa
 	}
 }
 
-// wsRead reads and unmarshals a WebSocket message, calling t.Fatal on error.
+// wsRead reads and unmarshals a WebSocket message, past any "seat" message,
+// calling t.Fatal on error.
 func wsRead(ctx context.Context, t *testing.T, conn *websocket.Conn) WSMessage {
 	t.Helper()
-	_, data, err := conn.Read(ctx)
+	msg, err := readPastSeat(ctx, conn)
 	if err != nil {
 		t.Fatalf("ws read: %v", err)
 	}
-	var msg WSMessage
-	if err := json.Unmarshal(data, &msg); err != nil {
-		t.Fatalf("unmarshal ws message: %v", err)
-	}
 	return msg
 }
 
@@ -155,10 +235,14 @@ func joinMsg(playerID string) WSMessage {
 	return WSMessage{Type: "join", Payload: payload}
 }
 
-// readState reads a WebSocket message and expects it to be a "state" message.
+// readState reads a WebSocket message and expects it to be a "state" message,
+// skipping any "seat" or "events" messages that precede it.
 func readState(t *testing.T, ctx context.Context, conn *websocket.Conn) statePayload {
 	t.Helper()
-	msg, err := readWS(ctx, conn)
+	msg, err := readPastSeat(ctx, conn)
+	for err == nil && msg.Type == "events" {
+		msg, err = readPastSeat(ctx, conn)
+	}
 	if err != nil {
 		t.Fatalf("read state: %v", err)
 	}
@@ -175,7 +259,7 @@ func readState(t *testing.T, ctx context.Context, conn *websocket.Conn) statePay
 // readError reads a WebSocket message and expects it to be an "error" message.
 func readError(t *testing.T, ctx context.Context, conn *websocket.Conn) string {
 	t.Helper()
-	msg, err := readWS(ctx, conn)
+	msg, err := readPastSeat(ctx, conn)
 	if err != nil {
 		t.Fatalf("read error: %v", err)
 	}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+)
+
+// DefaultMessageRate is how many messages a session connection is sent
+// per second, after a burst of as many, unless set with SetMessageRate.
+const DefaultMessageRate = 20
+
+// maxQueued bounds the messages other than state waiting on a throttled
+// connection, like the send channel's buffer does.
+const maxQueued = 64
+
+// statePrefix starts every encoded state message. A state supersedes the
+// one before it, so only the latest still waiting need be written.
+var statePrefix = []byte(`{"type":"state",`)
+
+// SetMessageRate caps the messages written to each session connection per
+// second. A rate of 0 or less writes them as fast as the client takes them.
+func (s *Server) SetMessageRate(rate float64) {
+	s.messageRate = rate
+}
+
+// writeLoop writes the messages queued on send until send is closed or
+// ctx is done, at most rate a second after a burst of as many. While a
+// connection waits its turn, a new state replaces any state still
+// waiting, so a flurry of changes arrives as its final state; other
+// messages keep their order.
+func writeLoop(ctx context.Context, send <-chan []byte, rate float64, write func([]byte) error) {
+	burst := max(rate, 1)
+	tokens, last := burst, time.Now()
+	var queue [][]byte
+	for {
+		var wait <-chan time.Time
+		if len(queue) > 0 {
+			if rate > 0 {
+				now := time.Now()
+				tokens = min(burst, tokens+now.Sub(last).Seconds()*rate)
+				last = now
+			}
+			if rate <= 0 || tokens >= 1 {
+				if err := write(queue[0]); err != nil {
+					return
+				}
+				queue = queue[1:]
+				tokens--
+				continue
+			}
+			wait = time.After(time.Duration((1 - tokens) / rate * float64(time.Second)))
+		}
+		select {
+		case msg, ok := <-send:
+			if !ok {
+				return
+			}
+			queue = enqueue(queue, msg)
+		case <-wait:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// enqueue adds msg to the messages waiting to be written. A state goes to
+// the back in place of any state already waiting; other messages are
+// dropped once maxQueued are waiting.
+func enqueue(queue [][]byte, msg []byte) [][]byte {
+	if bytes.HasPrefix(msg, statePrefix) {
+		queue = slices.DeleteFunc(queue, func(m []byte) bool { return bytes.HasPrefix(m, statePrefix) })
+		return append(queue, msg)
+	}
+	if len(queue) >= maxQueued {
+		return queue
+	}
+	return append(queue, msg)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStatePrefix(t *testing.T) {
+	if msg := encodeWSMsg("state", statePayload{}); !bytes.HasPrefix(msg, statePrefix) {
+		t.Fatalf("state message %s does not start with %s", msg, statePrefix)
+	}
+	if msg := encodeWSMsg("events", eventsPayload{}); bytes.HasPrefix(msg, statePrefix) {
+		t.Fatalf("events message %s taken for a state", msg)
+	}
+}
+
+// collect runs writeLoop over the messages queued on send, returning a
+// function that waits for n writes.
+func collect(t *testing.T, send chan []byte, rate float64) func(n int) []string {
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+	var mu sync.Mutex
+	var written []string
+	go writeLoop(ctx, send, rate, func(msg []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, string(msg))
+		return nil
+	})
+	return func(n int) []string {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			if len(written) >= n {
+				defer mu.Unlock()
+				return written
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %d writes", n)
+		return nil
+	}
+}
+
+func TestWriteLoopCoalescesStates(t *testing.T) {
+	send := make(chan []byte, 64)
+	var want []string
+	for i := range 20 {
+		e := fmt.Sprintf(`{"type":"events","payload":%d}`, i)
+		send <- []byte(e)
+		want = append(want, e)
+	}
+	// The burst is spent, so these wait and the first two states are
+	// superseded by the third
+	send <- []byte(`{"type":"state","payload":1}`)
+	send <- []byte(`{"type":"events","payload":20}`)
+	send <- []byte(`{"type":"state","payload":2}`)
+	send <- []byte(`{"type":"state","payload":3}`)
+	want = append(want, `{"type":"events","payload":20}`, `{"type":"state","payload":3}`)
+
+	got := collect(t, send, 20)(len(want))
+	time.Sleep(100 * time.Millisecond)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("wrote %v, want %v", got, want)
+	}
+}
+
+func TestWriteLoopUnthrottled(t *testing.T) {
+	send := make(chan []byte, 64)
+	for i := range 50 {
+		send <- []byte(fmt.Sprintf(`{"type":"state","payload":%d}`, i))
+	}
+	got := collect(t, send, 0)(50)
+	if got[0] != `{"type":"state","payload":0}` || got[49] != `{"type":"state","payload":49}` {
+		t.Fatalf("expected every message in order, got %v", got)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"games/internal/game"
+	"games/internal/session"
+)
+
+// maxThumbnails bounds how many sessions one thumbnails request reads.
+const maxThumbnails = 50
+
+// sessionThumbnail is the compact observer view of a session a lobby draws
+// a mini-board from.
+type sessionThumbnail struct {
+	Code     string         `json:"code"`
+	GameType string         `json:"gameType"`
+	Status   session.Status `json:"status"`
+	Players  []string       `json:"players"`
+	// State is the game's thumbnail of the match, or its spectator state
+	// if it has none; empty while waiting.
+	State any `json:"state,omitempty"`
+}
+
+// handleSessionStates returns thumbnails of up to 50 public sessions,
+// ?codes=a,b,c, in the order asked. Codes that are unknown or private are
+// left out, so a lobby can ask for what it lists without opening a
+// connection to each.
+func (s *Server) handleSessionStates(w http.ResponseWriter, r *http.Request) {
+	var codes []string
+	for _, code := range strings.Split(r.URL.Query().Get("codes"), ",") {
+		if code = strings.TrimSpace(code); code != "" && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 || len(codes) > maxThumbnails {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("codes must list 1 to %d sessions", maxThumbnails)})
+		return
+	}
+	thumbnails := []sessionThumbnail{}
+	for _, code := range codes {
+		sess, ok := s.manager.Get(code)
+		if !ok {
+			continue
+		}
+		t, err := thumbnail(sess)
+		if err != nil {
+			s.failIfPanicked(context.Background(), sess, err)
+			continue
+		}
+		if t != nil {
+			thumbnails = append(thumbnails, *t)
+		}
+	}
+	writeJSON(w, http.StatusOK, thumbnails)
+}
+
+// thumbnail reads a session's thumbnail, or nil for a private session. It
+// fails with a *session.PanicError if the game panics.
+func thumbnail(sess *session.Session) (*sessionThumbnail, error) {
+	sess.RLock()
+	defer sess.RUnlock()
+	info := sess.InfoLocked()
+	if info.Private {
+		return nil, nil
+	}
+	t := &sessionThumbnail{Code: info.Code, GameType: info.GameType, Status: info.Status, Players: info.Players}
+	if sess.Match == nil || info.Status == session.StatusWaiting || info.Status == session.StatusErrored {
+		return t, nil
+	}
+	err := session.Protect(func() error {
+		t.State = game.Thumbnail(sess.Match)
+		return nil
+	})
+	return t, err
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSessionStates(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	playing := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	waiting := createSessionViaAPI(t, env.ts, "tictactoe", "carol")
+	resp := postJSON(t, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"dave","private":true}`)
+	var private createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&private)
+	resp.Body.Close()
+
+	alice := wsConnect(t, env.ts, playing, "alice")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, playing, "bob")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "action", makeAction(t, 4))
+	readState(t, ctx, alice)
+
+	codes := strings.Join([]string{waiting, private.Code, "NOPE", playing, playing}, ",")
+	resp, err := http.Get(env.ts.URL + "/api/sessions/states?codes=" + codes)
+	if err != nil {
+		t.Fatalf("get states: %v", err)
+	}
+	var got []sessionThumbnail
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(got) != 2 {
+		t.Fatalf("expected the two public sessions, got %d %+v", resp.StatusCode, got)
+	}
+	if got[0].Code != waiting || got[0].State != nil {
+		t.Errorf("expected the waiting session first without a state, got %+v", got[0])
+	}
+	if got[1].Code != playing || got[1].State != "....X...." || len(got[1].Players) != 2 {
+		t.Errorf("expected the playing session's board, got %+v", got[1])
+	}
+
+	tooMany := make([]string, maxThumbnails+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint(i)
+	}
+	for _, q := range []string{"", ",", strings.Join(tooMany, ",")} {
+		resp, err := http.Get(env.ts.URL + "/api/sessions/states?codes=" + q)
+		if err != nil {
+			t.Fatalf("get states: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("codes=%q: expected 400, got %d", q, resp.StatusCode)
+		}
+	}
+}
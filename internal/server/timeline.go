@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"games/internal/game"
+	"games/internal/i18n"
+	"games/internal/session"
+)
+
+const (
+	defaultTimelinePage = 100
+	maxTimelinePage     = 500
+)
+
+// timelineStart is the detail of a match start in a session's timeline.
+type timelineStart struct {
+	Players []string `json:"players"`
+}
+
+// timelineMove is the detail of a move in a session's timeline: only the
+// events it caused, which every watcher is shown, as the action itself may
+// reveal what the mover alone could see.
+type timelineMove struct {
+	Seq    int          `json:"seq"`
+	Events []game.Event `json:"events,omitempty"`
+}
+
+// timelineFinish is the detail of a finished match in a session's
+// timeline.
+type timelineFinish struct {
+	Results   []game.PlayerResult `json:"results"`
+	Abandoned bool                `json:"abandoned,omitempty"`
+}
+
+type timelineResponse struct {
+	Events []session.TimelineEntry `json:"events"`
+}
+
+// handleSessionEvents lists what happened in a session after the entry
+// with ID since, oldest first, so a client coming back can show what it
+// missed. Without since it lists from the start. With ?format=text it
+// exports the entries as a transcript to read, in the request's locale.
+func (s *Server) handleSessionEvents(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.manager.Get(r.PathValue("code"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	q := r.URL.Query()
+	var since int64
+	if v := q.Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be a non-negative event ID"})
+			return
+		}
+		since = n
+	}
+	limit := defaultTimelinePage
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTimelinePage {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and " + strconv.Itoa(maxTimelinePage)})
+			return
+		}
+		limit = n
+	}
+	events, err := s.manager.Timeline(r.Context(), sess.Code, since, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if q.Get("format") == "text" {
+		loc := requestLocale(r)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Language", loc.Tag)
+		w.Write([]byte(timelineText(loc, sess.Info(), events, time.Now())))
+		return
+	}
+	writeJSON(w, http.StatusOK, timelineResponse{Events: events})
+}
+
+// timelineText writes a session's timeline as lines of text for loc,
+// under a heading naming the session and when it was exported. Times are
+// in UTC.
+func timelineText(loc *i18n.Locale, info session.Info, events []session.TimelineEntry, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(loc.T("timeline.title", info.Code, info.GameType) + "\n")
+	b.WriteString(loc.T("timeline.exported", loc.FormatDateTime(now.UTC())) + "\n\n")
+	for _, e := range events {
+		b.WriteString(loc.T("timeline.entry", loc.FormatDateTime(e.At.UTC()), describeTimeline(loc, e)) + "\n")
+		if e.Type != session.TimelineFinished {
+			continue
+		}
+		var finish timelineFinish
+		json.Unmarshal(e.Detail, &finish)
+		for _, res := range finish.Results {
+			b.WriteString("    " + loc.T("timeline.result", loc.FormatInt(int64(res.Rank)), res.PlayerID, loc.FormatInt(int64(res.Score))) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// describeTimeline says what a timeline entry records, for loc.
+func describeTimeline(loc *i18n.Locale, e session.TimelineEntry) string {
+	switch e.Type {
+	case session.TimelineJoined:
+		return loc.T("timeline.joined", e.PlayerID)
+	case session.TimelineStarted:
+		var start timelineStart
+		json.Unmarshal(e.Detail, &start)
+		return loc.T("timeline.started", strings.Join(start.Players, loc.T("list.versus")))
+	case session.TimelineMoved:
+		var move timelineMove
+		json.Unmarshal(e.Detail, &move)
+		return loc.T("timeline.moved", e.PlayerID, loc.FormatInt(int64(move.Seq)))
+	case session.TimelineFinished:
+		var finish timelineFinish
+		json.Unmarshal(e.Detail, &finish)
+		if finish.Abandoned {
+			return loc.T("timeline.abandoned")
+		}
+		return loc.T("timeline.finished")
+	case session.TimelineChat:
+		var chat struct {
+			Text string `json:"text"`
+		}
+		json.Unmarshal(e.Detail, &chat)
+		return loc.T("timeline.chat", e.PlayerID, chat.Text)
+	}
+	return e.Type
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/i18n"
+	"games/internal/session"
+)
+
+func TestSessionEvents(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, bob)
+	if resp := startViaAPI(t, env, code); resp.StatusCode != http.StatusOK {
+		t.Fatalf("start: %d", resp.StatusCode)
+	}
+	// Alice joined first, so moves first
+	if err := sendWS(ctx, alice, "action", makeAction(t, 4)); err != nil {
+		t.Fatalf("send action: %v", err)
+	}
+	for msg := wsRead(ctx, t, alice); msg.Type != "events"; msg = wsRead(ctx, t, alice) {
+		if msg.Type == "error" {
+			t.Fatalf("move refused: %s", msg.Payload)
+		}
+	}
+
+	events := func(query string, want int) []session.TimelineEntry {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + "/api/sessions/" + code + "/events" + query)
+		if err != nil {
+			t.Fatalf("get events: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("events%s: expected %d, got %d", query, want, resp.StatusCode)
+		}
+		var body timelineResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body.Events
+	}
+	all := events("", http.StatusOK)
+	var got []string
+	for _, e := range all {
+		got = append(got, e.Type+" "+e.PlayerID)
+	}
+	if fmt.Sprint(got) != "[joined alice joined bob started  moved alice]" {
+		t.Fatalf("unexpected timeline %q", got)
+	}
+	var move timelineMove
+	if err := json.Unmarshal(all[3].Detail, &move); err != nil || move.Seq != 1 || len(move.Events) == 0 {
+		t.Errorf("expected the move's seq and events, got %s", all[3].Detail)
+	}
+	if d := string(all[2].Detail); d != `{"players":["alice","bob"]}` {
+		t.Errorf("expected the start to list the players, got %s", d)
+	}
+
+	// A client that saw the start asks only for what came after
+	if after := events(fmt.Sprintf("?since=%d", all[2].ID), http.StatusOK); len(after) != 1 || after[0].Type != session.TimelineMoved {
+		t.Errorf("expected only the move after the start, got %v", after)
+	}
+	if page := events("?limit=2", http.StatusOK); len(page) != 2 || page[1].ID != all[1].ID {
+		t.Errorf("expected the first two entries, got %v", page)
+	}
+	events("?since=x", http.StatusBadRequest)
+	events("?limit=501", http.StatusBadRequest)
+
+	// The transcript export reads in the locale asked for
+	req, _ := http.NewRequest("GET", env.ts.URL+"/api/sessions/"+code+"/events?format=text", nil)
+	req.Header.Set("Accept-Language", "ar-EG, en;q=0.5")
+	text, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("get transcript: %v", err)
+	}
+	body, _ := io.ReadAll(text.Body)
+	text.Body.Close()
+	if text.Header.Get("Content-Language") != "ar" || !strings.HasPrefix(text.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected an Arabic text transcript, got %v", text.Header)
+	}
+	for _, want := range []string{"الجلسة " + code + ": tictactoe", "انضم alice", "بدأت المباراة: alice ضد bob", "لعب alice النقلة ١"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in the transcript, got %s", want, body)
+		}
+	}
+
+	resp, err := http.Get(env.ts.URL + "/api/sessions/NOPE/events")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown session, got %d", resp.StatusCode)
+	}
+}
+
+func TestTimelineText(t *testing.T) {
+	at := time.Date(2026, time.January, 5, 9, 30, 0, 0, time.UTC)
+	entries := []session.TimelineEntry{
+		{Type: session.TimelineJoined, PlayerID: "alice", At: at},
+		{Type: session.TimelineChat, PlayerID: "alice", Detail: json.RawMessage(`{"text":"gg"}`), At: at},
+		{Type: session.TimelineFinished, Detail: json.RawMessage(`{"results":[{"playerId":"alice","rank":1,"score":1200}]}`), At: at},
+	}
+	got := timelineText(i18n.Default, session.Info{Code: "ABC123", GameType: "tictactoe"}, entries, at)
+	want := "Session ABC123: tictactoe\n" +
+		"Exported Jan 5, 2026, 9:30 AM UTC\n\n" +
+		"[Jan 5, 2026, 9:30 AM] alice joined\n" +
+		"[Jan 5, 2026, 9:30 AM] alice: gg\n" +
+		"[Jan 5, 2026, 9:30 AM] The match finished\n" +
+		"    1. alice, score 1,200\n"
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"games/internal/event"
+	"games/internal/session"
+)
+
+// tournamentEventBuffer is how many events the tournament runner may fall
+// behind the bus before it misses some.
+const tournamentEventBuffer = 256
+
+type createTournamentRequest struct {
+	Name     string `json:"name"`
+	GameType string `json:"gameType"`
+	Format   string `json:"format"`
+	// Rounds is how many rounds a Swiss plays; 0 leaves it to the number
+	// of players.
+	Rounds   int    `json:"rounds,omitempty"`
+	PlayerID string `json:"playerId"` // the organizer
+	// Club makes it a private tournament of the club, which the organizer
+	// must be an owner or admin of.
+	Club string `json:"club,omitempty"`
+}
+
+type tournamentPlayerRequest struct {
+	PlayerID string `json:"playerId"`
+}
+
+type tournamentsResponse struct {
+	Tournaments []session.Tournament `json:"tournaments"`
+}
+
+type standingsResponse struct {
+	Standings []session.Standing `json:"standings"`
+}
+
+// tournamentUpdate is what the tournament stream sends: the pairings with
+// the links to their matches, and the standings.
+type tournamentUpdate struct {
+	Tournament *session.Tournament `json:"tournament"`
+	Standings  []session.Standing  `json:"standings"`
+}
+
+func (s *Server) handleCreateTournament(w http.ResponseWriter, r *http.Request) {
+	var req createTournamentRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	t, err := s.manager.CreateTournament(r.Context(), req.Name, strings.TrimSpace(req.GameType), strings.TrimSpace(req.Format), req.Rounds, req.PlayerID, strings.TrimSpace(req.Club))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, session.ErrClubNotFound) || errors.Is(err, session.ErrNotPermitted) {
+			status = clubErrorStatus(err)
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// handleListTournaments lists the open tournaments, or with ?club= the
+// club's, for a member named by ?playerId= and calling from their own
+// browser.
+func (s *Server) handleListTournaments(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("club") != "" && !s.isGuest(r, q.Get("playerId")) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	list, err := s.manager.Tournaments(r.Context(), q.Get("club"), q.Get("playerId"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, session.ErrClubNotFound) || errors.Is(err, session.ErrNotPermitted) {
+			status = clubErrorStatus(err)
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, tournamentsResponse{Tournaments: list})
+}
+
+func (s *Server) handleGetTournament(w http.ResponseWriter, r *http.Request) {
+	t, err := s.manager.Tournament(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, tournamentErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) handleJoinTournament(w http.ResponseWriter, r *http.Request) {
+	var req tournamentPlayerRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	t, err := s.manager.JoinTournament(r.Context(), r.PathValue("id"), req.PlayerID)
+	if err != nil {
+		writeJSON(w, tournamentErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// handleStartTournament lets the organizer, from their own browser, close
+// entries and pair the first round, whose matches start straight away.
+func (s *Server) handleStartTournament(w http.ResponseWriter, r *http.Request) {
+	var req tournamentPlayerRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !s.isGuest(r, req.PlayerID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errNotYourBrowser.Error()})
+		return
+	}
+	t, started, err := s.manager.StartTournament(r.Context(), r.PathValue("id"), req.PlayerID)
+	if err != nil {
+		writeJSON(w, tournamentErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	for _, sess := range started {
+		s.matchStarted(r.Context(), sess)
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// handleTournamentStandings ranks a tournament's players with their
+// tie-breaks, on the games finished so far.
+func (s *Server) handleTournamentStandings(w http.ResponseWriter, r *http.Request) {
+	standings, err := s.manager.TournamentStandings(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, tournamentErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, standingsResponse{Standings: standings})
+}
+
+// handleTournamentStream sends a tournament's pairings and standings as
+// a tournament server-sent event on connecting, and again whenever a game
+// of it finishes or a round starts, so spectators can follow it live.
+func (s *Server) handleTournamentStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	id := r.PathValue("id")
+	events, unsubscribe := s.manager.Events().Subscribe(64)
+	defer unsubscribe()
+	update, err := s.tournamentUpdate(r.Context(), id)
+	if err != nil {
+		writeJSON(w, tournamentErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	heartbeat := time.NewTicker(feedHeartbeat)
+	defer heartbeat.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	var last []byte
+	send := func(update *tournamentUpdate) {
+		data, _ := json.Marshal(update)
+		if slices.Equal(data, last) {
+			return
+		}
+		last = data
+		fmt.Fprintf(w, "event: tournament\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+	send(update)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Tournament != id {
+				continue
+			}
+			if update, err := s.tournamentUpdate(r.Context(), id); err == nil {
+				send(update)
+			}
+		}
+	}
+}
+
+func (s *Server) tournamentUpdate(ctx context.Context, id string) (*tournamentUpdate, error) {
+	t, err := s.manager.Tournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	standings, err := s.manager.TournamentStandings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &tournamentUpdate{Tournament: t, Standings: standings}, nil
+}
+
+// runTournaments records the result of each finished tournament game and
+// announces the matches of the rounds that starts, until the channel is
+// closed.
+func (s *Server) runTournaments(events <-chan event.Event) {
+	ctx := context.Background()
+	for e := range events {
+		if e.Type != event.MatchFinished {
+			continue
+		}
+		started, err := s.manager.RecordTournamentGame(ctx, e)
+		if err != nil {
+			log.Printf("tournament game %s: %v", e.SessionCode, err)
+		}
+		for _, sess := range started {
+			s.matchStarted(ctx, sess)
+		}
+	}
+}
+
+func tournamentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, session.ErrTournamentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, session.ErrNotPermitted):
+		return http.StatusForbidden
+	}
+	return http.StatusConflict
+}
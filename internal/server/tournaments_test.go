@@ -0,0 +1,199 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/event"
+	"games/internal/game"
+	"games/internal/session"
+)
+
+func getTournament(t *testing.T, env *testEnv, id string) session.Tournament {
+	t.Helper()
+	resp, err := http.Get(env.ts.URL + "/api/tournaments/" + id)
+	if err != nil {
+		t.Fatalf("GET tournament: %v", err)
+	}
+	defer resp.Body.Close()
+	var tn session.Tournament
+	json.NewDecoder(resp.Body).Decode(&tn)
+	return tn
+}
+
+func TestTournamentFlow(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	aliceBrowser, aliceID := guestBrowser(t, env.ts)
+	bobBrowser, bobID := guestBrowser(t, env.ts)
+	bad := browserPost(t, aliceBrowser, env.ts.URL+"/api/tournaments", `{"name":"Open","gameType":"tictactoe","format":"knockout","playerId":"`+aliceID+`"}`)
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", bad.StatusCode)
+	}
+	resp := browserPost(t, aliceBrowser, env.ts.URL+"/api/tournaments", `{"name":"Open","gameType":"tictactoe","format":"swiss","playerId":"`+aliceID+`"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var tn session.Tournament
+	json.NewDecoder(resp.Body).Decode(&tn)
+	if tn.Status != "registering" || tn.Organizer != aliceID {
+		t.Fatalf("unexpected tournament %+v", tn)
+	}
+	for _, p := range []struct {
+		client *http.Client
+		id     string
+	}{{aliceBrowser, aliceID}, {bobBrowser, bobID}} {
+		join := browserPost(t, p.client, env.ts.URL+"/api/tournaments/"+tn.ID+"/join", `{"playerId":"`+p.id+`"}`)
+		join.Body.Close()
+		if join.StatusCode != http.StatusOK {
+			t.Fatalf("expected %s entered, got %d", p.id, join.StatusCode)
+		}
+	}
+	wrong := browserPost(t, bobBrowser, env.ts.URL+"/api/tournaments/"+tn.ID+"/start", `{"playerId":"`+bobID+`"}`)
+	wrong.Body.Close()
+	if wrong.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for bob starting it, got %d", wrong.StatusCode)
+	}
+	start := browserPost(t, aliceBrowser, env.ts.URL+"/api/tournaments/"+tn.ID+"/start", `{"playerId":"`+aliceID+`"}`)
+	defer start.Body.Close()
+	json.NewDecoder(start.Body).Decode(&tn)
+	if start.StatusCode != http.StatusOK || tn.Round != 1 || tn.Rounds != 1 || len(tn.Pairings) != 1 {
+		t.Fatalf("expected one round of one game, got %d %+v", start.StatusCode, tn)
+	}
+
+	// The players find their match by its code and play it out, the
+	// first of the pairing winning
+	p := tn.Pairings[0]
+	code := p.SessionCode
+	alice := wsConnectAs(t, env.ts, aliceBrowser, code, aliceID)
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnectAs(t, env.ts, bobBrowser, code, bobID)
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	conns := map[string]*websocket.Conn{aliceID: alice, bobID: bob}
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		conn := conns[[]string{p.PlayerA, p.PlayerB}[i%2]]
+		sendWS(ctx, conn, "action", makeAction(t, cell))
+		readState(t, ctx, alice)
+		readState(t, ctx, bob)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if tn = getTournament(t, env, tn.ID); tn.Status == "finished" {
+			break
+		}
+	}
+	if tn.Status != "finished" || tn.Pairings[0].Result != "a" {
+		t.Fatalf("expected %s's win to finish the tournament, got %+v", p.PlayerA, tn)
+	}
+
+	standingsResp, err := http.Get(env.ts.URL + "/api/tournaments/" + tn.ID + "/standings")
+	if err != nil {
+		t.Fatalf("GET standings: %v", err)
+	}
+	defer standingsResp.Body.Close()
+	var body standingsResponse
+	json.NewDecoder(standingsResp.Body).Decode(&body)
+	if len(body.Standings) != 2 || body.Standings[0].PlayerID != p.PlayerA || body.Standings[0].Rank != 1 || body.Standings[1].Losses != 1 {
+		t.Fatalf("expected %s ahead, got %+v", p.PlayerA, body.Standings)
+	}
+
+	listResp, err := http.Get(env.ts.URL + "/api/tournaments")
+	if err != nil {
+		t.Fatalf("GET tournaments: %v", err)
+	}
+	defer listResp.Body.Close()
+	var list tournamentsResponse
+	json.NewDecoder(listResp.Body).Decode(&list)
+	if len(list.Tournaments) != 1 || list.Tournaments[0].ID != tn.ID {
+		t.Fatalf("expected the tournament listed, got %+v", list)
+	}
+
+	if missing := getTournament(t, env, "tn-missing"); missing.ID != "" {
+		t.Fatalf("expected no tournament, got %+v", missing)
+	}
+}
+
+func TestTournamentStream(t *testing.T) {
+	env := setupTestEnv(t)
+	alice, aliceID := guestBrowser(t, env.ts)
+	tn, err := env.mgr.CreateTournament(t.Context(), "Open", "tictactoe", session.FormatRoundRobin, 0, aliceID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{aliceID, "bob", "carol"} {
+		env.mgr.JoinTournament(t.Context(), tn.ID, id)
+	}
+
+	missing, err := http.Get(env.ts.URL + "/api/tournaments/tn-missing/stream")
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", missing.StatusCode)
+	}
+
+	streamCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	req, _ := http.NewRequestWithContext(streamCtx, "GET", env.ts.URL+"/api/tournaments/"+tn.ID+"/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	next := func() tournamentUpdate {
+		t.Helper()
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var update tournamentUpdate
+				json.Unmarshal([]byte(data), &update)
+				return update
+			}
+		}
+		t.Fatalf("stream ended: %v", scanner.Err())
+		return tournamentUpdate{}
+	}
+	if got := next(); got.Tournament.Status != "registering" || len(got.Standings) != 3 {
+		t.Fatalf("expected the tournament taking entries, got %+v", got)
+	}
+
+	start := browserPost(t, alice, env.ts.URL+"/api/tournaments/"+tn.ID+"/start", `{"playerId":"`+aliceID+`"}`)
+	start.Body.Close()
+	got := next()
+	if got.Tournament.Round != 1 || len(got.Tournament.Pairings) != 2 || got.Tournament.Pairings[0].SessionCode == "" {
+		t.Fatalf("expected round 1 with a live board, got %+v", got.Tournament)
+	}
+
+	// A result comes through once the tournament has recorded it
+	p := got.Tournament.Pairings[0]
+	env.mgr.Events().Publish(event.Event{
+		Type:        event.MatchFinished,
+		SessionCode: p.SessionCode,
+		GameType:    "tictactoe",
+		Results: []game.PlayerResult{
+			{PlayerID: p.PlayerA, Outcome: game.OutcomeWin},
+			{PlayerID: p.PlayerB, Outcome: game.OutcomeForfeit},
+		},
+	})
+	for got.Tournament.Pairings[0].Result == "" {
+		got = next()
+	}
+	if got.Tournament.Pairings[0].Result != "a" || got.Standings[0].PlayerID != p.PlayerA {
+		t.Fatalf("expected %s's win in the standings, got %+v", p.PlayerA, got)
+	}
+}
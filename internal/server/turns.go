@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"games/internal/event"
+	"games/internal/session"
+)
+
+// turnsResponse tells a player which of their sessions wait on their move.
+type turnsResponse struct {
+	Count    int            `json:"count"`
+	Sessions []session.Turn `json:"sessions"`
+}
+
+// playerTurns lists the sessions waiting on playerID's move, their
+// private ones only when r comes from their own guest browser.
+func (s *Server) playerTurns(r *http.Request, playerID string) (turnsResponse, error) {
+	turns, err := s.manager.Turns(r.Context(), playerID, s.isGuest(r, playerID))
+	return turnsResponse{Count: len(turns), Sessions: turns}, err
+}
+
+// handlePlayerTurns lists the sessions waiting on a player's move, for
+// badge counts and correspondence dashboards.
+func (s *Server) handlePlayerTurns(w http.ResponseWriter, r *http.Request) {
+	turns, err := s.playerTurns(r, r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, turns)
+}
+
+// turnEvents are the events after which whose turn it is may have
+// changed for the players they name.
+var turnEvents = map[string]bool{event.YourTurn: true, event.MatchStarted: true, event.MatchFinished: true}
+
+// handlePlayerTurnsStream streams a player's turns as server-sent events:
+// a turns event with the current list on connecting, then another
+// whenever it changes.
+func (s *Server) handlePlayerTurnsStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	playerID := r.PathValue("id")
+	events, unsubscribe := s.manager.Events().Subscribe(64)
+	defer unsubscribe()
+	heartbeat := time.NewTicker(feedHeartbeat)
+	defer heartbeat.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	var last []byte
+	send := func() {
+		turns, err := s.playerTurns(r, playerID)
+		if err != nil {
+			return
+		}
+		data, _ := json.Marshal(turns)
+		if slices.Equal(data, last) {
+			return
+		}
+		last = data
+		fmt.Fprintf(w, "event: turns\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+	send()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if turnEvents[e.Type] && (slices.Contains(e.Players, playerID) || slices.Contains(e.Recipients, playerID)) {
+				send()
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+func TestPlayerTurns(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	alice := wsConnect(t, env.ts, code, "alice")
+	defer alice.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	bob := wsConnect(t, env.ts, code, "bob")
+	defer bob.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+	sendWS(ctx, alice, "start", nil)
+	readState(t, ctx, alice)
+	readState(t, ctx, bob)
+
+	turns := func(player string) turnsResponse {
+		t.Helper()
+		resp, err := http.Get(env.ts.URL + "/api/players/" + player + "/turns")
+		if err != nil {
+			t.Fatalf("turns of %s: %v", player, err)
+		}
+		defer resp.Body.Close()
+		var body turnsResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	first, second := "alice", "bob"
+	if turns("bob").Count == 1 {
+		first, second = second, first
+	}
+	if got := turns(first); got.Count != 1 || got.Sessions[0].Code != code || got.Sessions[0].Since == "" {
+		t.Fatalf("expected %s to have the move in %s, got %+v", first, code, got)
+	}
+	if got := turns(second); got.Count != 0 || got.Sessions == nil {
+		t.Fatalf("expected an empty list for %s, got %+v", second, got)
+	}
+
+	// The stream follows the move from one player to the other
+	streamCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	req, _ := http.NewRequestWithContext(streamCtx, "GET", env.ts.URL+"/api/players/"+second+"/turns/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	next := func() turnsResponse {
+		t.Helper()
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var body turnsResponse
+				json.Unmarshal([]byte(data), &body)
+				return body
+			}
+		}
+		t.Fatalf("stream ended: %v", scanner.Err())
+		return turnsResponse{}
+	}
+	if got := next(); got.Count != 0 {
+		t.Fatalf("expected no turns at first, got %+v", got)
+	}
+	mover := alice
+	if first == "bob" {
+		mover = bob
+	}
+	sendWS(ctx, mover, "action", makeAction(t, 4))
+	if got := next(); got.Count != 1 || got.Sessions[0].Code != code {
+		t.Fatalf("expected the move to come to %s, got %+v", second, got)
+	}
+}
+
+func TestPlayerTurnsPrivate(t *testing.T) {
+	env := setupTestEnv(t)
+
+	browser, guest := guestBrowser(t, env.ts)
+	resp := browserPost(t, browser, env.ts.URL+"/api/sessions", `{"gameType":"tictactoe","playerId":"erin","private":true}`)
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	sess, _ := env.mgr.Get(created.Code)
+	sess.AddPlayer("zed")
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	env.mgr.SaveMatchStart(t.Context(), sess)
+
+	// The guest joined first, so has the first move
+	count := func(client *http.Client) int {
+		t.Helper()
+		resp, err := client.Get(env.ts.URL + "/api/players/" + guest + "/turns")
+		if err != nil {
+			t.Fatalf("turns: %v", err)
+		}
+		defer resp.Body.Close()
+		var body turnsResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body.Count
+	}
+	if got := count(http.DefaultClient); got != 0 {
+		t.Errorf("expected a private session's turn hidden from others, got %d", got)
+	}
+	if got := count(browser); got != 1 {
+		t.Errorf("expected the guest to see their own turn, got %d", got)
+	}
+}
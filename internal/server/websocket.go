@@ -3,13 +3,19 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"nhooyr.io/websocket"
 
+	"games/internal/event"
 	"games/internal/game"
 	"games/internal/session"
+	"games/internal/storage"
 )
 
 // WSMessage is the JSON envelope for WebSocket messages.
@@ -20,23 +26,157 @@ type WSMessage struct {
 
 type joinPayload struct {
 	PlayerID string `json:"playerId"`
+	Spectate bool   `json:"spectate,omitempty"` // watch without taking a seat
+	// Handoff redeems a code from another device's "handoff" message,
+	// taking over its seat; PlayerID may then be left empty.
+	Handoff string `json:"handoff,omitempty"`
+	// Token is the token from a "seat" message, required to reconnect
+	// to a seat once claimed.
+	Token string `json:"token,omitempty"`
+	// Sections lists the parts of each state message to send, by their
+	// JSON keys, so a thin client can leave out what it does not show.
+	// Empty sends them all.
+	Sections []string `json:"sections,omitempty"`
+	// Toasts asks for an achievement_unlocked message whenever the player
+	// unlocks an achievement in this session.
+	Toasts bool `json:"toasts,omitempty"`
+	// Narration asks for a narration message after each move in a game
+	// that describes its moves, for screen readers and voice clients.
+	Narration bool `json:"narration,omitempty"`
 }
 
 type actionPayload struct {
 	Action game.Action `json:"action"`
+	// SentAt is when the client sent the action, in Unix milliseconds by
+	// its estimate of the server's clock, for games that compensate for
+	// lag.
+	SentAt int64 `json:"sentAt,omitempty"`
+	// As is the seat the action is for, in a practice session where the
+	// player plays more than one; by default, the seat to move.
+	As string `json:"as,omitempty"`
+}
+
+// move is the action as playerID sent it, received at the given time.
+func (ap actionPayload) move(playerID string, received time.Time) session.Move {
+	mv := session.Move{PlayerID: playerID, Action: ap.Action, At: received}
+	if ap.SentAt > 0 {
+		mv.SentAt = time.UnixMilli(ap.SentAt)
+	}
+	return mv
+}
+
+// actionTextPayload is a move written in the game's notation, such as
+// "b2", for clients that take moves as text.
+type actionTextPayload struct {
+	Text   string `json:"text"`
+	SentAt int64  `json:"sentAt,omitempty"`
+	As     string `json:"as,omitempty"` // as in an action
 }
 
 type statePayload struct {
-	State        any                 `json:"state"`
-	ValidActions []game.Action       `json:"validActions"`
-	SessionInfo  session.Info        `json:"sessionInfo"`
-	Results      []game.PlayerResult `json:"results,omitempty"`
+	State        any                   `json:"state"`
+	ValidActions []game.Action         `json:"validActions"`
+	SessionInfo  session.Info          `json:"sessionInfo"`
+	Results      []game.PlayerResult   `json:"results,omitempty"`
+	Summary      *session.MatchSummary `json:"summary,omitempty"`
+	Scoreboard   []session.ScoreEntry  `json:"scoreboard,omitempty"`
+	Fairness     *game.Commitment      `json:"fairness,omitempty"`
+	// ServerTime is when the message was encoded, in Unix milliseconds,
+	// for clients to keep countdowns in step with the server.
+	ServerTime int64 `json:"serverTime"`
+}
+
+// eventsPayload carries the events one action caused; Seq is the move's
+// position in the match history.
+type eventsPayload struct {
+	Seq      int          `json:"seq"`
+	PlayerID string       `json:"playerId"`
+	Events   []game.Event `json:"events"`
+}
+
+type addBotPayload struct {
+	Strategy string `json:"strategy"`
+}
+
+// chooseSeatPayload picks a seat, 0 moving first, or -1 to give one up.
+// The host may set PlayerID to seat someone else.
+type chooseSeatPayload struct {
+	Seat     int    `json:"seat"`
+	PlayerID string `json:"playerId,omitempty"`
+}
+
+// reservePayload lists the players to hold seats for, replacing any
+// earlier reservations.
+type reservePayload struct {
+	PlayerIDs []string `json:"playerIds"`
+}
+
+// turnOrderPayload names how unseated players are seated: "join" or
+// "random".
+type turnOrderPayload struct {
+	TurnOrder string `json:"turnOrder"`
+}
+
+// handicapsPayload gives players an uneven start: the game's handicap
+// values by player ID. It replaces any handicaps set before, and players
+// left out start even.
+type handicapsPayload struct {
+	Handicaps game.Handicaps `json:"handicaps"`
+}
+
+// votePayload casts a vote to skip the turn of, or remove, an unresponsive
+// player. Kind is "skip" or "remove".
+type votePayload struct {
+	Kind     string `json:"kind"`
+	PlayerID string `json:"playerId"`
+}
+
+// handoffPayload answers a "handoff" request with the code to enter on the
+// other device.
+type handoffPayload struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
+// seatPayload tells a device which seat it holds and the token it must
+// present to reconnect: the join secret when it first claims a seat, the
+// device token after redeeming a handoff, or, for a guest, whichever it
+// joined with.
+type seatPayload struct {
+	PlayerID string `json:"playerId"`
+	Token    string `json:"token"`
+}
+
+// statusSeatMoved closes a connection whose seat was handed off to another
+// device; clients should not reconnect automatically.
+const statusSeatMoved websocket.StatusCode = 4001
+
 type errorPayload struct {
 	Message string `json:"message"`
 }
 
+// decodeWSMessage decodes a client message's envelope.
+func decodeWSMessage(data []byte) (WSMessage, error) {
+	var msg WSMessage
+	if err := unmarshalStrict(data, &msg); err != nil || msg.Type == "" {
+		return WSMessage{}, fmt.Errorf("invalid message")
+	}
+	return msg, nil
+}
+
+// decodeJoin decodes the join message a connection must open with.
+func decodeJoin(data []byte) (joinPayload, error) {
+	msg, err := decodeWSMessage(data)
+	if err != nil || msg.Type != "join" {
+		return joinPayload{}, fmt.Errorf("first message must be a join")
+	}
+	var join joinPayload
+	if err := unmarshalStrict(msg.Payload, &join); err != nil || (join.PlayerID == "" && join.Handoff == "") {
+		return joinPayload{}, fmt.Errorf("invalid join payload")
+	}
+	return join, nil
+}
+
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	code := r.PathValue("code")
 	sess, ok := s.manager.Get(code)
@@ -45,14 +185,25 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		InsecureSkipVerify: true, // allow any origin for dev
-	})
+	// External bots authenticate the upgrade request with their API key
+	var bot *storage.BotRow
+	if key := bearerToken(r); key != "" {
+		var err error
+		if bot, err = s.manager.AuthenticateBot(r.Context(), key); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	raw, err := s.acceptWS(w, r)
 	if err != nil {
 		log.Printf("websocket accept: %v", err)
 		return
 	}
+	conn, recorded := s.recordConn(raw, sess, "")
+	defer recorded()
 	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(maxWSMessageBytes)
 
 	ctx := r.Context()
 
@@ -61,87 +212,297 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	var msg WSMessage
-	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "join" {
-		sendWSError(ctx, conn, "first message must be a join")
+	join, err := decodeJoin(data)
+	if err != nil {
+		sendWSError(ctx, conn, err.Error())
 		return
 	}
-	var join joinPayload
-	if err := json.Unmarshal(msg.Payload, &join); err != nil || join.PlayerID == "" {
-		sendWSError(ctx, conn, "invalid join payload")
-		return
+	handedOff := join.Handoff != "" && !join.Spectate && bot == nil
+	if handedOff {
+		id, token, err := sess.RedeemHandoff(join.Handoff)
+		if err != nil {
+			sendWSError(ctx, conn, err.Error())
+			return
+		}
+		join.PlayerID, join.Token = id, token
+	}
+	// A guest's browser sits as its guest ID, shown by the ID it joined with
+	var name string
+	if !handedOff && !join.Spectate && bot == nil {
+		if join.PlayerID, name, err = s.seatAs(r, join.PlayerID, sess.HoldsSeat); err != nil {
+			sendWSError(ctx, conn, err.Error())
+			return
+		}
 	}
 
+	sec, err := parseSections(join.Sections)
+	if err != nil {
+		sendWSError(ctx, conn, err.Error())
+		return
+	}
 	playerID := join.PlayerID
 	send := make(chan []byte, 64)
+	defer s.subs.subscribe(send, sec)()
+	if join.Toasts {
+		s.toasts.Store(send, true)
+		defer s.toasts.Delete(send)
+	}
+	if join.Narration {
+		s.narration.Store(send, true)
+		defer s.narration.Delete(send)
+	}
+
+	if bot != nil && playerID != bot.ID {
+		sendWSError(ctx, conn, "join playerId must match the bot's ID")
+		return
+	}
+	if bot == nil && !join.Spectate && strings.HasPrefix(playerID, session.ExternalBotPrefix) {
+		sendWSError(ctx, conn, "player IDs starting with "+session.ExternalBotPrefix+" are reserved for bots")
+		return
+	}
+
+	// A practice session is its player's alone
+	sess.RLock()
+	practice, host := sess.Practice, sess.HostID
+	sess.RUnlock()
+	if practice && (join.Spectate || playerID != host) {
+		sendWSError(ctx, conn, "this practice session is another player's")
+		return
+	}
+
+	if join.Spectate {
+		s.spectate(ctx, conn, sess, playerID, send)
+		return
+	}
 
 	// Try to reconnect existing player, or add new one
+	if !sess.AcceptsToken(playerID, join.Token) {
+		sendWSError(ctx, conn, "this seat belongs to another device; rejoin with its token")
+		return
+	}
 	if !sess.ConnectPlayer(playerID, send) {
 		if err := sess.AddPlayer(playerID); err != nil {
 			sendWSError(ctx, conn, err.Error())
 			return
 		}
 		sess.ConnectPlayer(playerID, send)
+		s.announceIfFull(sess)
+		if err := s.manager.SaveSessionPlayers(ctx, sess); err != nil {
+			log.Printf("save players: %v", err)
+		}
+		s.manager.RecordTimeline(ctx, sess.Code, session.TimelineJoined, playerID, nil)
+	}
+	if name != "" {
+		sess.SetName(playerID, name)
 	}
+	if err := s.manager.Touch(ctx, sess); err != nil {
+		log.Printf("save activity: %v", err)
+	}
+	defer sess.DisconnectPlayer(playerID, send)
+	s.manager.PlayerConnected(playerID)
+	defer s.manager.PlayerDisconnected(playerID)
 
-	// Notify all players about the roster change
-	s.broadcastState(sess)
+	// The first connection to a seat claims it, and is told its secret
+	var claimed bool
+	if bot == nil {
+		if secret := sess.ClaimSeat(playerID); secret != "" {
+			join.Token, claimed = secret, true
+		}
+	}
+	if handedOff || claimed {
+		if err := s.manager.SaveSessionPlayers(ctx, sess); err != nil {
+			log.Printf("save players: %v", err)
+		}
+	}
+	if handedOff || claimed || strings.HasPrefix(playerID, GuestPrefix) {
+		sendWSMsg(send, "seat", seatPayload{PlayerID: playerID, Token: join.Token})
+	}
 
-	// Writer goroutine: send messages from the channel to the websocket
+	// Close the connection if a moderator kicks the player, or if the seat
+	// is handed off to another device
+	var kicked <-chan struct{}
+	if p := sess.GetPlayer(playerID); p != nil {
+		kicked = p.Kicked
+	}
+	replaced := sess.Replaced(playerID, send)
 	go func() {
-		for msg := range send {
-			if err := conn.Write(ctx, websocket.MessageText, msg); err != nil {
-				return
-			}
+		select {
+		case <-kicked:
+			sendWSError(ctx, conn, "you were removed from the session")
+			conn.Close(websocket.StatusPolicyViolation, "removed from session")
+		case <-replaced:
+			sendWSError(ctx, conn, "your seat moved to another connection")
+			conn.Close(statusSeatMoved, "seat moved to another connection")
+		case <-ctx.Done():
 		}
 	}()
 
+	// Notify all players about the roster change
+	s.broadcastState(sess)
+	sendRecentEmotes(sess, send)
+
+	// Writer goroutine: send messages from the channel to the websocket
+	// until the connection ends
+	go writeLoop(ctx, send, s.messageRate, func(msg []byte) error {
+		s.countMessage(msg)
+		return conn.Write(ctx, websocket.MessageText, msg)
+	})
+
 	// Reader loop: handle incoming messages
 	for {
 		_, data, err := conn.Read(ctx)
 		if err != nil {
 			break
 		}
-		var msg WSMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
-			sendWSMsg(send, "error", errorPayload{Message: "invalid message"})
+		select {
+		case <-replaced:
+			return // a message racing the takeover is dropped
+		default:
+		}
+		msg, err := decodeWSMessage(data)
+		if err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			continue
+		}
+		if bot != nil && !s.botLimiter.Allow(bot.ID) {
+			sendWSMsg(send, "error", errorPayload{Message: "rate limit exceeded"})
 			continue
 		}
-		s.handleMessage(sess, playerID, send, msg)
+		start := time.Now()
+		s.handleMessage(ctx, sess, playerID, send, msg)
+		s.observeMessage(sess, msg.Type, start)
 	}
 
 	// Player disconnected — don't remove, allow reconnect
 	log.Printf("player %s disconnected from session %s", playerID, code)
 }
 
-func (s *Server) handleMessage(sess *session.Session, playerID string, send chan []byte, msg WSMessage) {
+// spectate streams broadcasts to a watcher until it disconnects. Spectators
+// cannot send game messages, only clock probes.
+func (s *Server) spectate(ctx context.Context, conn *wsConn, sess *session.Session, id string, send chan []byte) {
+	sess.AddSpectator(id, send)
+	defer sess.RemoveSpectator(id, send)
+	s.sendSpectatorState(sess, send)
+	sendRecentEmotes(sess, send)
+
+	go writeLoop(ctx, send, s.messageRate, func(msg []byte) error {
+		s.countMessage(msg)
+		return conn.Write(ctx, websocket.MessageText, msg)
+	})
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		if msg, err := decodeWSMessage(data); err == nil && msg.Type == "ping" {
+			pong(send, msg.Payload)
+			continue
+		}
+		sendWSMsg(send, "error", errorPayload{Message: "spectators cannot send messages"})
+	}
+}
+
+func (s *Server) handleMessage(ctx context.Context, sess *session.Session, playerID string, send chan []byte, msg WSMessage) {
 	switch msg.Type {
 	case "action":
+		received := time.Now()
 		var ap actionPayload
-		if err := json.Unmarshal(msg.Payload, &ap); err != nil {
+		if err := unmarshalStrict(msg.Payload, &ap); err != nil {
+			s.manager.CountAction(playerID, session.RejectMalformed)
 			sendWSMsg(send, "error", errorPayload{Message: "invalid action payload"})
 			return
 		}
-		sess.Lock()
-		if sess.Match == nil {
-			sess.Unlock()
-			sendWSMsg(send, "error", errorPayload{Message: "game not started"})
+		mover, err := moverFor(sess, playerID, ap.As)
+		if s.failIfPanicked(ctx, sess, err) {
 			return
 		}
-		if err := sess.Match.ApplyAction(playerID, ap.Action); err != nil {
-			sess.Unlock()
+		if err != nil {
 			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
 			return
 		}
-		if sess.Match.IsOver() {
-			sess.Status = session.StatusFinished
+		if err := s.applyAction(ctx, sess, ap.move(mover, received)); err != nil {
+			// A panic has already been announced to everyone
+			if !errors.As(err, new(*session.PanicError)) {
+				sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			}
+			return
 		}
-		sess.Unlock()
+		s.playBots(ctx, sess, 0)
 
-		if err := s.manager.SaveMatchState(sess); err != nil {
-			log.Printf("save match state: %v", err)
+	case "action_text":
+		received := time.Now()
+		var tp actionTextPayload
+		if err := unmarshalStrict(msg.Payload, &tp); err != nil {
+			s.manager.CountAction(playerID, session.RejectMalformed)
+			sendWSMsg(send, "error", errorPayload{Message: "invalid action_text payload"})
+			return
 		}
-		s.broadcastState(sess)
+		mover, err := moverFor(sess, playerID, tp.As)
+		if s.failIfPanicked(ctx, sess, err) {
+			return
+		}
+		if err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		action, err := parseMove(sess, mover, tp.Text)
+		if s.failIfPanicked(ctx, sess, err) {
+			return
+		}
+		if err != nil {
+			s.manager.CountAction(playerID, session.RejectMalformed)
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		ap := actionPayload{Action: action, SentAt: tp.SentAt}
+		if err := s.applyAction(ctx, sess, ap.move(mover, received)); err != nil {
+			if !errors.As(err, new(*session.PanicError)) {
+				sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			}
+			return
+		}
+		s.playBots(ctx, sess, 0)
+
+	case "ping":
+		pong(send, msg.Payload)
+
+	case "undo":
+		s.undo(ctx, sess, send)
+
+	case "setup":
+		s.setUp(ctx, sess, playerID, send, msg.Payload)
+
+	case "chat":
+		var cp chatPayload
+		if err := unmarshalStrict(msg.Payload, &cp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid chat payload"})
+			return
+		}
+		s.chat(ctx, sess, playerID, send, cp.Text)
+
+	case "emote":
+		var ep emotePayload
+		if err := unmarshalStrict(msg.Payload, &ep); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid emote payload"})
+			return
+		}
+		s.emote(sess, playerID, send, ep.Emote)
+
+	case "survey_answer":
+		var sr session.SurveyResponse
+		if err := unmarshalStrict(msg.Payload, &sr); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid survey_answer payload"})
+			return
+		}
+		s.answerSurvey(ctx, sess, playerID, send, sr)
+
+	case "rtc_signal":
+		var rp rtcSignalPayload
+		if err := unmarshalStrict(msg.Payload, &rp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid rtc_signal payload"})
+			return
+		}
+		s.relayRTCSignal(sess, playerID, send, rp)
 
 	case "start":
 		if sess.Info().HostID != playerID {
@@ -149,53 +510,589 @@ func (s *Server) handleMessage(sess *session.Session, playerID string, send chan
 			return
 		}
 		if err := sess.Start(); err != nil {
+			if !s.failIfPanicked(ctx, sess, err) {
+				sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			}
+			return
+		}
+		s.matchStarted(ctx, sess)
+		s.broadcastState(sess)
+		s.playBots(ctx, sess, 0)
+
+	case "next_game":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can start the next game"})
+			return
+		}
+		if err := s.manager.NextPartyGame(ctx, sess); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		if err := sess.Start(); err != nil {
+			if !s.failIfPanicked(ctx, sess, err) {
+				sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+				s.broadcastState(sess)
+			}
+			return
+		}
+		s.matchStarted(ctx, sess)
+		s.broadcastState(sess)
+		s.playBots(ctx, sess, 0)
+
+	case "rematch":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can start a rematch"})
+			return
+		}
+		if err := s.manager.Rematch(ctx, sess); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		if err := sess.Start(); err != nil {
+			if !s.failIfPanicked(ctx, sess, err) {
+				sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+				s.broadcastState(sess)
+			}
+			return
+		}
+		s.matchStarted(ctx, sess)
+		s.broadcastState(sess)
+		s.playBots(ctx, sess, 0)
+
+	case "restore":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can restore the game"})
+			return
+		}
+		if err := s.manager.RestoreMatch(ctx, sess); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.broadcastState(sess)
+		s.announceTurn(sess)
+		s.playBots(ctx, sess, 0)
+
+	case "abort":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can abort the game"})
+			return
+		}
+		if err := s.manager.AbortMatch(ctx, sess); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.broadcastState(sess)
+
+	case "add_bot":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can add bots"})
+			return
+		}
+		var bp addBotPayload
+		if err := unmarshalStrict(msg.Payload, &bp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid add_bot payload"})
+			return
+		}
+		strategy, ok := s.registry.Strategy(sess.GameType, bp.Strategy)
+		if !ok {
+			sendWSMsg(send, "error", errorPayload{Message: "unknown bot strategy: " + bp.Strategy})
+			return
+		}
+		botID, err := sess.AddBot(strategy)
+		if err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.manager.RecordTimeline(ctx, sess.Code, session.TimelineJoined, botID, nil)
+		s.broadcastState(sess)
+
+	case "choose_seat":
+		var cp chooseSeatPayload
+		if err := unmarshalStrict(msg.Payload, &cp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid choose_seat payload"})
+			return
+		}
+		target := playerID
+		if cp.PlayerID != "" && cp.PlayerID != playerID {
+			if sess.Info().HostID != playerID {
+				sendWSMsg(send, "error", errorPayload{Message: "only the host can seat other players"})
+				return
+			}
+			target = cp.PlayerID
+		}
+		if err := sess.ChooseSeat(target, cp.Seat); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.broadcastState(sess)
+
+	case "shuffle_seats":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can shuffle seats"})
+			return
+		}
+		if err := sess.ShuffleSeats(); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.broadcastState(sess)
+
+	case "handoff":
+		code, expires, err := sess.NewHandoff(playerID)
+		if err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		sendWSMsg(send, "handoff", handoffPayload{Code: code, ExpiresAt: expires})
+
+	case "turn_order":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can change the turn order"})
+			return
+		}
+		var tp turnOrderPayload
+		if err := unmarshalStrict(msg.Payload, &tp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid turn_order payload"})
+			return
+		}
+		order, err := session.ParseTurnOrder(tp.TurnOrder)
+		if err == nil {
+			err = s.manager.SetTurnOrder(ctx, sess, order)
+		}
+		if err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.broadcastState(sess)
+
+	case "reserve":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can reserve seats"})
+			return
+		}
+		var rp reservePayload
+		if err := unmarshalStrict(msg.Payload, &rp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid reserve payload"})
+			return
+		}
+		if err := sess.Reserve(rp.PlayerIDs); err != nil {
 			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
 			return
 		}
-		if err := s.manager.SaveMatchState(sess); err != nil {
-			log.Printf("save match state: %v", err)
+		s.broadcastState(sess)
+
+	case "vote_thresholds":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can change vote thresholds"})
+			return
+		}
+		var vt session.VoteThresholds
+		if err := unmarshalStrict(msg.Payload, &vt); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid vote_thresholds payload"})
+			return
+		}
+		if err := s.manager.SetVoteThresholds(ctx, sess, vt); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
 		}
 		s.broadcastState(sess)
 
+	case "handicaps":
+		if sess.Info().HostID != playerID {
+			sendWSMsg(send, "error", errorPayload{Message: "only the host can set handicaps"})
+			return
+		}
+		var hp handicapsPayload
+		if err := unmarshalStrict(msg.Payload, &hp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid handicaps payload"})
+			return
+		}
+		if err := s.manager.SetHandicaps(ctx, sess, hp.Handicaps); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			return
+		}
+		s.broadcastState(sess)
+
+	case "vote":
+		var vp votePayload
+		if err := unmarshalStrict(msg.Payload, &vp); err != nil {
+			sendWSMsg(send, "error", errorPayload{Message: "invalid vote payload"})
+			return
+		}
+		tally, err := sess.Vote(playerID, vp.PlayerID, session.VoteKind(vp.Kind))
+		if err != nil {
+			if !s.failIfPanicked(ctx, sess, err) {
+				sendWSMsg(send, "error", errorPayload{Message: err.Error()})
+			}
+			return
+		}
+		s.broadcastVote(sess, tally)
+		if tally.Passed {
+			s.votePassed(ctx, sess)
+		}
+
 	default:
 		sendWSMsg(send, "error", errorPayload{Message: "unknown message type: " + msg.Type})
 	}
 }
 
+// applyAction applies a player's move to the session's match, crediting it
+// for lag if the game allows, records and persists it, and broadcasts the
+// new state.
+func (s *Server) applyAction(ctx context.Context, sess *session.Session, move session.Move) error {
+	playerID, action := move.PlayerID, move.Action
+	sess.Lock()
+	if sess.Match == nil {
+		sess.Unlock()
+		return fmt.Errorf("game not started")
+	}
+	if sess.Status == session.StatusErrored {
+		sess.Unlock()
+		return fmt.Errorf("game stopped after an error")
+	}
+	var events []game.Event
+	var narration string
+	var finished *event.Event
+	var rejection session.Rejection
+	var flag string
+	sess.CompensateLagLocked(&move)
+	err := session.Protect(func() error {
+		if checker, ok := sess.Match.(game.CheatChecker); ok {
+			since := sess.StartedAt
+			if n := len(sess.History); n > 0 {
+				since = sess.History[n-1].Time()
+			}
+			flag = checker.CheckAction(playerID, action, move.Time().Sub(since))
+		}
+		var err error
+		if events, err = game.ApplyAt(sess.Match, playerID, action, move.Time()); err != nil {
+			rejection = session.RejectInvalid
+			if len(sess.Match.ValidActions(playerID)) == 0 {
+				rejection = session.RejectTurn
+			}
+			return err
+		}
+		sess.History = append(sess.History, move)
+		sess.LastActivity = move.At
+		narration = game.Describe(sess.Match, playerID, action, events)
+		finished = finishIfOverLocked(sess, move.At)
+		return nil
+	})
+	seq := len(sess.History)
+	sess.Unlock()
+	if flag != "" {
+		s.manager.Flag(sess, playerID, flag)
+	}
+	if err != nil {
+		if !s.failIfPanicked(ctx, sess, err) {
+			s.manager.CountAction(playerID, rejection)
+		}
+		return err
+	}
+	s.manager.CountAction(playerID, "")
+
+	if err := s.manager.SaveMove(ctx, sess, seq, move); err != nil {
+		log.Printf("save move: %v", err)
+	}
+	s.manager.RecordTimeline(ctx, sess.Code, session.TimelineMoved, playerID, timelineMove{Seq: seq, Events: events})
+	s.archiveFinished(ctx, sess, finished)
+	if len(events) > 0 {
+		s.broadcastEvents(sess, eventsPayload{Seq: seq, PlayerID: playerID, Events: events})
+	}
+	if narration != "" {
+		s.broadcastNarration(sess, narrationPayload{Seq: seq, PlayerID: playerID, Text: narration})
+	}
+	s.broadcastState(sess)
+	if finished != nil {
+		s.manager.Events().Publish(*finished)
+	} else {
+		s.announceTurn(sess)
+	}
+	return nil
+}
+
+// finishIfOverLocked marks the session finished at the given time if its
+// match is over, returning the event to publish; a practice match counts
+// toward nothing and has none. The caller must hold the write lock.
+func finishIfOverLocked(sess *session.Session, at time.Time) *event.Event {
+	if !sess.Match.IsOver() {
+		return nil
+	}
+	sess.Status = session.StatusFinished
+	sess.FinishedAt = at
+	if sess.Practice {
+		return nil
+	}
+	info := sess.InfoLocked()
+	finished := &event.Event{
+		Type:        event.MatchFinished,
+		SessionCode: info.Code,
+		GameType:    info.GameType,
+		Players:     info.Players,
+		Results:     game.Results(sess.Match),
+		Private:     info.Private,
+	}
+	sess.RecordPartyRoundLocked(finished.Results)
+	return finished
+}
+
+// saveMatch persists the match state and, once the match is finished,
+// archives it along with the party standings of a party match.
+func (s *Server) saveMatch(ctx context.Context, sess *session.Session, finished *event.Event) {
+	if err := s.manager.SaveMatchState(ctx, sess); err != nil {
+		log.Printf("save match state: %v", err)
+	}
+	s.archiveFinished(ctx, sess, finished)
+}
+
+// archiveFinished archives a match that just finished, along with the
+// party standings of a party match. It does nothing if finished is nil.
+func (s *Server) archiveFinished(ctx context.Context, sess *session.Session, finished *event.Event) {
+	if finished == nil {
+		return
+	}
+	if err := s.manager.ArchiveMatch(ctx, sess); err != nil {
+		log.Printf("archive match %s: %v", sess.Code, err)
+	}
+	s.manager.RecordTimeline(ctx, sess.Code, session.TimelineFinished, "", timelineFinish{Results: finished.Results, Abandoned: finished.Abandoned})
+	if sess.Info().Party != nil {
+		if err := s.manager.SaveParty(ctx, sess); err != nil {
+			log.Printf("save party: %v", err)
+		}
+	}
+}
+
+// votePassed persists and broadcasts the match after a vote skipped or
+// removed a player, then lets play carry on.
+func (s *Server) votePassed(ctx context.Context, sess *session.Session) {
+	sess.Lock()
+	finished := finishIfOverLocked(sess, time.Now())
+	sess.Unlock()
+
+	s.saveMatch(ctx, sess, finished)
+	s.broadcastState(sess)
+	if finished != nil {
+		s.manager.Events().Publish(*finished)
+		return
+	}
+	s.announceTurn(sess)
+	s.playBots(ctx, sess, 0)
+}
+
+// broadcastVote tells every player how a vote stands.
+func (s *Server) broadcastVote(sess *session.Session, tally session.VoteTally) {
+	for _, pid := range sess.PlayerIDs() {
+		for _, send := range sess.PlayerSends(pid) {
+			sendWSMsg(send, "vote", tally)
+		}
+	}
+}
+
+// playBots lets bot players move until it is a human's turn or the match
+// is over, pausing for delay before each bot move.
+func (s *Server) playBots(ctx context.Context, sess *session.Session, delay time.Duration) {
+	for {
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		sess.RLock()
+		var bot *session.Player
+		var ok bool
+		var action game.Action
+		err := session.Protect(func() error {
+			if bot, ok = sess.NextBotMove(); !ok {
+				return nil
+			}
+			var err error
+			action, err = bot.Strategy.ChooseAction(sess.Match, bot.ID)
+			return err
+		})
+		sess.RUnlock()
+		if s.failIfPanicked(ctx, sess, err) || !ok {
+			return
+		}
+		if err == nil {
+			err = s.applyAction(ctx, sess, session.Move{PlayerID: bot.ID, Action: action, At: time.Now()})
+		}
+		if err != nil {
+			log.Printf("bot %s in session %s: %v", bot.ID, sess.Code, err)
+			return
+		}
+	}
+}
+
 func (s *Server) broadcastState(sess *session.Session) {
-	sess.RLock()
+	start := time.Now()
+	sess.Lock()
+	messages := sess.TakeMessagesLocked()
 	info := sess.InfoLocked()
+	scoreboard := sess.ScoreboardLocked()
+	fairness := sess.CommitmentLocked()
+	summary := sess.SummaryLocked()
 	match := sess.Match
 	status := sess.Status
-	sess.RUnlock()
+	sess.Unlock()
+	defer s.observeBroadcast(info.GameType, start)
+
+	// Private messages go out first, so a player's state never runs ahead
+	// of what they have been told.
+	for _, m := range messages {
+		for _, send := range sess.PlayerSends(m.PlayerID) {
+			sendWSMsg(send, "message", m)
+		}
+	}
 
 	for _, pid := range info.Players {
 		p := sess.GetPlayer(pid)
 		if p == nil {
 			continue
 		}
-		sp := statePayload{SessionInfo: info}
-		if match != nil && status != session.StatusWaiting {
-			sp.State = match.State(pid)
-			sp.ValidActions = match.ValidActions(pid)
-			if match.IsOver() {
-				sp.Results = match.Results()
+		sp := statePayload{SessionInfo: info, Scoreboard: scoreboard, Fairness: fairness}
+		if match != nil && status != session.StatusWaiting && status != session.StatusErrored {
+			// The match may be moving on already; read it as spectatorState
+			// does, under the lock
+			sess.RLock()
+			err := session.Protect(func() error {
+				seat := sess.SeatToPlayLocked(pid)
+				sp.State = match.State(seat)
+				sp.ValidActions = match.ValidActions(seat)
+				if match.IsOver() {
+					sp.Results = game.Results(match)
+					sp.Summary = summary
+				}
+				return nil
+			})
+			sess.RUnlock()
+			if err != nil {
+				s.failIfPanicked(context.Background(), sess, err)
+				return
 			}
 		}
-		sendWSMsg(p.Send, "state", sp)
+		// A player's view is marshaled once for all of their connections
+		// that subscribed to the same sections
+		enc := s.newStateEncoder(sp)
+		for _, send := range sess.PlayerSends(pid) {
+			enc.send(send)
+		}
+
+		sess.RLock()
+		hook := p.Webhook
+		sess.RUnlock()
+		if hook != "" && len(sp.ValidActions) > 0 {
+			go s.notifyWebhook(hook, turnNotification{SessionCode: info.Code, PlayerID: pid, statePayload: sp})
+		}
+	}
+
+	sess.RLock()
+	spectators := make([]chan []byte, 0, len(sess.Spectators))
+	for _, send := range sess.Spectators {
+		spectators = append(spectators, send)
+	}
+	sess.RUnlock()
+	if len(spectators) == 0 {
+		return
+	}
+	sp, err := spectatorState(sess)
+	if s.failIfPanicked(context.Background(), sess, err) {
+		return
+	}
+	enc := s.newStateEncoder(sp)
+	for _, send := range spectators {
+		enc.send(send)
+	}
+}
+
+// broadcastEvents sends an action's events to everyone watching, ahead of
+// the state they lead to.
+func (s *Server) broadcastEvents(sess *session.Session, ep eventsPayload) {
+	msg := encodeWSMsg("events", ep)
+	for _, send := range watcherSends(sess) {
+		sendEncoded(send, msg)
 	}
 }
 
+// watcherSends returns the send channels of every player's connections
+// and every spectator.
+func watcherSends(sess *session.Session) []chan []byte {
+	var sends []chan []byte
+	for _, pid := range sess.PlayerIDs() {
+		sends = append(sends, sess.PlayerSends(pid)...)
+	}
+	sess.RLock()
+	for _, send := range sess.Spectators {
+		sends = append(sends, send)
+	}
+	sess.RUnlock()
+	return sends
+}
+
+// sendSpectatorState sends the observer view to one spectator.
+func (s *Server) sendSpectatorState(sess *session.Session, send chan []byte) {
+	sp, err := spectatorState(sess)
+	if s.failIfPanicked(context.Background(), sess, err) {
+		return
+	}
+	s.newStateEncoder(sp).send(send)
+}
+
+// spectatorState is the observer view: the state as seen by no particular
+// player, with no valid actions. It fails with a *session.PanicError if
+// the game panics.
+func spectatorState(sess *session.Session) (statePayload, error) {
+	sess.RLock()
+	defer sess.RUnlock()
+	sp := statePayload{
+		SessionInfo: sess.InfoLocked(),
+		Scoreboard:  sess.ScoreboardLocked(),
+		Fairness:    sess.CommitmentLocked(),
+	}
+	if sess.Match == nil || sess.Status == session.StatusWaiting || sess.Status == session.StatusErrored {
+		return sp, nil
+	}
+	err := session.Protect(func() error {
+		sp.State = sess.Match.State("")
+		if sess.Match.IsOver() {
+			sp.Results = game.Results(sess.Match)
+			sp.Summary = sess.SummaryLocked()
+		}
+		return nil
+	})
+	return sp, err
+}
+
+// encodeState marshals a state message with the given sections of sp, or
+// an error in its place if it is over the game's broadcast limit.
+func (s *Server) encodeState(sp statePayload, sec sections) []byte {
+	sp.ServerTime = serverNow()
+	msg := encodeWSMsg("state", sp.filter(sec))
+	if err := s.manager.CheckBroadcast(sp.SessionInfo.GameType, sp.SessionInfo.Code, len(msg)); err != nil {
+		return encodeWSMsg("error", errorPayload{Message: "game state too large to send"})
+	}
+	return msg
+}
+
 func sendWSMsg(send chan []byte, msgType string, payload any) {
+	sendEncoded(send, encodeWSMsg(msgType, payload))
+}
+
+// encodeWSMsg marshals a message once, for sending to any number of
+// connections.
+func encodeWSMsg(msgType string, payload any) []byte {
 	p, _ := json.Marshal(payload)
 	msg, _ := json.Marshal(WSMessage{Type: msgType, Payload: p})
+	return msg
+}
+
+// sendEncoded queues an encoded message, dropping it if the connection
+// is not keeping up.
+func sendEncoded(send chan []byte, msg []byte) {
 	select {
 	case send <- msg:
 	default:
 	}
 }
 
-func sendWSError(ctx context.Context, conn *websocket.Conn, message string) {
+func sendWSError(ctx context.Context, conn wsWriter, message string) {
 	p, _ := json.Marshal(errorPayload{Message: message})
 	msg, _ := json.Marshal(WSMessage{Type: "error", Payload: p})
 	conn.Write(ctx, websocket.MessageText, msg)
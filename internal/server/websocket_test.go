@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"nhooyr.io/websocket"
 
 	"games/internal/game"
+	"games/internal/session"
 )
 
 func TestWSJoinNewPlayer(t *testing.T) {
@@ -17,7 +19,7 @@ func TestWSJoinNewPlayer(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	sess, _ := env.mgr.Create("tictactoe")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
 	// Don't pre-add "alice" — let the WS handler add them
 	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, sess.Code), nil)
 	if err != nil {
@@ -44,7 +46,7 @@ func TestWSJoinInvalidPayload(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	sess, _ := env.mgr.Create("tictactoe")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
 
 	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, sess.Code), nil)
 	if err != nil {
@@ -67,7 +69,7 @@ func TestWSFirstMessageNotJoin(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	sess, _ := env.mgr.Create("tictactoe")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
 
 	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, sess.Code), nil)
 	if err != nil {
@@ -108,7 +110,7 @@ func TestWSActionValid(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	sess, err := env.mgr.Create("tictactoe")
+	sess, err := env.mgr.Create(t.Context(), "tictactoe")
 	if err != nil {
 		t.Fatalf("create: %v", err)
 	}
@@ -142,7 +144,7 @@ func TestWSActionValid(t *testing.T) {
 	wsRead(ctx, t, connAlice) // broadcast from bob's join
 	bobState := wsRead(ctx, t, connBob)
 
-	// Determine who has valid actions (goes first)
+	// Alice joined first, so she moves first
 	var aliceSP, bobSP statePayload
 	if err := json.Unmarshal(aliceState.Payload, &aliceSP); err != nil {
 		t.Fatalf("unmarshal alice state: %v", err)
@@ -150,22 +152,27 @@ func TestWSActionValid(t *testing.T) {
 	if err := json.Unmarshal(bobState.Payload, &bobSP); err != nil {
 		t.Fatalf("unmarshal bob state: %v", err)
 	}
-
-	// Pick the connection whose player has valid actions
-	var activeConn *websocket.Conn
-	if len(aliceSP.ValidActions) > 0 {
-		activeConn = connAlice
-	} else {
-		activeConn = connBob
+	if len(aliceSP.ValidActions) == 0 || len(bobSP.ValidActions) != 0 {
+		t.Fatalf("expected alice to move first, got %d and %d valid actions", len(aliceSP.ValidActions), len(bobSP.ValidActions))
 	}
+	activeConn := connAlice
 
 	// Send a move action from the active player
 	movePayload, _ := json.Marshal(map[string]int{"cell": 0})
 	actionData, _ := json.Marshal(actionPayload{Action: game.Action{Type: "move", Payload: movePayload}})
 	wsSend(ctx, t, activeConn, WSMessage{Type: "action", Payload: actionData})
 
-	// Read the state update
+	// The move's events come first, then the state update
 	msg := wsRead(ctx, t, activeConn)
+	if msg.Type != "events" {
+		t.Fatalf("expected events after action, got %s", msg.Type)
+	}
+	var ep eventsPayload
+	json.Unmarshal(msg.Payload, &ep)
+	if ep.Seq != 1 || len(ep.Events) != 1 || ep.Events[0].Type != "placed" {
+		t.Fatalf("unexpected events: %s", msg.Payload)
+	}
+	msg = wsRead(ctx, t, activeConn)
 	if msg.Type != "state" {
 		t.Fatalf("expected state after action, got %s", msg.Type)
 	}
@@ -176,7 +183,7 @@ func TestWSActionGameNotStarted(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	sess, _ := env.mgr.Create("tictactoe")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 
 	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, sess.Code), nil)
@@ -212,7 +219,7 @@ func TestWSStartByNonHost(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	sess, _ := env.mgr.Create("tictactoe")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice") // host
 	sess.AddPlayer("bob")
 
@@ -247,7 +254,7 @@ func TestWSUnknownMessageType(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	sess, _ := env.mgr.Create("tictactoe")
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 
 	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, sess.Code), nil)
@@ -275,3 +282,158 @@ func TestWSUnknownMessageType(t *testing.T) {
 	}
 }
 
+
+func TestWSPlayAgainstBot(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	code := createSessionViaAPI(t, env.ts, "tictactoe", "alice")
+	conn := wsConnect(t, env.ts, code, "alice")
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, conn)
+
+	if err := sendWS(ctx, conn, "add_bot", addBotPayload{Strategy: "nonexistent"}); err != nil {
+		t.Fatalf("send add_bot: %v", err)
+	}
+	if errMsg := readError(t, ctx, conn); !strings.Contains(errMsg, "unknown bot strategy") {
+		t.Fatalf("expected unknown strategy error, got %q", errMsg)
+	}
+
+	if err := sendWS(ctx, conn, "add_bot", addBotPayload{Strategy: "perfect"}); err != nil {
+		t.Fatalf("send add_bot: %v", err)
+	}
+	sp := readState(t, ctx, conn)
+	if len(sp.SessionInfo.Bots) != 1 || sp.SessionInfo.Bots[0].Difficulty != 3 {
+		t.Fatalf("expected perfect bot in session info, got %+v", sp.SessionInfo.Bots)
+	}
+
+	if err := sendWS(ctx, conn, "start", nil); err != nil {
+		t.Fatalf("send start: %v", err)
+	}
+	// Keep reading until it is alice's move; the bot moves on its own.
+	for {
+		sp = readState(t, ctx, conn)
+		if len(sp.ValidActions) > 0 {
+			break
+		}
+	}
+	if err := sendWS(ctx, conn, "action", actionPayload{Action: sp.ValidActions[0]}); err != nil {
+		t.Fatalf("send action: %v", err)
+	}
+	readState(t, ctx, conn) // alice's move
+	sp = readState(t, ctx, conn)
+	if sm := stateMap(t, sp); sm["turn"] != "alice" {
+		t.Fatalf("expected bot to reply and hand the turn back, got turn %v", sm["turn"])
+	}
+}
+
+func TestWSSpectateExhibition(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	body := `{"gameType":"tictactoe","bots":["perfect","blocking"],"moveDelayMs":20}`
+	resp, err := http.Post(env.ts.URL+"/api/exhibitions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST exhibition: %v", err)
+	}
+	var created createSessionResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+
+	conn, _, err := websocket.Dial(ctx, wsURL(env.ts, created.Code), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	payload, _ := json.Marshal(joinPayload{PlayerID: "watcher", Spectate: true})
+	wsSend(ctx, t, conn, WSMessage{Type: "join", Payload: payload})
+
+	// Watch until the bots finish
+	for {
+		sp := readState(t, ctx, conn)
+		if len(sp.ValidActions) != 0 {
+			t.Fatal("spectator should not receive valid actions")
+		}
+		if sp.Results != nil {
+			if sp.Summary == nil || sp.Summary.Moves < 5 {
+				t.Fatalf("expected a match summary with the results, got %+v", sp.Summary)
+			}
+			break
+		}
+	}
+
+	// Spectators cannot act
+	if err := sendWS(ctx, conn, "action", makeAction(t, 0)); err != nil {
+		t.Fatalf("send action: %v", err)
+	}
+	if errMsg := readError(t, ctx, conn); !strings.Contains(errMsg, "spectators") {
+		t.Fatalf("expected spectator error, got %q", errMsg)
+	}
+
+	// Results are archived shortly after the match ends
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Get(env.ts.URL + "/api/games/tictactoe/bots/standings")
+		if err != nil {
+			t.Fatalf("GET standings: %v", err)
+		}
+		var standings []session.StrategyStanding
+		json.NewDecoder(resp.Body).Decode(&standings)
+		resp.Body.Close()
+		if len(standings) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 archived strategies, got %+v", standings)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err = http.Get(env.ts.URL + "/api/games/tictactoe/stats")
+	if err != nil {
+		t.Fatalf("GET stats: %v", err)
+	}
+	var stats session.GameStats
+	json.NewDecoder(resp.Body).Decode(&stats)
+	resp.Body.Close()
+	if stats.Matches != 1 || stats.AvgMoves < 5 {
+		t.Fatalf("expected the match in the game stats, got %+v", stats)
+	}
+}
+
+func TestWSPlayerWithTwoTabs(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	first := wsConnect(t, env.ts, sess.Code, "alice")
+	defer first.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, first)
+	second := wsConnect(t, env.ts, sess.Code, "alice")
+	defer second.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, first) // the second tab joining does not cut off the first
+	readState(t, ctx, second)
+
+	// A move from either tab reaches both.
+	sendWS(ctx, second, "action", makeAction(t, 0))
+	for _, conn := range []*websocket.Conn{first, second} {
+		if sp := readState(t, ctx, conn); len(sp.ValidActions) != 0 {
+			t.Fatalf("expected bob to move next, got %d valid actions", len(sp.ValidActions))
+		}
+	}
+	if sends := sess.PlayerSends("alice"); len(sends) != 2 {
+		t.Fatalf("expected 2 connections for alice, got %d", len(sends))
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"games/internal/session"
+)
+
+// wsWriter is what a message is written to: a session connection,
+// recorded or not, or another WebSocket.
+type wsWriter interface {
+	Write(ctx context.Context, typ websocket.MessageType, p []byte) error
+}
+
+// wsConn is a session WebSocket. While the server records traffic,
+// every message read from and written to it goes into the session's
+// transcript.
+type wsConn struct {
+	*websocket.Conn
+	rec  *wsRecording // nil when not recording
+	name string
+	path string // see wsFrame.Path
+}
+
+func (c *wsConn) Read(ctx context.Context) (websocket.MessageType, []byte, error) {
+	typ, data, err := c.Conn.Read(ctx)
+	if err == nil {
+		c.rec.record(c.name, c.path, "client", data)
+	}
+	return typ, data, err
+}
+
+func (c *wsConn) Write(ctx context.Context, typ websocket.MessageType, p []byte) error {
+	err := c.Conn.Write(ctx, typ, p)
+	if err == nil {
+		c.rec.record(c.name, c.path, "server", p)
+	}
+	return err
+}
+
+// wsTranscriptHeader is the first line of a transcript: the session as
+// recording found it. Only a session recorded from before its first
+// join can be replayed.
+type wsTranscriptHeader struct {
+	Code    string         `json:"code"`
+	Game    string         `json:"game"`
+	Options map[string]int `json:"options,omitempty"`
+	Status  session.Status `json:"status"`
+}
+
+// wsFrame is one message in a transcript, a line after the header, or the
+// end of a connection.
+type wsFrame struct {
+	Conn string `json:"conn"` // "c1", "c2", ... in the order connections opened
+	// Path is where a connection other than the session's own socket
+	// was opened, after /api/sessions/<code>/, such as
+	// "analysis?player=alice".
+	Path string `json:"path,omitempty"`
+	From string `json:"from,omitempty"` // "client" or "server"
+	Ms   int64  `json:"ms"`             // since recording began
+	// Message is the message as sent, unless it is not JSON, when Text
+	// holds it instead.
+	Message json.RawMessage `json:"message,omitempty"`
+	Text    string          `json:"text,omitempty"`
+	Closed  bool            `json:"closed,omitempty"`
+}
+
+// wsRecording appends a session's WebSocket traffic to its transcript,
+// keeping the file open while any connection is.
+type wsRecording struct {
+	mu    sync.Mutex
+	path  string
+	start time.Time
+	conns int // opened so far, to name the next
+	open  int
+	file  *os.File
+}
+
+// SetWSRecordDir makes the server record the WebSocket traffic of every
+// session to dir/<code>.jsonl, for turning into replay tests. Empty turns
+// recording off.
+func (s *Server) SetWSRecordDir(dir string) {
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	s.recordDir = dir
+}
+
+// recordConn wraps a newly accepted session connection, recording it if
+// the server records traffic. path is empty for the session's socket, or
+// else where under the session the connection was opened. done must be
+// called when the connection ends.
+func (s *Server) recordConn(conn *websocket.Conn, sess *session.Session, path string) (c *wsConn, done func()) {
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	if s.recordDir == "" {
+		return &wsConn{Conn: conn, path: path}, func() {}
+	}
+	rec, ok := s.recordings[sess.Code]
+	if !ok {
+		rec = &wsRecording{path: filepath.Join(s.recordDir, sess.Code+".jsonl"), start: time.Now()}
+		if s.recordings == nil {
+			s.recordings = make(map[string]*wsRecording)
+		}
+		s.recordings[sess.Code] = rec
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.file == nil {
+		f, err := os.OpenFile(rec.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Printf("record session %s: %v", sess.Code, err)
+			return &wsConn{Conn: conn, path: path}, func() {}
+		}
+		if rec.conns == 0 {
+			info := sess.Info()
+			header, _ := json.Marshal(wsTranscriptHeader{Code: info.Code, Game: info.GameType, Options: info.Options, Status: info.Status})
+			fmt.Fprintf(f, "%s\n", header)
+		}
+		rec.file = f
+	}
+	rec.conns++
+	rec.open++
+	name := "c" + strconv.Itoa(rec.conns)
+	return &wsConn{Conn: conn, rec: rec, name: name, path: path}, func() { rec.closeConn(name) }
+}
+
+func (r *wsRecording) record(conn, path, from string, msg []byte) {
+	if r == nil {
+		return
+	}
+	f := wsFrame{Conn: conn, Path: path, From: from}
+	if json.Valid(msg) {
+		f.Message = msg
+	} else {
+		f.Text = string(msg)
+	}
+	r.write(f)
+}
+
+func (r *wsRecording) write(f wsFrame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	f.Ms = time.Since(r.start).Milliseconds()
+	line, _ := json.Marshal(f)
+	r.file.Write(append(line, '\n'))
+}
+
+// closeConn records the end of a connection, closing the transcript once
+// no connection is left to record.
+func (r *wsRecording) closeConn(conn string) {
+	r.write(wsFrame{Conn: conn, Closed: true})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.open--; r.open == 0 && r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"games/internal/game"
+	"games/internal/session"
+)
+
+// wsMessageSpec describes one WebSocket message type. Payload is a value
+// of the Go type its payload is marshaled from or decoded into, or nil for
+// a message without one.
+type wsMessageSpec struct {
+	Type        string
+	Description string
+	Payload     any
+}
+
+// wsClientMessages are the messages handleMessage accepts, and join, which
+// opens every connection. Spectators may send only join and ping.
+var wsClientMessages = []wsMessageSpec{
+	{"join", "Opens the connection, taking or reclaiming a seat, or watching as a spectator.", joinPayload{}},
+	{"action", "Makes a move.", actionPayload{}},
+	{"action_text", "Makes a move written in the game's notation, such as b2.", actionTextPayload{}},
+	{"ping", "Probes the server's clock; answered with pong.", pingPayload{}},
+	{"undo", "Takes back the last move and any bot replies to it. Practice sessions only.", nil},
+	{"setup", "Before the start, sets the starting position, or the usual start when empty; host only. In practice, sets the match up in a position to practise from, which undo goes back no further than.", setupPayload{}},
+	{"start", "Starts the match. Host only.", nil},
+	{"next_game", "Starts a party's next round. Host only.", nil},
+	{"rematch", "Plays the same game again with the same players. Host only.", nil},
+	{"restore", "Restores an errored match from its last saved state. Host only.", nil},
+	{"abort", "Abandons an errored match without a result. Host only.", nil},
+	{"add_bot", "Seats a built-in bot. Host only.", addBotPayload{}},
+	{"choose_seat", "Picks a seat before the start.", chooseSeatPayload{}},
+	{"shuffle_seats", "Seats everyone at random. Host only.", nil},
+	{"handoff", "Asks for a code to move this seat to another device; answered with handoff.", nil},
+	{"turn_order", "Sets how unseated players are seated. Host only.", turnOrderPayload{}},
+	{"reserve", "Holds seats for invited players. Host only.", reservePayload{}},
+	{"vote_thresholds", "Sets the share of players needed to skip or remove a player. Host only.", session.VoteThresholds{}},
+	{"handicaps", "Gives players an uneven start, replacing any handicaps set before. Host only.", handicapsPayload{}},
+	{"vote", "Votes to skip the turn of, or remove, an unresponsive player.", votePayload{}},
+	{"chat", "Says something to the session, or runs a slash command such as /help.", chatPayload{}},
+	{"emote", "Sends a quick reaction: 👍, 😮 or GG.", emotePayload{}},
+	{"survey_answer", "Answers the survey about the last match: ratings from 1 to 5, a comment on the opponents and a bug report, each optional. An empty answer dismisses it.", session.SurveyResponse{}},
+	{"rtc_signal", "Passes a WebRTC offer, answer, ICE candidate or hangup to another connected player, for voice chat.", rtcSignalPayload{}},
+}
+
+// wsServerMessages are the messages the server sends.
+var wsServerMessages = []wsMessageSpec{
+	{"state", "The session and the match as the receiver may see them, with only the sections it subscribed to.", statePayload{}},
+	{"events", "What an action did, sent before the state it leads to.", eventsPayload{}},
+	{"narration", "What a move did, in words, for connections that joined with narration in a game that describes its moves.", narrationPayload{}},
+	{"message", "A private message from the game to this player.", game.Message{}},
+	{"vote", "The tally of a vote after each ballot.", session.VoteTally{}},
+	{"chat", "A chat line, or the answer to a slash command.", chatMessagePayload{}},
+	{"emote", "A player's quick reaction, apart from chat; those of the last half minute are replayed on joining.", session.Emote{}},
+	{"survey", "Asks how the match that just finished went, when the server runs surveys; answered with survey_answer.", surveyPayload{}},
+	{"rtc_signal", "A WebRTC signal another player sent this one.", rtcSignalMessagePayload{}},
+	{"seat", "The seat a redeemed handoff code gave this device.", seatPayload{}},
+	{"handoff", "The code to enter on the device taking over this seat.", handoffPayload{}},
+	{"pong", "The answer to ping.", pongPayload{}},
+	{"achievement_unlocked", "An achievement this player just unlocked, to show as a toast.", session.UnlockedAchievement{}},
+	{"error", "Why a message was refused, or that the game stopped.", errorPayload{}},
+}
+
+// wsSchema is the document GET /api/ws-schema serves, built once.
+var wsSchema = sync.OnceValue(func() []byte {
+	data, _ := json.Marshal(WSSchema(nil))
+	return data
+})
+
+// handleWSSchema describes the session WebSocket protocol as an AsyncAPI
+// 3.0 document, with a JSON Schema for every message, so clients can
+// validate messages and generate types.
+func (s *Server) handleWSSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(wsSchema())
+}
+
+// WSSchema reflects the WebSocket protocol into an AsyncAPI document. The
+// server receives client messages and sends its own; a type both sides
+// use, such as handoff, appears once for each. states maps game names to
+// a state view of each game, added to the schemas as "<Game>State"; the
+// protocol leaves a game's state open.
+func WSSchema(states map[string]any) map[string]any {
+	sg := newSchemaGen()
+	sg.define(reflect.TypeFor[WSMessage](), "")
+	for name, state := range states {
+		t := reflect.TypeOf(state)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			sg.define(t, exportedName(name)+"State")
+		}
+	}
+	messages := make(map[string]any)
+	refs := func(prefix string, specs []wsMessageSpec) []any {
+		var out []any
+		for _, spec := range specs {
+			name := prefix + exportedName(spec.Type)
+			payload := map[string]any{"type": "null"}
+			if spec.Payload != nil {
+				payload = sg.schema(reflect.TypeOf(spec.Payload))
+			}
+			messages[name] = map[string]any{
+				"name":    spec.Type,
+				"summary": spec.Description,
+				"payload": map[string]any{
+					"type":     "object",
+					"required": []string{"type"},
+					"properties": map[string]any{
+						"type":    map[string]any{"const": spec.Type},
+						"payload": payload,
+					},
+				},
+			}
+			out = append(out, map[string]any{"$ref": "#/channels/session/messages/" + name})
+		}
+		return out
+	}
+	received := refs("Client", wsClientMessages)
+	sent := refs("Server", wsServerMessages)
+	channelMessages := make(map[string]any, len(messages))
+	for name := range messages {
+		channelMessages[name] = map[string]any{"$ref": "#/components/messages/" + name}
+	}
+	return map[string]any{
+		"asyncapi": "3.0.0",
+		"info": map[string]any{
+			"title":   "Game session WebSocket",
+			"version": "1",
+			"description": "Every message is a JSON object with a type and a payload. " +
+				"A connection first sends join; the server answers with state.",
+		},
+		"channels": map[string]any{
+			"session": map[string]any{
+				"address":  "/api/sessions/{code}/ws",
+				"messages": channelMessages,
+				"parameters": map[string]any{
+					"code": map[string]any{"description": "The session code."},
+				},
+			},
+		},
+		"operations": map[string]any{
+			"receiveClientMessages": map[string]any{
+				"action":   "receive",
+				"channel":  map[string]any{"$ref": "#/channels/session"},
+				"messages": received,
+			},
+			"sendServerMessages": map[string]any{
+				"action":   "send",
+				"channel":  map[string]any{"$ref": "#/channels/session"},
+				"messages": sent,
+			},
+		},
+		"components": map[string]any{
+			"messages": messages,
+			"schemas":  sg.defs,
+		},
+	}
+}
+
+// schemaGen builds JSON Schemas from Go types the way encoding/json
+// marshals them, naming each struct type once under components.
+type schemaGen struct {
+	defs  map[string]any
+	names map[reflect.Type]string
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{defs: make(map[string]any), names: make(map[reflect.Type]string)}
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	numberType     = reflect.TypeFor[json.Number]()
+)
+
+func (sg *schemaGen) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	case numberType:
+		return map[string]any{"type": "number"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return sg.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": sg.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": sg.schema(t.Elem())}
+	case reflect.Struct:
+		return map[string]any{"$ref": "#/components/schemas/" + sg.define(t, "")}
+	}
+	return map[string]any{} // interfaces hold anything
+}
+
+// define adds a struct type's schema to the components, returning its
+// name there: name if given, otherwise the type's own.
+func (sg *schemaGen) define(t reflect.Type, name string) string {
+	if name, ok := sg.names[t]; ok {
+		return name
+	}
+	if name == "" {
+		name = exportedName(t.Name())
+	}
+	if _, taken := sg.defs[name]; taken {
+		name = exportedName(t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]) + name
+	}
+	sg.names[t] = name
+	sg.defs[name] = nil // reserve the name while fields refer back to it
+	properties := make(map[string]any)
+	required := []string{}
+	sg.fields(t, properties, &required)
+	sg.defs[name] = map[string]any{"type": "object", "properties": properties, "required": required}
+	return name
+}
+
+// fields adds t's marshaled fields to properties, flattening embedded
+// structs as encoding/json does.
+func (sg *schemaGen) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			sg.fields(f.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		schema := sg.schema(f.Type)
+		if strings.Contains(opts, "string") {
+			schema = map[string]any{"type": "string"}
+		}
+		properties[name] = schema
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// exportedName turns a message type or Go type name into a component
+// name: "choose_seat" becomes "ChooseSeat", "joinPayload" "JoinPayload".
+func exportedName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"nhooyr.io/websocket"
+)
+
+func TestWSSchema(t *testing.T) {
+	env := setupTestEnv(t)
+	resp, err := http.Get(env.ts.URL + "/api/ws-schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc struct {
+		Components struct {
+			Messages map[string]struct {
+				Name    string `json:"name"`
+				Payload struct {
+					Properties struct {
+						Payload map[string]any `json:"payload"`
+					} `json:"properties"`
+				} `json:"payload"`
+			} `json:"messages"`
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+				Required   []string       `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	join := doc.Components.Messages["ClientJoin"]
+	if join.Name != "join" || join.Payload.Properties.Payload["$ref"] != "#/components/schemas/JoinPayload" {
+		t.Fatalf("unexpected join message %+v", join)
+	}
+	jp := doc.Components.Schemas["JoinPayload"]
+	if _, ok := jp.Properties["sections"]; !ok || !slices.Equal(jp.Required, []string{"playerId"}) {
+		t.Fatalf("unexpected join payload schema %+v", jp)
+	}
+	if sp := doc.Components.Schemas["StatePayload"]; !slices.Contains(sp.Required, "serverTime") || slices.Contains(sp.Required, "results") {
+		t.Fatalf("unexpected state payload schema %+v", sp)
+	}
+	if info, ok := doc.Components.Schemas["Info"]; !ok || info.Properties["voteThresholds"] == nil {
+		t.Fatalf("expected session info described, got %+v", info)
+	}
+}
+
+// TestWSSchemaCoversMessages checks that the schema lists every message
+// the server handles.
+func TestWSSchemaCoversMessages(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx, cancel := timeoutCtx(t)
+	defer cancel()
+
+	sess, _ := env.mgr.Create(t.Context(), "tictactoe")
+	conn := wsConnect(t, env.ts, sess.Code, "alice")
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	readState(t, ctx, conn)
+
+	sendWS(ctx, conn, "bogus", nil)
+	if msg := readError(t, ctx, conn); msg != "unknown message type: bogus" {
+		t.Fatalf("unexpected error %q", msg)
+	}
+	for _, spec := range wsClientMessages {
+		if spec.Type == "join" || spec.Type == "start" {
+			continue // already joined; starting would change what follows
+		}
+		// Every message gets some answer, if only an error
+		sendWS(ctx, conn, spec.Type, nil)
+		msg := wsRead(ctx, t, conn)
+		var ep errorPayload
+		json.Unmarshal(msg.Payload, &ep)
+		if strings.HasPrefix(ep.Message, "unknown message type") {
+			t.Fatalf("schema lists %s, which the server does not handle", spec.Type)
+		}
+	}
+}
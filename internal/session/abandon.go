@@ -0,0 +1,107 @@
+package session
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"games/internal/event"
+	"games/internal/game"
+	"games/internal/storage"
+)
+
+// awayLocked reports whether a playing session has players who connect
+// over WebSocket but none of them is connected. Bots, built-in or driven
+// by webhook, never connect and do not count. The caller must hold the
+// lock.
+func (s *Session) awayLocked() bool {
+	connecting := 0
+	for _, p := range s.Players {
+		if p.Strategy != nil || p.Webhook != "" {
+			continue
+		}
+		if len(p.conns) > 0 {
+			return false
+		}
+		connecting++
+	}
+	return connecting > 0
+}
+
+// abandonLocked finishes the match as abandoned if every player has
+// been away since before cutoff, returning the event to publish. Practice
+// matches are left for the player to come back to. The caller must hold
+// the write lock.
+func (s *Session) abandonLocked(now, cutoff time.Time) *event.Event {
+	if s.Status != StatusPlaying || s.Practice || !s.awayLocked() {
+		s.awaySince = time.Time{}
+		return nil
+	}
+	if s.awaySince.IsZero() {
+		s.awaySince = now
+	}
+	if s.awaySince.After(cutoff) {
+		return nil
+	}
+	s.Status = StatusFinished
+	s.Abandoned = true
+	s.FinishedAt = now
+	s.LastActivity = now
+	info := s.infoLocked()
+	return &event.Event{
+		Type:        event.MatchFinished,
+		SessionCode: info.Code,
+		GameType:    info.GameType,
+		Players:     info.Players,
+		Results:     abandonedResults(s.seating),
+		Abandoned:   true,
+		Private:     info.Private,
+	}
+}
+
+// abandonedResults gives every player of an abandoned match the abandoned
+// outcome. Nobody is ranked.
+func abandonedResults(players []string) []game.PlayerResult {
+	results := make([]game.PlayerResult, len(players))
+	for i, id := range players {
+		results[i] = game.PlayerResult{PlayerID: id, Outcome: game.OutcomeAbandoned}
+	}
+	return results
+}
+
+// abandonIdle finishes every playing session whose players have all been
+// disconnected for longer than after, recording the match as abandoned
+// instead of leaving it in play until cleanup deletes it.
+func (m *Manager) abandonIdle(ctx context.Context, after time.Duration) {
+	m.mu.RLock()
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	m.mu.RUnlock()
+
+	now := time.Now()
+	for _, s := range sessions {
+		s.mu.Lock()
+		finished := s.abandonLocked(now, now.Add(-after))
+		s.mu.Unlock()
+		if finished == nil {
+			continue
+		}
+		log.Printf("session %s abandoned", s.Code)
+		err := m.store.WithTx(ctx, func(tx storage.Backend) error {
+			if err := tx.SetSessionAbandoned(ctx, s.Code, true); err != nil {
+				return err
+			}
+			return m.saveMatchState(ctx, tx, s)
+		})
+		if err != nil {
+			log.Printf("save abandoned session %s: %v", s.Code, err)
+		}
+		if err := m.ArchiveMatch(ctx, s); err != nil {
+			log.Printf("archive abandoned session %s: %v", s.Code, err)
+		}
+		m.RecordTimeline(ctx, s.Code, TimelineFinished, "", map[string]any{"results": finished.Results, "abandoned": true})
+		m.events.Publish(*finished)
+	}
+}
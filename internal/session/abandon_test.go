@@ -0,0 +1,77 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"games/internal/event"
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+)
+
+func TestAbandonIdle(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	events, unsubscribe := mgr.Events().Subscribe(10)
+	defer unsubscribe()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+	send := make(chan []byte, 1)
+	sess.ConnectPlayer("alice", send)
+
+	mgr.abandonIdle(t.Context(), time.Hour)
+	if !sess.awaySince.IsZero() {
+		t.Fatal("expected a match with a connected player not to be away")
+	}
+
+	sess.DisconnectPlayer("alice", send)
+	mgr.abandonIdle(t.Context(), time.Hour)
+	if sess.Info().Status != StatusPlaying {
+		t.Fatal("expected the match to wait out the threshold")
+	}
+
+	sess.awaySince = time.Now().Add(-2 * time.Hour)
+	mgr.abandonIdle(t.Context(), time.Hour)
+	info := sess.Info()
+	if info.Status != StatusFinished || !info.Abandoned || info.FinishedAt == "" {
+		t.Fatalf("expected the match to be abandoned, got %+v", info)
+	}
+	select {
+	case e := <-events:
+		if e.Type != event.MatchFinished || !e.Abandoned || len(e.Results) != 2 || e.Results[0].Outcome != game.OutcomeAbandoned {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	default:
+		t.Fatal("expected a match finished event")
+	}
+	for _, e := range sess.Scoreboard() {
+		if e.Abandoned != 1 || e.Played != 0 {
+			t.Fatalf("expected an abandoned match on the scoreboard, got %+v", e)
+		}
+	}
+	row, err := mgr.store.GetSession(t.Context(), sess.Code)
+	if err != nil || !row.Abandoned || row.Status != string(StatusFinished) {
+		t.Fatalf("expected the abandonment to be stored, got %+v %v", row, err)
+	}
+	if stats, _ := mgr.GameStats(t.Context(), "tictactoe"); stats.Abandoned != 1 || stats.Matches != 0 {
+		t.Fatalf("expected the abandoned match to be archived apart, got %+v", stats)
+	}
+}
+
+func TestAbandonIdleIgnoresBots(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	for _, st := range tictactoe.Strategies()[:2] {
+		sess.AddBot(st)
+	}
+	sess.Start()
+	mgr.abandonIdle(t.Context(), 0)
+	if info := sess.Info(); info.Status != StatusPlaying {
+		t.Fatalf("expected a bot-only match to keep playing, got %s", info.Status)
+	}
+}
@@ -0,0 +1,183 @@
+package session
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"games/internal/event"
+	"games/internal/game"
+	"games/internal/storage"
+)
+
+const (
+	// streakLength is how many matches in a row a player must win for the
+	// win streak achievement.
+	streakLength = 10
+	// marathonLength is how long a session must have run when a player
+	// finishes a match in it for the marathon achievement.
+	marathonLength = time.Hour
+)
+
+// genericAchievements can be earned in every game. A game's own
+// achievements have IDs prefixed with the game's name, such as
+// tictactoe/flawless.
+var genericAchievements = []game.Achievement{
+	{ID: "first_win", Name: "First Win", Description: "Win a match"},
+	{ID: "win_streak", Name: "On a Roll", Description: "Win 10 matches in a row"},
+	{ID: "marathon", Name: "Marathon", Description: "Finish a match in a session that has run for an hour"},
+}
+
+// AchievementInfo describes an achievement; GameType is empty for those
+// every game shares.
+type AchievementInfo struct {
+	game.Achievement
+	GameType string `json:"gameType,omitempty"`
+}
+
+// UnlockedAchievement is an achievement a player has, and the session
+// they earned it in.
+type UnlockedAchievement struct {
+	AchievementInfo
+	SessionCode string    `json:"sessionCode"`
+	UnlockedAt  time.Time `json:"unlockedAt"`
+}
+
+// Achievements describes every achievement that can be earned: the
+// generic ones, then each game's own.
+func (m *Manager) Achievements() []AchievementInfo {
+	var all []AchievementInfo
+	for _, a := range genericAchievements {
+		all = append(all, AchievementInfo{Achievement: a})
+	}
+	for _, name := range m.registry.Names() {
+		g, _ := m.registry.Get(name)
+		achiever, ok := g.(game.Achiever)
+		if !ok {
+			continue
+		}
+		for _, a := range achiever.Achievements() {
+			a.ID = name + "/" + a.ID
+			all = append(all, AchievementInfo{Achievement: a, GameType: name})
+		}
+	}
+	return all
+}
+
+// Achievement describes the achievement with the given ID.
+func (m *Manager) Achievement(id string) (AchievementInfo, bool) {
+	all := m.Achievements()
+	i := slices.IndexFunc(all, func(a AchievementInfo) bool { return a.ID == id })
+	if i < 0 {
+		return AchievementInfo{}, false
+	}
+	return all[i], true
+}
+
+// PlayerAchievements returns the achievements playerID unlocked, oldest
+// first. One of a game no longer registered keeps only its ID.
+func (m *Manager) PlayerAchievements(ctx context.Context, playerID string) ([]UnlockedAchievement, error) {
+	rows, err := m.store.ListAchievements(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	unlocked := make([]UnlockedAchievement, len(rows))
+	for i, r := range rows {
+		info, ok := m.Achievement(r.Achievement)
+		if !ok {
+			info = AchievementInfo{Achievement: game.Achievement{ID: r.Achievement}}
+			if gameType, _, ok := strings.Cut(r.Achievement, "/"); ok {
+				info.GameType = gameType
+			}
+		}
+		unlocked[i] = UnlockedAchievement{AchievementInfo: info, SessionCode: r.SessionCode, UnlockedAt: r.UnlockedAt}
+	}
+	return unlocked, nil
+}
+
+// RunAchievements awards the achievements human players earned in each
+// finished match, until the channel is closed, and publishes an
+// AchievementUnlocked event addressed to the player for each one they did
+// not have yet. Abandoned matches earn nothing.
+func (m *Manager) RunAchievements(events <-chan event.Event) {
+	for e := range events {
+		if e.Type != event.MatchFinished || e.Abandoned {
+			continue
+		}
+		m.awardAchievements(context.Background(), e)
+	}
+}
+
+func (m *Manager) awardAchievements(ctx context.Context, e event.Event) {
+	humans := m.humanPlayers(e)
+	earned := make(map[string][]string, len(humans))
+	for _, r := range e.Results {
+		if !slices.Contains(humans, r.PlayerID) {
+			continue
+		}
+		if r.Outcome != game.OutcomeWin {
+			if err := m.store.ResetProgress(ctx, r.PlayerID, "win_streak"); err != nil {
+				log.Printf("achievements of %s: %v", r.PlayerID, err)
+			}
+			continue
+		}
+		earned[r.PlayerID] = append(earned[r.PlayerID], "first_win")
+		streak, err := m.store.IncrementProgress(ctx, r.PlayerID, "win_streak")
+		if err != nil {
+			log.Printf("achievements of %s: %v", r.PlayerID, err)
+		} else if streak >= streakLength {
+			earned[r.PlayerID] = append(earned[r.PlayerID], "win_streak")
+		}
+	}
+	// The rest look at the session, as long as it still holds the match
+	if s, ok := m.Get(e.SessionCode); ok {
+		s.mu.RLock()
+		if s.Status == StatusFinished && s.Match != nil {
+			achiever, _ := s.game.(game.Achiever)
+			for _, id := range humans {
+				if e.At.Sub(s.CreatedAt) >= marathonLength {
+					earned[id] = append(earned[id], "marathon")
+				}
+				if achiever == nil {
+					continue
+				}
+				var own []string
+				err := Protect(func() error {
+					own = achiever.Earned(s.Match, id)
+					return nil
+				})
+				if err != nil {
+					log.Printf("achievements in %s: %v", s.Code, err)
+					break
+				}
+				for _, a := range own {
+					earned[id] = append(earned[id], e.GameType+"/"+a)
+				}
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	for _, id := range humans {
+		for _, a := range earned[id] {
+			row := storage.AchievementRow{PlayerID: id, Achievement: a, SessionCode: e.SessionCode}
+			unlocked, err := m.store.UnlockAchievement(ctx, row)
+			if err != nil {
+				log.Printf("achievement %s of %s: %v", a, id, err)
+				continue
+			}
+			if unlocked {
+				m.events.Publish(event.Event{
+					Type:        event.AchievementUnlocked,
+					SessionCode: e.SessionCode,
+					GameType:    e.GameType,
+					Achievement: a,
+					Private:     true,
+					Recipients:  []string{id},
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"games/internal/event"
+	"games/internal/game"
+)
+
+// playFlawless starts a match in a new session that alice wins with the
+// top row, from her first three marks, and returns its finished event.
+func playFlawless(t *testing.T, mgr *Manager) (*Session, event.Event) {
+	t.Helper()
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for i, cell := range []int{0, 3, 1, 4, 2} {
+		payload, _ := json.Marshal(map[string]int{"cell": cell})
+		if err := sess.Match.ApplyAction([]string{"alice", "bob"}[i%2], game.Action{Type: "move", Payload: payload}); err != nil {
+			t.Fatalf("move %d: %v", i, err)
+		}
+	}
+	sess.Finish()
+	return sess, event.Event{
+		Type:        event.MatchFinished,
+		SessionCode: sess.Code,
+		GameType:    "tictactoe",
+		Players:     []string{"alice", "bob"},
+		Results:     game.Results(sess.Match),
+		At:          time.Now(),
+	}
+}
+
+func TestAwardAchievements(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	unlocks, unsubscribe := mgr.Events().Subscribe(16)
+	defer unsubscribe()
+
+	sess, finished := playFlawless(t, mgr)
+	sess.CreatedAt = finished.At.Add(-2 * time.Hour)
+	mgr.awardAchievements(t.Context(), finished)
+
+	got := make(map[string][]string)
+	for len(unlocks) > 0 {
+		e := <-unlocks
+		if e.Type != event.AchievementUnlocked || len(e.Recipients) != 1 || e.SessionCode != sess.Code {
+			t.Fatalf("unexpected event %+v", e)
+		}
+		got[e.Recipients[0]] = append(got[e.Recipients[0]], e.Achievement)
+	}
+	if a := got["alice"]; len(a) != 3 || a[0] != "first_win" || a[1] != "marathon" || a[2] != "tictactoe/flawless" {
+		t.Fatalf("expected alice's first win, marathon and flawless unlocked, got %v", a)
+	}
+	if b := got["bob"]; len(b) != 1 || b[0] != "marathon" {
+		t.Fatalf("expected bob's marathon unlocked, got %v", b)
+	}
+
+	// Nothing is unlocked twice
+	_, finished = playFlawless(t, mgr)
+	mgr.awardAchievements(t.Context(), finished)
+	if len(unlocks) != 0 {
+		t.Fatalf("expected no new unlocks, got %+v", <-unlocks)
+	}
+	unlocked, err := mgr.PlayerAchievements(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(unlocked) != 3 || unlocked[2].Name != "Flawless" || unlocked[2].GameType != "tictactoe" || unlocked[0].SessionCode != sess.Code {
+		t.Fatalf("unexpected achievements %+v", unlocked)
+	}
+	if bob, _ := mgr.PlayerAchievements(t.Context(), "bob"); len(bob) != 1 {
+		t.Fatalf("expected only the marathon for bob, got %+v", bob)
+	}
+}
+
+func TestWinStreakAchievement(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	result := func(outcome game.Outcome) event.Event {
+		return event.Event{
+			Type:        event.MatchFinished,
+			SessionCode: "gone",
+			GameType:    "tictactoe",
+			Players:     []string{"alice"},
+			Results:     []game.PlayerResult{{PlayerID: "alice", Rank: 1, Outcome: outcome}},
+		}
+	}
+	for range streakLength - 1 {
+		mgr.awardAchievements(t.Context(), result(game.OutcomeWin))
+	}
+	mgr.awardAchievements(t.Context(), result(game.OutcomeDraw))
+	mgr.awardAchievements(t.Context(), result(game.OutcomeWin))
+	if got, _ := mgr.PlayerAchievements(t.Context(), "alice"); len(got) != 1 {
+		t.Fatalf("expected a draw to break the streak, got %+v", got)
+	}
+	for range streakLength - 1 {
+		mgr.awardAchievements(t.Context(), result(game.OutcomeWin))
+	}
+	if got, _ := mgr.PlayerAchievements(t.Context(), "alice"); len(got) != 2 || got[1].ID != "win_streak" {
+		t.Fatalf("expected the win streak unlocked, got %+v", got)
+	}
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"games/internal/storage"
+)
+
+// Kick removes a player from a session on a moderator's behalf.
+func (m *Manager) Kick(code, playerID string) error {
+	s, ok := m.Get(code)
+	if !ok {
+		return fmt.Errorf("session not found")
+	}
+	if !s.Kick(playerID) {
+		return fmt.Errorf("player %s not in session", playerID)
+	}
+	if err := m.SaveSessionPlayers(context.Background(), s); err != nil {
+		log.Printf("save players of session %s: %v", code, err)
+	}
+	return nil
+}
+
+// RecordAudit appends an admin action to the audit log. Failures are
+// logged rather than returned so they never mask the action's own result.
+// The action has already happened, so the entry is written even if ctx is
+// cancelled.
+func (m *Manager) RecordAudit(ctx context.Context, entry storage.AuditRow) {
+	ctx = detach(ctx)
+	if err := m.store.AppendAudit(ctx, entry); err != nil {
+		log.Printf("audit %s by %q: %v", entry.Action, entry.Actor, err)
+	}
+}
+
+// AuditLog returns recorded admin actions matching f, newest first.
+func (m *Manager) AuditLog(ctx context.Context, f storage.AuditFilter) ([]storage.AuditRow, error) {
+	return m.store.ListAudit(ctx, f)
+}
+
+// DatabaseSize reports how much space storage takes and how many rows
+// each table holds.
+func (m *Manager) DatabaseSize(ctx context.Context) (storage.SizeRow, error) {
+	return m.store.Size(ctx)
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"games/internal/game"
+)
+
+// Analysis lets a single viewer step backward and forward through a match
+// and play alternative moves on branches. Every position is rebuilt from a
+// clone of the start position, so the recorded match is never touched.
+type Analysis struct {
+	initial  game.Match
+	mainline []Move
+	line     []Move // mainline, or a mainline prefix followed by branch moves
+	ply      int    // number of moves of line applied to current
+	current  game.Match
+	players  []string
+}
+
+// AnalysisView is the analysis position as sent to the client.
+type AnalysisView struct {
+	Ply            int                 `json:"ply"`
+	Length         int                 `json:"length"`
+	MainlineLength int                 `json:"mainlineLength"`
+	BranchPly      int                 `json:"branchPly"` // -1 when on the mainline
+	Line           []Move              `json:"line"`
+	ToMove         string              `json:"toMove,omitempty"`
+	State          any                 `json:"state"`
+	ValidActions   []game.Action       `json:"validActions"`
+	Results        []game.PlayerResult `json:"results,omitempty"`
+}
+
+// NewAnalysis creates an analysis positioned at the end of the mainline.
+func NewAnalysis(initial game.Match, mainline []Move, players []string) (*Analysis, error) {
+	a := &Analysis{
+		initial:  initial,
+		mainline: mainline,
+		line:     mainline,
+		players:  players,
+	}
+	if err := a.Goto(len(mainline)); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Goto moves the cursor to the given ply of the current line.
+func (a *Analysis) Goto(ply int) error {
+	if ply < 0 || ply > len(a.line) {
+		return fmt.Errorf("ply %d out of range 0-%d", ply, len(a.line))
+	}
+	m := a.initial.Clone()
+	for i, mv := range a.line[:ply] {
+		if err := m.ApplyAction(mv.PlayerID, mv.Action); err != nil {
+			return fmt.Errorf("replay move %d: %w", i+1, err)
+		}
+	}
+	a.current = m
+	a.ply = ply
+	return nil
+}
+
+// Step moves the cursor by delta plies along the current line.
+func (a *Analysis) Step(delta int) error {
+	return a.Goto(a.ply + delta)
+}
+
+// Play applies an alternative move at the cursor. If playerID is empty the
+// player to move is used. Playing the move that already follows on the
+// current line just steps forward; anything else starts a new branch.
+func (a *Analysis) Play(playerID string, action game.Action) error {
+	if playerID == "" {
+		playerID = a.toMove()
+	}
+	next := a.current.Clone()
+	if err := next.ApplyAction(playerID, action); err != nil {
+		return err
+	}
+	mv := Move{PlayerID: playerID, Action: action}
+	if a.ply < len(a.line) && sameMove(a.line[a.ply], mv) {
+		a.current = next
+		a.ply++
+		return nil
+	}
+	line := make([]Move, a.ply, a.ply+1)
+	copy(line, a.line[:a.ply])
+	a.line = append(line, mv)
+	a.current = next
+	a.ply++
+	return nil
+}
+
+// Mainline abandons any branch and returns to the recorded line, keeping
+// the cursor at the point where the branch left it.
+func (a *Analysis) Mainline() error {
+	ply := a.ply
+	if bp := a.branchPly(); bp >= 0 && ply > bp {
+		ply = bp
+	}
+	a.line = a.mainline
+	return a.Goto(ply)
+}
+
+// View returns the current position as seen by viewer. An empty viewer
+// sees the position from the perspective of the player to move.
+func (a *Analysis) View(viewer string) AnalysisView {
+	toMove := a.toMove()
+	if viewer == "" {
+		viewer = toMove
+	}
+	v := AnalysisView{
+		Ply:            a.ply,
+		Length:         len(a.line),
+		MainlineLength: len(a.mainline),
+		BranchPly:      a.branchPly(),
+		Line:           a.line,
+		ToMove:         toMove,
+		State:          a.current.State(viewer),
+		ValidActions:   a.current.ValidActions(toMove),
+	}
+	if a.current.IsOver() {
+		v.Results = game.Results(a.current)
+	}
+	return v
+}
+
+// toMove returns the first player with any valid action, or "".
+func (a *Analysis) toMove() string {
+	for _, id := range a.players {
+		if len(a.current.ValidActions(id)) > 0 {
+			return id
+		}
+	}
+	return ""
+}
+
+// branchPly returns the index of the first move where the current line
+// diverges from the mainline, or -1 if it does not diverge.
+func (a *Analysis) branchPly() int {
+	for i, mv := range a.line {
+		if i >= len(a.mainline) || !sameMove(mv, a.mainline[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameMove(a, b Move) bool {
+	return a.PlayerID == b.PlayerID &&
+		a.Action.Type == b.Action.Type &&
+		bytes.Equal(compactJSON(a.Action.Payload), compactJSON(b.Action.Payload))
+}
+
+// compactJSON strips insignificant whitespace so payloads sent by different
+// clients compare equal.
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
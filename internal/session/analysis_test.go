@@ -0,0 +1,169 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+)
+
+func cellAction(cell int) game.Action {
+	payload, _ := json.Marshal(map[string]int{"cell": cell})
+	return game.Action{Type: "move", Payload: payload}
+}
+
+// playCells starts a two-player session and plays the given cells,
+// alternating players and recording history like the server does.
+func playCells(t *testing.T, mgr *Manager, cells ...int) *Session {
+	t.Helper()
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	players := sess.Match.(*tictactoe.Match).Players
+	for i, cell := range cells {
+		pid := players[i%2]
+		if err := sess.Match.ApplyAction(pid, cellAction(cell)); err != nil {
+			t.Fatalf("move %d: %v", i, err)
+		}
+		sess.History = append(sess.History, Move{PlayerID: pid, Action: cellAction(cell)})
+	}
+	if sess.Match.IsOver() {
+		sess.Status = StatusFinished
+	}
+	return sess
+}
+
+func board(t *testing.T, v AnalysisView) [9]int {
+	t.Helper()
+	data, _ := json.Marshal(v.State)
+	var s struct {
+		Board [9]int `json:"board"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("unmarshal state: %v", err)
+	}
+	return s.Board
+}
+
+func TestAnalyzeRequiresFinished(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess := playCells(t, mgr, 0, 3)
+	if _, err := sess.Analyze(); err == nil {
+		t.Fatal("expected error analyzing unfinished match")
+	}
+}
+
+func TestAnalysisStepping(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess := playCells(t, mgr, 0, 3, 1, 4, 2)
+	a, err := sess.Analyze()
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+
+	v := a.View("")
+	if v.Ply != 5 || v.Length != 5 || v.Results == nil {
+		t.Fatalf("expected final position with results, got ply=%d len=%d", v.Ply, v.Length)
+	}
+
+	if err := a.Goto(1); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	if b := board(t, a.View("")); b[0] == 0 || b[3] != 0 {
+		t.Fatalf("unexpected board at ply 1: %v", b)
+	}
+	if err := a.Step(1); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if b := board(t, a.View("")); b[3] == 0 {
+		t.Fatalf("expected cell 3 occupied at ply 2: %v", b)
+	}
+	if err := a.Step(10); err == nil {
+		t.Fatal("expected error stepping past the end")
+	}
+	if err := a.Goto(-1); err == nil {
+		t.Fatal("expected error for negative ply")
+	}
+}
+
+func TestAnalysisBranchDoesNotTouchRecord(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess := playCells(t, mgr, 0, 3, 1, 4, 2)
+	a, _ := sess.Analyze()
+	a.Goto(2)
+
+	// Replaying the recorded move stays on the mainline
+	if err := a.Play("", cellAction(1)); err != nil {
+		t.Fatalf("play mainline move: %v", err)
+	}
+	if v := a.View(""); v.BranchPly != -1 || v.Length != 5 {
+		t.Fatalf("expected to stay on mainline, got branchPly=%d len=%d", v.BranchPly, v.Length)
+	}
+
+	// A different move opens a branch
+	if err := a.Play("", cellAction(8)); err != nil {
+		t.Fatalf("play branch move: %v", err)
+	}
+	v := a.View("")
+	if v.BranchPly != 3 || v.Length != 4 || v.Ply != 4 {
+		t.Fatalf("expected branch at 3 with length 4, got branchPly=%d len=%d ply=%d", v.BranchPly, v.Length, v.Ply)
+	}
+	if err := a.Play("", cellAction(8)); err == nil {
+		t.Fatal("expected error playing an occupied cell")
+	}
+
+	if len(sess.History) != 5 || !sess.Match.IsOver() {
+		t.Fatal("branch modified the recorded match")
+	}
+
+	if err := a.Mainline(); err != nil {
+		t.Fatalf("mainline: %v", err)
+	}
+	v = a.View("")
+	if v.BranchPly != -1 || v.Length != 5 || v.Ply != 3 {
+		t.Fatalf("expected mainline at ply 3, got branchPly=%d len=%d ply=%d", v.BranchPly, v.Length, v.Ply)
+	}
+}
+
+func TestHistoryRestored(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess := playCells(t, mgr, 4, 0)
+	if err := mgr.SaveMatchState(t.Context(), sess); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := mgr.SaveInitialState(t.Context(), sess); err != nil {
+		t.Fatalf("save initial: %v", err)
+	}
+	for i, mv := range sess.History {
+		if err := mgr.AppendMove(t.Context(), sess, i+1, mv); err != nil {
+			t.Fatalf("append move: %v", err)
+		}
+	}
+
+	mgr2 := NewManager(mgr.registry, mgr.store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	sess2, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("session not restored")
+	}
+	if len(sess2.History) != 2 {
+		t.Fatalf("expected 2 moves restored, got %d", len(sess2.History))
+	}
+	if sess2.initial == nil {
+		t.Fatal("expected initial state restored")
+	}
+}
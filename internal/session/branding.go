@@ -0,0 +1,71 @@
+package session
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxBrandNameLen caps the length of a brand's display name, in
+// characters.
+const MaxBrandNameLen = 64
+
+var brandColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Branding is how a site, a tenant or a club presents itself in the web
+// frontend. Fields left empty keep the look of whatever it is shown
+// over: the site's, or the frontend's own.
+type Branding struct {
+	Name    string `json:"name,omitempty"`
+	LogoURL string `json:"logoUrl,omitempty"` // https URL, or a path on the site
+	// AccentColor marks headings and buttons; SecondaryColor borders and
+	// secondary buttons. Both are #rrggbb.
+	AccentColor    string `json:"accentColor,omitempty"`
+	SecondaryColor string `json:"secondaryColor,omitempty"`
+}
+
+// Validate checks what the pages will be given: a short name, a logo
+// they can load, and colors they can set without escaping into the rest
+// of the stylesheet.
+func (b Branding) Validate() error {
+	if utf8.RuneCountInString(b.Name) > MaxBrandNameLen {
+		return fmt.Errorf("name must be at most %d characters", MaxBrandNameLen)
+	}
+	if b.LogoURL != "" {
+		u, err := url.Parse(b.LogoURL)
+		switch {
+		case err != nil:
+			return fmt.Errorf("logoUrl: %v", err)
+		case u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/"):
+		case u.Scheme == "https" && u.Host != "":
+		default:
+			return fmt.Errorf("logoUrl must be an https URL or a path starting with /")
+		}
+	}
+	for _, c := range [][2]string{{"accentColor", b.AccentColor}, {"secondaryColor", b.SecondaryColor}} {
+		if c[1] != "" && !brandColor.MatchString(c[1]) {
+			return fmt.Errorf("%s must be a color like #e94560", c[0])
+		}
+	}
+	return nil
+}
+
+// Over returns the branding with the fields it leaves empty taken from
+// base.
+func (b Branding) Over(base Branding) Branding {
+	if b.Name == "" {
+		b.Name = base.Name
+	}
+	if b.LogoURL == "" {
+		b.LogoURL = base.LogoURL
+	}
+	if b.AccentColor == "" {
+		b.AccentColor = base.AccentColor
+	}
+	if b.SecondaryColor == "" {
+		b.SecondaryColor = base.SecondaryColor
+	}
+	return b
+}
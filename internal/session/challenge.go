@@ -0,0 +1,168 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"games/internal/event"
+	"games/internal/storage"
+)
+
+// ChallengeTTL is how long a challenge waits for an answer.
+const ChallengeTTL = 10 * time.Minute
+
+// ErrChallengeNotFound is returned for unknown challenge IDs.
+var ErrChallengeNotFound = errors.New("challenge not found")
+
+// IssueChallenge records a challenge from challenger to target and notifies
+// the target on the event bus.
+func (m *Manager) IssueChallenge(ctx context.Context, gameType, challengerID, targetID string) (*storage.ChallengeRow, error) {
+	g, err := m.playable(gameType)
+	if err != nil {
+		return nil, err
+	}
+	if gi := g.Info(); gi.MinPlayers > 2 || gi.MaxPlayers < 2 {
+		return nil, fmt.Errorf("%s is not a two-player game", gameType)
+	}
+	challengerID = strings.TrimSpace(challengerID)
+	targetID = strings.TrimSpace(targetID)
+	if challengerID == "" || targetID == "" {
+		return nil, fmt.Errorf("challenger and target required")
+	}
+	if challengerID == targetID {
+		return nil, fmt.Errorf("cannot challenge yourself")
+	}
+
+	c := &storage.ChallengeRow{
+		ID:           generateChallengeID(),
+		GameType:     gameType,
+		ChallengerID: challengerID,
+		TargetID:     targetID,
+		Status:       "pending",
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().Add(ChallengeTTL),
+	}
+	if err := m.store.CreateChallenge(ctx, *c); err != nil {
+		return nil, fmt.Errorf("persist challenge: %w", err)
+	}
+	m.publishChallenge(event.ChallengeIssued, c, targetID)
+	return c, nil
+}
+
+// Challenges returns the pending challenges sent or received by playerID.
+func (m *Manager) Challenges(ctx context.Context, playerID string) ([]storage.ChallengeRow, error) {
+	return m.store.ListPendingChallenges(ctx, playerID, time.Now())
+}
+
+// AcceptChallenge lets the challenged player accept. It creates a session
+// with both players seated and starts the match.
+func (m *Manager) AcceptChallenge(ctx context.Context, id, playerID string) (*Session, error) {
+	c, err := m.pendingChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.TargetID != playerID {
+		return nil, fmt.Errorf("only %s can accept this challenge", c.TargetID)
+	}
+
+	s, err := m.Create(ctx, c.GameType)
+	if err != nil {
+		return nil, err
+	}
+	for _, pid := range []string{c.ChallengerID, c.TargetID} {
+		if err := s.AddPlayer(pid); err != nil {
+			m.Remove(ctx, s.Code)
+			return nil, err
+		}
+	}
+	ok, err := m.store.ResolveChallenge(ctx, c.ID, "accepted", s.Code)
+	if err != nil || !ok {
+		m.Remove(ctx, s.Code)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("challenge is no longer pending")
+	}
+	if err := m.startMatch(ctx, s); err != nil {
+		return nil, err
+	}
+	c.Status, c.SessionCode = "accepted", s.Code
+	m.publishChallenge(event.ChallengeAccepted, c, c.ChallengerID, c.TargetID)
+	return s, nil
+}
+
+// DeclineChallenge lets the target decline, or the challenger withdraw, a
+// pending challenge.
+func (m *Manager) DeclineChallenge(ctx context.Context, id, playerID string) error {
+	c, err := m.pendingChallenge(ctx, id)
+	if err != nil {
+		return err
+	}
+	if playerID != c.TargetID && playerID != c.ChallengerID {
+		return fmt.Errorf("not your challenge")
+	}
+	ok, err := m.store.ResolveChallenge(ctx, c.ID, "declined", "")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("challenge is no longer pending")
+	}
+	c.Status = "declined"
+	m.publishChallenge(event.ChallengeDeclined, c, c.ChallengerID, c.TargetID)
+	return nil
+}
+
+// pendingChallenge loads a challenge that can still be answered.
+func (m *Manager) pendingChallenge(ctx context.Context, id string) (*storage.ChallengeRow, error) {
+	c, err := m.store.GetChallenge(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChallengeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Status == "pending" && !time.Now().Before(c.ExpiresAt) {
+		c.Status = "expired"
+	}
+	if c.Status != "pending" {
+		return nil, fmt.Errorf("challenge is %s", c.Status)
+	}
+	return c, nil
+}
+
+func (m *Manager) publishChallenge(typ string, c *storage.ChallengeRow, recipients ...string) {
+	m.events.Publish(event.Event{
+		Type:        typ,
+		SessionCode: c.SessionCode,
+		GameType:    c.GameType,
+		Players:     []string{c.ChallengerID, c.TargetID},
+		ChallengeID: c.ID,
+		Recipients:  recipients,
+	})
+}
+
+// expireChallenges marks unanswered challenges as expired.
+func (m *Manager) expireChallenges(ctx context.Context) {
+	n, err := m.store.ExpireChallenges(ctx, time.Now())
+	if err != nil {
+		log.Printf("expire challenges: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("expired %d challenges", n)
+	}
+}
+
+func generateChallengeID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "ch-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,74 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"games/internal/event"
+)
+
+func TestChallengeAccept(t *testing.T) {
+	mgr := setupBotTest(t)
+	events, unsubscribe := mgr.Events().Subscribe(10)
+	defer unsubscribe()
+
+	if _, err := mgr.IssueChallenge(t.Context(), "tictactoe", "alice", "alice"); err == nil {
+		t.Fatal("expected error challenging yourself")
+	}
+	c, err := mgr.IssueChallenge(t.Context(), "tictactoe", "alice", "bob")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	e := <-events
+	if e.Type != event.ChallengeIssued || e.ChallengeID != c.ID || len(e.Recipients) != 1 || e.Recipients[0] != "bob" {
+		t.Fatalf("unexpected issue event: %+v", e)
+	}
+
+	pending, err := mgr.Challenges(t.Context(), "bob")
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("expected one pending challenge for bob, got %v (err %v)", pending, err)
+	}
+
+	if _, err := mgr.AcceptChallenge(t.Context(), c.ID, "alice"); err == nil {
+		t.Fatal("expected challenger to be unable to accept")
+	}
+	sess, err := mgr.AcceptChallenge(t.Context(), c.ID, "bob")
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	info := sess.Info()
+	if info.Status != StatusPlaying || len(info.Players) != 2 {
+		t.Fatalf("expected started two-player session, got %+v", info)
+	}
+	e = <-events
+	if e.Type != event.ChallengeAccepted || e.SessionCode != sess.Code {
+		t.Fatalf("unexpected accept event: %+v", e)
+	}
+
+	if _, err := mgr.AcceptChallenge(t.Context(), c.ID, "bob"); err == nil {
+		t.Fatal("expected second accept to fail")
+	}
+	if pending, _ := mgr.Challenges(t.Context(), "bob"); len(pending) != 0 {
+		t.Fatalf("expected no pending challenges, got %v", pending)
+	}
+}
+
+func TestChallengeDecline(t *testing.T) {
+	mgr := setupBotTest(t)
+	c, err := mgr.IssueChallenge(t.Context(), "tictactoe", "alice", "bob")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if err := mgr.DeclineChallenge(t.Context(), c.ID, "carol"); err == nil {
+		t.Fatal("expected outsider decline to fail")
+	}
+	if err := mgr.DeclineChallenge(t.Context(), c.ID, "bob"); err != nil {
+		t.Fatalf("decline: %v", err)
+	}
+	if _, err := mgr.AcceptChallenge(t.Context(), c.ID, "bob"); err == nil {
+		t.Fatal("expected accept after decline to fail")
+	}
+	if err := mgr.DeclineChallenge(t.Context(), "ch-missing", "bob"); !errors.Is(err, ErrChallengeNotFound) {
+		t.Fatalf("expected ErrChallengeNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"games/internal/metrics"
+)
+
+// DefaultCleanupMaxAge is how long a finished session may sit idle before
+// cleanup removes it, unless a CleanupPolicy says otherwise.
+const DefaultCleanupMaxAge = time.Hour
+
+// CleanupPolicy decides which sessions cleanup removes.
+type CleanupPolicy struct {
+	// MaxAge is how long a finished session may sit idle before removal.
+	MaxAge time.Duration
+	// DryRun logs and counts the sessions cleanup would remove, but
+	// removes none, for tuning MaxAge.
+	DryRun bool
+}
+
+// Why cleanup removed or kept a session.
+const (
+	CleanupEmpty         = "empty"           // removed: nobody joined, or everyone left
+	CleanupIdle          = "idle"            // removed: finished and idle past MaxAge
+	CleanupRecent        = "recent"          // kept: finished, but not idle long enough
+	CleanupPartyContinue = "party_continues" // kept: a party round ended, with more to play
+	CleanupActive        = "active"          // kept: waiting or playing, with players
+	CleanupPinned        = "pinned"          // kept: an admin pinned it
+	CleanupExpired       = "expired"         // removed: idle past its own expiry
+	CleanupUnexpired     = "unexpired"       // kept: not yet idle past its own expiry
+)
+
+// What cleanup did with a session.
+const (
+	CleanupRemoved     = "removed"
+	CleanupWouldRemove = "would_remove" // in a dry run
+	CleanupKept        = "kept"
+)
+
+// CleanupDecision is what a cleanup pass did with one session, and why.
+type CleanupDecision struct {
+	Code     string `json:"code"`
+	GameType string `json:"gameType"`
+	Status   Status `json:"status"`
+	Action   string `json:"action"`
+	Reason   string `json:"reason"`
+	// Idle is how long since anything happened in the session, such as
+	// "1h5m0s".
+	Idle string `json:"idle"`
+}
+
+// CleanupReport is what one cleanup pass decided.
+type CleanupReport struct {
+	DryRun    bool              `json:"dryRun"`
+	MaxAge    string            `json:"maxAge"`
+	Removed   int               `json:"removed"` // or would have been, in a dry run
+	Kept      int               `json:"kept"`
+	Decisions []CleanupDecision `json:"decisions"`
+}
+
+// SetCleanupPolicy changes how later cleanup passes decide.
+func (m *Manager) SetCleanupPolicy(p CleanupPolicy) {
+	m.cleanupMu.Lock()
+	defer m.cleanupMu.Unlock()
+	m.cleanupPolicy = p
+}
+
+// CleanupMetrics returns the count of cleanup decisions, labeled by
+// action and reason.
+func (m *Manager) CleanupMetrics() *metrics.Counter {
+	return m.cleanups
+}
+
+// SetPinned pins a session, keeping it from cleanup however long it sits
+// idle or empty, or unpins it, and persists it. A pinned session also
+// outlives a restart once finished.
+func (m *Manager) SetPinned(ctx context.Context, s *Session, pinned bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := m.store.SetSessionPinned(ctx, s.Code, pinned); err != nil {
+		return fmt.Errorf("persist pinned: %w", err)
+	}
+	s.Pinned = pinned
+	return nil
+}
+
+// SetExpireAfter gives a session its own expiry, for sessions such as
+// correspondence or scheduled games that sit idle longer than most: cleanup
+// keeps it, even empty, until it has been idle for after, then removes it
+// whatever its status. Zero leaves it to the cleanup policy. Expiries are
+// kept to the second.
+func (m *Manager) SetExpireAfter(ctx context.Context, s *Session, after time.Duration) error {
+	if after < 0 {
+		return fmt.Errorf("expiry must not be negative")
+	}
+	after = after.Truncate(time.Second)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := m.store.SetSessionExpireAfter(ctx, s.Code, after); err != nil {
+		return fmt.Errorf("persist expiry: %w", err)
+	}
+	s.ExpireAfter = after
+	return nil
+}
+
+// expireAfter formats a session's own expiry for Info, or "" for none.
+func expireAfter(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// CleanupLoop periodically abandons matches whose players have all been
+// away for abandonAfter, cleans up sessions by the cleanup policy and
+// expires unanswered challenges, until ctx is done.
+func (m *Manager) CleanupLoop(ctx context.Context, interval, abandonAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.abandonIdle(ctx, abandonAfter)
+		m.Cleanup(ctx, false)
+		m.expireChallenges(ctx)
+	}
+}
+
+// Cleanup runs a cleanup pass now by the cleanup policy, reporting what
+// it decided about every loaded session. With dryRun, or a policy that
+// says so, it removes nothing.
+func (m *Manager) Cleanup(ctx context.Context, dryRun bool) CleanupReport {
+	m.cleanupMu.Lock()
+	p := m.cleanupPolicy
+	m.cleanupMu.Unlock()
+	return m.cleanupPass(ctx, p.MaxAge, dryRun || p.DryRun)
+}
+
+// cleanupPass removes sessions nobody is in and finished ones idle for
+// longer than maxAge, or than their own expiry, but never pinned ones. It
+// logs each it removes and counts every decision.
+func (m *Manager) cleanupPass(ctx context.Context, maxAge time.Duration, dryRun bool) CleanupReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	report := CleanupReport{DryRun: dryRun, MaxAge: maxAge.String(), Decisions: []CleanupDecision{}}
+	now := time.Now()
+	for code, s := range m.sessions {
+		s.mu.RLock()
+		reason := cleanupReasonLocked(s, now, maxAge)
+		idle := now.Sub(s.LastActivity).Round(time.Second)
+		d := CleanupDecision{Code: code, GameType: s.GameType, Status: s.Status, Action: CleanupKept, Reason: reason, Idle: idle.String()}
+		s.mu.RUnlock()
+
+		if reason == CleanupEmpty || reason == CleanupIdle || reason == CleanupExpired {
+			d.Action = CleanupRemoved
+			if dryRun {
+				d.Action = CleanupWouldRemove
+			}
+			log.Printf("cleanup session=%s game=%s status=%s action=%s reason=%s idle=%s max_age=%s",
+				code, d.GameType, d.Status, d.Action, reason, d.Idle, report.MaxAge)
+			if !dryRun {
+				m.store.DeleteSession(ctx, code)
+				delete(m.sessions, code)
+				m.forgetSurveys(code)
+			}
+			report.Removed++
+		} else {
+			report.Kept++
+		}
+		m.cleanups.Inc(d.Action, reason)
+		report.Decisions = append(report.Decisions, d)
+	}
+	return report
+}
+
+// cleanupReasonLocked decides whether cleanup removes a session, and why.
+// The caller must hold the session's lock.
+func cleanupReasonLocked(s *Session, now time.Time, maxAge time.Duration) string {
+	switch {
+	case s.Pinned:
+		return CleanupPinned
+	case s.ExpireAfter > 0 && now.Sub(s.LastActivity) > s.ExpireAfter:
+		return CleanupExpired
+	case s.ExpireAfter > 0:
+		return CleanupUnexpired
+	case len(s.Players) == 0:
+		return CleanupEmpty
+	case s.Status == StatusFinished && !s.Abandoned && s.Party.HasNext():
+		return CleanupPartyContinue
+	case s.Status != StatusFinished && s.Status != StatusErrored:
+		return CleanupActive
+	case now.Sub(s.LastActivity) > maxAge:
+		return CleanupIdle
+	default:
+		return CleanupRecent
+	}
+}
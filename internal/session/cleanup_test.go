@@ -0,0 +1,156 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCleanupDecisions(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	empty, _ := mgr.Create(t.Context(), "tictactoe")
+	active, _ := mgr.Create(t.Context(), "tictactoe")
+	active.AddPlayer("alice")
+	finished := func(idle time.Duration) *Session {
+		s, _ := mgr.Create(t.Context(), "tictactoe")
+		s.AddPlayer("alice")
+		s.AddPlayer("bob")
+		s.Start()
+		s.Finish()
+		s.Lock()
+		s.LastActivity = time.Now().Add(-idle)
+		s.Unlock()
+		return s
+	}
+	recent, idle := finished(time.Minute), finished(2*time.Hour)
+	want := map[string][2]string{
+		empty.Code:  {CleanupWouldRemove, CleanupEmpty},
+		active.Code: {CleanupKept, CleanupActive},
+		recent.Code: {CleanupKept, CleanupRecent},
+		idle.Code:   {CleanupWouldRemove, CleanupIdle},
+	}
+
+	check := func(report CleanupReport) {
+		t.Helper()
+		if report.Removed != 2 || report.Kept != 2 || len(report.Decisions) != 4 {
+			t.Fatalf("expected two of four sessions removed, got %+v", report)
+		}
+		for _, d := range report.Decisions {
+			if w := want[d.Code]; d.Action != w[0] || d.Reason != w[1] {
+				t.Errorf("session %s: expected %v, got %s for %s", d.Code, w, d.Action, d.Reason)
+			}
+		}
+	}
+	check(mgr.Cleanup(t.Context(), true))
+	mgr.SetCleanupPolicy(CleanupPolicy{MaxAge: time.Hour, DryRun: true})
+	check(mgr.Cleanup(t.Context(), false))
+	if len(mgr.List()) != 4 {
+		t.Fatal("expected a dry run to remove nothing")
+	}
+	if n := mgr.CleanupMetrics().Count(CleanupWouldRemove, CleanupIdle); n != 2 {
+		t.Errorf("expected two dry-run decisions counted, got %d", n)
+	}
+
+	mgr.SetCleanupPolicy(CleanupPolicy{MaxAge: time.Hour})
+	want[empty.Code] = [2]string{CleanupRemoved, CleanupEmpty}
+	want[idle.Code] = [2]string{CleanupRemoved, CleanupIdle}
+	check(mgr.Cleanup(t.Context(), false))
+	if _, ok := mgr.Get(idle.Code); ok {
+		t.Fatal("expected the idle session removed")
+	}
+	if _, ok := mgr.Get(recent.Code); !ok {
+		t.Fatal("expected the recent session kept")
+	}
+}
+
+func TestCleanupPinnedAndExpiry(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	pinned, _ := mgr.Create(t.Context(), "tictactoe")
+	if err := mgr.SetPinned(t.Context(), pinned, true); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	idle := func(after time.Duration) *Session {
+		s, _ := mgr.Create(t.Context(), "tictactoe")
+		s.AddPlayer("alice")
+		if err := mgr.SetExpireAfter(t.Context(), s, 24*time.Hour); err != nil {
+			t.Fatalf("expiry: %v", err)
+		}
+		s.Lock()
+		s.LastActivity = time.Now().Add(-after)
+		s.Unlock()
+		return s
+	}
+	waiting, expired := idle(2*time.Hour), idle(25*time.Hour)
+	if err := mgr.SetExpireAfter(t.Context(), waiting, -time.Hour); err == nil {
+		t.Error("expected a negative expiry refused")
+	}
+
+	want := map[string][2]string{
+		pinned.Code:  {CleanupKept, CleanupPinned},
+		waiting.Code: {CleanupKept, CleanupUnexpired},
+		expired.Code: {CleanupRemoved, CleanupExpired},
+	}
+	report := mgr.Cleanup(t.Context(), false)
+	for _, d := range report.Decisions {
+		if w := want[d.Code]; d.Action != w[0] || d.Reason != w[1] {
+			t.Errorf("session %s: expected %v, got %s for %s", d.Code, w, d.Action, d.Reason)
+		}
+	}
+	if _, ok := mgr.Get(expired.Code); ok {
+		t.Fatal("expected the session past its own expiry removed")
+	}
+	if info := waiting.Info(); info.ExpireAfter != "24h0m0s" {
+		t.Errorf("expected the expiry in session info, got %q", info.ExpireAfter)
+	}
+
+	// A pinned session outlives a restart, even finished
+	pinned.AddPlayer("alice")
+	pinned.AddPlayer("bob")
+	pinned.Start()
+	mgr.SaveMatchStart(t.Context(), pinned)
+	pinned.Finish()
+	mgr.SaveMatchState(t.Context(), pinned)
+	restarted := NewManager(mgr.registry, mgr.store)
+	if err := restarted.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if s, ok := restarted.Get(pinned.Code); !ok || !s.Info().Pinned {
+		t.Fatal("expected the finished pinned session restored")
+	}
+}
+
+// TestTouchSurvivesRestart checks a restored session is idle from its last
+// activity, not from when it was created.
+func TestTouchSurvivesRestart(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	s, _ := mgr.Create(t.Context(), "tictactoe")
+	s.AddPlayer("alice")
+	if row, _ := mgr.store.GetSession(t.Context(), s.Code); !row.LastActivity.IsZero() {
+		t.Fatalf("expected no activity stored yet, got %v", row.LastActivity)
+	}
+	if err := mgr.Touch(t.Context(), s); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	row, _ := mgr.store.GetSession(t.Context(), s.Code)
+	if time.Since(row.LastActivity) > time.Minute {
+		t.Fatalf("expected the activity stored, got %v", row.LastActivity)
+	}
+
+	restarted := NewManager(mgr.registry, mgr.store)
+	restarted.Restore(t.Context())
+	restored, ok := restarted.Get(s.Code)
+	if !ok {
+		t.Fatal("expected the session restored")
+	}
+	restored.RLock()
+	active := restored.LastActivity
+	restored.RUnlock()
+	if !active.Equal(row.LastActivity) {
+		t.Errorf("expected the stored activity %v restored, got %v", row.LastActivity, active)
+	}
+}
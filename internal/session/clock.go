@@ -0,0 +1,38 @@
+package session
+
+import (
+	"time"
+
+	"games/internal/game"
+)
+
+// FlagFallLocked ends a playing match on time if the player to move ran
+// out before now, reporting whether it did. A game that forgives lag
+// gets its window first, since a move made in time may still be on its
+// way. The caller must hold the write lock.
+func (s *Session) FlagFallLocked(now time.Time) bool {
+	cm, ok := s.Match.(game.ClockedMatch)
+	if !ok || s.Status != StatusPlaying {
+		return false
+	}
+	if lc, ok := s.game.(game.LagCompensator); ok {
+		now = now.Add(-lc.LagWindow())
+	}
+	return cm.FlagFall(now)
+}
+
+// Clocked returns the playing sessions whose match is played on a clock,
+// for the server to watch for flag falls.
+func (m *Manager) Clocked() []*Session {
+	var clocked []*Session
+	for _, s := range m.loaded() {
+		s.mu.RLock()
+		_, ok := s.Match.(game.ClockedMatch)
+		playing := s.Status == StatusPlaying
+		s.mu.RUnlock()
+		if ok && playing {
+			clocked = append(clocked, s)
+		}
+	}
+	return clocked
+}
@@ -0,0 +1,419 @@
+package session
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"games/internal/storage"
+)
+
+// Club roles. The owner runs the club and appoints its admins; admins
+// organize its tournaments and remove members; members enter its
+// tournaments and see its lobby and leaderboard.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var (
+	// ErrClubNotFound is returned for unknown club IDs.
+	ErrClubNotFound = errors.New("club not found")
+	// ErrNotPermitted is returned when a player's place in a club does
+	// not allow what they asked.
+	ErrNotPermitted = errors.New("not permitted")
+)
+
+// Club is a club as its members and the club directory see it.
+type Club struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Owner       string `json:"owner"`
+	MemberCount int    `json:"memberCount"`
+	// Members are listed in the order they joined; omitted from the club
+	// directory.
+	Members []ClubMember `json:"members,omitempty"`
+	// Role is the player's own role, in the list of their clubs.
+	Role string `json:"role,omitempty"`
+	// Branding is how the club's pages present it; omitted when it keeps
+	// the site's look.
+	Branding  *Branding `json:"branding,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ClubMember is a player's membership of a club.
+type ClubMember struct {
+	PlayerID string    `json:"playerId"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// ClubStanding is a member's place on a club's leaderboard, over the
+// games of the club's tournaments.
+type ClubStanding struct {
+	Rank        int     `json:"rank"`
+	PlayerID    string  `json:"playerId"`
+	Role        string  `json:"role"`
+	Tournaments int     `json:"tournaments"` // how many they entered
+	Score       float64 `json:"score"`       // a point a win or bye, half a draw
+	Played      int     `json:"played"`
+	Wins        int     `json:"wins"`
+	Draws       int     `json:"draws"`
+	Losses      int     `json:"losses"`
+	Byes        int     `json:"byes"`
+}
+
+// ClubGame is a game of a club tournament in play.
+type ClubGame struct {
+	Tournament     string `json:"tournament"`
+	TournamentName string `json:"tournamentName"`
+	Pairing
+}
+
+// ClubLobby is what a club's members see of it: the club, its
+// tournaments and the games of them in play.
+type ClubLobby struct {
+	Club        *Club        `json:"club"`
+	Tournaments []Tournament `json:"tournaments"`
+	Games       []ClubGame   `json:"games"`
+}
+
+// CreateClub founds a club with its owner as its first member.
+func (m *Manager) CreateClub(ctx context.Context, name, owner string) (*Club, error) {
+	name, owner = strings.TrimSpace(name), strings.TrimSpace(owner)
+	if name == "" || owner == "" {
+		return nil, fmt.Errorf("name and owner required")
+	}
+	if strings.HasPrefix(owner, ExternalBotPrefix) {
+		return nil, fmt.Errorf("player IDs starting with %s are reserved for bots", ExternalBotPrefix)
+	}
+	c := storage.ClubRow{ID: generateClubID(), Name: name, Owner: owner}
+	if err := m.store.CreateClub(ctx, c); err != nil {
+		return nil, fmt.Errorf("persist club: %w", err)
+	}
+	return m.Club(ctx, c.ID)
+}
+
+// Club returns a club with its members.
+func (m *Manager) Club(ctx context.Context, id string) (*Club, error) {
+	row, err := m.store.GetClub(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClubNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	members, err := m.store.ListClubMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c := clubInfo(row)
+	c.Members = make([]ClubMember, len(members))
+	for i, r := range members {
+		c.Members[i] = ClubMember{PlayerID: r.PlayerID, Role: r.Role, JoinedAt: r.JoinedAt}
+	}
+	return c, nil
+}
+
+// Clubs returns every club, by name, without their members.
+func (m *Manager) Clubs(ctx context.Context) ([]Club, error) {
+	rows, err := m.store.ListClubs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Club, len(rows))
+	for i := range rows {
+		result[i] = *clubInfo(&rows[i])
+	}
+	return result, nil
+}
+
+// PlayerClubs returns the clubs a player belongs to, in the order they
+// joined, each with the player's role.
+func (m *Manager) PlayerClubs(ctx context.Context, playerID string) ([]Club, error) {
+	memberships, err := m.store.ListPlayerClubs(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Club, 0, len(memberships))
+	for _, ms := range memberships {
+		row, err := m.store.GetClub(ctx, ms.ClubID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c := clubInfo(row)
+		c.Role = ms.Role
+		result = append(result, *c)
+	}
+	return result, nil
+}
+
+// JoinClub makes a player a member of a club.
+func (m *Manager) JoinClub(ctx context.Context, id, playerID string) (*Club, error) {
+	playerID = strings.TrimSpace(playerID)
+	if playerID == "" {
+		return nil, fmt.Errorf("playerId required")
+	}
+	if strings.HasPrefix(playerID, ExternalBotPrefix) {
+		return nil, fmt.Errorf("player IDs starting with %s are reserved for bots", ExternalBotPrefix)
+	}
+	m.clubMu.Lock()
+	defer m.clubMu.Unlock()
+	if _, err := m.clubRole(ctx, id, playerID); err != nil {
+		return nil, err
+	}
+	added, err := m.store.AddClubMember(ctx, storage.ClubMemberRow{ClubID: id, PlayerID: playerID, Role: RoleMember})
+	if err != nil {
+		return nil, fmt.Errorf("persist member: %w", err)
+	}
+	if !added {
+		return nil, fmt.Errorf("%s is already a member", playerID)
+	}
+	return m.Club(ctx, id)
+}
+
+// SetClubRole lets a club's owner make a member an admin, or an admin a
+// member again. The owner's own role cannot change.
+func (m *Manager) SetClubRole(ctx context.Context, id, actor, playerID, role string) (*Club, error) {
+	if role != RoleAdmin && role != RoleMember {
+		return nil, fmt.Errorf("role must be %s or %s", RoleAdmin, RoleMember)
+	}
+	m.clubMu.Lock()
+	defer m.clubMu.Unlock()
+	actorRole, err := m.clubRole(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if actorRole != RoleOwner {
+		return nil, fmt.Errorf("%w: only the club's owner may change roles", ErrNotPermitted)
+	}
+	current, err := m.clubMember(ctx, id, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Role == RoleOwner {
+		return nil, fmt.Errorf("the owner's role cannot change")
+	}
+	if _, err := m.store.SetClubRole(ctx, id, playerID, role); err != nil {
+		return nil, fmt.Errorf("persist role: %w", err)
+	}
+	return m.Club(ctx, id)
+}
+
+// SetClubBranding lets a club's owner or an admin change how its pages
+// present it. An empty Branding goes back to the site's look.
+func (m *Manager) SetClubBranding(ctx context.Context, id, actor string, b Branding) (*Club, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	role, err := m.clubRole(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if role != RoleOwner && role != RoleAdmin {
+		return nil, fmt.Errorf("%w: only the club's owner and admins may change its branding", ErrNotPermitted)
+	}
+	data := ""
+	if b != (Branding{}) {
+		encoded, _ := json.Marshal(b)
+		data = string(encoded)
+	}
+	if err := m.store.SetClubBranding(ctx, id, data); err != nil {
+		return nil, fmt.Errorf("persist branding: %w", err)
+	}
+	return m.Club(ctx, id)
+}
+
+// RemoveClubMember takes a player out of a club: a member leaving of
+// their own accord, or removed by the owner or an admin. Admins may
+// remove members but not other admins, and the owner cannot leave.
+func (m *Manager) RemoveClubMember(ctx context.Context, id, actor, playerID string) (*Club, error) {
+	m.clubMu.Lock()
+	defer m.clubMu.Unlock()
+	target, err := m.clubMember(ctx, id, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Role == RoleOwner {
+		return nil, fmt.Errorf("%w: the owner cannot leave the club", ErrNotPermitted)
+	}
+	if actor != playerID {
+		actorRole, err := m.clubRole(ctx, id, actor)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case actorRole == RoleOwner:
+		case actorRole == RoleAdmin && target.Role == RoleMember:
+		default:
+			return nil, fmt.Errorf("%w: you cannot remove %s", ErrNotPermitted, playerID)
+		}
+	}
+	if _, err := m.store.RemoveClubMember(ctx, id, playerID); err != nil {
+		return nil, fmt.Errorf("persist member: %w", err)
+	}
+	return m.Club(ctx, id)
+}
+
+// ClubLeaderboard ranks a club's members over the games of its
+// tournaments, of one game type or of all if gameType is empty: by score,
+// then wins, then order of joining. Members level on score and wins share
+// a rank. Only members may see it.
+func (m *Manager) ClubLeaderboard(ctx context.Context, id, playerID, gameType string) ([]ClubStanding, error) {
+	if _, err := m.clubMember(ctx, id, playerID); err != nil {
+		return nil, err
+	}
+	members, err := m.store.ListClubMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tournaments, err := m.store.ListTournaments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(members))
+	for i, r := range members {
+		ids[i] = r.PlayerID
+	}
+	entered := make(map[string]int)
+	var pairings []storage.TournamentPairingRow
+	for i := range tournaments {
+		t := &tournaments[i]
+		if gameType != "" && t.GameType != gameType {
+			continue
+		}
+		for _, pid := range tournamentPlayers(t) {
+			entered[pid]++
+		}
+		rows, err := m.store.ListPairings(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		pairings = append(pairings, rows...)
+	}
+
+	recs := tally(ids, pairings)
+	board := make([]ClubStanding, len(members))
+	for i, r := range members {
+		rec := recs[r.PlayerID]
+		board[i] = ClubStanding{
+			PlayerID:    r.PlayerID,
+			Role:        r.Role,
+			Tournaments: entered[r.PlayerID],
+			Score:       rec.Score,
+			Played:      rec.Played,
+			Wins:        rec.Wins,
+			Draws:       rec.Draws,
+			Losses:      rec.Losses,
+			Byes:        rec.Byes,
+		}
+	}
+	level := func(a, b ClubStanding) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(b.Wins, a.Wins))
+	}
+	slices.SortStableFunc(board, level)
+	for i := range board {
+		board[i].Rank = i + 1
+		if i > 0 && level(board[i-1], board[i]) == 0 {
+			board[i].Rank = board[i-1].Rank
+		}
+	}
+	return board, nil
+}
+
+// ClubLobby returns a club as its members see it, with its tournaments
+// newest first and the games of them in play. Only members may see it.
+func (m *Manager) ClubLobby(ctx context.Context, id, playerID string) (*ClubLobby, error) {
+	if _, err := m.clubMember(ctx, id, playerID); err != nil {
+		return nil, err
+	}
+	c, err := m.Club(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.store.ListTournaments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	lobby := &ClubLobby{Club: c, Tournaments: make([]Tournament, len(rows)), Games: []ClubGame{}}
+	for i := range rows {
+		t := &rows[i]
+		lobby.Tournaments[i] = *tournamentInfo(t, nil)
+		if t.Status != "running" {
+			continue
+		}
+		pairings, err := m.store.ListPairings(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range tournamentInfo(t, pairings).Pairings {
+			if p.Round == t.Round && p.SessionCode != "" && p.Result == "" {
+				lobby.Games = append(lobby.Games, ClubGame{Tournament: t.ID, TournamentName: t.Name, Pairing: p})
+			}
+		}
+	}
+	return lobby, nil
+}
+
+// clubRole returns a player's role in a club, or "" if they are not a
+// member.
+func (m *Manager) clubRole(ctx context.Context, id, playerID string) (string, error) {
+	member, err := m.clubMember(ctx, id, playerID)
+	if errors.Is(err, ErrNotPermitted) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// clubMember returns a player's membership of a club, failing with
+// ErrNotPermitted if they are not a member.
+func (m *Manager) clubMember(ctx context.Context, id, playerID string) (*storage.ClubMemberRow, error) {
+	if _, err := m.store.GetClub(ctx, id); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClubNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	member, err := m.store.GetClubMember(ctx, id, playerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s is not a member of the club", ErrNotPermitted, playerID)
+	}
+	return member, err
+}
+
+func clubInfo(c *storage.ClubRow) *Club {
+	info := &Club{
+		ID:          c.ID,
+		Name:        c.Name,
+		Owner:       c.Owner,
+		MemberCount: c.Members,
+		CreatedAt:   c.CreatedAt,
+	}
+	if c.Branding != "" {
+		var b Branding
+		if json.Unmarshal([]byte(c.Branding), &b) == nil {
+			info.Branding = &b
+		}
+	}
+	return info
+}
+
+func generateClubID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "cl-" + hex.EncodeToString(b)
+}
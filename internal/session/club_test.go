@@ -0,0 +1,185 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"games/internal/event"
+)
+
+func TestClubRoles(t *testing.T) {
+	mgr := setupBotTest(t)
+	if _, err := mgr.CreateClub(t.Context(), " ", "alice"); err == nil {
+		t.Fatal("expected a club without a name refused")
+	}
+	c, err := mgr.CreateClub(t.Context(), "Rooks", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MemberCount != 1 || len(c.Members) != 1 || c.Members[0].Role != RoleOwner {
+		t.Fatalf("expected alice the owner and only member, got %+v", c)
+	}
+	for _, id := range []string{"bob", "carol", "dave"} {
+		if _, err := mgr.JoinClub(t.Context(), c.ID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mgr.JoinClub(t.Context(), c.ID, "bob"); err == nil {
+		t.Fatal("expected a second join refused")
+	}
+	if _, err := mgr.JoinClub(t.Context(), "cl-missing", "bob"); !errors.Is(err, ErrClubNotFound) {
+		t.Fatalf("expected ErrClubNotFound, got %v", err)
+	}
+
+	if _, err := mgr.SetClubRole(t.Context(), c.ID, "bob", "carol", RoleAdmin); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected only the owner to appoint admins, got %v", err)
+	}
+	if _, err := mgr.SetClubRole(t.Context(), c.ID, "alice", "bob", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.SetClubRole(t.Context(), c.ID, "alice", "alice", RoleMember); err == nil {
+		t.Fatal("expected the owner's role fixed")
+	}
+
+	// An admin removes members but not other admins, nor the owner
+	if _, err := mgr.SetClubRole(t.Context(), c.ID, "alice", "carol", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.RemoveClubMember(t.Context(), c.ID, "bob", "carol"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected an admin unable to remove an admin, got %v", err)
+	}
+	if _, err := mgr.RemoveClubMember(t.Context(), c.ID, "dave", "bob"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected a member unable to remove anyone, got %v", err)
+	}
+	if _, err := mgr.RemoveClubMember(t.Context(), c.ID, "alice", "alice"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected the owner unable to leave, got %v", err)
+	}
+	if _, err := mgr.RemoveClubMember(t.Context(), c.ID, "bob", "dave"); err != nil {
+		t.Fatal(err)
+	}
+	c, err = mgr.RemoveClubMember(t.Context(), c.ID, "carol", "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MemberCount != 2 || c.Members[1].PlayerID != "bob" || c.Members[1].Role != RoleAdmin {
+		t.Fatalf("expected alice and bob left, got %+v", c)
+	}
+
+	mine, _ := mgr.PlayerClubs(t.Context(), "bob")
+	if len(mine) != 1 || mine[0].ID != c.ID || mine[0].Role != RoleAdmin {
+		t.Fatalf("expected bob's club with bob's role, got %+v", mine)
+	}
+}
+
+func TestClubTournaments(t *testing.T) {
+	mgr := setupBotTest(t)
+	events, unsubscribe := mgr.Events().Subscribe(10)
+	defer unsubscribe()
+	c, _ := mgr.CreateClub(t.Context(), "Rooks", "alice")
+	for _, id := range []string{"bob", "carol"} {
+		mgr.JoinClub(t.Context(), c.ID, id)
+	}
+	if _, err := mgr.CreateTournament(t.Context(), "Club night", "tictactoe", FormatRoundRobin, 0, "bob", c.ID); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected a member unable to organize, got %v", err)
+	}
+	tn, err := mgr.CreateTournament(t.Context(), "Club night", "tictactoe", FormatRoundRobin, 0, "alice", c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tn.Club != c.ID {
+		t.Fatalf("expected the club's tournament, got %+v", tn)
+	}
+	if open, _ := mgr.Tournaments(t.Context(), "", ""); len(open) != 0 {
+		t.Fatalf("expected the club's tournament left out of the open list, got %+v", open)
+	}
+	if _, err := mgr.Tournaments(t.Context(), c.ID, "erin"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected a non-member unable to list the club's, got %v", err)
+	}
+	if list, _ := mgr.Tournaments(t.Context(), c.ID, "carol"); len(list) != 1 || list[0].ID != tn.ID {
+		t.Fatalf("expected the club's tournament listed for a member, got %+v", list)
+	}
+	if _, err := mgr.JoinTournament(t.Context(), tn.ID, "erin"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected a non-member unable to enter, got %v", err)
+	}
+	for _, id := range []string{"alice", "bob"} {
+		if _, err := mgr.JoinTournament(t.Context(), tn.ID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tn, started, err := mgr.StartTournament(t.Context(), tn.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(started) != 1 || !started[0].Private {
+		t.Fatalf("expected the club's match private, got %+v", started)
+	}
+	if e := <-events; e.Type != event.TournamentRound || !e.Private {
+		t.Fatalf("expected the round announced privately, got %+v", e)
+	}
+
+	lobby, err := mgr.ClubLobby(t.Context(), c.ID, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lobby.Tournaments) != 1 || len(lobby.Games) != 1 || lobby.Games[0].SessionCode != started[0].Code || lobby.Games[0].TournamentName != "Club night" {
+		t.Fatalf("expected the game in play in the lobby, got %+v", lobby)
+	}
+	if _, err := mgr.ClubLobby(t.Context(), c.ID, "erin"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected the lobby closed to non-members, got %v", err)
+	}
+
+	finishPairing(t, mgr, tn.Pairings[0], "bob")
+	board, err := mgr.ClubLeaderboard(t.Context(), c.ID, "carol", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(board) != 3 || board[0].PlayerID != "bob" || board[0].Wins != 1 || board[0].Tournaments != 1 {
+		t.Fatalf("expected bob top of the leaderboard, got %+v", board)
+	}
+	if board[1].Rank != 2 || board[2].Rank != 2 || board[1].PlayerID != "alice" || board[2].Tournaments != 0 {
+		t.Fatalf("expected alice and carol level on nothing, got %+v", board)
+	}
+	if other, _ := mgr.ClubLeaderboard(t.Context(), c.ID, "carol", "connect4"); other[0].Played != 0 {
+		t.Fatalf("expected nothing counted for another game, got %+v", other)
+	}
+	if lobby, _ := mgr.ClubLobby(t.Context(), c.ID, "carol"); len(lobby.Games) != 0 || lobby.Tournaments[0].Status != "finished" {
+		t.Fatalf("expected no games left in play, got %+v", lobby)
+	}
+}
+
+func TestClubBranding(t *testing.T) {
+	mgr := setupBotTest(t)
+	c, _ := mgr.CreateClub(t.Context(), "Rooks", "alice")
+	mgr.JoinClub(t.Context(), c.ID, "bob")
+	b := Branding{Name: "Rooks Chess Club", LogoURL: "/logos/rooks.png", AccentColor: "#336699"}
+	if _, err := mgr.SetClubBranding(t.Context(), c.ID, "bob", b); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected a member unable to brand the club, got %v", err)
+	}
+	for _, bad := range []Branding{
+		{AccentColor: "red"},
+		{SecondaryColor: "#12345"},
+		{LogoURL: "javascript:alert(1)"},
+		{LogoURL: "//evil.example/logo.png"},
+		{LogoURL: "http://rooks.example/logo.png"},
+		{Name: strings.Repeat("x", MaxBrandNameLen+1)},
+	} {
+		if _, err := mgr.SetClubBranding(t.Context(), c.ID, "alice", bad); err == nil {
+			t.Fatalf("expected %+v refused", bad)
+		}
+	}
+	c, err := mgr.SetClubBranding(t.Context(), c.ID, "alice", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Branding == nil || *c.Branding != b {
+		t.Fatalf("expected the club's branding, got %+v", c.Branding)
+	}
+	site := Branding{Name: "Games", AccentColor: "#000000", SecondaryColor: "#ffffff"}
+	if over := c.Branding.Over(site); over.Name != b.Name || over.AccentColor != b.AccentColor || over.SecondaryColor != site.SecondaryColor {
+		t.Fatalf("expected the club's fields over the site's, got %+v", over)
+	}
+	if c, _ = mgr.SetClubBranding(t.Context(), c.ID, "alice", Branding{}); c.Branding != nil {
+		t.Fatalf("expected the branding cleared, got %+v", c.Branding)
+	}
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// Rejection says why a player's action was refused.
+type Rejection string
+
+const (
+	RejectMalformed Rejection = "malformed" // the action could not be read
+	RejectTurn      Rejection = "turn"      // the player had no move to make
+	RejectInvalid   Rejection = "invalid"   // the game refused the move
+)
+
+// maxFlags bounds the anti-cheat flags kept per player.
+const maxFlags = 20
+
+// ConductReport counts a player's actions by how they were received since
+// the server started, with the latest anti-cheat flags raised against
+// them.
+type ConductReport struct {
+	PlayerID  string `json:"playerId,omitempty"`
+	Accepted  int    `json:"accepted"`
+	Malformed int    `json:"malformed"`
+	WrongTurn int    `json:"wrongTurn"`
+	Invalid   int    `json:"invalid"`
+	// RejectRate is the share of the player's actions refused, 0 to 1.
+	RejectRate float64     `json:"rejectRate"`
+	Flags      []CheatFlag `json:"flags,omitempty"` // newest first
+}
+
+// CheatFlag is an action a game's CheatChecker found impossible.
+type CheatFlag struct {
+	SessionCode string    `json:"sessionCode"`
+	GameType    string    `json:"gameType"`
+	Reason      string    `json:"reason"`
+	At          time.Time `json:"at"`
+}
+
+func (r *ConductReport) rejected() int {
+	return r.Malformed + r.WrongTurn + r.Invalid
+}
+
+// CountAction records that playerID's action was accepted, for an empty
+// rejection, or refused for the given reason.
+func (m *Manager) CountAction(playerID string, rejection Rejection) {
+	m.countConduct(playerID, func(r *ConductReport) {
+		switch rejection {
+		case "":
+			r.Accepted++
+		case RejectMalformed:
+			r.Malformed++
+		case RejectTurn:
+			r.WrongTurn++
+		default:
+			r.Invalid++
+		}
+	})
+}
+
+// Flag records an anti-cheat flag against a player of s, logging it for
+// moderators.
+func (m *Manager) Flag(s *Session, playerID, reason string) {
+	log.Printf("session %s: %s flagged by %s: %s", s.Code, playerID, s.GameType, reason)
+	flag := CheatFlag{SessionCode: s.Code, GameType: s.GameType, Reason: reason, At: time.Now()}
+	m.countConduct(playerID, func(r *ConductReport) {
+		r.Flags = append([]CheatFlag{flag}, r.Flags[:min(len(r.Flags), maxFlags-1)]...)
+	})
+}
+
+func (m *Manager) countConduct(playerID string, fn func(*ConductReport)) {
+	m.conductMu.Lock()
+	defer m.conductMu.Unlock()
+	r, ok := m.conduct[playerID]
+	if !ok {
+		r = &ConductReport{PlayerID: playerID}
+		m.conduct[playerID] = r
+	}
+	fn(r)
+}
+
+// ConductReports returns every player's action counts, the players with
+// the most refused actions first, and the totals across all of them.
+func (m *Manager) ConductReports() (players []ConductReport, total ConductReport) {
+	m.conductMu.Lock()
+	players = make([]ConductReport, 0, len(m.conduct))
+	for _, r := range m.conduct {
+		c := *r
+		c.Flags = append([]CheatFlag(nil), r.Flags...)
+		players = append(players, c)
+	}
+	m.conductMu.Unlock()
+
+	for i := range players {
+		r := &players[i]
+		r.RejectRate = rejectRate(r)
+		total.Accepted += r.Accepted
+		total.Malformed += r.Malformed
+		total.WrongTurn += r.WrongTurn
+		total.Invalid += r.Invalid
+	}
+	total.RejectRate = rejectRate(&total)
+	sort.Slice(players, func(i, j int) bool {
+		if a, b := players[i].rejected(), players[j].rejected(); a != b {
+			return a > b
+		}
+		return players[i].PlayerID < players[j].PlayerID
+	})
+	return players, total
+}
+
+func rejectRate(r *ConductReport) float64 {
+	n := r.Accepted + r.rejected()
+	if n == 0 {
+		return 0
+	}
+	return float64(r.rejected()) / float64(n)
+}
@@ -0,0 +1,159 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"games/internal/game"
+)
+
+// errGameStopped is what players of an errored session are told.
+const errGameStopped = "the game stopped after an internal error"
+
+// PanicError is a panic in game code, recovered so that it fails only the
+// session it happened in.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error is what players are told; the panic itself goes to the log.
+func (e *PanicError) Error() string {
+	return errGameStopped
+}
+
+// Protect calls fn, which calls into a game, turning a panic into a
+// *PanicError. Call it with the session locked if fn needs the lock, and
+// hand a PanicError to Manager.Fail once the lock is released.
+func Protect(fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// Fail marks a session errored after its game panicked, logs the panic
+// with the stack and the match's state for reproducing it, and stores the
+// new status. Nothing calls into an errored session's game again. Fail
+// reports false if the session had already failed.
+func (m *Manager) Fail(ctx context.Context, s *Session, perr *PanicError) bool {
+	ctx = detach(ctx)
+	s.mu.Lock()
+	if s.Status == StatusErrored {
+		s.mu.Unlock()
+		return false
+	}
+	s.Status = StatusErrored
+	s.LastActivity = time.Now()
+	state := "none"
+	if s.Match != nil {
+		err := Protect(func() error {
+			data, err := s.Match.MarshalJSON()
+			state = string(data)
+			return err
+		})
+		if err != nil {
+			state = fmt.Sprintf("unavailable: %v", err)
+		}
+	}
+	moves := len(s.History)
+	s.mu.Unlock()
+
+	log.Printf("session %s: game %s panicked after %d moves: %v\nstate: %s\n%s", s.Code, s.GameType, moves, perr.Value, state, perr.Stack)
+	if err := m.store.UpdateSessionStatus(ctx, s.Code, string(StatusErrored)); err != nil {
+		log.Printf("save errored session %s: %v", s.Code, err)
+	}
+	return true
+}
+
+// startMatch starts s's match, failing the session if the game panics.
+func (m *Manager) startMatch(ctx context.Context, s *Session) error {
+	err := s.Start()
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		m.Fail(ctx, s, perr)
+	}
+	return err
+}
+
+// RestoreMatch brings an errored session back into play from the match
+// state stored after its last move, which came before the panic. A match
+// that failed as it started goes back to waiting. If loading the state
+// panics too, the session stays errored.
+func (m *Manager) RestoreMatch(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.RLock()
+	status, started := s.Status, s.Match != nil
+	s.mu.RUnlock()
+	if status != StatusErrored {
+		return fmt.Errorf("game has not stopped after an error")
+	}
+	var match game.Match
+	if started {
+		data, err := m.store.GetMatchState(ctx, s.Code)
+		if err != nil {
+			return fmt.Errorf("no stored state to restore: %w", err)
+		}
+		err = Protect(func() error {
+			var err error
+			match, err = unmarshalMatch(s.game, data)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("restore match: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	if s.Status != StatusErrored {
+		s.mu.Unlock()
+		return fmt.Errorf("game has not stopped after an error")
+	}
+	s.Match = match
+	switch {
+	case match == nil:
+		s.Status = StatusWaiting
+	case s.FinishedAt.IsZero():
+		s.Status = StatusPlaying
+	default:
+		s.Status = StatusFinished
+	}
+	s.LastActivity = time.Now()
+	status = s.Status
+	s.mu.Unlock()
+	log.Printf("session %s restored after an error", s.Code)
+	return m.store.UpdateSessionStatus(ctx, s.Code, string(status))
+}
+
+// AbortMatch gives up on an errored session's match without recording it:
+// no results, archive, party round or scoreboard entry. The session goes
+// back to waiting with the same players and the same game, ready to start
+// afresh.
+func (m *Manager) AbortMatch(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.Lock()
+	if s.Status != StatusErrored {
+		s.mu.Unlock()
+		return fmt.Errorf("game has not stopped after an error")
+	}
+	s.clearMatchLocked()
+	options, _ := json.Marshal(s.Options)
+	previous, _ := json.Marshal(s.previous)
+	var party []byte
+	if s.Party != nil {
+		party, _ = json.Marshal(s.Party)
+	}
+	s.mu.Unlock()
+
+	if err := m.store.NextRound(ctx, s.Code, s.GameType, string(options), string(party), string(previous)); err != nil {
+		return fmt.Errorf("persist abort: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+// brokenGame is tic-tac-toe that panics as a match starts.
+type brokenGame struct{ tictactoe.TicTacToe }
+
+func (brokenGame) Info() game.GameInfo {
+	info := tictactoe.TicTacToe{}.Info()
+	info.Name = "broken"
+	return info
+}
+
+func (brokenGame) NewMatch(game.MatchConfig) game.Match {
+	panic("no board")
+}
+
+func TestFailedStartSurvivesRestart(t *testing.T) {
+	store := storage.NewMemory()
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(brokenGame{})
+
+	mgr := NewManager(reg, store)
+	sess, _ := mgr.Create(t.Context(), "broken")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	err := mgr.startMatch(t.Context(), sess)
+	var perr *PanicError
+	if !errors.As(err, &perr) || perr.Value != "no board" || len(perr.Stack) == 0 {
+		t.Fatalf("expected the panic returned, got %v", err)
+	}
+	if info := sess.Info(); info.Status != StatusErrored || info.Error != errGameStopped {
+		t.Fatalf("expected an errored session, got %+v", info)
+	}
+	if mgr.Fail(t.Context(), sess, perr) {
+		t.Fatal("expected a second failure to be ignored")
+	}
+
+	mgr2 := NewManager(reg, store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	sess2, ok := mgr2.Get(sess.Code)
+	if !ok || sess2.Info().Status != StatusErrored {
+		t.Fatalf("expected the errored session loaded, got %v", ok)
+	}
+	if err := mgr2.RestoreMatch(t.Context(), sess2); err != nil {
+		t.Fatalf("restore match: %v", err)
+	}
+	if info := sess2.Info(); info.Status != StatusWaiting || info.Error != "" {
+		t.Fatalf("expected the session back in its lobby, got %+v", info)
+	}
+	if err := mgr2.AbortMatch(t.Context(), sess2); err == nil {
+		t.Fatal("expected a waiting session not to be aborted")
+	}
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"games/internal/storage"
+)
+
+// ErrNoEmail is returned when a player has not set an email address.
+var ErrNoEmail = errors.New("no email address set")
+
+// SetEmail records playerID's email address as unverified and returns the
+// token that verifies it.
+func (m *Manager) SetEmail(ctx context.Context, playerID, email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email address")
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	if err := m.store.SaveEmailContact(ctx, playerID, email, token); err != nil {
+		return "", fmt.Errorf("persist email: %w", err)
+	}
+	return token, nil
+}
+
+// VerifyEmail confirms the address that was sent token and returns its
+// player ID.
+func (m *Manager) VerifyEmail(ctx context.Context, token string) (string, error) {
+	playerID, err := m.store.VerifyEmail(ctx, token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("invalid or used verification token")
+	}
+	return playerID, err
+}
+
+// EmailContact returns playerID's email address and preferences.
+func (m *Manager) EmailContact(ctx context.Context, playerID string) (*storage.EmailContactRow, error) {
+	c, err := m.store.GetEmailContact(ctx, playerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoEmail
+	}
+	return c, err
+}
+
+// SetEmailPreferences chooses which events playerID is emailed about.
+func (m *Manager) SetEmailPreferences(ctx context.Context, playerID string, turns, challenges bool) error {
+	ok, err := m.store.UpdateEmailPreferences(ctx, playerID, turns, challenges)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoEmail
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Emotes are the quick reactions players may send, apart from chat.
+var Emotes = []string{"👍", "😮", "GG"}
+
+// EmoteTTL is how long an emote is kept for players who reconnect after
+// it was sent.
+const EmoteTTL = 30 * time.Second
+
+// A player may send EmoteBurst emotes in any EmoteWindow.
+const (
+	EmoteBurst  = 3
+	EmoteWindow = 5 * time.Second
+)
+
+// Emote is a reaction a player sent to the session.
+type Emote struct {
+	PlayerID string    `json:"playerId"`
+	Emote    string    `json:"emote"`
+	At       time.Time `json:"at"`
+}
+
+// AddEmote records a reaction from playerID, refusing one that is not in
+// Emotes, from someone not seated, or over the player's rate.
+func (s *Session) AddEmote(playerID, emote string) (Emote, error) {
+	if !slices.Contains(Emotes, emote) {
+		return Emote{}, fmt.Errorf("unknown emote %q", emote)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Players[playerID]; !ok {
+		return Emote{}, fmt.Errorf("player %s not in session", playerID)
+	}
+	now := time.Now()
+	s.pruneEmotesLocked(now)
+	sent := 0
+	for _, e := range s.emotes {
+		if e.PlayerID == playerID && now.Sub(e.At) < EmoteWindow {
+			sent++
+		}
+	}
+	if sent >= EmoteBurst {
+		return Emote{}, fmt.Errorf("too many emotes; wait a moment")
+	}
+	e := Emote{PlayerID: playerID, Emote: emote, At: now}
+	s.emotes = append(s.emotes, e)
+	return e, nil
+}
+
+// RecentEmotes returns the emotes sent in the last EmoteTTL, oldest first.
+func (s *Session) RecentEmotes() []Emote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneEmotesLocked(time.Now())
+	return slices.Clone(s.emotes)
+}
+
+func (s *Session) pruneEmotesLocked(now time.Time) {
+	i := 0
+	for i < len(s.emotes) && now.Sub(s.emotes[i].At) >= EmoteTTL {
+		i++
+	}
+	s.emotes = s.emotes[i:]
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"games/internal/game/tictactoe"
+)
+
+func TestEmotes(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+
+	if _, err := sess.AddEmote("alice", "lol"); err == nil {
+		t.Fatal("expected an emote outside the set refused")
+	}
+	if _, err := sess.AddEmote("carol", "GG"); err == nil {
+		t.Fatal("expected an emote from someone not seated refused")
+	}
+	for range EmoteBurst {
+		if _, err := sess.AddEmote("alice", "👍"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sess.AddEmote("alice", "👍"); err == nil {
+		t.Fatal("expected emotes over the rate refused")
+	}
+	// Each player has a rate of their own
+	e, err := sess.AddEmote("bob", "GG")
+	if err != nil || e.PlayerID != "bob" || e.Emote != "GG" {
+		t.Fatalf("bob's emote: %+v %v", e, err)
+	}
+
+	// Once the window passes alice may react again, and once EmoteTTL
+	// passes the earlier ones are forgotten
+	sess.mu.Lock()
+	for i := range sess.emotes[:EmoteBurst] {
+		sess.emotes[i].At = sess.emotes[i].At.Add(-EmoteTTL)
+	}
+	sess.mu.Unlock()
+	if _, err := sess.AddEmote("alice", "😮"); err != nil {
+		t.Fatalf("expected alice allowed after the window: %v", err)
+	}
+	recent := sess.RecentEmotes()
+	if len(recent) != 2 || recent[0].PlayerID != "bob" || recent[1].Emote != "😮" {
+		t.Fatalf("unexpected recent emotes %+v", recent)
+	}
+	if time.Since(recent[0].At) > EmoteWindow {
+		t.Fatalf("unexpected time %v", recent[0].At)
+	}
+}
@@ -0,0 +1,148 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"games/internal/game"
+	"games/internal/storage"
+)
+
+// StrategyStanding aggregates archived exhibition results for one strategy.
+type StrategyStanding struct {
+	Strategy string `json:"strategy"`
+	Games    int    `json:"games"`
+	Wins     int    `json:"wins"`
+	Draws    int    `json:"draws"`
+	Losses   int    `json:"losses"`
+}
+
+// CreateExhibition makes a session seated entirely by bots playing the named
+// strategies and starts it. The caller drives the bots' moves.
+func (m *Manager) CreateExhibition(ctx context.Context, gameType string, strategies []string) (*Session, error) {
+	g, err := m.playable(gameType)
+	if err != nil {
+		return nil, err
+	}
+	gi := g.Info()
+	if len(strategies) < gi.MinPlayers || len(strategies) > gi.MaxPlayers {
+		return nil, fmt.Errorf("%s needs %d-%d bots, got %d", gameType, gi.MinPlayers, gi.MaxPlayers, len(strategies))
+	}
+	for _, name := range strategies {
+		if _, ok := m.registry.Strategy(gameType, name); !ok {
+			return nil, fmt.Errorf("unknown bot strategy: %s", name)
+		}
+	}
+
+	s, err := m.Create(ctx, gameType)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strategies {
+		strategy, _ := m.registry.Strategy(gameType, name)
+		if _, err := s.AddBot(strategy); err != nil {
+			m.Remove(ctx, s.Code)
+			return nil, err
+		}
+	}
+	if err := m.startMatch(ctx, s); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	return s, nil
+}
+
+// ArchiveExhibition records the results of a finished bot-vs-bot match for
+// strategy comparison.
+func (m *Manager) ArchiveExhibition(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.RLock()
+	if s.Match == nil || !s.Match.IsOver() {
+		s.mu.RUnlock()
+		return fmt.Errorf("match is not over")
+	}
+	var rows []storage.ExhibitionResultRow
+	for _, r := range game.Results(s.Match) {
+		p := s.Players[r.PlayerID]
+		if p == nil || p.Strategy == nil {
+			continue
+		}
+		rows = append(rows, storage.ExhibitionResultRow{
+			SessionCode: s.Code,
+			GameType:    s.GameType,
+			PlayerID:    r.PlayerID,
+			Strategy:    p.Strategy.Info().Name,
+			Rank:        r.Rank,
+			Score:       r.Score,
+			Outcome:     string(r.Outcome),
+		})
+	}
+	s.mu.RUnlock()
+	return m.store.ArchiveExhibitionResults(ctx, rows)
+}
+
+// ExhibitionStandings aggregates archived exhibition results per strategy,
+// best win rate first, counting the current season's when the leaderboards
+// run in seasons. Results archived without an outcome are judged by rank,
+// a rank-1 finish shared with another bot being a draw.
+func (m *Manager) ExhibitionStandings(ctx context.Context, gameType string) ([]StrategyStanding, error) {
+	return m.exhibitionStandings(ctx, gameType, m.seasonStart(), time.Time{})
+}
+
+// exhibitionStandings aggregates the exhibition results archived in the
+// given period; a zero time leaves that end open.
+func (m *Manager) exhibitionStandings(ctx context.Context, gameType string, from, to time.Time) ([]StrategyStanding, error) {
+	all, err := m.store.ListExhibitionResults(ctx, gameType)
+	if err != nil {
+		return nil, err
+	}
+	var rows []storage.ExhibitionResultRow
+	for _, r := range all {
+		if (from.IsZero() || !r.FinishedAt.Before(from)) && (to.IsZero() || r.FinishedAt.Before(to)) {
+			rows = append(rows, r)
+		}
+	}
+	firsts := make(map[string]int) // session code -> rank-1 finishers
+	for _, r := range rows {
+		if r.Rank == 1 {
+			firsts[r.SessionCode]++
+		}
+	}
+	byStrategy := make(map[string]*StrategyStanding)
+	for _, r := range rows {
+		st := byStrategy[r.Strategy]
+		if st == nil {
+			st = &StrategyStanding{Strategy: r.Strategy}
+			byStrategy[r.Strategy] = st
+		}
+		st.Games++
+		switch {
+		case r.Outcome == string(game.OutcomeWin):
+			st.Wins++
+		case r.Outcome == string(game.OutcomeDraw):
+			st.Draws++
+		case r.Outcome != "":
+			st.Losses++ // including forfeits and timeouts
+		case r.Rank > 1:
+			st.Losses++
+		case firsts[r.SessionCode] > 1:
+			st.Draws++
+		default:
+			st.Wins++
+		}
+	}
+	standings := make([]StrategyStanding, 0, len(byStrategy))
+	for _, st := range byStrategy {
+		standings = append(standings, *st)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Wins*b.Games != b.Wins*a.Games {
+			return a.Wins*b.Games > b.Wins*a.Games
+		}
+		return a.Strategy < b.Strategy
+	})
+	return standings, nil
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+func setupBotTest(t *testing.T) *Manager {
+	t.Helper()
+	store, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	reg := game.NewRegistry()
+	reg.Register(tictactoe.TicTacToe{})
+	for _, s := range tictactoe.Strategies() {
+		reg.RegisterStrategy("tictactoe", s)
+	}
+	return NewManager(reg, store)
+}
+
+func TestCreateExhibitionValidation(t *testing.T) {
+	mgr := setupBotTest(t)
+
+	if _, err := mgr.CreateExhibition(t.Context(), "chess", []string{"random", "random"}); err == nil {
+		t.Fatal("expected error for unknown game")
+	}
+	if _, err := mgr.CreateExhibition(t.Context(), "tictactoe", []string{"random"}); err == nil {
+		t.Fatal("expected error for too few bots")
+	}
+	if _, err := mgr.CreateExhibition(t.Context(), "tictactoe", []string{"random", "genius"}); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+	if n := len(mgr.List()); n != 0 {
+		t.Fatalf("expected no sessions left behind, got %d", n)
+	}
+}
+
+func TestExhibitionArchiveAndStandings(t *testing.T) {
+	mgr := setupBotTest(t)
+
+	for i := 0; i < 3; i++ {
+		sess, err := mgr.CreateExhibition(t.Context(), "tictactoe", []string{"perfect", "random"})
+		if err != nil {
+			t.Fatalf("create exhibition: %v", err)
+		}
+		if sess.Status != StatusPlaying {
+			t.Fatalf("expected exhibition to start, got %s", sess.Status)
+		}
+		for !sess.Match.IsOver() {
+			bot, _ := sess.NextBotMove()
+			action, err := bot.Strategy.ChooseAction(sess.Match, bot.ID)
+			if err != nil {
+				t.Fatalf("choose: %v", err)
+			}
+			if err := sess.Match.ApplyAction(bot.ID, action); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+		}
+		if err := mgr.ArchiveExhibition(t.Context(), sess); err != nil {
+			t.Fatalf("archive: %v", err)
+		}
+	}
+
+	standings, err := mgr.ExhibitionStandings(t.Context(), "tictactoe")
+	if err != nil {
+		t.Fatalf("standings: %v", err)
+	}
+	if len(standings) != 2 {
+		t.Fatalf("expected 2 strategies, got %+v", standings)
+	}
+	for _, st := range standings {
+		if st.Games != 3 || st.Wins+st.Draws+st.Losses != 3 {
+			t.Fatalf("inconsistent standing: %+v", st)
+		}
+		if st.Strategy == "perfect" && st.Losses != 0 {
+			t.Fatalf("perfect bot lost: %+v", st)
+		}
+	}
+	if standings[0].Strategy != "perfect" && standings[0].Wins != standings[1].Wins {
+		t.Fatalf("expected perfect bot ranked first, got %+v", standings)
+	}
+}
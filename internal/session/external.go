@@ -0,0 +1,89 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"games/internal/storage"
+)
+
+// ExternalBotPrefix starts every external bot's player ID. Only a client
+// holding the bot's API key may take a seat with such an ID.
+const ExternalBotPrefix = "ext-"
+
+// RegisterBot creates an external bot and returns it with its API key. The
+// key is not stored and cannot be recovered later.
+func (m *Manager) RegisterBot(ctx context.Context, name, webhookURL string) (*storage.BotRow, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("bot name required")
+	}
+	idBytes := make([]byte, 4)
+	keyBytes := make([]byte, 32)
+	rand.Read(idBytes)
+	rand.Read(keyBytes)
+	key := hex.EncodeToString(keyBytes)
+	bot := &storage.BotRow{
+		ID:         ExternalBotPrefix + hex.EncodeToString(idBytes),
+		Name:       name,
+		KeyHash:    hashKey(key),
+		WebhookURL: webhookURL,
+	}
+	if err := m.store.CreateBot(ctx, bot.ID, bot.Name, bot.KeyHash, bot.WebhookURL); err != nil {
+		return nil, "", fmt.Errorf("persist bot: %w", err)
+	}
+	return bot, key, nil
+}
+
+// AuthenticateBot returns the external bot owning the given API key.
+func (m *Manager) AuthenticateBot(ctx context.Context, key string) (*storage.BotRow, error) {
+	if key == "" {
+		return nil, fmt.Errorf("missing API key")
+	}
+	bot, err := m.store.GetBotByKeyHash(ctx, hashKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key")
+	}
+	return bot, nil
+}
+
+// CreateSandbox starts a practice session seating an external bot against a
+// built-in strategy. The caller drives the built-in bot's moves.
+func (m *Manager) CreateSandbox(ctx context.Context, gameType string, bot *storage.BotRow, opponent string) (*Session, error) {
+	strategy, ok := m.registry.Strategy(gameType, opponent)
+	if !ok {
+		return nil, fmt.Errorf("unknown bot strategy: %s", opponent)
+	}
+	s, err := m.Create(ctx, gameType)
+	if err != nil {
+		return nil, err
+	}
+	s.Sandbox = true
+	if err := m.SetPrivate(ctx, s, true); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	if err := s.AddPlayer(bot.ID); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	s.SetWebhook(bot.ID, bot.WebhookURL)
+	if _, err := s.AddBot(strategy); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	if err := m.startMatch(ctx, s); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	return s, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
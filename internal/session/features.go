@@ -0,0 +1,162 @@
+package session
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature is a new behavior rolled out to a share of sessions, so it can
+// be tried on some before all and turned off without a redeploy.
+type Feature struct {
+	Name    string `json:"name"`
+	Percent int    `json:"percent"` // of sessions that get it, 0 to 100
+}
+
+// SessionFeature is whether one session has a feature, and why.
+type SessionFeature struct {
+	Feature
+	Enabled bool `json:"enabled"`
+	// Overridden is set when the session was switched on or off by hand
+	// rather than by the rollout.
+	Overridden bool `json:"overridden,omitempty"`
+}
+
+// ParseFeatures reads a comma-separated list of name=percent pairs. A
+// name on its own is rolled out to every session.
+func ParseFeatures(spec string) ([]Feature, error) {
+	var features []Feature
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, percent, ok := strings.Cut(item, "=")
+		f := Feature{Name: name, Percent: 100}
+		if ok {
+			p, err := strconv.Atoi(percent)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: want a percentage, got %q", name, percent)
+			}
+			f.Percent = p
+		}
+		if err := f.validate(); err != nil {
+			return nil, err
+		}
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+func (f Feature) validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("feature name required")
+	}
+	if f.Percent < 0 || f.Percent > 100 {
+		return fmt.Errorf("feature %s: percentage must be 0 to 100, got %d", f.Name, f.Percent)
+	}
+	return nil
+}
+
+// rolledOut reports whether the session with the given code falls in f's
+// share. Each session lands in the same bucket every time, so raising the
+// percentage only ever adds sessions, and each feature buckets sessions
+// differently, so a session early for one is not early for all.
+func (f Feature) rolledOut(code string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(f.Name + "/" + code))
+	return int(h.Sum32()%100) < f.Percent
+}
+
+// SetFeature adds a feature or changes its rollout.
+func (m *Manager) SetFeature(f Feature) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	m.featuresMu.Lock()
+	defer m.featuresMu.Unlock()
+	m.features[f.Name] = f.Percent
+	return nil
+}
+
+// RemoveFeature forgets a feature, turning it off everywhere. It reports
+// false if there was no such feature.
+func (m *Manager) RemoveFeature(name string) bool {
+	m.featuresMu.Lock()
+	defer m.featuresMu.Unlock()
+	_, ok := m.features[name]
+	delete(m.features, name)
+	return ok
+}
+
+// Features returns every feature, by name.
+func (m *Manager) Features() []Feature {
+	m.featuresMu.RLock()
+	features := make([]Feature, 0, len(m.features))
+	for name, percent := range m.features {
+		features = append(features, Feature{Name: name, Percent: percent})
+	}
+	m.featuresMu.RUnlock()
+	sort.Slice(features, func(i, j int) bool { return features[i].Name < features[j].Name })
+	return features
+}
+
+// FeatureEnabled reports whether s has the named feature: as overridden
+// for the session if it was, otherwise by the feature's rollout. Unknown
+// features are off.
+func (m *Manager) FeatureEnabled(s *Session, name string) bool {
+	m.featuresMu.RLock()
+	percent, ok := m.features[name]
+	m.featuresMu.RUnlock()
+	if !ok {
+		return false
+	}
+	s.mu.RLock()
+	on, overridden := s.featureOverrides[name]
+	s.mu.RUnlock()
+	if overridden {
+		return on
+	}
+	return Feature{Name: name, Percent: percent}.rolledOut(s.Code)
+}
+
+// SessionFeatures returns whether s has each feature, by name.
+func (m *Manager) SessionFeatures(s *Session) []SessionFeature {
+	features := m.Features()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]SessionFeature, len(features))
+	for i, f := range features {
+		on, overridden := s.featureOverrides[f.Name]
+		if !overridden {
+			on = f.rolledOut(s.Code)
+		}
+		out[i] = SessionFeature{Feature: f, Enabled: on, Overridden: overridden}
+	}
+	return out
+}
+
+// OverrideFeature turns a feature on or off for s whatever its rollout,
+// or with nil hands it back to the rollout. Overrides are kept in memory
+// only, for trying a feature on a session.
+func (m *Manager) OverrideFeature(s *Session, name string, on *bool) error {
+	m.featuresMu.RLock()
+	_, ok := m.features[name]
+	m.featuresMu.RUnlock()
+	if !ok && on != nil {
+		return fmt.Errorf("unknown feature: %s", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on == nil {
+		delete(s.featureOverrides, name)
+		return nil
+	}
+	if s.featureOverrides == nil {
+		s.featureOverrides = make(map[string]bool)
+	}
+	s.featureOverrides[name] = *on
+	return nil
+}
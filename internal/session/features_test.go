@@ -0,0 +1,76 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+func TestParseFeatures(t *testing.T) {
+	features, err := ParseFeatures(" delta-broadcasts=10, new-timers ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 2 || features[0] != (Feature{"delta-broadcasts", 10}) || features[1] != (Feature{"new-timers", 100}) {
+		t.Fatalf("unexpected features %+v", features)
+	}
+	for _, spec := range []string{"x=half", "x=101", "=5"} {
+		if _, err := ParseFeatures(spec); err == nil {
+			t.Errorf("expected %q refused", spec)
+		}
+	}
+}
+
+func TestFeatureRollout(t *testing.T) {
+	store := storage.NewMemory()
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(tictactoe.TicTacToe{})
+	mgr := NewManager(reg, store)
+	mgr.SetFeature(Feature{Name: "delta-broadcasts", Percent: 30})
+
+	var sessions []*Session
+	enabled := 0
+	for i := range 1000 {
+		sess := NewSession(fmt.Sprintf("S%04d", i), "tictactoe", tictactoe.TicTacToe{})
+		sessions = append(sessions, sess)
+		if mgr.FeatureEnabled(sess, "delta-broadcasts") {
+			enabled++
+		}
+	}
+	if enabled < 250 || enabled > 350 {
+		t.Fatalf("expected about 30%% of sessions, got %d of 1000", enabled)
+	}
+
+	mgr.SetFeature(Feature{Name: "delta-broadcasts", Percent: 60})
+	for _, sess := range sessions {
+		was := Feature{Name: "delta-broadcasts", Percent: 30}.rolledOut(sess.Code)
+		if was && !mgr.FeatureEnabled(sess, "delta-broadcasts") {
+			t.Fatalf("expected %s to keep the feature as the rollout grew", sess.Code)
+		}
+	}
+
+	sess := sessions[0]
+	on := !mgr.FeatureEnabled(sess, "delta-broadcasts")
+	if err := mgr.OverrideFeature(sess, "delta-broadcasts", &on); err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.SessionFeatures(sess); len(got) != 1 || got[0].Enabled != on || !got[0].Overridden {
+		t.Fatalf("expected the override to win, got %+v", got)
+	}
+	mgr.OverrideFeature(sess, "delta-broadcasts", nil)
+	if got := mgr.SessionFeatures(sess); got[0].Enabled == on || got[0].Overridden {
+		t.Fatalf("expected the rollout back, got %+v", got)
+	}
+	if err := mgr.OverrideFeature(sess, "new-timers", &on); err == nil {
+		t.Fatal("expected an unknown feature refused")
+	}
+
+	mgr.RemoveFeature("delta-broadcasts")
+	if mgr.FeatureEnabled(sess, "delta-broadcasts") || len(mgr.SessionFeatures(sess)) != 0 {
+		t.Fatal("expected a removed feature off everywhere")
+	}
+}
@@ -0,0 +1,38 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"games/internal/game"
+)
+
+// SetHandicaps gives players of a waiting session an uneven start, as
+// checked by its game, and persists them. Handicaps of players who leave
+// before the start are ignored.
+func (m *Manager) SetHandicaps(ctx context.Context, s *Session, h game.Handicaps) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Status != StatusWaiting {
+		return fmt.Errorf("handicaps can only be changed before the game starts")
+	}
+	var resolved game.Handicaps
+	err := Protect(func() error {
+		var err error
+		resolved, err = game.ResolveHandicaps(s.game, h)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if resolved != nil {
+		data, _ = json.Marshal(resolved)
+	}
+	if err := m.store.SetSessionHandicaps(ctx, s.Code, string(data)); err != nil {
+		return fmt.Errorf("persist handicaps: %w", err)
+	}
+	s.Handicaps = resolved
+	return nil
+}
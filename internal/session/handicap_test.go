@@ -0,0 +1,71 @@
+package session
+
+import (
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+)
+
+func TestHandicaps(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := t.Context()
+
+	sess, _ := mgr.Create(ctx, "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+
+	if err := mgr.SetHandicaps(ctx, sess, game.Handicaps{"bob": {"centre": 2}}); err == nil {
+		t.Fatal("expected an out-of-range handicap to be refused")
+	}
+	if err := mgr.SetHandicaps(ctx, sess, game.Handicaps{"bob": {"centre": 1}, "carol": {"centre": 1}}); err == nil {
+		t.Fatal("expected the game to refuse two players in the centre")
+	}
+	if err := mgr.SetHandicaps(ctx, sess, game.Handicaps{"alice": {"centre": 0}, "carol": {"centre": 1}}); err != nil {
+		t.Fatalf("set handicaps: %v", err)
+	}
+	if h := sess.Info().Handicaps; len(h) != 1 || h["carol"]["centre"] != 1 {
+		t.Fatalf("expected only carol's handicap kept, got %v", h)
+	}
+
+	// Handicaps survive a restart.
+	mgr2 := NewManager(mgr.registry, mgr.store)
+	if err := mgr2.Restore(ctx); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored, _ := mgr2.Get(sess.Code); restored.Info().Handicaps["carol"]["centre"] != 1 {
+		t.Fatalf("expected handicaps restored, got %v", restored.Info().Handicaps)
+	}
+
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if board := sess.Match.(*tictactoe.Match).Board; board[4] != 0 {
+		t.Fatalf("expected the handicap of a player not seated ignored, got %v", board)
+	}
+	if tr, _ := sess.Transcript(); tr.Handicaps != nil {
+		t.Fatalf("expected an even transcript, got %v", tr.Handicaps)
+	}
+	if err := mgr.SetHandicaps(ctx, sess, nil); err == nil {
+		t.Fatal("expected handicaps fixed once the match starts")
+	}
+
+	sess2, _ := mgr.Create(ctx, "tictactoe")
+	sess2.AddPlayer("alice")
+	sess2.AddPlayer("bob")
+	mgr.SetHandicaps(ctx, sess2, game.Handicaps{"bob": {"centre": 1}})
+	sess2.Start()
+	m := sess2.Match.(*tictactoe.Match)
+	if mark := m.Board[4]; m.Players[mark-1] != "bob" {
+		t.Fatalf("expected bob to start in the centre, got %v", m.Board)
+	}
+	tr, err := sess2.Transcript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := Replay(mgr.registry, tr)
+	if err != nil || replayed.(*tictactoe.Match).Board != m.Board {
+		t.Fatalf("expected the transcript to replay the handicap, got %v %v", replayed, err)
+	}
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HandoffTimeout is how long a handoff code stays redeemable.
+const HandoffTimeout = 2 * time.Minute
+
+type handoff struct {
+	playerID  string
+	expiresAt time.Time
+}
+
+// NewHandoff issues a one-time code that moves playerID's seat to whichever
+// connection joins with it before HandoffTimeout. A newer code replaces the
+// player's previous one.
+func (s *Session) NewHandoff(playerID string) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.Players[playerID]
+	if p == nil {
+		return "", time.Time{}, fmt.Errorf("player %s not in session", playerID)
+	}
+	if p.Strategy != nil {
+		return "", time.Time{}, fmt.Errorf("bots cannot hand off their seat")
+	}
+	now := time.Now()
+	for code, h := range s.handoffs {
+		if h.playerID == playerID || now.After(h.expiresAt) {
+			delete(s.handoffs, code)
+		}
+	}
+	if s.handoffs == nil {
+		s.handoffs = make(map[string]handoff)
+	}
+	code := newHandoffCode()
+	expires := now.Add(HandoffTimeout)
+	s.handoffs[code] = handoff{playerID: playerID, expiresAt: expires}
+	return code, expires, nil
+}
+
+// RedeemHandoff consumes a handoff code, signals every connection the
+// player has through Replaced, and binds the seat to a fresh device token.
+// From then on only connections presenting that token may take the seat,
+// so the device that handed it off cannot reclaim it by player ID alone.
+func (s *Session) RedeemHandoff(code string) (playerID, token string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code = strings.ToUpper(strings.TrimSpace(code))
+	h, ok := s.handoffs[code]
+	if !ok || time.Now().After(h.expiresAt) {
+		return "", "", fmt.Errorf("handoff code is invalid or expired")
+	}
+	delete(s.handoffs, code)
+	p := s.Players[h.playerID]
+	if p == nil {
+		return "", "", fmt.Errorf("player %s is no longer in the session", h.playerID)
+	}
+	for _, replaced := range p.conns {
+		close(replaced)
+	}
+	p.conns = nil
+	token = newHandoffToken()
+	s.setSecretLocked(h.playerID, token)
+	return h.playerID, token, nil
+}
+
+// ClaimSeat gives playerID's seat its join secret on its first
+// connection and returns it. From then on only connections presenting the
+// secret may take the seat, so no one else can take it over by player ID.
+// Seats with a secret already, and bots' seats, get none and return "".
+func (s *Session) ClaimSeat(playerID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.Players[playerID]
+	if p == nil || p.Strategy != nil || s.secrets[playerID] != nil {
+		return ""
+	}
+	secret := newHandoffToken()
+	s.setSecretLocked(playerID, secret)
+	return secret
+}
+
+// AcceptsToken reports whether a connection presenting token may take
+// playerID's seat: its join secret, or the device token of a handoff.
+// Seats not yet claimed accept any connection.
+func (s *Session) AcceptsToken(playerID, token string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	want := s.secrets[playerID]
+	return want == nil || subtle.ConstantTimeCompare(want, hashSecret(token)) == 1
+}
+
+// SeatWithToken returns the seat whose join secret or device token is
+// token, identifying a caller outside a WebSocket connection.
+func (s *Session) SeatWithToken(token string) (playerID string, ok bool) {
+	if token == "" {
+		return "", false
+	}
+	sum := hashSecret(token)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for id, want := range s.secrets {
+		if s.Players[id] != nil && subtle.ConstantTimeCompare(want, sum) == 1 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// setSecretLocked makes secret the only one playerID's seat accepts. The
+// caller must hold the write lock.
+func (s *Session) setSecretLocked(playerID, secret string) {
+	if s.secrets == nil {
+		s.secrets = make(map[string][]byte)
+	}
+	s.secrets[playerID] = hashSecret(secret)
+}
+
+// hashSecret is what is kept of a join secret or device token, so that
+// neither memory nor storage holds one a client could present.
+func hashSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// Replaced returns a channel that is closed once the seat is handed off
+// away from the connection sending on send. It is already closed if send is
+// not one of playerID's connections.
+func (s *Session) Replaced(playerID string, send chan []byte) <-chan struct{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p := s.Players[playerID]; p != nil && p.conns[send] != nil {
+		return p.conns[send]
+	}
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+// handoffAlphabet leaves out letters and digits that are easily confused
+// when a code is read off one screen and typed into another.
+const handoffAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func newHandoffCode() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	for i := range b {
+		b[i] = handoffAlphabet[int(b[i])%len(handoffAlphabet)]
+	}
+	return string(b)
+}
+
+func newHandoffToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,114 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"games/internal/game/tictactoe"
+)
+
+func TestHandoffBindsSeatToNewDevice(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	phone := make(chan []byte, 1)
+	sess.ConnectPlayer("alice", phone)
+	replaced := sess.Replaced("alice", phone)
+
+	if _, _, err := sess.NewHandoff("bob"); err == nil {
+		t.Fatal("expected error handing off a seat not in the session")
+	}
+	old, _, _ := sess.NewHandoff("alice")
+	code, expires, err := sess.NewHandoff("alice")
+	if err != nil {
+		t.Fatalf("new handoff: %v", err)
+	}
+	if time.Until(expires) > HandoffTimeout {
+		t.Fatalf("unexpected expiry %v", expires)
+	}
+	if _, _, err := sess.RedeemHandoff(old); err == nil {
+		t.Fatal("expected the earlier code to be replaced")
+	}
+	if !sess.AcceptsToken("alice", "") {
+		t.Fatal("expected a seat never claimed to accept any connection")
+	}
+
+	id, token, err := sess.RedeemHandoff(code)
+	if err != nil || id != "alice" || token == "" {
+		t.Fatalf("redeem: %q %q %v", id, token, err)
+	}
+	if sess.AcceptsToken("alice", "") || !sess.AcceptsToken("alice", token) {
+		t.Fatal("expected the seat to require the new device token")
+	}
+	sess.ConnectPlayer("alice", make(chan []byte, 1))
+	select {
+	case <-replaced:
+	default:
+		t.Fatal("expected the phone's connection to be replaced")
+	}
+}
+
+func TestHandoffExpires(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	code, _, _ := sess.NewHandoff("alice")
+	sess.handoffs[code] = handoff{playerID: "alice", expiresAt: time.Now().Add(-time.Second)}
+	if _, _, err := sess.RedeemHandoff(code); err == nil {
+		t.Fatal("expected an expired code to be rejected")
+	}
+}
+
+func TestClaimSeat(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	secret := sess.ClaimSeat("alice")
+	if secret == "" {
+		t.Fatal("expected the first claim to get a secret")
+	}
+	if again := sess.ClaimSeat("alice"); again != "" {
+		t.Fatalf("expected a claimed seat to give no second secret, got %q", again)
+	}
+	if sess.AcceptsToken("alice", "") || !sess.AcceptsToken("alice", secret) {
+		t.Fatal("expected the seat to require its secret")
+	}
+	if id, ok := sess.SeatWithToken(secret); !ok || id != "alice" {
+		t.Fatalf("expected the secret to name alice's seat, got %q %v", id, ok)
+	}
+	if _, ok := sess.SeatWithToken(""); ok {
+		t.Fatal("expected no seat for an empty token")
+	}
+	if sess.ClaimSeat("bob") != "" {
+		t.Fatal("expected no secret for a seat not in the session")
+	}
+}
+
+func TestSeatSecretSurvivesRestore(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	secret := sess.ClaimSeat("alice")
+	if err := mgr.SaveSessionPlayers(t.Context(), sess); err != nil {
+		t.Fatalf("save players: %v", err)
+	}
+	data, _ := mgr.store.GetMatchState(t.Context(), sess.Code+"_players")
+	if strings.Contains(data, secret) {
+		t.Fatal("expected only the secret's hash stored")
+	}
+
+	mgr2 := NewManager(mgr.registry, mgr.store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("session not restored")
+	}
+	if restored.AcceptsToken("alice", "") || restored.AcceptsToken("alice", "guess") || !restored.AcceptsToken("alice", secret) {
+		t.Fatal("expected alice's seat to still take only her secret")
+	}
+	if !restored.AcceptsToken("bob", "") {
+		t.Fatal("expected a seat never claimed to accept any connection")
+	}
+}
@@ -0,0 +1,94 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"games/internal/event"
+	"games/internal/storage"
+)
+
+// inboxEvents are the events kept for players who were away when they
+// came: their turn, a challenge, and how a match of theirs ended.
+var inboxEvents = map[string]bool{event.YourTurn: true, event.ChallengeIssued: true, event.MatchFinished: true}
+
+// InboxMessage is an event kept for a player until they next look.
+type InboxMessage struct {
+	ID int64 `json:"id"`
+	event.Event
+}
+
+// RunInbox keeps the events in inboxEvents for each player they concern
+// who has no connection open, until the channel is closed. Like the push
+// and email notifiers it leaves out players who saw the event in a page.
+func (m *Manager) RunInbox(events <-chan event.Event) {
+	for e := range events {
+		if !inboxEvents[e.Type] {
+			continue
+		}
+		data, _ := json.Marshal(e)
+		topic := e.Type + "/" + e.SessionCode
+		if e.ChallengeID != "" {
+			topic = e.Type + "/" + e.ChallengeID
+		}
+		for _, playerID := range m.inboxRecipients(e) {
+			if m.IsOnline(playerID) {
+				continue
+			}
+			row := storage.InboxRow{PlayerID: playerID, Topic: topic, EventJSON: string(data)}
+			if err := m.store.AddToInbox(context.Background(), row); err != nil {
+				log.Printf("inbox of %s: %v", playerID, err)
+			}
+		}
+	}
+}
+
+// inboxRecipients are the players an event concerns: those it is
+// addressed to or, for a finished match, which is not, its human players.
+func (m *Manager) inboxRecipients(e event.Event) []string {
+	if e.Type != event.MatchFinished {
+		return e.Recipients
+	}
+	return m.humanPlayers(e)
+}
+
+// humanPlayers are the players of an event's match who are not bots.
+func (m *Manager) humanPlayers(e event.Event) []string {
+	s, live := m.Get(e.SessionCode)
+	var humans []string
+	for _, id := range e.Players {
+		if strings.HasPrefix(id, ExternalBotPrefix) {
+			continue
+		}
+		if live && s.IsBot(id) {
+			continue
+		}
+		humans = append(humans, id)
+	}
+	return humans
+}
+
+// Inbox returns the messages kept for playerID, oldest first.
+func (m *Manager) Inbox(ctx context.Context, playerID string) ([]InboxMessage, error) {
+	rows, err := m.store.ListInbox(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]InboxMessage, 0, len(rows))
+	for _, row := range rows {
+		msg := InboxMessage{ID: row.ID}
+		if err := json.Unmarshal([]byte(row.EventJSON), &msg.Event); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// ClearInbox removes playerID's messages up to the one with ID through,
+// once they have been shown, keeping any that arrived since.
+func (m *Manager) ClearInbox(ctx context.Context, playerID string, through int64) error {
+	return m.store.ClearInbox(ctx, playerID, through)
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"time"
+
+	"games/internal/game"
+)
+
+// moveTiming is how a move's times are stored alongside it.
+type moveTiming struct {
+	At      time.Time `json:"at"`
+	SentAt  time.Time `json:"sentAt,omitzero"`
+	ActedAt time.Time `json:"actedAt,omitzero"`
+}
+
+// Time is when the move counts as made: when the server received it, less
+// any lag compensation.
+func (mv Move) Time() time.Time {
+	if !mv.ActedAt.IsZero() {
+		return mv.ActedAt
+	}
+	return mv.At
+}
+
+// CompensateLagLocked credits mv for network delay if the session's game
+// is a game.LagCompensator, setting ActedAt to when the client said it
+// sent the action. The credit is capped by the game's window and never
+// puts the move before the one it follows or the start of the match. The
+// caller must hold the write lock.
+func (s *Session) CompensateLagLocked(mv *Move) {
+	lc, ok := s.game.(game.LagCompensator)
+	if !ok || mv.SentAt.IsZero() || !mv.SentAt.Before(mv.At) {
+		return
+	}
+	earliest := later(mv.At.Add(-lc.LagWindow()), s.StartedAt)
+	if n := len(s.History); n > 0 {
+		earliest = later(earliest, s.History[n-1].Time())
+	}
+	if acted := later(mv.SentAt, earliest); acted.Before(mv.At) {
+		mv.ActedAt = acted
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,84 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+// clockGame is tic-tac-toe that forgives players 200ms of lag.
+type clockGame struct{ tictactoe.TicTacToe }
+
+func (clockGame) Info() game.GameInfo {
+	info := tictactoe.TicTacToe{}.Info()
+	info.Name = "clock"
+	return info
+}
+
+func (clockGame) LagWindow() time.Duration { return 200 * time.Millisecond }
+
+func TestCompensateLag(t *testing.T) {
+	store := storage.NewMemory()
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(clockGame{})
+	reg.Register(tictactoe.TicTacToe{})
+	mgr := NewManager(reg, store)
+
+	sess, _ := mgr.Create(t.Context(), "clock")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	ms := time.Millisecond
+	at := sess.StartedAt.Add(time.Second)
+	for _, c := range []struct {
+		name         string
+		sentAt, want time.Time
+	}{
+		{"no claim", time.Time{}, time.Time{}},
+		{"within the window", at.Add(-50 * ms), at.Add(-50 * ms)},
+		{"beyond the window", at.Add(-time.Second), at.Add(-200 * ms)},
+		{"from the future", at.Add(time.Second), time.Time{}},
+	} {
+		mv := Move{PlayerID: "alice", At: at, SentAt: c.sentAt}
+		sess.CompensateLagLocked(&mv)
+		if !mv.ActedAt.Equal(c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, mv.ActedAt)
+		}
+	}
+
+	sess.History = []Move{{PlayerID: "bob", At: at.Add(-100 * ms)}}
+	mv := Move{PlayerID: "alice", At: at, SentAt: at.Add(-150 * ms)}
+	sess.CompensateLagLocked(&mv)
+	if !mv.ActedAt.Equal(at.Add(-100 * ms)) {
+		t.Errorf("expected no credit past the previous move, got %v", mv.ActedAt)
+	}
+
+	plain, _ := mgr.Create(t.Context(), "tictactoe")
+	mv = Move{PlayerID: "alice", At: at, SentAt: at.Add(-50 * ms)}
+	plain.CompensateLagLocked(&mv)
+	if !mv.ActedAt.IsZero() {
+		t.Errorf("expected a game without a window to give no credit, got %v", mv.ActedAt)
+	}
+
+	mv = Move{
+		PlayerID: "alice", Action: game.Action{Type: "move", Payload: json.RawMessage(`{"cell":4}`)},
+		At: at.Add(123 * ms), SentAt: at, ActedAt: at.Add(23 * ms),
+	}
+	if err := mgr.AppendMove(t.Context(), sess, 1, mv); err != nil {
+		t.Fatalf("append move: %v", err)
+	}
+	moves, err := mgr.loadMoves(t.Context(), sess.Code)
+	if err != nil || len(moves) != 1 {
+		t.Fatalf("load moves: %v %+v", err, moves)
+	}
+	if got := moves[0]; !got.At.Equal(mv.At) || !got.SentAt.Equal(mv.SentAt) || !got.ActedAt.Equal(mv.ActedAt) {
+		t.Fatalf("expected the times stored to the millisecond, got %+v", got)
+	}
+}
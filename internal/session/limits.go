@@ -0,0 +1,74 @@
+package session
+
+import (
+	"log"
+
+	"games/internal/game"
+)
+
+// LimitReport is a game's size limits and how often its state went over
+// them since the server started.
+type LimitReport struct {
+	GameType string `json:"gameType"`
+	game.Limits
+	// StatesRefused counts match states not stored for being too large.
+	StatesRefused int `json:"statesRefused"`
+	// BroadcastsDropped counts state messages not sent for being too
+	// large.
+	BroadcastsDropped int `json:"broadcastsDropped"`
+}
+
+// Limits returns the size limits of a game type.
+func (m *Manager) Limits(gameType string) game.Limits {
+	return m.registry.Limits(gameType)
+}
+
+// checkState refuses a marshaled state over the game's limit, logging it
+// for the game's author.
+func (m *Manager) checkState(gameType, code, what string, size int) error {
+	err := game.CheckSize(what, size, m.registry.Limits(gameType).MaxStateBytes)
+	if err != nil {
+		log.Printf("game %s, session %s: %v; not stored", gameType, code, err)
+		m.countOversize(gameType, func(r *LimitReport) { r.StatesRefused++ })
+	}
+	return err
+}
+
+// CheckBroadcast refuses a state message over the game's limit, logging
+// it for the game's author.
+func (m *Manager) CheckBroadcast(gameType, code string, size int) error {
+	err := game.CheckSize("state message", size, m.registry.Limits(gameType).MaxBroadcastBytes)
+	if err != nil {
+		log.Printf("game %s, session %s: %v; not sent", gameType, code, err)
+		m.countOversize(gameType, func(r *LimitReport) { r.BroadcastsDropped++ })
+	}
+	return err
+}
+
+func (m *Manager) countOversize(gameType string, fn func(*LimitReport)) {
+	m.oversizeMu.Lock()
+	defer m.oversizeMu.Unlock()
+	r, ok := m.oversize[gameType]
+	if !ok {
+		r = &LimitReport{GameType: gameType}
+		m.oversize[gameType] = r
+	}
+	fn(r)
+}
+
+// LimitReports returns the limits of every registered game and how often
+// each was hit, by game name.
+func (m *Manager) LimitReports() []LimitReport {
+	names := m.registry.Names()
+	m.oversizeMu.Lock()
+	defer m.oversizeMu.Unlock()
+	reports := make([]LimitReport, len(names))
+	for i, name := range names {
+		reports[i] = LimitReport{GameType: name}
+		if r, ok := m.oversize[name]; ok {
+			reports[i] = *r
+		}
+		reports[i].Limits = m.registry.Limits(name)
+	}
+	return reports
+}
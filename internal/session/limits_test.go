@@ -0,0 +1,40 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"games/internal/game"
+)
+
+func TestSaveMatchStateOverLimit(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+	if err := mgr.SaveMatchStart(t.Context(), sess); err != nil {
+		t.Fatalf("save match start: %v", err)
+	}
+	stored, _ := mgr.store.GetMatchState(t.Context(), sess.Code)
+
+	mgr.registry.SetLimits("tictactoe", game.Limits{MaxStateBytes: len(stored) - 1})
+	err := mgr.SaveMatchState(t.Context(), sess)
+	var se *game.SizeError
+	if !errors.As(err, &se) || se.Size != len(stored) {
+		t.Fatalf("expected a size error, got %v", err)
+	}
+	if got, _ := mgr.store.GetMatchState(t.Context(), sess.Code); got != stored {
+		t.Fatalf("expected the last stored state kept, got %s", got)
+	}
+
+	if err := mgr.CheckBroadcast("tictactoe", sess.Code, 1<<20); err != nil {
+		t.Fatalf("expected no broadcast limit, got %v", err)
+	}
+	reports := mgr.LimitReports()
+	if len(reports) != 1 || reports[0].StatesRefused != 1 || reports[0].BroadcastsDropped != 0 {
+		t.Fatalf("unexpected limit reports %+v", reports)
+	}
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"context"
+
+	"games/internal/storage"
+)
+
+// Listing is a stored session as session listings show it.
+type Listing struct {
+	Code      string `json:"code"`
+	GameType  string `json:"gameType"`
+	Status    Status `json:"status"`
+	Private   bool   `json:"private,omitempty"`
+	Pinned    bool   `json:"pinned,omitempty"`
+	Abandoned bool   `json:"abandoned,omitempty"`
+	// RulesVersion is the version of the rules the match started under;
+	// NewerRules is set when new matches would play by another.
+	RulesVersion string `json:"rulesVersion,omitempty"`
+	NewerRules   string `json:"newerRules,omitempty"`
+	// Players is filled in for sessions still loaded; a stored session
+	// does not keep its players.
+	Players []string `json:"players,omitempty"`
+	// Names are the names players are shown by, as in Info.
+	Names map[string]string `json:"names,omitempty"`
+	// YourTurn is set, in a player's own listing, when the player has a
+	// move to make.
+	YourTurn bool `json:"yourTurn,omitempty"`
+
+	CreatedAt    string `json:"createdAt"`
+	StartedAt    string `json:"startedAt,omitempty"`
+	FinishedAt   string `json:"finishedAt,omitempty"`
+	LastActivity string `json:"lastActivity,omitempty"`
+}
+
+// ListSessions returns one page of the stored sessions matching f.
+// Unlike List it reads storage, so it also covers sessions no longer
+// loaded.
+func (m *Manager) ListSessions(ctx context.Context, f storage.SessionFilter) ([]Listing, error) {
+	rows, err := m.store.ListSessions(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	listings := make([]Listing, len(rows))
+	for i, r := range rows {
+		listings[i] = m.listing(r)
+	}
+	return listings, nil
+}
+
+// PlayerSessions lists the sessions playerID is seated in that are not
+// over, waiting or playing, most recently active first. It reads the
+// membership storage keeps, so it also covers sessions no longer loaded.
+// Private sessions are listed only withPrivate, for the player themself.
+func (m *Manager) PlayerSessions(ctx context.Context, playerID string, withPrivate bool) ([]Listing, error) {
+	f := storage.SessionFilter{PlayerID: playerID, PublicOnly: !withPrivate, Unfinished: true, Sort: storage.SortActivity}
+	rows, err := m.store.ListSessions(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	listings := make([]Listing, len(rows))
+	for i, r := range rows {
+		listings[i] = m.listing(r)
+		if s, ok := m.Get(r.Code); ok {
+			listings[i].YourTurn = s.isTurn(playerID)
+		}
+	}
+	return listings, nil
+}
+
+// listing describes a stored session, with its players if it is loaded.
+func (m *Manager) listing(r storage.SessionRow) Listing {
+	l := Listing{
+		Code:         r.Code,
+		GameType:     r.GameType,
+		Status:       Status(r.Status),
+		Private:      r.Private,
+		Pinned:       r.Pinned,
+		Abandoned:    r.Abandoned,
+		RulesVersion: r.RulesVersion,
+		NewerRules:   m.newerRules(r.GameType, Status(r.Status), r.RulesVersion),
+		CreatedAt:    rfc3339(r.CreatedAt),
+		StartedAt:    rfc3339(r.StartedAt),
+		FinishedAt:   rfc3339(r.FinishedAt),
+		LastActivity: rfc3339(r.LastActivity),
+	}
+	if s, ok := m.Get(r.Code); ok {
+		info := s.Info()
+		l.Players, l.Names = info.Players, info.Names
+	}
+	return l
+}
+
+// newerRules returns the version of a game's rules new matches play by,
+// when a match under version would not. Waiting sessions, with no match
+// yet, have none newer.
+func (m *Manager) newerRules(gameType string, status Status, version string) string {
+	if status == StatusWaiting {
+		return ""
+	}
+	g, ok := m.registry.Get(gameType)
+	if !ok || g.Info().Version == version {
+		return ""
+	}
+	return g.Info().Version
+}
+
+// isTurn reports whether playerID has a move to make in the session's
+// match. A game that panics deciding counts as no.
+func (s *Session) isTurn(playerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Status != StatusPlaying || s.Match == nil {
+		return false
+	}
+	turn := false
+	Protect(func() error {
+		turn = !s.Match.IsOver() && len(s.Match.ValidActions(playerID)) > 0
+		return nil
+	})
+	return turn
+}
@@ -0,0 +1,37 @@
+package session
+
+import (
+	"testing"
+
+	"games/internal/storage"
+)
+
+func TestManagerListSessions(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	live, _ := mgr.Create(t.Context(), "tictactoe")
+	live.AddPlayer("alice")
+	gone, _ := mgr.Create(t.Context(), "tictactoe")
+	mgr.SetPrivate(t.Context(), gone, true)
+	mgr.mu.Lock()
+	delete(mgr.sessions, gone.Code) // stored, but no longer loaded
+	mgr.mu.Unlock()
+
+	listings, err := mgr.ListSessions(t.Context(), storage.SessionFilter{Sort: storage.SortOldest})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(listings) != 2 {
+		t.Fatalf("expected both stored sessions, got %+v", listings)
+	}
+	if l := listings[0]; l.Code != live.Code || len(l.Players) != 1 || l.Status != StatusWaiting || l.CreatedAt == "" {
+		t.Fatalf("unexpected listing for the loaded session %+v", l)
+	}
+	if l := listings[1]; l.Code != gone.Code || l.Players != nil || !l.Private {
+		t.Fatalf("unexpected listing for the unloaded session %+v", l)
+	}
+	if public, _ := mgr.ListSessions(t.Context(), storage.SessionFilter{PublicOnly: true}); len(public) != 1 {
+		t.Fatalf("expected the private session left out, got %+v", public)
+	}
+}
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -9,7 +10,9 @@ import (
 	"sync"
 	"time"
 
+	"games/internal/event"
 	"games/internal/game"
+	"games/internal/metrics"
 	"games/internal/storage"
 )
 
@@ -18,35 +21,160 @@ type Manager struct {
 	mu       sync.RWMutex
 	sessions map[string]*Session
 	registry *game.Registry
-	store    *storage.Store
+	store    storage.Backend
+	events   *event.Bus
+
+	presenceMu sync.Mutex
+	online     map[string]int // player ID -> open connections
+
+	oversizeMu sync.Mutex
+	oversize   map[string]*LimitReport // by game type
+
+	conductMu sync.Mutex
+	conduct   map[string]*ConductReport // by player ID
+
+	featuresMu sync.RWMutex
+	features   map[string]int // rollout percentage by feature name
+
+	cleanupMu     sync.Mutex
+	cleanupPolicy CleanupPolicy
+	cleanups      *metrics.Counter // cleanup decisions by action and reason
+
+	seasonMu     sync.Mutex
+	seasonLength SeasonLength
+
+	surveyMu       sync.Mutex
+	surveyInterval time.Duration            // how often a player is asked; 0 for never
+	surveyed       map[string]time.Time     // when each player was last asked
+	pendingSurveys map[string]pendingSurvey // the survey each player may answer
+
+	tournamentMu sync.Mutex // one change to tournaments at a time
+	clubMu       sync.Mutex // one change to club memberships at a time
 }
 
 // NewManager creates a session manager.
-func NewManager(registry *game.Registry, store *storage.Store) *Manager {
+func NewManager(registry *game.Registry, store storage.Backend) *Manager {
 	return &Manager{
 		sessions: make(map[string]*Session),
 		registry: registry,
 		store:    store,
+		events:   event.NewBus(50),
+		online:   make(map[string]int),
+		oversize: make(map[string]*LimitReport),
+		conduct:  make(map[string]*ConductReport),
+		features: make(map[string]int),
+
+		surveyed:       make(map[string]time.Time),
+		pendingSurveys: make(map[string]pendingSurvey),
+
+		cleanupPolicy: CleanupPolicy{MaxAge: DefaultCleanupMaxAge},
+		cleanups: metrics.NewCounter("games_cleanup_sessions_total",
+			"Sessions each cleanup pass looked at, by what it did and why.", "action", "reason"),
 	}
 }
 
-// Create makes a new session and persists it.
-func (m *Manager) Create(gameType string) (*Session, error) {
-	g, ok := m.registry.Get(gameType)
-	if !ok {
-		return nil, fmt.Errorf("unknown game type: %s", gameType)
+// Events returns the bus on which session lifecycle events are published.
+func (m *Manager) Events() *event.Bus {
+	return m.events
+}
+
+// Create makes a new session with the game's default options and persists
+// it.
+func (m *Manager) Create(ctx context.Context, gameType string) (*Session, error) {
+	return m.CreateWithOptions(ctx, gameType, nil)
+}
+
+// CreateWithOptions makes a new session and persists it. Options not given
+// take their configured defaults, or the values of the variant the options
+// choose; values outside the configured ranges are rejected.
+func (m *Manager) CreateWithOptions(ctx context.Context, gameType string, options map[string]int) (*Session, error) {
+	g, err := m.playable(gameType)
+	if err != nil {
+		return nil, err
+	}
+	options, err = game.ResolveVariant(g.Info(), options)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := game.ResolveOptions(m.registry.Options(gameType), options)
+	if err != nil {
+		return nil, err
 	}
 	code := generateCode()
-	if err := m.store.CreateSession(code, gameType); err != nil {
+	if err := m.store.CreateSession(ctx, code, gameType); err != nil {
 		return nil, fmt.Errorf("persist session: %w", err)
 	}
+	optionsJSON, _ := json.Marshal(resolved)
+	if err := m.store.SetSessionOptions(ctx, code, string(optionsJSON)); err != nil {
+		return nil, fmt.Errorf("persist session options: %w", err)
+	}
 	s := NewSession(code, gameType, g)
+	s.Options = resolved
 	m.mu.Lock()
 	m.sessions[code] = s
 	m.mu.Unlock()
 	return s, nil
 }
 
+// playable returns a game new matches may start in: one registered and
+// not disabled by an operator.
+func (m *Manager) playable(gameType string) (game.Game, error) {
+	g, ok := m.registry.Get(gameType)
+	if !ok {
+		return nil, fmt.Errorf("unknown game type: %s", gameType)
+	}
+	if !m.registry.Enabled(gameType) {
+		return nil, fmt.Errorf("%s is disabled", gameType)
+	}
+	return g, nil
+}
+
+// SetTurnOrder changes how a waiting session seats players who have not
+// chosen a seat, and persists it.
+func (m *Manager) SetTurnOrder(ctx context.Context, s *Session, order TurnOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Status != StatusWaiting {
+		return fmt.Errorf("turn order can only be changed before the game starts")
+	}
+	if err := m.store.SetSessionTurnOrder(ctx, s.Code, string(order)); err != nil {
+		return fmt.Errorf("persist turn order: %w", err)
+	}
+	s.TurnOrder = order
+	return nil
+}
+
+// SetPrivate changes whether a session is left out of public listings,
+// and persists it.
+func (m *Manager) SetPrivate(ctx context.Context, s *Session, private bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := m.store.SetSessionPrivate(ctx, s.Code, private); err != nil {
+		return fmt.Errorf("persist private: %w", err)
+	}
+	s.Private = private
+	return nil
+}
+
+// SetVoteThresholds changes how many players must agree to skip or remove
+// a player in a waiting session, and persists them.
+func (m *Manager) SetVoteThresholds(ctx context.Context, s *Session, t VoteThresholds) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Status != StatusWaiting {
+		return fmt.Errorf("vote thresholds can only be changed before the game starts")
+	}
+	data, _ := json.Marshal(t)
+	if err := m.store.SetSessionVoteThresholds(ctx, s.Code, string(data)); err != nil {
+		return fmt.Errorf("persist vote thresholds: %w", err)
+	}
+	s.VoteThresholds = t
+	return nil
+}
+
 // Get returns a session by code.
 func (m *Manager) Get(code string) (*Session, bool) {
 	m.mu.RLock()
@@ -61,19 +189,103 @@ func (m *Manager) List() []Info {
 	defer m.mu.RUnlock()
 	infos := make([]Info, 0, len(m.sessions))
 	for _, s := range m.sessions {
-		infos = append(infos, s.Info())
+		info := s.Info()
+		info.NewerRules = m.newerRules(info.GameType, info.Status, info.RulesVersion)
+		infos = append(infos, info)
 	}
 	return infos
 }
 
-// SaveMatchState persists the current match state for a session.
-func (m *Manager) SaveMatchState(s *Session) error {
+// detach keeps a write that records a change already made in memory
+// going after ctx is cancelled: giving up half way because a client went
+// away would leave storage behind memory. The store's query timeout still
+// bounds it.
+func detach(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// SaveMatchState persists the current match state for a session, along
+// with its status and times, in one transaction.
+func (m *Manager) SaveMatchState(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	return m.store.WithTx(ctx, func(tx storage.Backend) error {
+		return m.saveMatchState(ctx, tx, s)
+	})
+}
+
+// Touch records activity in a session now, such as a player joining or
+// coming back, and persists it, so that after a restart cleanup measures
+// how long the session has been idle from it rather than from when the
+// session was created.
+func (m *Manager) Touch(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.Lock()
+	s.LastActivity = time.Now()
+	started, finished, active := s.StartedAt, s.FinishedAt, s.LastActivity
+	s.mu.Unlock()
+	return m.store.SetSessionTimes(ctx, s.Code, started, finished, active)
+}
+
+// SaveMatchStart persists a newly started match: its state, its start
+// position and the player roster, in one transaction, so a restart never
+// finds a playing session without them.
+func (m *Manager) SaveMatchStart(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	return m.store.WithTx(ctx, func(tx storage.Backend) error {
+		if err := m.saveMatchState(ctx, tx, s); err != nil {
+			return err
+		}
+		if err := m.saveInitialState(ctx, tx, s); err != nil {
+			return err
+		}
+		s.mu.RLock()
+		version := s.RulesVersion
+		s.mu.RUnlock()
+		if err := tx.SetSessionRulesVersion(ctx, s.Code, version); err != nil {
+			return err
+		}
+		return saveSessionPlayers(ctx, tx, s)
+	})
+}
+
+// SaveMove persists the seq-th move (1-based) of a session's history and
+// the match state it led to, in one transaction.
+func (m *Manager) SaveMove(ctx context.Context, s *Session, seq int, mv Move) error {
+	ctx = detach(ctx)
+	return m.store.WithTx(ctx, func(tx storage.Backend) error {
+		if err := appendMove(ctx, tx, s, seq, mv); err != nil {
+			return err
+		}
+		return m.saveMatchState(ctx, tx, s)
+	})
+}
+
+// SaveInitialState persists the start position of a newly started match so
+// its history can be replayed later.
+func (m *Manager) SaveInitialState(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	return m.saveInitialState(ctx, m.store, s)
+}
+
+// AppendMove persists the seq-th move (1-based) of a session's history.
+func (m *Manager) AppendMove(ctx context.Context, s *Session, seq int, mv Move) error {
+	ctx = detach(ctx)
+	return appendMove(ctx, m.store, s, seq, mv)
+}
+
+// saveMatchState stores a session's status, times and match state. A
+// state over the game's limit is refused, keeping the last one stored.
+func (m *Manager) saveMatchState(ctx context.Context, b storage.Backend, s *Session) error {
 	s.mu.RLock()
 	match := s.Match
-	status := s.Status
+	status, gameType := s.Status, s.GameType
+	started, finished, active := s.StartedAt, s.FinishedAt, s.LastActivity
 	s.mu.RUnlock()
 
-	if err := m.store.UpdateSessionStatus(s.Code, string(status)); err != nil {
+	if err := b.UpdateSessionStatus(ctx, s.Code, string(status)); err != nil {
+		return err
+	}
+	if err := b.SetSessionTimes(ctx, s.Code, started, finished, active); err != nil {
 		return err
 	}
 	if match == nil {
@@ -83,39 +295,65 @@ func (m *Manager) SaveMatchState(s *Session) error {
 	if err != nil {
 		return fmt.Errorf("marshal match state: %w", err)
 	}
-	return m.store.SaveMatchState(s.Code, string(data))
+	if err := m.checkState(gameType, s.Code, "match state", len(data)); err != nil {
+		return err
+	}
+	return b.SaveMatchState(ctx, s.Code, string(data))
+}
+
+func (m *Manager) saveInitialState(ctx context.Context, b storage.Backend, s *Session) error {
+	s.mu.RLock()
+	initial, seed, seating, salt := s.initial, s.Seed, s.seating, s.salt
+	gameType := s.GameType
+	s.mu.RUnlock()
+	if initial == nil {
+		return nil
+	}
+	data, err := initial.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("marshal initial state: %w", err)
+	}
+	if err := m.checkState(gameType, s.Code, "initial state", len(data)); err != nil {
+		return err
+	}
+	players, _ := json.Marshal(seating)
+	return b.SaveInitialState(ctx, s.Code, storage.InitialStateRow{
+		StateJSON:   string(data),
+		Seed:        seed,
+		PlayersJSON: string(players),
+		Salt:        salt,
+	})
+}
+
+func appendMove(ctx context.Context, b storage.Backend, s *Session, seq int, mv Move) error {
+	data, err := json.Marshal(mv.Action)
+	if err != nil {
+		return fmt.Errorf("marshal action: %w", err)
+	}
+	timing, err := json.Marshal(moveTiming{At: mv.At, SentAt: mv.SentAt, ActedAt: mv.ActedAt})
+	if err != nil {
+		return fmt.Errorf("marshal move timing: %w", err)
+	}
+	return b.AppendMove(ctx, s.Code, seq, mv.PlayerID, string(data), string(timing))
 }
 
 // Restore loads sessions from the database on startup.
-func (m *Manager) Restore() error {
-	rows, err := m.store.ListSessions("")
+func (m *Manager) Restore(ctx context.Context) error {
+	rows, err := m.store.ListSessions(ctx, storage.SessionFilter{})
 	if err != nil {
 		return fmt.Errorf("list sessions: %w", err)
 	}
 	for _, row := range rows {
-		if row.Status == "finished" {
+		if row.Status == "finished" && row.Party == "" && !row.Pinned && !row.Practice {
 			continue
 		}
-		g, ok := m.registry.Get(row.GameType)
-		if !ok {
-			log.Printf("skipping session %s: unknown game type %s", row.Code, row.GameType)
+		s, err := m.load(ctx, row)
+		if err != nil {
+			log.Printf("skipping session %s: %v", row.Code, err)
 			continue
 		}
-		s := NewSession(row.Code, row.GameType, g)
-		s.Status = Status(row.Status)
-
-		if row.Status == "playing" {
-			stateJSON, err := m.store.GetMatchState(row.Code)
-			if err != nil {
-				log.Printf("skipping session %s: no match state: %v", row.Code, err)
-				continue
-			}
-			match := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"_", "_"}})
-			if err := match.UnmarshalJSON([]byte(stateJSON)); err != nil {
-				log.Printf("skipping session %s: unmarshal error: %v", row.Code, err)
-				continue
-			}
-			s.Match = match
+		if s.Status == StatusFinished && !s.Pinned && !s.Practice && (s.Abandoned || !s.Party.HasNext()) {
+			continue // party over
 		}
 		m.mu.Lock()
 		m.sessions[row.Code] = s
@@ -124,44 +362,229 @@ func (m *Manager) Restore() error {
 	return nil
 }
 
-// Remove deletes a session from memory and storage.
-func (m *Manager) Remove(code string) {
+// load rebuilds a session from its stored row and match state.
+func (m *Manager) load(ctx context.Context, row storage.SessionRow) (*Session, error) {
+	g, ok := m.registry.Get(row.GameType)
+	if !ok {
+		return nil, fmt.Errorf("unknown game type %s", row.GameType)
+	}
+	s := NewSession(row.Code, row.GameType, g)
+	s.Status = Status(row.Status)
+	s.TurnOrder = TurnOrder(row.TurnOrder)
+	s.Abandoned = row.Abandoned
+	s.Private = row.Private
+	s.Pinned = row.Pinned
+	s.Practice = row.Practice
+	s.ExpireAfter = row.ExpireAfter
+	s.CreatedAt = row.CreatedAt
+	s.StartedAt = row.StartedAt
+	s.FinishedAt = row.FinishedAt
+	s.LastActivity = row.LastActivity
+	if s.LastActivity.IsZero() {
+		s.LastActivity = row.CreatedAt
+	}
+	if row.Options != "" {
+		if err := json.Unmarshal([]byte(row.Options), &s.Options); err != nil {
+			return nil, fmt.Errorf("unmarshal options: %w", err)
+		}
+	}
+	if row.VoteThresholds != "" {
+		if err := json.Unmarshal([]byte(row.VoteThresholds), &s.VoteThresholds); err != nil {
+			return nil, fmt.Errorf("unmarshal vote thresholds: %w", err)
+		}
+	}
+	if row.Handicaps != "" {
+		if err := json.Unmarshal([]byte(row.Handicaps), &s.Handicaps); err != nil {
+			return nil, fmt.Errorf("unmarshal handicaps: %w", err)
+		}
+	}
+	if row.Setup != "" {
+		s.Setup = json.RawMessage(row.Setup)
+	}
+	if row.Party != "" {
+		if err := json.Unmarshal([]byte(row.Party), &s.Party); err != nil {
+			return nil, fmt.Errorf("unmarshal party: %w", err)
+		}
+	}
+	if row.Previous != "" {
+		if err := json.Unmarshal([]byte(row.Previous), &s.previous); err != nil {
+			return nil, fmt.Errorf("unmarshal previous match: %w", err)
+		}
+	}
+	// Claimed seats still take only their secrets when players rejoin
+	if snap, err := m.loadSessionPlayers(ctx, row.Code); err == nil {
+		for id, h := range snap.Secrets {
+			if sum, err := hex.DecodeString(h); err == nil {
+				if s.secrets == nil {
+					s.secrets = make(map[string][]byte)
+				}
+				s.secrets[id] = sum
+			}
+		}
+	}
+	if row.Status == "waiting" {
+		return s, nil
+	}
+	// The match plays on by the rules it started under, if still kept
+	s.RulesVersion = row.RulesVersion
+	if rules, ok := m.registry.Rules(row.GameType, row.RulesVersion); ok {
+		g = rules
+		s.game = g
+	} else {
+		log.Printf("session %s: %s rules %q no longer registered; playing on by %q", row.Code, row.GameType, row.RulesVersion, g.Info().Version)
+	}
+
+	stateJSON, err := m.store.GetMatchState(ctx, row.Code)
+	if err != nil && s.Status == StatusErrored {
+		return s, nil // the game failed as the match started
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no match state: %w", err)
+	}
+	match, err := unmarshalMatch(g, stateJSON)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal error: %w", err)
+	}
+	s.Match = match
+	if err := m.restoreHistory(ctx, s, g); err != nil {
+		log.Printf("session %s: history unavailable: %v", row.Code, err)
+	}
+	return s, nil
+}
+
+// restoreHistory loads the start position and move log of a restored match.
+func (m *Manager) restoreHistory(ctx context.Context, s *Session, g game.Game) error {
+	row, err := m.store.GetInitialState(ctx, s.Code)
+	if err != nil {
+		return fmt.Errorf("load initial state: %w", err)
+	}
+	initial, err := unmarshalMatch(g, row.StateJSON)
+	if err != nil {
+		return fmt.Errorf("unmarshal initial state: %w", err)
+	}
+	var seating []string
+	if err := json.Unmarshal([]byte(row.PlayersJSON), &seating); err != nil {
+		return fmt.Errorf("unmarshal seating: %w", err)
+	}
+	history, err := m.loadMoves(ctx, s.Code)
+	if err != nil {
+		return err
+	}
+	s.initial = initial
+	s.History = history
+	s.Seed = row.Seed
+	s.seating = seating
+	s.salt = row.Salt
+	return nil
+}
+
+// loadMoves reads a session's move log.
+func (m *Manager) loadMoves(ctx context.Context, code string) ([]Move, error) {
+	rows, err := m.store.ListMoves(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("load moves: %w", err)
+	}
+	moves := make([]Move, 0, len(rows))
+	for _, row := range rows {
+		var action game.Action
+		if err := json.Unmarshal([]byte(row.ActionJSON), &action); err != nil {
+			return nil, fmt.Errorf("unmarshal move %d: %w", row.Seq, err)
+		}
+		mv := Move{PlayerID: row.PlayerID, Action: action, At: row.CreatedAt}
+		if row.TimingJSON != "" {
+			var timing moveTiming
+			if err := json.Unmarshal([]byte(row.TimingJSON), &timing); err != nil {
+				return nil, fmt.Errorf("unmarshal move %d timing: %w", row.Seq, err)
+			}
+			mv.At, mv.SentAt, mv.ActedAt = timing.At, timing.SentAt, timing.ActedAt
+		}
+		moves = append(moves, mv)
+	}
+	return moves, nil
+}
+
+func unmarshalMatch(g game.Game, data string) (game.Match, error) {
+	match := g.NewMatch(game.MatchConfig{PlayerIDs: []string{"_", "_"}})
+	if err := match.UnmarshalJSON([]byte(data)); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// Remove deletes a session from memory and soft-deletes it in storage, from
+// which it can be recovered with Undelete until purged.
+func (m *Manager) Remove(ctx context.Context, code string) {
+	ctx = detach(ctx)
 	m.mu.Lock()
 	delete(m.sessions, code)
 	m.mu.Unlock()
-	m.store.DeleteSession(code)
+	m.forgetSurveys(code)
+	m.store.DeleteSession(ctx, code)
+}
+
+// DeletedSessions lists soft-deleted sessions awaiting purge.
+func (m *Manager) DeletedSessions(ctx context.Context) ([]storage.SessionRow, error) {
+	return m.store.ListDeletedSessions(ctx)
 }
 
-// CleanupLoop removes stale sessions periodically.
-func (m *Manager) CleanupLoop(interval, maxAge time.Duration) {
+// Undelete recovers a soft-deleted session and loads it back into memory.
+// Player connections are not restored; players rejoin by code.
+func (m *Manager) Undelete(ctx context.Context, code string) (*Session, error) {
+	row, err := m.store.GetDeletedSession(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("no deleted session %s", code)
+	}
+	ok, err := m.store.RestoreSession(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no deleted session %s", code)
+	}
+	s, err := m.load(ctx, *row)
+	if err != nil {
+		m.store.DeleteSession(ctx, code)
+		return nil, fmt.Errorf("load session: %w", err)
+	}
+	m.mu.Lock()
+	m.sessions[code] = s
+	m.mu.Unlock()
+	return s, nil
+}
+
+// PurgeLoop permanently removes sessions soft-deleted more than retention
+// ago, checking every interval until ctx is done.
+func (m *Manager) PurgeLoop(ctx context.Context, interval, retention time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	for range ticker.C {
-		m.cleanup(maxAge)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := m.store.PurgeDeletedSessions(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("purge deleted sessions: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d deleted sessions", n)
+		}
 	}
 }
 
-func (m *Manager) cleanup(maxAge time.Duration) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	now := time.Now()
-	for code, s := range m.sessions {
-		s.mu.RLock()
-		empty := len(s.Players) == 0
-		finished := s.Status == StatusFinished
-		s.mu.RUnlock()
-
-		if finished || empty {
-			row, err := m.store.GetSession(code)
-			if err != nil {
-				delete(m.sessions, code)
-				continue
-			}
-			if now.Sub(row.CreatedAt) > maxAge || empty {
-				log.Printf("cleaning up session %s", code)
-				m.store.DeleteSession(code)
-				delete(m.sessions, code)
-			}
+// MaintainLoop compacts storage every interval until ctx is done, so
+// space freed by purged sessions and moves goes back to the file system.
+func (m *Manager) MaintainLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.store.Maintain(ctx); err != nil {
+			log.Printf("database maintenance: %v", err)
 		}
 	}
 }
@@ -176,9 +599,20 @@ func generateCode() string {
 type sessionSnapshot struct {
 	Players []string `json:"players"`
 	HostID  string   `json:"hostId"`
+	// Secrets are the hashes of the seats' secrets, hex-encoded, by
+	// player ID.
+	Secrets map[string]string `json:"secrets,omitempty"`
+}
+
+// SaveSessionPlayers persists a session's roster, which also indexes the
+// session under each of its players for PlayerSessions, with what is kept
+// of the seats' secrets.
+func (m *Manager) SaveSessionPlayers(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	return saveSessionPlayers(ctx, m.store, s)
 }
 
-func (m *Manager) SaveSessionPlayers(s *Session) error {
+func saveSessionPlayers(ctx context.Context, b storage.Backend, s *Session) error {
 	s.mu.RLock()
 	snap := sessionSnapshot{
 		Players: make([]string, 0, len(s.Players)),
@@ -187,13 +621,22 @@ func (m *Manager) SaveSessionPlayers(s *Session) error {
 	for id := range s.Players {
 		snap.Players = append(snap.Players, id)
 	}
+	for id, sum := range s.secrets {
+		if snap.Secrets == nil {
+			snap.Secrets = make(map[string]string)
+		}
+		snap.Secrets[id] = hex.EncodeToString(sum)
+	}
 	s.mu.RUnlock()
 	data, _ := json.Marshal(snap)
-	return m.store.SaveMatchState(s.Code+"_players", string(data))
+	if err := b.SaveMatchState(ctx, s.Code+"_players", string(data)); err != nil {
+		return err
+	}
+	return b.SetSessionPlayers(ctx, s.Code, snap.Players)
 }
 
-func (m *Manager) loadSessionPlayers(code string) (sessionSnapshot, error) {
-	data, err := m.store.GetMatchState(code + "_players")
+func (m *Manager) loadSessionPlayers(ctx context.Context, code string) (sessionSnapshot, error) {
+	data, err := m.store.GetMatchState(ctx, code+"_players")
 	if err != nil {
 		return sessionSnapshot{}, err
 	}
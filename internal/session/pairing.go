@@ -0,0 +1,289 @@
+package session
+
+import (
+	"cmp"
+	"math/bits"
+	"slices"
+
+	"games/internal/storage"
+)
+
+// swissSearchBudget bounds the search for a Swiss round without rematches;
+// past it the round is paired down the standings, rematches and all.
+const swissSearchBudget = 100000
+
+// Standing is a player's place in a tournament, with the tie-breaks that
+// separate players on the same score.
+type Standing struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"playerId"`
+	Score    float64 `json:"score"` // a point a win or bye, half a draw
+	Played   int     `json:"played"`
+	Wins     int     `json:"wins"`
+	Draws    int     `json:"draws"`
+	Losses   int     `json:"losses"` // games nobody won count as lost by both
+	Byes     int     `json:"byes"`
+	// Buchholz is the sum of the opponents' scores.
+	Buchholz float64 `json:"buchholz"`
+	// SonnebornBerger is the sum of the scores of the opponents beaten,
+	// and half those of the opponents drawn with.
+	SonnebornBerger float64 `json:"sonnebornBerger"`
+}
+
+// record is a player's standing as it is tallied, and the points they
+// scored against each opponent.
+type record struct {
+	Standing
+	seed   int // order of entry
+	versus []versus
+}
+
+type versus struct {
+	opponent string
+	points   float64
+}
+
+// tally counts up the finished games and byes of a tournament's players.
+func tally(players []string, pairings []storage.TournamentPairingRow) map[string]*record {
+	recs := make(map[string]*record, len(players))
+	for i, id := range players {
+		recs[id] = &record{Standing: Standing{PlayerID: id}, seed: i}
+	}
+	play := func(id, opponent string, points float64) {
+		r := recs[id]
+		if r == nil {
+			return
+		}
+		r.Played++
+		r.Score += points
+		switch points {
+		case 1:
+			r.Wins++
+		case 0.5:
+			r.Draws++
+		default:
+			r.Losses++
+		}
+		r.versus = append(r.versus, versus{opponent: opponent, points: points})
+	}
+	for _, p := range pairings {
+		switch p.Result {
+		case "bye":
+			if r := recs[p.PlayerA]; r != nil {
+				r.Score++
+				r.Byes++
+			}
+		case "a":
+			play(p.PlayerA, p.PlayerB, 1)
+			play(p.PlayerB, p.PlayerA, 0)
+		case "b":
+			play(p.PlayerA, p.PlayerB, 0)
+			play(p.PlayerB, p.PlayerA, 1)
+		case "draw":
+			play(p.PlayerA, p.PlayerB, 0.5)
+			play(p.PlayerB, p.PlayerA, 0.5)
+		case "none":
+			play(p.PlayerA, p.PlayerB, 0)
+			play(p.PlayerB, p.PlayerA, 0)
+		}
+	}
+	return recs
+}
+
+// standings ranks a tournament's players by score, then by the tie-breaks
+// that suit the format: Buchholz first in a Swiss, where it measures how
+// hard a player's draw was, and Sonneborn-Berger first in a round robin,
+// where everyone meets the same opponents. Players level on all of them
+// share a rank.
+func standings(format string, players []string, pairings []storage.TournamentPairingRow) []Standing {
+	recs := tally(players, pairings)
+	for _, r := range recs {
+		for _, v := range r.versus {
+			if opp := recs[v.opponent]; opp != nil {
+				r.Buchholz += opp.Score
+				r.SonnebornBerger += v.points * opp.Score
+			}
+		}
+	}
+	ordered := make([]*record, 0, len(players))
+	for _, id := range players {
+		ordered = append(ordered, recs[id])
+	}
+	level := func(a, b *record) int {
+		first, second := a.Buchholz, a.SonnebornBerger
+		otherFirst, otherSecond := b.Buchholz, b.SonnebornBerger
+		if format == FormatRoundRobin {
+			first, second, otherFirst, otherSecond = second, first, otherSecond, otherFirst
+		}
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(otherFirst, first),
+			cmp.Compare(otherSecond, second),
+		)
+	}
+	slices.SortStableFunc(ordered, func(a, b *record) int {
+		return cmp.Or(level(a, b), cmp.Compare(a.seed, b.seed))
+	})
+	result := make([]Standing, len(ordered))
+	for i, r := range ordered {
+		r.Rank = i + 1
+		if i > 0 && level(ordered[i-1], r) == 0 {
+			r.Rank = result[i-1].Rank
+		}
+		result[i] = r.Standing
+	}
+	return result
+}
+
+// roundRobinRounds is how many rounds a round robin between n players
+// takes: one fewer than n, or n when odd, each player sitting out once.
+func roundRobinRounds(n int) int {
+	if n%2 == 1 {
+		return n
+	}
+	return n - 1
+}
+
+// swissRounds is how many rounds a Swiss between n players plays unless
+// its organizer chose: enough to leave a single player on full points,
+// and never more than a round robin would.
+func swissRounds(n int) int {
+	if n < 2 {
+		return 0
+	}
+	return min(bits.Len(uint(n-1)), roundRobinRounds(n))
+}
+
+// roundRobinPairs pairs a round (from 1) of a round robin by the circle
+// method: the first player stays put while the rest move round one place
+// a round, so over every round each pair meets once. The player staying
+// put moves first in odd rounds, and of every other pair the one nearer
+// the front of the circle, which shares the first moves out evenly. A
+// player without an opponent has a bye, paired last with nobody.
+func roundRobinPairs(players []string, round int) [][2]string {
+	seats := slices.Clone(players)
+	if len(seats)%2 == 1 {
+		seats = append(seats, "")
+	}
+	n := len(seats)
+	rest := seats[1:]
+	k := (round - 1) % (n - 1)
+	circle := append([]string{seats[0]}, rest[len(rest)-k:]...)
+	circle = append(circle, rest[:len(rest)-k]...)
+
+	var pairs [][2]string
+	bye := ""
+	for i := range n / 2 {
+		a, b := circle[i], circle[n-1-i]
+		if i == 0 && round%2 == 0 {
+			a, b = b, a
+		}
+		switch {
+		case a == "":
+			bye = b
+		case b == "":
+			bye = a
+		default:
+			pairs = append(pairs, [2]string{a, b})
+		}
+	}
+	if bye != "" {
+		pairs = append(pairs, [2]string{bye, ""})
+	}
+	return pairs
+}
+
+// swissPairs pairs the next round of a Swiss: players in order of score,
+// then of entry, each paired with the highest placed player below them
+// they have not met, backtracking when that leaves the rest unpairable.
+// With an odd number the bye goes to the lowest placed player who has not
+// had one yet. Only when every pairing would repeat a game are rematches
+// allowed. Of a pair, the one who has moved first less often moves first.
+func swissPairs(players []string, pairings []storage.TournamentPairingRow) [][2]string {
+	recs := tally(players, pairings)
+	order := slices.Clone(players)
+	slices.SortStableFunc(order, func(a, b string) int { return cmp.Compare(recs[b].Score, recs[a].Score) })
+
+	met := make(map[[2]string]bool)
+	firsts := make(map[string]int)
+	for _, p := range pairings {
+		if p.PlayerB == "" {
+			continue
+		}
+		met[[2]string{p.PlayerA, p.PlayerB}] = true
+		met[[2]string{p.PlayerB, p.PlayerA}] = true
+		firsts[p.PlayerA]++
+	}
+
+	byes := []int{-1}
+	if len(order)%2 == 1 {
+		byes = nil
+		for i := len(order) - 1; i >= 0; i-- {
+			if recs[order[i]].Byes == 0 {
+				byes = append(byes, i)
+			}
+		}
+		if len(byes) == 0 {
+			byes = []int{len(order) - 1}
+		}
+	}
+	budget := swissSearchBudget
+	var pairs [][2]string
+	bye := ""
+	paired := false
+	for _, i := range byes {
+		rest := order
+		if i >= 0 {
+			rest = slices.Delete(slices.Clone(order), i, i+1)
+		}
+		if pairs, paired = pairOff(rest, met, &budget); paired {
+			if i >= 0 {
+				bye = order[i]
+			}
+			break
+		}
+	}
+	if !paired {
+		rest := order
+		if i := byes[0]; i >= 0 {
+			bye = order[i]
+			rest = slices.Delete(slices.Clone(order), i, i+1)
+		}
+		for j := 0; j+1 < len(rest); j += 2 {
+			pairs = append(pairs, [2]string{rest[j], rest[j+1]})
+		}
+	}
+
+	for i, p := range pairs {
+		if firsts[p[1]] < firsts[p[0]] {
+			pairs[i] = [2]string{p[1], p[0]}
+		}
+	}
+	if bye != "" {
+		pairs = append(pairs, [2]string{bye, ""})
+	}
+	return pairs
+}
+
+// pairOff pairs players in order, each with the first player after them
+// they have not met, backtracking as needed. It reports false if there is
+// no such pairing, or the budget of steps ran out looking for one.
+func pairOff(order []string, met map[[2]string]bool, budget *int) ([][2]string, bool) {
+	if len(order) == 0 {
+		return nil, true
+	}
+	for j := 1; j < len(order); j++ {
+		if *budget <= 0 {
+			return nil, false
+		}
+		*budget--
+		if met[[2]string{order[0], order[j]}] {
+			continue
+		}
+		rest := append(slices.Clone(order[1:j]), order[j+1:]...)
+		if pairs, ok := pairOff(rest, met, budget); ok {
+			return append([][2]string{{order[0], order[j]}}, pairs...), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"slices"
+	"testing"
+
+	"games/internal/storage"
+)
+
+func TestRoundRobinPairsEveryoneOnce(t *testing.T) {
+	for _, n := range []int{2, 4, 5, 6} {
+		players := []string{"a", "b", "c", "d", "e", "f"}[:n]
+		met := make(map[[2]string]int)
+		byes := make(map[string]int)
+		firsts := make(map[string]int)
+		for round := 1; round <= roundRobinRounds(n); round++ {
+			seen := make(map[string]bool)
+			for _, p := range roundRobinPairs(players, round) {
+				if seen[p[0]] || seen[p[1]] {
+					t.Fatalf("%d players, round %d: %v plays twice", n, round, p)
+				}
+				seen[p[0]], seen[p[1]] = true, true
+				if p[1] == "" {
+					byes[p[0]]++
+					continue
+				}
+				firsts[p[0]]++
+				met[[2]string{min(p[0], p[1]), max(p[0], p[1])}]++
+			}
+		}
+		if want := n * (n - 1) / 2; len(met) != want {
+			t.Fatalf("%d players: expected %d pairings, got %v", n, want, met)
+		}
+		for pair, times := range met {
+			if times != 1 {
+				t.Fatalf("%d players: %v met %d times", n, pair, times)
+			}
+		}
+		for _, id := range players {
+			if n%2 == 1 && byes[id] != 1 {
+				t.Fatalf("%d players: expected one bye each, got %v", n, byes)
+			}
+			if games := n - 1; firsts[id] < games/2 || firsts[id] > (games+1)/2 {
+				t.Fatalf("%d players: expected first moves shared out, got %v", n, firsts)
+			}
+		}
+	}
+}
+
+func TestSwissPairsAvoidRematches(t *testing.T) {
+	players := []string{"a", "b", "c", "d"}
+	// a beat b and c beat d; the winners meet, as do the losers
+	played := []storage.TournamentPairingRow{
+		{Round: 1, Board: 1, PlayerA: "a", PlayerB: "b", Result: "a"},
+		{Round: 1, Board: 2, PlayerA: "c", PlayerB: "d", Result: "a"},
+	}
+	pairs := swissPairs(players, played)
+	if len(pairs) != 2 || !samePair(pairs[0], "a", "c") || !samePair(pairs[1], "b", "d") {
+		t.Fatalf("expected a-c and b-d, got %v", pairs)
+	}
+	if pairs[0][0] != "a" || pairs[1][0] != "b" {
+		t.Fatalf("expected the first moves to the players who had as many and placed higher, got %v", pairs)
+	}
+
+	// Had a and c met already, a must look further down
+	played = append(played,
+		storage.TournamentPairingRow{Round: 2, Board: 1, PlayerA: "a", PlayerB: "c", Result: "draw"},
+		storage.TournamentPairingRow{Round: 2, Board: 2, PlayerA: "b", PlayerB: "d", Result: "b"},
+	)
+	pairs = swissPairs(players, played)
+	if len(pairs) != 2 || !samePair(pairs[0], "a", "d") || !samePair(pairs[1], "c", "b") {
+		t.Fatalf("expected a-d and b-c, got %v", pairs)
+	}
+}
+
+func TestSwissByeGoesToLowestWithoutOne(t *testing.T) {
+	players := []string{"a", "b", "c"}
+	played := []storage.TournamentPairingRow{
+		{Round: 1, Board: 1, PlayerA: "a", PlayerB: "b", Result: "a"},
+		{Round: 1, Board: 2, PlayerA: "c", Result: "bye"},
+	}
+	pairs := swissPairs(players, played)
+	if len(pairs) != 2 || pairs[1] != [2]string{"b", ""} {
+		t.Fatalf("expected b, last without a bye, to sit out, got %v", pairs)
+	}
+	if !samePair(pairs[0], "a", "c") {
+		t.Fatalf("expected a and c to meet, got %v", pairs)
+	}
+
+	// Once everyone has met, rematches are better than no round
+	played = append(played,
+		storage.TournamentPairingRow{Round: 2, Board: 1, PlayerA: "c", PlayerB: "a", Result: "b"},
+		storage.TournamentPairingRow{Round: 2, Board: 2, PlayerA: "b", Result: "bye"},
+		storage.TournamentPairingRow{Round: 3, Board: 1, PlayerA: "b", PlayerB: "c", Result: "draw"},
+		storage.TournamentPairingRow{Round: 3, Board: 2, PlayerA: "a", Result: "bye"},
+	)
+	if pairs := swissPairs(players, played); len(pairs) != 2 || pairs[1][1] != "" {
+		t.Fatalf("expected a round paired anyway, got %v", pairs)
+	}
+}
+
+func TestStandingsTieBreaks(t *testing.T) {
+	players := []string{"a", "b", "c", "d"}
+	played := []storage.TournamentPairingRow{
+		{Round: 1, Board: 1, PlayerA: "a", PlayerB: "b", Result: "a"},
+		{Round: 1, Board: 2, PlayerA: "c", PlayerB: "d", Result: "draw"},
+		{Round: 2, Board: 1, PlayerA: "c", PlayerB: "a", Result: "a"},
+		{Round: 2, Board: 2, PlayerA: "b", PlayerB: "d", Result: "none"},
+		{Round: 3, Board: 1, PlayerA: "d", PlayerB: "a", Result: ""},
+	}
+	got := standings(FormatSwiss, players, played)
+	// c 1.5, a 1, b 0, d 0.5
+	if ids := standingIDs(got); !slices.Equal(ids, []string{"c", "a", "d", "b"}) {
+		t.Fatalf("expected c, a, d, b, got %+v", got)
+	}
+	c, a := got[0], got[1]
+	if c.Score != 1.5 || c.Wins != 1 || c.Draws != 1 || c.Played != 2 {
+		t.Fatalf("unexpected record for c: %+v", c)
+	}
+	// c met d (0.5) and a (1); c beat a and drew with d
+	if c.Buchholz != 1.5 || c.SonnebornBerger != 1.25 {
+		t.Fatalf("expected c's tie-breaks 1.5 and 1.25, got %+v", c)
+	}
+	if a.Buchholz != 1.5 || a.SonnebornBerger != 0 || a.Losses != 1 {
+		t.Fatalf("expected a's tie-breaks 1.5 and 0, got %+v", a)
+	}
+	if b := got[3]; b.Losses != 2 || b.Score != 0 {
+		t.Fatalf("expected a game nobody won lost by both, got %+v", b)
+	}
+
+	// Level on everything, players share a rank
+	level := standings(FormatRoundRobin, []string{"x", "y"}, []storage.TournamentPairingRow{
+		{Round: 1, Board: 1, PlayerA: "x", PlayerB: "y", Result: "draw"},
+	})
+	if level[0].Rank != 1 || level[1].Rank != 1 || level[0].PlayerID != "x" {
+		t.Fatalf("expected a shared first place in entry order, got %+v", level)
+	}
+}
+
+func TestStandingsOrderByFormat(t *testing.T) {
+	// a and b finish level on 2: a's opponents scored more (Buchholz),
+	// but b beat the stronger one (Sonneborn-Berger)
+	players := []string{"a", "b", "c", "d", "e"}
+	played := []storage.TournamentPairingRow{
+		{Round: 1, Board: 1, PlayerA: "a", PlayerB: "c", Result: "a"},
+		{Round: 1, Board: 2, PlayerA: "b", PlayerB: "e", Result: "a"},
+		{Round: 2, Board: 1, PlayerA: "a", PlayerB: "d", Result: "a"},
+		{Round: 2, Board: 2, PlayerA: "e", PlayerB: "c", Result: "a"},
+		{Round: 2, Board: 3, PlayerA: "b", Result: "bye"},
+		{Round: 3, Board: 1, PlayerA: "e", PlayerB: "a", Result: "a"},
+		{Round: 3, Board: 2, PlayerA: "d", PlayerB: "c", Result: "draw"},
+	}
+	swiss := standingIDs(standings(FormatSwiss, players, played))
+	robin := standingIDs(standings(FormatRoundRobin, players, played))
+	if slices.Index(swiss, "a") > slices.Index(swiss, "b") {
+		t.Fatalf("expected Buchholz to put a ahead in a Swiss, got %v", swiss)
+	}
+	if slices.Index(robin, "b") > slices.Index(robin, "a") {
+		t.Fatalf("expected Sonneborn-Berger to put b ahead in a round robin, got %v", robin)
+	}
+}
+
+func samePair(p [2]string, x, y string) bool {
+	return p == [2]string{x, y} || p == [2]string{y, x}
+}
+
+func standingIDs(standings []Standing) []string {
+	ids := make([]string, len(standings))
+	for i, s := range standings {
+		ids[i] = s.PlayerID
+	}
+	return ids
+}
@@ -0,0 +1,175 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"games/internal/game"
+)
+
+// Party is a queue of games played back to back by the same roster, with
+// points carried from game to game.
+type Party struct {
+	Games     []string              `json:"games"`
+	Round     int                   `json:"round"`     // index into Games of the current game
+	Standings map[string]int        `json:"standings"` // player ID -> points
+	Rounds    [][]game.PlayerResult `json:"rounds"`    // results of finished games, in order
+}
+
+// HasNext reports whether games remain after the current one.
+func (p *Party) HasNext() bool {
+	return p != nil && p.Round+1 < len(p.Games)
+}
+
+func (p *Party) clone() *Party {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Games = append([]string(nil), p.Games...)
+	c.Standings = make(map[string]int, len(p.Standings))
+	for id, pts := range p.Standings {
+		c.Standings[id] = pts
+	}
+	c.Rounds = append([][]game.PlayerResult(nil), p.Rounds...)
+	return &c
+}
+
+// partyPoints awards each player one point per player they finished level
+// with or ahead of, so a winner of a two-player game scores 2, the loser 1,
+// and a draw 2 each.
+func partyPoints(results []game.PlayerResult) map[string]int {
+	points := make(map[string]int, len(results))
+	for _, r := range results {
+		points[r.PlayerID] = len(results) - r.Rank + 1
+	}
+	return points
+}
+
+// CreateParty makes a session that plays games in order with one roster.
+// Each game uses its default options.
+func (m *Manager) CreateParty(ctx context.Context, games []string) (*Session, error) {
+	if len(games) < 2 {
+		return nil, fmt.Errorf("a party needs at least two games")
+	}
+	for _, name := range games {
+		if _, err := m.playable(name); err != nil {
+			return nil, err
+		}
+	}
+	s, err := m.Create(ctx, games[0])
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.Party = &Party{Games: games, Standings: make(map[string]int), Rounds: [][]game.PlayerResult{}}
+	s.mu.Unlock()
+	if err := m.SaveParty(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RecordPartyRoundLocked adds a finished match's results to the party
+// standings. The caller must hold the session lock. It does nothing for
+// sessions that are not parties.
+func (s *Session) RecordPartyRoundLocked(results []game.PlayerResult) {
+	if s.Party == nil {
+		return
+	}
+	s.Party.Rounds = append(s.Party.Rounds, results)
+	for id, pts := range partyPoints(results) {
+		s.Party.Standings[id] += pts
+	}
+}
+
+// SaveParty persists a party session's queue and standings.
+func (m *Manager) SaveParty(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.RLock()
+	data, err := json.Marshal(s.Party)
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("marshal party: %w", err)
+	}
+	return m.store.SetSessionParty(ctx, s.Code, string(data))
+}
+
+// NextPartyGame moves a finished party round on to the next game, keeping
+// the roster, and leaves the session waiting to be started. Bots switch to
+// the strategy of the same name in the new game, or its easiest one.
+func (m *Manager) NextPartyGame(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.Lock()
+	if s.Party == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("not a party session")
+	}
+	if s.Status != StatusFinished {
+		s.mu.Unlock()
+		return fmt.Errorf("current game is not finished")
+	}
+	if !s.Party.HasNext() {
+		s.mu.Unlock()
+		return fmt.Errorf("no games left in the party")
+	}
+	next := s.Party.Games[s.Party.Round+1]
+	g, err := m.playable(next)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	info := g.Info()
+	if n := len(s.Players); n < info.MinPlayers || n > info.MaxPlayers {
+		s.mu.Unlock()
+		return fmt.Errorf("%s needs %d-%d players, party has %d", next, info.MinPlayers, info.MaxPlayers, n)
+	}
+	strategies := make(map[string]game.Strategy)
+	for id, p := range s.Players {
+		if p.Strategy == nil {
+			continue
+		}
+		st, ok := m.registry.Strategy(next, p.Strategy.Info().Name)
+		if !ok {
+			available := m.registry.Strategies(next)
+			if len(available) == 0 {
+				s.mu.Unlock()
+				return fmt.Errorf("%s has no bots to replace %s", next, id)
+			}
+			st, _ = m.registry.Strategy(next, available[0].Name)
+		}
+		strategies[id] = st
+	}
+	options, err := game.ResolveOptions(m.registry.Options(next), nil)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	for id, st := range strategies {
+		s.Players[id].Strategy = st
+	}
+	s.resetMatchLocked()
+	s.game = g
+	s.GameType = next
+	s.Options = options
+	s.Handicaps = nil // named for the last game; the host sets them again
+	s.Setup = nil
+	s.Party.Round++
+	party, _ := json.Marshal(s.Party)
+	previous, _ := json.Marshal(s.previous)
+	s.mu.Unlock()
+
+	optionsJSON, _ := json.Marshal(options)
+	if err := m.store.NextRound(ctx, s.Code, next, string(optionsJSON), string(party), string(previous)); err != nil {
+		return fmt.Errorf("persist party round: %w", err)
+	}
+	if err := m.store.SetSessionHandicaps(ctx, s.Code, ""); err != nil {
+		return fmt.Errorf("persist party round: %w", err)
+	}
+	if err := m.store.SetSessionSetup(ctx, s.Code, ""); err != nil {
+		return fmt.Errorf("persist party round: %w", err)
+	}
+	return nil
+}
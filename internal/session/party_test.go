@@ -0,0 +1,120 @@
+package session
+
+import (
+	"testing"
+
+	"games/internal/event"
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+func TestPartyPoints(t *testing.T) {
+	pts := partyPoints([]game.PlayerResult{{PlayerID: "a", Rank: 1}, {PlayerID: "b", Rank: 2}})
+	if pts["a"] != 2 || pts["b"] != 1 {
+		t.Fatalf("expected winner 2 and loser 1, got %v", pts)
+	}
+	pts = partyPoints([]game.PlayerResult{{PlayerID: "a", Rank: 1}, {PlayerID: "b", Rank: 1}})
+	if pts["a"] != 2 || pts["b"] != 2 {
+		t.Fatalf("expected 2 each for a draw, got %v", pts)
+	}
+}
+
+func TestPartyRoundsSurviveRestart(t *testing.T) {
+	store, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(tictactoe.TicTacToe{})
+	mgr := NewManager(reg, store)
+
+	if _, err := mgr.CreateParty(t.Context(), []string{"tictactoe"}); err == nil {
+		t.Fatal("expected error for a one-game party")
+	}
+	if _, err := mgr.CreateParty(t.Context(), []string{"tictactoe", "chess"}); err == nil {
+		t.Fatal("expected error for unknown game")
+	}
+	sess, err := mgr.CreateParty(t.Context(), []string{"tictactoe", "tictactoe"})
+	if err != nil {
+		t.Fatalf("create party: %v", err)
+	}
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := mgr.NextPartyGame(t.Context(), sess); err == nil {
+		t.Fatal("expected error advancing before the game finished")
+	}
+	sess.Start()
+
+	sess.Lock()
+	sess.Status = StatusFinished
+	sess.RecordPartyRoundLocked([]game.PlayerResult{{PlayerID: "alice", Rank: 1}, {PlayerID: "bob", Rank: 2}})
+	sess.Unlock()
+	mgr.SaveMatchState(t.Context(), sess)
+	mgr.SaveParty(t.Context(), sess)
+
+	// A finished round of an unfinished party is restored.
+	mgr2 := NewManager(reg, store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	sess2, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("party session not restored between rounds")
+	}
+	if sess2.Party.Standings["alice"] != 2 {
+		t.Fatalf("expected standings restored, got %v", sess2.Party.Standings)
+	}
+
+	if err := mgr.NextPartyGame(t.Context(), sess); err != nil {
+		t.Fatalf("next game: %v", err)
+	}
+	if sess.Status != StatusWaiting || sess.Party.Round != 1 || sess.Match != nil {
+		t.Fatalf("expected fresh second round, got status %s round %d", sess.Status, sess.Party.Round)
+	}
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start second game: %v", err)
+	}
+	sess.Finish()
+	if err := mgr.NextPartyGame(t.Context(), sess); err == nil {
+		t.Fatal("expected error advancing past the last game")
+	}
+}
+
+func TestHumanPlayersWhileBotsSwitchGames(t *testing.T) {
+	store, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(tictactoe.TicTacToe{})
+	reg.RegisterStrategy("tictactoe", tictactoe.RandomBot{})
+	mgr := NewManager(reg, store)
+
+	sess, err := mgr.CreateParty(t.Context(), []string{"tictactoe", "tictactoe"})
+	if err != nil {
+		t.Fatalf("create party: %v", err)
+	}
+	sess.AddPlayer("alice")
+	botID, err := sess.AddBot(tictactoe.RandomBot{})
+	if err != nil {
+		t.Fatalf("add bot: %v", err)
+	}
+	sess.Start()
+	sess.Finish()
+
+	// The bot's strategy is replaced under the lock as the party moves
+	// on, while a finished match's recipients are worked out.
+	done := make(chan error)
+	go func() { done <- mgr.NextPartyGame(t.Context(), sess) }()
+	e := event.Event{SessionCode: sess.Code, Players: []string{"alice", botID}}
+	humans := mgr.humanPlayers(e)
+	if err := <-done; err != nil {
+		t.Fatalf("next game: %v", err)
+	}
+	if len(humans) != 1 || humans[0] != "alice" {
+		t.Fatalf("expected only alice, got %v", humans)
+	}
+}
@@ -0,0 +1,211 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"games/internal/game"
+	"games/internal/storage"
+)
+
+// ErrNotPractice is returned for practice moves tried in other sessions.
+var ErrNotPractice = errors.New("only practice sessions allow this")
+
+// PracticeSeat is the ID of the n-th seat (from 2) that a practising
+// player plays for themselves, when no bot takes it.
+func PracticeSeat(playerID string, n int) string {
+	return fmt.Sprintf("%s~%d", playerID, n)
+}
+
+// CreatePractice starts a practice session of the game for playerID, who
+// plays every other seat too or, given an opponent strategy, plays against
+// that bot. The session is private and nobody else may join or watch it,
+// so its code is never worth sharing.
+func (m *Manager) CreatePractice(ctx context.Context, gameType, playerID, opponent string, options map[string]int) (*Session, error) {
+	var strategy game.Strategy
+	if opponent != "" {
+		var ok bool
+		if strategy, ok = m.registry.Strategy(gameType, opponent); !ok {
+			return nil, fmt.Errorf("unknown bot strategy: %s", opponent)
+		}
+	}
+	s, err := m.CreateWithOptions(ctx, gameType, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.SetSessionPractice(ctx, s.Code, true); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, fmt.Errorf("persist practice: %w", err)
+	}
+	s.Practice = true
+	if err := m.SetPrivate(ctx, s, true); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	if err := s.AddPlayer(playerID); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	for n := 2; n <= s.game.Info().MinPlayers; n++ {
+		if strategy != nil {
+			_, err = s.AddBot(strategy)
+		} else {
+			err = s.AddPlayer(PracticeSeat(playerID, n))
+		}
+		if err != nil {
+			m.Remove(ctx, s.Code)
+			return nil, err
+		}
+	}
+	if err := m.startMatch(ctx, s); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	return s, nil
+}
+
+// ActsFor reports whether playerID may move for seat: their own, and in a
+// practice session the host may move for every seat but a bot's.
+func (s *Session) ActsFor(playerID, seat string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.actsForLocked(playerID, seat)
+}
+
+func (s *Session) actsForLocked(playerID, seat string) bool {
+	if playerID == seat {
+		return true
+	}
+	p := s.Players[seat]
+	return s.Practice && playerID == s.HostID && p != nil && p.Strategy == nil
+}
+
+// SeatToPlayLocked returns the seat playerID plays for now: their own,
+// unless it has nothing to do in a practice session and another seat they
+// play for does. The caller must hold the lock.
+func (s *Session) SeatToPlayLocked(playerID string) string {
+	if !s.Practice || s.Match == nil || s.Status != StatusPlaying || len(s.Match.ValidActions(playerID)) > 0 {
+		return playerID
+	}
+	for _, seat := range s.seating {
+		if seat != playerID && s.actsForLocked(playerID, seat) && len(s.Match.ValidActions(seat)) > 0 {
+			return seat
+		}
+	}
+	return playerID
+}
+
+// SeatToPlay is SeatToPlayLocked for callers not holding the lock.
+func (s *Session) SeatToPlay(playerID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.SeatToPlayLocked(playerID)
+}
+
+// Undo takes back the last move a person made in a practice match, with
+// any bot moves after it, and persists the match as it was before. It
+// replays the rest of the history from the start position and returns how
+// many moves it took back. A finished match is back in play.
+func (m *Manager) Undo(ctx context.Context, s *Session) (int, error) {
+	ctx = detach(ctx)
+	s.mu.Lock()
+	if err := s.practiceLocked(); err != nil {
+		s.mu.Unlock()
+		return 0, err
+	}
+	keep := len(s.History) - 1
+	for keep >= 0 && s.isBotLocked(s.History[keep].PlayerID) {
+		keep--
+	}
+	if keep < 0 {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("no moves to undo")
+	}
+	match := s.initial.Clone()
+	err := Protect(func() error {
+		for i, mv := range s.History[:keep] {
+			if _, err := game.ApplyAt(match, mv.PlayerID, mv.Action, mv.Time()); err != nil {
+				return fmt.Errorf("replay move %d by %s: %w", i+1, mv.PlayerID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.mu.Unlock()
+		return 0, err
+	}
+	taken := len(s.History) - keep
+	s.Match = match
+	s.History = s.History[:keep]
+	s.resumePracticeLocked()
+	s.mu.Unlock()
+
+	return taken, m.store.WithTx(ctx, func(tx storage.Backend) error {
+		if err := tx.TruncateMoves(ctx, s.Code, keep); err != nil {
+			return err
+		}
+		return m.saveMatchState(ctx, tx, s)
+	})
+}
+
+// SetUpPosition sets a practice match up in the given position, written
+// as the game documents, and makes it the start position: the history so
+// far is dropped, and undo goes back no further.
+func (m *Manager) SetUpPosition(ctx context.Context, s *Session, position json.RawMessage) error {
+	ctx = detach(ctx)
+	s.mu.Lock()
+	if err := s.practiceLocked(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	match := s.Match.Clone()
+	if err := Protect(func() error { return game.SetPosition(match, position) }); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.Match = match
+	s.initial = match.Clone()
+	s.History = nil
+	s.resumePracticeLocked()
+	s.mu.Unlock()
+
+	return m.store.WithTx(ctx, func(tx storage.Backend) error {
+		if err := tx.TruncateMoves(ctx, s.Code, 0); err != nil {
+			return err
+		}
+		if err := m.saveMatchState(ctx, tx, s); err != nil {
+			return err
+		}
+		return m.saveInitialState(ctx, tx, s)
+	})
+}
+
+// practiceLocked returns why the session's match cannot be rewound or set
+// up, or nil if it can. The caller must hold the lock.
+func (s *Session) practiceLocked() error {
+	switch {
+	case !s.Practice:
+		return ErrNotPractice
+	case s.Match == nil || s.initial == nil:
+		return fmt.Errorf("game not started")
+	case s.Status == StatusErrored:
+		return fmt.Errorf("game stopped after an error")
+	}
+	return nil
+}
+
+// resumePracticeLocked puts a practice match that was rewound or set up
+// back in play. The caller must hold the write lock.
+func (s *Session) resumePracticeLocked() {
+	s.Status = StatusPlaying
+	s.FinishedAt = time.Time{}
+	s.LastActivity = time.Now()
+}
+
+func (s *Session) isBotLocked(playerID string) bool {
+	p := s.Players[playerID]
+	return p != nil && p.Strategy != nil
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"games/internal/game/tictactoe"
+)
+
+// practiceMove applies and persists a move the way the server does.
+func practiceMove(t *testing.T, mgr *Manager, sess *Session, pid string, cell int) {
+	t.Helper()
+	sess.mu.Lock()
+	mv := Move{PlayerID: pid, Action: cellAction(cell), At: time.Now()}
+	if err := sess.Match.ApplyAction(pid, mv.Action); err != nil {
+		sess.mu.Unlock()
+		t.Fatalf("%s at %d: %v", pid, cell, err)
+	}
+	sess.History = append(sess.History, mv)
+	seq := len(sess.History)
+	if sess.Match.IsOver() {
+		sess.Status = StatusFinished
+		sess.FinishedAt = mv.At
+	}
+	sess.mu.Unlock()
+	if err := mgr.SaveMove(t.Context(), sess, seq, mv); err != nil {
+		t.Fatalf("save move: %v", err)
+	}
+}
+
+func TestPracticeUndo(t *testing.T) {
+	mgr := setupBotTest(t)
+	sess, err := mgr.CreatePractice(t.Context(), "tictactoe", "alice", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := sess.Info()
+	if !info.Practice || !info.Private || info.Status != StatusPlaying || len(info.Players) != 2 || info.Players[1] != "alice~2" {
+		t.Fatalf("expected alice playing both seats in private, got %+v", info)
+	}
+	if !sess.ActsFor("alice", "alice~2") || sess.ActsFor("bob", "alice~2") || sess.ActsFor("alice~2", "alice") {
+		t.Fatal("expected only alice to act for her second seat")
+	}
+
+	x := sess.PlayerIDs()[0]
+	o := "alice"
+	if x == o {
+		o = "alice~2"
+	}
+	practiceMove(t, mgr, sess, x, 0)
+	if seat := sess.SeatToPlay("alice"); seat != o {
+		t.Fatalf("expected alice to play %s now, got %s", o, seat)
+	}
+	for i, cell := range []int{3, 1, 4, 2} {
+		practiceMove(t, mgr, sess, []string{o, x}[i%2], cell)
+	}
+	if sess.Info().Status != StatusFinished {
+		t.Fatal("expected the line to finish the match")
+	}
+
+	if taken, err := mgr.Undo(t.Context(), sess); err != nil || taken != 1 {
+		t.Fatalf("expected one move taken back, got %d %v", taken, err)
+	}
+	m := sess.Match.(*tictactoe.Match)
+	if sess.Info().Status != StatusPlaying || m.Done || m.Board[2] != 0 || len(sess.History) != 4 {
+		t.Fatalf("expected the winning move gone, got %+v after %d moves", m, len(sess.History))
+	}
+	if moves, _ := mgr.store.ListMoves(t.Context(), sess.Code); len(moves) != 4 {
+		t.Fatalf("expected 4 stored moves, got %d", len(moves))
+	}
+	for range 4 {
+		if _, err := mgr.Undo(t.Context(), sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mgr.Undo(t.Context(), sess); err == nil {
+		t.Fatal("expected nothing left to undo")
+	}
+
+	plain, _ := mgr.Create(t.Context(), "tictactoe")
+	if _, err := mgr.Undo(t.Context(), plain); !errors.Is(err, ErrNotPractice) {
+		t.Fatalf("expected undo refused outside practice, got %v", err)
+	}
+}
+
+func TestPracticeUndoAgainstBot(t *testing.T) {
+	mgr := setupBotTest(t)
+	sess, err := mgr.CreatePractice(t.Context(), "tictactoe", "alice", "random", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot := "bot-random"
+	if sess.ActsFor("alice", bot) {
+		t.Fatal("expected alice not to move for the bot")
+	}
+	first := sess.PlayerIDs()[0]
+	if first == bot {
+		practiceMove(t, mgr, sess, bot, 4)
+	}
+	practiceMove(t, mgr, sess, "alice", 0)
+	practiceMove(t, mgr, sess, bot, 8)
+	before := len(sess.History)
+	if taken, err := mgr.Undo(t.Context(), sess); err != nil || taken != 2 {
+		t.Fatalf("expected alice's move and the bot's reply taken back, got %d %v", taken, err)
+	}
+	if len(sess.History) != before-2 {
+		t.Fatalf("expected %d moves left, got %d", before-2, len(sess.History))
+	}
+}
+
+func TestPracticeSetUpPosition(t *testing.T) {
+	mgr := setupBotTest(t)
+	sess, err := mgr.CreatePractice(t.Context(), "tictactoe", "alice", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	practiceMove(t, mgr, sess, sess.PlayerIDs()[0], 4)
+	if err := mgr.SetUpPosition(t.Context(), sess, json.RawMessage(`{"board":"XXX......"}`)); err == nil {
+		t.Fatal("expected a finished position refused")
+	}
+	if err := mgr.SetUpPosition(t.Context(), sess, json.RawMessage(`{"board":"XO.XO...."}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.History) != 0 {
+		t.Fatalf("expected the history dropped, got %d moves", len(sess.History))
+	}
+	if _, err := mgr.Undo(t.Context(), sess); err == nil {
+		t.Fatal("expected undo to stop at the set-up position")
+	}
+	if moves, _ := mgr.store.ListMoves(t.Context(), sess.Code); len(moves) != 0 {
+		t.Fatalf("expected the stored moves dropped, got %d", len(moves))
+	}
+	stored, err := mgr.StoredMatch(t.Context(), sess.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, _ := stored.MatchAt(0)
+	if start.(*tictactoe.Match).Board != [9]int{1, 2, 0, 1, 2, 0, 0, 0, 0} {
+		t.Fatalf("expected the set-up position stored as the start, got %+v", start)
+	}
+}
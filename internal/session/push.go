@@ -0,0 +1,27 @@
+package session
+
+import (
+	"context"
+	"fmt"
+
+	"games/internal/storage"
+)
+
+// SavePushSubscription registers a browser to receive Web Push
+// notifications for playerID.
+func (m *Manager) SavePushSubscription(ctx context.Context, sub storage.PushSubscriptionRow) error {
+	if sub.PlayerID == "" || sub.Endpoint == "" || sub.P256dh == "" || sub.Auth == "" {
+		return fmt.Errorf("playerId, endpoint and keys required")
+	}
+	return m.store.SavePushSubscription(ctx, sub)
+}
+
+// PushSubscriptions returns the browsers subscribed for playerID.
+func (m *Manager) PushSubscriptions(ctx context.Context, playerID string) ([]storage.PushSubscriptionRow, error) {
+	return m.store.ListPushSubscriptions(ctx, playerID)
+}
+
+// DeletePushSubscription unregisters a browser.
+func (m *Manager) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	return m.store.DeletePushSubscription(ctx, endpoint)
+}
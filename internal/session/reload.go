@@ -0,0 +1,84 @@
+package session
+
+import (
+	"context"
+	"io"
+	"log"
+	"reflect"
+	"time"
+
+	"games/internal/game"
+)
+
+// Upgrade moves the waiting sessions of gameType onto the version of the
+// game registered now, after it is replaced in the registry, and returns
+// how many moved. Matches under way finish on the version they started
+// with; a rematch moves on to the new one.
+func (m *Manager) Upgrade(gameType string) int {
+	g, ok := m.registry.Get(gameType)
+	if !ok {
+		return 0
+	}
+	moved := 0
+	for _, s := range m.loaded() {
+		s.mu.Lock()
+		if s.GameType == gameType && s.Status == StatusWaiting && !sameGame(s.game, g) {
+			s.game = g
+			moved++
+		}
+		s.mu.Unlock()
+	}
+	return moved
+}
+
+// Pinned returns how many sessions still need g: those waiting to start
+// on it or playing a match on it.
+func (m *Manager) Pinned(g game.Game) int {
+	n := 0
+	for _, s := range m.loaded() {
+		s.mu.RLock()
+		if (s.Status == StatusWaiting || s.Status == StatusPlaying) && sameGame(s.game, g) {
+			n++
+		}
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Retire waits, checking every interval, until no session needs a game
+// that was replaced or unregistered, then closes it if it holds a
+// resource such as a process. A match finished on it afterwards may still
+// be viewed; a game process starts again for that.
+func (m *Manager) Retire(ctx context.Context, old game.Game, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for m.Pinned(old) > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+	if c, ok := old.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("retire game %s: %v", old.Info().Name, err)
+		}
+	}
+}
+
+func (m *Manager) loaded() []*Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// sameGame reports whether a and b are the same implementation. Games
+// whose values cannot be compared are never the same.
+func sameGame(a, b game.Game) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t != nil && t.Comparable() && a == b
+}
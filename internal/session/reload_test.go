@@ -0,0 +1,69 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"games/internal/game/tictactoe"
+)
+
+// versionedGame is tic-tac-toe as a game loaded at runtime would be: each
+// load a distinct value holding a resource to close.
+type versionedGame struct {
+	tictactoe.TicTacToe
+	closed bool
+}
+
+func (g *versionedGame) Close() error {
+	g.closed = true
+	return nil
+}
+
+func TestReloadPinsMatchesUnderWay(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	v1, v2 := &versionedGame{}, &versionedGame{}
+	mgr.registry.Replace(v1)
+
+	waiting, _ := mgr.Create(t.Context(), "tictactoe")
+	playing, _ := mgr.Create(t.Context(), "tictactoe")
+	playing.AddPlayer("alice")
+	playing.AddPlayer("bob")
+	playing.Start()
+
+	mgr.registry.Replace(v2)
+	if moved := mgr.Upgrade("tictactoe"); moved != 1 || waiting.game != v2 {
+		t.Fatalf("expected the waiting session moved to the new version, moved %d", moved)
+	}
+	if playing.game != v1 || mgr.Pinned(v1) != 1 {
+		t.Fatal("expected the match under way to keep the old version")
+	}
+
+	retired := make(chan struct{})
+	go func() {
+		mgr.Retire(t.Context(), v1, time.Millisecond)
+		close(retired)
+	}()
+	select {
+	case <-retired:
+		t.Fatal("expected the old version kept while its match plays")
+	case <-time.After(20 * time.Millisecond):
+	}
+	playing.Finish()
+	select {
+	case <-retired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the old version retired once its match finished")
+	}
+	if !v1.closed || v2.closed {
+		t.Fatal("expected only the old version closed")
+	}
+
+	mgr.SaveMatchState(t.Context(), playing)
+	if err := mgr.Rematch(t.Context(), playing); err != nil {
+		t.Fatalf("rematch: %v", err)
+	}
+	if playing.game != v2 {
+		t.Fatal("expected the rematch played on the new version")
+	}
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"games/internal/game"
+)
+
+// resetMatchLocked leaves a finished session waiting for its next match,
+// remembering the one just played so the game can decide who moves first.
+// The caller must hold the write lock.
+func (s *Session) resetMatchLocked() {
+	if s.Match != nil {
+		results := abandonedResults(s.seating)
+		if !s.Abandoned {
+			results = game.Results(s.Match)
+		}
+		s.previous = &game.PreviousMatch{PlayerIDs: slices.Clone(s.seating), Results: results}
+	}
+	s.clearMatchLocked()
+}
+
+// clearMatchLocked drops the session's match and leaves it waiting. The
+// caller must hold the write lock.
+func (s *Session) clearMatchLocked() {
+	s.Match = nil
+	s.initial = nil
+	s.History = nil
+	s.Seed = 0
+	s.seating = nil
+	s.salt = ""
+	s.StartedAt = time.Time{}
+	s.FinishedAt = time.Time{}
+	s.LastActivity = time.Now()
+	s.Abandoned = false
+	s.RulesVersion = ""
+	s.Status = StatusWaiting
+}
+
+// Rematch sets a finished session up to play the same game again, with
+// the same players and options, and leaves it waiting to be started.
+// Parties move on with NextPartyGame instead.
+func (m *Manager) Rematch(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.Lock()
+	if s.Party != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("party sessions move on to their next game")
+	}
+	if s.Status != StatusFinished {
+		s.mu.Unlock()
+		return fmt.Errorf("current game is not finished")
+	}
+	g, err := m.playable(s.GameType)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.resetMatchLocked()
+	s.game = g // the version registered now, should the game have been reloaded
+	options, _ := json.Marshal(s.Options)
+	previous, _ := json.Marshal(s.previous)
+	s.mu.Unlock()
+
+	if err := m.store.NextRound(ctx, s.Code, s.GameType, string(options), "", string(previous)); err != nil {
+		return fmt.Errorf("persist rematch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRematchSwapsColors(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if err := mgr.Rematch(t.Context(), sess); err == nil {
+		t.Fatal("expected error rematching before a game is played")
+	}
+	sess.Start()
+	if info := sess.Info(); !slices.Equal(info.Seats, []string{"alice", "bob"}) {
+		t.Fatalf("expected alice to move first, got %v", info.Seats)
+	}
+	sess.Finish()
+	mgr.SaveMatchState(t.Context(), sess)
+
+	if err := mgr.Rematch(t.Context(), sess); err != nil {
+		t.Fatalf("rematch: %v", err)
+	}
+	if info := sess.Info(); info.Status != StatusWaiting || info.StartedAt != "" {
+		t.Fatalf("expected the session to wait for the rematch, got %+v", info)
+	}
+
+	// The last match survives a restart while the rematch waits.
+	mgr2 := NewManager(mgr.registry, mgr.store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("expected the waiting rematch to be restored")
+	}
+	restored.AddPlayer("alice")
+	restored.AddPlayer("bob")
+	for _, s := range []*Session{sess, restored} {
+		if err := s.Start(); err != nil {
+			t.Fatalf("start rematch: %v", err)
+		}
+		if info := s.Info(); !slices.Equal(info.Seats, []string{"bob", "alice"}) {
+			t.Fatalf("expected bob to move first in the rematch, got %v", info.Seats)
+		}
+	}
+}
+
+func TestDisabledGameStartsNothingNew(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+	sess.Finish()
+	mgr.SaveMatchState(t.Context(), sess)
+
+	mgr.registry.SetEnabled("tictactoe", false)
+	if _, err := mgr.Create(t.Context(), "tictactoe"); err == nil {
+		t.Error("expected a disabled game's session refused")
+	}
+	if _, err := mgr.IssueChallenge(t.Context(), "tictactoe", "alice", "bob"); err == nil {
+		t.Error("expected a challenge to a disabled game refused")
+	}
+	if err := mgr.Rematch(t.Context(), sess); err == nil {
+		t.Error("expected a rematch of a disabled game refused")
+	}
+	mgr.registry.SetEnabled("tictactoe", true)
+	if err := mgr.Rematch(t.Context(), sess); err != nil {
+		t.Errorf("rematch once enabled: %v", err)
+	}
+}
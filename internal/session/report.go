@@ -0,0 +1,173 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"games/internal/storage"
+)
+
+// MaxReportNote bounds the note of a problem report, in characters.
+const MaxReportNote = 2000
+
+// ReportTimeline is how many of the latest timeline entries a report
+// keeps.
+const ReportTimeline = 50
+
+var (
+	// ErrReportNotFound is returned for unknown report IDs.
+	ErrReportNotFound = errors.New("report not found")
+	// ErrNotInSession is returned for a report from someone neither
+	// playing nor watching the session.
+	ErrNotInSession = errors.New("only the session's players and spectators may report a problem")
+)
+
+// ServerVersion names the build that served a session, so a report can be
+// matched with the code that misbehaved.
+type ServerVersion struct {
+	Revision  string    `json:"revision,omitempty"` // VCS commit, when the binary recorded one
+	Modified  bool      `json:"modified,omitempty"` // built with uncommitted changes
+	BuildTime time.Time `json:"buildTime,omitzero"`
+	Go        string    `json:"go"`
+	Assets    string    `json:"assets,omitempty"` // version of the web assets
+}
+
+// Report is a problem a player reported in a session, bundled with what
+// an admin needs to look into it: the match as it stood, the latest of
+// the session's timeline, and the server's version.
+type Report struct {
+	ID           int64     `json:"id"`
+	SessionCode  string    `json:"sessionCode"`
+	GameType     string    `json:"gameType"`
+	PlayerID     string    `json:"playerId"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"createdAt"`
+	*Diagnostics           // left out of report lists
+}
+
+// Diagnostics is the session's state captured with a report.
+type Diagnostics struct {
+	Server ServerVersion `json:"server"`
+	Info   Info          `json:"info"`
+	// State is the match's full state, hidden parts included; null before
+	// it starts. An errored session's comes from the store, as it was
+	// saved after the last move.
+	State      json.RawMessage `json:"state"`
+	StateError string          `json:"stateError,omitempty"`
+	Transcript *Transcript     `json:"transcript,omitempty"`
+	Timeline   []TimelineEntry `json:"timeline"` // oldest first
+}
+
+// SubmitReport stores playerID's report of a problem in s, with the note
+// they wrote and the session's diagnostics, and returns its ID. Players
+// and spectators may report.
+func (m *Manager) SubmitReport(ctx context.Context, s *Session, playerID, note string, server ServerVersion) (int64, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return 0, fmt.Errorf("say what went wrong")
+	}
+	if utf8.RuneCountInString(note) > MaxReportNote {
+		return 0, fmt.Errorf("reports are limited to %d characters", MaxReportNote)
+	}
+	if !s.Involves(playerID) {
+		return 0, ErrNotInSession
+	}
+
+	d := m.diagnose(ctx, s)
+	d.Server = server
+	bundle, err := json.Marshal(d)
+	if err != nil {
+		return 0, fmt.Errorf("encode diagnostics: %w", err)
+	}
+	return m.store.SaveReport(detach(ctx), storage.ReportRow{
+		SessionCode: s.Code,
+		GameType:    s.GameType,
+		PlayerID:    playerID,
+		Note:        note,
+		Bundle:      string(bundle),
+	})
+}
+
+// diagnose captures s's info, match state, transcript and latest timeline.
+// What cannot be captured is left out rather than failing the report.
+func (m *Manager) diagnose(ctx context.Context, s *Session) *Diagnostics {
+	d := &Diagnostics{State: json.RawMessage("null")}
+	s.mu.RLock()
+	d.Info = s.InfoLocked()
+	errored, started := s.Status == StatusErrored, s.Match != nil
+	if started && !errored {
+		err := Protect(func() error {
+			data, err := s.Match.MarshalJSON()
+			d.State = data
+			return err
+		})
+		if err != nil {
+			d.State, d.StateError = json.RawMessage("null"), err.Error()
+		}
+	}
+	s.mu.RUnlock()
+	if started && errored {
+		if data, err := m.store.GetMatchState(ctx, s.Code); err != nil {
+			d.StateError = err.Error()
+		} else {
+			d.State = json.RawMessage(data)
+		}
+	}
+	if t, err := s.Transcript(); err == nil {
+		d.Transcript = &t
+	}
+	d.Timeline = []TimelineEntry{}
+	if all, err := m.Timeline(ctx, s.Code, 0, 0); err == nil {
+		d.Timeline = append(d.Timeline, all[max(0, len(all)-ReportTimeline):]...)
+	}
+	return d
+}
+
+// Reports returns the stored reports matching f, newest first, without
+// their diagnostics.
+func (m *Manager) Reports(ctx context.Context, f storage.ReportFilter) ([]Report, error) {
+	rows, err := m.store.ListReports(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]Report, len(rows))
+	for i, r := range rows {
+		reports[i] = reportFromRow(r)
+	}
+	return reports, nil
+}
+
+// GetReport returns a stored report with its diagnostics, or
+// ErrReportNotFound.
+func (m *Manager) GetReport(ctx context.Context, id int64) (Report, error) {
+	row, err := m.store.GetReport(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Report{}, ErrReportNotFound
+	}
+	if err != nil {
+		return Report{}, err
+	}
+	r := reportFromRow(*row)
+	r.Diagnostics = new(Diagnostics)
+	if err := json.Unmarshal([]byte(row.Bundle), r.Diagnostics); err != nil {
+		return Report{}, fmt.Errorf("decode report %d: %w", id, err)
+	}
+	return r, nil
+}
+
+func reportFromRow(r storage.ReportRow) Report {
+	return Report{
+		ID:          r.ID,
+		SessionCode: r.SessionCode,
+		GameType:    r.GameType,
+		PlayerID:    r.PlayerID,
+		Note:        r.Note,
+		CreatedAt:   r.CreatedAt,
+	}
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"games/internal/storage"
+)
+
+func TestReports(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess := playCells(t, mgr, 0, 3)
+	for i := 0; i < ReportTimeline+5; i++ {
+		mgr.RecordTimeline(t.Context(), sess.Code, TimelineChat, "alice", map[string]int{"n": i})
+	}
+	sess.AddSpectator("carol", make(chan []byte, 1))
+	version := ServerVersion{Revision: "abc123", Go: "go1.24", Assets: "0123456789ab"}
+
+	if _, err := mgr.SubmitReport(t.Context(), sess, "alice", "  ", version); err == nil {
+		t.Fatal("expected an empty note refused")
+	}
+	if _, err := mgr.SubmitReport(t.Context(), sess, "alice", strings.Repeat("x", MaxReportNote+1), version); err == nil {
+		t.Fatal("expected a long note refused")
+	}
+	if _, err := mgr.SubmitReport(t.Context(), sess, "dave", "stuck", version); !errors.Is(err, ErrNotInSession) {
+		t.Fatalf("expected a report from outside the session refused, got %v", err)
+	}
+	id, err := mgr.SubmitReport(t.Context(), sess, "alice", " the board got stuck ", version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.SubmitReport(t.Context(), sess, "carol", "it froze for me too", version); err != nil {
+		t.Fatalf("expected a spectator's report stored, got %v", err)
+	}
+
+	list, err := mgr.Reports(t.Context(), storage.ReportFilter{SessionCode: sess.Code})
+	if err != nil || len(list) != 2 || list[1].ID != id || list[1].Note != "the board got stuck" || list[1].Diagnostics != nil {
+		t.Fatalf("expected both reports newest first without diagnostics, got %+v %v", list, err)
+	}
+
+	r, err := mgr.GetReport(t.Context(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PlayerID != "alice" || r.GameType != "tictactoe" || r.Diagnostics == nil || r.Server != version {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.Info.Status != StatusPlaying || r.Transcript == nil || len(r.Transcript.Moves) != 2 {
+		t.Fatalf("expected the match in play with its two moves, got %+v", r.Diagnostics)
+	}
+	var state struct{ Board [9]int }
+	if err := json.Unmarshal(r.State, &state); err != nil || state.Board[0] == 0 || state.Board[3] == 0 {
+		t.Fatalf("expected the board with both moves, got %s %v", r.State, err)
+	}
+	if len(r.Timeline) != ReportTimeline || string(r.Timeline[len(r.Timeline)-1].Detail) != `{"n":54}` {
+		t.Fatalf("expected the latest %d timeline entries, got %d", ReportTimeline, len(r.Timeline))
+	}
+
+	if _, err := mgr.GetReport(t.Context(), id+100); !errors.Is(err, ErrReportNotFound) {
+		t.Fatalf("expected ErrReportNotFound, got %v", err)
+	}
+}
+
+func TestReportBeforeStart(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	id, err := mgr.SubmitReport(t.Context(), sess, "alice", "the start button does nothing", ServerVersion{Go: "go1.24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := mgr.GetReport(t.Context(), id)
+	if string(r.State) != "null" || r.StateError != "" || r.Transcript != nil || r.Timeline == nil {
+		t.Fatalf("expected no state or transcript before the match, got %+v", r.Diagnostics)
+	}
+}
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ReservationTimeout is how long a reserved seat is held for its player.
+const ReservationTimeout = 10 * time.Minute
+
+// Reservation holds a seat for an invited player until it expires.
+type Reservation struct {
+	PlayerID  string    `json:"playerId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Reserve replaces the session's reservations with seats for playerIDs,
+// each held for ReservationTimeout. Players already seated are skipped; an
+// empty list clears all reservations.
+func (s *Session) Reserve(playerIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Status != StatusWaiting {
+		return fmt.Errorf("session is not accepting players")
+	}
+	expires := time.Now().Add(ReservationTimeout)
+	reserved := make(map[string]time.Time)
+	for _, id := range playerIDs {
+		if id != "" && s.Players[id] == nil {
+			reserved[id] = expires
+		}
+	}
+	if max := s.game.Info().MaxPlayers; len(s.Players)+len(reserved) > max {
+		return fmt.Errorf("only %d seats left to reserve", max-len(s.Players))
+	}
+	s.reservations = reserved
+	return nil
+}
+
+// reservedForOthers counts unexpired reservations for anyone but playerID.
+// The caller must hold the lock.
+func (s *Session) reservedForOthers(playerID string, now time.Time) int {
+	n := 0
+	for id, expires := range s.reservations {
+		if id != playerID && now.Before(expires) {
+			n++
+		}
+	}
+	return n
+}
+
+// claimSeatLocked checks that playerID may take a seat, counting seats held
+// for other invited players as taken, and uses up playerID's reservation.
+// The caller must hold the write lock.
+func (s *Session) claimSeatLocked(playerID string) error {
+	now := time.Now()
+	if len(s.Players)+s.reservedForOthers(playerID, now) >= s.game.Info().MaxPlayers {
+		if len(s.Players) < s.game.Info().MaxPlayers {
+			return fmt.Errorf("remaining seats are reserved")
+		}
+		return fmt.Errorf("session is full")
+	}
+	delete(s.reservations, playerID)
+	return nil
+}
+
+// reservationsLocked lists unexpired reservations by player ID.
+func (s *Session) reservationsLocked(now time.Time) []Reservation {
+	var out []Reservation
+	for id, expires := range s.reservations {
+		if now.Before(expires) {
+			out = append(out, Reservation{PlayerID: id, ExpiresAt: expires.UTC()})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PlayerID < out[j].PlayerID })
+	return out
+}
+
+// HoldsSeat reports whether playerID is seated in the session or has a
+// seat reserved.
+func (s *Session) HoldsSeat(playerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Players[playerID] != nil {
+		return true
+	}
+	expires, ok := s.reservations[playerID]
+	return ok && time.Now().Before(expires)
+}
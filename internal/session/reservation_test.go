@@ -0,0 +1,63 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"games/internal/game/tictactoe"
+)
+
+func TestReservedSeatsHeldForInvitees(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	if err := sess.Reserve([]string{"bob", "carol"}); err == nil {
+		t.Fatal("expected error reserving more seats than are left")
+	}
+	if err := sess.Reserve([]string{"alice", "bob"}); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	info := sess.Info()
+	if info.OpenSeats != 0 || len(info.Reservations) != 1 || info.Reservations[0].PlayerID != "bob" {
+		t.Fatalf("expected bob's seat held and none open, got %+v", info)
+	}
+
+	if err := sess.AddPlayer("mallory"); err == nil {
+		t.Fatal("expected a random joiner to be turned away")
+	}
+	if _, err := sess.AddBot(nil); err == nil {
+		t.Fatal("expected a bot to be turned away")
+	}
+	if err := sess.AddPlayer("bob"); err != nil {
+		t.Fatalf("bob joins his reserved seat: %v", err)
+	}
+	if len(sess.Info().Reservations) != 0 {
+		t.Fatal("expected bob's reservation used up")
+	}
+}
+
+func TestReservationsExpire(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	sess.Reserve([]string{"bob"})
+
+	sess.Lock()
+	sess.reservations["bob"] = time.Now().Add(-time.Second)
+	sess.Unlock()
+	if info := sess.Info(); info.OpenSeats != 1 || len(info.Reservations) != 0 {
+		t.Fatalf("expected the expired seat open again, got %+v", info)
+	}
+	if err := sess.AddPlayer("carol"); err != nil {
+		t.Fatalf("carol takes the expired seat: %v", err)
+	}
+}
+
+func TestHoldsSeat(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	sess.Reserve([]string{"bob"})
+	for id, want := range map[string]bool{"alice": true, "bob": true, "carol": false} {
+		if got := sess.HoldsSeat(id); got != want {
+			t.Errorf("%s: expected %v, got %v", id, want, got)
+		}
+	}
+}
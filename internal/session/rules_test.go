@@ -0,0 +1,98 @@
+package session
+
+import (
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+// versionedRules is tic-tac-toe under a named version of its rules.
+type versionedRules struct {
+	tictactoe.TicTacToe
+	version string
+}
+
+func (g versionedRules) Info() game.GameInfo {
+	info := g.TicTacToe.Info()
+	info.Version = g.version
+	return info
+}
+
+func TestRestoreKeepsRulesVersion(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	v1, v2 := versionedRules{version: "1"}, versionedRules{version: "2"}
+	mgr.registry.Replace(v1)
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+	mgr.SaveMatchStart(t.Context(), sess)
+	if sess.Info().RulesVersion != "1" {
+		t.Fatalf("expected the match to record its rules, got %+v", sess.Info())
+	}
+
+	// The server is upgraded to the new rules, keeping the old ones
+	upgraded := game.NewRegistry()
+	upgraded.Register(v2)
+	upgraded.RegisterPrevious(v1)
+	mgr2 := NewManager(upgraded, mgr.store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("expected the match restored")
+	}
+	if restored.game != v1 || restored.Info().RulesVersion != "1" {
+		t.Fatal("expected the restored match to play by the rules it started under")
+	}
+	infos := mgr2.List()
+	if len(infos) != 1 || infos[0].NewerRules != "2" {
+		t.Fatalf("expected the listing to point out the newer rules, got %+v", infos)
+	}
+	listings, _ := mgr2.ListSessions(t.Context(), storage.SessionFilter{})
+	if len(listings) != 1 || listings[0].RulesVersion != "1" || listings[0].NewerRules != "2" {
+		t.Fatalf("expected stored listings to point out the newer rules, got %+v", listings)
+	}
+	transcript, _ := restored.Transcript()
+	if transcript.RulesVersion != "1" {
+		t.Fatalf("expected the transcript to name its rules, got %q", transcript.RulesVersion)
+	}
+	if _, err := Replay(upgraded, transcript); err != nil {
+		t.Fatalf("replay by the old rules: %v", err)
+	}
+
+	// Without the old rules the match plays on by the new
+	current := game.NewRegistry()
+	current.Register(v2)
+	mgr3 := NewManager(current, mgr.store)
+	mgr3.Restore(t.Context())
+	if restored, ok := mgr3.Get(sess.Code); !ok || restored.game != v2 {
+		t.Fatal("expected the match restored under the current rules")
+	}
+	if _, err := Replay(current, transcript); err == nil {
+		t.Fatal("expected a replay by rules no longer registered refused")
+	}
+
+	// A rematch plays by the current rules
+	restored.Finish()
+	mgr2.SaveMatchState(t.Context(), restored)
+	if err := mgr2.Rematch(t.Context(), restored); err != nil {
+		t.Fatalf("rematch: %v", err)
+	}
+	if info := restored.Info(); info.RulesVersion != "" {
+		t.Fatalf("expected a waiting rematch to have no rules yet, got %q", info.RulesVersion)
+	}
+	restored.AddPlayer("alice")
+	restored.AddPlayer("bob")
+	if err := restored.Start(); err != nil {
+		t.Fatalf("start rematch: %v", err)
+	}
+	if info := restored.Info(); info.RulesVersion != "2" {
+		t.Fatalf("expected the rematch under the new rules, got %q", info.RulesVersion)
+	}
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"sort"
+
+	"games/internal/game"
+)
+
+// ScoreEntry is one player's line on a session scoreboard.
+type ScoreEntry struct {
+	PlayerID string `json:"playerId"`
+	Played   int    `json:"played"`
+	Wins     int    `json:"wins"`   // matches finished in first place, shared or not
+	Points   int    `json:"points"` // party points; see partyPoints
+	Score    int    `json:"score"`  // sum of the games' own scores
+	Rank     int    `json:"rank"`   // 1 = leading; tied players share a rank
+
+	// Matches by outcome, where the game reported one. Abandoned matches
+	// are not counted as played.
+	Draws     int `json:"draws"`
+	Losses    int `json:"losses"`
+	Forfeits  int `json:"forfeits,omitempty"`
+	Timeouts  int `json:"timeouts,omitempty"`
+	Abandoned int `json:"abandoned,omitempty"`
+}
+
+// Scoreboard totals every match played in the session.
+func (s *Session) Scoreboard() []ScoreEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ScoreboardLocked()
+}
+
+// ScoreboardLocked is Scoreboard for callers already holding the lock.
+// Players are ranked by points, then wins, then score. Seated players who
+// have yet to finish a match are listed with zeros.
+func (s *Session) ScoreboardLocked() []ScoreEntry {
+	var rounds [][]game.PlayerResult
+	if s.Party != nil {
+		rounds = s.Party.Rounds
+	} else if s.Match != nil && s.Match.IsOver() {
+		rounds = [][]game.PlayerResult{game.Results(s.Match)}
+	}
+
+	byID := make(map[string]*ScoreEntry)
+	entry := func(id string) *ScoreEntry {
+		e, ok := byID[id]
+		if !ok {
+			e = &ScoreEntry{PlayerID: id}
+			byID[id] = e
+		}
+		return e
+	}
+	for id := range s.Players {
+		entry(id)
+	}
+	if s.Abandoned {
+		rounds = append(rounds, abandonedResults(s.seating))
+	}
+	for _, results := range rounds {
+		points := partyPoints(results)
+		for _, r := range results {
+			e := entry(r.PlayerID)
+			switch r.Outcome {
+			case game.OutcomeDraw:
+				e.Draws++
+			case game.OutcomeLoss:
+				e.Losses++
+			case game.OutcomeForfeit:
+				e.Forfeits++
+			case game.OutcomeTimeout:
+				e.Timeouts++
+			case game.OutcomeAbandoned:
+				e.Abandoned++
+				continue
+			}
+			e.Played++
+			if r.Rank == 1 {
+				e.Wins++
+			}
+			e.Points += points[r.PlayerID]
+			e.Score += r.Score
+		}
+	}
+
+	board := make([]ScoreEntry, 0, len(byID))
+	for _, e := range byID {
+		board = append(board, *e)
+	}
+	ahead := func(a, b ScoreEntry) bool {
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Score > b.Score
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if ahead(board[i], board[j]) || ahead(board[j], board[i]) {
+			return ahead(board[i], board[j])
+		}
+		return board[i].PlayerID < board[j].PlayerID
+	})
+	for i := range board {
+		if i > 0 && !ahead(board[i-1], board[i]) {
+			board[i].Rank = board[i-1].Rank
+		} else {
+			board[i].Rank = i + 1
+		}
+	}
+	return board
+}
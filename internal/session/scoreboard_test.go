@@ -0,0 +1,81 @@
+package session
+
+import (
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+)
+
+func TestScoreboardTotalsPartyRounds(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Party = &Party{Standings: map[string]int{}}
+	sess.RecordPartyRoundLocked([]game.PlayerResult{{PlayerID: "alice", Rank: 1, Score: 1}, {PlayerID: "bob", Rank: 2}})
+	sess.RecordPartyRoundLocked([]game.PlayerResult{{PlayerID: "bob", Rank: 1}, {PlayerID: "alice", Rank: 1}})
+	sess.RecordPartyRoundLocked([]game.PlayerResult{{PlayerID: "bob", Rank: 1, Score: 1}, {PlayerID: "alice", Rank: 2}})
+
+	board := sess.Scoreboard()
+	if len(board) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", board)
+	}
+	for i, want := range []ScoreEntry{
+		{PlayerID: "alice", Played: 3, Wins: 2, Points: 5, Score: 1, Rank: 1},
+		{PlayerID: "bob", Played: 3, Wins: 2, Points: 5, Score: 1, Rank: 1},
+	} {
+		if board[i] != want {
+			t.Errorf("entry %d: got %+v, want %+v", i, board[i], want)
+		}
+	}
+
+	sess.RecordPartyRoundLocked([]game.PlayerResult{{PlayerID: "bob", Rank: 2}, {PlayerID: "alice", Rank: 1}})
+	if board := sess.Scoreboard(); board[0].PlayerID != "alice" || board[1].Rank != 2 {
+		t.Fatalf("expected alice to lead alone, got %+v", board)
+	}
+}
+
+func TestScoreboardSingleMatch(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if board := sess.Scoreboard(); board[0].Played != 0 {
+		t.Fatalf("expected nothing played before the match, got %+v", board)
+	}
+	sess.Start()
+	for !sess.Match.IsOver() {
+		for _, id := range []string{"alice", "bob"} {
+			if actions := sess.Match.ValidActions(id); len(actions) > 0 {
+				sess.Match.ApplyAction(id, actions[0])
+				break
+			}
+		}
+	}
+	board := sess.Scoreboard()
+	if board[0].Played != 1 || board[1].Played != 1 {
+		t.Fatalf("expected the finished match counted, got %+v", board)
+	}
+}
+
+func TestScoreboardCountsOutcomes(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Party = &Party{Standings: map[string]int{}}
+	sess.RecordPartyRoundLocked([]game.PlayerResult{
+		{PlayerID: "alice", Rank: 1, Outcome: game.OutcomeDraw},
+		{PlayerID: "bob", Rank: 1, Outcome: game.OutcomeDraw},
+	})
+	sess.RecordPartyRoundLocked([]game.PlayerResult{
+		{PlayerID: "alice", Rank: 1, Outcome: game.OutcomeWin},
+		{PlayerID: "bob", Rank: 2, Outcome: game.OutcomeForfeit},
+	})
+
+	board := sess.Scoreboard()
+	if a := board[0]; a.PlayerID != "alice" || a.Played != 2 || a.Draws != 1 || a.Losses != 0 {
+		t.Fatalf("unexpected entry for alice: %+v", a)
+	}
+	if b := board[1]; b.Draws != 1 || b.Forfeits != 1 || b.Losses != 0 {
+		t.Fatalf("unexpected entry for bob: %+v", b)
+	}
+}
@@ -0,0 +1,209 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"games/internal/storage"
+)
+
+// SeasonLength is how long each season of the leaderboards runs. Seasons
+// follow the UTC calendar, so every server agrees where one ends.
+type SeasonLength string
+
+const (
+	SeasonsOff    SeasonLength = ""
+	SeasonWeek    SeasonLength = "week"    // ISO weeks, from Monday
+	SeasonMonth   SeasonLength = "month"   // calendar months
+	SeasonQuarter SeasonLength = "quarter" // January, April, July and October
+)
+
+// ErrSeasonNotFound is returned for seasons that have no snapshot.
+var ErrSeasonNotFound = errors.New("season not found")
+
+// ParseSeasonLength reads a season length; "" turns seasons off.
+func ParseSeasonLength(v string) (SeasonLength, error) {
+	switch l := SeasonLength(v); l {
+	case SeasonsOff, SeasonWeek, SeasonMonth, SeasonQuarter:
+		return l, nil
+	}
+	return "", fmt.Errorf("want week, month or quarter, got %q", v)
+}
+
+// Season is one run of the leaderboards, from Start up to but not
+// including End.
+type Season struct {
+	Name  string    `json:"name"` // such as 2026-W42, 2026-10 or 2026-Q4
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SeasonAt returns the season of the given length that t falls in.
+func (l SeasonLength) SeasonAt(t time.Time) Season {
+	t = t.UTC()
+	y, m, d := t.Date()
+	switch l {
+	case SeasonWeek:
+		start := time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+		year, week := start.ISOWeek()
+		return Season{Name: fmt.Sprintf("%d-W%02d", year, week), Start: start, End: start.AddDate(0, 0, 7)}
+	case SeasonQuarter:
+		q := (int(m) - 1) / 3
+		start := time.Date(y, time.Month(q*3+1), 1, 0, 0, 0, 0, time.UTC)
+		return Season{Name: fmt.Sprintf("%d-Q%d", y, q+1), Start: start, End: start.AddDate(0, 3, 0)}
+	default:
+		start := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return Season{Name: start.Format("2006-01"), Start: start, End: start.AddDate(0, 1, 0)}
+	}
+}
+
+// Leaderboards is what a game's leaderboards showed for a season.
+type Leaderboards struct {
+	Stats        GameStats          `json:"stats"`
+	BotStandings []StrategyStanding `json:"botStandings"`
+}
+
+// SeasonSnapshot is a finished season with its leaderboards by game type.
+// Games nobody played that season are left out.
+type SeasonSnapshot struct {
+	Season
+	Games map[string]Leaderboards `json:"games"`
+}
+
+// SetSeasonLength divides the leaderboards into seasons of the given
+// length, or runs them over all time for SeasonsOff.
+func (m *Manager) SetSeasonLength(l SeasonLength) {
+	m.seasonMu.Lock()
+	defer m.seasonMu.Unlock()
+	m.seasonLength = l
+}
+
+func (m *Manager) seasonLengthNow() SeasonLength {
+	m.seasonMu.Lock()
+	defer m.seasonMu.Unlock()
+	return m.seasonLength
+}
+
+// CurrentSeason returns the season the leaderboards are counting, or nil
+// when seasons are off.
+func (m *Manager) CurrentSeason() *Season {
+	l := m.seasonLengthNow()
+	if l == SeasonsOff {
+		return nil
+	}
+	season := l.SeasonAt(time.Now())
+	return &season
+}
+
+// Seasons returns every finished season with a snapshot, most recent
+// first, without their leaderboards.
+func (m *Manager) Seasons(ctx context.Context) ([]Season, error) {
+	rows, err := m.store.ListSeasons(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seasons := make([]Season, len(rows))
+	for i, r := range rows {
+		seasons[i] = Season{Name: r.Name, Start: r.StartedAt.UTC(), End: r.EndedAt.UTC()}
+	}
+	return seasons, nil
+}
+
+// SeasonSnapshot returns a finished season's leaderboards.
+func (m *Manager) SeasonSnapshot(ctx context.Context, name string) (*SeasonSnapshot, error) {
+	row, err := m.store.GetSeason(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSeasonNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	snap := &SeasonSnapshot{Season: Season{Name: row.Name, Start: row.StartedAt.UTC(), End: row.EndedAt.UTC()}}
+	if err := json.Unmarshal([]byte(row.Leaderboards), &snap.Games); err != nil {
+		return nil, fmt.Errorf("unmarshal season %s: %w", name, err)
+	}
+	return snap, nil
+}
+
+// RolloverSeason snapshots the leaderboards of the season before the one
+// now falls in, unless it already has a snapshot, and reports whether it
+// took one. The live leaderboards start over by themselves, counting only
+// the current season.
+func (m *Manager) RolloverSeason(ctx context.Context, now time.Time) (bool, error) {
+	l := m.seasonLengthNow()
+	if l == SeasonsOff {
+		return false, nil
+	}
+	ctx = detach(ctx)
+	previous := l.SeasonAt(l.SeasonAt(now).Start.Add(-time.Nanosecond))
+	_, err := m.store.GetSeason(ctx, previous.Name)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	games := make(map[string]Leaderboards)
+	for _, name := range m.registry.Names() {
+		stats, err := m.gameStats(ctx, name, previous.Start, previous.End)
+		if err != nil {
+			return false, err
+		}
+		standings, err := m.exhibitionStandings(ctx, name, previous.Start, previous.End)
+		if err != nil {
+			return false, err
+		}
+		if stats.Matches+stats.Abandoned == 0 && len(standings) == 0 {
+			continue
+		}
+		stats.Season = previous.Name
+		games[name] = Leaderboards{Stats: stats, BotStandings: standings}
+	}
+	data, err := json.Marshal(games)
+	if err != nil {
+		return false, err
+	}
+	if err := m.store.SaveSeason(ctx, storage.SeasonRow{
+		Name:         previous.Name,
+		StartedAt:    previous.Start,
+		EndedAt:      previous.End,
+		Leaderboards: string(data),
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SeasonLoop checks every interval until ctx is done whether a season
+// has ended, and snapshots it if so.
+func (m *Manager) SeasonLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		took, err := m.RolloverSeason(ctx, time.Now())
+		if err != nil {
+			log.Printf("season rollover: %v", err)
+		} else if took {
+			log.Printf("snapshotted the leaderboards of the last season")
+		}
+	}
+}
+
+// seasonStart returns when the current season started, or the zero time
+// when seasons are off and the leaderboards cover all time.
+func (m *Manager) seasonStart() time.Time {
+	if season := m.CurrentSeason(); season != nil {
+		return season.Start
+	}
+	return time.Time{}
+}
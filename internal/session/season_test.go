@@ -0,0 +1,82 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"games/internal/storage"
+)
+
+func TestSeasonAt(t *testing.T) {
+	at := time.Date(2026, 10, 16, 15, 4, 5, 0, time.UTC) // a Friday
+	newYear := time.Date(2027, 1, 1, 0, 30, 0, 0, time.FixedZone("", 3600))
+	tests := []struct {
+		length SeasonLength
+		t      time.Time
+		name   string
+		start  time.Time
+		end    time.Time
+	}{
+		{SeasonWeek, at, "2026-W42", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{SeasonMonth, at, "2026-10", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{SeasonQuarter, at, "2026-Q4", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Half past midnight an hour east of UTC is still 2026 in UTC
+		{SeasonMonth, newYear, "2026-12", time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{SeasonWeek, newYear, "2026-W53", time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s := tt.length.SeasonAt(tt.t)
+		if s.Name != tt.name || !s.Start.Equal(tt.start) || !s.End.Equal(tt.end) {
+			t.Errorf("%s at %v: got %+v, want %s from %v to %v", tt.length, tt.t, s, tt.name, tt.start, tt.end)
+		}
+	}
+	if _, err := ParseSeasonLength("fortnight"); err == nil {
+		t.Fatal("expected an unknown season length to be refused")
+	}
+}
+
+func TestRolloverSeason(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	now := time.Now()
+	current := SeasonMonth.SeasonAt(now)
+	last := current.Start.Add(-time.Hour)
+	mgr.store.ArchiveMatch(t.Context(), storage.ArchivedMatchRow{SessionCode: "old", GameType: "tictactoe", Moves: 7, StartedAt: last.Add(-time.Minute), FinishedAt: last})
+	mgr.store.ArchiveMatch(t.Context(), storage.ArchivedMatchRow{SessionCode: "new", GameType: "tictactoe", Moves: 5, StartedAt: now.Add(-time.Minute), FinishedAt: now})
+
+	if took, err := mgr.RolloverSeason(t.Context(), now); took || err != nil {
+		t.Fatalf("expected no snapshot with seasons off, got %v %v", took, err)
+	}
+	if stats, _ := mgr.GameStats(t.Context(), "tictactoe"); stats.Matches != 2 || stats.Season != "" {
+		t.Fatalf("expected all-time stats with seasons off, got %+v", stats)
+	}
+
+	mgr.SetSeasonLength(SeasonMonth)
+	if stats, _ := mgr.GameStats(t.Context(), "tictactoe"); stats.Matches != 1 || stats.AvgMoves != 5 || stats.Season != current.Name {
+		t.Fatalf("expected only this season's match counted, got %+v", stats)
+	}
+	if took, err := mgr.RolloverSeason(t.Context(), now); !took || err != nil {
+		t.Fatalf("expected the last season snapshotted, got %v %v", took, err)
+	}
+	if took, _ := mgr.RolloverSeason(t.Context(), now); took {
+		t.Fatal("expected a season snapshotted only once")
+	}
+
+	previous := SeasonMonth.SeasonAt(last)
+	seasons, err := mgr.Seasons(t.Context())
+	if err != nil || len(seasons) != 1 || seasons[0].Name != previous.Name || !seasons[0].End.Equal(current.Start) {
+		t.Fatalf("expected the last season listed, got %+v %v", seasons, err)
+	}
+	snap, err := mgr.SeasonSnapshot(t.Context(), previous.Name)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if len(snap.Games) != 1 || snap.Games["tictactoe"].Stats.Matches != 1 || snap.Games["tictactoe"].Stats.AvgMoves != 7 {
+		t.Fatalf("expected the old match in the snapshot, got %+v", snap)
+	}
+	if _, err := mgr.SeasonSnapshot(t.Context(), "1999-01"); !errors.Is(err, ErrSeasonNotFound) {
+		t.Fatalf("expected ErrSeasonNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"fmt"
+	mrand "math/rand/v2"
+)
+
+// TurnOrder says how players who have not chosen a seat are seated.
+type TurnOrder string
+
+const (
+	// TurnOrderJoin seats players in the order they joined. It is the
+	// default, so the session creator moves first unless seats are chosen.
+	TurnOrderJoin TurnOrder = "join"
+	// TurnOrderRandom seats players in an order shuffled from the match seed.
+	TurnOrderRandom TurnOrder = "random"
+)
+
+// ParseTurnOrder validates a turn order name; "" means TurnOrderJoin.
+func ParseTurnOrder(name string) (TurnOrder, error) {
+	switch order := TurnOrder(name); order {
+	case "":
+		return TurnOrderJoin, nil
+	case TurnOrderJoin, TurnOrderRandom:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unknown turn order %q: want %q or %q", name, TurnOrderJoin, TurnOrderRandom)
+	}
+}
+
+// ChooseSeat puts playerID in a seat before the match starts; seat 0 moves
+// first. A seat of -1 gives up the player's choice.
+func (s *Session) ChooseSeat(playerID string, seat int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Status != StatusWaiting {
+		return fmt.Errorf("seats can only be chosen before the game starts")
+	}
+	if s.Players[playerID] == nil {
+		return fmt.Errorf("player %s not in session", playerID)
+	}
+	if seat == -1 {
+		delete(s.seatChoices, playerID)
+		return nil
+	}
+	if max := s.game.Info().MaxPlayers; seat < 0 || seat >= max {
+		return fmt.Errorf("seat %d out of range 0-%d", seat, max-1)
+	}
+	if taken := s.seatsLocked()[seat]; taken != "" && taken != playerID {
+		return fmt.Errorf("seat %d is taken by %s", seat, taken)
+	}
+	if s.seatChoices == nil {
+		s.seatChoices = make(map[string]int)
+	}
+	s.seatChoices[playerID] = seat
+	return nil
+}
+
+// ShuffleSeats seats every player in a random order, replacing their
+// choices.
+func (s *Session) ShuffleSeats() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Status != StatusWaiting {
+		return fmt.Errorf("seats can only be shuffled before the game starts")
+	}
+	s.seatChoices = make(map[string]int, len(s.Players))
+	seats := mrand.Perm(len(s.Players))
+	for id := range s.Players {
+		s.seatChoices[id], seats = seats[0], seats[1:]
+	}
+	return nil
+}
+
+// seatsLocked lists the chosen seats of players still in the session, ""
+// for open ones.
+func (s *Session) seatsLocked() []string {
+	seats := make([]string, s.game.Info().MaxPlayers)
+	for id, seat := range s.seatChoices {
+		if s.Players[id] != nil && seat < len(seats) {
+			seats[seat] = id
+		}
+	}
+	return seats
+}
+
+// seatOrderLocked is the turn order for a new match: players in their
+// chosen seats, with everyone else filling the open seats in join order,
+// or in an order shuffled from seed under TurnOrderRandom.
+func (s *Session) seatOrderLocked(seed int64) []string {
+	seats := s.seatsLocked()
+	var unseated []string
+	seated := make(map[string]bool)
+	for _, id := range seats {
+		seated[id] = true
+	}
+	for _, id := range s.joinOrder {
+		if !seated[id] {
+			unseated = append(unseated, id)
+		}
+	}
+	rest := unseated
+	if s.TurnOrder == TurnOrderRandom {
+		rest = shuffleSeats(unseated, seed)
+	}
+	order := make([]string, 0, len(s.Players))
+	for _, id := range seats {
+		if id == "" && len(rest) > 0 {
+			id, rest = rest[0], rest[1:]
+		}
+		if id != "" {
+			order = append(order, id)
+		}
+	}
+	return order
+}
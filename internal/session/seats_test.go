@@ -0,0 +1,102 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"games/internal/game/tictactoe"
+)
+
+func TestChosenSeatsSetTurnOrder(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+		sess.AddPlayer("alice")
+		sess.AddPlayer("bob")
+		if err := sess.ChooseSeat("bob", 0); err != nil {
+			t.Fatalf("choose seat: %v", err)
+		}
+		if err := sess.ChooseSeat("alice", 0); err == nil {
+			t.Fatal("expected error taking bob's seat")
+		}
+		if err := sess.ChooseSeat("alice", 2); err == nil {
+			t.Fatal("expected error for a seat out of range")
+		}
+		if got := sess.Info().Seats; !reflect.DeepEqual(got, []string{"bob", ""}) {
+			t.Fatalf("unexpected seats %v", got)
+		}
+		sess.Start()
+		if got := sess.Info().Seats; !reflect.DeepEqual(got, []string{"bob", "alice"}) {
+			t.Fatalf("expected bob to move first, got %v", got)
+		}
+		if len(sess.Match.ValidActions("bob")) == 0 {
+			t.Fatal("expected bob to have the first move")
+		}
+	}
+}
+
+func TestShuffleSeatsReplacesChoices(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.ChooseSeat("alice", 1)
+	if err := sess.ShuffleSeats(); err != nil {
+		t.Fatalf("shuffle: %v", err)
+	}
+	seats := sess.Info().Seats
+	if seats[0] == "" || seats[1] == "" || seats[0] == seats[1] {
+		t.Fatalf("expected both players seated, got %v", seats)
+	}
+	sess.ChooseSeat("alice", -1)
+	if seats := sess.Info().Seats; seats[0] == "alice" || seats[1] == "alice" {
+		t.Fatalf("expected alice's seat given up, got %v", seats)
+	}
+}
+
+func TestTurnOrderFollowsJoinOrder(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+		sess.AddPlayer("zoe")
+		sess.AddPlayer("alice")
+		sess.Start()
+		if got := sess.Info().Seats; !reflect.DeepEqual(got, []string{"zoe", "alice"}) {
+			t.Fatalf("expected join order, got %v", got)
+		}
+	}
+}
+
+func TestRandomTurnOrderFollowsSeed(t *testing.T) {
+	sess := NewSession("abc", "tictactoe", tictactoe.TicTacToe{})
+	sess.TurnOrder = TurnOrderRandom
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+	want := shuffleSeats([]string{"alice", "bob"}, sess.Seed)
+	if got := sess.Info().Seats; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected seating %v from the seed, got %v", want, got)
+	}
+}
+
+func TestTurnOrderPersists(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	if err := mgr.SetTurnOrder(t.Context(), sess, TurnOrderRandom); err != nil {
+		t.Fatalf("set turn order: %v", err)
+	}
+	if _, err := ParseTurnOrder("host"); err == nil {
+		t.Fatal("expected error for an unknown turn order")
+	}
+
+	mgr2 := NewManager(mgr.registry, mgr.store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("session not restored")
+	}
+	if got := restored.Info().TurnOrder; got != TurnOrderRandom {
+		t.Fatalf("expected random turn order, got %q", got)
+	}
+}
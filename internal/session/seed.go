@@ -0,0 +1,117 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand/v2"
+	"sort"
+
+	"games/internal/game"
+)
+
+// newSeed returns a random non-zero match seed.
+func newSeed() int64 {
+	var b [8]byte
+	for {
+		rand.Read(b[:])
+		if seed := int64(binary.LittleEndian.Uint64(b[:]) >> 1); seed != 0 {
+			return seed
+		}
+	}
+}
+
+// newSalt returns a random salt for a seed commitment.
+func newSalt() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// CommitmentLocked returns the fairness commitment to the current match's
+// seed, revealing the seed and salt once the match is over, or nil before
+// play starts. The caller must hold the lock.
+func (s *Session) CommitmentLocked() *game.Commitment {
+	if s.Seed == 0 || s.salt == "" {
+		return nil
+	}
+	c := &game.Commitment{Hash: game.Commit(s.Seed, s.salt)}
+	if s.Match != nil && s.Match.IsOver() {
+		c.Seed, c.Salt = s.Seed, s.salt
+	}
+	return c
+}
+
+// shuffleSeats orders players who did not choose a seat. The order comes
+// from the seed, so the same players and seed always sit the same way.
+func shuffleSeats(players []string, seed int64) []string {
+	ids := append([]string(nil), players...)
+	sort.Strings(ids)
+	r := mrand.New(mrand.NewPCG(uint64(seed), 0))
+	r.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	return ids
+}
+
+// Transcript is everything needed to replay a match: its game and the
+// version of its rules, players in seat order, options, handicaps, the
+// starting position the host set up and seed, and the moves played. Rated
+// play can tell an uneven match by its handicaps.
+type Transcript struct {
+	GameType     string          `json:"gameType"`
+	RulesVersion string          `json:"rulesVersion,omitempty"`
+	Players      []string        `json:"players"`
+	Options      map[string]int  `json:"options,omitempty"`
+	Handicaps    game.Handicaps  `json:"handicaps,omitempty"`
+	Setup        json.RawMessage `json:"setup,omitempty"`
+	Seed         int64           `json:"seed"`
+	Moves        []Move          `json:"moves"`
+}
+
+// Transcript returns the current match's transcript.
+func (s *Session) Transcript() (Transcript, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Match == nil {
+		return Transcript{}, fmt.Errorf("game not started")
+	}
+	if s.Seed == 0 {
+		return Transcript{}, fmt.Errorf("match seed not recorded")
+	}
+	return Transcript{
+		GameType:     s.GameType,
+		RulesVersion: s.RulesVersion,
+		Players:      append([]string(nil), s.seating...),
+		Options:      s.Options,
+		Handicaps:    s.Handicaps.Only(s.seating),
+		Setup:        s.Setup,
+		Seed:         s.Seed,
+		Moves:        append([]Move(nil), s.History...),
+	}, nil
+}
+
+// Replay plays a transcript from scratch and returns the resulting match.
+// An error names the first move that failed, with the match as it stood
+// before that move.
+func Replay(registry *game.Registry, t Transcript) (game.Match, error) {
+	if _, ok := registry.Get(t.GameType); !ok {
+		return nil, fmt.Errorf("unknown game type: %s", t.GameType)
+	}
+	g, ok := registry.Rules(t.GameType, t.RulesVersion)
+	if !ok {
+		return nil, fmt.Errorf("%s rules %q not registered", t.GameType, t.RulesVersion)
+	}
+	if t.Setup != nil {
+		if err := game.ValidateSetup(g, t.Setup); err != nil {
+			return nil, fmt.Errorf("setup: %w", err)
+		}
+	}
+	m := g.NewMatch(game.MatchConfig{PlayerIDs: t.Players, Options: t.Options, Handicaps: t.Handicaps, Setup: t.Setup, Seed: t.Seed})
+	for i, mv := range t.Moves {
+		if _, err := game.ApplyAt(m, mv.PlayerID, mv.Action, mv.Time()); err != nil {
+			return m, fmt.Errorf("move %d by %s: %w", i+1, mv.PlayerID, err)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+func TestShuffleSeatsBySeed(t *testing.T) {
+	a := shuffleSeats([]string{"alice", "bob", "carol"}, 7)
+	b := shuffleSeats([]string{"carol", "alice", "bob"}, 7)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("expected the same seating for the same seed, got %v and %v", a, b)
+	}
+	orders := map[string]bool{}
+	for seed := int64(1); seed <= 20; seed++ {
+		orders[shuffleSeats([]string{"alice", "bob"}, seed)[0]] = true
+	}
+	if len(orders) != 2 {
+		t.Fatalf("expected both players to go first for some seed, got %v", orders)
+	}
+}
+
+func TestReplayReproducesMatchAfterRestart(t *testing.T) {
+	store, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(tictactoe.TicTacToe{})
+	mgr := NewManager(reg, store)
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	if _, err := sess.Transcript(); err == nil {
+		t.Fatal("expected no transcript before the match starts")
+	}
+	sess.Start()
+	if sess.Seed == 0 {
+		t.Fatal("expected a seed once the match starts")
+	}
+	mgr.SaveInitialState(t.Context(), sess)
+	for seq := 1; seq <= 3; seq++ {
+		for _, id := range []string{"alice", "bob"} {
+			if actions := sess.Match.ValidActions(id); len(actions) > 0 {
+				sess.Match.ApplyAction(id, actions[0])
+				mv := Move{PlayerID: id, Action: actions[0]}
+				sess.History = append(sess.History, mv)
+				mgr.AppendMove(t.Context(), sess, seq, mv)
+				break
+			}
+		}
+	}
+	mgr.SaveMatchState(t.Context(), sess)
+
+	mgr2 := NewManager(reg, store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("session not restored")
+	}
+	tr, err := restored.Transcript()
+	if err != nil {
+		t.Fatalf("transcript: %v", err)
+	}
+	if tr.Seed != sess.Seed || len(tr.Moves) != 3 {
+		t.Fatalf("expected seed %d and 3 moves, got %+v", sess.Seed, tr)
+	}
+	m, err := Replay(reg, tr)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if !reflect.DeepEqual(m.State(""), sess.Match.State("")) {
+		t.Fatalf("replay diverged: %+v vs %+v", m.State(""), sess.Match.State(""))
+	}
+
+	tr.Moves = append(tr.Moves, tr.Moves[0])
+	if _, err := Replay(reg, tr); err == nil {
+		t.Fatal("expected the repeated move to fail")
+	}
+}
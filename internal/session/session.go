@@ -1,8 +1,12 @@
 package session
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"games/internal/game"
 )
@@ -14,12 +18,53 @@ const (
 	StatusWaiting  Status = "waiting"
 	StatusPlaying  Status = "playing"
 	StatusFinished Status = "finished"
+	StatusErrored  Status = "errored" // the game panicked; see Manager.Fail
 )
 
 // Player represents a connected player.
 type Player struct {
-	ID   string
-	Send chan []byte // outbound messages
+	ID       string
+	Send     chan []byte   // outbound messages on the latest connection
+	Strategy game.Strategy // non-nil for bot players
+	Webhook  string        // turn notification URL for external bots
+	Kicked   chan struct{} // closed when a moderator removes the player
+
+	// conns holds the Send channel of every open connection, mapped to a
+	// channel closed when a handoff takes the seat away from it.
+	conns map[chan []byte]chan struct{}
+}
+
+// sendsLocked lists the channels a message to p goes to: every open
+// connection, or Send while there is none.
+func (p *Player) sendsLocked() []chan []byte {
+	if len(p.conns) == 0 {
+		return []chan []byte{p.Send}
+	}
+	sends := make([]chan []byte, 0, len(p.conns))
+	for send := range p.conns {
+		sends = append(sends, send)
+	}
+	return sends
+}
+
+// BotInfo describes a bot seated in a session.
+type BotInfo struct {
+	PlayerID   string `json:"playerId"`
+	Strategy   string `json:"strategy"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// Move is one applied action in a match's history.
+type Move struct {
+	PlayerID string      `json:"playerId"`
+	Action   game.Action `json:"action"`
+	At       time.Time   `json:"at"` // when the server received the action
+	// SentAt is when the client said it sent the action, by its estimate
+	// of the server's clock; zero if it did not say.
+	SentAt time.Time `json:"sentAt,omitzero"`
+	// ActedAt is when the action counts as made, if lag compensation put
+	// it before At.
+	ActedAt time.Time `json:"actedAt,omitzero"`
 }
 
 // Session is one game session with connected players.
@@ -31,17 +76,98 @@ type Session struct {
 	HostID   string
 	Players  map[string]*Player
 	Match    game.Match
+	History  []Move // actions applied to Match, in order
 	game     game.Game
+	initial  game.Match // clone of Match as it was when play started
+
+	// Names are the names players are shown by, for those whose ID is not
+	// one, such as guests.
+	Names map[string]string
+	// Spectators receive broadcasts but hold no seat, keyed by spectator ID.
+	Spectators map[string]chan []byte
+	// Sandbox sessions pit an external bot against a built-in one.
+	Sandbox bool
+	// Practice sessions belong to their host alone, who plays every seat
+	// but a bot's and may take moves back and set up positions. Their
+	// matches count toward nothing.
+	Practice bool
+	// Private sessions are joinable by code but hidden from public listings.
+	Private bool
+	// Pinned sessions, such as tournaments and demos, are never cleaned up.
+	Pinned bool
+	// ExpireAfter is how long the session may sit idle before cleanup
+	// removes it, whatever its status, for sessions that keep their own
+	// pace; zero leaves it to the cleanup policy.
+	ExpireAfter time.Duration
+	// Options are the game option values the session was created with.
+	Options map[string]int
+	// RulesVersion is the version of the game's rules the current match
+	// started under; empty while waiting.
+	RulesVersion string
+	// Party is set for sessions that play a queue of games.
+	Party *Party
+	// Seed is the current match's seed; zero before play starts. It is
+	// kept from players, who could otherwise predict hidden draws.
+	Seed    int64
+	seating []string // the current match's players in turn order
+	salt    string   // salt of the seed's fairness commitment
+	// previous is the match played before the current one, if any, which
+	// the game may use to decide who moves first.
+	previous *game.PreviousMatch
+	// TurnOrder decides how players without a chosen seat are seated.
+	TurnOrder TurnOrder
+	// seatChoices maps players to the seats they picked before the start.
+	seatChoices map[string]int
+	joinOrder   []string // player IDs in the order they joined
+	// handoffs are pending seat transfers to another device, by code.
+	handoffs map[string]handoff
+	// secrets hold the SHA-256 of each claimed seat's join secret, or of
+	// its device token after a handoff, by player ID. They are stored with
+	// the roster, so a seat stays its player's across a restart.
+	secrets map[string][]byte
+	// reservations hold seats for invited players, by player ID, until
+	// the given time.
+	reservations map[string]time.Time
+	// featureOverrides turn features on or off for this session whatever
+	// their rollout, by feature name.
+	featureOverrides map[string]bool
+	// VoteThresholds decide how many players must agree to skip or remove
+	// an unresponsive player.
+	VoteThresholds VoteThresholds
+	votes          map[voteKey]ballot // open votes in the current match
+	removed        []string           // players voted out of the current match
+	// Handicaps are what the host gave players for an uneven start.
+	Handicaps game.Handicaps
+	// Setup is the starting position the host set up, as the game
+	// documents it, or nil for the usual start.
+	Setup json.RawMessage
+	// Abandoned is set when the current match was finished because every
+	// player stayed away, rather than played out.
+	Abandoned bool
+	awaySince time.Time // when the last player disconnected from a playing match
+	emotes    []Emote   // emotes sent in the last EmoteTTL, oldest first
+
+	// CreatedAt and LastActivity are always set; StartedAt and FinishedAt
+	// are zero until the current match starts and ends.
+	CreatedAt    time.Time
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	LastActivity time.Time
 }
 
 // NewSession creates a session in the waiting state.
 func NewSession(code, gameType string, g game.Game) *Session {
+	now := time.Now()
 	return &Session{
-		Code:     code,
-		GameType: gameType,
-		Status:   StatusWaiting,
-		Players:  make(map[string]*Player),
-		game:     g,
+		Code:           code,
+		GameType:       gameType,
+		Status:         StatusWaiting,
+		Players:        make(map[string]*Player),
+		TurnOrder:      TurnOrderJoin,
+		VoteThresholds: DefaultVoteThresholds,
+		game:           g,
+		CreatedAt:      now,
+		LastActivity:   now,
 	}
 }
 
@@ -53,34 +179,166 @@ func (s *Session) AddPlayer(playerID string) error {
 	if s.Status != StatusWaiting {
 		return fmt.Errorf("session is not accepting players")
 	}
-	info := s.game.Info()
-	if len(s.Players) >= info.MaxPlayers {
-		return fmt.Errorf("session is full")
-	}
 	if _, exists := s.Players[playerID]; exists {
 		return fmt.Errorf("player %s already in session", playerID)
 	}
+	if err := s.claimSeatLocked(playerID); err != nil {
+		return err
+	}
 	s.Players[playerID] = &Player{
-		ID:   playerID,
-		Send: make(chan []byte, 64),
+		ID:     playerID,
+		Send:   make(chan []byte, 64),
+		Kicked: make(chan struct{}),
 	}
+	s.joinOrder = append(s.joinOrder, playerID)
 	if s.HostID == "" {
 		s.HostID = playerID
 	}
+	s.LastActivity = time.Now()
 	return nil
 }
 
+// SetName sets the name playerID is shown by. An empty name, or the ID
+// itself, shows the ID.
+func (s *Session) SetName(playerID, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "" || name == playerID {
+		delete(s.Names, playerID)
+		return
+	}
+	if s.Names == nil {
+		s.Names = make(map[string]string)
+	}
+	s.Names[playerID] = name
+}
+
+// AddBot seats a bot playing the given strategy and returns its player ID.
+func (s *Session) AddBot(strategy game.Strategy) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Status != StatusWaiting {
+		return "", fmt.Errorf("session is not accepting players")
+	}
+	if err := s.claimSeatLocked(""); err != nil {
+		return "", err
+	}
+	base := "bot-" + strategy.Info().Name
+	id := base
+	for n := 2; s.Players[id] != nil; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	s.Players[id] = &Player{
+		ID:       id,
+		Send:     make(chan []byte, 64),
+		Strategy: strategy,
+		Kicked:   make(chan struct{}),
+	}
+	s.joinOrder = append(s.joinOrder, id)
+	return id, nil
+}
+
+// SetWebhook sets the turn notification URL for a seated player.
+func (s *Session) SetWebhook(playerID, url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.Players[playerID]
+	if !ok {
+		return false
+	}
+	p.Webhook = url
+	return true
+}
+
+// NextBotMove returns the first bot (by player ID) with a valid action in the
+// current match. The caller must hold the lock.
+func (s *Session) NextBotMove() (*Player, bool) {
+	if s.Status != StatusPlaying || s.Match == nil {
+		return nil, false
+	}
+	ids := make([]string, 0, len(s.Players))
+	for id, p := range s.Players {
+		if p.Strategy != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if len(s.Match.ValidActions(id)) > 0 {
+			return s.Players[id], true
+		}
+	}
+	return nil, false
+}
+
 // RemovePlayer removes a player from the session.
 func (s *Session) RemovePlayer(playerID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if p, ok := s.Players[playerID]; ok {
+		for send := range p.conns {
+			if send != p.Send {
+				close(send)
+			}
+		}
 		close(p.Send)
 		delete(s.Players, playerID)
+		delete(s.secrets, playerID)
+		s.joinOrder = slices.DeleteFunc(s.joinOrder, func(id string) bool { return id == playerID })
+	}
+}
+
+// Kick removes a player and signals their connection to close. Unlike
+// RemovePlayer it leaves Send open, since the connection may still be
+// writing to it.
+func (s *Session) Kick(playerID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.Players[playerID]
+	if !ok {
+		return false
+	}
+	close(p.Kicked)
+	delete(s.Players, playerID)
+	delete(s.secrets, playerID)
+	s.joinOrder = slices.DeleteFunc(s.joinOrder, func(id string) bool { return id == playerID })
+	if s.HostID == playerID {
+		s.HostID = ""
+		for id := range s.Players {
+			if s.HostID == "" || id < s.HostID {
+				s.HostID = id
+			}
+		}
+	}
+	return true
+}
+
+// AddSpectator registers a watcher. A spectator reconnecting with the same ID
+// replaces its previous channel. Like players' channels, spectators' are
+// never closed here, as a broadcast may still be sending on one; a
+// connection's writer stops when the connection does.
+func (s *Session) AddSpectator(id string, send chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Spectators == nil {
+		s.Spectators = make(map[string]chan []byte)
+	}
+	s.Spectators[id] = send
+}
+
+// RemoveSpectator unregisters a watcher if send is still its current channel.
+func (s *Session) RemoveSpectator(id string, send chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.Spectators[id]; ok && cur == send {
+		delete(s.Spectators, id)
 	}
 }
 
-// ConnectPlayer replaces the Send channel for a reconnecting player.
+// ConnectPlayer adds a connection for a player. A player may be connected
+// several times over, from several tabs or devices; messages go to every
+// connection and actions are accepted from any.
 func (s *Session) ConnectPlayer(playerID string, send chan []byte) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -88,19 +346,60 @@ func (s *Session) ConnectPlayer(playerID string, send chan []byte) bool {
 	if !ok {
 		return false
 	}
+	if p.conns == nil {
+		p.conns = make(map[chan []byte]chan struct{})
+	}
+	if p.conns[send] == nil {
+		p.conns[send] = make(chan struct{})
+	}
 	p.Send = send
+	s.awaySince = time.Time{}
 	return true
 }
 
-// PlayerIDs returns the list of player IDs.
-func (s *Session) PlayerIDs() []string {
+// DisconnectPlayer drops one of a player's connections once it closes.
+func (s *Session) DisconnectPlayer(playerID string, send chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.Players[playerID]
+	if !ok {
+		return
+	}
+	delete(p.conns, send)
+	if p.Send == send {
+		for other := range p.conns {
+			p.Send = other
+			break
+		}
+	}
+}
+
+// Connected reports whether a player has an open connection to the
+// session.
+func (s *Session) Connected(playerID string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	ids := make([]string, 0, len(s.Players))
-	for id := range s.Players {
-		ids = append(ids, id)
+	p, ok := s.Players[playerID]
+	return ok && len(p.conns) > 0
+}
+
+// PlayerSends returns the channels of a player's open connections, or its
+// Send channel while it has none.
+func (s *Session) PlayerSends(playerID string) []chan []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.Players[playerID]
+	if !ok {
+		return nil
 	}
-	return ids
+	return p.sendsLocked()
+}
+
+// PlayerIDs returns the player IDs in the order they joined.
+func (s *Session) PlayerIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.joinOrder)
 }
 
 // Start transitions the session from waiting to playing.
@@ -116,13 +415,49 @@ func (s *Session) Start() error {
 		return fmt.Errorf("need at least %d players, have %d", info.MinPlayers, len(s.Players))
 	}
 
+	s.Seed = newSeed()
+	s.salt = newSalt()
+	s.seating = game.TurnOrder(s.game, s.seatOrderLocked(s.Seed), s.previous, s.Seed)
+	var match game.Match
+	err := Protect(func() error {
+		match = s.game.NewMatch(game.MatchConfig{PlayerIDs: s.seating, Options: s.Options, Handicaps: s.Handicaps.Only(s.seating), Seed: s.Seed, Setup: s.Setup})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.Match = match
+	s.RulesVersion = info.Version
+	s.StartedAt = time.Now()
+	s.FinishedAt = time.Time{}
+	s.LastActivity = s.StartedAt
+	s.initial = s.Match.Clone()
+	s.History = nil
+	s.votes, s.removed = nil, nil
+	s.Abandoned = false
+	s.Status = StatusPlaying
+	return nil
+}
+
+// Analyze starts an analysis of the finished match. The analysis works on
+// clones, so the session's own match and history are never modified.
+func (s *Session) Analyze() (*Analysis, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Status != StatusFinished || s.Match == nil {
+		return nil, fmt.Errorf("match is not finished")
+	}
+	if s.initial == nil {
+		return nil, fmt.Errorf("no recorded start position")
+	}
+	history := make([]Move, len(s.History))
+	copy(history, s.History)
 	ids := make([]string, 0, len(s.Players))
 	for id := range s.Players {
 		ids = append(ids, id)
 	}
-	s.Match = s.game.NewMatch(game.MatchConfig{PlayerIDs: ids})
-	s.Status = StatusPlaying
-	return nil
+	sort.Strings(ids)
+	return NewAnalysis(s.initial.Clone(), history, ids)
 }
 
 // Finish marks the session as finished.
@@ -130,6 +465,8 @@ func (s *Session) Finish() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.Status = StatusFinished
+	s.FinishedAt = time.Now()
+	s.LastActivity = s.FinishedAt
 }
 
 // Broadcast sends a message to all connected players.
@@ -137,10 +474,18 @@ func (s *Session) Broadcast(msg []byte) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	for _, p := range s.Players {
+		for _, send := range p.sendsLocked() {
+			select {
+			case send <- msg:
+			default:
+				// drop message if buffer full
+			}
+		}
+	}
+	for _, send := range s.Spectators {
 		select {
-		case p.Send <- msg:
+		case send <- msg:
 		default:
-			// drop message if buffer full
 		}
 	}
 }
@@ -152,13 +497,87 @@ func (s *Session) GetPlayer(playerID string) *Player {
 	return s.Players[playerID]
 }
 
+// Involves reports whether id is one of the session's players or
+// spectators.
+func (s *Session) Involves(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, watching := s.Spectators[id]
+	return s.Players[id] != nil || watching
+}
+
+// IsBot reports whether playerID is a built-in bot of the session. Use it
+// rather than reading a player's Strategy, which changes under the lock
+// when a party moves to its next game.
+func (s *Session) IsBot(playerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isBotLocked(playerID)
+}
+
 // Info returns session info for the API.
 type Info struct {
-	Code     string   `json:"code"`
-	GameType string   `json:"gameType"`
-	Status   Status   `json:"status"`
-	Players  []string `json:"players"`
-	HostID   string   `json:"hostId"`
+	Code       string         `json:"code"`
+	GameType   string         `json:"gameType"`
+	Status     Status         `json:"status"`
+	Players    []string       `json:"players"`
+	HostID     string         `json:"hostId"`
+	Bots       []BotInfo      `json:"bots,omitempty"`
+	Spectators int            `json:"spectators,omitempty"`
+	Sandbox    bool           `json:"sandbox,omitempty"`
+	Practice   bool           `json:"practice,omitempty"`
+	Private    bool           `json:"private,omitempty"`
+	Pinned     bool           `json:"pinned,omitempty"`
+	Options    map[string]int `json:"options,omitempty"`
+	Variant    string         `json:"variant,omitempty"` // the variant Options choose
+	Party      *Party         `json:"party,omitempty"`
+	// OpenSeats counts seats neither taken nor reserved.
+	OpenSeats    int           `json:"openSeats"`
+	Reservations []Reservation `json:"reservations,omitempty"`
+	// Seats lists player IDs by seat, "" for open ones: the choices so far
+	// while waiting, then the match's turn order.
+	Seats     []string  `json:"seats,omitempty"`
+	TurnOrder TurnOrder `json:"turnOrder"`
+	// Abandoned is set when the match was finished because every player
+	// left.
+	Abandoned bool `json:"abandoned,omitempty"`
+	// Error says why an errored session stopped. Its host may restore the
+	// match or abort it.
+	Error string `json:"error,omitempty"`
+	// Removed lists players voted out of the current match.
+	Removed        []string       `json:"removed,omitempty"`
+	VoteThresholds VoteThresholds `json:"voteThresholds"`
+	// Handicaps are the players' uneven starts, by player ID; omitted for
+	// an even game.
+	Handicaps game.Handicaps `json:"handicaps,omitempty"`
+	// Setup is the starting position the host set up; omitted for the
+	// usual start.
+	Setup json.RawMessage `json:"setup,omitempty"`
+	// RulesVersion is the version of the rules the match is played by.
+	// NewerRules, set in the manager's listings, is the version new
+	// matches would play by when it differs.
+	RulesVersion string `json:"rulesVersion,omitempty"`
+	NewerRules   string `json:"newerRules,omitempty"`
+	// ExpireAfter is how long the session may sit idle before cleanup
+	// removes it, such as "168h0m0s", when it sets its own expiry.
+	ExpireAfter string `json:"expireAfter,omitempty"`
+	// Names maps the IDs of players shown by another name, such as
+	// guests, to that name.
+	Names map[string]string `json:"names,omitempty"`
+
+	// Times are RFC 3339 in UTC; clients show them in the viewer's zone.
+	CreatedAt    string `json:"createdAt"`
+	StartedAt    string `json:"startedAt,omitempty"`
+	FinishedAt   string `json:"finishedAt,omitempty"`
+	LastActivity string `json:"lastActivity"`
+}
+
+// rfc3339 formats t for Info, or "" for the zero time.
+func rfc3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
 }
 
 func (s *Session) Info() Info {
@@ -173,19 +592,84 @@ func (s *Session) InfoLocked() Info {
 }
 
 func (s *Session) infoLocked() Info {
-	ids := make([]string, 0, len(s.Players))
-	for id := range s.Players {
-		ids = append(ids, id)
+	var bots []BotInfo
+	for id, p := range s.Players {
+		if p.Strategy != nil {
+			si := p.Strategy.Info()
+			bots = append(bots, BotInfo{PlayerID: id, Strategy: si.Name, Difficulty: si.Difficulty})
+		}
+	}
+	sort.Slice(bots, func(i, j int) bool { return bots[i].PlayerID < bots[j].PlayerID })
+	reservations := s.reservationsLocked(time.Now())
+	open := 0
+	seats := s.seating
+	if s.Status == StatusWaiting {
+		open = max(s.game.Info().MaxPlayers-len(s.Players)-len(reservations), 0)
+		seats = s.seatsLocked()
+	}
+	var failure string
+	if s.Status == StatusErrored {
+		failure = errGameStopped
+	}
+	var variant string
+	if v := game.VariantOf(s.game.Info(), s.Options); v != nil {
+		variant = v.Name
+	}
+	var names map[string]string
+	for _, id := range s.joinOrder {
+		if name, ok := s.Names[id]; ok {
+			if names == nil {
+				names = make(map[string]string)
+			}
+			names[id] = name
+		}
 	}
 	return Info{
-		Code:     s.Code,
-		GameType: s.GameType,
-		Status:   s.Status,
-		Players:  ids,
-		HostID:   s.HostID,
+		Code:       s.Code,
+		GameType:   s.GameType,
+		Status:     s.Status,
+		Players:    slices.Clone(s.joinOrder),
+		HostID:     s.HostID,
+		Bots:       bots,
+		Spectators: len(s.Spectators),
+		Sandbox:    s.Sandbox,
+		Practice:   s.Practice,
+		Private:    s.Private,
+		Pinned:     s.Pinned,
+		Options:    s.Options,
+		Variant:    variant,
+		Party:      s.Party.clone(),
+
+		OpenSeats:      open,
+		Reservations:   reservations,
+		Seats:          seats,
+		TurnOrder:      s.TurnOrder,
+		Abandoned:      s.Abandoned,
+		Error:          failure,
+		Removed:        slices.Clone(s.removed),
+		VoteThresholds: s.VoteThresholds,
+		Handicaps:      s.Handicaps,
+		Setup:          s.Setup,
+		RulesVersion:   s.RulesVersion,
+		ExpireAfter:    expireAfter(s.ExpireAfter),
+		Names:          names,
+
+		CreatedAt:    rfc3339(s.CreatedAt),
+		StartedAt:    rfc3339(s.StartedAt),
+		FinishedAt:   rfc3339(s.FinishedAt),
+		LastActivity: rfc3339(s.LastActivity),
 	}
 }
 
+// TakeMessagesLocked drains the private messages the match has queued for
+// players. The caller must hold the write lock.
+func (s *Session) TakeMessagesLocked() []game.Message {
+	if m, ok := s.Match.(game.Messenger); ok {
+		return m.TakeMessages()
+	}
+	return nil
+}
+
 // Lock/RLock/Unlock/RUnlock expose the mutex for the server's websocket handler.
 func (s *Session) Lock()    { s.mu.Lock() }
 func (s *Session) Unlock()  { s.mu.Unlock() }
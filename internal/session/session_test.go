@@ -1,7 +1,9 @@
 package session
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"regexp"
 	"sort"
 	"testing"
@@ -14,10 +16,7 @@ import (
 
 func setupTest(t *testing.T) (*Manager, func()) {
 	t.Helper()
-	store, err := storage.New(":memory:")
-	if err != nil {
-		t.Fatalf("open db: %v", err)
-	}
+	store := storage.NewMemory()
 	reg := game.NewRegistry()
 	reg.Register(tictactoe.TicTacToe{})
 	mgr := NewManager(reg, store)
@@ -28,7 +27,7 @@ func TestCreateAndJoin(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, err := mgr.Create("tictactoe")
+	sess, err := mgr.Create(t.Context(), "tictactoe")
 	if err != nil {
 		t.Fatalf("create: %v", err)
 	}
@@ -56,7 +55,7 @@ func TestSessionFull(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 
@@ -70,7 +69,7 @@ func TestStartAndPlay(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 
@@ -89,7 +88,7 @@ func TestStartNotEnoughPlayers(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 
 	err := sess.Start()
@@ -109,7 +108,7 @@ func TestPersistence(t *testing.T) {
 	reg.Register(tictactoe.TicTacToe{})
 
 	mgr := NewManager(reg, store)
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 	sess.Start()
@@ -119,13 +118,13 @@ func TestPersistence(t *testing.T) {
 	sess.Match.ApplyAction(sess.PlayerIDs()[0], game.Action{Type: "move", Payload: payload})
 
 	// Save state
-	if err := mgr.SaveMatchState(sess); err != nil {
+	if err := mgr.SaveMatchState(t.Context(), sess); err != nil {
 		t.Fatalf("save: %v", err)
 	}
 
 	// Create new manager from same store, restore
 	mgr2 := NewManager(reg, store)
-	if err := mgr2.Restore(); err != nil {
+	if err := mgr2.Restore(t.Context()); err != nil {
 		t.Fatalf("restore: %v", err)
 	}
 
@@ -141,11 +140,49 @@ func TestPersistence(t *testing.T) {
 	}
 }
 
+func TestCreateWithOptions(t *testing.T) {
+	store, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer store.Close()
+
+	reg := game.NewRegistry()
+	reg.Register(tictactoe.TicTacToe{})
+	mgr := NewManager(reg, store)
+
+	if _, err := mgr.CreateWithOptions(t.Context(), "tictactoe", map[string]int{"misere": 5}); err == nil {
+		t.Fatal("expected error for out-of-range option")
+	}
+	sess, err := mgr.CreateWithOptions(t.Context(), "tictactoe", map[string]int{"misere": 1})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Options survive a restart and reach the match.
+	mgr2 := NewManager(reg, store)
+	if err := mgr2.Restore(t.Context()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	sess2, ok := mgr2.Get(sess.Code)
+	if !ok {
+		t.Fatal("session not restored")
+	}
+	sess2.AddPlayer("alice")
+	sess2.AddPlayer("bob")
+	if err := sess2.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if !sess2.Match.(*tictactoe.Match).Misere {
+		t.Fatal("expected misère match")
+	}
+}
+
 func TestUnknownGameType(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	_, err := mgr.Create("nonexistent")
+	_, err := mgr.Create(t.Context(), "nonexistent")
 	if err == nil {
 		t.Fatal("expected error for unknown game type")
 	}
@@ -157,7 +194,7 @@ func TestRemovePlayer(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 
@@ -181,7 +218,7 @@ func TestRemovePlayerNonexistent(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	// Should not panic
 	sess.RemovePlayer("nobody")
 }
@@ -190,7 +227,7 @@ func TestConnectPlayer(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 
 	newSend := make(chan []byte, 64)
@@ -210,18 +247,49 @@ func TestConnectPlayerNonexistent(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	ok := sess.ConnectPlayer("nobody", make(chan []byte, 1))
 	if ok {
 		t.Fatal("expected ConnectPlayer to return false for unknown player")
 	}
 }
 
+func TestConnectPlayerSeveralTimes(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	first, second := make(chan []byte, 1), make(chan []byte, 1)
+	sess.ConnectPlayer("alice", first)
+	sess.ConnectPlayer("alice", second)
+
+	sess.Broadcast([]byte("hi"))
+	for _, send := range []chan []byte{first, second} {
+		select {
+		case msg := <-send:
+			if string(msg) != "hi" {
+				t.Fatalf("unexpected message %q", msg)
+			}
+		default:
+			t.Fatal("expected every connection to receive the broadcast")
+		}
+	}
+
+	sess.DisconnectPlayer("alice", second)
+	if sends := sess.PlayerSends("alice"); len(sends) != 1 || sends[0] != first {
+		t.Fatalf("expected only the first connection to remain, got %d", len(sends))
+	}
+	if sess.GetPlayer("alice").Send != first {
+		t.Fatal("expected Send to fall back to the remaining connection")
+	}
+}
+
 func TestGetPlayerFound(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 
 	p := sess.GetPlayer("alice")
@@ -237,7 +305,7 @@ func TestGetPlayerNotFound(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	p := sess.GetPlayer("nobody")
 	if p != nil {
 		t.Fatal("expected nil for unknown player")
@@ -248,7 +316,7 @@ func TestBroadcastDelivery(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 
@@ -280,7 +348,7 @@ func TestBroadcastBufferFull(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 
 	p := sess.GetPlayer("alice")
@@ -297,7 +365,7 @@ func TestPlayerIDs(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 
@@ -312,7 +380,7 @@ func TestHostAssignment(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	if sess.Info().HostID != "alice" {
 		t.Fatalf("expected alice as host, got %s", sess.Info().HostID)
@@ -328,7 +396,7 @@ func TestAddPlayerDuplicate(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	err := sess.AddPlayer("alice")
 	if err == nil {
@@ -340,7 +408,7 @@ func TestAddPlayerToStartedSession(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 	sess.Start()
@@ -355,7 +423,7 @@ func TestStartTwice(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 	sess.Start()
@@ -370,7 +438,7 @@ func TestFinish(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 	sess.Start()
@@ -387,10 +455,10 @@ func TestManagerRemove(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	code := sess.Code
 
-	mgr.Remove(code)
+	mgr.Remove(t.Context(), code)
 
 	_, ok := mgr.Get(code)
 	if ok {
@@ -402,8 +470,8 @@ func TestManagerList(t *testing.T) {
 	mgr, cleanup := setupTest(t)
 	defer cleanup()
 
-	mgr.Create("tictactoe")
-	mgr.Create("tictactoe")
+	mgr.Create(t.Context(), "tictactoe")
+	mgr.Create(t.Context(), "tictactoe")
 
 	infos := mgr.List()
 	if len(infos) != 2 {
@@ -422,17 +490,17 @@ func TestManagerSaveSessionPlayers(t *testing.T) {
 	reg.Register(tictactoe.TicTacToe{})
 	mgr := NewManager(reg, store)
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 
-	if err := mgr.SaveSessionPlayers(sess); err != nil {
+	if err := mgr.SaveSessionPlayers(t.Context(), sess); err != nil {
 		t.Fatalf("save session players: %v", err)
 	}
 
 	// Verify roundtrip through the manager's own load method
 	mgr2 := NewManager(reg, store)
-	snap, err := mgr2.loadSessionPlayers(sess.Code)
+	snap, err := mgr2.loadSessionPlayers(t.Context(), sess.Code)
 	if err != nil {
 		t.Fatalf("load session players: %v", err)
 	}
@@ -444,6 +512,75 @@ func TestManagerSaveSessionPlayers(t *testing.T) {
 	}
 }
 
+func TestManagerSaveMatchStart(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+	if err := mgr.SaveMatchStart(t.Context(), sess); err != nil {
+		t.Fatalf("save match start: %v", err)
+	}
+	if row, _ := mgr.store.GetSession(t.Context(), sess.Code); row.Status != string(StatusPlaying) || row.StartedAt.IsZero() {
+		t.Fatalf("expected the session stored as playing, got %+v", row)
+	}
+	if _, err := mgr.store.GetMatchState(t.Context(), sess.Code); err != nil {
+		t.Fatalf("expected the match state stored: %v", err)
+	}
+	if _, err := mgr.store.GetInitialState(t.Context(), sess.Code); err != nil {
+		t.Fatalf("expected the initial state stored: %v", err)
+	}
+	if snap, err := mgr.loadSessionPlayers(t.Context(), sess.Code); err != nil || len(snap.Players) != 2 {
+		t.Fatalf("expected the roster stored, got %+v %v", snap, err)
+	}
+
+	mv := Move{PlayerID: "alice", Action: game.Action{Type: "move", Payload: json.RawMessage(`{"position":4}`)}}
+	if err := mgr.SaveMove(t.Context(), sess, 1, mv); err != nil {
+		t.Fatalf("save move: %v", err)
+	}
+	if err := mgr.SaveMove(t.Context(), sess, 1, mv); err == nil {
+		t.Fatal("expected error saving a move twice")
+	}
+	if moves, _ := mgr.store.ListMoves(t.Context(), sess.Code); len(moves) != 1 {
+		t.Fatalf("expected one move stored, got %+v", moves)
+	}
+}
+
+func TestManagerSaveAfterCancel(t *testing.T) {
+	store, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(tictactoe.TicTacToe{})
+	mgr := NewManager(reg, store)
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+
+	// The client is gone, but the match already started in memory
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := mgr.SaveMatchStart(ctx, sess); err != nil {
+		t.Fatalf("save match start: %v", err)
+	}
+	mv := Move{PlayerID: "alice", Action: game.Action{Type: "move", Payload: json.RawMessage(`{"position":4}`)}}
+	if err := mgr.SaveMove(ctx, sess, 1, mv); err != nil {
+		t.Fatalf("save move: %v", err)
+	}
+	if moves, _ := mgr.store.ListMoves(t.Context(), sess.Code); len(moves) != 1 {
+		t.Fatalf("expected the move stored, got %+v", moves)
+	}
+	if _, err := mgr.GameStats(ctx, "tictactoe"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a read for a cancelled request to fail, got %v", err)
+	}
+}
+
 func TestManagerCleanupFinished(t *testing.T) {
 	store, err := storage.New(":memory:")
 	if err != nil {
@@ -455,7 +592,7 @@ func TestManagerCleanupFinished(t *testing.T) {
 	reg.Register(tictactoe.TicTacToe{})
 	mgr := NewManager(reg, store)
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	sess.AddPlayer("bob")
 	sess.Start()
@@ -463,7 +600,7 @@ func TestManagerCleanupFinished(t *testing.T) {
 	code := sess.Code
 
 	// Cleanup with maxAge=0 should remove finished sessions
-	mgr.cleanup(0)
+	mgr.cleanupPass(t.Context(), 0, false)
 
 	_, ok := mgr.Get(code)
 	if ok {
@@ -482,11 +619,11 @@ func TestManagerCleanupEmpty(t *testing.T) {
 	reg.Register(tictactoe.TicTacToe{})
 	mgr := NewManager(reg, store)
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	code := sess.Code
 	// No players added — empty session
 
-	mgr.cleanup(time.Hour)
+	mgr.cleanupPass(t.Context(), time.Hour, false)
 
 	_, ok := mgr.Get(code)
 	if ok {
@@ -505,11 +642,11 @@ func TestManagerCleanupKeepsActive(t *testing.T) {
 	reg.Register(tictactoe.TicTacToe{})
 	mgr := NewManager(reg, store)
 
-	sess, _ := mgr.Create("tictactoe")
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
 	sess.AddPlayer("alice")
 	code := sess.Code
 
-	mgr.cleanup(time.Hour)
+	mgr.cleanupPass(t.Context(), time.Hour, false)
 
 	_, ok := mgr.Get(code)
 	if !ok {
@@ -526,3 +663,118 @@ func TestGenerateCodeFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestAddBot(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	id, err := sess.AddBot(tictactoe.PerfectBot{})
+	if err != nil {
+		t.Fatalf("add bot: %v", err)
+	}
+	if id != "bot-perfect" {
+		t.Fatalf("expected bot-perfect, got %s", id)
+	}
+	info := sess.Info()
+	if len(info.Bots) != 1 || info.Bots[0].Difficulty != 3 || info.Bots[0].Strategy != "perfect" {
+		t.Fatalf("unexpected bots in info: %+v", info.Bots)
+	}
+	if _, err := sess.AddBot(tictactoe.RandomBot{}); err == nil {
+		t.Fatal("expected error adding bot to full session")
+	}
+
+	sess.Start()
+	sess.RLock()
+	_, ok := sess.NextBotMove()
+	sess.RUnlock()
+	if ok {
+		t.Fatal("expected alice, who joined first, to move before the bot")
+	}
+}
+
+func TestAddBotUniqueIDs(t *testing.T) {
+	store, _ := storage.New(":memory:")
+	defer store.Close()
+	reg := game.NewRegistry()
+	reg.Register(game3{})
+	mgr := NewManager(reg, store)
+
+	sess, _ := mgr.Create(t.Context(), "three")
+	a, _ := sess.AddBot(tictactoe.RandomBot{})
+	b, _ := sess.AddBot(tictactoe.RandomBot{})
+	if a == b || b != "bot-random-2" {
+		t.Fatalf("expected distinct bot IDs, got %s and %s", a, b)
+	}
+}
+
+// game3 is a tic-tac-toe variant that seats three players, for roster tests.
+type game3 struct{ tictactoe.TicTacToe }
+
+func (game3) Info() game.GameInfo {
+	return game.GameInfo{Name: "three", MinPlayers: 2, MaxPlayers: 3}
+}
+
+func TestKickReassignsHost(t *testing.T) {
+	s := NewSession("abc", "game3", game3{})
+	s.AddPlayer("alice")
+	s.AddPlayer("bob")
+	kicked := s.GetPlayer("alice").Kicked
+
+	if !s.Kick("alice") {
+		t.Fatal("expected kick to succeed")
+	}
+	select {
+	case <-kicked:
+	default:
+		t.Fatal("expected Kicked to be closed")
+	}
+	if info := s.Info(); info.HostID != "bob" || len(info.Players) != 1 {
+		t.Fatalf("unexpected info after kick: %+v", info)
+	}
+	if s.Kick("alice") {
+		t.Fatal("expected second kick to fail")
+	}
+}
+
+func TestSessionTimesPersistAndDriveCleanup(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	info := sess.Info()
+	if _, err := time.Parse(time.RFC3339, info.CreatedAt); err != nil || info.StartedAt != "" {
+		t.Fatalf("expected only creation times before start, got %+v", info)
+	}
+	sess.Start()
+	mgr.SaveMatchState(t.Context(), sess)
+	if sess.Info().StartedAt == "" {
+		t.Fatal("expected startedAt once the match starts")
+	}
+
+	sess.Finish()
+	sess.Lock()
+	sess.LastActivity = time.Now().Add(-time.Hour)
+	sess.Unlock()
+	mgr.SaveMatchState(t.Context(), sess)
+	row, err := mgr.store.GetSession(t.Context(), sess.Code)
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if row.StartedAt.IsZero() || row.FinishedAt.IsZero() || time.Since(row.LastActivity) < 59*time.Minute {
+		t.Fatalf("expected stored times, got %+v", row)
+	}
+
+	// Recently finished sessions stay; ones idle past the limit go.
+	mgr.cleanupPass(t.Context(), 2*time.Hour, false)
+	if _, ok := mgr.Get(sess.Code); !ok {
+		t.Fatal("session cleaned up before it was idle long enough")
+	}
+	mgr.cleanupPass(t.Context(), 30*time.Minute, false)
+	if _, ok := mgr.Get(sess.Code); ok {
+		t.Fatal("expected the idle finished session to be cleaned up")
+	}
+}
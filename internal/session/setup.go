@@ -0,0 +1,39 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"games/internal/game"
+)
+
+// SetSetup sets the starting position of a waiting session's next match,
+// as checked by its game, and persists it. An empty setup brings back the
+// usual start.
+func (m *Manager) SetSetup(ctx context.Context, s *Session, setup json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Status != StatusWaiting {
+		return fmt.Errorf("the starting position can only be changed before the game starts")
+	}
+	if len(setup) == 0 || string(setup) == "null" {
+		setup = nil
+	}
+	if setup != nil {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, setup); err != nil {
+			return fmt.Errorf("invalid setup: %w", err)
+		}
+		setup = compact.Bytes()
+		if err := Protect(func() error { return game.ValidateSetup(s.game, setup) }); err != nil {
+			return err
+		}
+	}
+	if err := m.store.SetSessionSetup(ctx, s.Code, string(setup)); err != nil {
+		return fmt.Errorf("persist setup: %w", err)
+	}
+	s.Setup = setup
+	return nil
+}
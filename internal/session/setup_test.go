@@ -0,0 +1,81 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"games/internal/game"
+	"games/internal/game/tictactoe"
+)
+
+// fixedGame is tic-tac-toe seen only through game.Game, so it offers no
+// setup.
+type fixedGame struct{ game.Game }
+
+func (g fixedGame) Info() game.GameInfo {
+	info := g.Game.Info()
+	info.Name = "fixed"
+	return info
+}
+
+func TestSetup(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := t.Context()
+
+	sess, _ := mgr.Create(ctx, "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+
+	if err := mgr.SetSetup(ctx, sess, json.RawMessage(`{"board":"XX......."}`)); err == nil {
+		t.Fatal("expected a setup no match could reach refused")
+	}
+	if err := mgr.SetSetup(ctx, sess, json.RawMessage(`{ "board": "X...O....", "turn": "X" }`)); err != nil {
+		t.Fatalf("set setup: %v", err)
+	}
+	if got := string(sess.Info().Setup); got != `{"board":"X...O....","turn":"X"}` {
+		t.Fatalf("expected the setup kept, got %s", got)
+	}
+
+	// The setup survives a restart.
+	mgr2 := NewManager(mgr.registry, mgr.store)
+	if err := mgr2.Restore(ctx); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored, _ := mgr2.Get(sess.Code); string(restored.Info().Setup) != `{"board":"X...O....","turn":"X"}` {
+		t.Fatalf("expected the setup restored, got %s", restored.Info().Setup)
+	}
+
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	m := sess.Match.(*tictactoe.Match)
+	if m.Board != [9]int{1, 0, 0, 0, 2, 0, 0, 0, 0} || m.Turn != 0 {
+		t.Fatalf("expected the match to start from the setup, got %+v", m)
+	}
+	if err := mgr.SetSetup(ctx, sess, nil); err == nil {
+		t.Fatal("expected the setup fixed once the match starts")
+	}
+	tr, err := sess.Transcript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := Replay(mgr.registry, tr)
+	if err != nil || replayed.(*tictactoe.Match).Board != m.Board {
+		t.Fatalf("expected the transcript to replay the setup, got %v %v", replayed, err)
+	}
+	tr.Setup = json.RawMessage(`{"board":"XXX......"}`)
+	if _, err := Replay(mgr.registry, tr); err == nil {
+		t.Fatal("expected a transcript with a bad setup refused")
+	}
+
+	mgr.registry.Register(fixedGame{tictactoe.TicTacToe{}})
+	fixed, _ := mgr.Create(ctx, "fixed")
+	if err := mgr.SetSetup(ctx, fixed, json.RawMessage(`{"board":"........."}`)); !errors.Is(err, game.ErrNoPositionSetup) {
+		t.Fatalf("expected a game without setup refused, got %v", err)
+	}
+	if err := mgr.SetSetup(ctx, fixed, nil); err != nil {
+		t.Fatalf("expected the usual start kept, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"games/internal/event"
+)
+
+// Friend is an accepted friend and whether they are connected right now.
+type Friend struct {
+	PlayerID string `json:"playerId"`
+	Online   bool   `json:"online"`
+}
+
+// FriendList is a player's friends and open friend requests.
+type FriendList struct {
+	Friends  []Friend `json:"friends"`
+	Incoming []string `json:"incoming"` // requests awaiting this player's answer
+	Outgoing []string `json:"outgoing"` // requests this player has sent
+}
+
+// RecentOpponent is someone a player has recently shared a match with.
+type RecentOpponent struct {
+	PlayerID    string    `json:"playerId"`
+	GameType    string    `json:"gameType"`
+	SessionCode string    `json:"sessionCode"`
+	PlayedAt    time.Time `json:"playedAt"`
+	Online      bool      `json:"online"`
+	Friend      bool      `json:"friend"`
+}
+
+// RequestFriend sends a friend request from one player to another. If the
+// other player has already asked, the two become friends immediately.
+func (m *Manager) RequestFriend(ctx context.Context, fromID, toID string) error {
+	fromID, toID = strings.TrimSpace(fromID), strings.TrimSpace(toID)
+	if fromID == "" || toID == "" {
+		return fmt.Errorf("player IDs required")
+	}
+	if fromID == toID {
+		return fmt.Errorf("cannot befriend yourself")
+	}
+	existing, err := m.store.GetFriendship(ctx, fromID, toID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if err := m.store.CreateFriendRequest(ctx, fromID, toID); err != nil {
+			return fmt.Errorf("persist friend request: %w", err)
+		}
+		m.publishSocial(event.FriendRequested, []string{fromID, toID}, toID)
+		return nil
+	case err != nil:
+		return err
+	case existing.Status == "accepted":
+		return fmt.Errorf("already friends with %s", toID)
+	case existing.RequesterID == fromID:
+		return fmt.Errorf("friend request already sent")
+	default:
+		return m.AcceptFriend(ctx, fromID, toID)
+	}
+}
+
+// AcceptFriend accepts the pending request that requesterID sent playerID.
+func (m *Manager) AcceptFriend(ctx context.Context, playerID, requesterID string) error {
+	ok, err := m.store.AcceptFriendRequest(ctx, requesterID, playerID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no friend request from %s", requesterID)
+	}
+	m.publishSocial(event.FriendAccepted, []string{requesterID, playerID}, requesterID)
+	return nil
+}
+
+// RemoveFriend unfriends, declines, or withdraws a request, whichever
+// applies between the two players.
+func (m *Manager) RemoveFriend(ctx context.Context, playerID, otherID string) error {
+	ok, err := m.store.DeleteFriendship(ctx, playerID, otherID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no friendship with %s", otherID)
+	}
+	return nil
+}
+
+// Friends returns playerID's friends, with online status, and pending
+// requests.
+func (m *Manager) Friends(ctx context.Context, playerID string) (FriendList, error) {
+	rows, err := m.store.ListFriendships(ctx, playerID)
+	if err != nil {
+		return FriendList{}, err
+	}
+	list := FriendList{Friends: []Friend{}, Incoming: []string{}, Outgoing: []string{}}
+	for _, f := range rows {
+		other := f.AddresseeID
+		if other == playerID {
+			other = f.RequesterID
+		}
+		switch {
+		case f.Status == "accepted":
+			list.Friends = append(list.Friends, Friend{PlayerID: other, Online: m.IsOnline(other)})
+		case f.RequesterID == playerID:
+			list.Outgoing = append(list.Outgoing, other)
+		default:
+			list.Incoming = append(list.Incoming, other)
+		}
+	}
+	return list, nil
+}
+
+// RecentOpponents returns up to limit players that playerID recently shared
+// a match with, most recent first.
+func (m *Manager) RecentOpponents(ctx context.Context, playerID string, limit int) ([]RecentOpponent, error) {
+	rows, err := m.store.ListRecentOpponents(ctx, playerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	friends, err := m.Friends(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	isFriend := make(map[string]bool, len(friends.Friends))
+	for _, f := range friends.Friends {
+		isFriend[f.PlayerID] = true
+	}
+	opponents := make([]RecentOpponent, 0, len(rows))
+	for _, o := range rows {
+		opponents = append(opponents, RecentOpponent{
+			PlayerID:    o.PlayerID,
+			GameType:    o.GameType,
+			SessionCode: o.SessionCode,
+			PlayedAt:    o.PlayedAt,
+			Online:      m.IsOnline(o.PlayerID),
+			Friend:      isFriend[o.PlayerID],
+		})
+	}
+	return opponents, nil
+}
+
+// RecordMatchPlayers remembers the human players of a newly started match
+// for recent-opponent lists. Bots are not recorded.
+func (m *Manager) RecordMatchPlayers(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.RLock()
+	var humans []string
+	for id, p := range s.Players {
+		if p.Strategy == nil && !strings.HasPrefix(id, ExternalBotPrefix) {
+			humans = append(humans, id)
+		}
+	}
+	s.mu.RUnlock()
+	if len(humans) < 2 {
+		return nil
+	}
+	return m.store.RecordMatchPlayers(ctx, s.Code, s.GameType, humans)
+}
+
+// PlayerConnected notes that playerID opened a connection. Each call must
+// be paired with PlayerDisconnected.
+func (m *Manager) PlayerConnected(playerID string) {
+	m.presenceMu.Lock()
+	defer m.presenceMu.Unlock()
+	m.online[playerID]++
+}
+
+// PlayerDisconnected notes that one of playerID's connections closed.
+func (m *Manager) PlayerDisconnected(playerID string) {
+	m.presenceMu.Lock()
+	defer m.presenceMu.Unlock()
+	if m.online[playerID]--; m.online[playerID] <= 0 {
+		delete(m.online, playerID)
+	}
+}
+
+// IsOnline reports whether playerID has any open connection.
+func (m *Manager) IsOnline(playerID string) bool {
+	m.presenceMu.Lock()
+	defer m.presenceMu.Unlock()
+	return m.online[playerID] > 0
+}
+
+func (m *Manager) publishSocial(typ string, players []string, recipients ...string) {
+	m.events.Publish(event.Event{Type: typ, Players: players, Recipients: recipients})
+}
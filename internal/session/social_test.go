@@ -0,0 +1,104 @@
+package session
+
+import "testing"
+
+func TestFriendRequests(t *testing.T) {
+	mgr := setupBotTest(t)
+
+	if err := mgr.RequestFriend(t.Context(), "alice", "alice"); err == nil {
+		t.Fatal("expected error befriending yourself")
+	}
+	if err := mgr.RequestFriend(t.Context(), "alice", "bob"); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if err := mgr.RequestFriend(t.Context(), "alice", "bob"); err == nil {
+		t.Fatal("expected duplicate request to fail")
+	}
+	bob, _ := mgr.Friends(t.Context(), "bob")
+	if len(bob.Incoming) != 1 || bob.Incoming[0] != "alice" || len(bob.Friends) != 0 {
+		t.Fatalf("unexpected list for bob: %+v", bob)
+	}
+
+	// Asking back accepts the open request
+	if err := mgr.RequestFriend(t.Context(), "bob", "alice"); err != nil {
+		t.Fatalf("mutual request: %v", err)
+	}
+	mgr.PlayerConnected("bob")
+	alice, _ := mgr.Friends(t.Context(), "alice")
+	if len(alice.Friends) != 1 || alice.Friends[0] != (Friend{PlayerID: "bob", Online: true}) {
+		t.Fatalf("unexpected list for alice: %+v", alice)
+	}
+	mgr.PlayerDisconnected("bob")
+	if mgr.IsOnline("bob") {
+		t.Fatal("bob should be offline after disconnecting")
+	}
+
+	if err := mgr.RemoveFriend(t.Context(), "bob", "alice"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if alice, _ := mgr.Friends(t.Context(), "alice"); len(alice.Friends) != 0 {
+		t.Fatalf("expected no friends after removal, got %+v", alice)
+	}
+}
+
+func TestRecentOpponentsSkipsBots(t *testing.T) {
+	mgr := setupBotTest(t)
+	strategy, _ := mgr.registry.Strategy("tictactoe", "random")
+
+	s, _ := mgr.Create(t.Context(), "tictactoe")
+	s.AddPlayer("alice")
+	s.AddBot(strategy)
+	s.Start()
+	if err := mgr.RecordMatchPlayers(t.Context(), s); err != nil {
+		t.Fatalf("record bot match: %v", err)
+	}
+
+	s, _ = mgr.Create(t.Context(), "tictactoe")
+	s.AddPlayer("alice")
+	s.AddPlayer("bob")
+	s.Start()
+	if err := mgr.RecordMatchPlayers(t.Context(), s); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	opponents, err := mgr.RecentOpponents(t.Context(), "alice", 10)
+	if err != nil {
+		t.Fatalf("recent: %v", err)
+	}
+	if len(opponents) != 1 || opponents[0].PlayerID != "bob" || opponents[0].SessionCode != s.Code {
+		t.Fatalf("expected only bob, got %+v", opponents)
+	}
+}
+
+func TestPlayerSessions(t *testing.T) {
+	mgr := setupBotTest(t)
+
+	waiting, _ := mgr.Create(t.Context(), "tictactoe")
+	waiting.AddPlayer("alice")
+	mgr.SaveSessionPlayers(t.Context(), waiting)
+	finished, _ := mgr.Create(t.Context(), "tictactoe")
+	finished.AddPlayer("alice")
+	finished.AddPlayer("bob")
+	finished.Start()
+	mgr.SaveMatchStart(t.Context(), finished)
+	finished.Finish()
+	mgr.SaveMatchState(t.Context(), finished)
+
+	// Seats are found in storage, whether or not the session is loaded
+	restarted := NewManager(mgr.registry, mgr.store)
+	for _, m := range []*Manager{mgr, restarted} {
+		listings, err := m.PlayerSessions(t.Context(), "alice", true)
+		if err != nil {
+			t.Fatalf("player sessions: %v", err)
+		}
+		if len(listings) != 1 || listings[0].Code != waiting.Code {
+			t.Fatalf("expected only the waiting session, got %+v", listings)
+		}
+	}
+	if err := mgr.Kick(waiting.Code, "alice"); err != nil {
+		t.Fatalf("kick: %v", err)
+	}
+	if listings, _ := mgr.PlayerSessions(t.Context(), "alice", true); len(listings) != 0 {
+		t.Fatalf("expected a kicked player's seat gone, got %+v", listings)
+	}
+}
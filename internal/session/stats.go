@@ -0,0 +1,139 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"games/internal/game"
+	"games/internal/storage"
+)
+
+// MatchSummary is when a finished match was played and how long it ran.
+type MatchSummary struct {
+	StartedAt  string `json:"startedAt"`
+	FinishedAt string `json:"finishedAt"`
+	DurationMs int64  `json:"durationMs"`
+	Moves      int    `json:"moves"`
+}
+
+func newMatchSummary(started, finished time.Time, moves int) MatchSummary {
+	return MatchSummary{
+		StartedAt:  rfc3339(started),
+		FinishedAt: rfc3339(finished),
+		DurationMs: finished.Sub(started).Milliseconds(),
+		Moves:      moves,
+	}
+}
+
+// ArchivedMatch is a finished match from the archive.
+type ArchivedMatch struct {
+	SessionCode string `json:"sessionCode"`
+	GameType    string `json:"gameType"`
+	Abandoned   bool   `json:"abandoned,omitempty"`
+	// Handicaps are the players' uneven starts, so rated play can leave
+	// the match out or adjust for them; omitted for an even match.
+	Handicaps game.Handicaps `json:"handicaps,omitempty"`
+	MatchSummary
+}
+
+// GameStats aggregates the archived matches of one game type. The
+// averages cover matches played out, not abandoned ones.
+type GameStats struct {
+	GameType string `json:"gameType"`
+	// Season names the season counted, when the leaderboards run in
+	// seasons; omitted when they cover all time.
+	Season        string  `json:"season,omitempty"`
+	Matches       int     `json:"matches"`
+	Abandoned     int     `json:"abandoned"`
+	AvgDurationMs int64   `json:"avgDurationMs"`
+	AvgMoves      float64 `json:"avgMoves"`
+}
+
+// SummaryLocked returns the summary of a finished match, or nil while the
+// match is still in play. The caller must hold the lock.
+func (s *Session) SummaryLocked() *MatchSummary {
+	if s.Status != StatusFinished || s.StartedAt.IsZero() || s.FinishedAt.IsZero() {
+		return nil
+	}
+	summary := newMatchSummary(s.StartedAt, s.FinishedAt, len(s.History))
+	return &summary
+}
+
+// ArchiveMatch records a finished match's times and move count. Unlike
+// the session itself the record is kept after cleanup.
+func (m *Manager) ArchiveMatch(ctx context.Context, s *Session) error {
+	ctx = detach(ctx)
+	s.mu.RLock()
+	if s.Status != StatusFinished || s.StartedAt.IsZero() || s.FinishedAt.IsZero() {
+		s.mu.RUnlock()
+		return fmt.Errorf("match is not finished")
+	}
+	row := storage.ArchivedMatchRow{
+		SessionCode: s.Code,
+		GameType:    s.GameType,
+		Moves:       len(s.History),
+		Abandoned:   s.Abandoned,
+		StartedAt:   s.StartedAt,
+		FinishedAt:  s.FinishedAt,
+	}
+	if h := s.Handicaps.Only(s.seating); h != nil {
+		data, _ := json.Marshal(h)
+		row.Handicaps = string(data)
+	}
+	s.mu.RUnlock()
+	return m.store.ArchiveMatch(ctx, row)
+}
+
+// ArchivedMatches returns up to limit archived matches of a game type,
+// most recently finished first.
+func (m *Manager) ArchivedMatches(ctx context.Context, gameType string, limit int) ([]ArchivedMatch, error) {
+	rows, err := m.store.ListArchivedMatches(ctx, gameType, limit)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]ArchivedMatch, len(rows))
+	for i, r := range rows {
+		matches[i] = ArchivedMatch{
+			SessionCode:  r.SessionCode,
+			GameType:     r.GameType,
+			Abandoned:    r.Abandoned,
+			MatchSummary: newMatchSummary(r.StartedAt, r.FinishedAt, r.Moves),
+		}
+		if r.Handicaps != "" {
+			if err := json.Unmarshal([]byte(r.Handicaps), &matches[i].Handicaps); err != nil {
+				return nil, fmt.Errorf("unmarshal handicaps of %s: %w", r.SessionCode, err)
+			}
+		}
+	}
+	return matches, nil
+}
+
+// GameStats aggregates the archived matches of a game type, those of the
+// current season when the leaderboards run in seasons.
+func (m *Manager) GameStats(ctx context.Context, gameType string) (GameStats, error) {
+	season := m.CurrentSeason()
+	if season == nil {
+		return m.gameStats(ctx, gameType, time.Time{}, time.Time{})
+	}
+	stats, err := m.gameStats(ctx, gameType, season.Start, time.Time{})
+	stats.Season = season.Name
+	return stats, err
+}
+
+// gameStats aggregates the archived matches of a game type that finished
+// in the given period; a zero time leaves that end open.
+func (m *Manager) gameStats(ctx context.Context, gameType string, from, to time.Time) (GameStats, error) {
+	row, err := m.store.MatchStats(ctx, gameType, from, to)
+	if err != nil {
+		return GameStats{}, err
+	}
+	return GameStats{
+		GameType:      gameType,
+		Matches:       row.Matches,
+		Abandoned:     row.Abandoned,
+		AvgDurationMs: row.AvgDuration.Milliseconds(),
+		AvgMoves:      row.AvgMoves,
+	}, nil
+}
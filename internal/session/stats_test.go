@@ -0,0 +1,48 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiveMatch(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	sess.AddPlayer("bob")
+	sess.Start()
+	if err := mgr.ArchiveMatch(t.Context(), sess); err == nil {
+		t.Fatal("expected a match in play not to be archived")
+	}
+	if sess.SummaryLocked() != nil {
+		t.Fatal("expected no summary while the match is in play")
+	}
+
+	sess.History = append(sess.History, Move{PlayerID: "alice"}, Move{PlayerID: "bob"}, Move{PlayerID: "alice"})
+	sess.Finish()
+	sess.StartedAt = sess.FinishedAt.Add(-90 * time.Second)
+	summary := sess.SummaryLocked()
+	if summary == nil || summary.Moves != 3 || summary.DurationMs != 90000 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if err := mgr.ArchiveMatch(t.Context(), sess); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	matches, err := mgr.ArchivedMatches(t.Context(), "tictactoe", 10)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one archived match, got %+v %v", matches, err)
+	}
+	if m := matches[0]; m.SessionCode != sess.Code || m.Moves != 3 || m.DurationMs != 90000 {
+		t.Fatalf("unexpected archived match %+v", m)
+	}
+	stats, err := mgr.GameStats(t.Context(), "tictactoe")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if stats.Matches != 1 || stats.AvgMoves != 3 || stats.AvgDurationMs != 90000 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
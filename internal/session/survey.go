@@ -0,0 +1,181 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"games/internal/storage"
+)
+
+// MaxSurveyText bounds a survey's comment and bug report, in characters.
+const MaxSurveyText = 2000
+
+// ErrNoSurvey is returned for an answer to a survey the player was not
+// asked, or has answered already.
+var ErrNoSurvey = errors.New("no survey to answer for this match")
+
+// SurveyResponse is a player's answer to the survey after a match.
+// Ratings run from 1 to 5, with 0 for a question skipped.
+type SurveyResponse struct {
+	Fun     int    `json:"fun,omitempty"`
+	Conduct int    `json:"conduct,omitempty"` // how the opponents behaved
+	Comment string `json:"comment,omitempty"` // about the opponents
+	Bug     string `json:"bug,omitempty"`     // what went wrong, for a bug report
+}
+
+// Validate checks the ratings and the lengths of the texts.
+func (r SurveyResponse) Validate() error {
+	if r.Fun < 0 || r.Fun > 5 || r.Conduct < 0 || r.Conduct > 5 {
+		return fmt.Errorf("survey ratings must be between 1 and 5, or 0 to skip")
+	}
+	if utf8.RuneCountInString(r.Comment) > MaxSurveyText || utf8.RuneCountInString(r.Bug) > MaxSurveyText {
+		return fmt.Errorf("survey answers are limited to %d characters", MaxSurveyText)
+	}
+	return nil
+}
+
+// Survey is a stored answer, as admins read it.
+type Survey struct {
+	ID          int64  `json:"id"`
+	SessionCode string `json:"sessionCode"`
+	GameType    string `json:"gameType"`
+	PlayerID    string `json:"playerId"`
+	SurveyResponse
+	// Transcript is the match's transcript, attached to bug reports so
+	// the match can be replayed.
+	Transcript json.RawMessage `json:"transcript,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// pendingSurvey is the survey a player was last asked and may answer.
+type pendingSurvey struct {
+	sessionCode string
+	gameType    string
+	transcript  string // JSON; empty when the match has none
+}
+
+// SetSurveyInterval asks players about their matches, each player at most
+// once an interval; zero or less asks no one.
+func (m *Manager) SetSurveyInterval(d time.Duration) {
+	m.surveyMu.Lock()
+	defer m.surveyMu.Unlock()
+	m.surveyInterval = d
+}
+
+// PromptSurveys picks, of the human players of the match that just
+// finished in s, those to ask about it: the ones not asked in the last
+// interval. Each may then answer once with SubmitSurvey, until asked
+// about another match.
+func (m *Manager) PromptSurveys(s *Session, playerIDs []string) []string {
+	m.surveyMu.Lock()
+	defer m.surveyMu.Unlock()
+	if m.surveyInterval <= 0 {
+		return nil
+	}
+	var transcript string
+	if t, err := s.Transcript(); err == nil {
+		data, _ := json.Marshal(t)
+		transcript = string(data)
+	}
+	now := time.Now()
+	var prompted []string
+	for _, id := range playerIDs {
+		if s.GetPlayer(id) == nil || s.IsBot(id) || strings.HasPrefix(id, ExternalBotPrefix) {
+			continue
+		}
+		if last, ok := m.surveyed[id]; ok && now.Sub(last) < m.surveyInterval {
+			continue
+		}
+		m.surveyed[id] = now
+		m.pendingSurveys[id] = pendingSurvey{sessionCode: s.Code, gameType: s.GameType, transcript: transcript}
+		prompted = append(prompted, id)
+	}
+	return prompted
+}
+
+// forgetSurveys drops the surveys about the session code, which is gone,
+// and when players were asked, for those asked over an interval ago, whom
+// it no longer holds back.
+func (m *Manager) forgetSurveys(code string) {
+	m.surveyMu.Lock()
+	defer m.surveyMu.Unlock()
+	for id, pending := range m.pendingSurveys {
+		if pending.sessionCode == code {
+			delete(m.pendingSurveys, id)
+		}
+	}
+	now := time.Now()
+	for id, last := range m.surveyed {
+		if now.Sub(last) >= m.surveyInterval {
+			delete(m.surveyed, id)
+		}
+	}
+}
+
+// SubmitSurvey stores playerID's answer to the survey about the match in
+// the session code, attaching the match's transcript to a bug report. An
+// answer with nothing in it dismisses the survey.
+func (m *Manager) SubmitSurvey(ctx context.Context, code, playerID string, r SurveyResponse) error {
+	r.Comment, r.Bug = strings.TrimSpace(r.Comment), strings.TrimSpace(r.Bug)
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	m.surveyMu.Lock()
+	pending, ok := m.pendingSurveys[playerID]
+	if ok && pending.sessionCode == code {
+		delete(m.pendingSurveys, playerID)
+	}
+	m.surveyMu.Unlock()
+	if !ok || pending.sessionCode != code {
+		return ErrNoSurvey
+	}
+	if r == (SurveyResponse{}) {
+		return nil
+	}
+	row := storage.SurveyRow{
+		SessionCode: code,
+		GameType:    pending.gameType,
+		PlayerID:    playerID,
+		Fun:         r.Fun,
+		Conduct:     r.Conduct,
+		Comment:     r.Comment,
+		Bug:         r.Bug,
+	}
+	if r.Bug != "" {
+		row.Transcript = pending.transcript
+	}
+	return m.store.SaveSurvey(ctx, row)
+}
+
+// Surveys returns the stored answers matching f, newest first.
+func (m *Manager) Surveys(ctx context.Context, f storage.SurveyFilter) ([]Survey, error) {
+	rows, err := m.store.ListSurveys(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	surveys := make([]Survey, len(rows))
+	for i, r := range rows {
+		surveys[i] = Survey{
+			ID:          r.ID,
+			SessionCode: r.SessionCode,
+			GameType:    r.GameType,
+			PlayerID:    r.PlayerID,
+			SurveyResponse: SurveyResponse{
+				Fun:     r.Fun,
+				Conduct: r.Conduct,
+				Comment: r.Comment,
+				Bug:     r.Bug,
+			},
+			CreatedAt: r.CreatedAt,
+		}
+		if r.Transcript != "" {
+			surveys[i].Transcript = json.RawMessage(r.Transcript)
+		}
+	}
+	return surveys, nil
+}
@@ -0,0 +1,114 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"games/internal/game/tictactoe"
+	"games/internal/storage"
+)
+
+func TestSurveys(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+
+	sess := playCells(t, mgr, 0, 3, 1, 4, 2)
+	if got := mgr.PromptSurveys(sess, []string{"alice", "bob"}); got != nil {
+		t.Fatalf("expected no one asked with surveys off, got %v", got)
+	}
+	mgr.SetSurveyInterval(time.Hour)
+	if got := mgr.PromptSurveys(sess, []string{"alice", "bob", "carol"}); !slices.Equal(got, []string{"alice", "bob"}) {
+		t.Fatalf("expected the session's players asked, got %v", got)
+	}
+	// Once an interval
+	if got := mgr.PromptSurveys(sess, []string{"alice", "bob"}); got != nil {
+		t.Fatalf("expected no one asked twice in an hour, got %v", got)
+	}
+
+	if err := mgr.SubmitSurvey(t.Context(), sess.Code, "alice", SurveyResponse{Fun: 6}); err == nil {
+		t.Fatal("expected a rating over 5 refused")
+	}
+	if err := mgr.SubmitSurvey(t.Context(), "ZZZZ", "alice", SurveyResponse{Fun: 4}); !errors.Is(err, ErrNoSurvey) {
+		t.Fatalf("expected an answer about another match refused, got %v", err)
+	}
+	if err := mgr.SubmitSurvey(t.Context(), sess.Code, "carol", SurveyResponse{Fun: 4}); !errors.Is(err, ErrNoSurvey) {
+		t.Fatalf("expected an answer from someone not asked refused, got %v", err)
+	}
+	if err := mgr.SubmitSurvey(t.Context(), sess.Code, "alice", SurveyResponse{Fun: 5, Conduct: 4, Comment: " friendly "}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SubmitSurvey(t.Context(), sess.Code, "alice", SurveyResponse{Fun: 1}); !errors.Is(err, ErrNoSurvey) {
+		t.Fatalf("expected a second answer refused, got %v", err)
+	}
+	if err := mgr.SubmitSurvey(t.Context(), sess.Code, "bob", SurveyResponse{Bug: "the last move did not show"}); err != nil {
+		t.Fatal(err)
+	}
+
+	surveys, err := mgr.Surveys(t.Context(), storage.SurveyFilter{})
+	if err != nil || len(surveys) != 2 {
+		t.Fatalf("expected two answers, got %+v %v", surveys, err)
+	}
+	bug, rating := surveys[0], surveys[1]
+	if rating.PlayerID != "alice" || rating.Fun != 5 || rating.Conduct != 4 || rating.Comment != "friendly" || rating.Transcript != nil {
+		t.Fatalf("unexpected rating %+v", rating)
+	}
+	if bug.PlayerID != "bob" || bug.GameType != "tictactoe" || bug.SessionCode != sess.Code || bug.Transcript == nil {
+		t.Fatalf("expected bob's bug report with the transcript, got %+v", bug)
+	}
+	var tr Transcript
+	if err := json.Unmarshal(bug.Transcript, &tr); err != nil || len(tr.Moves) != 5 {
+		t.Fatalf("expected the match's five moves attached, got %+v %v", tr, err)
+	}
+}
+
+func TestSurveySkipsBots(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	mgr.SetSurveyInterval(time.Hour)
+
+	sess, _ := mgr.Create(t.Context(), "tictactoe")
+	sess.AddPlayer("alice")
+	bot, err := sess.AddBot(tictactoe.Strategies()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.PromptSurveys(sess, []string{"alice", bot, ExternalBotPrefix + "x"}); !slices.Equal(got, []string{"alice"}) {
+		t.Fatalf("expected only alice asked, got %v", got)
+	}
+	// Dismissing stores nothing
+	if err := mgr.SubmitSurvey(t.Context(), sess.Code, "alice", SurveyResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if surveys, _ := mgr.Surveys(t.Context(), storage.SurveyFilter{}); len(surveys) != 0 {
+		t.Fatalf("expected nothing stored, got %+v", surveys)
+	}
+}
+
+func TestSurveysForgottenWithSession(t *testing.T) {
+	mgr, cleanup := setupTest(t)
+	defer cleanup()
+	mgr.SetSurveyInterval(time.Hour)
+
+	sess := playCells(t, mgr, 0, 3, 1, 4, 2)
+	mgr.PromptSurveys(sess, []string{"alice", "bob"})
+	mgr.surveyMu.Lock()
+	mgr.surveyed["carol"] = time.Now().Add(-2 * time.Hour)
+	mgr.surveyMu.Unlock()
+
+	mgr.Remove(t.Context(), sess.Code)
+	if err := mgr.SubmitSurvey(t.Context(), sess.Code, "alice", SurveyResponse{Fun: 4}); !errors.Is(err, ErrNoSurvey) {
+		t.Fatalf("expected no survey about a removed session, got %v", err)
+	}
+	mgr.surveyMu.Lock()
+	defer mgr.surveyMu.Unlock()
+	if len(mgr.pendingSurveys) != 0 {
+		t.Fatalf("expected the removed session's surveys dropped, got %v", mgr.pendingSurveys)
+	}
+	// Asked within the hour still holds alice and bob back.
+	if _, ok := mgr.surveyed["carol"]; ok || len(mgr.surveyed) != 2 {
+		t.Fatalf("expected only carol's old prompt forgotten, got %v", mgr.surveyed)
+	}
+}
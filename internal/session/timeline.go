@@ -0,0 +1,66 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"games/internal/storage"
+)
+
+// Timeline entry types.
+const (
+	TimelineJoined   = "joined"
+	TimelineStarted  = "started"
+	TimelineMoved    = "moved"
+	TimelineFinished = "finished"
+	TimelineChat     = "chat"
+)
+
+// TimelineEntry is something that happened in a session, as a client
+// coming back shows what it missed.
+type TimelineEntry struct {
+	ID       int64           `json:"id"`
+	Type     string          `json:"type"`
+	PlayerID string          `json:"playerId,omitempty"`
+	Detail   json.RawMessage `json:"detail,omitempty"`
+	At       time.Time       `json:"at"`
+}
+
+// RecordTimeline appends an entry to a session's timeline, with detail,
+// if not nil, stored as JSON. Like RecordAudit it logs failures rather
+// than returning them, and writes the entry even if ctx is cancelled.
+func (m *Manager) RecordTimeline(ctx context.Context, code, typ, playerID string, detail any) {
+	ctx = detach(ctx)
+	row := storage.TimelineRow{SessionCode: code, Type: typ, PlayerID: playerID}
+	if detail != nil {
+		data, err := json.Marshal(detail)
+		if err != nil {
+			log.Printf("timeline %s of session %s: %v", typ, code, err)
+			return
+		}
+		row.Detail = string(data)
+	}
+	if err := m.store.AppendTimeline(ctx, row); err != nil {
+		log.Printf("timeline %s of session %s: %v", typ, code, err)
+	}
+}
+
+// Timeline returns up to limit entries of a session's timeline after the
+// one with ID afterID, oldest first.
+func (m *Manager) Timeline(ctx context.Context, code string, afterID int64, limit int) ([]TimelineEntry, error) {
+	rows, err := m.store.ListTimeline(ctx, code, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]TimelineEntry, 0, len(rows))
+	for _, row := range rows {
+		e := TimelineEntry{ID: row.ID, Type: row.Type, PlayerID: row.PlayerID, At: row.CreatedAt}
+		if row.Detail != "" {
+			e.Detail = json.RawMessage(row.Detail)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
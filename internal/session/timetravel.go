@@ -0,0 +1,83 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"games/internal/game"
+)
+
+// StoredMatch is a match as storage holds it: the start position, the
+// move log and the latest saved state. Replaying the log shows the match
+// at any point, for tracking down a position that went wrong.
+type StoredMatch struct {
+	GameType string
+	Moves    []Move
+	initial  game.Match
+	saved    json.RawMessage
+}
+
+// StoredMatch loads the persisted history of a session's current match,
+// whether or not the session is in memory.
+func (m *Manager) StoredMatch(ctx context.Context, code string) (*StoredMatch, error) {
+	row, err := m.store.GetSession(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	g, ok := m.registry.Get(row.GameType)
+	if !ok {
+		return nil, fmt.Errorf("unknown game type: %s", row.GameType)
+	}
+	if rules, ok := m.registry.Rules(row.GameType, row.RulesVersion); ok {
+		g = rules
+	}
+	initial, err := m.store.GetInitialState(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("load initial state: %w", err)
+	}
+	match, err := unmarshalMatch(g, initial.StateJSON)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal initial state: %w", err)
+	}
+	moves, err := m.loadMoves(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := m.store.GetMatchState(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("load match state: %w", err)
+	}
+	return &StoredMatch{GameType: row.GameType, Moves: moves, initial: match, saved: json.RawMessage(saved)}, nil
+}
+
+// StateAt returns the match's state after its first n moves, replayed
+// from the start position.
+func (sm *StoredMatch) StateAt(n int) (json.RawMessage, error) {
+	m, err := sm.MatchAt(n)
+	if err != nil {
+		return nil, err
+	}
+	return m.MarshalJSON()
+}
+
+// MatchAt returns the match after its first n moves, replayed from the
+// start position.
+func (sm *StoredMatch) MatchAt(n int) (game.Match, error) {
+	if n < 0 || n > len(sm.Moves) {
+		return nil, fmt.Errorf("move %d out of range 0-%d", n, len(sm.Moves))
+	}
+	m := sm.initial.Clone()
+	for i, mv := range sm.Moves[:n] {
+		if _, err := game.ApplyAt(m, mv.PlayerID, mv.Action, mv.Time()); err != nil {
+			return nil, fmt.Errorf("replay move %d by %s: %w", i+1, mv.PlayerID, err)
+		}
+	}
+	return m, nil
+}
+
+// SavedState returns the latest state stored for the match, which should
+// match the state after every move.
+func (sm *StoredMatch) SavedState() json.RawMessage {
+	return sm.saved
+}
@@ -0,0 +1,469 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"games/internal/event"
+	"games/internal/game"
+	"games/internal/storage"
+)
+
+// Tournament formats.
+const (
+	// FormatSwiss pairs players on the same score who have not met, for
+	// a set number of rounds.
+	FormatSwiss = "swiss"
+	// FormatRoundRobin has every player meet every other once.
+	FormatRoundRobin = "round_robin"
+)
+
+// MaxTournamentPlayers caps how many players may enter a tournament.
+const MaxTournamentPlayers = 64
+
+// ErrTournamentNotFound is returned for unknown tournament IDs.
+var ErrTournamentNotFound = errors.New("tournament not found")
+
+// Tournament is a tournament as players and organizers see it.
+type Tournament struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	GameType string `json:"gameType"`
+	Format   string `json:"format"`
+	// Rounds is how many rounds it plays; 0 for a Swiss that has not
+	// started and plays as many as its players need.
+	Rounds    int       `json:"rounds"`
+	Round     int       `json:"round"` // the round in play, 0 before the start
+	Status    string    `json:"status"`
+	Organizer string    `json:"organizer"`
+	Players   []string  `json:"players"`
+	Pairings  []Pairing `json:"pairings"`
+	// Club is the club holding the tournament, whose members alone may
+	// enter; omitted for an open tournament.
+	Club      string    `json:"club,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Pairing is one game of a tournament round, or a bye.
+type Pairing struct {
+	Round       int    `json:"round"`
+	Board       int    `json:"board"`
+	PlayerA     string `json:"playerA"`           // moves first
+	PlayerB     string `json:"playerB,omitempty"` // empty for a bye
+	SessionCode string `json:"sessionCode,omitempty"`
+	// Result is "a" or "b" for the winner, "draw", "bye", or "none" if
+	// the game ended without either player winning, such as when both
+	// left; empty while the game is in play.
+	Result string `json:"result,omitempty"`
+}
+
+// CreateTournament opens a tournament of a two-player game for entries.
+// A Swiss plays the given number of rounds, or as many as its players
+// need if 0; a round robin plays as many as it takes for everyone to meet.
+// A tournament held by a club, which only its owner and admins may
+// organize, is private to the club; club is empty for an open one.
+func (m *Manager) CreateTournament(ctx context.Context, name, gameType, format string, rounds int, organizer, club string) (*Tournament, error) {
+	g, err := m.playable(gameType)
+	if err != nil {
+		return nil, err
+	}
+	if gi := g.Info(); gi.MinPlayers > 2 || gi.MaxPlayers < 2 {
+		return nil, fmt.Errorf("%s is not a two-player game", gameType)
+	}
+	name = strings.TrimSpace(name)
+	organizer = strings.TrimSpace(organizer)
+	if name == "" || organizer == "" {
+		return nil, fmt.Errorf("name and organizer required")
+	}
+	switch {
+	case format != FormatSwiss && format != FormatRoundRobin:
+		return nil, fmt.Errorf("unknown tournament format: %s", format)
+	case rounds < 0:
+		return nil, fmt.Errorf("rounds must not be negative")
+	case format == FormatRoundRobin && rounds != 0:
+		return nil, fmt.Errorf("a round robin plays a round for every opponent; its rounds cannot be chosen")
+	}
+	if club != "" {
+		role, err := m.clubRole(ctx, club, organizer)
+		if err != nil {
+			return nil, err
+		}
+		if role != RoleOwner && role != RoleAdmin {
+			return nil, fmt.Errorf("%w: only the club's owner and admins may organize its tournaments", ErrNotPermitted)
+		}
+	}
+
+	t := storage.TournamentRow{
+		ID:        generateTournamentID(),
+		Name:      name,
+		GameType:  gameType,
+		Format:    format,
+		Rounds:    rounds,
+		Status:    "registering",
+		Organizer: organizer,
+		Players:   "[]",
+		Club:      club,
+	}
+	if err := m.store.CreateTournament(ctx, t); err != nil {
+		return nil, fmt.Errorf("persist tournament: %w", err)
+	}
+	return m.Tournament(ctx, t.ID)
+}
+
+// Tournament returns a tournament with its pairings so far.
+func (m *Manager) Tournament(ctx context.Context, id string) (*Tournament, error) {
+	t, err := m.store.GetTournament(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTournamentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	pairings, err := m.store.ListPairings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return tournamentInfo(t, pairings), nil
+}
+
+// Tournaments returns the open tournaments, newest first, without their
+// pairings. Given a club, it returns the club's instead, which only its
+// members may list.
+func (m *Manager) Tournaments(ctx context.Context, club, playerID string) ([]Tournament, error) {
+	if club != "" {
+		if _, err := m.clubMember(ctx, club, playerID); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := m.store.ListTournaments(ctx, club)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Tournament, len(rows))
+	for i := range rows {
+		result[i] = *tournamentInfo(&rows[i], nil)
+	}
+	return result, nil
+}
+
+// TournamentStandings ranks a tournament's players on the games finished
+// so far.
+func (m *Manager) TournamentStandings(ctx context.Context, id string) ([]Standing, error) {
+	t, err := m.store.GetTournament(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTournamentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	pairings, err := m.store.ListPairings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return standings(t.Format, tournamentPlayers(t), pairings), nil
+}
+
+// JoinTournament enters a player in a tournament still taking entries.
+func (m *Manager) JoinTournament(ctx context.Context, id, playerID string) (*Tournament, error) {
+	playerID = strings.TrimSpace(playerID)
+	if playerID == "" {
+		return nil, fmt.Errorf("playerId required")
+	}
+	if strings.HasPrefix(playerID, ExternalBotPrefix) {
+		return nil, fmt.Errorf("player IDs starting with %s are reserved for bots", ExternalBotPrefix)
+	}
+	m.tournamentMu.Lock()
+	defer m.tournamentMu.Unlock()
+	t, err := m.registeringTournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Club != "" {
+		if _, err := m.clubMember(ctx, t.Club, playerID); err != nil {
+			return nil, err
+		}
+	}
+	players := tournamentPlayers(t)
+	if slices.Contains(players, playerID) {
+		return nil, fmt.Errorf("%s has already entered", playerID)
+	}
+	if len(players) >= MaxTournamentPlayers {
+		return nil, fmt.Errorf("the tournament is full")
+	}
+	players = append(players, playerID)
+	data, _ := json.Marshal(players)
+	t.Players = string(data)
+	if err := m.store.UpdateTournament(ctx, *t); err != nil {
+		return nil, fmt.Errorf("persist tournament: %w", err)
+	}
+	return m.Tournament(ctx, id)
+}
+
+// StartTournament lets the organizer close entries and start the first
+// round. It returns the sessions whose matches it started, for the caller
+// to announce.
+func (m *Manager) StartTournament(ctx context.Context, id, playerID string) (*Tournament, []*Session, error) {
+	ctx = detach(ctx)
+	m.tournamentMu.Lock()
+	defer m.tournamentMu.Unlock()
+	t, err := m.registeringTournament(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.Organizer != playerID {
+		return nil, nil, fmt.Errorf("only %s can start this tournament", t.Organizer)
+	}
+	players := tournamentPlayers(t)
+	if len(players) < 2 {
+		return nil, nil, fmt.Errorf("a tournament needs at least 2 players")
+	}
+	switch {
+	case t.Format == FormatRoundRobin:
+		t.Rounds = roundRobinRounds(len(players))
+	case t.Rounds == 0:
+		t.Rounds = swissRounds(len(players))
+	default:
+		// Past this, every round would be a rematch
+		t.Rounds = min(t.Rounds, roundRobinRounds(len(players)))
+	}
+	sessions, err := m.startRoundLocked(ctx, t, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	tournament, err := m.Tournament(ctx, id)
+	return tournament, sessions, err
+}
+
+// RecordTournamentGame records the result of a finished match if it was a
+// tournament game, publishing a TournamentResult event. Once it was the
+// last of its round to finish, it starts the next round, returning the
+// sessions whose matches it started, or finishes the tournament after the
+// last.
+func (m *Manager) RecordTournamentGame(ctx context.Context, e event.Event) ([]*Session, error) {
+	p, err := m.store.GetPairingBySession(ctx, e.SessionCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.tournamentMu.Lock()
+	defer m.tournamentMu.Unlock()
+	ok, err := m.store.SetPairingResult(ctx, e.SessionCode, pairingResult(p, e))
+	if err != nil || !ok {
+		return nil, err
+	}
+	// For the tournament's followers; the match's own event already went
+	// to the feeds
+	m.events.Publish(event.Event{
+		Type:        event.TournamentResult,
+		SessionCode: e.SessionCode,
+		GameType:    e.GameType,
+		Tournament:  p.TournamentID,
+		Players:     []string{p.PlayerA, p.PlayerB},
+		Private:     true,
+	})
+	t, err := m.store.GetTournament(ctx, p.TournamentID)
+	if err != nil {
+		return nil, err
+	}
+	pairings, err := m.store.ListPairings(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(pairings, func(p storage.TournamentPairingRow) bool { return p.Round == t.Round && p.Result == "" }) {
+		return nil, nil
+	}
+	if t.Round < t.Rounds {
+		return m.startRoundLocked(ctx, t, pairings)
+	}
+
+	t.Status = "finished"
+	if err := m.store.UpdateTournament(ctx, *t); err != nil {
+		return nil, fmt.Errorf("persist tournament: %w", err)
+	}
+	var winners []string
+	for _, s := range standings(t.Format, tournamentPlayers(t), pairings) {
+		if s.Rank == 1 {
+			winners = append(winners, s.PlayerID)
+		}
+	}
+	m.events.Publish(event.Event{
+		Type:       event.TournamentFinished,
+		GameType:   t.GameType,
+		Tournament: t.ID,
+		Players:    winners,
+		Private:    t.Club != "",
+	})
+	return nil, nil
+}
+
+// startRoundLocked pairs the tournament's next round given the pairings so
+// far, starts a match for each game and records the round. The caller
+// must hold tournamentMu.
+func (m *Manager) startRoundLocked(ctx context.Context, t *storage.TournamentRow, pairings []storage.TournamentPairingRow) ([]*Session, error) {
+	players := tournamentPlayers(t)
+	round := t.Round + 1
+	var pairs [][2]string
+	if t.Format == FormatRoundRobin {
+		pairs = roundRobinPairs(players, round)
+	} else {
+		pairs = swissPairs(players, pairings)
+	}
+
+	var sessions []*Session
+	abort := func() {
+		for _, s := range sessions {
+			m.Remove(ctx, s.Code)
+		}
+	}
+	rows := make([]storage.TournamentPairingRow, len(pairs))
+	for i, pair := range pairs {
+		rows[i] = storage.TournamentPairingRow{TournamentID: t.ID, Round: round, Board: i + 1, PlayerA: pair[0], PlayerB: pair[1]}
+		if pair[1] == "" {
+			rows[i].Result = "bye"
+			continue
+		}
+		s, err := m.tournamentMatch(ctx, t, pair)
+		if err != nil {
+			abort()
+			return nil, fmt.Errorf("start round %d: %w", round, err)
+		}
+		sessions = append(sessions, s)
+		rows[i].SessionCode = s.Code
+	}
+	t.Round = round
+	t.Status = "running"
+	err := m.store.WithTx(ctx, func(tx storage.Backend) error {
+		if err := tx.AddPairings(ctx, rows); err != nil {
+			return err
+		}
+		return tx.UpdateTournament(ctx, *t)
+	})
+	if err != nil {
+		abort()
+		return nil, fmt.Errorf("persist round %d: %w", round, err)
+	}
+	m.events.Publish(event.Event{
+		Type:       event.TournamentRound,
+		GameType:   t.GameType,
+		Tournament: t.ID,
+		Players:    players,
+		Private:    t.Club != "",
+	})
+	return sessions, nil
+}
+
+// tournamentMatch creates a session with a pair seated, the first to move
+// first, and starts their match. A club's matches are private, like its
+// tournaments.
+func (m *Manager) tournamentMatch(ctx context.Context, t *storage.TournamentRow, pair [2]string) (*Session, error) {
+	s, err := m.Create(ctx, t.GameType)
+	if err != nil {
+		return nil, err
+	}
+	if t.Club != "" {
+		if err := m.SetPrivate(ctx, s, true); err != nil {
+			m.Remove(ctx, s.Code)
+			return nil, err
+		}
+	}
+	for _, pid := range pair {
+		if err := s.AddPlayer(pid); err != nil {
+			m.Remove(ctx, s.Code)
+			return nil, err
+		}
+	}
+	if err := m.startMatch(ctx, s); err != nil {
+		m.Remove(ctx, s.Code)
+		return nil, err
+	}
+	return s, nil
+}
+
+// registeringTournament loads a tournament still taking entries.
+func (m *Manager) registeringTournament(ctx context.Context, id string) (*storage.TournamentRow, error) {
+	t, err := m.store.GetTournament(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTournamentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != "registering" {
+		return nil, fmt.Errorf("tournament is %s", t.Status)
+	}
+	return t, nil
+}
+
+// pairingResult reads the result of a tournament game from its finished
+// match.
+func pairingResult(p *storage.TournamentPairingRow, e event.Event) string {
+	outcomes := make(map[string]game.Outcome, len(e.Results))
+	for _, r := range e.Results {
+		outcomes[r.PlayerID] = r.Outcome
+	}
+	a, b := outcomes[p.PlayerA], outcomes[p.PlayerB]
+	switch {
+	case e.Abandoned:
+		return "none"
+	case a == game.OutcomeWin:
+		return "a"
+	case b == game.OutcomeWin:
+		return "b"
+	case a == game.OutcomeDraw && b == game.OutcomeDraw:
+		return "draw"
+	}
+	return "none"
+}
+
+func tournamentPlayers(t *storage.TournamentRow) []string {
+	var players []string
+	json.Unmarshal([]byte(t.Players), &players)
+	return players
+}
+
+func tournamentInfo(t *storage.TournamentRow, pairings []storage.TournamentPairingRow) *Tournament {
+	info := &Tournament{
+		ID:        t.ID,
+		Name:      t.Name,
+		GameType:  t.GameType,
+		Format:    t.Format,
+		Rounds:    t.Rounds,
+		Round:     t.Round,
+		Status:    t.Status,
+		Organizer: t.Organizer,
+		Players:   tournamentPlayers(t),
+		Pairings:  make([]Pairing, len(pairings)),
+		Club:      t.Club,
+		CreatedAt: t.CreatedAt,
+	}
+	if info.Players == nil {
+		info.Players = []string{}
+	}
+	for i, p := range pairings {
+		info.Pairings[i] = Pairing{
+			Round:       p.Round,
+			Board:       p.Board,
+			PlayerA:     p.PlayerA,
+			PlayerB:     p.PlayerB,
+			SessionCode: p.SessionCode,
+			Result:      p.Result,
+		}
+	}
+	return info
+}
+
+func generateTournamentID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "tn-" + hex.EncodeToString(b)
+}
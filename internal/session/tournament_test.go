@@ -0,0 +1,160 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"games/internal/event"
+	"games/internal/game"
+)
+
+// finishPairing records a tournament game as won by winner, or drawn if
+// winner is empty, the way the finished match's event would.
+func finishPairing(t *testing.T, mgr *Manager, p Pairing, winner string) []*Session {
+	t.Helper()
+	e := event.Event{Type: event.MatchFinished, SessionCode: p.SessionCode, GameType: "tictactoe"}
+	for _, id := range []string{p.PlayerA, p.PlayerB} {
+		outcome := game.OutcomeDraw
+		if winner != "" {
+			outcome = game.OutcomeLoss
+			if id == winner {
+				outcome = game.OutcomeWin
+			}
+		}
+		e.Results = append(e.Results, game.PlayerResult{PlayerID: id, Outcome: outcome})
+	}
+	started, err := mgr.RecordTournamentGame(t.Context(), e)
+	if err != nil {
+		t.Fatalf("record %s: %v", p.SessionCode, err)
+	}
+	return started
+}
+
+func TestSwissTournament(t *testing.T) {
+	mgr := setupBotTest(t)
+	events, unsubscribe := mgr.Events().Subscribe(10)
+	defer unsubscribe()
+
+	if _, err := mgr.CreateTournament(t.Context(), "Open", "tictactoe", "knockout", 0, "alice", ""); err == nil {
+		t.Fatal("expected an unknown format refused")
+	}
+	tn, err := mgr.CreateTournament(t.Context(), "Open", "tictactoe", FormatSwiss, 0, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"alice", "bob", "carol"} {
+		if _, err := mgr.JoinTournament(t.Context(), tn.ID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mgr.JoinTournament(t.Context(), tn.ID, "bob"); err == nil {
+		t.Fatal("expected bob entered only once")
+	}
+	if _, _, err := mgr.StartTournament(t.Context(), tn.ID, "bob"); err == nil {
+		t.Fatal("expected only the organizer to start the tournament")
+	}
+	tn, started, err := mgr.StartTournament(t.Context(), tn.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tn.Status != "running" || tn.Round != 1 || tn.Rounds != 2 || len(tn.Pairings) != 2 || len(started) != 1 {
+		t.Fatalf("expected round 1 of 2 with one game and a bye, got %+v", tn)
+	}
+	if e := <-events; e.Type != event.TournamentRound || e.Tournament != tn.ID {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	game1, bye := tn.Pairings[0], tn.Pairings[1]
+	if bye.PlayerA != "carol" || bye.Result != "bye" || game1.PlayerA != "alice" || game1.SessionCode != started[0].Code {
+		t.Fatalf("expected alice to play bob and carol to sit out, got %+v", tn.Pairings)
+	}
+	if info := started[0].Info(); info.Status != StatusPlaying || info.Players[0] != "alice" {
+		t.Fatalf("expected alice's match started, got %+v", info)
+	}
+	if _, err := mgr.JoinTournament(t.Context(), tn.ID, "dave"); err == nil {
+		t.Fatal("expected entries closed once started")
+	}
+
+	started = finishPairing(t, mgr, game1, "alice")
+	if again := finishPairing(t, mgr, game1, "bob"); again != nil {
+		t.Fatal("expected a game's result recorded once")
+	}
+	tn, _ = mgr.Tournament(t.Context(), tn.ID)
+	if tn.Round != 2 || len(started) != 1 || tn.Pairings[0].Result != "a" {
+		t.Fatalf("expected round 2 started, got %+v", tn)
+	}
+	game2, bye := tn.Pairings[2], tn.Pairings[3]
+	if !samePair([2]string{game2.PlayerA, game2.PlayerB}, "alice", "carol") || bye.PlayerA != "bob" {
+		t.Fatalf("expected the leaders to meet and bob to sit out, got %+v", tn.Pairings)
+	}
+	if e := <-events; e.Type != event.TournamentResult || e.SessionCode != game1.SessionCode || !e.Private {
+		t.Fatalf("expected the result announced to the tournament's followers, got %+v", e)
+	}
+	if e := <-events; e.Type != event.TournamentRound {
+		t.Fatalf("expected round 2 announced, got %+v", e)
+	}
+
+	if started := finishPairing(t, mgr, game2, ""); started != nil {
+		t.Fatalf("expected no more rounds, got %d matches", len(started))
+	}
+	tn, _ = mgr.Tournament(t.Context(), tn.ID)
+	if tn.Status != "finished" {
+		t.Fatalf("expected the tournament over, got %+v", tn)
+	}
+	<-events // the result
+	if e := <-events; e.Type != event.TournamentFinished || len(e.Players) != 1 || e.Players[0] != "alice" {
+		t.Fatalf("expected alice announced the winner, got %+v", e)
+	}
+	standings, err := mgr.TournamentStandings(t.Context(), tn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := standingIDs(standings); ids[0] != "alice" || standings[0].Score != 1.5 || standings[1].Score != 1.5 {
+		t.Fatalf("expected alice first on tie-breaks, got %+v", standings)
+	}
+}
+
+func TestRoundRobinTournament(t *testing.T) {
+	mgr := setupBotTest(t)
+	if _, err := mgr.CreateTournament(t.Context(), "League", "tictactoe", FormatRoundRobin, 3, "alice", ""); err == nil {
+		t.Fatal("expected the rounds of a round robin fixed")
+	}
+	tn, err := mgr.CreateTournament(t.Context(), "League", "tictactoe", FormatRoundRobin, 0, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.StartTournament(t.Context(), tn.ID, "alice"); err == nil {
+		t.Fatal("expected a tournament without players refused")
+	}
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		mgr.JoinTournament(t.Context(), tn.ID, id)
+	}
+	tn, _, err = mgr.StartTournament(t.Context(), tn.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for round := 1; round <= 3; round++ {
+		tn, _ = mgr.Tournament(t.Context(), tn.ID)
+		if tn.Round != round {
+			t.Fatalf("expected round %d, got %+v", round, tn)
+		}
+		for _, p := range tn.Pairings {
+			if p.Round == round {
+				finishPairing(t, mgr, p, p.PlayerA)
+			}
+		}
+	}
+	tn, _ = mgr.Tournament(t.Context(), tn.ID)
+	if tn.Status != "finished" || len(tn.Pairings) != 6 {
+		t.Fatalf("expected all six games played, got %+v", tn)
+	}
+	standings, _ := mgr.TournamentStandings(t.Context(), tn.ID)
+	for _, s := range standings {
+		if s.Played != 3 {
+			t.Fatalf("expected everyone to play everyone, got %+v", standings)
+		}
+	}
+
+	if _, err := mgr.Tournament(t.Context(), "tn-missing"); !errors.Is(err, ErrTournamentNotFound) {
+		t.Fatalf("expected ErrTournamentNotFound, got %v", err)
+	}
+}
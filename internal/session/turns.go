@@ -0,0 +1,41 @@
+package session
+
+import (
+	"context"
+
+	"games/internal/storage"
+)
+
+// Turn is a session waiting on a player's move.
+type Turn struct {
+	Code     string `json:"code"`
+	GameType string `json:"gameType"`
+	// Since is when anything last happened in the session, about when the
+	// move came to the player.
+	Since string `json:"since"`
+}
+
+// Turns lists the sessions waiting on playerID's move, most recently
+// active first. It finds the player's matches through the seats storage
+// keeps and asks each game only whether the player may act, building no
+// state, so it is cheap enough to poll for badge counts. Private
+// sessions are listed only withPrivate, as by PlayerSessions.
+func (m *Manager) Turns(ctx context.Context, playerID string, withPrivate bool) ([]Turn, error) {
+	f := storage.SessionFilter{PlayerID: playerID, Status: string(StatusPlaying), PublicOnly: !withPrivate, Sort: storage.SortActivity}
+	rows, err := m.store.ListSessions(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	turns := []Turn{}
+	for _, r := range rows {
+		s, ok := m.Get(r.Code)
+		if !ok || !s.isTurn(playerID) {
+			continue
+		}
+		s.mu.RLock()
+		since := s.LastActivity
+		s.mu.RUnlock()
+		turns = append(turns, Turn{Code: r.Code, GameType: r.GameType, Since: rfc3339(since)})
+	}
+	return turns, nil
+}
@@ -0,0 +1,180 @@
+package session
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"games/internal/game"
+)
+
+// VoteKind is what players vote to do about an unresponsive player.
+type VoteKind string
+
+const (
+	// VoteSkip passes over the player's current turn.
+	VoteSkip VoteKind = "skip"
+	// VoteRemove takes the player out of the match for good.
+	VoteRemove VoteKind = "remove"
+)
+
+// MinVotePlayers is the smallest match in which players may vote. With two
+// players a vote would be one player's say-so.
+const MinVotePlayers = 3
+
+// VoteThresholds are the shares of eligible voters, in percent, needed to
+// skip a player's turn or remove them. Eligible voters are the other human
+// players still in the match.
+type VoteThresholds struct {
+	Skip   int `json:"skip"`
+	Remove int `json:"remove"`
+}
+
+// DefaultVoteThresholds take a strict majority to skip a turn and two
+// thirds to remove a player.
+var DefaultVoteThresholds = VoteThresholds{Skip: 51, Remove: 67}
+
+// Validate checks that both thresholds are percentages above zero.
+func (t VoteThresholds) Validate() error {
+	if t.Skip < 1 || t.Skip > 100 || t.Remove < 1 || t.Remove > 100 {
+		return fmt.Errorf("vote thresholds must be between 1 and 100 percent")
+	}
+	return nil
+}
+
+func (t VoteThresholds) percent(kind VoteKind) int {
+	if kind == VoteSkip {
+		return t.Skip
+	}
+	return t.Remove
+}
+
+// VoteTally is the standing of a vote after a player casts theirs.
+type VoteTally struct {
+	Kind     VoteKind `json:"kind"`
+	PlayerID string   `json:"playerId"` // the player voted on
+	Voters   []string `json:"voters"`
+	Needed   int      `json:"needed"`
+	Passed   bool     `json:"passed"`
+}
+
+type voteKey struct {
+	kind     VoteKind
+	playerID string
+}
+
+// ballot is the voters for one vote. Skip votes are for one turn, so they
+// lapse once another move is made.
+type ballot struct {
+	voters []string
+	moves  int // len(History) when the first vote was cast
+}
+
+// Vote casts voterID's vote to skip or remove playerID. Once enough
+// players agree the match is told, through game.TurnSkipper or
+// game.PlayerRemover, and the returned tally has Passed set.
+func (s *Session) Vote(voterID, playerID string, kind VoteKind) (VoteTally, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if kind != VoteSkip && kind != VoteRemove {
+		return VoteTally{}, fmt.Errorf("unknown vote %q: want %q or %q", kind, VoteSkip, VoteRemove)
+	}
+	if s.Status != StatusPlaying || s.Match == nil {
+		return VoteTally{}, fmt.Errorf("game not in progress")
+	}
+	if len(s.seating) < MinVotePlayers {
+		return VoteTally{}, fmt.Errorf("voting needs at least %d players", MinVotePlayers)
+	}
+	if voterID == playerID {
+		return VoteTally{}, fmt.Errorf("you cannot vote on yourself")
+	}
+	if !s.inMatchLocked(voterID) {
+		return VoteTally{}, fmt.Errorf("only players in the match can vote")
+	}
+	if p := s.Players[voterID]; p == nil || p.Strategy != nil {
+		return VoteTally{}, fmt.Errorf("bots cannot vote")
+	}
+	if !s.inMatchLocked(playerID) {
+		return VoteTally{}, fmt.Errorf("player %s is not in the match", playerID)
+	}
+	switch kind {
+	case VoteSkip:
+		if _, ok := s.Match.(game.TurnSkipper); !ok {
+			return VoteTally{}, fmt.Errorf("%s does not support skipping turns", s.GameType)
+		}
+		var turn bool
+		if err := Protect(func() error {
+			turn = len(s.Match.ValidActions(playerID)) > 0
+			return nil
+		}); err != nil {
+			return VoteTally{}, err
+		}
+		if !turn {
+			return VoteTally{}, fmt.Errorf("it is not %s's turn", playerID)
+		}
+	case VoteRemove:
+		if _, ok := s.Match.(game.PlayerRemover); !ok {
+			return VoteTally{}, fmt.Errorf("%s does not support removing players", s.GameType)
+		}
+	}
+
+	key := voteKey{kind: kind, playerID: playerID}
+	b, ok := s.votes[key]
+	if !ok || (kind == VoteSkip && b.moves != len(s.History)) {
+		b = ballot{moves: len(s.History)}
+	}
+	if !slices.Contains(b.voters, voterID) {
+		b.voters = append(b.voters, voterID)
+	}
+	// Voters who have since left the match no longer count.
+	b.voters = slices.DeleteFunc(b.voters, func(id string) bool { return !s.inMatchLocked(id) })
+
+	eligible := 0
+	for _, id := range s.seating {
+		if p := s.Players[id]; id != playerID && s.inMatchLocked(id) && p != nil && p.Strategy == nil {
+			eligible++
+		}
+	}
+	tally := VoteTally{
+		Kind:     kind,
+		PlayerID: playerID,
+		Voters:   slices.Clone(b.voters),
+		Needed:   max((eligible*s.VoteThresholds.percent(kind)+99)/100, 1),
+	}
+	if len(b.voters) < tally.Needed {
+		if s.votes == nil {
+			s.votes = make(map[voteKey]ballot)
+		}
+		s.votes[key] = b
+		return tally, nil
+	}
+
+	delete(s.votes, key)
+	err := Protect(func() error {
+		if kind == VoteSkip {
+			return s.Match.(game.TurnSkipper).SkipTurn(playerID)
+		}
+		return s.Match.(game.PlayerRemover).RemovePlayer(playerID)
+	})
+	if err != nil {
+		return tally, err
+	}
+	if kind == VoteRemove {
+		s.removed = append(s.removed, playerID)
+		for k := range s.votes {
+			if k.playerID == playerID {
+				delete(s.votes, k)
+			}
+		}
+	}
+	s.LastActivity = time.Now()
+	tally.Passed = true
+	return tally, nil
+}
+
+// inMatchLocked reports whether playerID is seated in the current match
+// and has not been voted out of it.
+func (s *Session) inMatchLocked(playerID string) bool {
+	return slices.Contains(s.seating, playerID) && !slices.Contains(s.removed, playerID)
+}
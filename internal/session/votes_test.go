@@ -0,0 +1,151 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"games/internal/game"
+)
+
+// roundGame is a game for three or four players who take turns passing,
+// with support for skipped turns and removed players.
+type roundGame struct{}
+
+func (roundGame) Info() game.GameInfo {
+	return game.GameInfo{Name: "round", MinPlayers: 3, MaxPlayers: 4}
+}
+
+func (roundGame) NewMatch(config game.MatchConfig) game.Match {
+	return &roundMatch{Players: slices.Clone(config.PlayerIDs)}
+}
+
+type roundMatch struct {
+	Players []string
+	Turn    int
+}
+
+func (m *roundMatch) State(string) any { return m.Turn }
+
+func (m *roundMatch) ValidActions(playerID string) []game.Action {
+	if m.Players[m.Turn] != playerID {
+		return nil
+	}
+	return []game.Action{{Type: "pass"}}
+}
+
+func (m *roundMatch) ApplyAction(playerID string, _ game.Action) error {
+	return m.SkipTurn(playerID)
+}
+
+func (m *roundMatch) SkipTurn(playerID string) error {
+	if m.Players[m.Turn] != playerID {
+		return fmt.Errorf("not your turn")
+	}
+	m.Turn = (m.Turn + 1) % len(m.Players)
+	return nil
+}
+
+func (m *roundMatch) RemovePlayer(playerID string) error {
+	i := slices.Index(m.Players, playerID)
+	if i < 0 {
+		return fmt.Errorf("unknown player")
+	}
+	m.Players = slices.Delete(m.Players, i, i+1)
+	if m.Turn > i || m.Turn == len(m.Players) {
+		m.Turn = (m.Turn - 1 + len(m.Players)) % len(m.Players)
+	}
+	return nil
+}
+
+func (m *roundMatch) IsOver() bool                    { return len(m.Players) < 2 }
+func (m *roundMatch) Results() []game.PlayerResult    { return nil }
+func (m *roundMatch) Clone() game.Match               { c := *m; c.Players = slices.Clone(m.Players); return &c }
+func (m *roundMatch) MarshalJSON() ([]byte, error)    { return json.Marshal(struct{}{}) }
+func (m *roundMatch) UnmarshalJSON(data []byte) error { return nil }
+
+func startRound(t *testing.T, players ...string) *Session {
+	t.Helper()
+	sess := NewSession("abc", "round", roundGame{})
+	for _, id := range players {
+		sess.AddPlayer(id)
+	}
+	if err := sess.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	return sess
+}
+
+func TestVoteSkip(t *testing.T) {
+	sess := startRound(t, "alice", "bob", "carol", "dave")
+
+	if _, err := sess.Vote("bob", "bob", VoteSkip); err == nil {
+		t.Fatal("expected error voting on yourself")
+	}
+	if _, err := sess.Vote("alice", "bob", VoteSkip); err == nil {
+		t.Fatal("expected error skipping a player whose turn it is not")
+	}
+
+	// Three others may vote; a strict majority is two.
+	tally, err := sess.Vote("bob", "alice", VoteSkip)
+	if err != nil || tally.Passed || tally.Needed != 2 {
+		t.Fatalf("first vote: %+v %v", tally, err)
+	}
+	if tally, _ := sess.Vote("bob", "alice", VoteSkip); len(tally.Voters) != 1 {
+		t.Fatalf("expected a repeated vote to count once, got %v", tally.Voters)
+	}
+	tally, err = sess.Vote("carol", "alice", VoteSkip)
+	if err != nil || !tally.Passed {
+		t.Fatalf("second vote: %+v %v", tally, err)
+	}
+	if len(sess.Match.ValidActions("bob")) == 0 {
+		t.Fatal("expected bob to move after alice's turn was skipped")
+	}
+}
+
+func TestVoteSkipLapsesAfterMove(t *testing.T) {
+	sess := startRound(t, "alice", "bob", "carol", "dave")
+	sess.Vote("bob", "alice", VoteSkip)
+	sess.Match.ApplyAction("alice", game.Action{Type: "pass"})
+	sess.History = append(sess.History, Move{PlayerID: "alice"})
+	sess.Match.ApplyAction("bob", game.Action{Type: "pass"})
+	sess.Match.ApplyAction("carol", game.Action{Type: "pass"})
+	sess.Match.ApplyAction("dave", game.Action{Type: "pass"})
+
+	if tally, _ := sess.Vote("carol", "alice", VoteSkip); tally.Passed || len(tally.Voters) != 1 {
+		t.Fatalf("expected the earlier skip vote to lapse, got %+v", tally)
+	}
+}
+
+func TestVoteRemove(t *testing.T) {
+	sess := startRound(t, "alice", "bob", "carol")
+	if err := (VoteThresholds{Skip: 0, Remove: 50}).Validate(); err == nil {
+		t.Fatal("expected a zero threshold to be rejected")
+	}
+	sess.VoteThresholds = VoteThresholds{Skip: 51, Remove: 100}
+
+	tally, err := sess.Vote("alice", "carol", VoteRemove)
+	if err != nil || tally.Passed || tally.Needed != 2 {
+		t.Fatalf("first vote: %+v %v", tally, err)
+	}
+	if tally, err = sess.Vote("bob", "carol", VoteRemove); err != nil || !tally.Passed {
+		t.Fatalf("second vote: %+v %v", tally, err)
+	}
+	if info := sess.Info(); !slices.Equal(info.Removed, []string{"carol"}) {
+		t.Fatalf("expected carol to be removed, got %v", info.Removed)
+	}
+	if _, err := sess.Vote("carol", "alice", VoteSkip); err == nil {
+		t.Fatal("expected a removed player to lose their vote")
+	}
+}
+
+func TestVoteNeedsThreePlayers(t *testing.T) {
+	sess := NewSession("abc", "round", roundGame{})
+	sess.Status = StatusPlaying
+	sess.Match = roundGame{}.NewMatch(game.MatchConfig{PlayerIDs: []string{"alice", "bob"}})
+	sess.seating = []string{"alice", "bob"}
+	if _, err := sess.Vote("bob", "alice", VoteSkip); err == nil {
+		t.Fatal("expected voting to need three players")
+	}
+}
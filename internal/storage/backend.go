@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Backend is what the rest of the server needs from storage. Store keeps
+// everything in SQLite; Memory keeps it in process, for tests and for
+// ephemeral deployments that need nothing to outlive a restart.
+//
+// Lookups of a single row that is not there return sql.ErrNoRows from
+// either implementation. Every call takes the context of the request or
+// job it serves; Store gives up with the context's error once it ends.
+type Backend interface {
+	// Sessions
+	CreateSession(ctx context.Context, code, gameType string) error
+	SetSessionOptions(ctx context.Context, code, optionsJSON string) error
+	SetSessionParty(ctx context.Context, code, partyJSON string) error
+	SetSessionTurnOrder(ctx context.Context, code, turnOrder string) error
+	SetSessionVoteThresholds(ctx context.Context, code, thresholdsJSON string) error
+	SetSessionHandicaps(ctx context.Context, code, handicapsJSON string) error
+	SetSessionAbandoned(ctx context.Context, code string, abandoned bool) error
+	SetSessionPrivate(ctx context.Context, code string, private bool) error
+	SetSessionRulesVersion(ctx context.Context, code, version string) error
+	SetSessionPinned(ctx context.Context, code string, pinned bool) error
+	SetSessionPractice(ctx context.Context, code string, practice bool) error
+	SetSessionSetup(ctx context.Context, code, setupJSON string) error
+	SetSessionPlayers(ctx context.Context, code string, playerIDs []string) error
+	SetSessionExpireAfter(ctx context.Context, code string, after time.Duration) error
+	NextRound(ctx context.Context, code, gameType, optionsJSON, partyJSON, previousJSON string) error
+	GetSession(ctx context.Context, code string) (*SessionRow, error)
+	SetSessionTimes(ctx context.Context, code string, startedAt, finishedAt, lastActivity time.Time) error
+	UpdateSessionStatus(ctx context.Context, code, status string) error
+	ListSessions(ctx context.Context, f SessionFilter) ([]SessionRow, error)
+	DeleteSession(ctx context.Context, code string) error
+	RestoreSession(ctx context.Context, code string) (bool, error)
+	GetDeletedSession(ctx context.Context, code string) (*SessionRow, error)
+	ListDeletedSessions(ctx context.Context) ([]SessionRow, error)
+	PurgeDeletedSessions(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// Match state and history
+	SaveMatchState(ctx context.Context, sessionCode, stateJSON string) error
+	GetMatchState(ctx context.Context, sessionCode string) (string, error)
+	SaveInitialState(ctx context.Context, sessionCode string, row InitialStateRow) error
+	GetInitialState(ctx context.Context, sessionCode string) (*InitialStateRow, error)
+	AppendMove(ctx context.Context, sessionCode string, seq int, playerID, actionJSON, timingJSON string) error
+	ListMoves(ctx context.Context, sessionCode string) ([]MoveRow, error)
+	TruncateMoves(ctx context.Context, sessionCode string, n int) error
+
+	// Archives that outlive sessions
+	ArchiveExhibitionResults(ctx context.Context, results []ExhibitionResultRow) error
+	ListExhibitionResults(ctx context.Context, gameType string) ([]ExhibitionResultRow, error)
+	ArchiveMatch(ctx context.Context, m ArchivedMatchRow) error
+	ListArchivedMatches(ctx context.Context, gameType string, limit int) ([]ArchivedMatchRow, error)
+	MatchStats(ctx context.Context, gameType string, from, to time.Time) (MatchStatsRow, error)
+	RecordMatchPlayers(ctx context.Context, sessionCode, gameType string, playerIDs []string) error
+	ListRecentOpponents(ctx context.Context, playerID string, limit int) ([]OpponentRow, error)
+	SaveSeason(ctx context.Context, season SeasonRow) error
+	GetSeason(ctx context.Context, name string) (*SeasonRow, error)
+	ListSeasons(ctx context.Context) ([]SeasonRow, error)
+
+	// External bots
+	CreateBot(ctx context.Context, id, name, keyHash, webhookURL string) error
+	GetBotByKeyHash(ctx context.Context, keyHash string) (*BotRow, error)
+
+	// Challenges and friends
+	CreateChallenge(ctx context.Context, c ChallengeRow) error
+	GetChallenge(ctx context.Context, id string) (*ChallengeRow, error)
+	ListPendingChallenges(ctx context.Context, playerID string, now time.Time) ([]ChallengeRow, error)
+	ResolveChallenge(ctx context.Context, id, status, sessionCode string) (bool, error)
+	ExpireChallenges(ctx context.Context, now time.Time) (int64, error)
+	CreateFriendRequest(ctx context.Context, requesterID, addresseeID string) error
+	GetFriendship(ctx context.Context, a, b string) (*FriendshipRow, error)
+	AcceptFriendRequest(ctx context.Context, requesterID, addresseeID string) (bool, error)
+	DeleteFriendship(ctx context.Context, a, b string) (bool, error)
+	ListFriendships(ctx context.Context, playerID string) ([]FriendshipRow, error)
+
+	// Notifications
+	SavePushSubscription(ctx context.Context, sub PushSubscriptionRow) error
+	ListPushSubscriptions(ctx context.Context, playerID string) ([]PushSubscriptionRow, error)
+	DeletePushSubscription(ctx context.Context, endpoint string) error
+	SaveEmailContact(ctx context.Context, playerID, email, verifyToken string) error
+	GetEmailContact(ctx context.Context, playerID string) (*EmailContactRow, error)
+	VerifyEmail(ctx context.Context, verifyToken string) (string, error)
+	UpdateEmailPreferences(ctx context.Context, playerID string, turns, challenges bool) (bool, error)
+
+	// Audit log
+	AppendAudit(ctx context.Context, a AuditRow) error
+	ListAudit(ctx context.Context, f AuditFilter) ([]AuditRow, error)
+
+	// Session timelines
+	AppendTimeline(ctx context.Context, t TimelineRow) error
+	ListTimeline(ctx context.Context, sessionCode string, afterID int64, limit int) ([]TimelineRow, error)
+
+	// Inboxes
+	AddToInbox(ctx context.Context, m InboxRow) error
+	ListInbox(ctx context.Context, playerID string) ([]InboxRow, error)
+	ClearInbox(ctx context.Context, playerID string, through int64) error
+
+	// Achievements
+	UnlockAchievement(ctx context.Context, a AchievementRow) (bool, error)
+	ListAchievements(ctx context.Context, playerID string) ([]AchievementRow, error)
+	IncrementProgress(ctx context.Context, playerID, counter string) (int, error)
+	ResetProgress(ctx context.Context, playerID, counter string) error
+
+	// Tournaments
+	CreateTournament(ctx context.Context, t TournamentRow) error
+	GetTournament(ctx context.Context, id string) (*TournamentRow, error)
+	ListTournaments(ctx context.Context, club string) ([]TournamentRow, error)
+	UpdateTournament(ctx context.Context, t TournamentRow) error
+	AddPairings(ctx context.Context, pairings []TournamentPairingRow) error
+	ListPairings(ctx context.Context, tournamentID string) ([]TournamentPairingRow, error)
+	GetPairingBySession(ctx context.Context, sessionCode string) (*TournamentPairingRow, error)
+	SetPairingResult(ctx context.Context, sessionCode, result string) (bool, error)
+
+	// Clubs
+	CreateClub(ctx context.Context, c ClubRow) error
+	GetClub(ctx context.Context, id string) (*ClubRow, error)
+	ListClubs(ctx context.Context) ([]ClubRow, error)
+	SetClubBranding(ctx context.Context, id, brandingJSON string) error
+	AddClubMember(ctx context.Context, m ClubMemberRow) (bool, error)
+	GetClubMember(ctx context.Context, clubID, playerID string) (*ClubMemberRow, error)
+	ListClubMembers(ctx context.Context, clubID string) ([]ClubMemberRow, error)
+	ListPlayerClubs(ctx context.Context, playerID string) ([]ClubMemberRow, error)
+	SetClubRole(ctx context.Context, clubID, playerID, role string) (bool, error)
+	RemoveClubMember(ctx context.Context, clubID, playerID string) (bool, error)
+
+	// Surveys
+	SaveSurvey(ctx context.Context, r SurveyRow) error
+	ListSurveys(ctx context.Context, f SurveyFilter) ([]SurveyRow, error)
+
+	// Problem reports
+	SaveReport(ctx context.Context, r ReportRow) (int64, error)
+	ListReports(ctx context.Context, f ReportFilter) ([]ReportRow, error)
+	GetReport(ctx context.Context, id int64) (*ReportRow, error)
+
+	// Maintenance
+	Maintain(ctx context.Context) error
+	Size(ctx context.Context) (SizeRow, error)
+
+	// WithTx runs fn so that its writes through tx land together or not
+	// at all.
+	WithTx(ctx context.Context, fn func(tx Backend) error) error
+
+	Close() error
+}
+
+var (
+	_ Backend = (*Store)(nil)
+	_ Backend = (*Memory)(nil)
+)
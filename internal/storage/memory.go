@@ -0,0 +1,1283 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a Backend that keeps everything in process and forgets it on
+// exit. It behaves like Store, down to timestamps being kept to the
+// second, so tests written against one hold for the other. Its calls
+// never wait on I/O, so they ignore their contexts.
+type Memory struct {
+	mu *sync.Mutex
+	tx bool // a view handed to a WithTx callback, which already holds mu
+	*memData
+}
+
+type memData struct {
+	sessions   map[string]*memSession
+	matchState map[string]string
+	initial    map[string]InitialStateRow
+	moves      map[string][]MoveRow
+
+	exhibition   []ExhibitionResultRow
+	archive      []ArchivedMatchRow
+	matchPlayers []memMatchPlayer
+	seasons      map[string]SeasonRow
+
+	bots        map[string]BotRow // by key hash
+	challenges  map[string]ChallengeRow
+	friendships []FriendshipRow
+	push        map[string]PushSubscriptionRow // by endpoint
+	email       map[string]EmailContactRow     // by player ID
+	audit       []AuditRow
+	timeline    map[string][]TimelineRow // by session code
+	timelineID  int64                    // last timeline entry's ID
+	inbox       map[string][]InboxRow    // by player ID
+	inboxID     int64                    // last inbox message's ID
+
+	achieved map[string][]AchievementRow // by player ID
+	progress map[[2]string]int           // by player ID and counter
+
+	tournaments []TournamentRow // in the order created
+	pairings    []TournamentPairingRow
+
+	clubs       []ClubRow
+	clubMembers []ClubMemberRow // in the order joined
+
+	surveys []SurveyRow
+	reports []ReportRow
+
+	seq int // insertion counter, to order rows created in the same second
+}
+
+// memSession is a session row, when it was inserted and who is seated
+// in it.
+type memSession struct {
+	SessionRow
+	seq     int
+	players []string
+}
+
+type memMatchPlayer struct {
+	sessionCode string
+	gameType    string
+	playerID    string
+	startedAt   time.Time
+}
+
+// NewMemory returns an empty in-memory backend.
+func NewMemory() *Memory {
+	return &Memory{mu: new(sync.Mutex), memData: &memData{
+		sessions:   make(map[string]*memSession),
+		matchState: make(map[string]string),
+		initial:    make(map[string]InitialStateRow),
+		moves:      make(map[string][]MoveRow),
+		bots:       make(map[string]BotRow),
+		challenges: make(map[string]ChallengeRow),
+		push:       make(map[string]PushSubscriptionRow),
+		email:      make(map[string]EmailContactRow),
+		timeline:   make(map[string][]TimelineRow),
+		inbox:      make(map[string][]InboxRow),
+		seasons:    make(map[string]SeasonRow),
+		achieved:   make(map[string][]AchievementRow),
+		progress:   make(map[[2]string]int),
+	}}
+}
+
+// lock takes the mutex and returns its release, or does nothing inside a
+// WithTx callback, whose transaction holds the mutex throughout.
+func (m *Memory) lock() func() {
+	if m.tx {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+// WithTx runs fn against a view of the backend with every other caller
+// shut out, and puts the data back as it was if fn returns an error.
+// Inside a transaction it runs fn in that transaction.
+func (m *Memory) WithTx(ctx context.Context, fn func(tx Backend) error) error {
+	if m.tx {
+		return fn(m)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := m.memData.clone()
+	if err := fn(&Memory{mu: m.mu, tx: true, memData: m.memData}); err != nil {
+		*m.memData = *saved
+		return err
+	}
+	return nil
+}
+
+// clone copies the data deeply enough that changes to d leave the copy
+// untouched. Move logs, archives, timelines, the audit log, surveys and
+// reports are only ever appended to, so copying their slice headers is
+// enough, and a transaction costs no more as they grow.
+func (d *memData) clone() *memData {
+	c := *d
+	c.sessions = make(map[string]*memSession, len(d.sessions))
+	for code, s := range d.sessions {
+		copied := *s
+		c.sessions[code] = &copied
+	}
+	c.matchState = maps.Clone(d.matchState)
+	c.initial = maps.Clone(d.initial)
+	c.moves = maps.Clone(d.moves)
+	c.bots = maps.Clone(d.bots)
+	c.challenges = maps.Clone(d.challenges)
+	c.friendships = slices.Clone(d.friendships)
+	c.push = maps.Clone(d.push)
+	c.email = maps.Clone(d.email)
+	c.timeline = maps.Clone(d.timeline)
+	c.seasons = maps.Clone(d.seasons)
+	c.progress = maps.Clone(d.progress)
+	c.tournaments = slices.Clone(d.tournaments)
+	c.pairings = slices.Clone(d.pairings)
+	c.clubs = slices.Clone(d.clubs)
+	c.clubMembers = slices.Clone(d.clubMembers)
+	// Inboxes and achievement lists are replaced rather than changed in
+	// place
+	c.inbox = maps.Clone(d.inbox)
+	c.achieved = maps.Clone(d.achieved)
+	return &c
+}
+
+// memNow is the current time as Store would record it.
+func memNow() time.Time {
+	return memTime(time.Now())
+}
+
+// memTime keeps t to the second in UTC, as a DATETIME column does.
+func memTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC().Truncate(time.Second)
+}
+
+func (m *Memory) CreateSession(ctx context.Context, code, gameType string) error {
+	defer m.lock()()
+	if _, ok := m.sessions[code]; ok {
+		return fmt.Errorf("session %s already exists", code)
+	}
+	m.seq++
+	m.sessions[code] = &memSession{
+		SessionRow: SessionRow{Code: code, GameType: gameType, Status: "waiting", Options: "{}", TurnOrder: "join", CreatedAt: memNow()},
+		seq:        m.seq,
+	}
+	return nil
+}
+
+// updateSession applies fn to a session, if there is one.
+func (m *Memory) updateSession(code string, fn func(*SessionRow)) error {
+	defer m.lock()()
+	if s, ok := m.sessions[code]; ok {
+		fn(&s.SessionRow)
+	}
+	return nil
+}
+
+func (m *Memory) SetSessionOptions(ctx context.Context, code, optionsJSON string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Options = optionsJSON })
+}
+
+func (m *Memory) SetSessionParty(ctx context.Context, code, partyJSON string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Party = partyJSON })
+}
+
+func (m *Memory) SetSessionTurnOrder(ctx context.Context, code, turnOrder string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.TurnOrder = turnOrder })
+}
+
+func (m *Memory) SetSessionVoteThresholds(ctx context.Context, code, thresholdsJSON string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.VoteThresholds = thresholdsJSON })
+}
+
+func (m *Memory) SetSessionHandicaps(ctx context.Context, code, handicapsJSON string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Handicaps = handicapsJSON })
+}
+
+func (m *Memory) SetSessionPrivate(ctx context.Context, code string, private bool) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Private = private })
+}
+
+func (m *Memory) SetSessionRulesVersion(ctx context.Context, code, version string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.RulesVersion = version })
+}
+
+func (m *Memory) SetSessionPlayers(ctx context.Context, code string, playerIDs []string) error {
+	defer m.lock()()
+	if s, ok := m.sessions[code]; ok {
+		s.players = slices.Clone(playerIDs)
+	}
+	return nil
+}
+
+func (m *Memory) SetSessionPinned(ctx context.Context, code string, pinned bool) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Pinned = pinned })
+}
+
+func (m *Memory) SetSessionPractice(ctx context.Context, code string, practice bool) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Practice = practice })
+}
+
+func (m *Memory) SetSessionSetup(ctx context.Context, code, setupJSON string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Setup = setupJSON })
+}
+
+func (m *Memory) SetSessionExpireAfter(ctx context.Context, code string, after time.Duration) error {
+	return m.updateSession(code, func(s *SessionRow) { s.ExpireAfter = after.Truncate(time.Second) })
+}
+
+func (m *Memory) SetSessionAbandoned(ctx context.Context, code string, abandoned bool) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Abandoned = abandoned })
+}
+
+func (m *Memory) SetSessionTimes(ctx context.Context, code string, startedAt, finishedAt, lastActivity time.Time) error {
+	return m.updateSession(code, func(s *SessionRow) {
+		s.StartedAt, s.FinishedAt, s.LastActivity = memTime(startedAt), memTime(finishedAt), memTime(lastActivity)
+	})
+}
+
+func (m *Memory) UpdateSessionStatus(ctx context.Context, code, status string) error {
+	return m.updateSession(code, func(s *SessionRow) { s.Status = status })
+}
+
+func (m *Memory) NextRound(ctx context.Context, code, gameType, optionsJSON, partyJSON, previousJSON string) error {
+	defer m.lock()()
+	if s, ok := m.sessions[code]; ok {
+		s.GameType, s.Options, s.Party, s.Previous = gameType, optionsJSON, partyJSON, previousJSON
+		s.Status, s.Abandoned, s.RulesVersion = "waiting", false, ""
+	}
+	m.deleteMatchLocked(code)
+	return nil
+}
+
+// deleteMatchLocked drops a session's match state and move log.
+func (m *Memory) deleteMatchLocked(code string) {
+	delete(m.matchState, code)
+	delete(m.initial, code)
+	delete(m.moves, code)
+}
+
+func (m *Memory) GetSession(ctx context.Context, code string) (*SessionRow, error) {
+	defer m.lock()()
+	s, ok := m.sessions[code]
+	if !ok || !s.DeletedAt.IsZero() {
+		return nil, sql.ErrNoRows
+	}
+	row := s.SessionRow
+	return &row, nil
+}
+
+// listSessionsLocked returns the sessions keep accepts, newest first.
+func (m *Memory) listSessionsLocked(keep func(*SessionRow) bool) []*memSession {
+	var list []*memSession
+	for _, s := range m.sessions {
+		if keep(&s.SessionRow) {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.After(list[j].CreatedAt)
+		}
+		return list[i].seq > list[j].seq
+	})
+	return list
+}
+
+func (m *Memory) ListSessions(ctx context.Context, f SessionFilter) ([]SessionRow, error) {
+	defer m.lock()()
+	list := m.listSessionsLocked(func(s *SessionRow) bool {
+		switch {
+		case !s.DeletedAt.IsZero():
+		case f.GameType != "" && s.GameType != f.GameType:
+		case f.Status != "" && s.Status != f.Status:
+		case f.PublicOnly && s.Private:
+		case f.PlayerID != "" && !slices.Contains(m.sessions[s.Code].players, f.PlayerID):
+		case f.Unfinished && s.Status == "finished":
+		case !f.CreatedAfter.IsZero() && s.CreatedAt.Before(memTime(f.CreatedAfter)):
+		case !f.CreatedBefore.IsZero() && !s.CreatedAt.Before(memTime(f.CreatedBefore)):
+		default:
+			return true
+		}
+		return false
+	})
+	switch f.Sort {
+	case SortOldest:
+		slices.Reverse(list)
+	case SortActivity:
+		active := func(s *memSession) time.Time {
+			if s.LastActivity.IsZero() {
+				return s.CreatedAt
+			}
+			return s.LastActivity
+		}
+		sort.SliceStable(list, func(i, j int) bool { return active(list[i]).After(active(list[j])) })
+	}
+	list = list[min(f.Offset, len(list)):]
+	if f.Limit > 0 && len(list) > f.Limit {
+		list = list[:f.Limit]
+	}
+	var result []SessionRow
+	for _, s := range list {
+		result = append(result, s.SessionRow)
+	}
+	return result, nil
+}
+
+func (m *Memory) DeleteSession(ctx context.Context, code string) error {
+	return m.updateSession(code, func(s *SessionRow) {
+		if s.DeletedAt.IsZero() {
+			s.DeletedAt = memNow()
+		}
+	})
+}
+
+func (m *Memory) RestoreSession(ctx context.Context, code string) (bool, error) {
+	defer m.lock()()
+	s, ok := m.sessions[code]
+	if !ok || s.DeletedAt.IsZero() {
+		return false, nil
+	}
+	s.DeletedAt = time.Time{}
+	return true, nil
+}
+
+func (m *Memory) GetDeletedSession(ctx context.Context, code string) (*SessionRow, error) {
+	defer m.lock()()
+	s, ok := m.sessions[code]
+	if !ok || s.DeletedAt.IsZero() {
+		return nil, sql.ErrNoRows
+	}
+	row := s.SessionRow
+	return &row, nil
+}
+
+func (m *Memory) ListDeletedSessions(ctx context.Context) ([]SessionRow, error) {
+	defer m.lock()()
+	list := m.listSessionsLocked(func(s *SessionRow) bool { return !s.DeletedAt.IsZero() })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].DeletedAt.After(list[j].DeletedAt) })
+	var result []SessionRow
+	for _, s := range list {
+		result = append(result, s.SessionRow)
+	}
+	return result, nil
+}
+
+func (m *Memory) PurgeDeletedSessions(ctx context.Context, cutoff time.Time) (int64, error) {
+	defer m.lock()()
+	var n int64
+	for code, s := range m.sessions {
+		if s.DeletedAt.IsZero() || !s.DeletedAt.Before(memTime(cutoff)) {
+			continue
+		}
+		m.deleteMatchLocked(code)
+		// Player rosters are saved under "<code>_players"
+		delete(m.matchState, code+"_players")
+		delete(m.timeline, code)
+		delete(m.sessions, code)
+		n++
+	}
+	return n, nil
+}
+
+// deletedLocked reports whether sessionCode belongs to a soft-deleted
+// session, whose match data reads as missing.
+func (m *Memory) deletedLocked(sessionCode string) bool {
+	s, ok := m.sessions[sessionCode]
+	return ok && !s.DeletedAt.IsZero()
+}
+
+func (m *Memory) SaveMatchState(ctx context.Context, sessionCode, stateJSON string) error {
+	defer m.lock()()
+	m.matchState[sessionCode] = stateJSON
+	return nil
+}
+
+func (m *Memory) GetMatchState(ctx context.Context, sessionCode string) (string, error) {
+	defer m.lock()()
+	state, ok := m.matchState[sessionCode]
+	if !ok || m.deletedLocked(sessionCode) {
+		return "", sql.ErrNoRows
+	}
+	return state, nil
+}
+
+func (m *Memory) SaveInitialState(ctx context.Context, sessionCode string, row InitialStateRow) error {
+	defer m.lock()()
+	m.initial[sessionCode] = row
+	return nil
+}
+
+func (m *Memory) GetInitialState(ctx context.Context, sessionCode string) (*InitialStateRow, error) {
+	defer m.lock()()
+	row, ok := m.initial[sessionCode]
+	if !ok || m.deletedLocked(sessionCode) {
+		return nil, sql.ErrNoRows
+	}
+	return &row, nil
+}
+
+func (m *Memory) AppendMove(ctx context.Context, sessionCode string, seq int, playerID, actionJSON, timingJSON string) error {
+	defer m.lock()()
+	for _, mv := range m.moves[sessionCode] {
+		if mv.Seq == seq {
+			return fmt.Errorf("move %d of session %s already recorded", seq, sessionCode)
+		}
+	}
+	m.moves[sessionCode] = append(m.moves[sessionCode], MoveRow{
+		SessionCode: sessionCode, Seq: seq, PlayerID: playerID, ActionJSON: actionJSON, TimingJSON: timingJSON, CreatedAt: memNow(),
+	})
+	return nil
+}
+
+func (m *Memory) ListMoves(ctx context.Context, sessionCode string) ([]MoveRow, error) {
+	defer m.lock()()
+	if m.deletedLocked(sessionCode) {
+		return nil, nil
+	}
+	result := slices.Clone(m.moves[sessionCode])
+	sort.Slice(result, func(i, j int) bool { return result[i].Seq < result[j].Seq })
+	return result, nil
+}
+
+func (m *Memory) TruncateMoves(ctx context.Context, sessionCode string, n int) error {
+	defer m.lock()()
+	m.moves[sessionCode] = slices.DeleteFunc(m.moves[sessionCode], func(mv MoveRow) bool { return mv.Seq > n })
+	return nil
+}
+
+func (m *Memory) ArchiveExhibitionResults(ctx context.Context, results []ExhibitionResultRow) error {
+	defer m.lock()()
+	type key struct{ sessionCode, playerID string }
+	seen := make(map[key]bool)
+	for _, r := range append(slices.Clone(m.exhibition), results...) {
+		k := key{r.SessionCode, r.PlayerID}
+		if seen[k] {
+			return fmt.Errorf("result of %s in %s already archived", r.PlayerID, r.SessionCode)
+		}
+		seen[k] = true
+	}
+	now := memNow()
+	for _, r := range results {
+		r.FinishedAt = now
+		m.exhibition = append(m.exhibition, r)
+	}
+	return nil
+}
+
+func (m *Memory) ListExhibitionResults(ctx context.Context, gameType string) ([]ExhibitionResultRow, error) {
+	defer m.lock()()
+	var result []ExhibitionResultRow
+	for _, r := range m.exhibition {
+		if r.GameType == gameType {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].FinishedAt.Equal(result[j].FinishedAt) {
+			return result[i].FinishedAt.After(result[j].FinishedAt)
+		}
+		return result[i].SessionCode < result[j].SessionCode
+	})
+	return result, nil
+}
+
+func (m *Memory) ArchiveMatch(ctx context.Context, a ArchivedMatchRow) error {
+	defer m.lock()()
+	a.StartedAt, a.FinishedAt = memTime(a.StartedAt), memTime(a.FinishedAt)
+	m.archive = append(m.archive, a)
+	return nil
+}
+
+func (m *Memory) ListArchivedMatches(ctx context.Context, gameType string, limit int) ([]ArchivedMatchRow, error) {
+	defer m.lock()()
+	var result []ArchivedMatchRow
+	// Newest archived first, so the stable sort breaks ties as Store does.
+	for i := len(m.archive) - 1; i >= 0; i-- {
+		if m.archive[i].GameType == gameType {
+			result = append(result, m.archive[i])
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].FinishedAt.After(result[j].FinishedAt) })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *Memory) MatchStats(ctx context.Context, gameType string, from, to time.Time) (MatchStatsRow, error) {
+	defer m.lock()()
+	var st MatchStatsRow
+	var total time.Duration
+	moves := 0
+	for _, a := range m.archive {
+		switch {
+		case a.GameType != gameType:
+		case !from.IsZero() && a.FinishedAt.Before(from):
+		case !to.IsZero() && !a.FinishedAt.Before(to):
+		case a.Abandoned:
+			st.Abandoned++
+		default:
+			st.Matches++
+			total += a.FinishedAt.Sub(a.StartedAt)
+			moves += a.Moves
+		}
+	}
+	if st.Matches > 0 {
+		st.AvgDuration = (total / time.Duration(st.Matches)).Round(time.Second)
+		st.AvgMoves = float64(moves) / float64(st.Matches)
+	}
+	return st, nil
+}
+
+func (m *Memory) RecordMatchPlayers(ctx context.Context, sessionCode, gameType string, playerIDs []string) error {
+	defer m.lock()()
+	now := memNow()
+	for _, id := range playerIDs {
+		if !slices.ContainsFunc(m.matchPlayers, func(p memMatchPlayer) bool {
+			return p.sessionCode == sessionCode && p.playerID == id
+		}) {
+			m.matchPlayers = append(m.matchPlayers, memMatchPlayer{sessionCode, gameType, id, now})
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListRecentOpponents(ctx context.Context, playerID string, limit int) ([]OpponentRow, error) {
+	defer m.lock()()
+	latest := make(map[string]OpponentRow)
+	for _, me := range m.matchPlayers {
+		if me.playerID != playerID {
+			continue
+		}
+		for _, other := range m.matchPlayers {
+			if other.sessionCode != me.sessionCode || other.playerID == playerID {
+				continue
+			}
+			if o, ok := latest[other.playerID]; !ok || other.startedAt.After(o.PlayedAt) {
+				latest[other.playerID] = OpponentRow{PlayerID: other.playerID, GameType: other.gameType, SessionCode: other.sessionCode, PlayedAt: other.startedAt}
+			}
+		}
+	}
+	result := make([]OpponentRow, 0, len(latest))
+	for _, o := range latest {
+		result = append(result, o)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].PlayedAt.Equal(result[j].PlayedAt) {
+			return result[i].PlayedAt.After(result[j].PlayedAt)
+		}
+		return result[i].PlayerID < result[j].PlayerID
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *Memory) SaveSeason(ctx context.Context, season SeasonRow) error {
+	defer m.lock()()
+	if _, ok := m.seasons[season.Name]; !ok {
+		m.seasons[season.Name] = season
+	}
+	return nil
+}
+
+func (m *Memory) GetSeason(ctx context.Context, name string) (*SeasonRow, error) {
+	defer m.lock()()
+	r, ok := m.seasons[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &r, nil
+}
+
+func (m *Memory) ListSeasons(ctx context.Context) ([]SeasonRow, error) {
+	defer m.lock()()
+	result := slices.Collect(maps.Values(m.seasons))
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].StartedAt.After(result[j].StartedAt)
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (m *Memory) CreateBot(ctx context.Context, id, name, keyHash, webhookURL string) error {
+	defer m.lock()()
+	for _, b := range m.bots {
+		if b.ID == id {
+			return fmt.Errorf("bot %s already exists", id)
+		}
+	}
+	if _, ok := m.bots[keyHash]; ok {
+		return fmt.Errorf("bot key already registered")
+	}
+	m.bots[keyHash] = BotRow{ID: id, Name: name, KeyHash: keyHash, WebhookURL: webhookURL, CreatedAt: memNow()}
+	return nil
+}
+
+func (m *Memory) GetBotByKeyHash(ctx context.Context, keyHash string) (*BotRow, error) {
+	defer m.lock()()
+	b, ok := m.bots[keyHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &b, nil
+}
+
+func (m *Memory) CreateChallenge(ctx context.Context, c ChallengeRow) error {
+	defer m.lock()()
+	if _, ok := m.challenges[c.ID]; ok {
+		return fmt.Errorf("challenge %s already exists", c.ID)
+	}
+	m.challenges[c.ID] = ChallengeRow{
+		ID: c.ID, GameType: c.GameType, ChallengerID: c.ChallengerID, TargetID: c.TargetID,
+		Status: "pending", CreatedAt: memNow(), ExpiresAt: time.Unix(c.ExpiresAt.Unix(), 0),
+	}
+	return nil
+}
+
+func (m *Memory) GetChallenge(ctx context.Context, id string) (*ChallengeRow, error) {
+	defer m.lock()()
+	c, ok := m.challenges[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &c, nil
+}
+
+func (m *Memory) ListPendingChallenges(ctx context.Context, playerID string, now time.Time) ([]ChallengeRow, error) {
+	defer m.lock()()
+	var result []ChallengeRow
+	for _, c := range m.challenges {
+		if c.Status == "pending" && c.ExpiresAt.Unix() > now.Unix() && (c.ChallengerID == playerID || c.TargetID == playerID) {
+			result = append(result, c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (m *Memory) ResolveChallenge(ctx context.Context, id, status, sessionCode string) (bool, error) {
+	defer m.lock()()
+	c, ok := m.challenges[id]
+	if !ok || c.Status != "pending" {
+		return false, nil
+	}
+	c.Status, c.SessionCode = status, sessionCode
+	m.challenges[id] = c
+	return true, nil
+}
+
+func (m *Memory) ExpireChallenges(ctx context.Context, now time.Time) (int64, error) {
+	defer m.lock()()
+	var n int64
+	for id, c := range m.challenges {
+		if c.Status == "pending" && c.ExpiresAt.Unix() <= now.Unix() {
+			c.Status = "expired"
+			m.challenges[id] = c
+			n++
+		}
+	}
+	return n, nil
+}
+
+// friendshipLocked returns the index of the friendship or request between
+// two players, in either direction, or -1.
+func (m *Memory) friendshipLocked(a, b string) int {
+	return slices.IndexFunc(m.friendships, func(f FriendshipRow) bool {
+		return (f.RequesterID == a && f.AddresseeID == b) || (f.RequesterID == b && f.AddresseeID == a)
+	})
+}
+
+func (m *Memory) CreateFriendRequest(ctx context.Context, requesterID, addresseeID string) error {
+	defer m.lock()()
+	if slices.ContainsFunc(m.friendships, func(f FriendshipRow) bool {
+		return f.RequesterID == requesterID && f.AddresseeID == addresseeID
+	}) {
+		return fmt.Errorf("friend request from %s to %s already exists", requesterID, addresseeID)
+	}
+	m.friendships = append(m.friendships, FriendshipRow{RequesterID: requesterID, AddresseeID: addresseeID, Status: "pending", CreatedAt: memNow()})
+	return nil
+}
+
+func (m *Memory) GetFriendship(ctx context.Context, a, b string) (*FriendshipRow, error) {
+	defer m.lock()()
+	i := m.friendshipLocked(a, b)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	f := m.friendships[i]
+	return &f, nil
+}
+
+func (m *Memory) AcceptFriendRequest(ctx context.Context, requesterID, addresseeID string) (bool, error) {
+	defer m.lock()()
+	for i, f := range m.friendships {
+		if f.RequesterID == requesterID && f.AddresseeID == addresseeID && f.Status == "pending" {
+			m.friendships[i].Status = "accepted"
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) DeleteFriendship(ctx context.Context, a, b string) (bool, error) {
+	defer m.lock()()
+	n := len(m.friendships)
+	m.friendships = slices.DeleteFunc(m.friendships, func(f FriendshipRow) bool {
+		return (f.RequesterID == a && f.AddresseeID == b) || (f.RequesterID == b && f.AddresseeID == a)
+	})
+	return len(m.friendships) < n, nil
+}
+
+func (m *Memory) ListFriendships(ctx context.Context, playerID string) ([]FriendshipRow, error) {
+	defer m.lock()()
+	var result []FriendshipRow
+	for _, f := range m.friendships {
+		if f.RequesterID == playerID || f.AddresseeID == playerID {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) SavePushSubscription(ctx context.Context, sub PushSubscriptionRow) error {
+	defer m.lock()()
+	createdAt := memNow()
+	if old, ok := m.push[sub.Endpoint]; ok {
+		createdAt = old.CreatedAt
+	}
+	sub.CreatedAt = createdAt
+	m.push[sub.Endpoint] = sub
+	return nil
+}
+
+func (m *Memory) ListPushSubscriptions(ctx context.Context, playerID string) ([]PushSubscriptionRow, error) {
+	defer m.lock()()
+	var result []PushSubscriptionRow
+	for _, sub := range m.push {
+		if sub.PlayerID == playerID {
+			result = append(result, sub)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Endpoint < result[j].Endpoint })
+	return result, nil
+}
+
+func (m *Memory) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	defer m.lock()()
+	delete(m.push, endpoint)
+	return nil
+}
+
+func (m *Memory) SaveEmailContact(ctx context.Context, playerID, email, verifyToken string) error {
+	defer m.lock()()
+	c, ok := m.email[playerID]
+	if !ok {
+		c = EmailContactRow{PlayerID: playerID, NotifyTurns: true, NotifyChallenges: true, CreatedAt: memNow()}
+	}
+	c.Email, c.Verified, c.VerifyToken = email, false, verifyToken
+	m.email[playerID] = c
+	return nil
+}
+
+func (m *Memory) GetEmailContact(ctx context.Context, playerID string) (*EmailContactRow, error) {
+	defer m.lock()()
+	c, ok := m.email[playerID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &c, nil
+}
+
+func (m *Memory) VerifyEmail(ctx context.Context, verifyToken string) (string, error) {
+	defer m.lock()()
+	if verifyToken == "" {
+		return "", sql.ErrNoRows
+	}
+	for id, c := range m.email {
+		if c.VerifyToken == verifyToken {
+			c.Verified, c.VerifyToken = true, ""
+			m.email[id] = c
+			return id, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (m *Memory) UpdateEmailPreferences(ctx context.Context, playerID string, turns, challenges bool) (bool, error) {
+	defer m.lock()()
+	c, ok := m.email[playerID]
+	if !ok {
+		return false, nil
+	}
+	c.NotifyTurns, c.NotifyChallenges = turns, challenges
+	m.email[playerID] = c
+	return true, nil
+}
+
+func (m *Memory) AppendAudit(ctx context.Context, a AuditRow) error {
+	defer m.lock()()
+	a.ID = int64(len(m.audit) + 1)
+	a.CreatedAt = memNow()
+	m.audit = append(m.audit, a)
+	return nil
+}
+
+func (m *Memory) ListAudit(ctx context.Context, f AuditFilter) ([]AuditRow, error) {
+	defer m.lock()()
+	var result []AuditRow
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		a := m.audit[i]
+		switch {
+		case f.Actor != "" && a.Actor != f.Actor:
+		case f.Action != "" && a.Action != f.Action:
+		case f.SessionCode != "" && a.SessionCode != f.SessionCode:
+		case f.PlayerID != "" && a.PlayerID != f.PlayerID:
+		case !f.Since.IsZero() && a.CreatedAt.Before(memTime(f.Since)):
+		default:
+			result = append(result, a)
+		}
+		if f.Limit > 0 && len(result) == f.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) AppendTimeline(ctx context.Context, t TimelineRow) error {
+	defer m.lock()()
+	m.timelineID++
+	t.ID = m.timelineID
+	t.CreatedAt = memNow()
+	m.timeline[t.SessionCode] = append(m.timeline[t.SessionCode], t)
+	return nil
+}
+
+func (m *Memory) ListTimeline(ctx context.Context, sessionCode string, afterID int64, limit int) ([]TimelineRow, error) {
+	defer m.lock()()
+	var result []TimelineRow
+	for _, t := range m.timeline[sessionCode] {
+		if t.ID <= afterID {
+			continue
+		}
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func (m *Memory) AddToInbox(ctx context.Context, row InboxRow) error {
+	defer m.lock()()
+	m.inboxID++
+	row.ID = m.inboxID
+	row.CreatedAt = memNow()
+	kept := slices.DeleteFunc(slices.Clone(m.inbox[row.PlayerID]), func(r InboxRow) bool { return r.Topic == row.Topic })
+	m.inbox[row.PlayerID] = append(kept, row)
+	return nil
+}
+
+func (m *Memory) ListInbox(ctx context.Context, playerID string) ([]InboxRow, error) {
+	defer m.lock()()
+	return slices.Clone(m.inbox[playerID]), nil
+}
+
+func (m *Memory) ClearInbox(ctx context.Context, playerID string, through int64) error {
+	defer m.lock()()
+	kept := slices.DeleteFunc(slices.Clone(m.inbox[playerID]), func(r InboxRow) bool { return r.ID <= through })
+	if len(kept) == 0 {
+		delete(m.inbox, playerID)
+	} else {
+		m.inbox[playerID] = kept
+	}
+	return nil
+}
+
+func (m *Memory) UnlockAchievement(ctx context.Context, a AchievementRow) (bool, error) {
+	defer m.lock()()
+	list := m.achieved[a.PlayerID]
+	if slices.ContainsFunc(list, func(r AchievementRow) bool { return r.Achievement == a.Achievement }) {
+		return false, nil
+	}
+	a.UnlockedAt = memNow()
+	m.achieved[a.PlayerID] = append(slices.Clone(list), a)
+	return true, nil
+}
+
+func (m *Memory) ListAchievements(ctx context.Context, playerID string) ([]AchievementRow, error) {
+	defer m.lock()()
+	return slices.Clone(m.achieved[playerID]), nil
+}
+
+func (m *Memory) IncrementProgress(ctx context.Context, playerID, counter string) (int, error) {
+	defer m.lock()()
+	m.progress[[2]string{playerID, counter}]++
+	return m.progress[[2]string{playerID, counter}], nil
+}
+
+func (m *Memory) ResetProgress(ctx context.Context, playerID, counter string) error {
+	defer m.lock()()
+	delete(m.progress, [2]string{playerID, counter})
+	return nil
+}
+
+func (m *Memory) CreateTournament(ctx context.Context, t TournamentRow) error {
+	defer m.lock()()
+	if slices.ContainsFunc(m.tournaments, func(r TournamentRow) bool { return r.ID == t.ID }) {
+		return fmt.Errorf("tournament %s already exists", t.ID)
+	}
+	m.tournaments = append(m.tournaments, TournamentRow{
+		ID: t.ID, Name: t.Name, GameType: t.GameType, Format: t.Format, Rounds: t.Rounds,
+		Status: "registering", Organizer: t.Organizer, Players: t.Players, Club: t.Club, CreatedAt: memNow(),
+	})
+	return nil
+}
+
+func (m *Memory) GetTournament(ctx context.Context, id string) (*TournamentRow, error) {
+	defer m.lock()()
+	i := slices.IndexFunc(m.tournaments, func(r TournamentRow) bool { return r.ID == id })
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	t := m.tournaments[i]
+	return &t, nil
+}
+
+func (m *Memory) ListTournaments(ctx context.Context, club string) ([]TournamentRow, error) {
+	defer m.lock()()
+	var result []TournamentRow
+	for i := len(m.tournaments) - 1; i >= 0; i-- {
+		if m.tournaments[i].Club == club {
+			result = append(result, m.tournaments[i])
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) UpdateTournament(ctx context.Context, t TournamentRow) error {
+	defer m.lock()()
+	for i, r := range m.tournaments {
+		if r.ID == t.ID {
+			r.Rounds, r.Round, r.Status, r.Players = t.Rounds, t.Round, t.Status, t.Players
+			m.tournaments[i] = r
+		}
+	}
+	return nil
+}
+
+func (m *Memory) AddPairings(ctx context.Context, pairings []TournamentPairingRow) error {
+	defer m.lock()()
+	for _, p := range pairings {
+		if slices.ContainsFunc(m.pairings, func(r TournamentPairingRow) bool {
+			return r.TournamentID == p.TournamentID && r.Round == p.Round && r.Board == p.Board
+		}) {
+			return fmt.Errorf("round %d board %d of %s is already paired", p.Round, p.Board, p.TournamentID)
+		}
+	}
+	m.pairings = append(m.pairings, pairings...)
+	return nil
+}
+
+func (m *Memory) ListPairings(ctx context.Context, tournamentID string) ([]TournamentPairingRow, error) {
+	defer m.lock()()
+	var result []TournamentPairingRow
+	for _, p := range m.pairings {
+		if p.TournamentID == tournamentID {
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Round != result[j].Round {
+			return result[i].Round < result[j].Round
+		}
+		return result[i].Board < result[j].Board
+	})
+	return result, nil
+}
+
+func (m *Memory) GetPairingBySession(ctx context.Context, sessionCode string) (*TournamentPairingRow, error) {
+	defer m.lock()()
+	i := slices.IndexFunc(m.pairings, func(p TournamentPairingRow) bool { return sessionCode != "" && p.SessionCode == sessionCode })
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	p := m.pairings[i]
+	return &p, nil
+}
+
+func (m *Memory) SetPairingResult(ctx context.Context, sessionCode, result string) (bool, error) {
+	defer m.lock()()
+	i := slices.IndexFunc(m.pairings, func(p TournamentPairingRow) bool { return sessionCode != "" && p.SessionCode == sessionCode })
+	if i < 0 || m.pairings[i].Result != "" {
+		return false, nil
+	}
+	m.pairings[i].Result = result
+	return true, nil
+}
+
+func (m *Memory) CreateClub(ctx context.Context, c ClubRow) error {
+	defer m.lock()()
+	if slices.ContainsFunc(m.clubs, func(r ClubRow) bool { return r.ID == c.ID }) {
+		return fmt.Errorf("club %s already exists", c.ID)
+	}
+	now := memNow()
+	m.clubs = append(m.clubs, ClubRow{ID: c.ID, Name: c.Name, Owner: c.Owner, CreatedAt: now})
+	m.clubMembers = append(m.clubMembers, ClubMemberRow{ClubID: c.ID, PlayerID: c.Owner, Role: "owner", JoinedAt: now})
+	return nil
+}
+
+// clubRow returns a club with its member count. The caller must hold
+// the lock.
+func (m *Memory) clubRow(c ClubRow) ClubRow {
+	c.Members = 0
+	for _, r := range m.clubMembers {
+		if r.ClubID == c.ID {
+			c.Members++
+		}
+	}
+	return c
+}
+
+func (m *Memory) GetClub(ctx context.Context, id string) (*ClubRow, error) {
+	defer m.lock()()
+	i := slices.IndexFunc(m.clubs, func(r ClubRow) bool { return r.ID == id })
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	c := m.clubRow(m.clubs[i])
+	return &c, nil
+}
+
+func (m *Memory) ListClubs(ctx context.Context) ([]ClubRow, error) {
+	defer m.lock()()
+	result := make([]ClubRow, len(m.clubs))
+	for i, c := range m.clubs {
+		result[i] = m.clubRow(c)
+	}
+	slices.SortStableFunc(result, func(a, b ClubRow) int { return strings.Compare(a.Name, b.Name) })
+	return result, nil
+}
+
+func (m *Memory) SetClubBranding(ctx context.Context, id, brandingJSON string) error {
+	defer m.lock()()
+	for i := range m.clubs {
+		if m.clubs[i].ID == id {
+			m.clubs[i].Branding = brandingJSON
+		}
+	}
+	return nil
+}
+
+// clubMember returns the index of a player's membership of a club, or
+// -1. The caller must hold the lock.
+func (m *Memory) clubMember(clubID, playerID string) int {
+	return slices.IndexFunc(m.clubMembers, func(r ClubMemberRow) bool { return r.ClubID == clubID && r.PlayerID == playerID })
+}
+
+func (m *Memory) AddClubMember(ctx context.Context, row ClubMemberRow) (bool, error) {
+	defer m.lock()()
+	if m.clubMember(row.ClubID, row.PlayerID) >= 0 {
+		return false, nil
+	}
+	row.JoinedAt = memNow()
+	m.clubMembers = append(m.clubMembers, row)
+	return true, nil
+}
+
+func (m *Memory) GetClubMember(ctx context.Context, clubID, playerID string) (*ClubMemberRow, error) {
+	defer m.lock()()
+	i := m.clubMember(clubID, playerID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	r := m.clubMembers[i]
+	return &r, nil
+}
+
+func (m *Memory) ListClubMembers(ctx context.Context, clubID string) ([]ClubMemberRow, error) {
+	defer m.lock()()
+	var result []ClubMemberRow
+	for _, r := range m.clubMembers {
+		if r.ClubID == clubID {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) ListPlayerClubs(ctx context.Context, playerID string) ([]ClubMemberRow, error) {
+	defer m.lock()()
+	var result []ClubMemberRow
+	for _, r := range m.clubMembers {
+		if r.PlayerID == playerID {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) SetClubRole(ctx context.Context, clubID, playerID, role string) (bool, error) {
+	defer m.lock()()
+	i := m.clubMember(clubID, playerID)
+	if i < 0 {
+		return false, nil
+	}
+	m.clubMembers[i].Role = role
+	return true, nil
+}
+
+func (m *Memory) RemoveClubMember(ctx context.Context, clubID, playerID string) (bool, error) {
+	defer m.lock()()
+	i := m.clubMember(clubID, playerID)
+	if i < 0 {
+		return false, nil
+	}
+	m.clubMembers = slices.Delete(m.clubMembers, i, i+1)
+	return true, nil
+}
+
+func (m *Memory) SaveSurvey(ctx context.Context, r SurveyRow) error {
+	defer m.lock()()
+	r.ID = int64(len(m.surveys) + 1)
+	r.CreatedAt = memNow()
+	m.surveys = append(m.surveys, r)
+	return nil
+}
+
+func (m *Memory) ListSurveys(ctx context.Context, f SurveyFilter) ([]SurveyRow, error) {
+	defer m.lock()()
+	var result []SurveyRow
+	for i := len(m.surveys) - 1; i >= 0; i-- {
+		r := m.surveys[i]
+		switch {
+		case f.GameType != "" && r.GameType != f.GameType:
+		case f.PlayerID != "" && r.PlayerID != f.PlayerID:
+		case f.Bugs && r.Bug == "":
+		default:
+			result = append(result, r)
+		}
+		if f.Limit > 0 && len(result) == f.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) SaveReport(ctx context.Context, r ReportRow) (int64, error) {
+	defer m.lock()()
+	r.ID = int64(len(m.reports) + 1)
+	r.CreatedAt = memNow()
+	m.reports = append(m.reports, r)
+	return r.ID, nil
+}
+
+func (m *Memory) ListReports(ctx context.Context, f ReportFilter) ([]ReportRow, error) {
+	defer m.lock()()
+	var result []ReportRow
+	for i := len(m.reports) - 1; i >= 0; i-- {
+		r := m.reports[i]
+		r.Bundle = ""
+		switch {
+		case f.SessionCode != "" && r.SessionCode != f.SessionCode:
+		case f.GameType != "" && r.GameType != f.GameType:
+		default:
+			result = append(result, r)
+		}
+		if f.Limit > 0 && len(result) == f.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) GetReport(ctx context.Context, id int64) (*ReportRow, error) {
+	defer m.lock()()
+	if id < 1 || id > int64(len(m.reports)) {
+		return nil, sql.ErrNoRows
+	}
+	r := m.reports[id-1]
+	return &r, nil
+}
+
+// Maintain does nothing; there is no file to compact.
+func (m *Memory) Maintain(ctx context.Context) error {
+	return nil
+}
+
+// Size reports row counts under the table names Store uses. Nothing is
+// on disk, so the byte counts are zero.
+func (m *Memory) Size(ctx context.Context) (SizeRow, error) {
+	defer m.lock()()
+	moves, players, timeline, inbox, achieved := 0, 0, 0, 0, 0
+	for _, list := range m.moves {
+		moves += len(list)
+	}
+	for _, list := range m.timeline {
+		timeline += len(list)
+	}
+	for _, list := range m.inbox {
+		inbox += len(list)
+	}
+	for _, s := range m.sessions {
+		players += len(s.players)
+	}
+	for _, list := range m.achieved {
+		achieved += len(list)
+	}
+	return SizeRow{Rows: map[string]int64{
+		"sessions":            int64(len(m.sessions)),
+		"match_state":         int64(len(m.matchState)),
+		"match_initial_state": int64(len(m.initial)),
+		"match_moves":         int64(moves),
+		"exhibition_results":  int64(len(m.exhibition)),
+		"match_archive":       int64(len(m.archive)),
+		"external_bots":       int64(len(m.bots)),
+		"challenges":          int64(len(m.challenges)),
+		"friendships":         int64(len(m.friendships)),
+		"match_players":       int64(len(m.matchPlayers)),
+		"session_players":     int64(players),
+		"push_subscriptions":  int64(len(m.push)),
+		"email_contacts":      int64(len(m.email)),
+		"audit_log":           int64(len(m.audit)),
+		"session_timeline":    int64(timeline),
+		"inbox":               int64(inbox),
+		"seasons":             int64(len(m.seasons)),
+		"achievements":        int64(achieved),
+		"player_progress":     int64(len(m.progress)),
+		"tournaments":         int64(len(m.tournaments)),
+		"tournament_pairings": int64(len(m.pairings)),
+		"clubs":               int64(len(m.clubs)),
+		"club_members":        int64(len(m.clubMembers)),
+		"surveys":             int64(len(m.surveys)),
+		"reports":             int64(len(m.reports)),
+	}}, nil
+}
+
+// Close does nothing; the data goes when the process does.
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,560 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// eachBackend runs fn against a fresh Store and a fresh Memory, so the two
+// are held to the same behavior.
+func eachBackend(t *testing.T, fn func(t *testing.T, b Backend)) {
+	t.Run("sqlite", func(t *testing.T) { fn(t, newTestStore(t)) })
+	t.Run("memory", func(t *testing.T) { fn(t, NewMemory()) })
+}
+
+func TestBackendSessions(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		b.CreateSession(t.Context(), "AAAA", "tictactoe")
+		b.CreateSession(t.Context(), "BBBB", "tictactoe")
+		if err := b.CreateSession(t.Context(), "AAAA", "tictactoe"); err == nil {
+			t.Fatal("expected error creating a duplicate session")
+		}
+		b.UpdateSessionStatus(t.Context(), "BBBB", "playing")
+		b.SetSessionOptions(t.Context(), "BBBB", `{"misere":1}`)
+		start := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+		b.SetSessionTimes(t.Context(), "BBBB", start, time.Time{}, start)
+
+		row, err := b.GetSession(t.Context(), "BBBB")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if row.Status != "playing" || row.Options != `{"misere":1}` || row.TurnOrder != "join" {
+			t.Fatalf("unexpected row %+v", row)
+		}
+		if !row.StartedAt.Equal(start.Truncate(time.Second)) || !row.FinishedAt.IsZero() {
+			t.Fatalf("expected times kept to the second, got %v %v", row.StartedAt, row.FinishedAt)
+		}
+		if _, err := b.GetSession(t.Context(), "ZZZZ"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+		if rows, _ := b.ListSessions(t.Context(), SessionFilter{Status: "playing"}); len(rows) != 1 || rows[0].Code != "BBBB" {
+			t.Fatalf("expected one playing session, got %+v", rows)
+		}
+
+		b.SaveMatchState(t.Context(), "AAAA", `{"turn":1}`)
+		b.AppendMove(t.Context(), "AAAA", 1, "alice", `{"type":"move"}`, "")
+		if err := b.AppendMove(t.Context(), "AAAA", 1, "bob", `{"type":"move"}`, ""); err == nil {
+			t.Fatal("expected error recording a move twice")
+		}
+		b.DeleteSession(t.Context(), "AAAA")
+		if _, err := b.GetMatchState(t.Context(), "AAAA"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected a deleted session's state to be hidden, got %v", err)
+		}
+		if moves, _ := b.ListMoves(t.Context(), "AAAA"); len(moves) != 0 {
+			t.Fatalf("expected a deleted session's moves to be hidden, got %+v", moves)
+		}
+		if ok, _ := b.RestoreSession(t.Context(), "AAAA"); !ok {
+			t.Fatal("expected the session to be restored")
+		}
+		if moves, _ := b.ListMoves(t.Context(), "AAAA"); len(moves) != 1 || moves[0].PlayerID != "alice" {
+			t.Fatalf("expected the move back, got %+v", moves)
+		}
+		b.AppendMove(t.Context(), "AAAA", 2, "bob", `{"type":"move"}`, "")
+		b.AppendMove(t.Context(), "AAAA", 3, "alice", `{"type":"move"}`, "")
+		b.TruncateMoves(t.Context(), "AAAA", 1)
+		if moves, _ := b.ListMoves(t.Context(), "AAAA"); len(moves) != 1 || moves[0].Seq != 1 {
+			t.Fatalf("expected only the first move kept, got %+v", moves)
+		}
+		if err := b.AppendMove(t.Context(), "AAAA", 2, "bob", `{"type":"move"}`, ""); err != nil {
+			t.Fatalf("expected a taken back move's seq free again, got %v", err)
+		}
+
+		b.SetSessionRulesVersion(t.Context(), "AAAA", "2")
+		if row, _ = b.GetSession(t.Context(), "AAAA"); row.RulesVersion != "2" {
+			t.Fatalf("expected the rules version stored, got %q", row.RulesVersion)
+		}
+		b.SetSessionPinned(t.Context(), "AAAA", true)
+		b.SetSessionPractice(t.Context(), "AAAA", true)
+		b.SetSessionSetup(t.Context(), "AAAA", `{"board":"X........"}`)
+		b.SetSessionExpireAfter(t.Context(), "AAAA", 72*time.Hour+time.Millisecond)
+		if row, _ = b.GetSession(t.Context(), "AAAA"); !row.Pinned || !row.Practice || row.Setup != `{"board":"X........"}` || row.ExpireAfter != 72*time.Hour {
+			t.Fatalf("expected the pin, practice, setup and the expiry, to the second, stored, got %+v", row)
+		}
+		b.NextRound(t.Context(), "AAAA", "tictactoe", "{}", "", `{"playerIds":["a","b"]}`)
+		row, _ = b.GetSession(t.Context(), "AAAA")
+		if row.Status != "waiting" || row.Previous != `{"playerIds":["a","b"]}` || row.RulesVersion != "" {
+			t.Fatalf("unexpected row after next round %+v", row)
+		}
+		if _, err := b.GetMatchState(t.Context(), "AAAA"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected the match state cleared, got %v", err)
+		}
+
+		b.DeleteSession(t.Context(), "BBBB")
+		if n, _ := b.PurgeDeletedSessions(t.Context(), time.Now().Add(time.Hour)); n != 1 {
+			t.Fatalf("expected one session purged, got %d", n)
+		}
+		if _, err := b.GetDeletedSession(t.Context(), "BBBB"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected the purged session gone, got %v", err)
+		}
+	})
+}
+
+func TestBackendListSessions(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		for _, code := range []string{"AAAA", "BBBB", "CCCC", "DDDD"} {
+			b.CreateSession(t.Context(), code, "tictactoe")
+		}
+		b.UpdateSessionStatus(t.Context(), "BBBB", "playing")
+		b.SetSessionPrivate(t.Context(), "CCCC", true)
+		b.SetSessionPlayers(t.Context(), "AAAA", []string{"alice", "bob"})
+		b.SetSessionPlayers(t.Context(), "BBBB", []string{"carol"})
+		b.SetSessionPlayers(t.Context(), "BBBB", []string{"alice"})
+		b.SetSessionTimes(t.Context(), "AAAA", time.Time{}, time.Time{}, time.Now().Add(time.Hour))
+		b.DeleteSession(t.Context(), "DDDD")
+
+		codes := func(f SessionFilter) []string {
+			t.Helper()
+			rows, err := b.ListSessions(t.Context(), f)
+			if err != nil {
+				t.Fatalf("list %+v: %v", f, err)
+			}
+			var codes []string
+			for _, r := range rows {
+				codes = append(codes, r.Code)
+			}
+			return codes
+		}
+		for _, c := range []struct {
+			f    SessionFilter
+			want []string
+		}{
+			{SessionFilter{}, []string{"CCCC", "BBBB", "AAAA"}},
+			{SessionFilter{Sort: SortOldest}, []string{"AAAA", "BBBB", "CCCC"}},
+			{SessionFilter{Sort: SortActivity}, []string{"AAAA", "CCCC", "BBBB"}},
+			{SessionFilter{PublicOnly: true}, []string{"BBBB", "AAAA"}},
+			{SessionFilter{Status: "waiting", Sort: SortOldest}, []string{"AAAA", "CCCC"}},
+			{SessionFilter{GameType: "chess"}, nil},
+			{SessionFilter{Limit: 2}, []string{"CCCC", "BBBB"}},
+			{SessionFilter{Limit: 2, Offset: 2}, []string{"AAAA"}},
+			{SessionFilter{Offset: 1}, []string{"BBBB", "AAAA"}},
+			{SessionFilter{CreatedAfter: time.Now().Add(time.Hour)}, nil},
+			{SessionFilter{CreatedBefore: time.Now().Add(time.Hour)}, []string{"CCCC", "BBBB", "AAAA"}},
+			{SessionFilter{PlayerID: "alice"}, []string{"BBBB", "AAAA"}},
+			{SessionFilter{PlayerID: "carol"}, nil},
+			{SessionFilter{PlayerID: "alice", Unfinished: true, Status: "playing"}, []string{"BBBB"}},
+		} {
+			if got := codes(c.f); !slices.Equal(got, c.want) {
+				t.Errorf("list %+v: expected %v, got %v", c.f, c.want, got)
+			}
+		}
+		if row, _ := b.GetSession(t.Context(), "CCCC"); !row.Private {
+			t.Fatal("expected the session stored as private")
+		}
+	})
+}
+
+func TestBackendSocial(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		now := time.Now()
+		b.CreateChallenge(t.Context(), ChallengeRow{ID: "c1", GameType: "tictactoe", ChallengerID: "alice", TargetID: "bob", ExpiresAt: now.Add(time.Hour)})
+		b.CreateChallenge(t.Context(), ChallengeRow{ID: "c2", GameType: "tictactoe", ChallengerID: "carol", TargetID: "bob", ExpiresAt: now.Add(-time.Hour)})
+		if pending, _ := b.ListPendingChallenges(t.Context(), "bob", now); len(pending) != 1 || pending[0].ID != "c1" {
+			t.Fatalf("expected one pending challenge, got %+v", pending)
+		}
+		if n, _ := b.ExpireChallenges(t.Context(), now); n != 1 {
+			t.Fatalf("expected one challenge expired, got %d", n)
+		}
+		if ok, _ := b.ResolveChallenge(t.Context(), "c1", "accepted", "AAAA"); !ok {
+			t.Fatal("expected the challenge to resolve")
+		}
+		if ok, _ := b.ResolveChallenge(t.Context(), "c1", "declined", ""); ok {
+			t.Fatal("expected a resolved challenge to stay resolved")
+		}
+
+		b.CreateFriendRequest(t.Context(), "alice", "bob")
+		if f, err := b.GetFriendship(t.Context(), "bob", "alice"); err != nil || f.Status != "pending" {
+			t.Fatalf("expected a pending request, got %+v %v", f, err)
+		}
+		if ok, _ := b.AcceptFriendRequest(t.Context(), "bob", "alice"); ok {
+			t.Fatal("expected only the addressee's acceptance to count")
+		}
+		b.AcceptFriendRequest(t.Context(), "alice", "bob")
+		if list, _ := b.ListFriendships(t.Context(), "bob"); len(list) != 1 || list[0].Status != "accepted" {
+			t.Fatalf("expected an accepted friendship, got %+v", list)
+		}
+		if ok, _ := b.DeleteFriendship(t.Context(), "bob", "alice"); !ok {
+			t.Fatal("expected the friendship removed")
+		}
+
+		b.SaveEmailContact(t.Context(), "alice", "alice@example.com", "tok")
+		b.UpdateEmailPreferences(t.Context(), "alice", false, true)
+		b.SaveEmailContact(t.Context(), "alice", "alice@example.org", "tok2")
+		if id, err := b.VerifyEmail(t.Context(), "tok2"); err != nil || id != "alice" {
+			t.Fatalf("verify: %q %v", id, err)
+		}
+		if _, err := b.VerifyEmail(t.Context(), ""); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected an empty token not to verify, got %v", err)
+		}
+		c, _ := b.GetEmailContact(t.Context(), "alice")
+		if !c.Verified || c.Email != "alice@example.org" || c.NotifyTurns || !c.NotifyChallenges {
+			t.Fatalf("unexpected contact %+v", c)
+		}
+	})
+}
+
+func TestBackendArchives(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		b.RecordMatchPlayers(t.Context(), "m1", "tictactoe", []string{"alice", "bob"})
+		b.RecordMatchPlayers(t.Context(), "m1", "tictactoe", []string{"alice", "bob"})
+		if opponents, _ := b.ListRecentOpponents(t.Context(), "alice", 10); len(opponents) != 1 || opponents[0].PlayerID != "bob" {
+			t.Fatalf("expected bob as the one opponent, got %+v", opponents)
+		}
+
+		rows := []ExhibitionResultRow{
+			{SessionCode: "e1", GameType: "tictactoe", PlayerID: "bot-1", Strategy: "random", Rank: 1},
+			{SessionCode: "e1", GameType: "tictactoe", PlayerID: "bot-2", Strategy: "perfect", Rank: 2},
+		}
+		b.ArchiveExhibitionResults(t.Context(), rows)
+		if err := b.ArchiveExhibitionResults(t.Context(), rows[:1]); err == nil {
+			t.Fatal("expected error archiving a result twice")
+		}
+		if results, _ := b.ListExhibitionResults(t.Context(), "tictactoe"); len(results) != 2 {
+			t.Fatalf("expected two results, got %+v", results)
+		}
+
+		b.AppendAudit(t.Context(), AuditRow{Actor: "root", Action: "session.delete", SessionCode: "AAAA", Status: 200})
+		b.AppendAudit(t.Context(), AuditRow{Actor: "root", Action: "session.kick", SessionCode: "AAAA", Status: 200})
+		b.AppendAudit(t.Context(), AuditRow{Actor: "mod", Action: "session.kick", SessionCode: "BBBB", Status: 403})
+		if size, err := b.Size(t.Context()); err != nil || size.Rows["audit_log"] != 3 || size.Rows["match_players"] != 2 || size.Rows["exhibition_results"] != 2 {
+			t.Fatalf("unexpected row counts %+v %v", size, err)
+		}
+		audit, _ := b.ListAudit(t.Context(), AuditFilter{Actor: "root", Limit: 1})
+		if len(audit) != 1 || audit[0].Action != "session.kick" || audit[0].ID != 2 {
+			t.Fatalf("expected the latest root entry, got %+v", audit)
+		}
+	})
+}
+
+func TestBackendSeasons(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		if _, err := b.GetSeason(t.Context(), "2026-09"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows for an unknown season, got %v", err)
+		}
+		aug := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+		sep := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+		oct := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		b.SaveSeason(t.Context(), SeasonRow{Name: "2026-08", StartedAt: aug, EndedAt: sep, Leaderboards: `{"a":1}`})
+		b.SaveSeason(t.Context(), SeasonRow{Name: "2026-09", StartedAt: sep, EndedAt: oct, Leaderboards: `{"b":2}`})
+		if err := b.SaveSeason(t.Context(), SeasonRow{Name: "2026-09", StartedAt: sep, EndedAt: oct, Leaderboards: `{"b":3}`}); err != nil {
+			t.Fatalf("save again: %v", err)
+		}
+
+		got, err := b.GetSeason(t.Context(), "2026-09")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !got.StartedAt.Equal(sep) || !got.EndedAt.Equal(oct) || got.Leaderboards != `{"b":2}` {
+			t.Fatalf("expected the first snapshot kept, got %+v", got)
+		}
+		seasons, _ := b.ListSeasons(t.Context())
+		if len(seasons) != 2 || seasons[0].Name != "2026-09" || seasons[1].Name != "2026-08" {
+			t.Fatalf("expected seasons newest first, got %+v", seasons)
+		}
+	})
+}
+
+func TestBackendAchievements(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		if ok, err := b.UnlockAchievement(t.Context(), AchievementRow{PlayerID: "alice", Achievement: "first_win", SessionCode: "AAAA"}); !ok || err != nil {
+			t.Fatalf("expected the achievement unlocked, got %v %v", ok, err)
+		}
+		if ok, _ := b.UnlockAchievement(t.Context(), AchievementRow{PlayerID: "alice", Achievement: "first_win", SessionCode: "BBBB"}); ok {
+			t.Fatal("expected an achievement unlocked only once")
+		}
+		b.UnlockAchievement(t.Context(), AchievementRow{PlayerID: "alice", Achievement: "tictactoe/flawless", SessionCode: "BBBB"})
+		b.UnlockAchievement(t.Context(), AchievementRow{PlayerID: "bob", Achievement: "first_win", SessionCode: "BBBB"})
+		got, err := b.ListAchievements(t.Context(), "alice")
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(got) != 2 || got[0].Achievement != "first_win" || got[0].SessionCode != "AAAA" || got[1].Achievement != "tictactoe/flawless" || got[0].UnlockedAt.IsZero() {
+			t.Fatalf("expected alice's two achievements in order, got %+v", got)
+		}
+
+		b.IncrementProgress(t.Context(), "alice", "win_streak")
+		if n, err := b.IncrementProgress(t.Context(), "alice", "win_streak"); n != 2 || err != nil {
+			t.Fatalf("expected a streak of 2, got %d %v", n, err)
+		}
+		if n, _ := b.IncrementProgress(t.Context(), "bob", "win_streak"); n != 1 {
+			t.Fatalf("expected bob's own count, got %d", n)
+		}
+		b.ResetProgress(t.Context(), "alice", "win_streak")
+		if n, _ := b.IncrementProgress(t.Context(), "alice", "win_streak"); n != 1 {
+			t.Fatalf("expected the streak to start over, got %d", n)
+		}
+	})
+}
+
+func TestBackendTournaments(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		if _, err := b.GetTournament(t.Context(), "tn-1"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows for an unknown tournament, got %v", err)
+		}
+		b.CreateTournament(t.Context(), TournamentRow{ID: "tn-1", Name: "Autumn", GameType: "tictactoe", Format: "swiss", Organizer: "alice", Players: `["alice"]`})
+		b.CreateTournament(t.Context(), TournamentRow{ID: "tn-2", Name: "Winter", GameType: "tictactoe", Format: "round_robin", Organizer: "bob", Players: `[]`})
+		b.CreateTournament(t.Context(), TournamentRow{ID: "tn-3", Name: "Club night", GameType: "tictactoe", Format: "swiss", Organizer: "bob", Players: `[]`, Club: "cl-1"})
+		if err := b.CreateTournament(t.Context(), TournamentRow{ID: "tn-1", Format: "swiss"}); err == nil {
+			t.Fatal("expected a duplicate ID refused")
+		}
+		all, _ := b.ListTournaments(t.Context(), "")
+		if len(all) != 2 || all[0].ID != "tn-2" || all[1].Status != "registering" || all[1].CreatedAt.IsZero() {
+			t.Fatalf("expected both open tournaments newest first, got %+v", all)
+		}
+		if club, _ := b.ListTournaments(t.Context(), "cl-1"); len(club) != 1 || club[0].ID != "tn-3" || club[0].Club != "cl-1" {
+			t.Fatalf("expected only the club's tournament, got %+v", club)
+		}
+
+		b.UpdateTournament(t.Context(), TournamentRow{ID: "tn-1", Rounds: 2, Round: 1, Status: "running", Players: `["alice","bob","carol"]`})
+		b.AddPairings(t.Context(), []TournamentPairingRow{
+			{TournamentID: "tn-1", Round: 1, Board: 2, PlayerA: "carol", Result: "bye"},
+			{TournamentID: "tn-1", Round: 1, Board: 1, PlayerA: "alice", PlayerB: "bob", SessionCode: "AAAA"},
+		})
+		got, err := b.GetTournament(t.Context(), "tn-1")
+		if err != nil || got.Round != 1 || got.Status != "running" || got.Players != `["alice","bob","carol"]` || got.Name != "Autumn" {
+			t.Fatalf("expected the tournament updated, got %+v %v", got, err)
+		}
+		if _, err := b.GetPairingBySession(t.Context(), ""); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected byes not found by session, got %v", err)
+		}
+		if ok, err := b.SetPairingResult(t.Context(), "AAAA", "b"); !ok || err != nil {
+			t.Fatalf("expected the result recorded, got %v %v", ok, err)
+		}
+		if ok, _ := b.SetPairingResult(t.Context(), "AAAA", "a"); ok {
+			t.Fatal("expected a result recorded only once")
+		}
+		p, err := b.GetPairingBySession(t.Context(), "AAAA")
+		if err != nil || p.TournamentID != "tn-1" || p.Result != "b" {
+			t.Fatalf("expected the game found by its session, got %+v %v", p, err)
+		}
+		pairings, _ := b.ListPairings(t.Context(), "tn-1")
+		if len(pairings) != 2 || pairings[0].Board != 1 || pairings[1].PlayerB != "" {
+			t.Fatalf("expected the round's pairings by board, got %+v", pairings)
+		}
+	})
+}
+
+func TestBackendClubs(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		if _, err := b.GetClub(t.Context(), "cl-1"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows for an unknown club, got %v", err)
+		}
+		b.CreateClub(t.Context(), ClubRow{ID: "cl-1", Name: "Rooks", Owner: "alice"})
+		b.CreateClub(t.Context(), ClubRow{ID: "cl-2", Name: "Knights", Owner: "bob"})
+		if err := b.CreateClub(t.Context(), ClubRow{ID: "cl-1", Name: "Again", Owner: "carol"}); err == nil {
+			t.Fatal("expected a duplicate ID refused")
+		}
+		owner, err := b.GetClubMember(t.Context(), "cl-1", "alice")
+		if err != nil || owner.Role != "owner" || owner.JoinedAt.IsZero() {
+			t.Fatalf("expected the owner a member, got %+v %v", owner, err)
+		}
+
+		if ok, err := b.AddClubMember(t.Context(), ClubMemberRow{ClubID: "cl-1", PlayerID: "bob", Role: "member"}); !ok || err != nil {
+			t.Fatalf("expected bob added, got %v %v", ok, err)
+		}
+		if ok, _ := b.AddClubMember(t.Context(), ClubMemberRow{ClubID: "cl-1", PlayerID: "bob", Role: "admin"}); ok {
+			t.Fatal("expected a second join refused")
+		}
+		b.AddClubMember(t.Context(), ClubMemberRow{ClubID: "cl-1", PlayerID: "carol", Role: "member"})
+		if ok, _ := b.SetClubRole(t.Context(), "cl-1", "bob", "admin"); !ok {
+			t.Fatal("expected bob's role changed")
+		}
+		if ok, _ := b.SetClubRole(t.Context(), "cl-1", "dave", "admin"); ok {
+			t.Fatal("expected no role for a non-member")
+		}
+		members, _ := b.ListClubMembers(t.Context(), "cl-1")
+		if len(members) != 3 || members[0].PlayerID != "alice" || members[1].Role != "admin" || members[2].PlayerID != "carol" {
+			t.Fatalf("expected the members in the order joined, got %+v", members)
+		}
+		if mine, _ := b.ListPlayerClubs(t.Context(), "bob"); len(mine) != 2 || mine[0].ClubID != "cl-2" || mine[1].Role != "admin" {
+			t.Fatalf("expected both of bob's clubs, got %+v", mine)
+		}
+
+		if ok, _ := b.RemoveClubMember(t.Context(), "cl-1", "carol"); !ok {
+			t.Fatal("expected carol removed")
+		}
+		if ok, _ := b.RemoveClubMember(t.Context(), "cl-1", "carol"); ok {
+			t.Fatal("expected carol removed only once")
+		}
+		b.SetClubBranding(t.Context(), "cl-1", `{"accentColor":"#336699"}`)
+		clubs, _ := b.ListClubs(t.Context())
+		if len(clubs) != 2 || clubs[0].Name != "Knights" || clubs[1].Members != 2 || clubs[1].CreatedAt.IsZero() {
+			t.Fatalf("expected the clubs by name with their member counts, got %+v", clubs)
+		}
+		if clubs[0].Branding != "" || clubs[1].Branding != `{"accentColor":"#336699"}` {
+			t.Fatalf("expected only the Rooks' branding set, got %+v", clubs)
+		}
+	})
+}
+
+func TestBackendSurveys(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		b.SaveSurvey(t.Context(), SurveyRow{SessionCode: "AAAA", GameType: "tictactoe", PlayerID: "alice", Fun: 5, Conduct: 4})
+		b.SaveSurvey(t.Context(), SurveyRow{SessionCode: "AAAA", GameType: "tictactoe", PlayerID: "bob", Fun: 2, Bug: "the board froze", Transcript: `{"seed":1}`})
+		b.SaveSurvey(t.Context(), SurveyRow{SessionCode: "BBBB", GameType: "connect4", PlayerID: "alice", Comment: "friendly"})
+
+		all, err := b.ListSurveys(t.Context(), SurveyFilter{})
+		if err != nil || len(all) != 3 || all[0].Comment != "friendly" || all[2].Fun != 5 || all[2].Conduct != 4 {
+			t.Fatalf("expected every answer newest first, got %+v %v", all, err)
+		}
+		if all[2].CreatedAt.IsZero() || all[2].ID >= all[1].ID {
+			t.Fatalf("expected increasing IDs and times, got %+v", all)
+		}
+		bugs, _ := b.ListSurveys(t.Context(), SurveyFilter{Bugs: true})
+		if len(bugs) != 1 || bugs[0].PlayerID != "bob" || bugs[0].Transcript != `{"seed":1}` {
+			t.Fatalf("expected bob's bug report with its transcript, got %+v", bugs)
+		}
+		if mine, _ := b.ListSurveys(t.Context(), SurveyFilter{PlayerID: "alice", GameType: "tictactoe"}); len(mine) != 1 || mine[0].SessionCode != "AAAA" {
+			t.Fatalf("expected alice's tictactoe answer, got %+v", mine)
+		}
+		if latest, _ := b.ListSurveys(t.Context(), SurveyFilter{Limit: 1}); len(latest) != 1 || latest[0].SessionCode != "BBBB" {
+			t.Fatalf("expected the latest answer, got %+v", latest)
+		}
+	})
+}
+
+func TestBackendReports(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		first, err := b.SaveReport(t.Context(), ReportRow{SessionCode: "AAAA", GameType: "tictactoe", PlayerID: "alice", Note: "the board got stuck", Bundle: `{"n":1}`})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _ := b.SaveReport(t.Context(), ReportRow{SessionCode: "BBBB", GameType: "connect4", PlayerID: "bob", Note: "no pieces", Bundle: `{"n":2}`})
+		if second <= first {
+			t.Fatalf("expected increasing IDs, got %d then %d", first, second)
+		}
+
+		all, err := b.ListReports(t.Context(), ReportFilter{})
+		if err != nil || len(all) != 2 || all[0].ID != second || all[1].Note != "the board got stuck" || all[1].CreatedAt.IsZero() {
+			t.Fatalf("expected both reports newest first, got %+v %v", all, err)
+		}
+		if all[0].Bundle != "" || all[1].Bundle != "" {
+			t.Fatalf("expected the list without bundles, got %+v", all)
+		}
+		if mine, _ := b.ListReports(t.Context(), ReportFilter{SessionCode: "AAAA"}); len(mine) != 1 || mine[0].PlayerID != "alice" {
+			t.Fatalf("expected session AAAA's report, got %+v", mine)
+		}
+		if games, _ := b.ListReports(t.Context(), ReportFilter{GameType: "connect4", Limit: 1}); len(games) != 1 || games[0].ID != second {
+			t.Fatalf("expected the connect4 report, got %+v", games)
+		}
+
+		r, err := b.GetReport(t.Context(), first)
+		if err != nil || r.Bundle != `{"n":1}` || r.SessionCode != "AAAA" {
+			t.Fatalf("expected the first report with its bundle, got %+v %v", r, err)
+		}
+		if _, err := b.GetReport(t.Context(), second+1); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows for a missing report, got %v", err)
+		}
+	})
+}
+
+func TestBackendTimeline(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		b.CreateSession(t.Context(), "AAAA", "tictactoe")
+		b.AppendTimeline(t.Context(), TimelineRow{SessionCode: "AAAA", Type: "joined", PlayerID: "alice"})
+		b.AppendTimeline(t.Context(), TimelineRow{SessionCode: "BBBB", Type: "joined", PlayerID: "carol"})
+		b.AppendTimeline(t.Context(), TimelineRow{SessionCode: "AAAA", Type: "moved", PlayerID: "alice", Detail: `{"seq":1}`})
+		b.AppendTimeline(t.Context(), TimelineRow{SessionCode: "AAAA", Type: "finished"})
+
+		all, err := b.ListTimeline(t.Context(), "AAAA", 0, 0)
+		if err != nil || len(all) != 3 || all[0].Type != "joined" || all[1].Detail != `{"seq":1}` || all[2].Type != "finished" {
+			t.Fatalf("expected the session's three entries in order, got %+v %v", all, err)
+		}
+		if all[0].CreatedAt.IsZero() || all[0].ID >= all[1].ID {
+			t.Fatalf("expected increasing IDs and times, got %+v", all)
+		}
+		if later, _ := b.ListTimeline(t.Context(), "AAAA", all[0].ID, 1); len(later) != 1 || later[0].ID != all[1].ID {
+			t.Fatalf("expected one entry after the first, got %+v", later)
+		}
+
+		b.DeleteSession(t.Context(), "AAAA")
+		b.PurgeDeletedSessions(t.Context(), time.Now().Add(time.Hour))
+		if left, _ := b.ListTimeline(t.Context(), "AAAA", 0, 0); len(left) != 0 {
+			t.Fatalf("expected a purged session's timeline gone, got %+v", left)
+		}
+	})
+}
+
+func TestBackendInbox(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		b.AddToInbox(t.Context(), InboxRow{PlayerID: "alice", Topic: "your_turn/AAAA", EventJSON: `{"n":1}`})
+		b.AddToInbox(t.Context(), InboxRow{PlayerID: "alice", Topic: "challenge_issued/c1", EventJSON: `{"n":2}`})
+		b.AddToInbox(t.Context(), InboxRow{PlayerID: "bob", Topic: "your_turn/AAAA", EventJSON: `{"n":3}`})
+		// A later turn in the same session replaces the first
+		b.AddToInbox(t.Context(), InboxRow{PlayerID: "alice", Topic: "your_turn/AAAA", EventJSON: `{"n":4}`})
+
+		got, err := b.ListInbox(t.Context(), "alice")
+		if err != nil || len(got) != 2 || got[0].EventJSON != `{"n":2}` || got[1].EventJSON != `{"n":4}` {
+			t.Fatalf("expected alice's two latest messages in order, got %+v %v", got, err)
+		}
+		if got[1].PlayerID != "alice" || got[1].Topic != "your_turn/AAAA" || got[1].CreatedAt.IsZero() {
+			t.Errorf("unexpected row %+v", got[1])
+		}
+		if again, _ := b.ListInbox(t.Context(), "alice"); len(again) != 2 {
+			t.Errorf("expected reading to keep the inbox, got %+v", again)
+		}
+		// A message kept after the inbox was read outlives clearing it
+		b.AddToInbox(t.Context(), InboxRow{PlayerID: "alice", Topic: "your_turn/BBBB", EventJSON: `{"n":5}`})
+		if err := b.ClearInbox(t.Context(), "alice", got[1].ID); err != nil {
+			t.Fatalf("clear inbox: %v", err)
+		}
+		if left, _ := b.ListInbox(t.Context(), "alice"); len(left) != 1 || left[0].EventJSON != `{"n":5}` {
+			t.Errorf("expected only the newer message left, got %+v", left)
+		}
+		if bob, _ := b.ListInbox(t.Context(), "bob"); len(bob) != 1 {
+			t.Errorf("expected bob's message kept, got %+v", bob)
+		}
+	})
+}
+
+func TestBackendWithTx(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b Backend) {
+		b.CreateSession(t.Context(), "AAAA", "tictactoe")
+		failed := errors.New("failed")
+		err := b.WithTx(t.Context(), func(tx Backend) error {
+			tx.UpdateSessionStatus(t.Context(), "AAAA", "playing")
+			tx.SaveMatchState(t.Context(), "AAAA", `{"turn":1}`)
+			return tx.WithTx(t.Context(), func(Backend) error { return failed })
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("expected the callback's error, got %v", err)
+		}
+		if row, _ := b.GetSession(t.Context(), "AAAA"); row.Status != "waiting" {
+			t.Fatalf("expected the status update rolled back, got %q", row.Status)
+		}
+		if _, err := b.GetMatchState(t.Context(), "AAAA"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected the match state rolled back, got %v", err)
+		}
+
+		err = b.WithTx(t.Context(), func(tx Backend) error {
+			if err := tx.UpdateSessionStatus(t.Context(), "AAAA", "playing"); err != nil {
+				return err
+			}
+			if err := tx.SaveMatchState(t.Context(), "AAAA", `{"turn":1}`); err != nil {
+				return err
+			}
+			if row, _ := tx.GetSession(t.Context(), "AAAA"); row.Status != "playing" {
+				t.Errorf("expected the transaction to see its own writes, got %q", row.Status)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("with tx: %v", err)
+		}
+		if state, _ := b.GetMatchState(t.Context(), "AAAA"); state != `{"turn":1}` {
+			t.Fatalf("expected the match state committed, got %q", state)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"games/internal/metrics"
+)
+
+// DefaultSlowQuery is how long a query may take before it is logged,
+// unless SetSlowQueryThreshold says otherwise.
+const DefaultSlowQuery = 250 * time.Millisecond
+
+// QueryMetrics returns the histogram of query durations, labeled by the
+// kind of statement and the table it names first, such as "select
+// sessions".
+func (s *Store) QueryMetrics() *metrics.Histogram {
+	return s.queries
+}
+
+// SetSlowQueryThreshold changes how long a query may take before it is
+// logged with its SQL, without its arguments. Zero or less logs none.
+func (s *Store) SetSlowQueryThreshold(d time.Duration) {
+	s.slowQuery = d
+}
+
+// timedConn times the queries run on a conn. A query's time runs until
+// its first row is ready, not while the caller reads the rest.
+type timedConn struct {
+	conn
+	s *Store
+}
+
+func (c timedConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer c.s.observe(query, time.Now())
+	return c.conn.ExecContext(ctx, query, args...)
+}
+
+func (c timedConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer c.s.observe(query, time.Now())
+	return c.conn.QueryContext(ctx, query, args...)
+}
+
+func (c timedConn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	defer c.s.observe(query, time.Now())
+	return c.conn.QueryRowContext(ctx, query, args...)
+}
+
+func (s *Store) observe(query string, start time.Time) {
+	d := time.Since(start)
+	s.queries.Observe(d, queryName(query))
+	if s.slowQuery > 0 && d >= s.slowQuery {
+		log.Printf("slow query (%s): %s", d.Round(time.Millisecond), strings.Join(strings.Fields(query), " "))
+	}
+}
+
+// queryName labels a statement by its verb and the first table it names,
+// which keeps the label set as small as the set of queries in this file.
+func queryName(query string) string {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return ""
+	}
+	name := strings.ToLower(words[0])
+	for i, w := range words[:len(words)-1] {
+		switch strings.ToUpper(w) {
+		case "FROM", "INTO", "UPDATE":
+			return name + " " + strings.Trim(words[i+1], "(),;")
+		}
+	}
+	return name
+}
@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"games/internal/metrics"
 )
 
 // SessionRow represents a session in the database.
@@ -13,7 +16,44 @@ type SessionRow struct {
 	Code      string
 	GameType  string
 	Status    string // "waiting", "playing", "finished"
+	Options   string // JSON object of game option values
+	Party     string // JSON party queue and standings; empty for single games
+	TurnOrder string // how unseated players are seated: "join" or "random"
+	// VoteThresholds is a JSON object of vote thresholds; empty for the
+	// defaults.
+	VoteThresholds string
+	// Handicaps is a JSON object of handicap values by player ID; empty
+	// when nobody has one.
+	Handicaps string
+	// Abandoned is set when the match was finished because every player
+	// left, rather than played out.
+	Abandoned bool
+	// Previous is a JSON description of the session's last match, which
+	// may decide who moves first in the next; empty before a rematch.
+	Previous string
+	// RulesVersion is the version of the game's rules the match started
+	// under; empty while waiting.
+	RulesVersion string
+	// Pinned sessions are never cleaned up.
+	Pinned bool
+	// Practice sessions belong to one player and count toward nothing.
+	Practice bool
+	// Setup is the starting position the host set up, as its game
+	// documents it; empty for the usual start.
+	Setup string
+	// ExpireAfter is how long the session may sit idle before cleanup
+	// removes it, whatever its status; zero leaves it to the cleanup
+	// policy.
+	ExpireAfter time.Duration
+	// Private sessions are left out of public listings.
+	Private   bool
 	CreatedAt time.Time
+	DeletedAt time.Time // zero unless soft-deleted
+
+	// Activity times, zero until they happen.
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	LastActivity time.Time
 }
 
 // MatchStateRow represents serialized match state.
@@ -23,9 +63,369 @@ type MatchStateRow struct {
 	UpdatedAt   time.Time
 }
 
-// Store handles SQLite persistence.
+// InitialStateRow is a match's start position and what it was built from.
+type InitialStateRow struct {
+	StateJSON   string
+	Seed        int64
+	PlayersJSON string // JSON array of player IDs in seat order
+	Salt        string // salt of the seed's fairness commitment
+}
+
+// MoveRow represents one recorded action in a match's move log.
+type MoveRow struct {
+	SessionCode string
+	Seq         int
+	PlayerID    string
+	ActionJSON  string
+	// TimingJSON holds when the move was received and made, to the
+	// millisecond; empty for moves stored before it was kept.
+	TimingJSON string
+	CreatedAt  time.Time
+}
+
+// ExhibitionResultRow is one bot's archived result from a bot-vs-bot match.
+type ExhibitionResultRow struct {
+	SessionCode string
+	GameType    string
+	PlayerID    string
+	Strategy    string
+	Rank        int
+	Score       int
+	Outcome     string // "win", "loss", ...; empty for results archived before outcomes
+	FinishedAt  time.Time
+}
+
+// ArchivedMatchRow is the record of a finished match: when it was played
+// and how long it ran. Like exhibition results it outlives the session.
+type ArchivedMatchRow struct {
+	SessionCode string
+	GameType    string
+	Moves       int
+	Abandoned   bool
+	// Handicaps is a JSON object of the match's handicap values by player
+	// ID; empty for an even match.
+	Handicaps  string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// MatchStatsRow aggregates the archived matches of a game type that were
+// played out. Abandoned matches are left out of the averages.
+type MatchStatsRow struct {
+	Matches     int
+	AvgDuration time.Duration
+	AvgMoves    float64
+	Abandoned   int
+}
+
+// SeasonRow is the snapshot of the leaderboards taken when a season
+// ended. Leaderboards is a JSON document the session package defines.
+type SeasonRow struct {
+	Name         string
+	StartedAt    time.Time
+	EndedAt      time.Time
+	Leaderboards string
+}
+
+// BotRow represents a registered external bot. Only a hash of its API key
+// is stored.
+type BotRow struct {
+	ID         string
+	Name       string
+	KeyHash    string
+	WebhookURL string // empty for bots that connect over WebSocket
+	CreatedAt  time.Time
+}
+
+// ChallengeRow is a direct challenge from one player to another.
+type ChallengeRow struct {
+	ID           string
+	GameType     string
+	ChallengerID string
+	TargetID     string
+	Status       string // "pending", "accepted", "declined", "expired"
+	SessionCode  string // set once accepted
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// FriendshipRow is a friend request, or an accepted friendship.
+type FriendshipRow struct {
+	RequesterID string
+	AddresseeID string
+	Status      string // "pending", "accepted"
+	CreatedAt   time.Time
+}
+
+// OpponentRow is a player someone has played against, with their most
+// recent match together.
+type OpponentRow struct {
+	PlayerID    string
+	GameType    string
+	SessionCode string
+	PlayedAt    time.Time
+}
+
+// PushSubscriptionRow is a browser's Web Push subscription for a player.
+type PushSubscriptionRow struct {
+	Endpoint  string
+	PlayerID  string
+	P256dh    string
+	Auth      string
+	CreatedAt time.Time
+}
+
+// EmailContactRow is a player's email address and what they want to be
+// emailed about.
+type EmailContactRow struct {
+	PlayerID         string
+	Email            string
+	Verified         bool
+	VerifyToken      string // cleared once verified
+	NotifyTurns      bool
+	NotifyChallenges bool
+	CreatedAt        time.Time
+}
+
+// AuditRow is one recorded admin or moderation action.
+type AuditRow struct {
+	ID          int64
+	Actor       string // admin name, or "" if authentication failed
+	Action      string
+	SessionCode string
+	PlayerID    string
+	Detail      string
+	Status      int // HTTP status of the response
+	CreatedAt   time.Time
+}
+
+// AuditFilter narrows ListAudit. Zero fields match everything.
+type AuditFilter struct {
+	Actor       string
+	Action      string
+	SessionCode string
+	PlayerID    string
+	Since       time.Time
+	Limit       int
+}
+
+// TimelineRow is an entry in a session's timeline: a join, start, move or
+// finish, kept so a client coming back can see what it missed.
+type TimelineRow struct {
+	ID          int64 // increasing, across sessions
+	SessionCode string
+	Type        string
+	PlayerID    string // who did it, if anyone
+	Detail      string // JSON, or "" for none
+	CreatedAt   time.Time
+}
+
+// InboxRow is a message kept for a player who was away when it came, such
+// as their turn or a challenge. A newer message with the same topic
+// replaces it.
+type InboxRow struct {
+	ID        int64
+	PlayerID  string
+	Topic     string // such as your_turn/<session code>
+	EventJSON string
+	CreatedAt time.Time
+}
+
+// AchievementRow is an achievement a player unlocked, and the match they
+// unlocked it in.
+type AchievementRow struct {
+	PlayerID    string
+	Achievement string // such as first_win or tictactoe/flawless
+	SessionCode string
+	UnlockedAt  time.Time
+}
+
+// TournamentRow is a tournament: who entered, how it pairs them and how
+// far it has got.
+type TournamentRow struct {
+	ID        string
+	Name      string
+	GameType  string
+	Format    string // "swiss", "round_robin"
+	Rounds    int    // rounds to play; for Swiss, 0 until it starts if left to the player count
+	Round     int    // the round in play, 0 before the start
+	Status    string // "registering", "running", "finished"
+	Organizer string
+	Players   string // JSON array of player IDs, in the order they entered
+	Club      string // the club holding it, whose members alone may enter; empty for an open tournament
+	CreatedAt time.Time
+}
+
+// TournamentPairingRow is one game of a tournament round, or a bye.
+type TournamentPairingRow struct {
+	TournamentID string
+	Round        int
+	Board        int
+	PlayerA      string // moves first
+	PlayerB      string // empty for a bye
+	SessionCode  string // empty for a bye
+	Result       string // "" while in play, then "a", "b", "draw", "bye", or "none" if nobody won
+}
+
+// ClubRow is a club: players who hold private tournaments and share a
+// leaderboard.
+type ClubRow struct {
+	ID        string
+	Name      string
+	Owner     string
+	Members   int    // how many belong, owner included; filled in when read
+	Branding  string // JSON document the session package defines; empty for none
+	CreatedAt time.Time
+}
+
+// ClubMemberRow is a player's membership of a club.
+type ClubMemberRow struct {
+	ClubID   string
+	PlayerID string
+	Role     string // "owner", "admin", "member"
+	JoinedAt time.Time
+}
+
+// SurveyRow is a player's answer to the survey after a match.
+type SurveyRow struct {
+	ID          int64
+	SessionCode string
+	GameType    string
+	PlayerID    string
+	Fun         int    // 1 to 5, or 0 when skipped
+	Conduct     int    // how the opponents behaved, 1 to 5, or 0 when skipped
+	Comment     string // about the opponents
+	Bug         string // what went wrong; empty for no bug report
+	Transcript  string // JSON of the match's transcript, kept with bug reports
+	CreatedAt   time.Time
+}
+
+// SurveyFilter narrows ListSurveys. Zero fields match everything.
+type SurveyFilter struct {
+	GameType string
+	PlayerID string
+	Bugs     bool // only answers with a bug report
+	Limit    int
+}
+
+// ReportRow is a problem a player reported in a session, with the
+// diagnostics captured when they did.
+type ReportRow struct {
+	ID          int64
+	SessionCode string
+	GameType    string
+	PlayerID    string // who reported it
+	Note        string // what they said went wrong
+	Bundle      string // JSON of the diagnostics
+	CreatedAt   time.Time
+}
+
+// ReportFilter narrows ListReports. Zero fields match everything.
+type ReportFilter struct {
+	SessionCode string
+	GameType    string
+	Limit       int
+}
+
+// SizeRow is how much space the database takes and how many rows each
+// table holds.
+type SizeRow struct {
+	Bytes     int64 // file size, free pages included
+	FreeBytes int64 // reclaimable by Maintain
+	Rows      map[string]int64
+}
+
+// SessionSort orders ListSessions.
+type SessionSort string
+
+const (
+	SortNewest   SessionSort = "newest" // most recently created first; the default
+	SortOldest   SessionSort = "oldest"
+	SortActivity SessionSort = "activity" // most recently active first
+)
+
+// SessionFilter narrows ListSessions. Zero fields match everything.
+type SessionFilter struct {
+	GameType      string
+	Status        string
+	PublicOnly    bool      // leave out private sessions
+	PlayerID      string    // only sessions the player is seated in
+	Unfinished    bool      // leave out finished sessions
+	CreatedAfter  time.Time // inclusive
+	CreatedBefore time.Time // exclusive
+	Sort          SessionSort
+	Limit         int
+	Offset        int
+}
+
+// DefaultQueryTimeout bounds each query unless SetQueryTimeout says
+// otherwise.
+const DefaultQueryTimeout = 5 * time.Second
+
+// Store handles SQLite persistence. Every query is bounded by the context
+// it is given and by the store's query timeout, so a slow database fails
+// requests rather than piling them up.
 type Store struct {
-	db *sql.DB
+	db      *sql.DB
+	tx      *sql.Tx // set on the Store a WithTx callback is given
+	timeout time.Duration
+
+	queries   *metrics.Histogram
+	slowQuery time.Duration
+}
+
+// conn is what queries run on: the database, or an open transaction.
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *Store) conn() conn {
+	if s.tx != nil {
+		return timedConn{s.tx, s}
+	}
+	return timedConn{s.db, s}
+}
+
+// SetQueryTimeout changes how long a single query or transaction may run.
+// Zero or less leaves queries bounded only by their context.
+func (s *Store) SetQueryTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// withTimeout bounds a query by the store's query timeout.
+func (s *Store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// WithTx runs fn with a Store whose writes commit together once fn
+// returns nil, or are rolled back if it returns an error or ctx ends
+// first. Calls on s itself are not part of the transaction. Inside fn,
+// WithTx joins the transaction already open.
+func (s *Store) WithTx(ctx context.Context, fn func(tx Backend) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&Store{db: s.db, tx: tx, timeout: s.timeout, queries: s.queries, slowQuery: s.slowQuery}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// inTx runs fn in the open transaction, or in a new one of its own.
+func (s *Store) inTx(ctx context.Context, fn func(c conn) error) error {
+	return s.WithTx(ctx, func(tx Backend) error { return fn(tx.(*Store).conn()) })
 }
 
 // New opens (or creates) the database and runs migrations.
@@ -34,12 +434,27 @@ func New(path string) (*Store, error) {
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
+	if path == ":memory:" {
+		// Every connection to :memory: opens a database of its own, so a
+		// query made while a transaction holds one must wait for it.
+		db.SetMaxOpenConns(1)
+	}
 	// WAL mode for better concurrent reads
 	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("set WAL: %w", err)
 	}
-	s := &Store{db: db}
+	s := &Store{
+		db:      db,
+		timeout: DefaultQueryTimeout,
+		queries: metrics.NewHistogram("games_db_query_duration_seconds",
+			"Time SQLite queries take, by statement and table.", "query"),
+		slowQuery: DefaultSlowQuery,
+	}
+	if err := s.enableIncrementalVacuum(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("enable incremental vacuum: %w", err)
+	}
 	if err := s.migrate(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("migrate: %w", err)
@@ -60,86 +475,1928 @@ func (s *Store) migrate() error {
 			state_json   TEXT NOT NULL,
 			updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 		);
+		CREATE TABLE IF NOT EXISTS match_initial_state (
+			session_code TEXT PRIMARY KEY REFERENCES sessions(code),
+			state_json   TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS match_moves (
+			session_code TEXT NOT NULL REFERENCES sessions(code),
+			seq          INTEGER NOT NULL,
+			player_id    TEXT NOT NULL,
+			action_json  TEXT NOT NULL,
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (session_code, seq)
+		);
+		CREATE TABLE IF NOT EXISTS exhibition_results (
+			session_code TEXT NOT NULL,
+			game_type    TEXT NOT NULL,
+			player_id    TEXT NOT NULL,
+			strategy     TEXT NOT NULL,
+			rank         INTEGER NOT NULL,
+			score        INTEGER NOT NULL,
+			finished_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (session_code, player_id)
+		);
+		CREATE TABLE IF NOT EXISTS match_archive (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_code TEXT NOT NULL,
+			game_type    TEXT NOT NULL,
+			moves        INTEGER NOT NULL,
+			abandoned    INTEGER NOT NULL DEFAULT 0,
+			started_at   DATETIME NOT NULL,
+			finished_at  DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS match_archive_game ON match_archive(game_type, finished_at);
+		CREATE TABLE IF NOT EXISTS seasons (
+			name         TEXT PRIMARY KEY,
+			started_at   DATETIME NOT NULL,
+			ended_at     DATETIME NOT NULL,
+			leaderboards TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS external_bots (
+			id          TEXT PRIMARY KEY,
+			name        TEXT NOT NULL,
+			key_hash    TEXT NOT NULL UNIQUE,
+			webhook_url TEXT NOT NULL DEFAULT '',
+			created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS challenges (
+			id            TEXT PRIMARY KEY,
+			game_type     TEXT NOT NULL,
+			challenger_id TEXT NOT NULL,
+			target_id     TEXT NOT NULL,
+			status        TEXT NOT NULL DEFAULT 'pending',
+			session_code  TEXT NOT NULL DEFAULT '',
+			created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at    INTEGER NOT NULL -- unix seconds
+		);
+		CREATE TABLE IF NOT EXISTS friendships (
+			requester_id TEXT NOT NULL,
+			addressee_id TEXT NOT NULL,
+			status       TEXT NOT NULL DEFAULT 'pending',
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (requester_id, addressee_id)
+		);
+		CREATE TABLE IF NOT EXISTS match_players (
+			session_code TEXT NOT NULL,
+			game_type    TEXT NOT NULL,
+			player_id    TEXT NOT NULL,
+			started_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (session_code, player_id)
+		);
+		CREATE TABLE IF NOT EXISTS session_players (
+			session_code TEXT NOT NULL,
+			player_id    TEXT NOT NULL,
+			PRIMARY KEY (session_code, player_id)
+		);
+		CREATE INDEX IF NOT EXISTS session_players_player ON session_players(player_id);
+		CREATE TABLE IF NOT EXISTS push_subscriptions (
+			endpoint   TEXT PRIMARY KEY,
+			player_id  TEXT NOT NULL,
+			p256dh     TEXT NOT NULL,
+			auth       TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS push_subscriptions_player ON push_subscriptions(player_id);
+		CREATE TABLE IF NOT EXISTS email_contacts (
+			player_id         TEXT PRIMARY KEY,
+			email             TEXT NOT NULL,
+			verified          INTEGER NOT NULL DEFAULT 0,
+			verify_token      TEXT NOT NULL DEFAULT '',
+			notify_turns      INTEGER NOT NULL DEFAULT 1,
+			notify_challenges INTEGER NOT NULL DEFAULT 1,
+			created_at        DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			actor        TEXT NOT NULL,
+			action       TEXT NOT NULL,
+			session_code TEXT NOT NULL DEFAULT '',
+			player_id    TEXT NOT NULL DEFAULT '',
+			detail       TEXT NOT NULL DEFAULT '',
+			status       INTEGER NOT NULL,
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS session_timeline (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_code TEXT NOT NULL,
+			type         TEXT NOT NULL,
+			player_id    TEXT NOT NULL DEFAULT '',
+			detail       TEXT NOT NULL DEFAULT '',
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS session_timeline_session ON session_timeline(session_code, id);
+		CREATE TABLE IF NOT EXISTS inbox (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			player_id  TEXT NOT NULL,
+			topic      TEXT NOT NULL,
+			event      TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(player_id, topic)
+		);
+		CREATE TABLE IF NOT EXISTS achievements (
+			player_id    TEXT NOT NULL,
+			achievement  TEXT NOT NULL,
+			session_code TEXT NOT NULL,
+			unlocked_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (player_id, achievement)
+		);
+		CREATE TABLE IF NOT EXISTS player_progress (
+			player_id TEXT NOT NULL,
+			counter   TEXT NOT NULL,
+			value     INTEGER NOT NULL,
+			PRIMARY KEY (player_id, counter)
+		);
+		CREATE TABLE IF NOT EXISTS tournaments (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			game_type  TEXT NOT NULL,
+			format     TEXT NOT NULL,
+			rounds     INTEGER NOT NULL DEFAULT 0,
+			round      INTEGER NOT NULL DEFAULT 0,
+			status     TEXT NOT NULL DEFAULT 'registering',
+			organizer  TEXT NOT NULL,
+			players    TEXT NOT NULL DEFAULT '[]',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS tournament_pairings (
+			tournament_id TEXT NOT NULL,
+			round         INTEGER NOT NULL,
+			board         INTEGER NOT NULL,
+			player_a      TEXT NOT NULL,
+			player_b      TEXT NOT NULL DEFAULT '',
+			session_code  TEXT NOT NULL DEFAULT '',
+			result        TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (tournament_id, round, board)
+		);
+		CREATE INDEX IF NOT EXISTS tournament_pairings_session ON tournament_pairings(session_code);
+		CREATE TABLE IF NOT EXISTS clubs (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			owner      TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS club_members (
+			club_id   TEXT NOT NULL,
+			player_id TEXT NOT NULL,
+			role      TEXT NOT NULL DEFAULT 'member',
+			joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (club_id, player_id)
+		);
+		CREATE INDEX IF NOT EXISTS club_members_player ON club_members(player_id);
+		CREATE TABLE IF NOT EXISTS surveys (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_code TEXT NOT NULL,
+			game_type    TEXT NOT NULL,
+			player_id    TEXT NOT NULL,
+			fun          INTEGER NOT NULL DEFAULT 0,
+			conduct      INTEGER NOT NULL DEFAULT 0,
+			comment      TEXT NOT NULL DEFAULT '',
+			bug          TEXT NOT NULL DEFAULT '',
+			transcript   TEXT NOT NULL DEFAULT '',
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS reports (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_code TEXT NOT NULL,
+			game_type    TEXT NOT NULL,
+			player_id    TEXT NOT NULL,
+			note         TEXT NOT NULL,
+			bundle       TEXT NOT NULL,
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
 	`)
+	if err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "deleted_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "options", "TEXT NOT NULL DEFAULT '{}'"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "party", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("match_initial_state", "seed", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumn("match_initial_state", "players", "TEXT NOT NULL DEFAULT '[]'"); err != nil {
+		return err
+	}
+	if err := s.addColumn("match_initial_state", "salt", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	for _, column := range []string{"started_at", "finished_at", "last_activity"} {
+		if err := s.addColumn("sessions", column, "DATETIME"); err != nil {
+			return err
+		}
+	}
+	if err := s.addColumn("sessions", "turn_order", "TEXT NOT NULL DEFAULT 'join'"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "vote_thresholds", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "abandoned", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumn("exhibition_results", "outcome", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "previous", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "private", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumn("match_moves", "timing", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "rules_version", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "pinned", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "expire_after_seconds", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "handicaps", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("match_archive", "handicaps", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "practice", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumn("sessions", "setup", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("tournaments", "club", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.addColumn("clubs", "branding", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_sessions_created ON sessions(created_at)"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// addColumn adds a column to an existing table unless it is already there.
+func (s *Store) addColumn(table, column, definition string) error {
+	rows, err := s.db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = s.db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
 	return err
 }
 
 // CreateSession inserts a new session.
-func (s *Store) CreateSession(code, gameType string) error {
-	_, err := s.db.Exec(
+func (s *Store) CreateSession(ctx context.Context, code, gameType string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
 		"INSERT INTO sessions (code, game_type, status) VALUES (?, ?, 'waiting')",
 		code, gameType,
 	)
 	return err
 }
 
-// GetSession retrieves a session by code.
-func (s *Store) GetSession(code string) (*SessionRow, error) {
-	row := s.db.QueryRow("SELECT code, game_type, status, created_at FROM sessions WHERE code = ?", code)
-	var sr SessionRow
-	if err := row.Scan(&sr.Code, &sr.GameType, &sr.Status, &sr.CreatedAt); err != nil {
-		return nil, err
-	}
-	return &sr, nil
+// SetSessionOptions stores the game options a session was created with.
+func (s *Store) SetSessionOptions(ctx context.Context, code, optionsJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET options = ? WHERE code = ?", optionsJSON, code)
+	return err
 }
 
-// UpdateSessionStatus changes a session's status.
-func (s *Store) UpdateSessionStatus(code, status string) error {
-	_, err := s.db.Exec("UPDATE sessions SET status = ? WHERE code = ?", status, code)
+// SetSessionParty stores a party session's queue and standings.
+func (s *Store) SetSessionParty(ctx context.Context, code, partyJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET party = ? WHERE code = ?", partyJSON, code)
 	return err
 }
 
-// ListSessions returns all sessions with the given status (or all if status is empty).
-func (s *Store) ListSessions(status string) ([]SessionRow, error) {
-	var rows *sql.Rows
-	var err error
-	if status == "" {
-		rows, err = s.db.Query("SELECT code, game_type, status, created_at FROM sessions ORDER BY created_at DESC")
-	} else {
-		rows, err = s.db.Query("SELECT code, game_type, status, created_at FROM sessions WHERE status = ? ORDER BY created_at DESC", status)
-	}
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var result []SessionRow
-	for rows.Next() {
-		var sr SessionRow
-		if err := rows.Scan(&sr.Code, &sr.GameType, &sr.Status, &sr.CreatedAt); err != nil {
-			return nil, err
+// SetSessionTurnOrder stores how a session seats players who have not
+// chosen a seat.
+func (s *Store) SetSessionTurnOrder(ctx context.Context, code, turnOrder string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET turn_order = ? WHERE code = ?", turnOrder, code)
+	return err
+}
+
+// SetSessionVoteThresholds stores how many players must agree to skip or
+// remove a player in a session.
+func (s *Store) SetSessionVoteThresholds(ctx context.Context, code, thresholdsJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET vote_thresholds = ? WHERE code = ?", thresholdsJSON, code)
+	return err
+}
+
+// SetSessionHandicaps stores the handicaps the host gave players in a
+// session.
+func (s *Store) SetSessionHandicaps(ctx context.Context, code, handicapsJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET handicaps = ? WHERE code = ?", handicapsJSON, code)
+	return err
+}
+
+// SetSessionPrivate records whether a session is left out of public
+// listings.
+func (s *Store) SetSessionPrivate(ctx context.Context, code string, private bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET private = ? WHERE code = ?", private, code)
+	return err
+}
+
+// SetSessionRulesVersion records the version of the game's rules a
+// session's match started under.
+func (s *Store) SetSessionRulesVersion(ctx context.Context, code, version string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET rules_version = ? WHERE code = ?", version, code)
+	return err
+}
+
+// SetSessionPlayers records who is seated in a session, replacing who was,
+// so a player's sessions can be listed without loading every session.
+func (s *Store) SetSessionPlayers(ctx context.Context, code string, playerIDs []string) error {
+	return s.inTx(ctx, func(tx conn) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM session_players WHERE session_code = ?", code); err != nil {
+			return err
 		}
-		result = append(result, sr)
-	}
-	return result, rows.Err()
+		for _, id := range playerIDs {
+			if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO session_players (session_code, player_id) VALUES (?, ?)", code, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-// SaveMatchState upserts match state JSON.
-func (s *Store) SaveMatchState(sessionCode, stateJSON string) error {
-	_, err := s.db.Exec(`
-		INSERT INTO match_state (session_code, state_json, updated_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(session_code) DO UPDATE SET state_json = excluded.state_json, updated_at = excluded.updated_at
-	`, sessionCode, stateJSON)
+// SetSessionPinned records whether a session is kept from cleanup.
+func (s *Store) SetSessionPinned(ctx context.Context, code string, pinned bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET pinned = ? WHERE code = ?", pinned, code)
 	return err
 }
 
-// GetMatchState retrieves match state JSON.
-func (s *Store) GetMatchState(sessionCode string) (string, error) {
-	var stateJSON string
-	err := s.db.QueryRow("SELECT state_json FROM match_state WHERE session_code = ?", sessionCode).Scan(&stateJSON)
-	return stateJSON, err
+// SetSessionPractice records whether a session is a practice session.
+func (s *Store) SetSessionPractice(ctx context.Context, code string, practice bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET practice = ? WHERE code = ?", practice, code)
+	return err
 }
 
-// DeleteSession removes a session and its match state.
-func (s *Store) DeleteSession(code string) error {
-	_, err := s.db.Exec("DELETE FROM match_state WHERE session_code = ?", code)
-	if err != nil {
-		return err
-	}
-	_, err = s.db.Exec("DELETE FROM sessions WHERE code = ?", code)
+// SetSessionSetup stores the starting position the host set up in a
+// session.
+func (s *Store) SetSessionSetup(ctx context.Context, code, setupJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET setup = ? WHERE code = ?", setupJSON, code)
+	return err
+}
+
+// SetSessionExpireAfter records how long a session may sit idle before
+// cleanup removes it, to the second; zero leaves it to the cleanup policy.
+func (s *Store) SetSessionExpireAfter(ctx context.Context, code string, after time.Duration) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET expire_after_seconds = ? WHERE code = ?", int64(after/time.Second), code)
+	return err
+}
+
+// SetSessionAbandoned records whether a session's match was abandoned.
+func (s *Store) SetSessionAbandoned(ctx context.Context, code string, abandoned bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET abandoned = ? WHERE code = ?", abandoned, code)
 	return err
 }
 
+// NextRound sets a finished session up for its next match, a party's next
+// game or a rematch: it records the game type, options, party state and
+// the match just played, puts the session back in the waiting state, and
+// clears the previous game's match state and moves.
+func (s *Store) NextRound(ctx context.Context, code, gameType, optionsJSON, partyJSON, previousJSON string) error {
+	return s.inTx(ctx, func(tx conn) error {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE sessions SET game_type = ?, options = ?, party = ?, previous = ?, status = 'waiting', abandoned = 0, rules_version = '' WHERE code = ?",
+			gameType, optionsJSON, partyJSON, previousJSON, code,
+		); err != nil {
+			return err
+		}
+		for _, table := range []string{"match_moves", "match_initial_state", "match_state", "session_players"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE session_code = ?", code); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetSession retrieves a session by code.
+func (s *Store) GetSession(ctx context.Context, code string) (*SessionRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	row := s.conn().QueryRowContext(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE code = ? AND deleted_at IS NULL", code)
+	return scanSession(row)
+}
+
+// sessionColumns are the columns scanSession reads, in order.
+const sessionColumns = "code, game_type, status, options, party, turn_order, vote_thresholds, handicaps, abandoned, previous, private, practice, setup, rules_version, pinned, expire_after_seconds, created_at, deleted_at, started_at, finished_at, last_activity"
+
+// scanSession reads a row selected with sessionColumns.
+func scanSession(row interface{ Scan(...any) error }) (*SessionRow, error) {
+	var sr SessionRow
+	var deleted, started, finished, active sql.NullTime
+	var expireAfter int64
+	if err := row.Scan(&sr.Code, &sr.GameType, &sr.Status, &sr.Options, &sr.Party, &sr.TurnOrder, &sr.VoteThresholds, &sr.Handicaps, &sr.Abandoned, &sr.Previous, &sr.Private, &sr.Practice, &sr.Setup, &sr.RulesVersion,
+		&sr.Pinned, &expireAfter, &sr.CreatedAt, &deleted, &started, &finished, &active); err != nil {
+		return nil, err
+	}
+	sr.ExpireAfter = time.Duration(expireAfter) * time.Second
+	sr.DeletedAt, sr.StartedAt, sr.FinishedAt, sr.LastActivity = deleted.Time, started.Time, finished.Time, active.Time
+	return &sr, nil
+}
+
+// SetSessionTimes records when a session's match started and finished and
+// when anything last happened in it. Zero times are stored as NULL.
+func (s *Store) SetSessionTimes(ctx context.Context, code string, startedAt, finishedAt, lastActivity time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"UPDATE sessions SET started_at = ?, finished_at = ?, last_activity = ? WHERE code = ?",
+		nullTime(startedAt), nullTime(finishedAt), nullTime(lastActivity), code,
+	)
+	return err
+}
+
+// nullTime formats t for a DATETIME column, or NULL for the zero time.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.DateTime)
+}
+
+// UpdateSessionStatus changes a session's status.
+func (s *Store) UpdateSessionStatus(ctx context.Context, code, status string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET status = ? WHERE code = ?", status, code)
+	return err
+}
+
+// ListSessions returns the sessions matching f, newest first unless f
+// says otherwise. Soft-deleted sessions are left out.
+func (s *Store) ListSessions(ctx context.Context, f SessionFilter) ([]SessionRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := "SELECT " + sessionColumns + " FROM sessions WHERE deleted_at IS NULL"
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"game_type", f.GameType}, {"status", f.Status},
+	} {
+		if c.value != "" {
+			query += " AND " + c.column + " = ?"
+			args = append(args, c.value)
+		}
+	}
+	if f.PublicOnly {
+		query += " AND private = 0"
+	}
+	if f.PlayerID != "" {
+		query += " AND code IN (SELECT session_code FROM session_players WHERE player_id = ?)"
+		args = append(args, f.PlayerID)
+	}
+	if f.Unfinished {
+		query += " AND status != 'finished'"
+	}
+	if !f.CreatedAfter.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, f.CreatedAfter.UTC().Format(time.DateTime))
+	}
+	if !f.CreatedBefore.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, f.CreatedBefore.UTC().Format(time.DateTime))
+	}
+	switch f.Sort {
+	case SortOldest:
+		query += " ORDER BY created_at, rowid"
+	case SortActivity:
+		query += " ORDER BY COALESCE(last_activity, created_at) DESC, rowid DESC"
+	default:
+		query += " ORDER BY created_at DESC, rowid DESC"
+	}
+	if f.Limit > 0 || f.Offset > 0 {
+		limit := f.Limit
+		if limit <= 0 {
+			limit = -1 // SQLite's "no limit"
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, f.Offset)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []SessionRow
+	for rows.Next() {
+		sr, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *sr)
+	}
+	return result, rows.Err()
+}
+
+// SaveMatchState upserts match state JSON.
+func (s *Store) SaveMatchState(ctx context.Context, sessionCode, stateJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, `
+		INSERT INTO match_state (session_code, state_json, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(session_code) DO UPDATE SET state_json = excluded.state_json, updated_at = excluded.updated_at
+	`, sessionCode, stateJSON)
+	return err
+}
+
+// notDeleted restricts a query on a session's child rows to sessions that
+// have not been soft-deleted.
+const notDeleted = "session_code NOT IN (SELECT code FROM sessions WHERE deleted_at IS NOT NULL)"
+
+// GetMatchState retrieves match state JSON.
+func (s *Store) GetMatchState(ctx context.Context, sessionCode string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var stateJSON string
+	err := s.conn().QueryRowContext(ctx, "SELECT state_json FROM match_state WHERE session_code = ? AND "+notDeleted, sessionCode).Scan(&stateJSON)
+	return stateJSON, err
+}
+
+// SaveInitialState stores the match state as it was when play started,
+// with the seed and seating the match was created from.
+func (s *Store) SaveInitialState(ctx context.Context, sessionCode string, row InitialStateRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, `
+		INSERT INTO match_initial_state (session_code, state_json, seed, players, salt) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(session_code) DO UPDATE SET
+			state_json = excluded.state_json, seed = excluded.seed, players = excluded.players, salt = excluded.salt
+	`, sessionCode, row.StateJSON, row.Seed, row.PlayersJSON, row.Salt)
+	return err
+}
+
+// GetInitialState retrieves the match state as it was when play started.
+func (s *Store) GetInitialState(ctx context.Context, sessionCode string) (*InitialStateRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var row InitialStateRow
+	err := s.conn().QueryRowContext(ctx, "SELECT state_json, seed, players, salt FROM match_initial_state WHERE session_code = ? AND "+notDeleted, sessionCode).Scan(&row.StateJSON, &row.Seed, &row.PlayersJSON, &row.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// AppendMove records an applied action. Seq starts at 1 for the first move.
+func (s *Store) AppendMove(ctx context.Context, sessionCode string, seq int, playerID, actionJSON, timingJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO match_moves (session_code, seq, player_id, action_json, timing) VALUES (?, ?, ?, ?, ?)",
+		sessionCode, seq, playerID, actionJSON, timingJSON,
+	)
+	return err
+}
+
+// TruncateMoves deletes a session's moves after the first n, as when
+// they are taken back.
+func (s *Store) TruncateMoves(ctx context.Context, sessionCode string, n int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "DELETE FROM match_moves WHERE session_code = ? AND seq > ?", sessionCode, n)
+	return err
+}
+
+// ListMoves returns a session's move log in order.
+func (s *Store) ListMoves(ctx context.Context, sessionCode string) ([]MoveRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT session_code, seq, player_id, action_json, timing, created_at FROM match_moves WHERE session_code = ? AND "+notDeleted+" ORDER BY seq",
+		sessionCode,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []MoveRow
+	for rows.Next() {
+		var mr MoveRow
+		if err := rows.Scan(&mr.SessionCode, &mr.Seq, &mr.PlayerID, &mr.ActionJSON, &mr.TimingJSON, &mr.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, mr)
+	}
+	return result, rows.Err()
+}
+
+// ArchiveExhibitionResults records the per-bot results of a finished
+// bot-vs-bot match. Archived results outlive the session itself.
+func (s *Store) ArchiveExhibitionResults(ctx context.Context, results []ExhibitionResultRow) error {
+	return s.inTx(ctx, func(tx conn) error {
+		for _, r := range results {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO exhibition_results (session_code, game_type, player_id, strategy, rank, score, outcome) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				r.SessionCode, r.GameType, r.PlayerID, r.Strategy, r.Rank, r.Score, r.Outcome,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListExhibitionResults returns archived exhibition results for a game type,
+// newest first.
+func (s *Store) ListExhibitionResults(ctx context.Context, gameType string) ([]ExhibitionResultRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT session_code, game_type, player_id, strategy, rank, score, outcome, finished_at
+		FROM exhibition_results WHERE game_type = ? ORDER BY finished_at DESC, session_code
+	`, gameType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []ExhibitionResultRow
+	for rows.Next() {
+		var r ExhibitionResultRow
+		if err := rows.Scan(&r.SessionCode, &r.GameType, &r.PlayerID, &r.Strategy, &r.Rank, &r.Score, &r.Outcome, &r.FinishedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// ArchiveMatch records a finished match. A party session archives one row
+// per round.
+func (s *Store) ArchiveMatch(ctx context.Context, m ArchivedMatchRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO match_archive (session_code, game_type, moves, abandoned, handicaps, started_at, finished_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		m.SessionCode, m.GameType, m.Moves, m.Abandoned, m.Handicaps, nullTime(m.StartedAt), nullTime(m.FinishedAt),
+	)
+	return err
+}
+
+// ListArchivedMatches returns up to limit archived matches of a game type,
+// most recently finished first.
+func (s *Store) ListArchivedMatches(ctx context.Context, gameType string, limit int) ([]ArchivedMatchRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT session_code, game_type, moves, abandoned, handicaps, started_at, finished_at
+		FROM match_archive WHERE game_type = ? ORDER BY finished_at DESC, id DESC LIMIT ?
+	`, gameType, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []ArchivedMatchRow
+	for rows.Next() {
+		var m ArchivedMatchRow
+		if err := rows.Scan(&m.SessionCode, &m.GameType, &m.Moves, &m.Abandoned, &m.Handicaps, &m.StartedAt, &m.FinishedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// MatchStats aggregates the archived matches of a game type that finished
+// from from up to but not including to. A zero time leaves that end open.
+func (s *Store) MatchStats(ctx context.Context, gameType string, from, to time.Time) (MatchStatsRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE abandoned = 0),
+			AVG((julianday(finished_at) - julianday(started_at)) * 86400) FILTER (WHERE abandoned = 0),
+			AVG(moves) FILTER (WHERE abandoned = 0),
+			COUNT(*) FILTER (WHERE abandoned = 1)
+		FROM match_archive WHERE game_type = ?`
+	args := []any{gameType}
+	if !from.IsZero() {
+		query += " AND julianday(finished_at) >= julianday(?)"
+		args = append(args, from.UTC().Format(time.DateTime))
+	}
+	if !to.IsZero() {
+		query += " AND julianday(finished_at) < julianday(?)"
+		args = append(args, to.UTC().Format(time.DateTime))
+	}
+	var st MatchStatsRow
+	var seconds, moves sql.NullFloat64
+	err := s.conn().QueryRowContext(ctx, query, args...).Scan(&st.Matches, &seconds, &moves, &st.Abandoned)
+	if err != nil {
+		return st, err
+	}
+	st.AvgDuration = time.Duration(seconds.Float64 * float64(time.Second)).Round(time.Second)
+	st.AvgMoves = moves.Float64
+	return st, nil
+}
+
+// SaveSeason records the snapshot of a season that ended. A season is
+// snapshotted once; saving it again keeps the first snapshot.
+func (s *Store) SaveSeason(ctx context.Context, season SeasonRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT OR IGNORE INTO seasons (name, started_at, ended_at, leaderboards) VALUES (?, ?, ?, ?)",
+		season.Name, season.StartedAt.UTC(), season.EndedAt.UTC(), season.Leaderboards,
+	)
+	return err
+}
+
+// GetSeason returns the snapshot of a season, or sql.ErrNoRows if it has
+// none.
+func (s *Store) GetSeason(ctx context.Context, name string) (*SeasonRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var r SeasonRow
+	err := s.conn().QueryRowContext(ctx,
+		"SELECT name, started_at, ended_at, leaderboards FROM seasons WHERE name = ?", name,
+	).Scan(&r.Name, &r.StartedAt, &r.EndedAt, &r.Leaderboards)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListSeasons returns every snapshotted season, most recent first.
+func (s *Store) ListSeasons(ctx context.Context) ([]SeasonRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT name, started_at, ended_at, leaderboards FROM seasons ORDER BY started_at DESC, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []SeasonRow
+	for rows.Next() {
+		var r SeasonRow
+		if err := rows.Scan(&r.Name, &r.StartedAt, &r.EndedAt, &r.Leaderboards); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// CreateBot registers an external bot.
+func (s *Store) CreateBot(ctx context.Context, id, name, keyHash, webhookURL string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO external_bots (id, name, key_hash, webhook_url) VALUES (?, ?, ?, ?)",
+		id, name, keyHash, webhookURL,
+	)
+	return err
+}
+
+// GetBotByKeyHash looks up an external bot by the hash of its API key.
+func (s *Store) GetBotByKeyHash(ctx context.Context, keyHash string) (*BotRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	row := s.conn().QueryRowContext(ctx, "SELECT id, name, key_hash, webhook_url, created_at FROM external_bots WHERE key_hash = ?", keyHash)
+	var b BotRow
+	if err := row.Scan(&b.ID, &b.Name, &b.KeyHash, &b.WebhookURL, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// CreateChallenge inserts a pending challenge.
+func (s *Store) CreateChallenge(ctx context.Context, c ChallengeRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO challenges (id, game_type, challenger_id, target_id, expires_at) VALUES (?, ?, ?, ?, ?)",
+		c.ID, c.GameType, c.ChallengerID, c.TargetID, c.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+const challengeColumns = "id, game_type, challenger_id, target_id, status, session_code, created_at, expires_at"
+
+func scanChallenge(row interface{ Scan(...any) error }) (*ChallengeRow, error) {
+	var c ChallengeRow
+	var expires int64
+	if err := row.Scan(&c.ID, &c.GameType, &c.ChallengerID, &c.TargetID, &c.Status, &c.SessionCode, &c.CreatedAt, &expires); err != nil {
+		return nil, err
+	}
+	c.ExpiresAt = time.Unix(expires, 0)
+	return &c, nil
+}
+
+// GetChallenge retrieves a challenge by ID.
+func (s *Store) GetChallenge(ctx context.Context, id string) (*ChallengeRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return scanChallenge(s.conn().QueryRowContext(ctx, "SELECT "+challengeColumns+" FROM challenges WHERE id = ?", id))
+}
+
+// ListPendingChallenges returns unexpired pending challenges sent or
+// received by playerID, oldest first.
+func (s *Store) ListPendingChallenges(ctx context.Context, playerID string, now time.Time) ([]ChallengeRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT "+challengeColumns+" FROM challenges WHERE status = 'pending' AND expires_at > ? AND (challenger_id = ? OR target_id = ?) ORDER BY created_at, id",
+		now.Unix(), playerID, playerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ChallengeRow
+	for rows.Next() {
+		c, err := scanChallenge(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *c)
+	}
+	return result, rows.Err()
+}
+
+// ResolveChallenge moves a pending challenge to status. It reports false if
+// the challenge was no longer pending, so only one caller can resolve it.
+func (s *Store) ResolveChallenge(ctx context.Context, id, status, sessionCode string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"UPDATE challenges SET status = ?, session_code = ? WHERE id = ? AND status = 'pending'",
+		status, sessionCode, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ExpireChallenges marks pending challenges past their expiry as expired
+// and returns how many were.
+func (s *Store) ExpireChallenges(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx, "UPDATE challenges SET status = 'expired' WHERE status = 'pending' AND expires_at <= ?", now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CreateFriendRequest records a pending friend request.
+func (s *Store) CreateFriendRequest(ctx context.Context, requesterID, addresseeID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO friendships (requester_id, addressee_id) VALUES (?, ?)",
+		requesterID, addresseeID,
+	)
+	return err
+}
+
+// GetFriendship returns the friendship or request between two players, in
+// either direction.
+func (s *Store) GetFriendship(ctx context.Context, a, b string) (*FriendshipRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	row := s.conn().QueryRowContext(ctx,
+		`SELECT requester_id, addressee_id, status, created_at FROM friendships
+		 WHERE (requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)`,
+		a, b, b, a,
+	)
+	var f FriendshipRow
+	if err := row.Scan(&f.RequesterID, &f.AddresseeID, &f.Status, &f.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// AcceptFriendRequest marks a pending request as accepted. It reports false
+// if there was no such pending request.
+func (s *Store) AcceptFriendRequest(ctx context.Context, requesterID, addresseeID string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"UPDATE friendships SET status = 'accepted' WHERE requester_id = ? AND addressee_id = ? AND status = 'pending'",
+		requesterID, addresseeID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteFriendship removes the friendship or request between two players.
+// It reports false if there was none.
+func (s *Store) DeleteFriendship(ctx context.Context, a, b string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"DELETE FROM friendships WHERE (requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)",
+		a, b, b, a,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListFriendships returns every friendship and request involving playerID.
+func (s *Store) ListFriendships(ctx context.Context, playerID string) ([]FriendshipRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT requester_id, addressee_id, status, created_at FROM friendships WHERE requester_id = ? OR addressee_id = ? ORDER BY created_at",
+		playerID, playerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []FriendshipRow
+	for rows.Next() {
+		var f FriendshipRow
+		if err := rows.Scan(&f.RequesterID, &f.AddresseeID, &f.Status, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
+
+// RecordMatchPlayers remembers who took part in a match. Unlike the rest
+// of a session's data it is kept after the session is cleaned up.
+func (s *Store) RecordMatchPlayers(ctx context.Context, sessionCode, gameType string, playerIDs []string) error {
+	return s.inTx(ctx, func(tx conn) error {
+		for _, id := range playerIDs {
+			if _, err := tx.ExecContext(ctx,
+				"INSERT OR IGNORE INTO match_players (session_code, game_type, player_id) VALUES (?, ?, ?)",
+				sessionCode, gameType, id,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListRecentOpponents returns the players playerID has shared a match with,
+// most recent first, each with their latest match together.
+func (s *Store) ListRecentOpponents(ctx context.Context, playerID string, limit int) ([]OpponentRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT other.player_id, other.game_type, other.session_code, MAX(other.started_at)
+		FROM match_players me
+		JOIN match_players other ON other.session_code = me.session_code AND other.player_id != me.player_id
+		WHERE me.player_id = ?
+		GROUP BY other.player_id
+		ORDER BY MAX(other.started_at) DESC, other.player_id
+		LIMIT ?`,
+		playerID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []OpponentRow
+	for rows.Next() {
+		var o OpponentRow
+		var playedAt string
+		if err := rows.Scan(&o.PlayerID, &o.GameType, &o.SessionCode, &playedAt); err != nil {
+			return nil, err
+		}
+		o.PlayedAt, _ = time.Parse(time.DateTime, playedAt)
+		result = append(result, o)
+	}
+	return result, rows.Err()
+}
+
+// SavePushSubscription stores a subscription, moving it to sub.PlayerID if
+// the browser was subscribed under another player.
+func (s *Store) SavePushSubscription(ctx context.Context, sub PushSubscriptionRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		`INSERT INTO push_subscriptions (endpoint, player_id, p256dh, auth) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(endpoint) DO UPDATE SET player_id = excluded.player_id, p256dh = excluded.p256dh, auth = excluded.auth`,
+		sub.Endpoint, sub.PlayerID, sub.P256dh, sub.Auth,
+	)
+	return err
+}
+
+// ListPushSubscriptions returns a player's push subscriptions.
+func (s *Store) ListPushSubscriptions(ctx context.Context, playerID string) ([]PushSubscriptionRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT endpoint, player_id, p256dh, auth, created_at FROM push_subscriptions WHERE player_id = ?",
+		playerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []PushSubscriptionRow
+	for rows.Next() {
+		var r PushSubscriptionRow
+		if err := rows.Scan(&r.Endpoint, &r.PlayerID, &r.P256dh, &r.Auth, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// DeletePushSubscription removes a subscription by endpoint.
+func (s *Store) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "DELETE FROM push_subscriptions WHERE endpoint = ?", endpoint)
+	return err
+}
+
+// SaveEmailContact sets a player's email address as unverified, pending
+// the given verification token. Notification preferences are kept.
+func (s *Store) SaveEmailContact(ctx context.Context, playerID, email, verifyToken string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		`INSERT INTO email_contacts (player_id, email, verify_token) VALUES (?, ?, ?)
+		 ON CONFLICT(player_id) DO UPDATE SET email = excluded.email, verified = 0, verify_token = excluded.verify_token`,
+		playerID, email, verifyToken,
+	)
+	return err
+}
+
+// GetEmailContact returns a player's email contact.
+func (s *Store) GetEmailContact(ctx context.Context, playerID string) (*EmailContactRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	row := s.conn().QueryRowContext(ctx,
+		"SELECT player_id, email, verified, verify_token, notify_turns, notify_challenges, created_at FROM email_contacts WHERE player_id = ?",
+		playerID,
+	)
+	var c EmailContactRow
+	if err := row.Scan(&c.PlayerID, &c.Email, &c.Verified, &c.VerifyToken, &c.NotifyTurns, &c.NotifyChallenges, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// VerifyEmail marks the address holding verifyToken as verified and returns
+// its player ID.
+func (s *Store) VerifyEmail(ctx context.Context, verifyToken string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var playerID string
+	err := s.conn().QueryRowContext(ctx,
+		"UPDATE email_contacts SET verified = 1, verify_token = '' WHERE verify_token = ? AND verify_token != '' RETURNING player_id",
+		verifyToken,
+	).Scan(&playerID)
+	return playerID, err
+}
+
+// UpdateEmailPreferences sets what a player wants to be emailed about. It
+// reports false if the player has no email contact.
+func (s *Store) UpdateEmailPreferences(ctx context.Context, playerID string, turns, challenges bool) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"UPDATE email_contacts SET notify_turns = ?, notify_challenges = ? WHERE player_id = ?",
+		turns, challenges, playerID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// AppendAudit records an admin action. The audit log cannot be updated or
+// deleted from.
+func (s *Store) AppendAudit(ctx context.Context, a AuditRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO audit_log (actor, action, session_code, player_id, detail, status) VALUES (?, ?, ?, ?, ?, ?)",
+		a.Actor, a.Action, a.SessionCode, a.PlayerID, a.Detail, a.Status,
+	)
+	return err
+}
+
+// ListAudit returns audit entries matching f, newest first.
+func (s *Store) ListAudit(ctx context.Context, f AuditFilter) ([]AuditRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := "SELECT id, actor, action, session_code, player_id, detail, status, created_at FROM audit_log WHERE 1=1"
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"actor", f.Actor}, {"action", f.Action}, {"session_code", f.SessionCode}, {"player_id", f.PlayerID},
+	} {
+		if c.value != "" {
+			query += " AND " + c.column + " = ?"
+			args = append(args, c.value)
+		}
+	}
+	if !f.Since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, f.Since.UTC().Format(time.DateTime))
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []AuditRow
+	for rows.Next() {
+		var a AuditRow
+		if err := rows.Scan(&a.ID, &a.Actor, &a.Action, &a.SessionCode, &a.PlayerID, &a.Detail, &a.Status, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// AppendTimeline adds an entry to the end of a session's timeline.
+func (s *Store) AppendTimeline(ctx context.Context, t TimelineRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO session_timeline (session_code, type, player_id, detail) VALUES (?, ?, ?, ?)",
+		t.SessionCode, t.Type, t.PlayerID, t.Detail,
+	)
+	return err
+}
+
+// ListTimeline returns up to limit entries of a session's timeline after
+// the one with ID afterID, oldest first. Zero limit means no limit.
+func (s *Store) ListTimeline(ctx context.Context, sessionCode string, afterID int64, limit int) ([]TimelineRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := "SELECT id, session_code, type, player_id, detail, created_at FROM session_timeline WHERE session_code = ? AND id > ? ORDER BY id"
+	args := []any{sessionCode, afterID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []TimelineRow
+	for rows.Next() {
+		var t TimelineRow
+		if err := rows.Scan(&t.ID, &t.SessionCode, &t.Type, &t.PlayerID, &t.Detail, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// AddToInbox keeps a message for a player, replacing any they have not
+// read with the same topic.
+func (s *Store) AddToInbox(ctx context.Context, m InboxRow) error {
+	return s.inTx(ctx, func(tx conn) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM inbox WHERE player_id = ? AND topic = ?", m.PlayerID, m.Topic); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO inbox (player_id, topic, event) VALUES (?, ?, ?)", m.PlayerID, m.Topic, m.EventJSON)
+		return err
+	})
+}
+
+// ListInbox returns a player's messages, oldest first.
+func (s *Store) ListInbox(ctx context.Context, playerID string) ([]InboxRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT id, player_id, topic, event, created_at FROM inbox WHERE player_id = ? ORDER BY id", playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []InboxRow
+	for rows.Next() {
+		var m InboxRow
+		if err := rows.Scan(&m.ID, &m.PlayerID, &m.Topic, &m.EventJSON, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// ClearInbox removes a player's messages up to the one with ID through,
+// leaving any kept since they were read.
+func (s *Store) ClearInbox(ctx context.Context, playerID string, through int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "DELETE FROM inbox WHERE player_id = ? AND id <= ?", playerID, through)
+	return err
+}
+
+// UnlockAchievement records that a player unlocked an achievement,
+// reporting false if they had already.
+func (s *Store) UnlockAchievement(ctx context.Context, a AchievementRow) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"INSERT OR IGNORE INTO achievements (player_id, achievement, session_code) VALUES (?, ?, ?)",
+		a.PlayerID, a.Achievement, a.SessionCode,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListAchievements returns the achievements a player unlocked, oldest
+// first.
+func (s *Store) ListAchievements(ctx context.Context, playerID string) ([]AchievementRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT player_id, achievement, session_code, unlocked_at FROM achievements WHERE player_id = ? ORDER BY unlocked_at, rowid",
+		playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []AchievementRow
+	for rows.Next() {
+		var a AchievementRow
+		if err := rows.Scan(&a.PlayerID, &a.Achievement, &a.SessionCode, &a.UnlockedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// IncrementProgress adds one to a player's progress counter towards an
+// achievement, such as their current win streak, and returns the new
+// count.
+func (s *Store) IncrementProgress(ctx context.Context, playerID, counter string) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var value int
+	err := s.conn().QueryRowContext(ctx, `
+		INSERT INTO player_progress (player_id, counter, value) VALUES (?, ?, 1)
+		ON CONFLICT (player_id, counter) DO UPDATE SET value = value + 1
+		RETURNING value
+	`, playerID, counter).Scan(&value)
+	return value, err
+}
+
+// ResetProgress sets a player's progress counter back to zero.
+func (s *Store) ResetProgress(ctx context.Context, playerID, counter string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"DELETE FROM player_progress WHERE player_id = ? AND counter = ?", playerID, counter)
+	return err
+}
+
+// CreateTournament inserts a tournament open for registration.
+func (s *Store) CreateTournament(ctx context.Context, t TournamentRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO tournaments (id, name, game_type, format, rounds, organizer, players, club) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		t.ID, t.Name, t.GameType, t.Format, t.Rounds, t.Organizer, t.Players, t.Club,
+	)
+	return err
+}
+
+const tournamentColumns = "id, name, game_type, format, rounds, round, status, organizer, players, club, created_at"
+
+func scanTournament(row interface{ Scan(...any) error }) (*TournamentRow, error) {
+	var t TournamentRow
+	if err := row.Scan(&t.ID, &t.Name, &t.GameType, &t.Format, &t.Rounds, &t.Round, &t.Status, &t.Organizer, &t.Players, &t.Club, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTournament retrieves a tournament by ID.
+func (s *Store) GetTournament(ctx context.Context, id string) (*TournamentRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return scanTournament(s.conn().QueryRowContext(ctx, "SELECT "+tournamentColumns+" FROM tournaments WHERE id = ?", id))
+}
+
+// ListTournaments returns the tournaments a club holds, or the open ones
+// for club "", newest first.
+func (s *Store) ListTournaments(ctx context.Context, club string) ([]TournamentRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+tournamentColumns+" FROM tournaments WHERE club = ? ORDER BY created_at DESC, rowid DESC", club)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []TournamentRow
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *t)
+	}
+	return result, rows.Err()
+}
+
+// UpdateTournament saves a tournament's players, rounds and progress.
+func (s *Store) UpdateTournament(ctx context.Context, t TournamentRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"UPDATE tournaments SET rounds = ?, round = ?, status = ?, players = ? WHERE id = ?",
+		t.Rounds, t.Round, t.Status, t.Players, t.ID,
+	)
+	return err
+}
+
+// AddPairings records the pairings of a tournament round.
+func (s *Store) AddPairings(ctx context.Context, pairings []TournamentPairingRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	for _, p := range pairings {
+		_, err := s.conn().ExecContext(ctx,
+			"INSERT INTO tournament_pairings (tournament_id, round, board, player_a, player_b, session_code, result) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			p.TournamentID, p.Round, p.Board, p.PlayerA, p.PlayerB, p.SessionCode, p.Result,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const pairingColumns = "tournament_id, round, board, player_a, player_b, session_code, result"
+
+func scanPairing(row interface{ Scan(...any) error }) (*TournamentPairingRow, error) {
+	var p TournamentPairingRow
+	if err := row.Scan(&p.TournamentID, &p.Round, &p.Board, &p.PlayerA, &p.PlayerB, &p.SessionCode, &p.Result); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPairings returns a tournament's pairings by round and board.
+func (s *Store) ListPairings(ctx context.Context, tournamentID string) ([]TournamentPairingRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT "+pairingColumns+" FROM tournament_pairings WHERE tournament_id = ? ORDER BY round, board", tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []TournamentPairingRow
+	for rows.Next() {
+		p, err := scanPairing(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *p)
+	}
+	return result, rows.Err()
+}
+
+// GetPairingBySession returns the tournament game played in a session, or
+// sql.ErrNoRows if it is not one.
+func (s *Store) GetPairingBySession(ctx context.Context, sessionCode string) (*TournamentPairingRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return scanPairing(s.conn().QueryRowContext(ctx,
+		"SELECT "+pairingColumns+" FROM tournament_pairings WHERE session_code = ? AND session_code != ''", sessionCode))
+}
+
+// SetPairingResult records the result of the tournament game played in a
+// session. It reports false if the game had one already, so only one
+// caller records it.
+func (s *Store) SetPairingResult(ctx context.Context, sessionCode, result string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"UPDATE tournament_pairings SET result = ? WHERE session_code = ? AND session_code != '' AND result = ''",
+		result, sessionCode,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// CreateClub inserts a club with its owner as its first member.
+func (s *Store) CreateClub(ctx context.Context, c ClubRow) error {
+	return s.inTx(ctx, func(tx conn) error {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO clubs (id, name, owner) VALUES (?, ?, ?)",
+			c.ID, c.Name, c.Owner,
+		); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO club_members (club_id, player_id, role) VALUES (?, ?, 'owner')",
+			c.ID, c.Owner,
+		)
+		return err
+	})
+}
+
+const clubColumns = "id, name, owner, (SELECT COUNT(*) FROM club_members WHERE club_id = clubs.id), branding, created_at"
+
+func scanClub(row interface{ Scan(...any) error }) (*ClubRow, error) {
+	var c ClubRow
+	if err := row.Scan(&c.ID, &c.Name, &c.Owner, &c.Members, &c.Branding, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetClub retrieves a club by ID.
+func (s *Store) GetClub(ctx context.Context, id string) (*ClubRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return scanClub(s.conn().QueryRowContext(ctx, "SELECT "+clubColumns+" FROM clubs WHERE id = ?", id))
+}
+
+// ListClubs returns every club, by name.
+func (s *Store) ListClubs(ctx context.Context) ([]ClubRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+clubColumns+" FROM clubs ORDER BY name, created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []ClubRow
+	for rows.Next() {
+		c, err := scanClub(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *c)
+	}
+	return result, rows.Err()
+}
+
+// SetClubBranding saves how a club presents itself.
+func (s *Store) SetClubBranding(ctx context.Context, id, brandingJSON string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE clubs SET branding = ? WHERE id = ?", brandingJSON, id)
+	return err
+}
+
+// AddClubMember adds a player to a club. It reports false if they
+// already belong.
+func (s *Store) AddClubMember(ctx context.Context, m ClubMemberRow) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"INSERT OR IGNORE INTO club_members (club_id, player_id, role) VALUES (?, ?, ?)",
+		m.ClubID, m.PlayerID, m.Role,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// GetClubMember returns a player's membership of a club.
+func (s *Store) GetClubMember(ctx context.Context, clubID, playerID string) (*ClubMemberRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var m ClubMemberRow
+	err := s.conn().QueryRowContext(ctx,
+		"SELECT club_id, player_id, role, joined_at FROM club_members WHERE club_id = ? AND player_id = ?",
+		clubID, playerID,
+	).Scan(&m.ClubID, &m.PlayerID, &m.Role, &m.JoinedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ListClubMembers returns a club's members in the order they joined.
+func (s *Store) ListClubMembers(ctx context.Context, clubID string) ([]ClubMemberRow, error) {
+	return s.listClubMembers(ctx, "club_id", clubID)
+}
+
+// ListPlayerClubs returns a player's memberships, in the order they
+// joined.
+func (s *Store) ListPlayerClubs(ctx context.Context, playerID string) ([]ClubMemberRow, error) {
+	return s.listClubMembers(ctx, "player_id", playerID)
+}
+
+func (s *Store) listClubMembers(ctx context.Context, column, value string) ([]ClubMemberRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx,
+		"SELECT club_id, player_id, role, joined_at FROM club_members WHERE "+column+" = ? ORDER BY joined_at, rowid",
+		value,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []ClubMemberRow
+	for rows.Next() {
+		var m ClubMemberRow
+		if err := rows.Scan(&m.ClubID, &m.PlayerID, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// SetClubRole changes a member's role. It reports false if the player
+// is not a member.
+func (s *Store) SetClubRole(ctx context.Context, clubID, playerID, role string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"UPDATE club_members SET role = ? WHERE club_id = ? AND player_id = ?",
+		role, clubID, playerID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RemoveClubMember takes a player out of a club. It reports false if
+// they were not a member.
+func (s *Store) RemoveClubMember(ctx context.Context, clubID, playerID string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"DELETE FROM club_members WHERE club_id = ? AND player_id = ?",
+		clubID, playerID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// SaveSurvey stores a player's answer to the survey after a match.
+// Answers outlive the sessions they are about.
+func (s *Store) SaveSurvey(ctx context.Context, r SurveyRow) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx,
+		"INSERT INTO surveys (session_code, game_type, player_id, fun, conduct, comment, bug, transcript) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		r.SessionCode, r.GameType, r.PlayerID, r.Fun, r.Conduct, r.Comment, r.Bug, r.Transcript,
+	)
+	return err
+}
+
+// ListSurveys returns survey answers matching f, newest first.
+func (s *Store) ListSurveys(ctx context.Context, f SurveyFilter) ([]SurveyRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := "SELECT id, session_code, game_type, player_id, fun, conduct, comment, bug, transcript, created_at FROM surveys WHERE 1=1"
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"game_type", f.GameType}, {"player_id", f.PlayerID},
+	} {
+		if c.value != "" {
+			query += " AND " + c.column + " = ?"
+			args = append(args, c.value)
+		}
+	}
+	if f.Bugs {
+		query += " AND bug != ''"
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []SurveyRow
+	for rows.Next() {
+		var r SurveyRow
+		if err := rows.Scan(&r.ID, &r.SessionCode, &r.GameType, &r.PlayerID, &r.Fun, &r.Conduct, &r.Comment, &r.Bug, &r.Transcript, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SaveReport stores a problem report and returns its ID. Like survey
+// answers, reports outlive the sessions they are about.
+func (s *Store) SaveReport(ctx context.Context, r ReportRow) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx,
+		"INSERT INTO reports (session_code, game_type, player_id, note, bundle) VALUES (?, ?, ?, ?, ?)",
+		r.SessionCode, r.GameType, r.PlayerID, r.Note, r.Bundle,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListReports returns problem reports matching f, newest first, without
+// their bundles; GetReport has those.
+func (s *Store) ListReports(ctx context.Context, f ReportFilter) ([]ReportRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := "SELECT id, session_code, game_type, player_id, note, created_at FROM reports WHERE 1=1"
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"session_code", f.SessionCode}, {"game_type", f.GameType},
+	} {
+		if c.value != "" {
+			query += " AND " + c.column + " = ?"
+			args = append(args, c.value)
+		}
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ReportRow
+	for rows.Next() {
+		var r ReportRow
+		if err := rows.Scan(&r.ID, &r.SessionCode, &r.GameType, &r.PlayerID, &r.Note, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// GetReport returns a problem report with its bundle, or sql.ErrNoRows if
+// there is none with that ID.
+func (s *Store) GetReport(ctx context.Context, id int64) (*ReportRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var r ReportRow
+	err := s.conn().QueryRowContext(ctx,
+		"SELECT id, session_code, game_type, player_id, note, bundle, created_at FROM reports WHERE id = ?", id,
+	).Scan(&r.ID, &r.SessionCode, &r.GameType, &r.PlayerID, &r.Note, &r.Bundle, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// DeleteSession soft-deletes a session. It disappears from reads until
+// restored with RestoreSession or removed for good by PurgeDeletedSessions.
+func (s *Store) DeleteSession(ctx context.Context, code string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn().ExecContext(ctx, "UPDATE sessions SET deleted_at = CURRENT_TIMESTAMP WHERE code = ? AND deleted_at IS NULL", code)
+	return err
+}
+
+// RestoreSession undoes a soft delete. It reports false if the session was
+// not deleted.
+func (s *Store) RestoreSession(ctx context.Context, code string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn().ExecContext(ctx, "UPDATE sessions SET deleted_at = NULL WHERE code = ? AND deleted_at IS NOT NULL", code)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// GetDeletedSession retrieves a soft-deleted session.
+func (s *Store) GetDeletedSession(ctx context.Context, code string) (*SessionRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	row := s.conn().QueryRowContext(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE code = ? AND deleted_at IS NOT NULL", code)
+	return scanSession(row)
+}
+
+// ListDeletedSessions returns soft-deleted sessions, most recently deleted
+// first.
+func (s *Store) ListDeletedSessions(ctx context.Context) ([]SessionRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []SessionRow
+	for rows.Next() {
+		sr, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *sr)
+	}
+	return result, rows.Err()
+}
+
+// PurgeDeletedSessions permanently removes sessions soft-deleted before
+// cutoff, with their match state, move log and timeline, and returns how
+// many.
+func (s *Store) PurgeDeletedSessions(ctx context.Context, cutoff time.Time) (int64, error) {
+	var n int64
+	err := s.inTx(ctx, func(tx conn) error {
+		const doomed = "SELECT code FROM sessions WHERE deleted_at IS NOT NULL AND deleted_at < ?"
+		at := cutoff.UTC().Format(time.DateTime)
+		for _, table := range []string{"match_moves", "match_initial_state", "match_state", "session_timeline"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE session_code IN ("+doomed+")", at); err != nil {
+				return err
+			}
+		}
+		// Player rosters are saved under "<code>_players"
+		if _, err := tx.ExecContext(ctx, "DELETE FROM match_state WHERE session_code IN (SELECT code || '_players' FROM sessions WHERE deleted_at IS NOT NULL AND deleted_at < ?)", at); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE deleted_at IS NOT NULL AND deleted_at < ?", at)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// enableIncrementalVacuum lets Maintain hand free pages back to the file
+// system. A database created before this setting needs a full VACUUM
+// once for it to take effect.
+func (s *Store) enableIncrementalVacuum() error {
+	var mode int
+	if err := s.db.QueryRow("PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return err
+	}
+	if mode == 2 { // incremental
+		return nil
+	}
+	if _, err := s.db.Exec("PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("VACUUM")
+	return err
+}
+
+// Maintain releases the database's free pages and checkpoints the WAL
+// into the main file, truncating it, so deleted sessions and moves stop
+// taking up disk. Compaction can take a while, so only ctx bounds it, not
+// the query timeout.
+func (s *Store) Maintain(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "PRAGMA incremental_vacuum"); err != nil {
+		return fmt.Errorf("incremental vacuum: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Size reports the database's size and the row count of every table.
+func (s *Store) Size(ctx context.Context) (SizeRow, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var pageSize, pages, free int64
+	if err := s.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return SizeRow{}, err
+	}
+	if err := s.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+		return SizeRow{}, err
+	}
+	if err := s.db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&free); err != nil {
+		return SizeRow{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return SizeRow{}, err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return SizeRow{}, err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return SizeRow{}, err
+	}
+	size := SizeRow{Bytes: pages * pageSize, FreeBytes: free * pageSize, Rows: make(map[string]int64, len(tables))}
+	for _, table := range tables {
+		var n int64
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+table+`"`).Scan(&n); err != nil {
+			return SizeRow{}, err
+		}
+		size.Rows[table] = n
+	}
+	return size, nil
+}
+
 // Close closes the database connection.
 func (s *Store) Close() error {
 	return s.db.Close()
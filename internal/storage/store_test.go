@@ -1,8 +1,14 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newTestStore(t *testing.T) *Store {
@@ -17,20 +23,20 @@ func newTestStore(t *testing.T) *Store {
 
 func TestCreateSession(t *testing.T) {
 	s := newTestStore(t)
-	if err := s.CreateSession("abc123", "tictactoe"); err != nil {
+	if err := s.CreateSession(t.Context(), "abc123", "tictactoe"); err != nil {
 		t.Fatalf("create session: %v", err)
 	}
 	// Duplicate code should error
-	if err := s.CreateSession("abc123", "tictactoe"); err == nil {
+	if err := s.CreateSession(t.Context(), "abc123", "tictactoe"); err == nil {
 		t.Fatal("expected error on duplicate code")
 	}
 }
 
 func TestGetSession(t *testing.T) {
 	s := newTestStore(t)
-	s.CreateSession("abc123", "tictactoe")
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
 
-	row, err := s.GetSession("abc123")
+	row, err := s.GetSession(t.Context(), "abc123")
 	if err != nil {
 		t.Fatalf("get session: %v", err)
 	}
@@ -50,7 +56,7 @@ func TestGetSession(t *testing.T) {
 
 func TestGetSessionNotFound(t *testing.T) {
 	s := newTestStore(t)
-	_, err := s.GetSession("nonexistent")
+	_, err := s.GetSession(t.Context(), "nonexistent")
 	if err != sql.ErrNoRows {
 		t.Fatalf("expected sql.ErrNoRows, got %v", err)
 	}
@@ -58,12 +64,12 @@ func TestGetSessionNotFound(t *testing.T) {
 
 func TestUpdateSessionStatus(t *testing.T) {
 	s := newTestStore(t)
-	s.CreateSession("abc123", "tictactoe")
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
 
-	if err := s.UpdateSessionStatus("abc123", "playing"); err != nil {
+	if err := s.UpdateSessionStatus(t.Context(), "abc123", "playing"); err != nil {
 		t.Fatalf("update status: %v", err)
 	}
-	row, err := s.GetSession("abc123")
+	row, err := s.GetSession(t.Context(), "abc123")
 	if err != nil {
 		t.Fatalf("get session: %v", err)
 	}
@@ -72,13 +78,30 @@ func TestUpdateSessionStatus(t *testing.T) {
 	}
 }
 
+func TestSetSessionOptions(t *testing.T) {
+	s := newTestStore(t)
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
+
+	row, _ := s.GetSession(t.Context(), "abc123")
+	if row.Options != "{}" {
+		t.Fatalf("expected empty options by default, got %q", row.Options)
+	}
+	if err := s.SetSessionOptions(t.Context(), "abc123", `{"misere":1}`); err != nil {
+		t.Fatalf("set options: %v", err)
+	}
+	row, _ = s.GetSession(t.Context(), "abc123")
+	if row.Options != `{"misere":1}` {
+		t.Fatalf("expected stored options, got %q", row.Options)
+	}
+}
+
 func TestListSessionsAll(t *testing.T) {
 	s := newTestStore(t)
-	s.CreateSession("aaa", "tictactoe")
-	s.CreateSession("bbb", "tictactoe")
-	s.CreateSession("ccc", "tictactoe")
+	s.CreateSession(t.Context(), "aaa", "tictactoe")
+	s.CreateSession(t.Context(), "bbb", "tictactoe")
+	s.CreateSession(t.Context(), "ccc", "tictactoe")
 
-	rows, err := s.ListSessions("")
+	rows, err := s.ListSessions(t.Context(), SessionFilter{})
 	if err != nil {
 		t.Fatalf("list sessions: %v", err)
 	}
@@ -89,11 +112,11 @@ func TestListSessionsAll(t *testing.T) {
 
 func TestListSessionsFiltered(t *testing.T) {
 	s := newTestStore(t)
-	s.CreateSession("aaa", "tictactoe")
-	s.CreateSession("bbb", "tictactoe")
-	s.UpdateSessionStatus("bbb", "playing")
+	s.CreateSession(t.Context(), "aaa", "tictactoe")
+	s.CreateSession(t.Context(), "bbb", "tictactoe")
+	s.UpdateSessionStatus(t.Context(), "bbb", "playing")
 
-	rows, err := s.ListSessions("waiting")
+	rows, err := s.ListSessions(t.Context(), SessionFilter{Status: "waiting"})
 	if err != nil {
 		t.Fatalf("list sessions: %v", err)
 	}
@@ -107,13 +130,13 @@ func TestListSessionsFiltered(t *testing.T) {
 
 func TestSaveAndGetMatchState(t *testing.T) {
 	s := newTestStore(t)
-	s.CreateSession("abc123", "tictactoe")
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
 
 	stateJSON := `{"board":[0,0,0,0,1,0,0,0,0],"turn":1}`
-	if err := s.SaveMatchState("abc123", stateJSON); err != nil {
+	if err := s.SaveMatchState(t.Context(), "abc123", stateJSON); err != nil {
 		t.Fatalf("save match state: %v", err)
 	}
-	got, err := s.GetMatchState("abc123")
+	got, err := s.GetMatchState(t.Context(), "abc123")
 	if err != nil {
 		t.Fatalf("get match state: %v", err)
 	}
@@ -124,12 +147,12 @@ func TestSaveAndGetMatchState(t *testing.T) {
 
 func TestSaveMatchStateUpsert(t *testing.T) {
 	s := newTestStore(t)
-	s.CreateSession("abc123", "tictactoe")
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
 
-	s.SaveMatchState("abc123", `{"v":1}`)
-	s.SaveMatchState("abc123", `{"v":2}`)
+	s.SaveMatchState(t.Context(), "abc123", `{"v":1}`)
+	s.SaveMatchState(t.Context(), "abc123", `{"v":2}`)
 
-	got, err := s.GetMatchState("abc123")
+	got, err := s.GetMatchState(t.Context(), "abc123")
 	if err != nil {
 		t.Fatalf("get match state: %v", err)
 	}
@@ -140,17 +163,17 @@ func TestSaveMatchStateUpsert(t *testing.T) {
 
 func TestDeleteSession(t *testing.T) {
 	s := newTestStore(t)
-	s.CreateSession("abc123", "tictactoe")
-	s.SaveMatchState("abc123", `{"v":1}`)
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
+	s.SaveMatchState(t.Context(), "abc123", `{"v":1}`)
 
-	if err := s.DeleteSession("abc123"); err != nil {
+	if err := s.DeleteSession(t.Context(), "abc123"); err != nil {
 		t.Fatalf("delete session: %v", err)
 	}
-	_, err := s.GetSession("abc123")
+	_, err := s.GetSession(t.Context(), "abc123")
 	if err != sql.ErrNoRows {
 		t.Fatalf("expected sql.ErrNoRows after delete, got %v", err)
 	}
-	_, err = s.GetMatchState("abc123")
+	_, err = s.GetMatchState(t.Context(), "abc123")
 	if err != sql.ErrNoRows {
 		t.Fatalf("expected sql.ErrNoRows for match state after delete, got %v", err)
 	}
@@ -158,8 +181,496 @@ func TestDeleteSession(t *testing.T) {
 
 func TestGetMatchStateNotFound(t *testing.T) {
 	s := newTestStore(t)
-	_, err := s.GetMatchState("nonexistent")
+	_, err := s.GetMatchState(t.Context(), "nonexistent")
 	if err != sql.ErrNoRows {
 		t.Fatalf("expected sql.ErrNoRows, got %v", err)
 	}
 }
+
+func TestAppendAndListMoves(t *testing.T) {
+	s := newTestStore(t)
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
+
+	if err := s.AppendMove(t.Context(), "abc123", 1, "alice", `{"type":"move"}`, ""); err != nil {
+		t.Fatalf("append move: %v", err)
+	}
+	if err := s.AppendMove(t.Context(), "abc123", 2, "bob", `{"type":"move"}`, `{"at":"2024-01-01T00:00:00.25Z"}`); err != nil {
+		t.Fatalf("append move: %v", err)
+	}
+	if err := s.AppendMove(t.Context(), "abc123", 2, "bob", `{"type":"move"}`, ""); err == nil {
+		t.Fatal("expected error on duplicate seq")
+	}
+
+	moves, err := s.ListMoves(t.Context(), "abc123")
+	if err != nil {
+		t.Fatalf("list moves: %v", err)
+	}
+	if len(moves) != 2 || moves[0].PlayerID != "alice" || moves[1].Seq != 2 || moves[1].TimingJSON == "" {
+		t.Fatalf("unexpected moves: %+v", moves)
+	}
+
+	s.DeleteSession(t.Context(), "abc123")
+	moves, _ = s.ListMoves(t.Context(), "abc123")
+	if len(moves) != 0 {
+		t.Fatalf("expected moves deleted with session, got %d", len(moves))
+	}
+}
+
+func TestSaveAndGetInitialState(t *testing.T) {
+	s := newTestStore(t)
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
+
+	if err := s.SaveInitialState(t.Context(), "abc123", InitialStateRow{StateJSON: `{"v":0}`, Seed: 42, PlayersJSON: `["b","a"]`, Salt: "s"}); err != nil {
+		t.Fatalf("save initial state: %v", err)
+	}
+	got, err := s.GetInitialState(t.Context(), "abc123")
+	if err != nil {
+		t.Fatalf("get initial state: %v", err)
+	}
+	if got.StateJSON != `{"v":0}` || got.Seed != 42 || got.PlayersJSON != `["b","a"]` || got.Salt != "s" {
+		t.Fatalf("unexpected initial state: %+v", got)
+	}
+}
+
+func TestCreateAndGetBot(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.CreateBot(t.Context(), "ext-1", "bot", "hash1", "http://example.com/hook"); err != nil {
+		t.Fatalf("create bot: %v", err)
+	}
+	if err := s.CreateBot(t.Context(), "ext-2", "bot", "hash1", ""); err == nil {
+		t.Fatal("expected error on duplicate key hash")
+	}
+	b, err := s.GetBotByKeyHash(t.Context(), "hash1")
+	if err != nil {
+		t.Fatalf("get bot: %v", err)
+	}
+	if b.ID != "ext-1" || b.WebhookURL != "http://example.com/hook" {
+		t.Fatalf("unexpected bot: %+v", b)
+	}
+	if _, err := s.GetBotByKeyHash(t.Context(), "nope"); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestChallengeLifecycle(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+	for _, c := range []ChallengeRow{
+		{ID: "c1", GameType: "tictactoe", ChallengerID: "alice", TargetID: "bob", ExpiresAt: now.Add(time.Minute)},
+		{ID: "c2", GameType: "tictactoe", ChallengerID: "carol", TargetID: "alice", ExpiresAt: now.Add(-time.Second)},
+	} {
+		if err := s.CreateChallenge(t.Context(), c); err != nil {
+			t.Fatalf("create challenge: %v", err)
+		}
+	}
+
+	pending, err := s.ListPendingChallenges(t.Context(), "alice", now)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != "c1" {
+		t.Fatalf("expected only c1 pending, got %+v", pending)
+	}
+
+	n, err := s.ExpireChallenges(t.Context(), now)
+	if err != nil || n != 1 {
+		t.Fatalf("expire: n=%d err=%v", n, err)
+	}
+	c2, _ := s.GetChallenge(t.Context(), "c2")
+	if c2.Status != "expired" {
+		t.Fatalf("expected c2 expired, got %s", c2.Status)
+	}
+
+	ok, err := s.ResolveChallenge(t.Context(), "c1", "accepted", "abc123")
+	if err != nil || !ok {
+		t.Fatalf("resolve: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := s.ResolveChallenge(t.Context(), "c1", "declined", ""); ok {
+		t.Fatal("expected second resolve to fail")
+	}
+	c1, _ := s.GetChallenge(t.Context(), "c1")
+	if c1.Status != "accepted" || c1.SessionCode != "abc123" {
+		t.Fatalf("unexpected c1: %+v", c1)
+	}
+}
+
+func TestFriendships(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.CreateFriendRequest(t.Context(), "alice", "bob"); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	f, err := s.GetFriendship(t.Context(), "bob", "alice")
+	if err != nil || f.RequesterID != "alice" || f.Status != "pending" {
+		t.Fatalf("unexpected friendship %+v (err %v)", f, err)
+	}
+	if ok, _ := s.AcceptFriendRequest(t.Context(), "bob", "alice"); ok {
+		t.Fatal("only the addressee's side can be accepted")
+	}
+	if ok, err := s.AcceptFriendRequest(t.Context(), "alice", "bob"); err != nil || !ok {
+		t.Fatalf("accept: ok=%v err=%v", ok, err)
+	}
+	list, _ := s.ListFriendships(t.Context(), "bob")
+	if len(list) != 1 || list[0].Status != "accepted" {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	if ok, _ := s.DeleteFriendship(t.Context(), "bob", "alice"); !ok {
+		t.Fatal("expected delete to remove the friendship")
+	}
+	if _, err := s.GetFriendship(t.Context(), "alice", "bob"); err != sql.ErrNoRows {
+		t.Fatalf("expected ErrNoRows, got %v", err)
+	}
+}
+
+func TestRecentOpponents(t *testing.T) {
+	s := newTestStore(t)
+	s.RecordMatchPlayers(t.Context(), "m1", "tictactoe", []string{"alice", "bob"})
+	s.RecordMatchPlayers(t.Context(), "m2", "tictactoe", []string{"alice", "carol"})
+	s.RecordMatchPlayers(t.Context(), "m3", "tictactoe", []string{"dave", "erin"})
+
+	opponents, err := s.ListRecentOpponents(t.Context(), "alice", 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(opponents) != 2 {
+		t.Fatalf("expected 2 opponents, got %+v", opponents)
+	}
+	for _, o := range opponents {
+		if o.PlayerID == "alice" || o.PlayerID == "dave" {
+			t.Fatalf("unexpected opponent %+v", o)
+		}
+		if o.PlayedAt.IsZero() {
+			t.Fatalf("expected a played-at time for %s", o.PlayerID)
+		}
+	}
+}
+
+func TestArchiveMatch(t *testing.T) {
+	s := newTestStore(t)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.ArchiveMatch(t.Context(), ArchivedMatchRow{SessionCode: "m1", GameType: "tictactoe", Moves: 5, StartedAt: start, FinishedAt: start.Add(time.Minute)})
+	s.ArchiveMatch(t.Context(), ArchivedMatchRow{SessionCode: "m2", GameType: "tictactoe", Moves: 9, Handicaps: `{"bob":{"centre":1}}`, StartedAt: start, FinishedAt: start.Add(3 * time.Minute)})
+	s.ArchiveMatch(t.Context(), ArchivedMatchRow{SessionCode: "m3", GameType: "tictactoe", Moves: 1, Abandoned: true, StartedAt: start, FinishedAt: start.Add(time.Hour)})
+	s.ArchiveMatch(t.Context(), ArchivedMatchRow{SessionCode: "m4", GameType: "other", Moves: 2, StartedAt: start, FinishedAt: start.Add(time.Hour)})
+
+	matches, err := s.ListArchivedMatches(t.Context(), "tictactoe", 2)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(matches) != 2 || matches[0].SessionCode != "m3" || !matches[0].Abandoned || matches[1].SessionCode != "m2" {
+		t.Fatalf("expected the two latest matches, newest first, got %+v", matches)
+	}
+	if !matches[1].StartedAt.Equal(start) || matches[1].Moves != 9 || matches[1].Handicaps != `{"bob":{"centre":1}}` {
+		t.Fatalf("unexpected archived match %+v", matches[1])
+	}
+
+	stats, err := s.MatchStats(t.Context(), "tictactoe", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	want := MatchStatsRow{Matches: 2, AvgDuration: 2 * time.Minute, AvgMoves: 7, Abandoned: 1}
+	if stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+	if stats, _ := s.MatchStats(t.Context(), "chess", time.Time{}, time.Time{}); stats != (MatchStatsRow{}) {
+		t.Fatalf("expected no stats for an unplayed game, got %+v", stats)
+	}
+	// m1 finished a minute in, the others later
+	stats, _ = s.MatchStats(t.Context(), "tictactoe", start.Add(2*time.Minute), start.Add(time.Hour))
+	if want := (MatchStatsRow{Matches: 1, AvgDuration: 3 * time.Minute, AvgMoves: 9}); stats != want {
+		t.Fatalf("expected only m2 within the period, got %+v", stats)
+	}
+}
+
+func TestPushSubscriptions(t *testing.T) {
+	s := newTestStore(t)
+	sub := PushSubscriptionRow{Endpoint: "https://push.example/1", PlayerID: "alice", P256dh: "k", Auth: "a"}
+	if err := s.SavePushSubscription(t.Context(), sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	// Re-subscribing the same browser as another player moves it
+	sub.PlayerID = "bob"
+	if err := s.SavePushSubscription(t.Context(), sub); err != nil {
+		t.Fatalf("resave: %v", err)
+	}
+	if subs, _ := s.ListPushSubscriptions(t.Context(), "alice"); len(subs) != 0 {
+		t.Fatalf("expected alice to have no subscriptions, got %+v", subs)
+	}
+	subs, err := s.ListPushSubscriptions(t.Context(), "bob")
+	if err != nil || len(subs) != 1 {
+		t.Fatalf("expected one subscription for bob, got %+v (err %v)", subs, err)
+	}
+	if err := s.DeletePushSubscription(t.Context(), sub.Endpoint); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if subs, _ := s.ListPushSubscriptions(t.Context(), "bob"); len(subs) != 0 {
+		t.Fatalf("expected no subscriptions after delete, got %+v", subs)
+	}
+}
+
+func TestEmailContacts(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.SaveEmailContact(t.Context(), "alice", "alice@example.com", "tok1"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if ok, err := s.UpdateEmailPreferences(t.Context(), "alice", false, true); err != nil || !ok {
+		t.Fatalf("preferences: ok=%v err=%v", ok, err)
+	}
+	if _, err := s.VerifyEmail(t.Context(), "wrong"); err != sql.ErrNoRows {
+		t.Fatalf("expected ErrNoRows for unknown token, got %v", err)
+	}
+	player, err := s.VerifyEmail(t.Context(), "tok1")
+	if err != nil || player != "alice" {
+		t.Fatalf("verify: player=%q err=%v", player, err)
+	}
+	c, _ := s.GetEmailContact(t.Context(), "alice")
+	if !c.Verified || c.VerifyToken != "" || c.NotifyTurns || !c.NotifyChallenges {
+		t.Fatalf("unexpected contact: %+v", c)
+	}
+
+	// Changing the address requires verifying again but keeps preferences
+	s.SaveEmailContact(t.Context(), "alice", "new@example.com", "tok2")
+	c, _ = s.GetEmailContact(t.Context(), "alice")
+	if c.Verified || c.Email != "new@example.com" || c.NotifyTurns {
+		t.Fatalf("unexpected contact after change: %+v", c)
+	}
+	if ok, _ := s.UpdateEmailPreferences(t.Context(), "bob", true, true); ok {
+		t.Fatal("expected no contact for bob")
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	s := newTestStore(t)
+	entries := []AuditRow{
+		{Actor: "root", Action: "session.delete", SessionCode: "abc", Status: 200},
+		{Actor: "root", Action: "session.kick", SessionCode: "abc", PlayerID: "bob", Status: 200},
+		{Actor: "mod", Action: "session.kick", SessionCode: "def", PlayerID: "carol", Status: 404},
+	}
+	for _, e := range entries {
+		if err := s.AppendAudit(t.Context(), e); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	all, err := s.ListAudit(t.Context(), AuditFilter{})
+	if err != nil || len(all) != 3 || all[0].Actor != "mod" {
+		t.Fatalf("expected 3 entries newest first, got %+v (err %v)", all, err)
+	}
+	kicks, _ := s.ListAudit(t.Context(), AuditFilter{Action: "session.kick", SessionCode: "abc"})
+	if len(kicks) != 1 || kicks[0].PlayerID != "bob" {
+		t.Fatalf("unexpected filtered entries: %+v", kicks)
+	}
+	if future, _ := s.ListAudit(t.Context(), AuditFilter{Since: time.Now().Add(time.Hour)}); len(future) != 0 {
+		t.Fatalf("expected no entries in the future, got %+v", future)
+	}
+	if limited, _ := s.ListAudit(t.Context(), AuditFilter{Limit: 2}); len(limited) != 2 {
+		t.Fatalf("expected limit 2, got %d", len(limited))
+	}
+
+	if _, err := s.db.Exec("DELETE FROM audit_log"); err == nil {
+		t.Fatal("expected audit log delete to be rejected")
+	}
+	if _, err := s.db.Exec("UPDATE audit_log SET actor = 'x'"); err == nil {
+		t.Fatal("expected audit log update to be rejected")
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	s := newTestStore(t)
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
+	s.SaveMatchState(t.Context(), "abc123", `{"v":1}`)
+	s.AppendMove(t.Context(), "abc123", 1, "alice", `{"type":"move"}`, "")
+	s.DeleteSession(t.Context(), "abc123")
+
+	if list, _ := s.ListSessions(t.Context(), SessionFilter{}); len(list) != 0 {
+		t.Fatalf("expected deleted session hidden, got %+v", list)
+	}
+	deleted, err := s.ListDeletedSessions(t.Context())
+	if err != nil || len(deleted) != 1 || deleted[0].DeletedAt.IsZero() {
+		t.Fatalf("expected one deleted session, got %+v (err %v)", deleted, err)
+	}
+
+	if ok, err := s.RestoreSession(t.Context(), "abc123"); err != nil || !ok {
+		t.Fatalf("restore: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := s.RestoreSession(t.Context(), "abc123"); ok {
+		t.Fatal("expected restoring a live session to report false")
+	}
+	if state, err := s.GetMatchState(t.Context(), "abc123"); err != nil || state != `{"v":1}` {
+		t.Fatalf("expected match state back after restore, got %q (err %v)", state, err)
+	}
+	if moves, _ := s.ListMoves(t.Context(), "abc123"); len(moves) != 1 {
+		t.Fatalf("expected move log back after restore, got %+v", moves)
+	}
+}
+
+func TestPurgeDeletedSessions(t *testing.T) {
+	s := newTestStore(t)
+	s.CreateSession(t.Context(), "old", "tictactoe")
+	s.SaveMatchState(t.Context(), "old", `{}`)
+	s.CreateSession(t.Context(), "live", "tictactoe")
+	s.DeleteSession(t.Context(), "old")
+
+	if n, err := s.PurgeDeletedSessions(t.Context(), time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("expected nothing old enough to purge, n=%d err=%v", n, err)
+	}
+	if n, err := s.PurgeDeletedSessions(t.Context(), time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("expected one purge, n=%d err=%v", n, err)
+	}
+	if ok, _ := s.RestoreSession(t.Context(), "old"); ok {
+		t.Fatal("purged session should not be restorable")
+	}
+	var states int
+	s.db.QueryRow("SELECT COUNT(*) FROM match_state").Scan(&states)
+	if states != 0 {
+		t.Fatalf("expected purged match state, %d rows left", states)
+	}
+	if _, err := s.GetSession(t.Context(), "live"); err != nil {
+		t.Fatalf("live session should survive purge: %v", err)
+	}
+}
+
+func TestMaintainReclaimsSpace(t *testing.T) {
+	s, err := New(t.TempDir() + "/games.db")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	s.CreateSession(t.Context(), "old", "tictactoe")
+	action := `{"type":"move","payload":"` + strings.Repeat("x", 1000) + `"}`
+	for i := 1; i <= 200; i++ {
+		s.AppendMove(t.Context(), "old", i, "alice", action, "")
+	}
+	size, err := s.Size(t.Context())
+	if err != nil {
+		t.Fatalf("size: %v", err)
+	}
+	if size.Rows["match_moves"] != 200 || size.Rows["sessions"] != 1 || size.Bytes == 0 {
+		t.Fatalf("unexpected size %+v", size)
+	}
+
+	s.DeleteSession(t.Context(), "old")
+	s.PurgeDeletedSessions(t.Context(), time.Now().Add(time.Minute))
+	before, _ := s.Size(t.Context())
+	if before.FreeBytes == 0 {
+		t.Fatalf("expected free pages after the purge, got %+v", before)
+	}
+	if err := s.Maintain(t.Context()); err != nil {
+		t.Fatalf("maintain: %v", err)
+	}
+	after, _ := s.Size(t.Context())
+	if after.FreeBytes != 0 || after.Bytes >= before.Bytes || after.Rows["match_moves"] != 0 {
+		t.Fatalf("expected the free pages released, before %+v after %+v", before, after)
+	}
+}
+
+func TestMigrateAddsDeletedAt(t *testing.T) {
+	path := t.TempDir() + "/old.db"
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE sessions (
+		code TEXT PRIMARY KEY, game_type TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'waiting', created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`)
+	db.Exec("INSERT INTO sessions (code, game_type) VALUES ('abc123', 'tictactoe')")
+	db.Close()
+	if err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+
+	for i := 0; i < 2; i++ { // migrating twice must be harmless
+		s, err := New(path)
+		if err != nil {
+			t.Fatalf("open store: %v", err)
+		}
+		if _, err := s.GetSession(t.Context(), "abc123"); err != nil {
+			t.Fatalf("existing session lost: %v", err)
+		}
+		s.Close()
+	}
+}
+
+func TestSetSessionTimes(t *testing.T) {
+	s := newTestStore(t)
+	s.CreateSession(t.Context(), "abc123", "tictactoe")
+	row, _ := s.GetSession(t.Context(), "abc123")
+	if !row.StartedAt.IsZero() || !row.LastActivity.IsZero() {
+		t.Fatalf("expected no activity yet, got %+v", row)
+	}
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	active := started.Add(5 * time.Minute)
+	if err := s.SetSessionTimes(t.Context(), "abc123", started, time.Time{}, active); err != nil {
+		t.Fatalf("set times: %v", err)
+	}
+	row, err := s.GetSession(t.Context(), "abc123")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if !row.StartedAt.Equal(started) || !row.FinishedAt.IsZero() || !row.LastActivity.Equal(active) {
+		t.Fatalf("unexpected times: started %v finished %v active %v", row.StartedAt, row.FinishedAt, row.LastActivity)
+	}
+}
+
+func TestStoreContext(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.CreateSession(t.Context(), "abc123", "tictactoe"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := s.GetSession(ctx, "abc123"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled query to fail, got %v", err)
+	}
+	err := s.WithTx(t.Context(), func(tx Backend) error {
+		if err := tx.UpdateSessionStatus(t.Context(), "abc123", "playing"); err != nil {
+			return err
+		}
+		return tx.UpdateSessionStatus(ctx, "abc123", "finished")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the transaction to fail, got %v", err)
+	}
+	if row, _ := s.GetSession(t.Context(), "abc123"); row.Status != "waiting" {
+		t.Fatalf("expected the transaction to roll back, got %s", row.Status)
+	}
+
+	s.SetQueryTimeout(time.Nanosecond)
+	if _, err := s.ListSessions(t.Context(), SessionFilter{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the query to time out, got %v", err)
+	}
+	s.SetQueryTimeout(0)
+	if _, err := s.ListSessions(t.Context(), SessionFilter{}); err != nil {
+		t.Fatalf("expected no timeout, got %v", err)
+	}
+}
+
+func TestQueryMetrics(t *testing.T) {
+	s := newTestStore(t)
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	s.SetSlowQueryThreshold(time.Nanosecond)
+	if err := s.CreateSession(t.Context(), "abc123", "tictactoe"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	s.WithTx(t.Context(), func(tx Backend) error {
+		return tx.UpdateSessionStatus(t.Context(), "abc123", "playing")
+	})
+	s.SetSlowQueryThreshold(0)
+	s.GetSession(t.Context(), "abc123")
+
+	for _, name := range []string{"insert sessions", "update sessions", "select sessions"} {
+		if s.QueryMetrics().Count(name) != 1 {
+			t.Errorf("expected one %q observed, got %d", name, s.QueryMetrics().Count(name))
+		}
+	}
+	if !strings.Contains(logged.String(), "slow query") || !strings.Contains(logged.String(), "INSERT INTO sessions") {
+		t.Errorf("expected the insert logged as slow, got %q", logged.String())
+	}
+	if strings.Contains(logged.String(), "SELECT") {
+		t.Errorf("expected nothing logged with the threshold off, got %q", logged.String())
+	}
+}
@@ -2,5 +2,7 @@ package games
 
 import "embed"
 
+//go:generate go run ./cmd/wstypes -o web/js/types.d.ts
+
 //go:embed all:web
 var WebFS embed.FS
@@ -0,0 +1,79 @@
+// Applies the site's branding from /api/branding to every page: its name
+// and logo above the page and its colors through the stylesheet's
+// variables. A page about a club loads the club's instead, over the
+// site's: the club page names its query parameter in data-club-param,
+// and other pages call loadBranding with the club once they know it.
+(function() {
+    const prefix = window.tenantPrefix;
+    const script = document.currentScript;
+    let brandName = "";
+    let latest = 0;
+
+    // darken scales a #rrggbb color toward black, for hovers.
+    function darken(color, by) {
+        const n = parseInt(color.slice(1), 16);
+        const channel = shift => Math.round(((n >> shift) & 255) * (1 - by));
+        return "#" + [16, 8, 0].map(s => channel(s).toString(16).padStart(2, "0")).join("");
+    }
+
+    // The pages set their own titles as they load; the brand's name
+    // follows them.
+    function suffixTitle() {
+        if (!brandName) return;
+        const suffix = " \u2014 " + brandName;
+        if (!document.title.endsWith(suffix)) document.title += suffix;
+    }
+
+    function apply(b) {
+        const root = document.documentElement.style;
+        // Colors left out go back to the stylesheet's
+        if (b.accentColor) {
+            root.setProperty("--accent", b.accentColor);
+            root.setProperty("--accent-hover", darken(b.accentColor, 0.15));
+        } else {
+            root.removeProperty("--accent");
+            root.removeProperty("--accent-hover");
+        }
+        if (b.secondaryColor) root.setProperty("--secondary", b.secondaryColor);
+        else root.removeProperty("--secondary");
+
+        let bar = document.querySelector(".brand-bar");
+        if (bar) bar.remove();
+        if (b.name || b.logoUrl) {
+            bar = document.createElement("a");
+            bar.className = "brand-bar";
+            bar.href = prefix + "/";
+            if (b.logoUrl) {
+                const logo = document.createElement("img");
+                logo.src = b.logoUrl;
+                logo.alt = b.name || "";
+                bar.appendChild(logo);
+            }
+            if (b.name) {
+                const name = document.createElement("span");
+                name.textContent = b.name;
+                bar.appendChild(name);
+            }
+            const container = document.querySelector(".container");
+            container.insertBefore(bar, container.firstChild);
+        }
+
+        if (brandName) document.title = document.title.replace(" \u2014 " + brandName, "");
+        brandName = b.name || "";
+        suffixTitle();
+    }
+
+    // loadBranding fetches and applies the site's branding, or the club's
+    // over it; only the latest call takes effect.
+    window.loadBranding = async function(club) {
+        const call = ++latest;
+        const resp = await fetch(prefix + "/api/branding" + (club ? "?club=" + encodeURIComponent(club) : ""));
+        if (!resp.ok || call !== latest) return;
+        apply(await resp.json());
+    };
+
+    new MutationObserver(suffixTitle).observe(document.querySelector("title"), {childList: true});
+
+    const param = script && script.dataset.clubParam;
+    window.loadBranding(param ? new URLSearchParams(window.location.search).get(param) : "");
+})();
@@ -0,0 +1,233 @@
+// The club lobby: a club's private tournaments, the games of them in
+// play, its leaderboard and its members, as one of them sees it.
+(function() {
+    const prefix = window.tenantPrefix;
+    const params = new URLSearchParams(window.location.search);
+    const id = params.get("id");
+    const player = params.get("player");
+    if (!id || !player) {
+        window.location.href = prefix + "/";
+        return;
+    }
+    document.getElementById("lobby-link").href = prefix + "/";
+
+    const base = prefix + "/api/clubs/" + encodeURIComponent(id);
+    const query = "?playerId=" + encodeURIComponent(player);
+    const errorMsg = document.getElementById("error-msg");
+
+    function showError(msg) {
+        errorMsg.textContent = msg;
+        errorMsg.hidden = false;
+    }
+
+    function cell(row, text) {
+        const el = document.createElement("td");
+        el.textContent = text;
+        row.appendChild(el);
+        return el;
+    }
+
+    function points(n) {
+        return String(Math.floor(n)) + (n % 1 ? "\u00bd" : "");
+    }
+
+    function card(text, meta) {
+        const el = document.createElement("div");
+        el.className = "session-card";
+        const label = document.createElement("span");
+        label.textContent = text;
+        el.appendChild(label);
+        if (meta) {
+            const m = document.createElement("span");
+            m.className = "meta";
+            m.textContent = meta;
+            el.appendChild(m);
+        }
+        return el;
+    }
+
+    function button(title, onClick) {
+        const btn = document.createElement("button");
+        btn.textContent = title;
+        btn.addEventListener("click", onClick);
+        return btn;
+    }
+
+    async function send(method, url, body) {
+        const resp = await fetch(url, {
+            method: method,
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify(body)
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return null; }
+        errorMsg.hidden = true;
+        return data;
+    }
+
+    function renderGames(games) {
+        const list = document.getElementById("games-list");
+        list.innerHTML = "";
+        if (games.length === 0) {
+            list.textContent = "No games in play";
+            return;
+        }
+        games.forEach(g => {
+            const el = card(g.playerA + " \u2013 " + g.playerB, g.tournamentName + ", round " + g.round);
+            const watch = document.createElement("a");
+            watch.className = "btn";
+            watch.textContent = "Watch live";
+            watch.href = prefix + "/session.html?code=" + encodeURIComponent(g.sessionCode) + "&spectate=1";
+            el.appendChild(watch);
+            list.appendChild(el);
+        });
+    }
+
+    function renderTournaments(tournaments) {
+        const list = document.getElementById("tournaments-list");
+        list.innerHTML = "";
+        if (tournaments.length === 0) {
+            list.textContent = "No tournaments";
+            return;
+        }
+        tournaments.forEach(t => {
+            const el = document.createElement("a");
+            el.className = "session-card";
+            el.href = prefix + "/tournament.html?id=" + encodeURIComponent(t.id);
+            const name = document.createElement("span");
+            name.className = "code";
+            name.textContent = t.name;
+            const meta = document.createElement("span");
+            meta.className = "meta";
+            meta.textContent = t.gameType + " \u2014 " + t.format.replace("_", " ") + " \u2014 " +
+                (t.status === "running" ? "round " + t.round + " of " + t.rounds : t.status);
+            el.appendChild(name);
+            el.appendChild(meta);
+            if (t.status === "registering" && !t.players.includes(player)) {
+                el.appendChild(button("Enter", async ev => {
+                    ev.preventDefault();
+                    if (await send("POST", prefix + "/api/tournaments/" + encodeURIComponent(t.id) + "/join", {playerId: player})) load();
+                }));
+            }
+            if (t.status === "registering" && t.organizer === player) {
+                el.appendChild(button("Start", async ev => {
+                    ev.preventDefault();
+                    if (await send("POST", prefix + "/api/tournaments/" + encodeURIComponent(t.id) + "/start", {playerId: player})) load();
+                }));
+            }
+            list.appendChild(el);
+        });
+    }
+
+    function renderLeaderboard(standings) {
+        const body = document.querySelector("#leaderboard tbody");
+        body.innerHTML = "";
+        standings.forEach(s => {
+            const row = document.createElement("tr");
+            cell(row, s.rank);
+            cell(row, s.playerId);
+            cell(row, points(s.score));
+            cell(row, s.wins + "-" + s.draws + "-" + s.losses + (s.byes ? " +" + s.byes + " bye" : ""));
+            cell(row, s.tournaments);
+            body.appendChild(row);
+        });
+    }
+
+    // The owner appoints and demotes admins; the owner and admins remove
+    // members, admins only plain ones.
+    function renderMembers(club, role) {
+        const list = document.getElementById("members-list");
+        list.innerHTML = "";
+        club.members.forEach(m => {
+            const el = card(m.playerId, m.role);
+            const url = base + "/members/" + encodeURIComponent(m.playerId);
+            if (role === "owner" && m.role !== "owner") {
+                const next = m.role === "admin" ? "member" : "admin";
+                el.appendChild(button(next === "admin" ? "Make admin" : "Make member", async () => {
+                    if (await send("PUT", url, {playerId: player, role: next})) load();
+                }));
+            }
+            if (m.playerId !== player && (role === "owner" && m.role !== "owner" || role === "admin" && m.role === "member")) {
+                el.appendChild(button("Remove", async () => {
+                    if (await send("DELETE", url, {playerId: player})) load();
+                }));
+            }
+            list.appendChild(el);
+        });
+    }
+
+    // The owner and admins set how the club's pages look; Reset goes
+    // back to the site's look.
+    function renderBranding(b) {
+        document.getElementById("brand-name").value = b.name || "";
+        document.getElementById("brand-logo").value = b.logoUrl || "";
+        const styles = getComputedStyle(document.documentElement);
+        document.getElementById("brand-accent").value = b.accentColor || styles.getPropertyValue("--accent").trim();
+        document.getElementById("brand-secondary").value = b.secondaryColor || styles.getPropertyValue("--secondary").trim();
+    }
+
+    async function saveBranding(b) {
+        const club = await send("PUT", base + "/branding", Object.assign({playerId: player}, b));
+        if (club) {
+            renderBranding(club.branding || {});
+            window.loadBranding(id);
+        }
+    }
+
+    async function load() {
+        const [lobbyResp, boardResp] = await Promise.all([
+            fetch(base + "/lobby" + query),
+            fetch(base + "/leaderboard" + query)
+        ]);
+        const lobby = await lobbyResp.json();
+        if (!lobbyResp.ok) { showError(lobby.error); return; }
+        const board = await boardResp.json();
+        const club = lobby.club;
+        const me = club.members.find(m => m.playerId === player);
+        const role = me ? me.role : "";
+        document.title = club.name;
+        document.getElementById("club-name").textContent = club.name;
+        document.getElementById("club-role").textContent = role;
+        document.getElementById("organize").hidden = role !== "owner" && role !== "admin";
+        document.getElementById("leave-btn").hidden = role === "owner";
+        document.getElementById("branding").hidden = role !== "owner" && role !== "admin";
+        renderBranding(club.branding || {});
+        renderGames(lobby.games);
+        renderTournaments(lobby.tournaments);
+        if (boardResp.ok) renderLeaderboard(board.standings);
+        renderMembers(club, role);
+    }
+
+    document.getElementById("organize-btn").addEventListener("click", async () => {
+        const name = document.getElementById("tournament-name").value.trim();
+        const gameType = document.getElementById("tournament-game").value.trim();
+        if (!name || !gameType) { showError("Enter the tournament's name and game"); return; }
+        const created = await send("POST", prefix + "/api/tournaments", {
+            name: name,
+            gameType: gameType,
+            format: document.getElementById("tournament-format").value,
+            playerId: player,
+            club: id
+        });
+        if (created) load();
+    });
+
+    document.getElementById("brand-save-btn").addEventListener("click", () => {
+        saveBranding({
+            name: document.getElementById("brand-name").value.trim(),
+            logoUrl: document.getElementById("brand-logo").value.trim(),
+            accentColor: document.getElementById("brand-accent").value,
+            secondaryColor: document.getElementById("brand-secondary").value
+        });
+    });
+
+    document.getElementById("brand-reset-btn").addEventListener("click", () => saveBranding({}));
+
+    document.getElementById("leave-btn").addEventListener("click", async () => {
+        if (await send("DELETE", base + "/members/" + encodeURIComponent(player), {playerId: player})) {
+            window.location.href = prefix + "/";
+        }
+    });
+
+    load();
+})();
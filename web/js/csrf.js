@@ -0,0 +1,17 @@
+// Adds the CSRF token from the csrf cookie to every request a page makes
+// that changes state, as the server requires of requests carrying the
+// guest cookie. Shared by the lobby, session and club pages.
+(function() {
+    const send = window.fetch;
+    window.fetch = function(input, init) {
+        init = init || {};
+        const method = (init.method || "GET").toUpperCase();
+        const token = (document.cookie.match(/(?:^|;\s*)csrf=([^;]*)/) || [])[1];
+        if (token && !["GET", "HEAD", "OPTIONS"].includes(method)) {
+            const headers = new Headers(init.headers);
+            headers.set("X-CSRF-Token", decodeURIComponent(token));
+            init = Object.assign({}, init, {headers: headers});
+        }
+        return send.call(this, input, init);
+    };
+})();
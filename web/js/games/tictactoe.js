@@ -2,12 +2,29 @@ window.TicTacToeRenderer = (function() {
     let boardEl = null;
     let onAction = null;
     const marks = ["", "X", "O"];
+    // Cells to animate on the next render, from the move's events.
+    let placed = -1;
+    let line = [];
+    // Ticks the running player's clock down between states.
+    let clockTimer = null;
+
+    /** @param {import("../types").Event[]} list */
+    function events(list) {
+        list.forEach(e => {
+            if (e.type === "placed") placed = e.data.cell;
+            if (e.type === "line") line = e.data.cells;
+        });
+    }
 
     function init(container, actionCallback) {
         boardEl = container;
         onAction = actionCallback;
     }
 
+    /**
+     * @param {import("../types").TictactoeState} state
+     * @param {import("../types").Action[]} validActions
+     */
     function render(state, validActions) {
         boardEl.innerHTML = "";
         const grid = document.createElement("div");
@@ -27,6 +44,8 @@ window.TicTacToeRenderer = (function() {
             cell.textContent = marks[val];
             if (val === 1) cell.classList.add("x");
             if (val === 2) cell.classList.add("o");
+            if (i === placed) cell.classList.add("placed");
+            if (line.includes(i)) cell.classList.add("line");
 
             if (validCells.has(i)) {
                 cell.addEventListener("click", () => {
@@ -38,7 +57,53 @@ window.TicTacToeRenderer = (function() {
             grid.appendChild(cell);
         }
         boardEl.appendChild(grid);
+        placed = -1;
+        if (state.done === false) line = [];
+        clearInterval(clockTimer);
+        if (state.clock) renderClock(state);
+        if (state.misere) {
+            const note = document.createElement("p");
+            note.className = "ttt-note";
+            note.textContent = "Misère: three in a row loses.";
+            boardEl.appendChild(note);
+        }
+    }
+
+    /** @param {import("../types").TictactoeState} state */
+    function renderClock(state) {
+        const clock = state.clock;
+        const el = document.createElement("div");
+        el.className = "ttt-clock";
+        const faces = state.players.map((id, i) => {
+            const face = document.createElement("span");
+            face.className = "ttt-clock-face";
+            if (id === clock.running) face.classList.add("running");
+            if (id === clock.flagged) face.classList.add("flagged");
+            el.appendChild(face);
+            return () => {
+                let left = clock.banks[id];
+                if (id === clock.running) left -= serverNow() - clock.since;
+                face.textContent = marks[i + 1] + " " + formatClock(Math.max(left, 0));
+            };
+        });
+        const tick = () => faces.forEach(f => f());
+        tick();
+        if (clock.running) clockTimer = setInterval(tick, 100);
+        if (clock.increment) {
+            const inc = document.createElement("span");
+            inc.className = "ttt-note";
+            inc.textContent = "+" + clock.increment / 1000 + "s a move";
+            el.appendChild(inc);
+        }
+        boardEl.appendChild(el);
+    }
+
+    // formatClock shows milliseconds as m:ss, with tenths under ten seconds.
+    function formatClock(ms) {
+        if (ms < 10000) return (ms / 1000).toFixed(1);
+        const s = Math.ceil(ms / 1000);
+        return Math.floor(s / 60) + ":" + String(s % 60).padStart(2, "0");
     }
 
-    return {init, render};
+    return {init, render, events};
 })();
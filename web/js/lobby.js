@@ -1,8 +1,14 @@
 (function() {
+    const prefix = window.tenantPrefix;
+    // Dates and times follow the language the server rendered the page
+    // in, in the browser's region for it where it has one.
+    const lang = document.documentElement.lang;
+    const locale = (navigator.languages || []).find(l => l.split("-")[0] === lang) || lang || undefined;
     const gameSelect = document.getElementById("game-select");
     const createBtn = document.getElementById("create-btn");
     const joinBtn = document.getElementById("join-btn");
     const errorMsg = document.getElementById("error-msg");
+    let games = [];
 
     function showError(msg) {
         errorMsg.textContent = msg;
@@ -11,8 +17,8 @@
     }
 
     async function loadGames() {
-        const resp = await fetch("/api/games");
-        const games = await resp.json();
+        const resp = await fetch(prefix + "/api/games");
+        games = await resp.json();
         gameSelect.innerHTML = "";
         games.forEach(g => {
             const opt = document.createElement("option");
@@ -20,22 +26,152 @@
             opt.textContent = g.name + " (" + g.minPlayers + "-" + g.maxPlayers + " players)";
             gameSelect.appendChild(opt);
         });
+        renderVariants();
+        renderOptions();
+        loadStats();
+        loadPracticeOpponents();
+    }
+
+    document.getElementById("variant-select").addEventListener("change", renderOptions);
+
+    // loadStats shows how many matches of the selected game have been
+    // played and how long they usually take.
+    async function loadStats() {
+        const el = document.getElementById("game-stats");
+        const name = gameSelect.value;
+        el.hidden = true;
+        if (!name) return;
+        const resp = await fetch(prefix + "/api/games/" + encodeURIComponent(name) + "/stats");
+        if (!resp.ok || gameSelect.value !== name) return;
+        const stats = await resp.json();
+        if (stats.matches === 0) return;
+        const seconds = Math.round(stats.avgDurationMs / 1000);
+        el.textContent = stats.matches + " matches played, averaging " +
+            (seconds >= 60 ? Math.floor(seconds / 60) + "m " : "") + (seconds % 60) + "s and " +
+            Math.round(stats.avgMoves) + " moves";
+        el.hidden = false;
     }
 
+    // loadPracticeOpponents offers the selected game's bots to practise
+    // against, besides playing both sides.
+    async function loadPracticeOpponents() {
+        const select = document.getElementById("practice-opponent");
+        const name = gameSelect.value;
+        select.length = 1;
+        if (!name) return;
+        const resp = await fetch(prefix + "/api/games/" + encodeURIComponent(name) + "/bots");
+        if (!resp.ok || gameSelect.value !== name) return;
+        const bots = await resp.json();
+        bots.forEach(b => {
+            const opt = document.createElement("option");
+            opt.value = b.name;
+            opt.textContent = "Against " + b.name;
+            select.appendChild(opt);
+        });
+    }
+
+    // renderVariants offers the selected game's variants, grouped under it
+    // rather than listed as games of their own.
+    function renderVariants() {
+        const select = document.getElementById("variant-select");
+        const game = games.find(g => g.name === gameSelect.value);
+        const variants = (game && game.variants) || [];
+        select.innerHTML = "";
+        const standard = document.createElement("option");
+        standard.value = "";
+        standard.textContent = "standard rules";
+        select.appendChild(standard);
+        variants.forEach(v => {
+            const opt = document.createElement("option");
+            opt.value = v.name;
+            opt.textContent = v.name;
+            opt.title = v.description || "";
+            select.appendChild(opt);
+        });
+        select.hidden = variants.length === 0;
+    }
+
+    // renderOptions shows an input per option of the selected game: a
+    // checkbox for 0/1 flags, a number field otherwise. Options the chosen
+    // variant fixes are shown at its values and cannot be changed.
+    function renderOptions() {
+        const container = document.getElementById("game-options");
+        container.innerHTML = "";
+        const game = games.find(g => g.name === gameSelect.value);
+        const variantName = document.getElementById("variant-select").value;
+        const variant = ((game && game.variants) || []).find(v => v.name === variantName);
+        const fixed = (variant && variant.options) || {};
+        (game && game.options || []).forEach(o => {
+            if (o.name === "variant") return; // chosen with the variant select
+            const label = document.createElement("label");
+            label.title = o.description || "";
+            const input = document.createElement("input");
+            input.dataset.option = o.name;
+            if (o.min === 0 && o.max === 1) {
+                input.type = "checkbox";
+                input.checked = o.default === 1;
+                label.append(input, " " + (o.description || o.name));
+            } else {
+                input.type = "number";
+                input.min = o.min;
+                input.max = o.max;
+                input.value = o.default;
+                label.append((o.description || o.name) + " ", input);
+            }
+            if (o.name in fixed) {
+                input.checked = fixed[o.name] === 1;
+                input.value = fixed[o.name];
+            }
+            input.disabled = o.min === o.max || o.name in fixed;
+            container.appendChild(label);
+        });
+    }
+
+    function chosenOptions() {
+        const options = {};
+        document.querySelectorAll("#game-options input").forEach(input => {
+            options[input.dataset.option] = input.type === "checkbox" ? (input.checked ? 1 : 0) : Number(input.value);
+        });
+        // The variant option counts from 1, after the standard rules
+        const variant = document.getElementById("variant-select");
+        if (variant.selectedIndex > 0) options.variant = variant.selectedIndex;
+        return options;
+    }
+
+    gameSelect.addEventListener("change", () => {
+        renderVariants();
+        renderOptions();
+        loadStats();
+        loadPracticeOpponents();
+    });
+
     createBtn.addEventListener("click", async () => {
         const name = document.getElementById("player-name").value.trim();
         const gameType = gameSelect.value;
         if (!name) { showError("Enter your name"); return; }
 
-        const resp = await fetch("/api/sessions", {
+        const party = document.getElementById("party-games").value
+            .split(",").map(g => g.trim()).filter(g => g);
+
+        const resp = await fetch(prefix + "/api/sessions", {
             method: "POST",
             headers: {"Content-Type": "application/json"},
-            body: JSON.stringify({gameType: gameType, playerId: name})
+            body: JSON.stringify({
+                gameType: gameType,
+                playerId: name,
+                private: document.getElementById("private-check").checked,
+                turnOrder: document.getElementById("random-order-check").checked ? "random" : undefined,
+                options: party.length ? undefined : chosenOptions(),
+                party: party.length ? party : undefined
+            })
         });
         const data = await resp.json();
         if (!resp.ok) { showError(data.error); return; }
 
-        window.location.href = "/session.html?code=" + data.code + "&player=" + encodeURIComponent(name);
+        // The host's seat is claimed on creation; the session page joins
+        // with its secret.
+        localStorage.setItem("seat-token:" + data.code, data.token);
+        window.location.href = prefix + "/session.html?code=" + data.code + "&player=" + encodeURIComponent(name);
     });
 
     joinBtn.addEventListener("click", () => {
@@ -44,8 +180,617 @@
         if (!name) { showError("Enter your name"); return; }
         if (!code) { showError("Enter session code"); return; }
 
-        window.location.href = "/session.html?code=" + code + "&player=" + encodeURIComponent(name);
+        window.location.href = prefix + "/session.html?code=" + code + "&player=" + encodeURIComponent(name);
+    });
+
+    // A handoff code continues a seat from another device; the session code
+    // comes from the join form.
+    document.getElementById("handoff-btn").addEventListener("click", () => {
+        const code = document.getElementById("join-code").value.trim();
+        const handoff = document.getElementById("handoff-code").value.trim();
+        if (!code || !handoff) { showError("Enter the session code and the handoff code"); return; }
+        window.location.href = prefix + "/session.html?code=" + encodeURIComponent(code) + "&handoff=" + encodeURIComponent(handoff);
+    });
+
+    document.getElementById("practice-btn").addEventListener("click", async () => {
+        const name = document.getElementById("player-name").value.trim();
+        if (!name) { showError("Enter your name"); return; }
+        const resp = await fetch(prefix + "/api/practice", {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({
+                gameType: gameSelect.value,
+                playerId: name,
+                opponent: document.getElementById("practice-opponent").value || undefined,
+                options: chosenOptions()
+            })
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+
+        localStorage.setItem("seat-token:" + data.code, data.token);
+        window.location.href = prefix + "/session.html?code=" + data.code + "&player=" + encodeURIComponent(name);
+    });
+
+    document.getElementById("exhibition-btn").addEventListener("click", async () => {
+        const gameType = gameSelect.value;
+        const game = games.find(g => g.name === gameType);
+        const botsResp = await fetch(prefix + "/api/games/" + encodeURIComponent(gameType) + "/bots");
+        const bots = await botsResp.json();
+        if (!game || !bots.length) { showError("No bots available for " + gameType); return; }
+
+        // Pit the hardest bot against the easiest, round-robin across seats
+        const seats = [];
+        for (let i = 0; i < game.minPlayers; i++) {
+            seats.push(i % 2 === 0 ? bots[bots.length - 1].name : bots[0].name);
+        }
+        const resp = await fetch(prefix + "/api/exhibitions", {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({gameType: gameType, bots: seats})
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+
+        window.location.href = prefix + "/session.html?code=" + data.code + "&spectate=1";
+    });
+
+    // --- Challenges ---
+
+    const challengesList = document.getElementById("challenges-list");
+    let challengeFeed = null;
+
+    function goToSession(code, name) {
+        window.location.href = prefix + "/session.html?code=" + code + "&player=" + encodeURIComponent(name);
+    }
+
+    async function answerChallenge(id, name, answer) {
+        const resp = await fetch(prefix + "/api/challenges/" + id + "/" + answer, {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({playerId: name})
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+        if (answer === "accept") {
+            // Accepting claims our seat in the new match, as creating does.
+            localStorage.setItem("seat-token:" + data.code, data.token);
+            goToSession(data.code, name);
+            return;
+        }
+        loadChallenges(name);
+    }
+
+    async function loadChallenges(name) {
+        const resp = await fetch(prefix + "/api/challenges?player=" + encodeURIComponent(name));
+        const list = await resp.json();
+        if (!resp.ok) { showError(list.error); return; }
+        challengesList.innerHTML = "";
+        list.forEach(c => {
+            const row = document.createElement("div");
+            row.className = "session-card";
+            const text = document.createElement("span");
+            const incoming = c.targetId === name;
+            text.textContent = incoming
+                ? c.challengerId + " challenges you to " + c.gameType
+                : "Waiting for " + c.targetId + " (" + c.gameType + ")";
+            row.appendChild(text);
+            const actions = document.createElement("span");
+            if (incoming) {
+                const accept = document.createElement("button");
+                accept.textContent = "Accept";
+                accept.addEventListener("click", () => answerChallenge(c.id, name, "accept"));
+                actions.appendChild(accept);
+            }
+            const decline = document.createElement("button");
+            decline.textContent = incoming ? "Decline" : "Withdraw";
+            decline.addEventListener("click", () => answerChallenge(c.id, name, "decline"));
+            actions.appendChild(decline);
+            row.appendChild(actions);
+            challengesList.appendChild(row);
+        });
+    }
+
+    // watchChallenges follows challenges addressed to name, jumping into the
+    // match when the opponent accepts.
+    function watchChallenges(name) {
+        if (challengeFeed) challengeFeed.close();
+        challengeFeed = new EventSource(prefix + "/api/feed?player=" + encodeURIComponent(name) +
+            "&types=challenge_issued,challenge_accepted,challenge_declined,friend_requested,friend_accepted");
+        challengeFeed.addEventListener("friend_requested", () => loadFriends(name));
+        challengeFeed.addEventListener("friend_accepted", () => loadFriends(name));
+        challengeFeed.addEventListener("challenge_issued", () => loadChallenges(name));
+        challengeFeed.addEventListener("challenge_declined", () => loadChallenges(name));
+        challengeFeed.addEventListener("challenge_accepted", ev => {
+            const e = JSON.parse(ev.data);
+            goToSession(e.sessionCode, name);
+        });
+        loadChallenges(name);
+        loadFriends(name);
+    }
+
+    // Challenges are issued, answered and followed as this browser's guest,
+    // the only player the server lets it act for.
+    async function issueChallenge(target, gameType) {
+        const name = await guest;
+        const resp = await fetch(prefix + "/api/challenges", {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({gameType: gameType, challengerId: name, targetId: target})
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+        watchChallenges(name);
+    }
+
+    document.getElementById("challenge-btn").addEventListener("click", () => {
+        const target = document.getElementById("challenge-target").value.trim();
+        if (!target) { showError("Enter your opponent's ID"); return; }
+
+        issueChallenge(target, gameSelect.value);
+    });
+
+    document.getElementById("inbox-btn").addEventListener("click", async () => {
+        watchChallenges(await guest);
+    });
+
+    // Notifications are for this browser's guest, the only player the
+    // server lets it subscribe for.
+    document.getElementById("notify-btn").addEventListener("click", async () => {
+        const err = await window.enablePush(await guest);
+        if (err) showError(err);
+    });
+
+    // The address belongs to this browser's guest, the only caller the
+    // server lets set it.
+    document.getElementById("email-btn").addEventListener("click", async () => {
+        const email = document.getElementById("email-input").value.trim();
+        if (!email) { showError("Enter your email"); return; }
+
+        const id = await guest;
+        const resp = await fetch(prefix + "/api/players/" + encodeURIComponent(id) + "/email", {
+            method: "PUT",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({email: email})
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+        showError("Check your inbox to confirm " + email);
+    });
+
+    // --- Friends ---
+
+    const friendsList = document.getElementById("friends-list");
+    const recentList = document.getElementById("recent-list");
+
+    function playerRow(text, buttons) {
+        const row = document.createElement("div");
+        row.className = "session-card";
+        const label = document.createElement("span");
+        label.textContent = text;
+        row.appendChild(label);
+        const actions = document.createElement("span");
+        buttons.forEach(([title, onClick]) => {
+            const btn = document.createElement("button");
+            btn.textContent = title;
+            btn.addEventListener("click", onClick);
+            actions.appendChild(btn);
+        });
+        row.appendChild(actions);
+        return row;
+    }
+
+    async function friendRequest(method, url) {
+        const resp = await fetch(url, {
+            method: method,
+            headers: {"Content-Type": "application/json"}
+        });
+        if (!resp.ok) { showError((await resp.json()).error); }
+    }
+
+    async function loadFriends(name) {
+        const base = prefix + "/api/players/" + encodeURIComponent(name);
+        const [friendsResp, recentResp] = await Promise.all([
+            fetch(base + "/friends"),
+            fetch(base + "/recent")
+        ]);
+        const friends = await friendsResp.json();
+        const recent = await recentResp.json();
+
+        friendsList.innerHTML = "";
+        friends.incoming.forEach(id => {
+            friendsList.appendChild(playerRow(id + " wants to be friends", [
+                ["Accept", async () => {
+                    await friendRequest("POST", base + "/friends/" + encodeURIComponent(id) + "/accept");
+                    loadFriends(name);
+                }],
+                ["Decline", async () => {
+                    await friendRequest("DELETE", base + "/friends/" + encodeURIComponent(id));
+                    loadFriends(name);
+                }]
+            ]));
+        });
+        friends.friends.forEach(f => {
+            friendsList.appendChild(playerRow(f.playerId + (f.online ? " (online)" : ""), [
+                ["Challenge", () => issueChallenge(name, f.playerId, gameSelect.value)]
+            ]));
+        });
+        friends.outgoing.forEach(id => {
+            friendsList.appendChild(playerRow("Request sent to " + id, []));
+        });
+
+        recentList.innerHTML = "";
+        recent.forEach(o => {
+            recentList.appendChild(playerRow(o.playerId + " \u2014 " + o.gameType + (o.online ? " (online)" : ""), [
+                ["Rematch", () => issueChallenge(name, o.playerId, o.gameType)]
+            ]));
+        });
+    }
+
+    // --- My games ---
+
+    const myGamesList = document.getElementById("my-games-list");
+    const myTurns = document.getElementById("my-turns");
+    let turnsFeed = null;
+
+    // The guest ID this browser sits in games as, from its guest cookie
+    const guest = fetch(prefix + "/api/guest").then(resp => resp.json()).then(g => g.playerId);
+
+    // watchTurns keeps the count of games waiting on this browser's move
+    // current, refreshing the list whenever it changes.
+    async function watchTurns() {
+        const id = await guest;
+        if (turnsFeed) turnsFeed.close();
+        turnsFeed = new EventSource(prefix + "/api/players/" + encodeURIComponent(id) + "/turns/stream");
+        turnsFeed.addEventListener("turns", ev => {
+            const turns = JSON.parse(ev.data);
+            myTurns.textContent = turns.count ? turns.count : "";
+            loadMyGames();
+        });
+    }
+
+    async function loadMyGames() {
+        const id = await guest;
+        const resp = await fetch(prefix + "/api/players/" + encodeURIComponent(id) + "/sessions");
+        if (!resp.ok) return;
+        const games = await resp.json();
+        myGamesList.innerHTML = "";
+        if (games.length === 0) {
+            myGamesList.textContent = "No games under way";
+            return;
+        }
+        games.forEach(g => {
+            const names = g.names || {};
+            const name = names[id] || id;
+            const others = (g.players || []).filter(p => p !== id).map(p => names[p] || p);
+            let text = g.gameType + " \u2014 " + g.code;
+            if (others.length) text += " with " + others.join(", ");
+            text += g.status === "waiting" ? " (waiting)" : g.yourTurn ? " (your turn)" : "";
+            const row = playerRow(text, [
+                ["Open", () => goToSession(g.code, name)]
+            ]);
+            if (g.status !== "waiting") {
+                // Games that can't be drawn answer 404; leave them without
+                const img = document.createElement("img");
+                img.className = "board-picture";
+                img.alt = "";
+                img.src = prefix + "/api/sessions/" + encodeURIComponent(g.code) + "/picture";
+                img.addEventListener("error", () => img.remove());
+                row.prepend(img);
+            }
+            myGamesList.appendChild(row);
+        });
+    }
+
+    // --- While you were away ---
+
+    // loadAway shows what this browser's guest missed while it had no game
+    // open, then clears what it showed, so each message shows once.
+    async function loadAway() {
+        const id = await guest;
+        const inbox = prefix + "/api/players/" + encodeURIComponent(id) + "/inbox";
+        const resp = await fetch(inbox);
+        if (!resp.ok) return;
+        const messages = (await resp.json()).messages;
+        const list = document.getElementById("away-list");
+        messages.forEach(m => {
+            const text = new Date(m.at).toLocaleString(locale) + " \u2014 " + describeAway(m, id);
+            list.appendChild(playerRow(text, m.type === "your_turn" ? [["Open", () => goToSession(m.sessionCode, id)]] : []));
+        });
+        document.getElementById("away").hidden = messages.length === 0;
+        if (messages.length > 0) {
+            fetch(inbox + "?through=" + messages[messages.length - 1].id, { method: "DELETE" });
+        }
+    }
+
+    function describeAway(m, id) {
+        const others = (m.players || []).filter(p => p !== id).join(", ");
+        switch (m.type) {
+            case "your_turn":
+                return "Your move in " + m.gameType + " " + m.sessionCode;
+            case "challenge_issued":
+                return others + " challenged you to " + m.gameType;
+            default:
+                return describeEvent(m);
+        }
+    }
+
+    // Friends are added as this browser's guest, the only player the
+    // server lets it act for.
+    document.getElementById("add-friend-btn").addEventListener("click", async () => {
+        const friend = document.getElementById("friend-id").value.trim();
+        if (!friend) { showError("Enter your friend's ID"); return; }
+        const name = await guest;
+
+        const resp = await fetch(prefix + "/api/players/" + encodeURIComponent(name) + "/friends", {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({friendId: friend})
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+        watchChallenges(name);
+    });
+
+    // --- Activity feed ---
+
+    const sessionsList = document.getElementById("sessions-list");
+    const activityList = document.getElementById("activity-list");
+    const sessions = new Map();
+    const thumbnails = new Map(); // session code to its game's thumbnail
+    const maxActivity = 20;
+    const maxThumbnails = 50;
+
+    function renderSessions() {
+        sessionsList.innerHTML = "";
+        if (sessions.size === 0) {
+            sessionsList.textContent = "No active sessions";
+            return;
+        }
+        sessions.forEach(s => {
+            const card = document.createElement("div");
+            card.className = "session-card";
+            const code = document.createElement("span");
+            code.className = "code";
+            code.textContent = s.code;
+            const meta = document.createElement("span");
+            meta.className = "meta";
+            meta.textContent = s.gameType + (s.variant ? " (" + s.variant + ")" : "") + " \u2014 " + s.status + " \u2014 " + (s.players || []).join(", ");
+            if (s.status === "waiting" && s.openSeats !== undefined) {
+                meta.textContent += " \u2014 " + (s.openSeats ? s.openSeats + " open" : "full");
+            }
+            card.appendChild(code);
+            card.appendChild(meta);
+            if (s.newerRules) {
+                const rules = document.createElement("span");
+                rules.className = "rules";
+                rules.textContent = "older rules (" + s.rulesVersion + ")";
+                rules.title = "Started under rules " + s.rulesVersion + "; new games play by " + s.newerRules;
+                card.appendChild(rules);
+            }
+            if (s.createdAt) {
+                const created = document.createElement("span");
+                created.className = "meta";
+                created.textContent = "created " + timeAgo(s.createdAt);
+                created.title = new Date(s.createdAt).toLocaleString(locale);
+                card.appendChild(created);
+            }
+            const mini = thumbnailElement(thumbnails.get(s.code));
+            if (mini) card.appendChild(mini);
+            card.addEventListener("click", () => {
+                document.getElementById("join-code").value = s.code;
+            });
+            sessionsList.appendChild(card);
+        });
+    }
+
+    // thumbnailElement draws a game's text thumbnail, such as tic-tac-toe's
+    // nine cells, as a square when it has a square number of characters.
+    function thumbnailElement(state) {
+        if (typeof state !== "string" || state === "") return null;
+        const el = document.createElement("pre");
+        el.className = "thumbnail";
+        const side = Math.round(Math.sqrt(state.length));
+        el.textContent = side * side === state.length
+            ? state.match(new RegExp(".{" + side + "}", "g")).join("\n")
+            : state;
+        return el;
+    }
+
+    // loadThumbnails fetches the mini-boards of the matches being played,
+    // all in one request.
+    async function loadThumbnails() {
+        const codes = [...sessions.values()].filter(s => s.status === "playing").map(s => s.code).slice(0, maxThumbnails);
+        if (codes.length === 0) return;
+        const resp = await fetch(prefix + "/api/sessions/states?codes=" + codes.map(encodeURIComponent).join(","));
+        if (!resp.ok) return;
+        thumbnails.clear();
+        (await resp.json()).forEach(t => { if (t.state !== undefined) thumbnails.set(t.code, t.state); });
+        renderSessions();
+    }
+
+    // timeAgo renders an RFC 3339 time relative to now in the browser's
+    // language, e.g. "5 minutes ago".
+    const relative = new Intl.RelativeTimeFormat(undefined, {numeric: "auto"});
+    function timeAgo(iso) {
+        const seconds = Math.round((new Date(iso) - Date.now()) / 1000);
+        const units = [["day", 86400], ["hour", 3600], ["minute", 60]];
+        for (const [unit, size] of units) {
+            if (Math.abs(seconds) >= size) {
+                return relative.format(Math.round(seconds / size), unit);
+            }
+        }
+        return relative.format(0, "second");
+    }
+
+    function describeEvent(e) {
+        const players = (e.players || []).join(", ");
+        switch (e.type) {
+            case "session_created":
+                return players + " opened a " + e.gameType + " session";
+            case "match_started":
+                return e.gameType + " started: " + players;
+            case "match_finished": {
+                const winners = (e.results || []).filter(r => r.rank === 1).map(r => r.playerId);
+                return e.gameType + " finished" + (winners.length ? " \u2014 won by " + winners.join(", ") : "");
+            }
+            case "tournament_round":
+                return "A " + e.gameType + " tournament started a round";
+            case "tournament_finished":
+                return "A " + e.gameType + " tournament finished \u2014 won by " + players;
+            default:
+                return e.type;
+        }
+    }
+
+    // Each tournament links to its hub, where its games can be watched.
+    async function loadTournaments() {
+        const resp = await fetch(prefix + "/api/tournaments");
+        if (!resp.ok) return;
+        const data = await resp.json();
+        const list = document.getElementById("tournaments-list");
+        list.innerHTML = "";
+        if (data.tournaments.length === 0) {
+            list.textContent = "No tournaments";
+            return;
+        }
+        data.tournaments.forEach(t => {
+            const card = document.createElement("a");
+            card.className = "session-card";
+            card.href = prefix + "/tournament.html?id=" + encodeURIComponent(t.id);
+            const name = document.createElement("span");
+            name.className = "code";
+            name.textContent = t.name;
+            const meta = document.createElement("span");
+            meta.className = "meta";
+            meta.textContent = t.gameType + " \u2014 " + t.format.replace("_", " ") + " \u2014 " +
+                (t.status === "running" ? "round " + t.round + " of " + t.rounds : t.status);
+            card.appendChild(name);
+            card.appendChild(meta);
+            list.appendChild(card);
+        });
+    }
+
+    // Clubs link to their lobby, which shows members their private
+    // tournaments and leaderboard.
+    async function loadClubs() {
+        const resp = await fetch(prefix + "/api/clubs");
+        if (!resp.ok) return;
+        const data = await resp.json();
+        const list = document.getElementById("clubs-list");
+        list.innerHTML = "";
+        if (data.clubs.length === 0) {
+            list.textContent = "No clubs";
+            return;
+        }
+        data.clubs.forEach(c => {
+            const members = c.memberCount + (c.memberCount === 1 ? " member" : " members");
+            list.appendChild(playerRow(c.name + " \u2014 " + members, [
+                ["Join", async () => {
+                    const id = await guest;
+                    const resp = await fetch(prefix + "/api/clubs/" + encodeURIComponent(c.id) + "/join", {
+                        method: "POST",
+                        headers: {"Content-Type": "application/json"},
+                        body: JSON.stringify({playerId: id})
+                    });
+                    if (!resp.ok) { showError((await resp.json()).error); return; }
+                    goToClub(c.id, id);
+                }],
+                ["Open", async () => goToClub(c.id, await guest)]
+            ]));
+        });
+    }
+
+    // Clubs are joined and run as this browser's guest, the only player
+    // the server lets it act for.
+    function goToClub(id, player) {
+        window.location.href = prefix + "/club.html?id=" + encodeURIComponent(id) + "&player=" + encodeURIComponent(player);
+    }
+
+    document.getElementById("create-club-btn").addEventListener("click", async () => {
+        const club = document.getElementById("club-name").value.trim();
+        if (!club) { showError("Enter the club's name"); return; }
+        const id = await guest;
+        const resp = await fetch(prefix + "/api/clubs", {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({name: club, playerId: id})
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+        goToClub(data.id, id);
     });
 
+    function addActivity(e) {
+        const row = document.createElement("div");
+        row.className = "session-card";
+        const text = document.createElement("span");
+        text.textContent = describeEvent(e);
+        const at = document.createElement("span");
+        at.className = "meta";
+        at.textContent = new Date(e.at).toLocaleTimeString(locale);
+        row.appendChild(text);
+        row.appendChild(at);
+        activityList.prepend(row);
+        while (activityList.children.length > maxActivity) {
+            activityList.lastChild.remove();
+        }
+    }
+
+    function applyEvent(e) {
+        switch (e.type) {
+            case "session_created":
+                sessions.set(e.sessionCode, {code: e.sessionCode, gameType: e.gameType, status: "waiting",
+                    players: e.players, createdAt: e.at});
+                break;
+            case "match_started": {
+                const prev = sessions.get(e.sessionCode) || {};
+                sessions.set(e.sessionCode, {code: e.sessionCode, gameType: e.gameType, status: "playing",
+                    players: e.players, createdAt: prev.createdAt});
+                break;
+            }
+            case "match_finished":
+                sessions.delete(e.sessionCode);
+                break;
+            case "tournament_round":
+            case "tournament_finished":
+                loadTournaments();
+                break;
+        }
+        renderSessions();
+        addActivity(e);
+    }
+
+    async function loadFeed() {
+        const resp = await fetch(prefix + "/api/feed/snapshot");
+        const snap = await resp.json();
+        snap.sessions.forEach(s => sessions.set(s.code, s));
+        renderSessions();
+        snap.events.forEach(addActivity);
+
+        setInterval(renderSessions, 30000); // keep "created ... ago" current
+        loadThumbnails();
+        setInterval(loadThumbnails, 10000);
+
+        const feed = new EventSource(prefix + "/api/feed");
+        ["session_created", "match_started", "match_finished", "tournament_round", "tournament_finished"].forEach(type => {
+            feed.addEventListener(type, ev => applyEvent(JSON.parse(ev.data)));
+        });
+    }
+
+    // Friendly share links: /s/<code> opens the lobby with the code filled in.
+    function routeFromPath() {
+        const match = window.location.pathname.slice(prefix.length).match(/^\/s\/([^\/]+)\/?$/);
+        if (!match) return;
+        document.getElementById("join-code").value = decodeURIComponent(match[1]);
+        document.getElementById("join-name").focus();
+    }
+
+    routeFromPath();
     loadGames();
+    loadFeed();
+    loadTournaments();
+    loadClubs();
+    watchTurns();
+    loadAway();
 })();
@@ -0,0 +1,36 @@
+// Web Push opt-in shared by the lobby and session pages.
+(function() {
+    const prefix = window.tenantPrefix;
+    function urlBase64ToUint8Array(base64) {
+        const padded = base64 + "=".repeat((4 - base64.length % 4) % 4);
+        const raw = atob(padded.replace(/-/g, "+").replace(/_/g, "/"));
+        return Uint8Array.from(raw, c => c.charCodeAt(0));
+    }
+
+    // enablePush subscribes this browser to notifications for playerId.
+    // It resolves to an error message, or null on success.
+    window.enablePush = async function(playerId) {
+        if (!("serviceWorker" in navigator) || !("PushManager" in window)) {
+            return "This browser does not support notifications";
+        }
+        const keyResp = await fetch(prefix + "/api/push/key");
+        if (!keyResp.ok) return "Notifications are not enabled on this server";
+        const {publicKey} = await keyResp.json();
+
+        if (await Notification.requestPermission() !== "granted") {
+            return "Notification permission denied";
+        }
+        const registration = await navigator.serviceWorker.register("/sw.js");
+        const subscription = await registration.pushManager.subscribe({
+            userVisibleOnly: true,
+            applicationServerKey: urlBase64ToUint8Array(publicKey)
+        });
+        const resp = await fetch(prefix + "/api/push/subscriptions", {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({playerId: playerId, subscription: subscription.toJSON()})
+        });
+        if (!resp.ok) return (await resp.json()).error;
+        return null;
+    };
+})();
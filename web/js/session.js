@@ -1,19 +1,50 @@
+// Message types are declared in types.d.ts, generated from the server's
+// Go types with go generate.
+/** @typedef {import("./types").ServerMessage} ServerMessage */
+/** @typedef {import("./types").StatePayload} StatePayload */
+
 (function() {
+    const prefix = window.tenantPrefix;
+    // Dates and times follow the language the server rendered the page
+    // in, in the browser's region for it where it has one.
+    const lang = document.documentElement.lang;
+    const locale = (navigator.languages || []).find(l => l.split("-")[0] === lang) || lang || undefined;
     const params = new URLSearchParams(window.location.search);
     const code = params.get("code");
-    const playerID = params.get("player");
+    const spectating = params.get("spectate") === "1";
+    let playerID = spectating ? "spectator-" + Math.random().toString(36).slice(2, 8) : params.get("player");
+    // A handoff code from another device takes over that device's seat.
+    let pendingHandoff = spectating ? null : params.get("handoff");
+    // The seat's secret outlives the tab, so closing and reopening the page
+    // reconnects to the seat.
+    const tokenKey = "seat-token:" + code;
+    let seatToken = localStorage.getItem(tokenKey);
 
-    if (!code || !playerID) {
-        window.location.href = "/";
+    if (!code || (!playerID && !pendingHandoff)) {
+        window.location.href = prefix + "/";
         return;
     }
 
     document.getElementById("session-code").textContent = code;
+    document.getElementById("lobby-link").href = prefix + "/";
+    const shareLink = document.getElementById("share-link");
+    shareLink.href = prefix + "/s/" + encodeURIComponent(code);
+    shareLink.textContent = window.location.host + shareLink.getAttribute("href");
+    const shareQR = document.getElementById("share-qr");
+    document.getElementById("qr-btn").addEventListener("click", () => {
+        if (!shareQR.src) shareQR.src = prefix + "/api/sessions/" + encodeURIComponent(code) + "/qr";
+        shareQR.hidden = !shareQR.hidden;
+    });
 
     const errorMsg = document.getElementById("error-msg");
+    const toast = document.getElementById("toast");
     const startBtn = document.getElementById("start-btn");
+    const botControls = document.getElementById("bot-controls");
+    const botSelect = document.getElementById("bot-select");
     const gameArea = document.getElementById("game-area");
     const resultsDiv = document.getElementById("results");
+    const nextGameBtn = document.getElementById("next-game-btn");
+    const rematchBtn = document.getElementById("rematch-btn");
 
     function showError(msg) {
         errorMsg.textContent = msg;
@@ -21,61 +52,474 @@
         setTimeout(() => errorMsg.hidden = true, 4000);
     }
 
+    /** @param {import("./types").UnlockedAchievement} a */
+    function showAchievement(a) {
+        toast.textContent = "Achievement unlocked: " + (a.name || a.id) + (a.description ? " — " + a.description : "");
+        toast.hidden = false;
+        setTimeout(() => toast.hidden = true, 6000);
+    }
+
     // Game renderers keyed by game type name
     const renderers = {
-        tictactoe: window.TicTacToeRenderer
+        tictactoe: window.TicTacToeRenderer,
+        blitz: window.TicTacToeRenderer,
+        // The sample script shares the built-in game's state view
+        'tictactoe-script': window.TicTacToeRenderer
     };
 
     let ws;
+    let stopped = false; // set once this tab must not reconnect
     let currentRenderer = null;
+    let currentGameType = null;
+    let botsLoaded = null; // game type whose bots are listed
+    // The handicaps the session's game offers, and the game they are for.
+    let handicapSpecs = [];
+    let handicapsLoaded = null;
+    let lastInfo = null; // the session info of the latest state
+
+    // clockOffset is the server's clock minus this one, in milliseconds,
+    // from the clock probe with the shortest round trip: its reply was the
+    // least delayed either way.
+    let clockOffset = 0;
+    let bestRTT = Infinity;
+    let pingTimer = null;
+    let voice = null; // null for spectators and browsers without WebRTC
+
+    // serverNow is the server's clock, for renderers counting down to a
+    // deadline the server set.
+    window.serverNow = () => Date.now() + clockOffset;
+
+    function ping() {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "ping", payload: {clientTime: Date.now()}}));
+        }
+    }
+
+    /** @param {import("./types").PongPayload} pong */
+    function handlePong(pong) {
+        const now = Date.now();
+        const rtt = now - pong.clientTime;
+        if (rtt > bestRTT) return;
+        bestRTT = rtt;
+        clockOffset = pong.serverTime + rtt / 2 - now;
+    }
+
+    async function loadBots(gameType) {
+        botsLoaded = gameType;
+        const resp = await fetch(prefix + "/api/games/" + encodeURIComponent(gameType) + "/bots");
+        if (!resp.ok) return;
+        const bots = await resp.json();
+        botSelect.innerHTML = "";
+        bots.forEach(b => {
+            const opt = document.createElement("option");
+            opt.value = b.name;
+            opt.textContent = b.name + " (difficulty " + b.difficulty + ")";
+            botSelect.appendChild(opt);
+        });
+    }
+
+    async function loadHandicaps(gameType) {
+        handicapsLoaded = gameType;
+        const resp = await fetch(prefix + "/api/games");
+        if (!resp.ok) return;
+        const game = (await resp.json()).find(g => g.name === gameType);
+        handicapSpecs = (game && game.handicaps) || [];
+        if (lastInfo) renderHandicaps(lastInfo);
+    }
+
+    // renderHandicaps lets the host of a waiting session give each player
+    // the game's handicaps: a checkbox for 0/1 flags, a number otherwise.
+    // Any change sends every player's values, replacing the last set.
+    function renderHandicaps(info) {
+        const el = document.getElementById("handicap-controls");
+        el.hidden = !(info.status === "waiting" && info.hostId === playerID && handicapSpecs.length > 0);
+        if (el.hidden) return;
+        el.innerHTML = "";
+        const heading = document.createElement("p");
+        heading.textContent = "Handicaps";
+        el.appendChild(heading);
+        info.players.forEach(p => {
+            const row = document.createElement("div");
+            row.className = "form-row";
+            row.append((info.names || {})[p] || p);
+            handicapSpecs.forEach(h => {
+                const label = document.createElement("label");
+                label.title = h.description || "";
+                const input = document.createElement("input");
+                input.dataset.player = p;
+                input.dataset.handicap = h.name;
+                const value = ((info.handicaps || {})[p] || {})[h.name] ?? h.default;
+                if (h.min === 0 && h.max === 1) {
+                    input.type = "checkbox";
+                    input.checked = value === 1;
+                    label.append(input, " " + (h.description || h.name));
+                } else {
+                    input.type = "number";
+                    input.min = h.min;
+                    input.max = h.max;
+                    input.value = value;
+                    label.append((h.description || h.name) + " ", input);
+                }
+                input.addEventListener("change", sendHandicaps);
+                row.appendChild(label);
+            });
+            el.appendChild(row);
+        });
+    }
+
+    function sendHandicaps() {
+        const handicaps = {};
+        document.querySelectorAll("#handicap-controls input").forEach(input => {
+            const values = handicaps[input.dataset.player] = handicaps[input.dataset.player] || {};
+            values[input.dataset.handicap] = input.type === "checkbox" ? (input.checked ? 1 : 0) : Number(input.value);
+        });
+        ws.send(JSON.stringify({type: "handicaps", payload: {handicaps}}));
+    }
+
+    // The session's timeline, fetched on each connection, lists what
+    // happened while this tab was disconnected. lastEventID is the last
+    // entry seen, and awaySince when the connection dropped, by the server's
+    // clock.
+    let lastEventID = 0;
+    let awaySince = null;
+
+    async function catchUp() {
+        const since = awaySince;
+        awaySince = null;
+        const missed = [];
+        for (;;) {
+            const resp = await fetch(prefix + "/api/sessions/" + encodeURIComponent(code) +
+                "/events?since=" + lastEventID + "&limit=500");
+            if (!resp.ok) return;
+            const page = (await resp.json()).events;
+            page.forEach(e => {
+                lastEventID = e.id;
+                if (since && Date.parse(e.at) >= since) missed.push(e);
+            });
+            if (page.length < 500) break;
+        }
+        if (missed.length === 0) return;
+        const list = document.getElementById("away-events");
+        list.innerHTML = "";
+        missed.forEach(e => {
+            const li = document.createElement("li");
+            li.textContent = new Date(e.at).toLocaleTimeString(locale) + " " + describeEvent(e);
+            list.appendChild(li);
+        });
+        document.getElementById("away").hidden = false;
+    }
+
+    function describeEvent(e) {
+        switch (e.type) {
+        case "joined": return e.playerId + " joined";
+        case "started": return "The match started";
+        case "moved": return e.playerId + " moved";
+        case "finished": return e.detail && e.detail.abandoned ? "The match was abandoned" : "The match finished";
+        case "chat": return e.playerId + ": " + e.detail.text;
+        }
+        return e.type;
+    }
+
+    document.getElementById("away-dismiss-btn").addEventListener("click", () => {
+        document.getElementById("away").hidden = true;
+    });
 
     function connect() {
         const proto = window.location.protocol === "https:" ? "wss:" : "ws:";
-        ws = new WebSocket(proto + "//" + window.location.host + "/api/sessions/" + code + "/ws");
+        ws = new WebSocket(proto + "//" + window.location.host + prefix + "/api/sessions/" + code + "/ws");
 
         ws.onopen = () => {
-            ws.send(JSON.stringify({type: "join", payload: {playerId: playerID}}));
+            ws.send(JSON.stringify({type: "join", payload: {
+                playerId: playerID || "", spectate: spectating,
+                handoff: pendingHandoff || undefined, token: seatToken || undefined,
+                toasts: true, narration: true
+            }}));
+            // A few probes up front for a quick estimate, then one every
+            // half minute in case the clocks drift
+            bestRTT = Infinity;
+            [0, 1000, 2000].forEach(delay => setTimeout(ping, delay));
+            pingTimer = setInterval(ping, 30000);
+            catchUp();
         };
 
         ws.onmessage = (evt) => {
+            /** @type {ServerMessage} */
             const msg = JSON.parse(evt.data);
             if (msg.type === "error") {
                 showError(msg.payload.message);
+                if (pendingHandoff) stopped = true; // the code was refused
+                return;
+            }
+            if (msg.type === "seat") {
+                handleSeat(msg.payload);
+                return;
+            }
+            if (msg.type === "handoff") {
+                showHandoff(msg.payload);
+                return;
+            }
+            if (msg.type === "pong") {
+                handlePong(msg.payload);
                 return;
             }
             if (msg.type === "state") {
                 handleState(msg.payload);
             }
+            if (msg.type === "events" && currentRenderer && currentRenderer.events) {
+                currentRenderer.events(msg.payload.events);
+            }
+            if (msg.type === "message") {
+                handleMessage(msg.payload.payload);
+            }
+            if (msg.type === "chat") {
+                showChat(msg.payload);
+            }
+            if (msg.type === "achievement_unlocked") {
+                showAchievement(msg.payload);
+            }
+            if (msg.type === "rtc_signal" && voice) {
+                voice.signal(msg.payload);
+                return;
+            }
+            if (msg.type === "survey") {
+                showSurvey(msg.payload);
+                return;
+            }
+            if (msg.type === "emote") {
+                showEmote(msg.payload);
+                return;
+            }
+            if (msg.type === "narration") {
+                // Read out by screen readers, as the board says nothing
+                document.getElementById("narration").textContent = msg.payload.text;
+            }
         };
 
-        ws.onclose = () => {
-            setTimeout(connect, 2000);
+        ws.onclose = (evt) => {
+            clearInterval(pingTimer);
+            if (!awaySince) awaySince = serverNow();
+            if (evt.code === 4001) {
+                stopped = true;
+                document.getElementById("handoff-info").textContent = "Your seat moved to another device.";
+            }
+            if (!stopped) setTimeout(connect, 2000);
         };
     }
 
+    // handleSeat records the seat this device holds and the token it needs
+    // to reconnect: the join secret of a seat it claimed first, or the seat
+    // of a redeemed handoff code or a guest ID.
+    function handleSeat(seat) {
+        playerID = seat.playerId;
+        seatToken = seat.token;
+        pendingHandoff = null;
+        localStorage.setItem(tokenKey, seatToken);
+        history.replaceState(null, "", prefix + "/session.html?code=" + encodeURIComponent(code) +
+            "&player=" + encodeURIComponent(playerID));
+    }
+
+    function showHandoff(handoff) {
+        const link = window.location.origin + prefix + "/session.html?code=" + encodeURIComponent(code) +
+            "&handoff=" + encodeURIComponent(handoff.code);
+        document.getElementById("handoff-info").textContent = "On your other device enter code " + handoff.code +
+            " or open " + link + " before " + new Date(handoff.expiresAt).toLocaleTimeString(locale) + ".";
+    }
+
+    // Chat lines show who said them; the server's answers to slash
+    // commands have no player.
+    /** @param {import("./types").ChatMessagePayload} line */
+    function showChat(line) {
+        const li = document.createElement("li");
+        li.textContent = line.playerId ? line.playerId + ": " + line.text : line.text;
+        if (!line.playerId) li.className = "chat-command";
+        const list = document.getElementById("chat-lines");
+        list.appendChild(li);
+        list.scrollTop = list.scrollHeight;
+    }
+
+    // Emotes float up over the board, or go to the game's renderer when it
+    // shows them its own way.
+    /** @param {import("./types").Emote} e */
+    function showEmote(e) {
+        if (currentRenderer && currentRenderer.emote) {
+            currentRenderer.emote(e);
+            return;
+        }
+        const float = document.createElement("div");
+        float.className = "emote-float";
+        float.textContent = e.emote;
+        const who = document.createElement("small");
+        who.textContent = (lastInfo && (lastInfo.names || {})[e.playerId]) || e.playerId;
+        float.appendChild(who);
+        float.style.insetInlineStart = (10 + Math.random() * 80) + "%";
+        float.addEventListener("animationend", () => float.remove());
+        gameArea.appendChild(float);
+    }
+
+    const emoteBar = document.getElementById("emote-bar");
+    emoteBar.hidden = spectating;
+    emoteBar.addEventListener("click", (evt) => {
+        const btn = evt.target.closest("button[data-emote]");
+        if (!btn || !ws || ws.readyState !== WebSocket.OPEN) return;
+        ws.send(JSON.stringify({type: "emote", payload: {emote: btn.dataset.emote}}));
+    });
+
+    // The server may ask how a match went once it ends. Answering, or
+    // dismissing with an empty answer, closes the form.
+    const surveyForm = document.getElementById("survey");
+    /** @param {import("./types").SurveyPayload} survey */
+    function showSurvey(survey) {
+        surveyForm.reset();
+        const names = survey.opponents.map(p => (lastInfo && (lastInfo.names || {})[p]) || p);
+        surveyForm.querySelector("h2").textContent = names.length ? "How was your game against " + names.join(", ") + "?" : "How was that game?";
+        surveyForm.hidden = false;
+    }
+
+    function answerSurvey(answer) {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "survey_answer", payload: answer}));
+        }
+        surveyForm.hidden = true;
+    }
+
+    surveyForm.addEventListener("submit", (evt) => {
+        evt.preventDefault();
+        answerSurvey({
+            fun: Number(document.getElementById("survey-fun").value),
+            conduct: Number(document.getElementById("survey-conduct").value),
+            comment: document.getElementById("survey-comment").value.trim(),
+            bug: document.getElementById("survey-bug").value.trim()
+        });
+        toast.textContent = "Thanks for the feedback";
+        toast.hidden = false;
+        setTimeout(() => toast.hidden = true, 3000);
+    });
+    document.getElementById("survey-dismiss-btn").addEventListener("click", () => answerSurvey({}));
+
+    // A report of a problem goes to the admins with the match as the
+    // server has it, so a board that seems stuck can be looked into.
+    const reportForm = document.getElementById("report");
+    document.getElementById("report-btn").addEventListener("click", () => {
+        reportForm.reset();
+        reportForm.hidden = false;
+        document.getElementById("report-note").focus();
+    });
+    document.getElementById("report-cancel-btn").addEventListener("click", () => reportForm.hidden = true);
+    reportForm.addEventListener("submit", async (evt) => {
+        evt.preventDefault();
+        const resp = await fetch(prefix + "/api/sessions/" + encodeURIComponent(code) + "/report", {
+            method: "POST",
+            // A seated player shows their seat token; a spectator is known
+            // by their guest cookie
+            headers: seatToken
+                ? {"Content-Type": "application/json", "Authorization": "Bearer " + seatToken}
+                : {"Content-Type": "application/json"},
+            body: JSON.stringify({note: document.getElementById("report-note").value.trim()})
+        });
+        const data = await resp.json();
+        if (!resp.ok) { showError(data.error); return; }
+        reportForm.hidden = true;
+        toast.textContent = "Thanks, your report was sent";
+        toast.hidden = false;
+        setTimeout(() => toast.hidden = true, 3000);
+    });
+
+    const chatInput = document.getElementById("chat-input");
+    document.getElementById("chat-form").addEventListener("submit", (evt) => {
+        evt.preventDefault();
+        const text = chatInput.value.trim();
+        if (!text || !ws || ws.readyState !== WebSocket.OPEN) return;
+        ws.send(JSON.stringify({type: "chat", payload: {text: text}}));
+        chatInput.value = "";
+    });
+    if (spectating) document.getElementById("chat-form").hidden = true;
+
+    // Private messages go to the game's renderer when it handles them, and
+    // are otherwise listed under the board.
+    function handleMessage(payload) {
+        if (currentRenderer && currentRenderer.message) {
+            currentRenderer.message(payload);
+            return;
+        }
+        const li = document.createElement("li");
+        li.textContent = typeof payload === "string" ? payload : JSON.stringify(payload);
+        document.getElementById("private-messages").appendChild(li);
+    }
+
+    /** @param {StatePayload} payload */
     function handleState(payload) {
-        const info = payload.sessionInfo;
+        // Until a probe comes back, the state's own stamp is the best guess
+        if (bestRTT === Infinity && payload.serverTime) {
+            clockOffset = payload.serverTime - Date.now();
+        }
+        const info = lastInfo = payload.sessionInfo;
         document.getElementById("session-status").textContent = info.status;
-        document.getElementById("game-title").textContent = info.gameType;
+        document.getElementById("game-title").textContent = info.gameType + (info.variant ? " (" + info.variant + ")" : "");
 
         // Update player list
         const playersList = document.getElementById("players");
         playersList.innerHTML = "";
+        const bots = {};
+        (info.bots || []).forEach(b => bots[b.playerId] = b);
         info.players.forEach(p => {
             const li = document.createElement("li");
-            li.textContent = p + (p === info.hostId ? " (host)" : "") + (p === playerID ? " (you)" : "");
+            const bot = bots[p] ? " (bot, difficulty " + bots[p].difficulty + ")" : "";
+            // Guests are shown by the name they gave
+            const name = (info.names || {})[p] || p;
+            const handicap = Object.entries((info.handicaps || {})[p] || {})
+                .filter(([, v]) => v !== 0).map(([k, v]) => v === 1 ? k : k + " " + v).join(", ");
+            li.textContent = name + (p === info.hostId ? " (host)" : "") + bot + (p === playerID ? " (you)" : "") +
+                (handicap ? " (handicap: " + handicap + ")" : "");
+            playersList.appendChild(li);
+        });
+        (info.reservations || []).forEach(r => {
+            const li = document.createElement("li");
+            li.className = "reserved";
+            li.textContent = r.playerId + " (seat reserved until " + new Date(r.expiresAt).toLocaleTimeString(locale) + ")";
             playersList.appendChild(li);
         });
 
-        // Show start button for host in waiting state
-        startBtn.hidden = !(info.status === "waiting" && info.hostId === playerID);
+        renderParty(info, payload.scoreboard || []);
+        renderFairness(payload.fairness);
+        renderErrored(info);
+        resultsDiv.hidden = !(payload.results && payload.results.length > 0);
+
+        // Show start button and bot controls for host in waiting state
+        const hostWaiting = info.status === "waiting" && info.hostId === playerID;
+        startBtn.hidden = !hostWaiting;
+        botControls.hidden = !hostWaiting;
+        document.getElementById("reserve-controls").hidden = !hostWaiting;
+        document.getElementById("shuffle-btn").hidden = !hostWaiting;
+        document.getElementById("turn-order-controls").hidden = !hostWaiting;
+        document.getElementById("turn-order-select").value = info.turnOrder || "join";
+        renderSeats(info);
+        if (hostWaiting && botsLoaded !== info.gameType) {
+            loadBots(info.gameType);
+        }
+        if (hostWaiting && handicapsLoaded !== info.gameType) {
+            loadHandicaps(info.gameType);
+        }
+        renderHandicaps(info);
+        document.getElementById("start-setup-controls").hidden = !hostWaiting;
+        const startPosition = document.getElementById("start-position-input");
+        if (document.activeElement !== startPosition) {
+            startPosition.value = info.setup ? JSON.stringify(info.setup) : "";
+        }
+        // A practice session is nobody else's to join, so it has no invite
+        shareLink.parentElement.hidden = !!info.practice;
+        document.getElementById("practice-controls").hidden = !(info.practice && (info.status === "playing" || info.status === "finished"));
+        if (info.status === "waiting") {
+            document.getElementById("players-list").hidden = false;
+            gameArea.hidden = true;
+        }
 
         if (info.status === "playing" || info.status === "finished") {
             document.getElementById("players-list").hidden = true;
             gameArea.hidden = false;
 
-            // Initialize renderer if needed
-            if (!currentRenderer && renderers[info.gameType]) {
+            // Initialize renderer if needed; party sessions change game type
+            if (currentGameType !== info.gameType && renderers[info.gameType]) {
+                currentGameType = info.gameType;
                 currentRenderer = renderers[info.gameType];
                 currentRenderer.init(document.getElementById("game-board"), sendAction);
             }
@@ -106,24 +550,241 @@
                     row.innerHTML = "<span>" + r.playerId + "</span><span>Rank #" + r.rank + "</span>";
                     list.appendChild(row);
                 });
+                const summary = document.getElementById("match-summary");
+                summary.hidden = !payload.summary;
+                if (payload.summary) {
+                    summary.textContent = payload.summary.moves + " moves in " + formatDuration(payload.summary.durationMs);
+                }
             }
         }
     }
 
+    // renderErrored explains why an errored session stopped, offering its
+    // host to restore the match from the last move or abort it.
+    function renderErrored(info) {
+        const errored = info.status === "errored";
+        document.getElementById("errored").hidden = !errored;
+        if (errored) {
+            document.getElementById("errored-msg").textContent = info.error;
+            gameArea.hidden = true;
+        }
+        const host = errored && info.hostId === playerID;
+        document.getElementById("restore-btn").hidden = !host;
+        document.getElementById("abort-btn").hidden = !host;
+    }
+
+    // formatDuration renders milliseconds as minutes and seconds.
+    function formatDuration(ms) {
+        const seconds = Math.round(ms / 1000);
+        const minutes = Math.floor(seconds / 60);
+        return (minutes > 0 ? minutes + "m " : "") + (seconds % 60) + "s";
+    }
+
+    // renderSeats lists the seats before the start, seat 1 moving first,
+    // with a button to take an open seat or give up your own.
+    function renderSeats(info) {
+        const el = document.getElementById("seats");
+        el.innerHTML = "";
+        if (info.status !== "waiting" || spectating) return;
+        (info.seats || []).forEach((id, seat) => {
+            const row = document.createElement("div");
+            row.className = "form-row";
+            const label = document.createElement("span");
+            label.textContent = "Seat " + (seat + 1) + ": " + (id || "open");
+            row.appendChild(label);
+            if (!id || id === playerID) {
+                const btn = document.createElement("button");
+                btn.textContent = id ? "Leave" : "Sit here";
+                btn.addEventListener("click", () => {
+                    ws.send(JSON.stringify({type: "choose_seat", payload: {seat: id ? -1 : seat}}));
+                });
+                row.appendChild(btn);
+            }
+            el.appendChild(row);
+        });
+    }
+
+    // renderFairness shows the seed commitment, and once the match is over
+    // checks the revealed seed against it: SHA-256 of "<salt>:<seed>".
+    async function renderFairness(fairness) {
+        const el = document.getElementById("fairness");
+        el.hidden = !fairness;
+        if (!fairness) return;
+        const short = fairness.hash.slice(0, 16);
+        if (!fairness.salt) {
+            el.textContent = "Seed commitment: " + short + "…";
+            return;
+        }
+        const data = new TextEncoder().encode(fairness.salt + ":" + fairness.seed);
+        const digest = new Uint8Array(await crypto.subtle.digest("SHA-256", data));
+        const hex = Array.from(digest, b => b.toString(16).padStart(2, "0")).join("");
+        el.textContent = "Seed " + fairness.seed + (hex === fairness.hash
+            ? " matches the commitment " + short + "…"
+            : " does NOT match the commitment " + short + "…");
+    }
+
+    function renderParty(info, scoreboard) {
+        const party = info.party;
+        document.getElementById("party").hidden = !party;
+        const hasNext = party && party.round + 1 < party.games.length;
+        nextGameBtn.hidden = !(hasNext && info.status === "finished" && info.hostId === playerID);
+        rematchBtn.hidden = !(!party && info.status === "finished" && info.hostId === playerID);
+        if (!party) return;
+        document.getElementById("party-progress").textContent =
+            "Game " + (party.round + 1) + " of " + party.games.length + ": " + party.games.join(", ");
+        const list = document.getElementById("party-standings");
+        list.innerHTML = "";
+        scoreboard.forEach(e => {
+            const row = document.createElement("div");
+            row.className = "result-row";
+            row.innerHTML = "<span>#" + e.rank + " " + e.playerId + "</span><span>" +
+                e.points + " pts, " + e.wins + " wins</span>";
+            list.appendChild(row);
+        });
+    }
+
+    /** @param {import("./types").Action} action */
     function sendAction(action) {
         if (ws && ws.readyState === WebSocket.OPEN) {
             ws.send(JSON.stringify({
                 type: "action",
-                payload: {action: action}
+                // sentAt lets games with strict clocks credit network delay
+                payload: {action: action, sentAt: Math.round(serverNow())}
             }));
         }
     }
 
+    document.getElementById("add-bot-btn").addEventListener("click", () => {
+        if (ws && ws.readyState === WebSocket.OPEN && botSelect.value) {
+            ws.send(JSON.stringify({type: "add_bot", payload: {strategy: botSelect.value}}));
+        }
+    });
+
+    // Practice sessions take moves back and set up positions to play from
+    document.getElementById("undo-btn").addEventListener("click", () => {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "undo", payload: {}}));
+        }
+    });
+
+    document.getElementById("setup-btn").addEventListener("click", () => {
+        const input = document.getElementById("position-input");
+        let position;
+        try {
+            position = JSON.parse(input.value);
+        } catch {
+            showError("The position must be JSON");
+            return;
+        }
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "setup", payload: {position: position}}));
+        }
+    });
+
+    // The host of a waiting session may set up the starting position; an
+    // empty one is the usual start
+    document.getElementById("start-setup-btn").addEventListener("click", () => {
+        const input = document.getElementById("start-position-input");
+        let position = null;
+        if (input.value.trim() !== "") {
+            try {
+                position = JSON.parse(input.value);
+            } catch {
+                showError("The position must be JSON");
+                return;
+            }
+        }
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "setup", payload: {position: position}}));
+        }
+    });
+
     startBtn.addEventListener("click", () => {
         if (ws && ws.readyState === WebSocket.OPEN) {
             ws.send(JSON.stringify({type: "start", payload: {}}));
         }
     });
 
+    document.getElementById("shuffle-btn").addEventListener("click", () => {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "shuffle_seats", payload: {}}));
+        }
+    });
+
+    document.getElementById("turn-order-select").addEventListener("change", (evt) => {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "turn_order", payload: {turnOrder: evt.target.value}}));
+        }
+    });
+
+    document.getElementById("reserve-btn").addEventListener("click", () => {
+        const ids = document.getElementById("reserve-input").value
+            .split(",").map(id => id.trim()).filter(id => id);
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "reserve", payload: {playerIds: ids}}));
+        }
+    });
+
+    nextGameBtn.addEventListener("click", () => {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "next_game", payload: {}}));
+        }
+    });
+
+    rematchBtn.addEventListener("click", () => {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "rematch", payload: {}}));
+        }
+    });
+
+    ["restore", "abort"].forEach(type => {
+        document.getElementById(type + "-btn").addEventListener("click", () => {
+            if (ws && ws.readyState === WebSocket.OPEN) {
+                ws.send(JSON.stringify({type: type, payload: {}}));
+            }
+        });
+    });
+
+    const handoffBtn = document.getElementById("handoff-btn");
+    handoffBtn.hidden = spectating;
+    handoffBtn.addEventListener("click", () => {
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            ws.send(JSON.stringify({type: "handoff", payload: {}}));
+        }
+    });
+
+    const notifyBtn = document.getElementById("notify-btn");
+    notifyBtn.hidden = spectating;
+    notifyBtn.addEventListener("click", async () => {
+        const err = await window.enablePush(playerID);
+        if (err) { showError(err); return; }
+        notifyBtn.hidden = true;
+    });
+
+    // Voice chat is with the other people seated, not bots, and starts
+    // only when asked, as it needs the microphone.
+    const voiceBtn = document.getElementById("voice-btn");
+    voiceBtn.hidden = spectating || !window.RTCPeerConnection;
+    if (!voiceBtn.hidden) {
+        voice = window.createVoiceChat(() => playerID, signal => {
+            if (ws && ws.readyState === WebSocket.OPEN) ws.send(JSON.stringify({type: "rtc_signal", payload: signal}));
+        }, line => document.getElementById("voice-status").textContent = line);
+    }
+    voiceBtn.addEventListener("click", async () => {
+        if (voice.active()) {
+            voice.stop();
+            voiceBtn.textContent = "Join Voice Chat";
+            return;
+        }
+        const bots = new Set((lastInfo && lastInfo.bots || []).map(b => b.playerId));
+        const others = (lastInfo ? lastInfo.players : []).filter(p => p !== playerID && !bots.has(p));
+        try {
+            await voice.start(others);
+            voiceBtn.textContent = "Leave Voice Chat";
+        } catch (e) {
+            showError("Could not start voice chat: " + e.message);
+        }
+    });
+
     connect();
 })();
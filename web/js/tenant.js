@@ -0,0 +1,4 @@
+// The path a tenant's pages live under, /t/<tenant>, or "" on the main
+// site. Its API does too, so the other scripts put it before every link
+// and request; each page loads this one first.
+window.tenantPrefix = (window.location.pathname.match(/^\/t\/[^\/]+/) || [""])[0];
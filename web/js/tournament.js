@@ -0,0 +1,150 @@
+// The tournament hub: pairings, results and standings of one tournament,
+// kept current by its stream, with links to watch the games in play.
+(function() {
+    const prefix = window.tenantPrefix;
+    const id = new URLSearchParams(window.location.search).get("id");
+    if (!id) {
+        window.location.href = prefix + "/";
+        return;
+    }
+    document.getElementById("lobby-link").href = prefix + "/";
+
+    const formats = {swiss: "Swiss", round_robin: "round robin"};
+    const errorMsg = document.getElementById("error-msg");
+
+    function cell(row, text, tag) {
+        const el = document.createElement(tag || "td");
+        el.textContent = text;
+        row.appendChild(el);
+        return el;
+    }
+
+    function points(n) {
+        return String(Math.floor(n)) + (n % 1 ? "\u00bd" : "");
+    }
+
+    let branded = false;
+
+    function renderHeader(t) {
+        // A club's tournament wears the club's branding
+        if (t.club && !branded) {
+            branded = true;
+            window.loadBranding(t.club);
+        }
+        document.title = t.name;
+        document.getElementById("tournament-name").textContent = t.name;
+        document.getElementById("tournament-game").textContent = t.gameType + ", " + (formats[t.format] || t.format);
+        let status = t.status;
+        if (t.status === "running") status = "round " + t.round + " of " + t.rounds;
+        if (t.status === "registering") status = "taking entries (" + t.players.length + ")";
+        document.getElementById("tournament-status").textContent = status;
+    }
+
+    function renderStandings(standings) {
+        const body = document.querySelector("#standings tbody");
+        body.innerHTML = "";
+        standings.forEach(s => {
+            const row = document.createElement("tr");
+            cell(row, s.rank);
+            cell(row, s.playerId);
+            cell(row, points(s.score));
+            cell(row, s.wins + "-" + s.draws + "-" + s.losses + (s.byes ? " +" + s.byes + " bye" : ""));
+            cell(row, points(s.buchholz));
+            cell(row, points(s.sonnebornBerger));
+            body.appendChild(row);
+        });
+    }
+
+    // The cross table shows what each player, in standings order, scored
+    // against each other.
+    function renderCrossTable(t, standings) {
+        const table = document.getElementById("crosstable");
+        table.innerHTML = "";
+        const order = standings.map(s => s.playerId);
+        const scored = new Map();
+        t.pairings.forEach(p => {
+            if (!p.playerB || !p.result || p.result === "bye") return;
+            const a = {a: "1", b: "0", draw: "\u00bd", none: "0"}[p.result];
+            const b = {a: "0", b: "1", draw: "\u00bd", none: "0"}[p.result];
+            scored.set(p.playerA + "\n" + p.playerB, (scored.get(p.playerA + "\n" + p.playerB) || "") + a);
+            scored.set(p.playerB + "\n" + p.playerA, (scored.get(p.playerB + "\n" + p.playerA) || "") + b);
+        });
+        const head = document.createElement("tr");
+        cell(head, "", "th");
+        order.forEach((_, i) => cell(head, i + 1, "th"));
+        table.appendChild(head);
+        order.forEach((player, i) => {
+            const row = document.createElement("tr");
+            cell(row, (i + 1) + ". " + player, "th");
+            order.forEach(opponent => {
+                const td = cell(row, player === opponent ? "\u2014" : (scored.get(player + "\n" + opponent) || ""));
+                if (player === opponent) td.className = "self";
+            });
+            table.appendChild(row);
+        });
+    }
+
+    function describeResult(p) {
+        switch (p.result) {
+            case "a": return "1\u20130";
+            case "b": return "0\u20131";
+            case "draw": return "\u00bd\u2013\u00bd";
+            case "bye": return "bye";
+            case "none": return "0\u20130";
+        }
+        return "";
+    }
+
+    function renderRounds(t) {
+        const rounds = document.getElementById("rounds");
+        rounds.innerHTML = "";
+        if (t.pairings.length === 0) {
+            rounds.textContent = t.status === "registering" ? "Entered: " + (t.players.join(", ") || "nobody yet") : "No games yet";
+            return;
+        }
+        for (let round = t.round; round >= 1; round--) {
+            const heading = document.createElement("h3");
+            heading.textContent = "Round " + round;
+            rounds.appendChild(heading);
+            const list = document.createElement("div");
+            list.className = "sessions-grid";
+            t.pairings.filter(p => p.round === round).forEach(p => {
+                const card = document.createElement("div");
+                card.className = "session-card";
+                const players = document.createElement("span");
+                players.textContent = p.board + ". " + p.playerA + (p.playerB ? " \u2013 " + p.playerB : "");
+                card.appendChild(players);
+                if (p.result) {
+                    const result = document.createElement("span");
+                    result.className = "meta";
+                    result.textContent = describeResult(p);
+                    card.appendChild(result);
+                } else if (p.sessionCode) {
+                    const watch = document.createElement("a");
+                    watch.className = "btn";
+                    watch.textContent = "Watch live";
+                    watch.href = prefix + "/session.html?code=" + encodeURIComponent(p.sessionCode) + "&spectate=1";
+                    card.appendChild(watch);
+                }
+                list.appendChild(card);
+            });
+            rounds.appendChild(list);
+        }
+    }
+
+    function render(update) {
+        errorMsg.hidden = true;
+        renderHeader(update.tournament);
+        renderStandings(update.standings);
+        renderCrossTable(update.tournament, update.standings);
+        renderRounds(update.tournament);
+    }
+
+    const stream = new EventSource(prefix + "/api/tournaments/" + encodeURIComponent(id) + "/stream");
+    stream.addEventListener("tournament", ev => render(JSON.parse(ev.data)));
+    stream.onerror = () => {
+        // EventSource retries on its own; say so while it does
+        errorMsg.textContent = "Lost the live feed; reconnecting\u2026";
+        errorMsg.hidden = false;
+    };
+})();
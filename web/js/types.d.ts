@@ -0,0 +1,378 @@
+// Code generated by go run ./cmd/wstypes; DO NOT EDIT.
+//
+// Types of the session WebSocket protocol, from the Go types the server
+// marshals. Use them from plain JavaScript through JSDoc, as in
+// /** @param {import("./types").StatePayload} payload */.
+
+export interface Action {
+    payload: unknown;
+    type: string;
+}
+
+export interface ActionPayload {
+    action: Action;
+    as?: string;
+    sentAt?: number;
+}
+
+export interface ActionTextPayload {
+    as?: string;
+    sentAt?: number;
+    text: string;
+}
+
+export interface AddBotPayload {
+    strategy: string;
+}
+
+export interface BotInfo {
+    difficulty: number;
+    playerId: string;
+    strategy: string;
+}
+
+export interface ChatMessagePayload {
+    at: string;
+    playerId?: string;
+    text: string;
+}
+
+export interface ChatPayload {
+    text: string;
+}
+
+export interface ChooseSeatPayload {
+    playerId?: string;
+    seat: number;
+}
+
+export interface ClockView {
+    banks: Record<string, number>;
+    flagged?: string;
+    increment: number;
+    running?: string;
+    since?: number;
+}
+
+export interface Commitment {
+    hash: string;
+    salt?: string;
+    seed?: number;
+}
+
+export interface Emote {
+    at: string;
+    emote: string;
+    playerId: string;
+}
+
+export interface EmotePayload {
+    emote: string;
+}
+
+export interface ErrorPayload {
+    message: string;
+}
+
+export interface Event {
+    data?: unknown;
+    type: string;
+}
+
+export interface EventsPayload {
+    events: Event[];
+    playerId: string;
+    seq: number;
+}
+
+export interface HandicapsPayload {
+    handicaps: Record<string, Record<string, number>>;
+}
+
+export interface HandoffPayload {
+    code: string;
+    expiresAt: string;
+}
+
+export interface Info {
+    abandoned?: boolean;
+    bots?: BotInfo[];
+    code: string;
+    createdAt: string;
+    error?: string;
+    expireAfter?: string;
+    finishedAt?: string;
+    gameType: string;
+    handicaps?: Record<string, Record<string, number>>;
+    hostId: string;
+    lastActivity: string;
+    names?: Record<string, string>;
+    newerRules?: string;
+    openSeats: number;
+    options?: Record<string, number>;
+    party?: Party;
+    pinned?: boolean;
+    players: string[];
+    practice?: boolean;
+    private?: boolean;
+    removed?: string[];
+    reservations?: Reservation[];
+    rulesVersion?: string;
+    sandbox?: boolean;
+    seats?: string[];
+    setup?: unknown;
+    spectators?: number;
+    startedAt?: string;
+    status: string;
+    turnOrder: string;
+    variant?: string;
+    voteThresholds: VoteThresholds;
+}
+
+export interface JoinPayload {
+    handoff?: string;
+    narration?: boolean;
+    playerId: string;
+    sections?: string[];
+    spectate?: boolean;
+    toasts?: boolean;
+    token?: string;
+}
+
+export interface MatchSummary {
+    durationMs: number;
+    finishedAt: string;
+    moves: number;
+    startedAt: string;
+}
+
+export interface Message {
+    payload: unknown;
+}
+
+export interface NarrationPayload {
+    playerId: string;
+    seq: number;
+    text: string;
+}
+
+export interface Party {
+    games: string[];
+    round: number;
+    rounds: PlayerResult[][];
+    standings: Record<string, number>;
+}
+
+export interface PingPayload {
+    clientTime: number;
+}
+
+export interface PlayerResult {
+    detail?: unknown;
+    outcome?: string;
+    playerId: string;
+    rank: number;
+    score: number;
+}
+
+export interface PongPayload {
+    clientTime: number;
+    serverTime: number;
+}
+
+export interface Reservation {
+    expiresAt: string;
+    playerId: string;
+}
+
+export interface ReservePayload {
+    playerIds: string[];
+}
+
+export interface RtcSignalMessagePayload {
+    data?: unknown;
+    from: string;
+    kind: string;
+}
+
+export interface RtcSignalPayload {
+    data?: unknown;
+    kind: string;
+    to: string;
+}
+
+export interface ScoreEntry {
+    abandoned?: number;
+    draws: number;
+    forfeits?: number;
+    losses: number;
+    played: number;
+    playerId: string;
+    points: number;
+    rank: number;
+    score: number;
+    timeouts?: number;
+    wins: number;
+}
+
+export interface SeatPayload {
+    playerId: string;
+    token: string;
+}
+
+export interface SetupPayload {
+    position: unknown;
+}
+
+export interface StatePayload {
+    fairness?: Commitment;
+    results?: PlayerResult[];
+    scoreboard?: ScoreEntry[];
+    serverTime: number;
+    sessionInfo: Info;
+    state: unknown;
+    summary?: MatchSummary;
+    validActions: Action[];
+}
+
+export interface SurveyPayload {
+    gameType: string;
+    opponents: string[];
+    sessionCode: string;
+}
+
+export interface SurveyResponse {
+    bug?: string;
+    comment?: string;
+    conduct?: number;
+    fun?: number;
+}
+
+export interface TictactoeState {
+    board: number[];
+    clock?: ClockView;
+    done: boolean;
+    misere?: boolean;
+    players: string[];
+    turn: string;
+    winner?: string;
+    you: number;
+}
+
+export interface TurnOrderPayload {
+    turnOrder: string;
+}
+
+export interface UnlockedAchievement {
+    description: string;
+    gameType?: string;
+    id: string;
+    name: string;
+    sessionCode: string;
+    unlockedAt: string;
+}
+
+export interface VotePayload {
+    kind: string;
+    playerId: string;
+}
+
+export interface VoteTally {
+    kind: string;
+    needed: number;
+    passed: boolean;
+    playerId: string;
+    voters: string[];
+}
+
+export interface VoteThresholds {
+    remove: number;
+    skip: number;
+}
+
+export interface WSMessage {
+    payload: unknown;
+    type: string;
+}
+
+/** A message a client sends. */
+export type ClientMessage =
+    /** Opens the connection, taking or reclaiming a seat, or watching as a spectator. */
+    | { type: "join"; payload: JoinPayload }
+    /** Makes a move. */
+    | { type: "action"; payload: ActionPayload }
+    /** Makes a move written in the game's notation, such as b2. */
+    | { type: "action_text"; payload: ActionTextPayload }
+    /** Probes the server's clock; answered with pong. */
+    | { type: "ping"; payload: PingPayload }
+    /** Takes back the last move and any bot replies to it. Practice sessions only. */
+    | { type: "undo"; payload?: null }
+    /** Before the start, sets the starting position, or the usual start when empty; host only. In practice, sets the match up in a position to practise from, which undo goes back no further than. */
+    | { type: "setup"; payload: SetupPayload }
+    /** Starts the match. Host only. */
+    | { type: "start"; payload?: null }
+    /** Starts a party's next round. Host only. */
+    | { type: "next_game"; payload?: null }
+    /** Plays the same game again with the same players. Host only. */
+    | { type: "rematch"; payload?: null }
+    /** Restores an errored match from its last saved state. Host only. */
+    | { type: "restore"; payload?: null }
+    /** Abandons an errored match without a result. Host only. */
+    | { type: "abort"; payload?: null }
+    /** Seats a built-in bot. Host only. */
+    | { type: "add_bot"; payload: AddBotPayload }
+    /** Picks a seat before the start. */
+    | { type: "choose_seat"; payload: ChooseSeatPayload }
+    /** Seats everyone at random. Host only. */
+    | { type: "shuffle_seats"; payload?: null }
+    /** Asks for a code to move this seat to another device; answered with handoff. */
+    | { type: "handoff"; payload?: null }
+    /** Sets how unseated players are seated. Host only. */
+    | { type: "turn_order"; payload: TurnOrderPayload }
+    /** Holds seats for invited players. Host only. */
+    | { type: "reserve"; payload: ReservePayload }
+    /** Sets the share of players needed to skip or remove a player. Host only. */
+    | { type: "vote_thresholds"; payload: VoteThresholds }
+    /** Gives players an uneven start, replacing any handicaps set before. Host only. */
+    | { type: "handicaps"; payload: HandicapsPayload }
+    /** Votes to skip the turn of, or remove, an unresponsive player. */
+    | { type: "vote"; payload: VotePayload }
+    /** Says something to the session, or runs a slash command such as /help. */
+    | { type: "chat"; payload: ChatPayload }
+    /** Sends a quick reaction: 👍, 😮 or GG. */
+    | { type: "emote"; payload: EmotePayload }
+    /** Answers the survey about the last match: ratings from 1 to 5, a comment on the opponents and a bug report, each optional. An empty answer dismisses it. */
+    | { type: "survey_answer"; payload: SurveyResponse }
+    /** Passes a WebRTC offer, answer, ICE candidate or hangup to another connected player, for voice chat. */
+    | { type: "rtc_signal"; payload: RtcSignalPayload };
+
+/** A message the server sends. */
+export type ServerMessage =
+    /** The session and the match as the receiver may see them, with only the sections it subscribed to. */
+    | { type: "state"; payload: StatePayload }
+    /** What an action did, sent before the state it leads to. */
+    | { type: "events"; payload: EventsPayload }
+    /** What a move did, in words, for connections that joined with narration in a game that describes its moves. */
+    | { type: "narration"; payload: NarrationPayload }
+    /** A private message from the game to this player. */
+    | { type: "message"; payload: Message }
+    /** The tally of a vote after each ballot. */
+    | { type: "vote"; payload: VoteTally }
+    /** A chat line, or the answer to a slash command. */
+    | { type: "chat"; payload: ChatMessagePayload }
+    /** A player's quick reaction, apart from chat; those of the last half minute are replayed on joining. */
+    | { type: "emote"; payload: Emote }
+    /** Asks how the match that just finished went, when the server runs surveys; answered with survey_answer. */
+    | { type: "survey"; payload: SurveyPayload }
+    /** A WebRTC signal another player sent this one. */
+    | { type: "rtc_signal"; payload: RtcSignalMessagePayload }
+    /** The seat a redeemed handoff code gave this device. */
+    | { type: "seat"; payload: SeatPayload }
+    /** The code to enter on the device taking over this seat. */
+    | { type: "handoff"; payload: HandoffPayload }
+    /** The answer to ping. */
+    | { type: "pong"; payload: PongPayload }
+    /** An achievement this player just unlocked, to show as a toast. */
+    | { type: "achievement_unlocked"; payload: UnlockedAchievement }
+    /** Why a message was refused, or that the game stopped. */
+    | { type: "error"; payload: ErrorPayload };
@@ -0,0 +1,114 @@
+// Optional voice chat between the players of a session. The browsers talk
+// to each other directly; the session WebSocket carries their offers,
+// answers and ICE candidates as rtc_signal messages, and
+// /api/rtc/config names the STUN and TURN servers to reach each other
+// through.
+(function() {
+    const prefix = window.tenantPrefix;
+
+    // createVoiceChat returns the voice chat of the player self names,
+    // which a handoff may change. send passes an rtc_signal payload to the
+    // server, and onStatus hears a line to show whenever the call changes.
+    window.createVoiceChat = function(self, send, onStatus) {
+        let stream = null;
+        let iceServers = [];
+        const peers = new Map(); // player ID -> {pc, audio, makingOffer, polite}
+
+        function status() {
+            if (!stream) return onStatus("");
+            const names = [...peers.keys()].filter(id => peers.get(id).pc.connectionState === "connected");
+            onStatus(names.length ? "In voice chat with " + names.join(", ") : "Waiting for others to join voice chat");
+        }
+
+        // Both players may offer at once. The one whose ID sorts first
+        // keeps its offer, and the other drops its own for the one it got.
+        function peer(id) {
+            if (peers.has(id)) return peers.get(id);
+            const pc = new RTCPeerConnection({iceServers});
+            const audio = new Audio();
+            audio.autoplay = true;
+            const p = {pc, audio, makingOffer: false, polite: self() > id};
+            stream.getTracks().forEach(track => pc.addTrack(track, stream));
+            pc.ontrack = evt => audio.srcObject = evt.streams[0];
+            pc.onicecandidate = evt => {
+                if (evt.candidate) send({to: id, kind: "candidate", data: evt.candidate.toJSON()});
+            };
+            pc.onnegotiationneeded = async () => {
+                try {
+                    p.makingOffer = true;
+                    await pc.setLocalDescription();
+                    send({to: id, kind: "offer", data: pc.localDescription.toJSON()});
+                } finally {
+                    p.makingOffer = false;
+                }
+            };
+            pc.onconnectionstatechange = () => {
+                if (pc.connectionState === "failed") close(id);
+                status();
+            };
+            peers.set(id, p);
+            return p;
+        }
+
+        function close(id) {
+            const p = peers.get(id);
+            if (!p) return;
+            p.pc.close();
+            p.audio.srcObject = null;
+            peers.delete(id);
+            status();
+        }
+
+        return {
+            active: () => stream !== null,
+
+            // start asks for the microphone and calls others, the players
+            // of the session other than this one and its bots.
+            async start(others) {
+                const resp = await fetch(prefix + "/api/rtc/config");
+                if (resp.ok) iceServers = (await resp.json()).iceServers;
+                stream = await navigator.mediaDevices.getUserMedia({audio: true});
+                others.forEach(peer);
+                status();
+            },
+
+            stop() {
+                for (const id of [...peers.keys()]) {
+                    send({to: id, kind: "hangup"});
+                    close(id);
+                }
+                if (stream) stream.getTracks().forEach(track => track.stop());
+                stream = null;
+                status();
+            },
+
+            // signal handles an rtc_signal from another player. Calls
+            // arriving while voice chat is off are left unanswered; the
+            // caller hears from this player once it joins.
+            /** @param {import("./types").RtcSignalMessagePayload} msg */
+            async signal(msg) {
+                if (msg.kind === "hangup") return close(msg.from);
+                if (!stream) {
+                    if (msg.kind === "offer") onStatus(msg.from + " started voice chat");
+                    return;
+                }
+                const p = peer(msg.from);
+                if (msg.kind === "candidate") {
+                    try {
+                        await p.pc.addIceCandidate(msg.data);
+                    } catch (e) {
+                        // A candidate for an offer this side dropped
+                    }
+                    return;
+                }
+                const collision = msg.kind === "offer" && (p.makingOffer || p.pc.signalingState !== "stable");
+                if (collision && !p.polite) return;
+                await p.pc.setRemoteDescription(msg.data);
+                if (msg.kind === "offer") {
+                    await p.pc.setLocalDescription();
+                    send({to: msg.from, kind: "answer", data: p.pc.localDescription.toJSON()});
+                }
+            }
+        };
+    };
+})();
@@ -0,0 +1,19 @@
+// Service worker for Web Push notifications.
+
+self.addEventListener("push", event => {
+    const msg = event.data ? event.data.json() : {title: "Games"};
+    event.waitUntil(self.registration.showNotification(msg.title, {
+        body: msg.body,
+        tag: msg.tag,
+        data: {url: msg.url || "/"}
+    }));
+});
+
+self.addEventListener("notificationclick", event => {
+    event.notification.close();
+    const url = event.notification.data.url;
+    event.waitUntil(clients.matchAll({type: "window"}).then(windows => {
+        const open = windows.find(w => new URL(w.url).pathname + new URL(w.url).search === url);
+        return open ? open.focus() : clients.openWindow(url);
+    }));
+});
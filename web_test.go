@@ -9,9 +9,16 @@ func TestWebFSContainsFiles(t *testing.T) {
 	files := []string{
 		"web/index.html",
 		"web/session.html",
+		"web/tournament.html",
+		"web/club.html",
 		"web/css/style.css",
 		"web/js/lobby.js",
 		"web/js/session.js",
+		"web/js/tournament.js",
+		"web/js/branding.js",
+		"web/js/club.js",
+		"web/js/voice.js",
+		"web/js/tenant.js",
 		"web/js/games/tictactoe.js",
 	}
 	for _, path := range files {